@@ -23,14 +23,22 @@ import (
 )
 
 // AdminScriptClusterRef identifies the Seaweed cluster whose masters and
-// filer the scheduled `weed shell` script runs against. The reference is
-// always resolved in the AdminScript's own namespace — cross-namespace
-// references are intentionally not supported, so the generated CronJob lives
-// next to the cluster it administers.
+// filer the scheduled `weed shell` script runs against.
+//
+// A cluster in another namespace must grant the reference with a
+// ResourceReferenceGrant. The generated CronJob then runs in the cluster's
+// namespace, where its TLS material lives, and spec.serviceAccountName,
+// spec.credentialsSecret and spec.imagePullSecrets must be left unset since
+// they would name objects in that namespace.
 type AdminScriptClusterRef struct {
-	// Name of the Seaweed CR in the same namespace as this AdminScript.
+	// Name of the Seaweed CR.
 	// +kubebuilder:validation:MinLength=1
 	Name string `json:"name"`
+
+	// Namespace of the Seaweed CR. Defaults to the AdminScript's own
+	// namespace.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
 }
 
 // AdminScriptSpec defines a `weed shell` script to run on a cron schedule.
@@ -42,8 +50,8 @@ type AdminScriptClusterRef struct {
 // same security.toml/TLS material so the shell can authenticate to the
 // masters over gRPC.
 type AdminScriptSpec struct {
-	// ClusterRef points at the Seaweed CR (same namespace) whose masters and
-	// filer the script administers.
+	// ClusterRef points at the Seaweed CR whose masters and filer the script
+	// administers.
 	// +kubebuilder:validation:Required
 	ClusterRef AdminScriptClusterRef `json:"clusterRef"`
 
@@ -192,6 +200,12 @@ const (
 	// AdminScriptConditionReady summarises whether the CronJob is reconciled
 	// and scheduling as configured.
 	AdminScriptConditionReady = "Ready"
+	// AdminScriptConditionReferenceGranted reports, for a cluster in another
+	// namespace, whether a ResourceReferenceGrant permits the reference: True
+	// (reason ReferenceGranted) while one does, False (reason
+	// ReferenceGrantMissing) while none does, in which case the CronJob is
+	// removed.
+	AdminScriptConditionReferenceGranted = "ReferenceGranted"
 )
 
 // AdminScriptStatus reflects the observed state of the AdminScript.
@@ -214,10 +228,15 @@ type AdminScriptStatus struct {
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 
 	// CronJobName is the name of the managed CronJob (equals the AdminScript
-	// name, in the same namespace).
+	// name when the cluster is in the same namespace).
 	// +optional
 	CronJobName string `json:"cronJobName,omitempty"`
 
+	// CronJobNamespace is the namespace of the managed CronJob: the
+	// cluster's namespace.
+	// +optional
+	CronJobNamespace string `json:"cronJobNamespace,omitempty"`
+
 	// Active is the number of currently running Jobs spawned by the CronJob.
 	// +optional
 	Active int32 `json:"active,omitempty"`
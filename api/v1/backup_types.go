@@ -16,6 +16,10 @@ limitations under the License.
 
 package v1
 
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
 // This file defines the Percona-style backup configuration carried on the
 // Seaweed CR (spec.backup). It is the shared vocabulary used by the
 // SeaweedBackup / SeaweedRestore CRDs and by the continuous data-mirror
@@ -30,6 +34,9 @@ package v1
 //   - Data: `weed filer.backup` continuously replicates file content into a
 //     replication sink (s3/gcs/azure/b2/local). It is a long-running daemon,
 //     so it is modeled as a Deployment (spec.backup.dataMirror), not a Job.
+//   - Metadata log: filer metadata change events are streamed continuously
+//     into time-ordered segments (spec.backup.metadataLog), so a restore can
+//     load the nearest snapshot and replay the log up to a point in time.
 
 // BackupStorageType enumerates the replication sink backends a storage maps to.
 // +kubebuilder:validation:Enum=s3;gcs;azure;b2;filesystem
@@ -195,11 +202,36 @@ type BackupStorageSpec struct {
 	// filesystem.
 	// +optional
 	CredentialsSecret *string `json:"credentialsSecret,omitempty"`
+
+	// Encryption, when set, encrypts the metadata snapshots written to this
+	// storage on the client side. Restores from the storage decrypt with the
+	// same key.
+	// +optional
+	Encryption *BackupEncryptionSpec `json:"encryption,omitempty"`
+}
+
+// BackupSecretKeyEncryptionKey is the key of an encryption Secret holding
+// the base64 encoding of a 32-byte key.
+const BackupSecretKeyEncryptionKey = "BACKUP_ENCRYPTION_KEY"
+
+// BackupEncryptionSpec configures AES-256-GCM envelope encryption of metadata
+// snapshots: each snapshot is sealed with a fresh data key, which the key
+// from KeySecret wraps. Changing the key leaves earlier snapshots readable
+// only with the key they were written with.
+type BackupEncryptionSpec struct {
+	// KeySecret names a Secret, in the cluster's namespace, holding the key
+	// under BACKUP_ENCRYPTION_KEY.
+	// +kubebuilder:validation:MinLength=1
+	KeySecret string `json:"keySecret"`
 }
 
 // BackupScheduleSpec drives recurring metadata snapshots. The operator's
 // internal scheduler creates a SeaweedBackup for the named storage each time
-// the cron fires, then prunes completed snapshots beyond Keep.
+// the cron fires, then prunes snapshots beyond Keep or Retention.
+// +kubebuilder:validation:XValidation:rule="!has(self.retention) || !has(self.keep) || self.keep == 0",message="keep and retention are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!has(self.collections) || (has(self.target) && self.target == 'volumes')",message="collections is only used by target volumes"
+// +kubebuilder:validation:XValidation:rule="!has(self.verification) || !has(self.target) || self.target == 'metadata'",message="verification drills restore metadata snapshots and cannot be combined with target volumes"
+// +kubebuilder:validation:XValidation:rule="!has(self.target) || self.target != 'volumeSnapshots'",message="schedules back up to a storage and cannot take target volumeSnapshots"
 type BackupScheduleSpec struct {
 	// Name identifies the schedule and prefixes the SeaweedBackups it creates.
 	// +kubebuilder:validation:MinLength=1
@@ -217,26 +249,114 @@ type BackupScheduleSpec struct {
 	StorageName string `json:"storageName"`
 
 	// Keep retains at most this many most-recent completed SeaweedBackups for
-	// this schedule; older ones are deleted. 0 keeps all.
+	// this schedule; older ones are deleted, leaving their artifacts on the
+	// storage. 0 keeps all.
 	// +optional
 	// +kubebuilder:validation:Minimum=0
 	Keep int32 `json:"keep,omitempty"`
 
+	// Retention selects the snapshots to keep by recency and calendar tiers.
+	// Backups it creates carry deletionPolicy Delete, so pruning one also
+	// removes its artifact from the storage.
+	// +optional
+	Retention *BackupRetentionPolicy `json:"retention,omitempty"`
+
+	// Target is copied into each SeaweedBackup this schedule creates.
+	// Defaults to metadata.
+	// +optional
+	// +kubebuilder:default:=metadata
+	Target BackupTarget `json:"target,omitempty"`
+
 	// FilerPath is the filer subtree to snapshot. Defaults to "/".
 	// +optional
 	// +kubebuilder:default:="/"
 	FilerPath string `json:"filerPath,omitempty"`
 
+	// Collections is copied into each SeaweedBackup this schedule creates.
+	// +optional
+	// +kubebuilder:validation:MaxItems=64
+	Collections []string `json:"collections,omitempty"`
+
 	// Suspend pauses this schedule without removing it.
 	// +optional
 	// +kubebuilder:default:=false
 	Suspend bool `json:"suspend,omitempty"`
+
+	// Verification periodically proves this schedule's backups restore, by
+	// running a BackupVerification drill against its latest completed
+	// backup.
+	// +optional
+	Verification *BackupScheduleVerification `json:"verification,omitempty"`
+
+	// PreHooks are copied into each SeaweedBackup this schedule creates.
+	// +optional
+	// +kubebuilder:validation:MaxItems=16
+	PreHooks []BackupHook `json:"preHooks,omitempty"`
+
+	// PostHooks are copied into each SeaweedBackup this schedule creates.
+	// +optional
+	// +kubebuilder:validation:MaxItems=16
+	// +kubebuilder:validation:XValidation:rule="self.all(h, !has(h.filerReadOnly))",message="filerReadOnly hooks are only allowed in preHooks"
+	PostHooks []BackupHook `json:"postHooks,omitempty"`
+}
+
+// BackupScheduleVerification schedules restore drills of a schedule's
+// backups. Each drill is a BackupVerification; the schedule keeps the most
+// recent few finished ones.
+type BackupScheduleVerification struct {
+	// Schedule is a standard cron expression, e.g. "0 6 * * 0".
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=120
+	Schedule string `json:"schedule"`
+
+	// SamplePaths are filer paths that must exist after the restore.
+	// +optional
+	// +kubebuilder:validation:MaxItems=32
+	SamplePaths []string `json:"samplePaths,omitempty"`
+
+	// MinEntries is the fewest entries the restored subtree must hold.
+	// Defaults to 1.
+	// +optional
+	// +kubebuilder:default:=1
+	// +kubebuilder:validation:Minimum=0
+	MinEntries int64 `json:"minEntries,omitempty"`
+
+	// Timeout bounds each drill. Defaults to 30m.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// BackupRetentionPolicy is a grandfather-father-son retention policy. A
+// terminal backup is kept when any tier selects it and pruned otherwise;
+// running backups are never pruned. All tiers zero keeps everything.
+type BackupRetentionPolicy struct {
+	// KeepLast keeps the most recent completed or failed backups.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	KeepLast int32 `json:"keepLast,omitempty"`
+
+	// KeepDaily keeps the newest completed backup of each of the most recent
+	// days (UTC) that have one.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	KeepDaily int32 `json:"keepDaily,omitempty"`
+
+	// KeepWeekly keeps the newest completed backup of each of the most recent
+	// ISO weeks that have one.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	KeepWeekly int32 `json:"keepWeekly,omitempty"`
+
+	// KeepMonthly keeps the newest completed backup of each of the most recent
+	// months (UTC) that have one.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	KeepMonthly int32 `json:"keepMonthly,omitempty"`
 }
 
 // BackupMirrorSpec declares a continuous `weed filer.backup` mirror Deployment
 // that streams file data into a storage sink. This is the data half of a
-// backup; for object-store storages it is also what carries metadata snapshots
-// (which the snapshot Job stages into a reserved filer path) off-cluster.
+// backup; metadata snapshots are written to the storage by SeaweedBackup.
 type BackupMirrorSpec struct {
 	// StorageName references a key in spec.backup.storages.
 	// +kubebuilder:validation:MinLength=1
@@ -247,6 +367,43 @@ type BackupMirrorSpec struct {
 	// +optional
 	// +kubebuilder:default:="/"
 	FilerPath string `json:"filerPath,omitempty"`
+
+	// Resync forces the mirror to walk the whole subtree again, copying
+	// every file to the sink, when set to a value it has not acted on yet.
+	// Without it the mirror walks the tree only when the sink has no
+	// checkpoint, and otherwise resumes from the checkpoint filer.backup
+	// persists on the filer.
+	// +optional
+	// +kubebuilder:validation:MaxLength=63
+	Resync string `json:"resync,omitempty"`
+}
+
+// BackupMetadataLogSpec declares a continuous metadata log: a Deployment that
+// subscribes to the filer's metadata change events, the way
+// `weed filer.meta.backup` does, and appends them to time-ordered segments on
+// a storage. SeaweedRestore.spec.pointInTime replays these segments on top of
+// a metadata snapshot.
+type BackupMetadataLogSpec struct {
+	// StorageName references a key in spec.backup.storages.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=50
+	StorageName string `json:"storageName"`
+
+	// FilerPath is the filer subtree whose changes are logged. Defaults to "/".
+	// +optional
+	// +kubebuilder:default:="/"
+	FilerPath string `json:"filerPath,omitempty"`
+
+	// SegmentMinutes is how long a log segment stays open before the next one
+	// starts. Segments on a filesystem storage are readable while open; on an
+	// object store a segment is uploaded to the bucket only once it is
+	// closed, so this bounds how much recent history a restore can miss.
+	// Defaults to 10.
+	// +optional
+	// +kubebuilder:default:=10
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=1440
+	SegmentMinutes int32 `json:"segmentMinutes,omitempty"`
 }
 
 // BackupSpec is the cluster-level backup configuration (Seaweed.spec.backup).
@@ -259,6 +416,7 @@ type BackupMirrorSpec struct {
 //
 // +kubebuilder:validation:XValidation:rule="!has(self.schedule) || self.schedule.all(s, s.storageName in self.storages)",message="schedule.storageName must reference a defined storage"
 // +kubebuilder:validation:XValidation:rule="!has(self.dataMirror) || self.dataMirror.all(m, m.storageName in self.storages)",message="dataMirror.storageName must reference a defined storage"
+// +kubebuilder:validation:XValidation:rule="!has(self.metadataLog) || self.metadataLog.all(l, l.storageName in self.storages)",message="metadataLog.storageName must reference a defined storage"
 type BackupSpec struct {
 	// Image optionally overrides the weed image used by backup/restore/mirror
 	// pods. Defaults to the cluster's spec.image.
@@ -283,16 +441,90 @@ type BackupSpec struct {
 	// +listMapKey=storageName
 	// +kubebuilder:validation:MaxItems=32
 	DataMirror []BackupMirrorSpec `json:"dataMirror,omitempty"`
+
+	// MetadataLog is a list of continuous metadata logs, at most one per
+	// storage, enabling point-in-time restores.
+	// +optional
+	// +listType=map
+	// +listMapKey=storageName
+	// +kubebuilder:validation:MaxItems=32
+	MetadataLog []BackupMetadataLogSpec `json:"metadataLog,omitempty"`
 }
 
-// BackupMirrorStatus reports the state of one continuous data mirror.
+// BackupMirrorStatus reports the state of one continuous data mirror or
+// metadata log.
 type BackupMirrorStatus struct {
-	// StorageName is the mirror's destination storage.
+	// StorageName is the mirror's or log's destination storage.
 	StorageName string `json:"storageName"`
-	// DeploymentName is the managed `weed filer.backup` Deployment.
+	// DeploymentName is the managed mirror or log Deployment.
 	// +optional
 	DeploymentName string `json:"deploymentName,omitempty"`
-	// Ready reports whether the mirror Deployment has an available replica.
+	// Ready reports whether the Deployment has an available replica.
 	// +optional
 	Ready bool `json:"ready,omitempty"`
+
+	// LastSyncedTime is the filer event time up to which a data mirror has
+	// copied changes to the sink, read from the checkpoint filer.backup
+	// persists on the filer.
+	// +optional
+	LastSyncedTime *metav1.Time `json:"lastSyncedTime,omitempty"`
+
+	// LagSeconds is the age of LastSyncedTime when last observed.
+	// +optional
+	LagSeconds *int64 `json:"lagSeconds,omitempty"`
+
+	// ErrorCount is the number of error lines filer.backup has logged since
+	// the mirror container last started, summed over its pods. It is read
+	// from the pods' logs on every reconcile.
+	// +optional
+	ErrorCount int32 `json:"errorCount,omitempty"`
+
+	// Restarts is the number of times the mirror container has restarted,
+	// summed over its pods.
+	// +optional
+	Restarts int32 `json:"restarts,omitempty"`
+
+	// InitialSnapshot reports whether the mirror is walking the whole subtree
+	// (filer.backup -initialSnapshot), because the sink has no checkpoint yet
+	// or a resync was requested.
+	// +optional
+	InitialSnapshot bool `json:"initialSnapshot,omitempty"`
+
+	// Resync is the spec.backup.dataMirror resync value last acted on.
+	// +optional
+	Resync string `json:"resync,omitempty"`
+
+	// ResyncStartTime is when the resync in Resync started; it is cleared
+	// once the checkpoint moves past it.
+	// +optional
+	ResyncStartTime *metav1.Time `json:"resyncStartTime,omitempty"`
+}
+
+// BackupStorageConditionReachable reports whether the last probe of a
+// storage wrote, read back and deleted a marker object in it.
+const BackupStorageConditionReachable = "Reachable"
+
+// BackupStorageStatus reports the probe of one spec.backup.storages entry.
+type BackupStorageStatus struct {
+	// Name is the storage's key in spec.backup.storages.
+	Name string `json:"name"`
+
+	// ProbedRevision digests the storage's settings and credentials the
+	// Reachable condition was observed with; a change starts a new probe.
+	// +optional
+	ProbedRevision string `json:"probedRevision,omitempty"`
+
+	// JobName is the storage's probe Job.
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+
+	// LastProbeTime is when the last probe finished.
+	// +optional
+	LastProbeTime *metav1.Time `json:"lastProbeTime,omitempty"`
+
+	// Conditions holds the storage's Reachable condition.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
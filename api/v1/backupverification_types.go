@@ -0,0 +1,190 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VerificationPhase summarises a BackupVerification's lifecycle.
+// +kubebuilder:validation:Enum=Pending;Running;Passed;Failed
+type VerificationPhase string
+
+const (
+	// VerificationPhasePending means the drill has not started yet (waiting
+	// on the backup to complete, or on the operator's backup-tool image).
+	VerificationPhasePending VerificationPhase = "Pending"
+	// VerificationPhaseRunning means the scratch cluster is being brought up,
+	// restored into, or checked.
+	VerificationPhaseRunning VerificationPhase = "Running"
+	// VerificationPhasePassed means the backup restored and its metadata
+	// passed the check.
+	VerificationPhasePassed VerificationPhase = "Passed"
+	// VerificationPhaseFailed means the restore or the check failed, or the
+	// drill timed out.
+	VerificationPhaseFailed VerificationPhase = "Failed"
+)
+
+// Condition types emitted by the BackupVerification controller.
+const (
+	// VerificationConditionBackupResolved reports whether the backup to
+	// verify, the cluster it was taken of, and its storage were resolvable.
+	VerificationConditionBackupResolved = "BackupResolved"
+	// VerificationConditionScratchReady is True once the scratch cluster's
+	// master and filer are ready.
+	VerificationConditionScratchReady = "ScratchReady"
+	// VerificationConditionRestored is True once the backup has been restored
+	// into the scratch cluster.
+	VerificationConditionRestored = "Restored"
+	// VerificationConditionChecked is True once the restored metadata passed
+	// the check, False with the reason it did not.
+	VerificationConditionChecked = "Checked"
+	// VerificationConditionCleanedUp is True once the scratch cluster and
+	// restore have been deleted.
+	VerificationConditionCleanedUp = "CleanedUp"
+)
+
+// LabelBackupVerification is set on the scratch cluster, restore and check
+// Job of a BackupVerification, naming it.
+const LabelBackupVerification = "seaweed.seaweedfs.com/backup-verification"
+
+// BackupVerificationSpec is a restore drill: the backup is restored into a
+// minimal scratch Seaweed cluster created for the purpose, the restored
+// filer's metadata is checked, and the scratch cluster is torn down.
+type BackupVerificationSpec struct {
+	// ClusterName is the Seaweed CR whose backup is verified. Its backup
+	// storages are copied into the scratch cluster so the snapshot can be
+	// read. Immutable once set.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="clusterName is immutable"
+	ClusterName string `json:"clusterName"`
+
+	// BackupName is the SeaweedBackup, in this namespace, to verify. Defaults
+	// to the latest Completed backup of the cluster when the drill starts.
+	// Immutable once set.
+	// +optional
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="backupName is immutable"
+	BackupName string `json:"backupName,omitempty"`
+
+	// SamplePaths are filer paths that must exist after the restore. Without
+	// any, a few paths found while counting entries are looked up.
+	// +optional
+	// +kubebuilder:validation:MaxItems=32
+	SamplePaths []string `json:"samplePaths,omitempty"`
+
+	// MinEntries is the fewest entries the restored subtree must hold.
+	// Defaults to 1, so that an empty restore fails.
+	// +optional
+	// +kubebuilder:default:=1
+	// +kubebuilder:validation:Minimum=0
+	MinEntries int64 `json:"minEntries,omitempty"`
+
+	// Timeout bounds the whole drill; a drill still running after it fails
+	// and is torn down. Defaults to 30m.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// BackupVerificationStatus reflects the observed state of a restore drill.
+type BackupVerificationStatus struct {
+	// ObservedGeneration is the .metadata.generation last reconciled.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Phase is a coarse summary of the drill's lifecycle.
+	// +optional
+	Phase VerificationPhase `json:"phase,omitempty"`
+
+	// BackupName is the SeaweedBackup being verified.
+	// +optional
+	BackupName string `json:"backupName,omitempty"`
+
+	// ScratchCluster is the Seaweed CR the backup is restored into.
+	// +optional
+	ScratchCluster string `json:"scratchCluster,omitempty"`
+
+	// RestoreName is the SeaweedRestore loading the backup into the scratch
+	// cluster.
+	// +optional
+	RestoreName string `json:"restoreName,omitempty"`
+
+	// JobName is the Job checking the restored metadata.
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+
+	// Entries is the number of filer entries the restore produced.
+	// +optional
+	Entries int64 `json:"entries,omitempty"`
+
+	// StartTime is when the drill started.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the drill passed or failed.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Duration is how long the drill took, from StartTime to CompletionTime.
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// Message says why the drill failed, or summarises the check.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Conditions are the structured per-aspect state signals.
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=swbv,categories=seaweedfs
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.clusterName`
+// +kubebuilder:printcolumn:name="Backup",type=string,JSONPath=`.status.backupName`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Entries",type=integer,JSONPath=`.status.entries`
+// +kubebuilder:printcolumn:name="Duration",type=string,JSONPath=`.status.duration`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// BackupVerification proves a SeaweedBackup restores: it restores the backup
+// into a scratch Seaweed cluster, checks the restored metadata, records the
+// result on the backup, and tears the scratch cluster down.
+type BackupVerification struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BackupVerificationSpec   `json:"spec,omitempty"`
+	Status BackupVerificationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// BackupVerificationList contains a list of BackupVerification.
+type BackupVerificationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BackupVerification `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BackupVerification{}, &BackupVerificationList{})
+}
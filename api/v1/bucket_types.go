@@ -176,6 +176,60 @@ type BucketPlacement struct {
 	// +optional
 	// +kubebuilder:validation:Minimum=0
 	VolumeGrowthCount *int32 `json:"volumeGrowthCount,omitempty"`
+
+	// ErasureCoding overrides the cluster's spec.erasureCoding for this
+	// bucket's collection. Not an fs.configure option: the cluster's
+	// controller reads it when it looks for volumes to encode.
+	// +optional
+	ErasureCoding *BucketErasureCoding `json:"erasureCoding,omitempty"`
+}
+
+// ObjectLockRetentionMode is an S3 Object Lock retention mode.
+// +kubebuilder:validation:Enum=GOVERNANCE;COMPLIANCE
+type ObjectLockRetentionMode string
+
+const (
+	// ObjectLockGovernance retention can be lifted or shortened by
+	// identities allowed to bypass governance retention.
+	ObjectLockGovernance ObjectLockRetentionMode = "GOVERNANCE"
+	// ObjectLockCompliance retention cannot be lifted or shortened by
+	// anyone until it expires.
+	ObjectLockCompliance ObjectLockRetentionMode = "COMPLIANCE"
+)
+
+// BucketObjectLockDefaultRetention is the retention applied to every new
+// object version that is uploaded without its own. Exactly one of days or
+// years must be set.
+// +kubebuilder:validation:XValidation:rule="has(self.days) != has(self.years)",message="exactly one of days or years must be set"
+type BucketObjectLockDefaultRetention struct {
+	// Mode is the retention mode applied to new objects.
+	// +kubebuilder:validation:Required
+	Mode ObjectLockRetentionMode `json:"mode"`
+
+	// Days is the retention period in days.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=36500
+	Days *int32 `json:"days,omitempty"`
+
+	// Years is the retention period in years. A year counts as 365 days
+	// when compared against a period given in days.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	Years *int32 `json:"years,omitempty"`
+}
+
+// BucketObjectLockConfiguration is the bucket's Object Lock configuration.
+type BucketObjectLockConfiguration struct {
+	// DefaultRetention is applied to new objects that carry no retention of
+	// their own. Objects already stored keep their retention. Under
+	// COMPLIANCE the period can only be lengthened: admission rejects
+	// removing it, switching it to GOVERNANCE or shortening it, and the
+	// controller refuses to weaken a COMPLIANCE default it finds on the
+	// bucket (ObjectLockRetentionRejected).
+	// +optional
+	DefaultRetention *BucketObjectLockDefaultRetention `json:"defaultRetention,omitempty"`
 }
 
 // BucketSpec defines the desired state of a SeaweedFS bucket.
@@ -183,9 +237,12 @@ type BucketPlacement struct {
 // Cross-field invariants are enforced via CEL `x-kubernetes-validations`
 // so they fail fast at admission. Object Lock immutability and the
 // "cannot return versioning to Off" rule are implemented as transition
-// rules on the individual fields.
+// rules on the individual fields; the COMPLIANCE retention guard spans
+// optional fields that may be absent on either side, so it lives here.
 //
 // +kubebuilder:validation:XValidation:rule="!self.objectLock || self.versioning == 'Enabled'",message="objectLock requires versioning: Enabled"
+// +kubebuilder:validation:XValidation:rule="!has(self.objectLockConfiguration) || self.objectLock",message="objectLockConfiguration requires objectLock: true"
+// +kubebuilder:validation:XValidation:rule="!has(oldSelf.objectLockConfiguration) || !has(oldSelf.objectLockConfiguration.defaultRetention) || oldSelf.objectLockConfiguration.defaultRetention.mode != 'COMPLIANCE' || (has(self.objectLockConfiguration) && has(self.objectLockConfiguration.defaultRetention) && self.objectLockConfiguration.defaultRetention.mode == 'COMPLIANCE' && (has(self.objectLockConfiguration.defaultRetention.days) ? self.objectLockConfiguration.defaultRetention.days : self.objectLockConfiguration.defaultRetention.years * 365) >= (has(oldSelf.objectLockConfiguration.defaultRetention.days) ? oldSelf.objectLockConfiguration.defaultRetention.days : oldSelf.objectLockConfiguration.defaultRetention.years * 365))",message="a COMPLIANCE default retention cannot be removed, switched to GOVERNANCE or shortened"
 type BucketSpec struct {
 	// Name is the S3 bucket name. Defaults to .metadata.name. Must satisfy
 	// the S3 bucket naming rules: 3-63 characters, lowercase alphanumeric,
@@ -247,6 +304,12 @@ type BucketSpec struct {
 	// +kubebuilder:validation:XValidation:rule="!oldSelf || self",message="objectLock cannot be disabled once enabled"
 	ObjectLock bool `json:"objectLock,omitempty"`
 
+	// ObjectLockConfiguration is the bucket's Object Lock configuration,
+	// the same settings `PutObjectLockConfiguration` accepts. Requires
+	// objectLock: true. Omit to leave the configuration unmanaged.
+	// +optional
+	ObjectLockConfiguration *BucketObjectLockConfiguration `json:"objectLockConfiguration,omitempty"`
+
 	// Quota optionally caps the bucket's total stored size. Omit the
 	// block entirely to leave quota unmanaged.
 	// +optional
@@ -287,8 +350,83 @@ type BucketSpec struct {
 	// +optional
 	// +kubebuilder:default:=false
 	AnonymousRead bool `json:"anonymousRead,omitempty"`
+
+	// RateLimits sets this bucket's S3 circuit breaker: per-action limits on
+	// concurrent requests and in-flight bytes, applied through
+	// `s3.circuitBreaker -buckets <name>`. They stack with the cluster-wide
+	// limits in the Seaweed's spec.s3.circuitBreaker. Removing the block
+	// deletes the bucket's entry.
+	// +optional
+	RateLimits *S3CircuitBreakerSpec `json:"rateLimits,omitempty"`
+
+	// CORS is the bucket's cross-origin resource sharing configuration,
+	// the same rules `PutBucketCors` accepts. Omit to leave CORS unmanaged;
+	// removing a list the controller applied clears it.
+	// +optional
+	// +kubebuilder:validation:MaxItems=100
+	CORS []BucketCORSRule `json:"cors,omitempty"`
+
+	// Policy is the bucket policy, a raw AWS-style JSON document as
+	// `PutBucketPolicy` accepts. Every statement's resources must name this
+	// bucket. Omit to leave the policy unmanaged; removing a policy the
+	// controller applied deletes it.
+	// +optional
+	Policy string `json:"policy,omitempty"`
+
+	// Tags are the bucket's tags (`PutBucketTagging`). Keys are 1-128
+	// characters and may not start with "aws:"; values are at most 256.
+	// The controller reconciles to exactly this set. Omit to leave tags
+	// unmanaged; removing tags the controller applied clears them.
+	// +optional
+	// +kubebuilder:validation:MaxProperties=50
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// BucketCORSMethod is an HTTP method a CORS rule allows.
+// +kubebuilder:validation:Enum=GET;PUT;POST;DELETE;HEAD
+type BucketCORSMethod string
+
+// BucketCORSRule is one rule of a bucket's CORS configuration.
+type BucketCORSRule struct {
+	// ID optionally names the rule.
+	// +optional
+	// +kubebuilder:validation:MaxLength=255
+	ID string `json:"id,omitempty"`
+
+	// AllowedOrigins are the origins allowed to make cross-origin requests.
+	// Each may contain at most one "*" wildcard.
+	// +kubebuilder:validation:MinItems=1
+	AllowedOrigins []string `json:"allowedOrigins"`
+
+	// AllowedMethods are the HTTP methods allowed from those origins.
+	// +kubebuilder:validation:MinItems=1
+	AllowedMethods []BucketCORSMethod `json:"allowedMethods"`
+
+	// AllowedHeaders are the headers a preflight request may ask for. Each
+	// may contain at most one "*" wildcard.
+	// +optional
+	AllowedHeaders []string `json:"allowedHeaders,omitempty"`
+
+	// ExposeHeaders are the response headers browsers may expose to the
+	// calling script.
+	// +optional
+	ExposeHeaders []string `json:"exposeHeaders,omitempty"`
+
+	// MaxAgeSeconds is how long browsers may cache the preflight response.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxAgeSeconds *int32 `json:"maxAgeSeconds,omitempty"`
 }
 
+// BucketConfigKind names a bucket sub-resource stored on the filer entry.
+type BucketConfigKind string
+
+const (
+	BucketConfigCORS   BucketConfigKind = "cors"
+	BucketConfigPolicy BucketConfigKind = "policy"
+	BucketConfigTags   BucketConfigKind = "tags"
+)
+
 // BucketUsage captures coarse usage stats refreshed periodically by the
 // controller. Populated only when usage stats are enabled on the operator.
 type BucketUsage struct {
@@ -317,6 +455,19 @@ type BucketStatusQuota struct {
 	Enforced bool `json:"enforced,omitempty"`
 }
 
+// BucketStatusRateLimits mirrors the circuit breaker scope applied to the
+// bucket on the filer.
+type BucketStatusRateLimits struct {
+	// Enabled reports whether the limits are enforced.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Limits maps "<action>:<Count|MB>" — the keys SeaweedFS stores — to the
+	// applied limit: concurrent requests for Count, in-flight MiB for MB.
+	// +optional
+	Limits map[string]int64 `json:"limits,omitempty"`
+}
+
 // BucketPhase summarises the bucket's lifecycle.
 // +kubebuilder:validation:Enum=Pending;Ready;Failed;Terminating
 type BucketPhase string
@@ -356,6 +507,19 @@ const (
 	// when a cross-namespace clusterRef lacks a permitting ResourceReferenceGrant;
 	// cleared once granted.
 	BucketConditionClusterRefForbidden = "ClusterRefForbidden"
+	// BucketConditionCORSRejected is set True when spec.cors fails the S3
+	// CORS rules and was not applied; cleared once it passes.
+	BucketConditionCORSRejected = "CORSRejected"
+	// BucketConditionPolicyRejected is set True when spec.policy is not a
+	// valid bucket policy for this bucket and was not applied.
+	BucketConditionPolicyRejected = "PolicyRejected"
+	// BucketConditionTagsRejected is set True when spec.tags break the S3
+	// tagging rules and were not applied.
+	BucketConditionTagsRejected = "TagsRejected"
+	// BucketConditionObjectLockRetentionRejected is set True when
+	// spec.objectLockConfiguration would weaken the COMPLIANCE default
+	// retention stored on the bucket, which is then left unchanged.
+	BucketConditionObjectLockRetentionRejected = "ObjectLockRetentionRejected"
 )
 
 // BucketStatus reflects the observed state of the bucket.
@@ -391,6 +555,12 @@ type BucketStatus struct {
 	// +optional
 	ObjectLockEnabled bool `json:"objectLockEnabled,omitempty"`
 
+	// ObjectLockDefaultRetention is the default retention the controller
+	// applied from spec.objectLockConfiguration. Only a retention recorded
+	// here is cleared when removed from spec.
+	// +optional
+	ObjectLockDefaultRetention *BucketObjectLockDefaultRetention `json:"objectLockDefaultRetention,omitempty"`
+
 	// Quota is the observed quota on the filer.
 	// +optional
 	Quota *BucketStatusQuota `json:"quota,omitempty"`
@@ -399,6 +569,33 @@ type BucketStatus struct {
 	// +optional
 	OwnerIdentity string `json:"ownerIdentity,omitempty"`
 
+	// RateLimits is the circuit breaker applied to the bucket; unset while
+	// spec.rateLimits is.
+	// +optional
+	RateLimits *BucketStatusRateLimits `json:"rateLimits,omitempty"`
+
+	// RetentionExpiry is the earliest Object Lock retain-until date the last
+	// scan found while retention blocks deleting the bucket. The bucket is
+	// scanned again once it passes.
+	// +optional
+	RetentionExpiry *metav1.Time `json:"retentionExpiry,omitempty"`
+
+	// ManagedConfig lists the bucket sub-resources (cors, policy, tags) the
+	// controller has applied from spec. Only these are cleared when removed
+	// from spec; configuration set through the S3 API on the others is left
+	// alone.
+	// +optional
+	// +listType=set
+	ManagedConfig []BucketConfigKind `json:"managedConfig,omitempty"`
+
+	// Replications summarises the BucketReplications this bucket is the
+	// source or destination of. Maintained by the BucketReplication
+	// controller.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Replications []BucketReplicationSummary `json:"replications,omitempty"`
+
 	// Usage is the latest usage snapshot. Refreshed on a separate cadence
 	// from spec reconciliation; may be unset when usage stats are disabled.
 	// +optional
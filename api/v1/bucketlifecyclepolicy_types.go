@@ -38,14 +38,60 @@ type BucketLifecycleRef struct {
 	Name string `json:"name"`
 }
 
+// BucketLifecycleTag is an object tag a rule filter matches.
+type BucketLifecycleTag struct {
+	// Key is the tag key.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=128
+	Key string `json:"key"`
+
+	// Value is the tag value the object must carry.
+	// +kubebuilder:validation:MaxLength=256
+	Value string `json:"value"`
+}
+
+// BucketLifecycleFilter narrows a rule to the objects matching every condition
+// set. A single condition is rendered as the S3 Filter's own child; several
+// are wrapped in an And element.
+// +kubebuilder:validation:XValidation:rule="!has(self.objectSizeGreaterThan) || !has(self.objectSizeLessThan) || self.objectSizeGreaterThan < self.objectSizeLessThan",message="objectSizeGreaterThan must be less than objectSizeLessThan"
+type BucketLifecycleFilter struct {
+	// Prefix limits the rule to object keys under this prefix.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Tags limits the rule to objects carrying all of these tags.
+	// +optional
+	// +kubebuilder:validation:MaxItems=10
+	// +listType=map
+	// +listMapKey=key
+	Tags []BucketLifecycleTag `json:"tags,omitempty"`
+
+	// ObjectSizeGreaterThan limits the rule to objects larger than this many
+	// bytes.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	ObjectSizeGreaterThan *int64 `json:"objectSizeGreaterThan,omitempty"`
+
+	// ObjectSizeLessThan limits the rule to objects smaller than this many
+	// bytes.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	ObjectSizeLessThan *int64 `json:"objectSizeLessThan,omitempty"`
+}
+
 // BucketLifecycleExpiration expires current object versions.
-// +kubebuilder:validation:XValidation:rule="has(self.days) || (has(self.expiredObjectDeleteMarker) && self.expiredObjectDeleteMarker)",message="expiration must set days or expiredObjectDeleteMarker"
+// +kubebuilder:validation:XValidation:rule="has(self.days) || has(self.date) || (has(self.expiredObjectDeleteMarker) && self.expiredObjectDeleteMarker)",message="expiration must set days, date or expiredObjectDeleteMarker"
+// +kubebuilder:validation:XValidation:rule="!has(self.days) || !has(self.date)",message="expiration must not set both days and date"
 type BucketLifecycleExpiration struct {
 	// Days is the object age in days after which it is expired.
 	// +optional
 	// +kubebuilder:validation:Minimum=1
 	Days int32 `json:"days,omitempty"`
 
+	// Date expires matching objects on this date. It must be midnight UTC.
+	// +optional
+	Date *metav1.Time `json:"date,omitempty"`
+
 	// ExpiredObjectDeleteMarker removes a version's delete marker once it is
 	// the only version left. Applies to versioned buckets only.
 	// +optional
@@ -68,6 +114,50 @@ type BucketLifecycleNoncurrentVersionExpiration struct {
 	NewerNoncurrentVersions int32 `json:"newerNoncurrentVersions,omitempty"`
 }
 
+// BucketLifecycleTransition moves current object versions to another storage
+// class.
+// +kubebuilder:validation:XValidation:rule="has(self.days) != has(self.date)",message="transition must set exactly one of days or date"
+type BucketLifecycleTransition struct {
+	// Days is the object age in days after which it is moved. Zero moves
+	// objects as soon as the lifecycle worker sees them.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Days *int32 `json:"days,omitempty"`
+
+	// Date moves matching objects on this date. It must be midnight UTC.
+	// +optional
+	Date *metav1.Time `json:"date,omitempty"`
+
+	// StorageClass is the target: a storage class configured on the S3
+	// gateway, or a SeaweedFS disk type such as ssd or hdd.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=64
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_-]+$`
+	StorageClass string `json:"storageClass"`
+}
+
+// BucketLifecycleNoncurrentVersionTransition moves non-current versions of
+// objects in a versioned bucket to another storage class.
+type BucketLifecycleNoncurrentVersionTransition struct {
+	// NoncurrentDays is the number of days a version stays non-current before
+	// it is moved.
+	// +kubebuilder:validation:Minimum=0
+	NoncurrentDays int32 `json:"noncurrentDays"`
+
+	// NewerNoncurrentVersions keeps this many newer non-current versions in
+	// place before moving the rest.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	NewerNoncurrentVersions int32 `json:"newerNoncurrentVersions,omitempty"`
+
+	// StorageClass is the target: a storage class configured on the S3
+	// gateway, or a SeaweedFS disk type such as ssd or hdd.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=64
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_-]+$`
+	StorageClass string `json:"storageClass"`
+}
+
 // BucketLifecycleAbortIncompleteMultipartUpload aborts multipart uploads that
 // were never completed.
 type BucketLifecycleAbortIncompleteMultipartUpload struct {
@@ -78,8 +168,11 @@ type BucketLifecycleAbortIncompleteMultipartUpload struct {
 }
 
 // BucketLifecycleRule is one S3 lifecycle rule. Each rule must carry at least
-// one action.
-// +kubebuilder:validation:XValidation:rule="has(self.expiration) || has(self.abortIncompleteMultipartUpload) || has(self.noncurrentVersionExpiration)",message="rule must set at least one of expiration, abortIncompleteMultipartUpload, or noncurrentVersionExpiration"
+// one action. Combinations S3 rejects across fields (mixing date- and
+// day-based actions, transitions after expiration, tag filters with
+// abortIncompleteMultipartUpload, ...) are checked by the validating webhook.
+// +kubebuilder:validation:XValidation:rule="has(self.expiration) || has(self.abortIncompleteMultipartUpload) || has(self.noncurrentVersionExpiration) || has(self.transitions) || has(self.noncurrentVersionTransitions)",message="rule must set at least one of expiration, abortIncompleteMultipartUpload, noncurrentVersionExpiration, transitions, or noncurrentVersionTransitions"
+// +kubebuilder:validation:XValidation:rule="!has(self.filter) || !has(self.prefix) || self.prefix.size() == 0",message="set prefix on the rule or in filter, not both"
 type BucketLifecycleRule struct {
 	// ID uniquely identifies the rule within the policy.
 	// +kubebuilder:validation:MinLength=1
@@ -87,10 +180,15 @@ type BucketLifecycleRule struct {
 	ID string `json:"id"`
 
 	// Prefix limits the rule to object keys under this prefix. Empty matches
-	// every object in the bucket.
+	// every object in the bucket. Use filter to also match on tags or size.
 	// +optional
 	Prefix string `json:"prefix,omitempty"`
 
+	// Filter limits the rule by prefix, tags and object size. Mutually
+	// exclusive with prefix.
+	// +optional
+	Filter *BucketLifecycleFilter `json:"filter,omitempty"`
+
 	// Status enables or disables the rule. Defaults to Enabled.
 	// +optional
 	// +kubebuilder:default:=Enabled
@@ -108,6 +206,17 @@ type BucketLifecycleRule struct {
 	// AbortIncompleteMultipartUpload aborts stale multipart uploads.
 	// +optional
 	AbortIncompleteMultipartUpload *BucketLifecycleAbortIncompleteMultipartUpload `json:"abortIncompleteMultipartUpload,omitempty"`
+
+	// Transitions move current object versions to other storage classes.
+	// +optional
+	// +kubebuilder:validation:MaxItems=8
+	Transitions []BucketLifecycleTransition `json:"transitions,omitempty"`
+
+	// NoncurrentVersionTransitions move non-current versions in a versioned
+	// bucket to other storage classes.
+	// +optional
+	// +kubebuilder:validation:MaxItems=8
+	NoncurrentVersionTransitions []BucketLifecycleNoncurrentVersionTransition `json:"noncurrentVersionTransitions,omitempty"`
 }
 
 // BucketLifecyclePolicySpec defines the desired lifecycle configuration of a
@@ -0,0 +1,161 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var bucketlifecyclepolicylog = logf.Log.WithName("bucketlifecyclepolicy-resource")
+
+func (r *BucketLifecyclePolicy) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, &BucketLifecyclePolicy{}).
+		WithValidator(&BucketLifecyclePolicyCustomValidator{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-seaweed-seaweedfs-com-v1-bucketlifecyclepolicy,mutating=false,failurePolicy=fail,sideEffects=None,groups=seaweed.seaweedfs.com,resources=bucketlifecyclepolicies,versions=v1,name=vbucketlifecyclepolicy.kb.io,admissionReviewVersions=v1
+
+// BucketLifecyclePolicyCustomValidator validates BucketLifecyclePolicy resources.
+// +kubebuilder:object:generate=false
+type BucketLifecyclePolicyCustomValidator struct{}
+
+var _ admission.Validator[*BucketLifecyclePolicy] = &BucketLifecyclePolicyCustomValidator{}
+
+// ValidateCreate implements admission.Validator so a webhook will be registered for the type
+func (v *BucketLifecyclePolicyCustomValidator) ValidateCreate(_ context.Context, obj *BucketLifecyclePolicy) (admission.Warnings, error) {
+	bucketlifecyclepolicylog.Info("validate create", "name", obj.Name)
+	return nil, ValidateBucketLifecycleRules(obj.Spec.Rules)
+}
+
+// ValidateUpdate implements admission.Validator so a webhook will be registered for the type
+func (v *BucketLifecyclePolicyCustomValidator) ValidateUpdate(_ context.Context, _, obj *BucketLifecyclePolicy) (admission.Warnings, error) {
+	bucketlifecyclepolicylog.Info("validate update", "name", obj.Name)
+	return nil, ValidateBucketLifecycleRules(obj.Spec.Rules)
+}
+
+// ValidateDelete implements admission.Validator so a webhook will be registered for the type
+func (v *BucketLifecyclePolicyCustomValidator) ValidateDelete(_ context.Context, _ *BucketLifecyclePolicy) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateBucketLifecycleRules checks the rule combinations S3 rejects that
+// the OpenAPI schema cannot express. The controller runs it too, so a policy
+// admitted while the webhook was disabled is still refused before it is
+// applied.
+func ValidateBucketLifecycleRules(rules []BucketLifecycleRule) error {
+	errs := []error{}
+	for i := range rules {
+		for _, err := range validateBucketLifecycleRule(&rules[i]) {
+			errs = append(errs, fmt.Errorf("spec.rules[%d] (%s): %w", i, rules[i].ID, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func validateBucketLifecycleRule(r *BucketLifecycleRule) []error {
+	errs := []error{}
+	hasTags := r.Filter != nil && len(r.Filter.Tags) > 0
+
+	if r.AbortIncompleteMultipartUpload != nil && hasTags {
+		errs = append(errs, errors.New("abortIncompleteMultipartUpload cannot be combined with a tag filter"))
+	}
+	if exp := r.Expiration; exp != nil {
+		if exp.ExpiredObjectDeleteMarker && hasTags {
+			errs = append(errs, errors.New("expiration.expiredObjectDeleteMarker cannot be combined with a tag filter"))
+		}
+		if exp.Date != nil && !isMidnightUTC(exp.Date) {
+			errs = append(errs, errors.New("expiration.date must be midnight UTC"))
+		}
+	}
+
+	// Transitions must all be day-based or all date-based, matching the
+	// expiration; each storage class is targeted once, at distinct times,
+	// and all of them before the objects expire.
+	byDate, byDays := false, false
+	classes := map[string]bool{}
+	days := map[int32]bool{}
+	dates := map[int64]bool{}
+	for j, t := range r.Transitions {
+		if classes[t.StorageClass] {
+			errs = append(errs, fmt.Errorf("transitions[%d]: storage class %q is targeted more than once", j, t.StorageClass))
+		}
+		classes[t.StorageClass] = true
+		switch {
+		case t.Date != nil:
+			byDate = true
+			if !isMidnightUTC(t.Date) {
+				errs = append(errs, fmt.Errorf("transitions[%d].date must be midnight UTC", j))
+			}
+			if dates[t.Date.Unix()] {
+				errs = append(errs, fmt.Errorf("transitions[%d]: another transition already runs on %s", j, t.Date.UTC().Format("2006-01-02")))
+			}
+			dates[t.Date.Unix()] = true
+			if exp := r.Expiration; exp != nil && exp.Date != nil && !exp.Date.After(t.Date.Time) {
+				errs = append(errs, fmt.Errorf("transitions[%d].date must be before expiration.date", j))
+			}
+		case t.Days != nil:
+			byDays = true
+			if days[*t.Days] {
+				errs = append(errs, fmt.Errorf("transitions[%d]: another transition already runs after %d days", j, *t.Days))
+			}
+			days[*t.Days] = true
+			if exp := r.Expiration; exp != nil && exp.Days > 0 && exp.Days <= *t.Days {
+				errs = append(errs, fmt.Errorf("transitions[%d].days must be less than expiration.days", j))
+			}
+		}
+	}
+	if exp := r.Expiration; exp != nil {
+		byDate = byDate || exp.Date != nil
+		byDays = byDays || exp.Days > 0
+	}
+	if byDate && byDays {
+		errs = append(errs, errors.New("expiration and transitions must all use days or all use date, not a mix"))
+	}
+
+	classes = map[string]bool{}
+	days = map[int32]bool{}
+	for j, t := range r.NoncurrentVersionTransitions {
+		if classes[t.StorageClass] {
+			errs = append(errs, fmt.Errorf("noncurrentVersionTransitions[%d]: storage class %q is targeted more than once", j, t.StorageClass))
+		}
+		classes[t.StorageClass] = true
+		if days[t.NoncurrentDays] {
+			errs = append(errs, fmt.Errorf("noncurrentVersionTransitions[%d]: another transition already runs after %d noncurrent days", j, t.NoncurrentDays))
+		}
+		days[t.NoncurrentDays] = true
+		if exp := r.NoncurrentVersionExpiration; exp != nil && exp.NoncurrentDays <= t.NoncurrentDays {
+			errs = append(errs, fmt.Errorf("noncurrentVersionTransitions[%d].noncurrentDays must be less than noncurrentVersionExpiration.noncurrentDays", j))
+		}
+	}
+	return errs
+}
+
+// isMidnightUTC reports whether t falls exactly on a UTC day boundary, the
+// only dates S3 lifecycle rules accept.
+func isMidnightUTC(t *metav1.Time) bool {
+	u := t.UTC()
+	return u.Hour() == 0 && u.Minute() == 0 && u.Second() == 0 && u.Nanosecond() == 0
+}
@@ -0,0 +1,106 @@
+package v1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestValidateBucketLifecycleRules(t *testing.T) {
+	midnight := metav1.NewTime(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	later := metav1.NewTime(time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC))
+	noon := metav1.NewTime(time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC))
+	tags := &BucketLifecycleFilter{Tags: []BucketLifecycleTag{{Key: "k", Value: "v"}}}
+
+	cases := []struct {
+		name    string
+		rule    BucketLifecycleRule
+		wantErr string
+	}{
+		{
+			name: "day-based transitions before expiration",
+			rule: BucketLifecycleRule{
+				Transitions: []BucketLifecycleTransition{
+					{Days: ptr.To[int32](0), StorageClass: "ssd"},
+					{Days: ptr.To[int32](30), StorageClass: "hdd"},
+				},
+				Expiration: &BucketLifecycleExpiration{Days: 90},
+			},
+		},
+		{
+			name: "date-based transition before expiration",
+			rule: BucketLifecycleRule{
+				Transitions: []BucketLifecycleTransition{{Date: &midnight, StorageClass: "hdd"}},
+				Expiration:  &BucketLifecycleExpiration{Date: &later},
+			},
+		},
+		{
+			name:    "date not at midnight",
+			rule:    BucketLifecycleRule{Expiration: &BucketLifecycleExpiration{Date: &noon}},
+			wantErr: "midnight UTC",
+		},
+		{
+			name: "mixed days and dates",
+			rule: BucketLifecycleRule{
+				Transitions: []BucketLifecycleTransition{{Date: &midnight, StorageClass: "hdd"}},
+				Expiration:  &BucketLifecycleExpiration{Days: 90},
+			},
+			wantErr: "not a mix",
+		},
+		{
+			name: "transition after expiration",
+			rule: BucketLifecycleRule{
+				Transitions: []BucketLifecycleTransition{{Days: ptr.To[int32](90), StorageClass: "hdd"}},
+				Expiration:  &BucketLifecycleExpiration{Days: 30},
+			},
+			wantErr: "less than expiration.days",
+		},
+		{
+			name: "storage class targeted twice",
+			rule: BucketLifecycleRule{Transitions: []BucketLifecycleTransition{
+				{Days: ptr.To[int32](10), StorageClass: "hdd"},
+				{Days: ptr.To[int32](20), StorageClass: "hdd"},
+			}},
+			wantErr: "more than once",
+		},
+		{
+			name: "noncurrent transition after noncurrent expiration",
+			rule: BucketLifecycleRule{
+				NoncurrentVersionTransitions: []BucketLifecycleNoncurrentVersionTransition{{NoncurrentDays: 30, StorageClass: "hdd"}},
+				NoncurrentVersionExpiration:  &BucketLifecycleNoncurrentVersionExpiration{NoncurrentDays: 30},
+			},
+			wantErr: "less than noncurrentVersionExpiration",
+		},
+		{
+			name: "tag filter with abort multipart",
+			rule: BucketLifecycleRule{
+				Filter:                         tags,
+				AbortIncompleteMultipartUpload: &BucketLifecycleAbortIncompleteMultipartUpload{DaysAfterInitiation: 1},
+			},
+			wantErr: "abortIncompleteMultipartUpload",
+		},
+		{
+			name: "tag filter with expired delete marker",
+			rule: BucketLifecycleRule{
+				Filter:     tags,
+				Expiration: &BucketLifecycleExpiration{ExpiredObjectDeleteMarker: true},
+			},
+			wantErr: "expiredObjectDeleteMarker",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.rule.ID = "r"
+			err := ValidateBucketLifecycleRules([]BucketLifecycleRule{tc.rule})
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("error = %v, want it to mention %q", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BucketReplicationDeleteMarkers controls whether deletions on the source are
+// replayed on the destination.
+// +kubebuilder:validation:Enum=Enabled;Disabled
+type BucketReplicationDeleteMarkers string
+
+const (
+	BucketReplicationDeleteMarkersEnabled  BucketReplicationDeleteMarkers = "Enabled"
+	BucketReplicationDeleteMarkersDisabled BucketReplicationDeleteMarkers = "Disabled"
+)
+
+// BucketReplicationBucketRef points at a Bucket in the same namespace as the
+// BucketReplication. The cluster and resolved bucket name are taken from it.
+type BucketReplicationBucketRef struct {
+	// Name of the Bucket CR in the same namespace.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// BucketReplicationDestination is where objects are replicated to. Exactly
+// one of bucketRef or remote must be set.
+// +kubebuilder:validation:XValidation:rule="has(self.bucketRef) != has(self.remote)",message="exactly one of bucketRef or remote must be set"
+type BucketReplicationDestination struct {
+	// BucketRef replicates into another Bucket, typically on a different
+	// Seaweed cluster, with `weed filer.sync` in active-passive mode. Both
+	// clusters must share gRPC TLS material (or both run without it), since
+	// the sync process dials them with the source cluster's security.toml.
+	// +optional
+	BucketRef *BucketReplicationBucketRef `json:"bucketRef,omitempty"`
+
+	// Remote replicates into an external store with `weed filer.backup`.
+	// Objects land under <directory>/<bucket>/ of the store. The
+	// credentialsSecret is read from the BucketReplication's namespace; a
+	// filesystem store's PVC must live in the source cluster's namespace,
+	// where the replication Deployment runs.
+	// +optional
+	Remote *BackupStorageSpec `json:"remote,omitempty"`
+}
+
+// BucketReplicationSpec defines the desired replication of a bucket.
+type BucketReplicationSpec struct {
+	// SourceRef points at the Bucket whose objects are replicated. Immutable.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="sourceRef is immutable"
+	SourceRef BucketReplicationBucketRef `json:"sourceRef"`
+
+	// Destination is the replication target. Immutable: the destination's
+	// checkpoint is tied to it, so point a new BucketReplication elsewhere
+	// instead.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="destination is immutable"
+	Destination BucketReplicationDestination `json:"destination"`
+
+	// Prefix limits replication to object keys under this directory-style
+	// prefix (it must end in '/'; the filer follows directories, not
+	// arbitrary key prefixes). It is kept in the destination key, so prefix
+	// "logs/" replicates src/logs/a to dst/logs/a. Empty replicates the
+	// whole bucket.
+	// +optional
+	// +kubebuilder:validation:MaxLength=512
+	// +kubebuilder:validation:XValidation:rule="self == '' || (self.endsWith('/') && !self.startsWith('/') && !self.contains('..') && !self.contains(','))",message="prefix must be a relative directory prefix ending in '/' without '..' or ','"
+	Prefix string `json:"prefix,omitempty"`
+
+	// ExcludePrefixes skips object keys under any of these directory-style
+	// prefixes, relative to the bucket root.
+	// +optional
+	// +kubebuilder:validation:MaxItems=32
+	// +kubebuilder:validation:items:MaxLength=512
+	// +kubebuilder:validation:items:XValidation:rule="self.endsWith('/') && !self.startsWith('/') && !self.contains('..') && !self.contains(',')",message="excludePrefixes entries must be relative directory prefixes ending in '/' without '..' or ','"
+	ExcludePrefixes []string `json:"excludePrefixes,omitempty"`
+
+	// DeleteMarkerReplication replays deletions on the destination when
+	// Enabled. Disabled (the default) keeps deleted objects on the
+	// destination, so a replication also protects against accidental
+	// deletes on the source.
+	// +optional
+	// +kubebuilder:default:=Disabled
+	DeleteMarkerReplication BucketReplicationDeleteMarkers `json:"deleteMarkerReplication,omitempty"`
+
+	// Suspend scales the replication Deployment to zero without losing its
+	// checkpoint, so resuming picks up where it stopped.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// Condition types emitted by the bucket replication controller.
+const (
+	// BucketReplicationConditionReady summarises whether the replication
+	// Deployment is running against resolved buckets.
+	BucketReplicationConditionReady = "Ready"
+	// BucketReplicationConditionResolved reports whether the source and
+	// destination resolve to provisioned Buckets and reachable clusters.
+	BucketReplicationConditionResolved = "Resolved"
+)
+
+// BucketReplicationRole is the part a bucket plays in a replication.
+// +kubebuilder:validation:Enum=Source;Destination
+type BucketReplicationRole string
+
+const (
+	BucketReplicationRoleSource      BucketReplicationRole = "Source"
+	BucketReplicationRoleDestination BucketReplicationRole = "Destination"
+)
+
+// BucketReplicationSummary is the per-replication view surfaced on a
+// Bucket's status.
+type BucketReplicationSummary struct {
+	// Name of the BucketReplication in the bucket's namespace.
+	Name string `json:"name"`
+
+	// Role is whether this bucket is the replication's source or destination.
+	Role BucketReplicationRole `json:"role"`
+
+	// Ready mirrors the replication's readiness.
+	// +optional
+	Ready bool `json:"ready,omitempty"`
+
+	// LagSeconds is how far the destination trails the source.
+	// +optional
+	LagSeconds *int64 `json:"lagSeconds,omitempty"`
+
+	// Restarts is the number of times the replication container has
+	// restarted, summed over its pods.
+	// +optional
+	Restarts int32 `json:"restarts,omitempty"`
+
+	// ErrorCount is the number of errors the replication has logged since
+	// its container last started, summed over its pods.
+	// +optional
+	ErrorCount int32 `json:"errorCount,omitempty"`
+}
+
+// BucketReplicationStatus reflects the observed state of a replication.
+type BucketReplicationStatus struct {
+	// ObservedGeneration is the .metadata.generation last reconciled.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Phase is a coarse summary of the replication's lifecycle.
+	// +optional
+	Phase BucketPhase `json:"phase,omitempty"`
+
+	// Conditions are the structured per-aspect state signals.
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// DeploymentName and DeploymentNamespace locate the replication
+	// Deployment. It runs next to the source cluster, so the namespace may
+	// differ from the BucketReplication's; recorded for cleanup.
+	// +optional
+	DeploymentName string `json:"deploymentName,omitempty"`
+	// +optional
+	DeploymentNamespace string `json:"deploymentNamespace,omitempty"`
+
+	// Ready reports whether the replication Deployment has an available
+	// replica.
+	// +optional
+	Ready bool `json:"ready,omitempty"`
+
+	// LastCheckpointTime is the source event time up to which the
+	// destination is known to be in sync, read from the checkpoint
+	// filer.backup / filer.sync persist on the filer.
+	// +optional
+	LastCheckpointTime *metav1.Time `json:"lastCheckpointTime,omitempty"`
+
+	// LagSeconds is the age of LastCheckpointTime when last observed.
+	// +optional
+	LagSeconds *int64 `json:"lagSeconds,omitempty"`
+
+	// Restarts is the number of times the replication container has
+	// restarted, summed over its pods. weed exits on some replication
+	// errors, so a rising count points at a failing replication.
+	// +optional
+	Restarts int32 `json:"restarts,omitempty"`
+
+	// ErrorCount is the number of error lines weed has logged since the
+	// replication container last started, summed over its pods. It is read
+	// from the pods' logs on every reconcile.
+	// +optional
+	ErrorCount int32 `json:"errorCount,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=swbr,categories=seaweedfs
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.spec.sourceRef.name`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Lag",type=integer,JSONPath=`.status.lagSeconds`
+// +kubebuilder:printcolumn:name="Errors",type=integer,JSONPath=`.status.errorCount`
+// +kubebuilder:printcolumn:name="Restarts",type=integer,JSONPath=`.status.restarts`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// BucketReplication is the Schema for continuously replicating a SeaweedFS
+// bucket to another bucket or an external object store.
+type BucketReplication struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BucketReplicationSpec   `json:"spec,omitempty"`
+	Status BucketReplicationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// BucketReplicationList contains a list of BucketReplication.
+type BucketReplicationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BucketReplication `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BucketReplication{}, &BucketReplicationList{})
+}
@@ -0,0 +1,179 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// This file defines spec.erasureCoding: the policy the operator follows to
+// erasure-code warm volumes (`weed shell ec.encode`) and to repair EC shards
+// lost with a volume server (`ec.rebuild`). Buckets may override it for their
+// collection through spec.placement.erasureCoding.
+
+// Default erasure coding layout: SeaweedFS's RS(10,4).
+const (
+	DefaultErasureCodingDataShards   = 10
+	DefaultErasureCodingParityShards = 4
+)
+
+// ErasureCodingRebuildPolicy selects how missing EC shards are handled.
+// +kubebuilder:validation:Enum=Automatic;Manual
+type ErasureCodingRebuildPolicy string
+
+const (
+	// ErasureCodingRebuildAutomatic runs ec.rebuild for a collection as soon
+	// as one of its EC volumes is missing shards.
+	ErasureCodingRebuildAutomatic ErasureCodingRebuildPolicy = "Automatic"
+	// ErasureCodingRebuildManual only reports missing shards.
+	ErasureCodingRebuildManual ErasureCodingRebuildPolicy = "Manual"
+)
+
+// ErasureCodingSpec is the cluster-wide erasure coding policy.
+type ErasureCodingSpec struct {
+	// Collections selects the collections to encode, as glob patterns (for
+	// example "logs-*"). A bucket's collection is the bucket name. Empty
+	// selects every collection.
+	// +optional
+	// +listType=set
+	// +kubebuilder:validation:MaxItems=64
+	Collections []string `json:"collections,omitempty"`
+
+	// QuietFor is how long a volume must go without writes before it is
+	// encoded.
+	// +optional
+	// +kubebuilder:default:="1h"
+	QuietFor *metav1.Duration `json:"quietFor,omitempty"`
+
+	// FullnessPercent is how full, relative to the volume size limit, a
+	// volume must be before it is encoded.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default:=95
+	FullnessPercent *int32 `json:"fullnessPercent,omitempty"`
+
+	// DataShards and ParityShards set the encoding layout. Leave both unset
+	// for the standard 10+4; other layouts need a weed build that supports
+	// them. Shard health is judged against this layout.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=32
+	DataShards *int32 `json:"dataShards,omitempty"`
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=32
+	ParityShards *int32 `json:"parityShards,omitempty"`
+
+	// Interval is how often the operator looks for volumes to encode.
+	// Shard health is checked on every reconcile.
+	// +optional
+	// +kubebuilder:default:="1h"
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// MaxVolumesPerPass caps the volumes encoded each interval, so a large
+	// backlog drains gradually and a reconcile never runs for long.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default:=4
+	MaxVolumesPerPass *int32 `json:"maxVolumesPerPass,omitempty"`
+
+	// Rebuild selects what happens when EC volumes are missing shards,
+	// typically after a volume server disappears. Defaults to Automatic.
+	// +optional
+	// +kubebuilder:default:=Automatic
+	Rebuild ErasureCodingRebuildPolicy `json:"rebuild,omitempty"`
+}
+
+// EffectiveShards returns the data and parity shard counts, defaulted.
+func (s *ErasureCodingSpec) EffectiveShards() (data, parity int32) {
+	data, parity = DefaultErasureCodingDataShards, DefaultErasureCodingParityShards
+	if s.DataShards != nil {
+		data = *s.DataShards
+	}
+	if s.ParityShards != nil {
+		parity = *s.ParityShards
+	}
+	return data, parity
+}
+
+// BucketErasureCoding overrides the cluster's erasure coding policy for a
+// bucket's collection. It only takes effect on clusters with
+// spec.erasureCoding set.
+type BucketErasureCoding struct {
+	// Enabled opts the bucket in (true) even when the cluster's collection
+	// selectors do not match it, or out (false). Unset follows the cluster.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// QuietFor overrides the cluster's quietFor.
+	// +optional
+	QuietFor *metav1.Duration `json:"quietFor,omitempty"`
+
+	// FullnessPercent overrides the cluster's fullnessPercent.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	FullnessPercent *int32 `json:"fullnessPercent,omitempty"`
+}
+
+// ErasureCodingCollectionStatus reports the EC volumes of one collection.
+type ErasureCodingCollectionStatus struct {
+	// Name of the collection; empty is the default collection.
+	Name string `json:"name"`
+
+	// EncodedVolumes is the number of EC volumes in the collection.
+	EncodedVolumes int32 `json:"encodedVolumes"`
+
+	// DegradedVolumes is the number of EC volumes missing shards.
+	// +optional
+	DegradedVolumes int32 `json:"degradedVolumes,omitempty"`
+}
+
+// ErasureCodingStatus reports erasure coding across the cluster.
+type ErasureCodingStatus struct {
+	// EncodedVolumes is the number of EC volumes in the cluster.
+	EncodedVolumes int32 `json:"encodedVolumes"`
+
+	// DegradedVolumes is the number of EC volumes missing at least one
+	// shard but still readable.
+	// +optional
+	DegradedVolumes int32 `json:"degradedVolumes,omitempty"`
+
+	// UnrecoverableVolumes is the number of EC volumes missing more shards
+	// than the parity can restore.
+	// +optional
+	UnrecoverableVolumes int32 `json:"unrecoverableVolumes,omitempty"`
+
+	// PendingVolumes is the number of plain volumes the policy selects that
+	// are still waiting to be encoded.
+	// +optional
+	PendingVolumes int32 `json:"pendingVolumes,omitempty"`
+
+	// Collections breaks the counts down per collection.
+	// +optional
+	Collections []ErasureCodingCollectionStatus `json:"collections,omitempty"`
+
+	// LastEncodeTime is when the operator last ran ec.encode.
+	// +optional
+	LastEncodeTime *metav1.Time `json:"lastEncodeTime,omitempty"`
+
+	// LastRebuildTime is when the operator last ran ec.rebuild.
+	// +optional
+	LastRebuildTime *metav1.Time `json:"lastRebuildTime,omitempty"`
+}
@@ -0,0 +1,209 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IcebergWarehouseRef points at the Bucket, in the same namespace as the
+// IcebergCatalog, that stores the catalog's tables. The cluster serving the
+// catalog is the Bucket's cluster.
+type IcebergWarehouseRef struct {
+	// Name of the Bucket CR in the same namespace.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// IcebergNamespace is a catalog namespace the operator creates.
+type IcebergNamespace struct {
+	// Name is the namespace, with levels separated by dots (for example
+	// "analytics" or "analytics.events").
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=255
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`
+	Name string `json:"name"`
+
+	// Properties are set on the namespace. Keys set here are kept in sync;
+	// other properties on the namespace are left alone.
+	// +optional
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// IcebergCatalogCredentialsRef names an S3Credentials resource in the same
+// namespace as the IcebergCatalog.
+type IcebergCatalogCredentialsRef struct {
+	// Name of the S3Credentials CR.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// IcebergCatalogAuth binds the catalog to an S3 identity. The catalog
+// authenticates REST requests with the cluster's S3 IAM, signed with SigV4.
+type IcebergCatalogAuth struct {
+	// CredentialsRef selects the S3Credentials whose access key the
+	// operator signs its catalog requests with. The identity it belongs to
+	// needs access to the warehouse Bucket (spec.access on the Bucket).
+	// Its Secret is reported in status for jobs to mount.
+	// +kubebuilder:validation:Required
+	CredentialsRef IcebergCatalogCredentialsRef `json:"credentialsRef"`
+
+	// SigningRegion is the SigV4 region. Defaults to us-east-1.
+	// +optional
+	// +kubebuilder:default:=us-east-1
+	SigningRegion string `json:"signingRegion,omitempty"`
+}
+
+// IcebergCatalogSpec defines the desired state of an Iceberg REST catalog
+// endpoint and its namespaces.
+type IcebergCatalogSpec struct {
+	// WarehouseRef points at the Bucket used as the catalog warehouse. The
+	// Bucket's cluster must run its filer with spec.filer.iceberg.enabled.
+	// Immutable.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="warehouseRef is immutable"
+	WarehouseRef IcebergWarehouseRef `json:"warehouseRef"`
+
+	// Namespaces are created in the catalog when missing. Removing an entry
+	// leaves the namespace, and any tables in it, in place.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=64
+	Namespaces []IcebergNamespace `json:"namespaces,omitempty"`
+
+	// Auth binds the catalog to an S3 identity. Omit only on clusters that
+	// run S3 without IAM.
+	// +optional
+	Auth *IcebergCatalogAuth `json:"auth,omitempty"`
+
+	// Service customises the dedicated catalog Service.
+	// +optional
+	Service *ServiceSpec `json:"service,omitempty"`
+
+	// Ingress exposes the catalog Service outside the cluster.
+	// +optional
+	Ingress *IngressSpec `json:"ingress,omitempty"`
+}
+
+// Condition types emitted by the Iceberg catalog controller.
+const (
+	// IcebergCatalogConditionReady summarises whether the endpoint is served
+	// and every declared namespace exists.
+	IcebergCatalogConditionReady = "Ready"
+	// IcebergCatalogConditionResolved reports whether the warehouse Bucket,
+	// its cluster and the credentials resolve.
+	IcebergCatalogConditionResolved = "Resolved"
+	// IcebergCatalogConditionReferenceGranted reports, for a warehouse on a
+	// cluster in another namespace, whether a ResourceReferenceGrant there
+	// permits the catalog's Service and Ingress; absent otherwise.
+	IcebergCatalogConditionReferenceGranted = "ReferenceGranted"
+)
+
+// IcebergCatalogAuthStatus reports the identity the catalog is bound to.
+type IcebergCatalogAuthStatus struct {
+	// IdentityName is the IAM user the credentials belong to.
+	// +optional
+	IdentityName string `json:"identityName,omitempty"`
+
+	// SecretName and SecretNamespace locate the Secret holding the access
+	// key pair, for jobs to mount.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+	// +optional
+	SecretNamespace string `json:"secretNamespace,omitempty"`
+}
+
+// IcebergCatalogStatus reflects the observed state of a catalog.
+type IcebergCatalogStatus struct {
+	// ObservedGeneration is the .metadata.generation last reconciled.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Phase is a coarse summary of the catalog's lifecycle.
+	// +optional
+	Phase BucketPhase `json:"phase,omitempty"`
+
+	// Conditions are the structured per-aspect state signals.
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// Endpoint is the in-cluster catalog URI (the Iceberg "uri" property).
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// ExternalEndpoint is the catalog URI through the Ingress, when one is
+	// enabled.
+	// +optional
+	ExternalEndpoint string `json:"externalEndpoint,omitempty"`
+
+	// Warehouse is the warehouse location (the Iceberg "warehouse"
+	// property), s3://<bucket>.
+	// +optional
+	Warehouse string `json:"warehouse,omitempty"`
+
+	// ServiceName and ServiceNamespace locate the catalog Service. It runs
+	// next to the cluster, so the namespace may differ from the catalog's;
+	// recorded for cleanup.
+	// +optional
+	ServiceName string `json:"serviceName,omitempty"`
+	// +optional
+	ServiceNamespace string `json:"serviceNamespace,omitempty"`
+
+	// Namespaces lists the declared namespaces that exist in the catalog.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Auth reports the identity the catalog is bound to.
+	// +optional
+	Auth *IcebergCatalogAuthStatus `json:"auth,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=swic,categories=seaweedfs
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Warehouse",type=string,JSONPath=`.status.warehouse`
+// +kubebuilder:printcolumn:name="Endpoint",type=string,JSONPath=`.status.endpoint`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// IcebergCatalog is the Schema for exposing a SeaweedFS Iceberg REST catalog
+// backed by a Bucket warehouse.
+type IcebergCatalog struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IcebergCatalogSpec   `json:"spec,omitempty"`
+	Status IcebergCatalogStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// IcebergCatalogList contains a list of IcebergCatalog.
+type IcebergCatalogList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IcebergCatalog `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&IcebergCatalog{}, &IcebergCatalogList{})
+}
@@ -0,0 +1,157 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// This file defines spec.filer.notification: the message queue the filer
+// publishes every metadata event (create/update/delete/rename) to. The
+// operator renders it into notification.toml, which weed loads at startup.
+// weed initializes only the first enabled [notification.*] section, so a
+// cluster has exactly one sink.
+
+// FilerNotificationType enumerates the sinks weed can publish filer events to.
+// +kubebuilder:validation:Enum=log;kafka;awsSqs;googlePubSub;webhook
+type FilerNotificationType string
+
+const (
+	// FilerNotificationLog writes each event to the filer's own log. Useful to
+	// see what a real sink would receive.
+	FilerNotificationLog FilerNotificationType = "log"
+	// FilerNotificationKafka publishes to a Kafka topic.
+	FilerNotificationKafka FilerNotificationType = "kafka"
+	// FilerNotificationAWSSQS publishes to an AWS SQS queue.
+	FilerNotificationAWSSQS FilerNotificationType = "awsSqs"
+	// FilerNotificationGooglePubSub publishes to a Google Cloud Pub/Sub topic.
+	FilerNotificationGooglePubSub FilerNotificationType = "googlePubSub"
+	// FilerNotificationWebhook POSTs each event as JSON to an HTTP endpoint.
+	FilerNotificationWebhook FilerNotificationType = "webhook"
+)
+
+// Well-known key read from a webhook sink's BearerTokenSecret when the
+// selector does not name one.
+const NotificationSecretKeyBearerToken = "token"
+
+// KafkaNotification configures the Kafka sink. weed's Kafka publisher speaks
+// plaintext to the brokers and has no SASL options, so it takes no credentials.
+type KafkaNotification struct {
+	// Hosts are the bootstrap brokers, as host:port.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=16
+	Hosts []string `json:"hosts"`
+
+	// Topic receives the events.
+	// +kubebuilder:validation:MinLength=1
+	Topic string `json:"topic"`
+}
+
+// AWSSQSNotification configures the AWS SQS sink. Credentials come from the
+// CredentialsSecret (AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY); when it is
+// omitted weed falls back to the ambient AWS credential chain (instance
+// profile, IRSA, etc.).
+type AWSSQSNotification struct {
+	// QueueName is an existing SQS queue.
+	// +kubebuilder:validation:MinLength=1
+	QueueName string `json:"queueName"`
+
+	// Region of the queue. Defaults to us-east-2 (the weed default).
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// CredentialsSecret names a Secret, in the cluster's namespace, holding
+	// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+	// +optional
+	CredentialsSecret *corev1.LocalObjectReference `json:"credentialsSecret,omitempty"`
+}
+
+// GooglePubSubNotification configures the Google Cloud Pub/Sub sink. The
+// service-account JSON is supplied via the CredentialsSecret under
+// GOOGLE_APPLICATION_CREDENTIALS_JSON; when it is omitted weed uses the
+// ambient credentials (Workload Identity).
+type GooglePubSubNotification struct {
+	// ProjectID is the project that owns the topic.
+	// +kubebuilder:validation:MinLength=1
+	ProjectID string `json:"projectId"`
+
+	// Topic receives the events. weed creates it if it does not exist.
+	// +kubebuilder:validation:MinLength=1
+	Topic string `json:"topic"`
+
+	// CredentialsSecret names a Secret, in the cluster's namespace, holding
+	// GOOGLE_APPLICATION_CREDENTIALS_JSON.
+	// +optional
+	CredentialsSecret *corev1.LocalObjectReference `json:"credentialsSecret,omitempty"`
+}
+
+// WebhookNotification configures the generic HTTP sink. Any endpoint that
+// accepts a JSON POST works, including an in-cluster Service, so it doubles as
+// a local stand-in for a real queue in development and tests.
+type WebhookNotification struct {
+	// Endpoint is the URL events are POSTed to.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Pattern=`^https?://`
+	Endpoint string `json:"endpoint"`
+
+	// BearerTokenSecret selects a Secret key, in the cluster's namespace, sent
+	// as "Authorization: Bearer <token>". The key defaults to "token".
+	// +optional
+	BearerTokenSecret *corev1.SecretKeySelector `json:"bearerTokenSecret,omitempty"`
+
+	// TimeoutSeconds bounds each delivery attempt. Defaults to weed's own (10).
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+
+	// MaxRetries is how often a failed delivery is retried before the event is
+	// dropped. Defaults to weed's own (3).
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxRetries *int32 `json:"maxRetries,omitempty"`
+
+	// EventTypes limits delivery to these event kinds. Empty sends all.
+	// +optional
+	// +listType=set
+	// +kubebuilder:validation:items:Enum=create;update;delete;rename
+	EventTypes []string `json:"eventTypes,omitempty"`
+
+	// PathPrefixes limits delivery to events under these filer paths. Empty
+	// sends all.
+	// +optional
+	// +kubebuilder:validation:MaxItems=32
+	PathPrefixes []string `json:"pathPrefixes,omitempty"`
+}
+
+// FilerNotificationSpec selects the sink filer metadata events are published
+// to. The sub-block matching Type must be set.
+//
+// +kubebuilder:validation:XValidation:rule="(self.type != 'kafka' || has(self.kafka)) && (self.type != 'awsSqs' || has(self.awsSqs)) && (self.type != 'googlePubSub' || has(self.googlePubSub)) && (self.type != 'webhook' || has(self.webhook))",message="notification must set the sub-block matching its type"
+type FilerNotificationSpec struct {
+	// Type selects which sub-block below is used. "log" needs none.
+	// +kubebuilder:validation:Required
+	Type FilerNotificationType `json:"type"`
+
+	// +optional
+	Kafka *KafkaNotification `json:"kafka,omitempty"`
+	// +optional
+	AWSSQS *AWSSQSNotification `json:"awsSqs,omitempty"`
+	// +optional
+	GooglePubSub *GooglePubSubNotification `json:"googlePubSub,omitempty"`
+	// +optional
+	Webhook *WebhookNotification `json:"webhook,omitempty"`
+}
@@ -0,0 +1,116 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// This file defines spec.remoteTiers: remote object stores that sealed
+// volumes are offloaded to with `weed shell volume.tier.upload` (and fetched
+// back with volume.tier.download). Each tier is rendered into the master's
+// [storage.backend] config, which the master hands to every volume server.
+
+// RemoteTierSpec is one remote storage tier.
+//
+// S3 tiers use weed's native s3 backend. GCS, Azure and B2 tiers go through
+// its rclone backend, which needs a weed image built with the rclone tag
+// (the *_full images).
+//
+// +kubebuilder:validation:XValidation:rule="self.storage.type != 'filesystem'",message="a remote tier must be an object store"
+type RemoteTierSpec struct {
+	// Name identifies the tier. The master reports offloaded volumes as
+	// living on "<backend>.<name>", where backend is s3 or rclone.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=30
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// Storage is the destination, in the shape of spec.backup.storages.
+	// CredentialsSecret is read from the cluster's namespace. The s3
+	// backend writes objects at the bucket root, so an s3 directory is
+	// ignored.
+	Storage BackupStorageSpec `json:"storage"`
+
+	// StorageClass is the S3 storage class volumes are written with.
+	// Defaults to STANDARD_IA. Ignored by other storage types.
+	// +optional
+	StorageClass *string `json:"storageClass,omitempty"`
+
+	// Policy selects the volumes the operator uploads to this tier. Without
+	// it the tier is only configured, and volumes are moved by hand (for
+	// example from an AdminScript).
+	// +optional
+	Policy *RemoteTierPolicy `json:"policy,omitempty"`
+}
+
+// RemoteTierPolicy selects sealed volumes for upload.
+type RemoteTierPolicy struct {
+	// Collections selects the collections to offload, as glob patterns. A
+	// bucket's collection is the bucket name. Empty selects every
+	// collection.
+	// +optional
+	// +listType=set
+	// +kubebuilder:validation:MaxItems=64
+	Collections []string `json:"collections,omitempty"`
+
+	// MinAge is how long a volume must go without writes before it is
+	// uploaded.
+	// +optional
+	// +kubebuilder:default:="168h"
+	MinAge *metav1.Duration `json:"minAge,omitempty"`
+
+	// FullnessPercent is how full, relative to the volume size limit, a
+	// volume must be before it is uploaded.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default:=95
+	FullnessPercent *int32 `json:"fullnessPercent,omitempty"`
+
+	// Interval is how often the operator looks for volumes to upload.
+	// +optional
+	// +kubebuilder:default:="1h"
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// MaxVolumesPerPass caps the uploads started each interval, so a large
+	// backlog drains gradually instead of saturating the uplink.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default:=1
+	MaxVolumesPerPass *int32 `json:"maxVolumesPerPass,omitempty"`
+}
+
+// RemoteTierStatus reports the volumes offloaded to one tier.
+type RemoteTierStatus struct {
+	// Name is the tier.
+	Name string `json:"name"`
+	// OffloadedVolumes is the number of volumes whose data lives on the tier.
+	// +optional
+	OffloadedVolumes int32 `json:"offloadedVolumes,omitempty"`
+	// OffloadedBytes is the size of those volumes.
+	// +optional
+	OffloadedBytes int64 `json:"offloadedBytes,omitempty"`
+	// PendingVolumes is the number of local volumes the policy selects that
+	// are still waiting for upload.
+	// +optional
+	PendingVolumes int32 `json:"pendingVolumes,omitempty"`
+	// LastUploadTime is when the operator last looked for volumes to upload.
+	// +optional
+	LastUploadTime *metav1.Time `json:"lastUploadTime,omitempty"`
+}
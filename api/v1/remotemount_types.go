@@ -0,0 +1,188 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RemoteMountFileFilter selects files under a mount for remote.cache or
+// remote.uncache. Every set field must match.
+type RemoteMountFileFilter struct {
+	// Include selects files whose name matches this glob, e.g. "*.parquet".
+	// +optional
+	// +kubebuilder:validation:MaxLength=256
+	Include string `json:"include,omitempty"`
+
+	// Exclude skips files whose name matches this glob.
+	// +optional
+	// +kubebuilder:validation:MaxLength=256
+	Exclude string `json:"exclude,omitempty"`
+
+	// MinSize selects files at least this large.
+	// +optional
+	MinSize *resource.Quantity `json:"minSize,omitempty"`
+
+	// MaxSize selects files at most this large.
+	// +optional
+	MaxSize *resource.Quantity `json:"maxSize,omitempty"`
+
+	// MinAge selects files last modified at least this long ago.
+	// +optional
+	MinAge *metav1.Duration `json:"minAge,omitempty"`
+
+	// MaxAge selects files last modified at most this long ago.
+	// +optional
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+}
+
+// RemoteMountSpec mounts a bucket of an external object store into a Seaweed
+// cluster's filer namespace (`weed shell remote.configure` and
+// `remote.mount`). Reads of files not cached locally are fetched from the
+// remote store; the operator refreshes the file listing every SyncInterval
+// (remote.meta.sync).
+//
+// +kubebuilder:validation:XValidation:rule="self.storage.type != 'filesystem'",message="a remote mount must be an object store"
+type RemoteMountSpec struct {
+	// SeaweedRef points at the Seaweed cluster whose filer mounts the
+	// bucket. A cluster in another namespace must grant the reference with a
+	// ResourceReferenceGrant. Immutable: the mount lives in that cluster's
+	// filer, and the operator unmounts it from there.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="seaweedRef is immutable"
+	SeaweedRef SeaweedReference `json:"seaweedRef"`
+
+	// Storage is the remote store, in the shape of spec.backup.storages.
+	// CredentialsSecret is read from this RemoteMount's namespace. Its
+	// directory is ignored: RemotePath selects what is mounted. GCS mounts
+	// use the filer's ambient credentials (Workload Identity), because weed
+	// reads GCS keys from a file on the filer. The type and the bucket (or
+	// Azure container) are immutable; credentials and endpoints may change.
+	// +kubebuilder:validation:XValidation:rule="self.type == oldSelf.type && (self.type != 's3' || self.s3.bucket == oldSelf.s3.bucket) && (self.type != 'gcs' || self.gcs.bucket == oldSelf.gcs.bucket) && (self.type != 'azure' || self.azure.container == oldSelf.azure.container) && (self.type != 'b2' || self.b2.bucket == oldSelf.b2.bucket)",message="storage type, bucket and container are immutable"
+	Storage BackupStorageSpec `json:"storage"`
+
+	// RemotePath is the prefix inside the bucket (or Azure container) that
+	// is mounted. Defaults to the whole bucket.
+	// +optional
+	// +kubebuilder:default:="/"
+	// +kubebuilder:validation:MaxLength=1024
+	// +kubebuilder:validation:XValidation:rule="self.startsWith('/') && !self.contains('..')",message="remotePath must be absolute and must not contain '..'"
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="remotePath is immutable"
+	RemotePath string `json:"remotePath,omitempty"`
+
+	// LocalDir is the filer directory the bucket is mounted at, e.g.
+	// /buckets/archive to serve it through the S3 gateway. It must be empty
+	// or missing when the mount is created.
+	// +kubebuilder:validation:MinLength=2
+	// +kubebuilder:validation:MaxLength=1024
+	// +kubebuilder:validation:XValidation:rule="self.startsWith('/') && !self.contains('..')",message="localDir must be absolute and must not contain '..'"
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="localDir is immutable"
+	LocalDir string `json:"localDir"`
+
+	// SyncInterval is how often the operator pulls the remote file listing
+	// into the filer (remote.meta.sync), so objects written to the bucket by
+	// other clients show up.
+	// +optional
+	// +kubebuilder:default:="1h"
+	SyncInterval *metav1.Duration `json:"syncInterval,omitempty"`
+
+	// Cache selects files whose content is pulled into the cluster after
+	// each sync (remote.cache), so reads no longer reach the remote store.
+	// +optional
+	Cache *RemoteMountFileFilter `json:"cache,omitempty"`
+
+	// Uncache selects cached files whose local content is dropped after each
+	// sync (remote.uncache); their metadata stays and reads go remote again.
+	// +optional
+	Uncache *RemoteMountFileFilter `json:"uncache,omitempty"`
+}
+
+// Condition types emitted by the remote mount controller.
+const (
+	// RemoteMountConditionReady reports whether the bucket is mounted at
+	// spec.localDir.
+	RemoteMountConditionReady = "Ready"
+	// RemoteMountConditionSynced reports the outcome of the last metadata
+	// sync and cache pass.
+	RemoteMountConditionSynced = "Synced"
+)
+
+// RemoteMountStatus reflects the observed state of a remote mount.
+type RemoteMountStatus struct {
+	// ObservedGeneration is the .metadata.generation last reconciled.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Phase is a coarse summary of the mount's lifecycle.
+	// +optional
+	Phase S3Phase `json:"phase,omitempty"`
+
+	// Conditions are the structured per-aspect state signals.
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// RemoteName is the remote storage configuration the operator stored in
+	// the filer for this mount.
+	// +optional
+	RemoteName string `json:"remoteName,omitempty"`
+
+	// Remote is the mounted location, as "<remoteName>/<bucket><remotePath>".
+	// +optional
+	Remote string `json:"remote,omitempty"`
+
+	// LastSyncTime is when the remote listing was last pulled successfully.
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=rmount,categories=seaweedfs
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.seaweedRef.name`
+// +kubebuilder:printcolumn:name="Dir",type=string,JSONPath=`.spec.localDir`
+// +kubebuilder:printcolumn:name="Remote",type=string,JSONPath=`.status.remote`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Last Sync",type=date,JSONPath=`.status.lastSyncTime`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// RemoteMount is the Schema for mounting an external object store bucket
+// into a Seaweed cluster's filer namespace.
+type RemoteMount struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RemoteMountSpec   `json:"spec,omitempty"`
+	Status RemoteMountStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// RemoteMountList contains a list of RemoteMount.
+type RemoteMountList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RemoteMount `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RemoteMount{}, &RemoteMountList{})
+}
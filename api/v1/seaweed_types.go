@@ -19,6 +19,7 @@ package v1
 import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -238,6 +239,18 @@ type SeaweedSpec struct {
 	// +optional
 	Backup *BackupSpec `json:"backup,omitempty"`
 
+	// ErasureCoding erasure-codes warm volumes and repairs lost EC shards.
+	// Buckets may override it through spec.placement.erasureCoding.
+	// +optional
+	ErasureCoding *ErasureCodingSpec `json:"erasureCoding,omitempty"`
+
+	// RemoteTiers are object stores sealed volumes can be offloaded to.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=8
+	RemoteTiers []RemoteTierSpec `json:"remoteTiers,omitempty"`
+
 	// Note: Standalone IAM has been removed. IAM is now embedded in S3 by default.
 	// When filer.s3.enabled=true, IAM API is available on the same S3 port.
 	// Use filer.iam=false to disable embedded IAM if needed.
@@ -313,6 +326,22 @@ type SeaweedSpec struct {
 	LoggingArgs []string `json:"loggingArgs,omitempty"`
 }
 
+// Condition types set on a Seaweed besides Ready.
+const (
+	// SeaweedConditionS3CircuitBreakerApplied reports whether the filer holds
+	// the global circuit breaker declared in spec.s3.circuitBreaker.
+	SeaweedConditionS3CircuitBreakerApplied = "S3CircuitBreakerApplied"
+	// SeaweedConditionAdminTasksApplied reports whether the admin server
+	// holds the maintenance policies declared in spec.admin.tasks.
+	SeaweedConditionAdminTasksApplied = "AdminTasksApplied"
+	// SeaweedConditionErasureCodingHealthy reports whether every EC volume
+	// has all of its shards.
+	SeaweedConditionErasureCodingHealthy = "ErasureCodingHealthy"
+	// SeaweedConditionRemoteTiersReady reports whether the last pass over
+	// spec.remoteTiers policies uploaded what it selected.
+	SeaweedConditionRemoteTiersReady = "RemoteTiersReady"
+)
+
 // SeaweedStatus defines the observed state of Seaweed
 type SeaweedStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -357,12 +386,86 @@ type SeaweedStatus struct {
 	// +optional
 	SFTP ComponentStatus `json:"sftp,omitempty"`
 
+	// SFTPHostKeys reports the operator-managed SFTP host keys; unset when
+	// the gateway is disabled or brings its own spec.sftp.hostKeysSecret.
+	// +optional
+	SFTPHostKeys *SFTPHostKeysStatus `json:"sftpHostKeys,omitempty"`
+
 	// BackupMirrors reports the state of the continuous data-mirror
 	// Deployments managed for spec.backup.dataMirror.
 	// +optional
 	// +listType=map
 	// +listMapKey=storageName
 	BackupMirrors []BackupMirrorStatus `json:"backupMirrors,omitempty"`
+
+	// BackupMetadataLogs reports the state of the continuous metadata-log
+	// Deployments managed for spec.backup.metadataLog.
+	// +optional
+	// +listType=map
+	// +listMapKey=storageName
+	BackupMetadataLogs []BackupMirrorStatus `json:"backupMetadataLogs,omitempty"`
+
+	// BackupStorages reports whether each of spec.backup.storages accepted
+	// the operator's probe. Schedules do not fire against a storage whose
+	// Reachable condition is False.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	BackupStorages []BackupStorageStatus `json:"backupStorages,omitempty"`
+
+	// ErasureCoding reports EC volume counts and shard health when
+	// spec.erasureCoding is set.
+	// +optional
+	ErasureCoding *ErasureCodingStatus `json:"erasureCoding,omitempty"`
+
+	// RemoteTiers reports the volumes offloaded to each of spec.remoteTiers.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	RemoteTiers []RemoteTierStatus `json:"remoteTiers,omitempty"`
+}
+
+// SFTPHostKeysStatus describes the host keys the SFTP gateway offers.
+type SFTPHostKeysStatus struct {
+	// SecretName is the generated Secret holding the private keys.
+	SecretName string `json:"secretName"`
+
+	// Keys lists the keys offered, current ones first. During a rotation's
+	// overlap window the previous keys are listed too, with Previous set.
+	// +optional
+	// +listType=atomic
+	Keys []SFTPHostKey `json:"keys,omitempty"`
+
+	// GeneratedAt is when the current keys were generated.
+	// +optional
+	GeneratedAt *metav1.Time `json:"generatedAt,omitempty"`
+
+	// NextRotationTime is when the current keys are next replaced; unset
+	// without spec.sftp.hostKeyRotation.
+	// +optional
+	NextRotationTime *metav1.Time `json:"nextRotationTime,omitempty"`
+
+	// PreviousKeysExpireAt is when the previous keys stop being offered;
+	// unset outside an overlap window.
+	// +optional
+	PreviousKeysExpireAt *metav1.Time `json:"previousKeysExpireAt,omitempty"`
+}
+
+// SFTPHostKey is one host key offered by the SFTP gateway.
+type SFTPHostKey struct {
+	// Type is the SSH key algorithm, e.g. ssh-ed25519 or ssh-rsa.
+	Type string `json:"type"`
+
+	// Fingerprint is the OpenSSH SHA256 fingerprint, as printed by
+	// ssh-keygen -lf.
+	Fingerprint string `json:"fingerprint"`
+
+	// PublicKey is the key in known_hosts format, without the host name.
+	PublicKey string `json:"publicKey"`
+
+	// Previous marks a key being retired at the end of an overlap window.
+	// +optional
+	Previous bool `json:"previous,omitempty"`
 }
 
 // ComponentStatus represents the status of a seaweedfs component
@@ -567,6 +670,12 @@ type S3Config struct {
 	// +kubebuilder:default:=true
 	Enabled      bool                      `json:"enabled,omitempty"`
 	ConfigSecret *corev1.SecretKeySelector `json:"configSecret,omitempty"`
+
+	// CircuitBreaker sets the cluster-wide S3 circuit breaker for the
+	// filer-embedded gateway, as spec.s3.circuitBreaker does for the
+	// standalone one.
+	// +optional
+	CircuitBreaker *S3CircuitBreakerSpec `json:"circuitBreaker,omitempty"`
 }
 
 // S3GatewaySpec defines a standalone S3 gateway Deployment that runs
@@ -616,11 +725,61 @@ type S3GatewaySpec struct {
 	// Ingress configuration for the standalone S3 gateway.
 	// +optional
 	Ingress *IngressSpec `json:"ingress,omitempty"`
+
+	// CircuitBreaker sets the cluster-wide S3 circuit breaker: limits on the
+	// requests every S3 gateway serves concurrently, per action class. The
+	// operator writes it as the global scope of the filer's
+	// circuit_breaker.json once the filer is up and re-applies it when the
+	// stored config drifts. Per-bucket limits
+	// live on the Bucket resource (spec.rateLimits).
+	// +optional
+	CircuitBreaker *S3CircuitBreakerSpec `json:"circuitBreaker,omitempty"`
+}
+
+// S3ActionLimit caps one class of S3 request. A request arriving while the
+// class is at either limit is rejected until load drops.
+//
+// +kubebuilder:validation:XValidation:rule="has(self.maxConcurrentRequests) || has(self.maxConcurrentSize)",message="set maxConcurrentRequests, maxConcurrentSize, or both"
+// +kubebuilder:validation:XValidation:rule="!has(self.maxConcurrentSize) || !string(self.maxConcurrentSize).startsWith('-')",message="maxConcurrentSize must be non-negative"
+type S3ActionLimit struct {
+	// Action is the class of S3 request the limit applies to.
+	// +kubebuilder:validation:Required
+	Action BucketAccessAction `json:"action"`
+
+	// MaxConcurrentRequests caps the requests of this class in flight at once.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxConcurrentRequests *int64 `json:"maxConcurrentRequests,omitempty"`
+
+	// MaxConcurrentSize caps the combined body size of the requests of this
+	// class in flight at once (e.g. "512Mi"). SeaweedFS tracks the limit in
+	// whole MiB, so the controller rounds up.
+	// +optional
+	MaxConcurrentSize *resource.Quantity `json:"maxConcurrentSize,omitempty"`
+}
+
+// S3CircuitBreakerSpec is one scope (global or a single bucket) of the S3
+// circuit breaker stored in the filer. The controller reconciles the scope to
+// exactly this list: actions not listed carry no limit.
+type S3CircuitBreakerSpec struct {
+	// Enabled toggles enforcement without discarding the limits. Defaults to
+	// true.
+	// +optional
+	// +kubebuilder:default:=true
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Limits is keyed by action so each class appears at most once.
+	// +optional
+	// +listType=map
+	// +listMapKey=action
+	// +kubebuilder:validation:MaxItems=5
+	Limits []S3ActionLimit `json:"limits,omitempty"`
 }
 
 // SFTPSpec defines a standalone SFTP gateway Deployment. The SFTP server
 // dials the filer via the filer's in-cluster Service and serves SSH
 // clients on its configured port.
+// +kubebuilder:validation:XValidation:rule="!(has(self.hostKeysSecret) && has(self.hostKeyRotation))",message="hostKeyRotation applies only to operator-managed host keys; remove hostKeysSecret"
 type SFTPSpec struct {
 	ComponentSpec               `json:",inline"`
 	corev1.ResourceRequirements `json:",inline"`
@@ -656,12 +815,17 @@ type SFTPSpec struct {
 
 	// HostKeysSecret references a Secret containing the SSH host keys
 	// the SFTP server presents to clients. The Secret is mounted
-	// read-only at /etc/sw/ssh. Omit to let the server generate an
-	// ephemeral host key on startup (clients will see a changed
-	// fingerprint on every pod restart — fine for dev, bad for prod).
+	// read-only at /etc/sw/ssh. Omit to have the operator generate
+	// ed25519 and RSA host keys into an owned <name>-sftp-host-keys
+	// Secret, whose fingerprints are published in status.sftpHostKeys.
 	// +optional
 	HostKeysSecret *corev1.LocalObjectReference `json:"hostKeysSecret,omitempty"`
 
+	// HostKeyRotation replaces the operator-managed host keys on a
+	// schedule. Omit to keep the generated keys indefinitely.
+	// +optional
+	HostKeyRotation *SFTPHostKeyRotation `json:"hostKeyRotation,omitempty"`
+
 	// AuthMethods is passed to weed sftp -authMethods (e.g. "password",
 	// "publickey", or a comma-separated list). Omit to accept the
 	// server's defaults.
@@ -682,6 +846,23 @@ type SFTPSpec struct {
 	Ingress *IngressSpec `json:"ingress,omitempty"`
 }
 
+// SFTPHostKeyRotation schedules the replacement of operator-managed SFTP
+// host keys.
+type SFTPHostKeyRotation struct {
+	// Schedule is a standard five-field cron expression, evaluated in UTC,
+	// at which fresh keys are generated (e.g. "0 3 1 */3 *" for quarterly).
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// OverlapWindow is how long the previous keys keep being offered next
+	// to the new ones after a rotation, so clients can learn the new
+	// fingerprints before the old ones disappear. The server holds one key
+	// per algorithm, so the previous ed25519 key is served next to the new
+	// RSA key. Defaults to 168h.
+	// +optional
+	OverlapWindow *metav1.Duration `json:"overlapWindow,omitempty"`
+}
+
 // IcebergConfig defines the Iceberg catalog REST API configuration
 type IcebergConfig struct {
 	// +kubebuilder:default:=true
@@ -742,6 +923,12 @@ type FilerSpec struct {
 	// Iceberg configuration for the Iceberg catalog REST API
 	Iceberg *IcebergConfig `json:"iceberg,omitempty"`
 
+	// Notification publishes filer metadata events to a message queue or
+	// webhook. The operator renders notification.toml from it, resolving the
+	// referenced credential Secrets, and rolls the filers when it changes.
+	// +optional
+	Notification *FilerNotificationSpec `json:"notification,omitempty"`
+
 	// Ingress configuration for the filer HTTP port.
 	// +optional
 	Ingress *IngressSpec `json:"ingress,omitempty"`
@@ -794,6 +981,134 @@ type AdminSpec struct {
 	// Ingress configuration for the admin UI HTTP port.
 	// +optional
 	Ingress *IngressSpec `json:"ingress,omitempty"`
+
+	// Tasks are the admin server's maintenance policies. The operator pushes
+	// them to the admin server's maintenance config API once it is ready and
+	// reapplies them when they drift, so edits made in the admin UI to a
+	// field set here are reverted. Fields left unset keep the admin server's
+	// value.
+	// +optional
+	Tasks *AdminTasksSpec `json:"tasks,omitempty"`
+}
+
+// AdminTasksSpec holds the maintenance policy of each admin task type.
+// Removing a task, or the whole block, leaves its last applied policy in
+// place on the admin server.
+type AdminTasksSpec struct {
+	// Vacuum reclaims space held by deleted needles.
+	// +optional
+	Vacuum *AdminVacuumTask `json:"vacuum,omitempty"`
+
+	// ErasureCoding converts full, quiet volumes to erasure-coded shards.
+	// +optional
+	ErasureCoding *AdminErasureCodingTask `json:"erasureCoding,omitempty"`
+
+	// Balance moves volumes between servers to even out their load.
+	// +optional
+	Balance *AdminBalanceTask `json:"balance,omitempty"`
+
+	// Replication repairs volumes that have fewer replicas than their
+	// replica placement asks for.
+	// +optional
+	Replication *AdminReplicationTask `json:"replication,omitempty"`
+}
+
+// AdminTaskPolicy holds the scheduling settings every task type shares.
+type AdminTaskPolicy struct {
+	// Enabled turns detection and execution of the task on or off.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// MaxConcurrent caps how many tasks of this type run at once across all
+	// workers.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxConcurrent *int32 `json:"maxConcurrent,omitempty"`
+
+	// RepeatInterval is the minimum time before the same volume is
+	// processed again. Rounded down to whole seconds.
+	// +optional
+	RepeatInterval *metav1.Duration `json:"repeatInterval,omitempty"`
+
+	// CheckInterval is how often the admin server scans for work of this
+	// type. Rounded down to whole seconds.
+	// +optional
+	CheckInterval *metav1.Duration `json:"checkInterval,omitempty"`
+}
+
+// AdminVacuumTask is the vacuum maintenance policy.
+type AdminVacuumTask struct {
+	AdminTaskPolicy `json:",inline"`
+
+	// GarbageThresholdPercent is the share of deleted data at which a volume
+	// is vacuumed.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	GarbageThresholdPercent *int32 `json:"garbageThresholdPercent,omitempty"`
+
+	// MinVolumeAge skips volumes younger than this.
+	// +optional
+	MinVolumeAge *metav1.Duration `json:"minVolumeAge,omitempty"`
+
+	// MinInterval is the minimum time between two vacuums of a volume.
+	// +optional
+	MinInterval *metav1.Duration `json:"minInterval,omitempty"`
+}
+
+// AdminErasureCodingTask is the erasure coding maintenance policy.
+type AdminErasureCodingTask struct {
+	AdminTaskPolicy `json:",inline"`
+
+	// FullnessPercent is how full, relative to the volume size limit, a
+	// volume must be before it is encoded.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	FullnessPercent *int32 `json:"fullnessPercent,omitempty"`
+
+	// QuietFor is how long a volume must go without writes before it is
+	// encoded.
+	// +optional
+	QuietFor *metav1.Duration `json:"quietFor,omitempty"`
+
+	// MinVolumeSize skips volumes smaller than this. Rounded up to whole
+	// MiB.
+	// +optional
+	MinVolumeSize *resource.Quantity `json:"minVolumeSize,omitempty"`
+
+	// CollectionFilter limits encoding to a comma-separated list of
+	// collections. Empty encodes every collection.
+	// +optional
+	CollectionFilter *string `json:"collectionFilter,omitempty"`
+}
+
+// AdminBalanceTask is the volume balance maintenance policy.
+type AdminBalanceTask struct {
+	AdminTaskPolicy `json:",inline"`
+
+	// ImbalanceThresholdPercent is the spread in volume counts between
+	// servers, relative to the average, that triggers a balance.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	ImbalanceThresholdPercent *int32 `json:"imbalanceThresholdPercent,omitempty"`
+
+	// MinServerCount is the number of volume servers below which no balance
+	// runs.
+	// +kubebuilder:validation:Minimum=2
+	// +optional
+	MinServerCount *int32 `json:"minServerCount,omitempty"`
+}
+
+// AdminReplicationTask is the replica repair maintenance policy.
+type AdminReplicationTask struct {
+	AdminTaskPolicy `json:",inline"`
+
+	// TargetReplicaCount is the number of replicas the task restores.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetReplicaCount *int32 `json:"targetReplicaCount,omitempty"`
 }
 
 // WorkerSpec is the spec for worker processes
@@ -828,6 +1143,57 @@ type WorkerSpec struct {
 	// MaxExecute is the max number of concurrent execute requests
 	// +kubebuilder:validation:Minimum=1
 	MaxExecute *int32 `json:"maxExecute,omitempty"`
+
+	// Pools run several worker Deployments side by side, each named
+	// <seaweed>-worker-<pool>, for example a few large workers for erasure
+	// coding next to small ones for vacuum. When set they replace the single
+	// <seaweed>-worker Deployment. Every pool inherits the settings above and
+	// overrides the ones it sets.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=16
+	Pools []WorkerPool `json:"pools,omitempty"`
+}
+
+// WorkerPool is one worker Deployment with its own job types and resources.
+type WorkerPool struct {
+	// Name identifies the pool and suffixes its Deployment.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=30
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// Replicas of the pool. Defaults to spec.worker.replicas.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// JobType overrides spec.worker.jobType.
+	// +optional
+	JobType *string `json:"jobType,omitempty"`
+
+	// MaxDetect overrides spec.worker.maxDetect.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxDetect *int32 `json:"maxDetect,omitempty"`
+
+	// MaxExecute overrides spec.worker.maxExecute.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxExecute *int32 `json:"maxExecute,omitempty"`
+
+	// Resources replace spec.worker's requests and limits.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// NodeSelector entries are merged over spec.worker.nodeSelector.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations are added to spec.worker.tolerations.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
 }
 
 // ProbeOverride tunes the timing fields of an operator-managed readiness probe.
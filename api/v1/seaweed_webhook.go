@@ -23,6 +23,7 @@ import (
 	"path"
 	"regexp"
 
+	"github.com/robfig/cron/v3"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
@@ -196,11 +197,21 @@ func (r *Seaweed) validateS3Exclusivity() error {
 
 // validateSFTP enforces that the standalone SFTP gateway has a filer in
 // the same CR to connect to. Without a filer the gateway would start but
-// fail every client request, so we reject at admission time.
+// fail every client request, so we reject at admission time. It also parses
+// the host key rotation schedule, which the schema cannot check.
 func (r *Seaweed) validateSFTP() error {
 	if r.Spec.SFTP != nil && r.Spec.Filer == nil {
 		return errors.New("spec.sftp requires spec.filer to be configured")
 	}
+	if r.Spec.SFTP != nil && r.Spec.SFTP.HostKeyRotation != nil {
+		rot := r.Spec.SFTP.HostKeyRotation
+		if _, err := cron.ParseStandard(rot.Schedule); err != nil {
+			return fmt.Errorf("spec.sftp.hostKeyRotation.schedule: %w", err)
+		}
+		if rot.OverlapWindow != nil && rot.OverlapWindow.Duration < 0 {
+			return errors.New("spec.sftp.hostKeyRotation.overlapWindow must not be negative")
+		}
+	}
 	return nil
 }
 
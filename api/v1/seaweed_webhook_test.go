@@ -13,6 +13,7 @@ package v1
 import (
 	"strings"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -296,6 +297,30 @@ func TestValidateBackup(t *testing.T) {
 	})
 }
 
+func TestValidateSFTPHostKeyRotation(t *testing.T) {
+	withRotation := func(rot *SFTPHostKeyRotation) *Seaweed {
+		sw := baseValid()
+		sw.Spec.Filer = &FilerSpec{Replicas: 1}
+		sw.Spec.SFTP = &SFTPSpec{Replicas: 1, HostKeyRotation: rot}
+		return sw
+	}
+
+	if err := withRotation(&SFTPHostKeyRotation{Schedule: "0 3 1 * *"}).validateSFTP(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := withRotation(&SFTPHostKeyRotation{Schedule: "monthly"}).validateSFTP()
+	if err == nil || !strings.Contains(err.Error(), "schedule") {
+		t.Fatalf("expected schedule error, got %v", err)
+	}
+	err = withRotation(&SFTPHostKeyRotation{
+		Schedule:      "0 3 1 * *",
+		OverlapWindow: &metav1.Duration{Duration: -time.Hour},
+	}).validateSFTP()
+	if err == nil || !strings.Contains(err.Error(), "overlapWindow") {
+		t.Fatalf("expected overlapWindow error, got %v", err)
+	}
+}
+
 // Every master keeps its raft state under the same subdirectory of -mdir, so a
 // claim shared by several of them is never a working configuration.
 func TestValidateMasterPersistence(t *testing.T) {
@@ -17,6 +17,8 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -36,6 +38,19 @@ const (
 	BackupPhaseFailed BackupPhase = "Failed"
 )
 
+// BackupDeletionPolicy says what happens to a backup's artifact when the
+// SeaweedBackup is deleted.
+// +kubebuilder:validation:Enum=Retain;Delete
+type BackupDeletionPolicy string
+
+const (
+	// BackupDeletionRetain leaves the snapshot on the storage.
+	BackupDeletionRetain BackupDeletionPolicy = "Retain"
+	// BackupDeletionDelete removes the snapshot from the storage with a
+	// cleanup Job before the SeaweedBackup goes away.
+	BackupDeletionDelete BackupDeletionPolicy = "Delete"
+)
+
 // Condition types emitted by the SeaweedBackup controller.
 const (
 	// BackupConditionComplete is True once the snapshot Job succeeds.
@@ -43,6 +58,48 @@ const (
 	// BackupConditionClusterReachable reports whether the referenced Seaweed
 	// cluster and its backup storage configuration were resolvable.
 	BackupConditionClusterReachable = "ClusterReachable"
+	// BackupConditionReferenceGranted is set False (reason
+	// ReferenceGrantMissing) while a required cross-namespace
+	// ResourceReferenceGrant is absent, and True (reason Granted) once one
+	// permits the reference. Same-namespace references do not carry it.
+	BackupConditionReferenceGranted = "ReferenceGranted"
+	// BackupConditionArtifactDeleted is set while a deleted SeaweedBackup
+	// with deletionPolicy Delete waits on its cleanup Job; False reports why
+	// the artifact could not be removed yet.
+	BackupConditionArtifactDeleted = "ArtifactDeleted"
+	// BackupConditionPreHooks reports the outcome of spec.preHooks; False
+	// when one with failurePolicy Fail failed and the snapshot was skipped.
+	BackupConditionPreHooks = "PreHooks"
+	// BackupConditionPostHooks reports the outcome of spec.postHooks.
+	BackupConditionPostHooks = "PostHooks"
+	// BackupConditionFilerReadOnly is True while a filerReadOnly pre hook
+	// holds filer paths read-only, and False once they are released.
+	BackupConditionFilerReadOnly = "FilerReadOnly"
+	// BackupConditionVolumesFrozen is True while a volumeSnapshots backup
+	// holds the cluster's volumes read-only, and False once they are
+	// writable again.
+	BackupConditionVolumesFrozen = "VolumesFrozen"
+)
+
+// BackupTarget selects what a SeaweedBackup copies.
+// +kubebuilder:validation:Enum=metadata;volumes;volumeSnapshots
+type BackupTarget string
+
+const (
+	// BackupTargetMetadata snapshots the filer metadata (`fs.meta.save`).
+	BackupTargetMetadata BackupTarget = "metadata"
+	// BackupTargetVolumes copies the .dat/.idx files of the cluster's
+	// volumes. Each backup stores only what changed since the previous
+	// volumes backup of the cluster on the same storage: volumes whose
+	// compaction revision is unchanged contribute just the bytes appended
+	// since, and compacted volumes are copied whole.
+	BackupTargetVolumes BackupTarget = "volumes"
+	// BackupTargetVolumeSnapshots takes a CSI VolumeSnapshot of every
+	// PersistentVolumeClaim of the cluster's StatefulSets, with the volumes
+	// held read-only until the snapshots are cut. Holding them read-only is
+	// not a write freeze: the master may still grow new volumes, so use pre
+	// hooks to quiesce writers. Nothing is written to a backup storage.
+	BackupTargetVolumeSnapshots BackupTarget = "volumeSnapshots"
 )
 
 // SeaweedBackup labels set on generated Jobs so the scheduler can find the
@@ -56,24 +113,181 @@ const (
 )
 
 // SeaweedBackupSpec is a single, on-demand or scheduled, point-in-time filer
-// metadata snapshot (`fs.meta.save`) stored on a named backup storage.
+// metadata snapshot (`fs.meta.save`), or copy of the cluster's volumes,
+// stored on a named backup storage, or a set of VolumeSnapshots of the
+// cluster's claims.
+// +kubebuilder:validation:XValidation:rule="!has(self.collections) || (has(self.target) && self.target == 'volumes')",message="collections is only used by target volumes"
+// +kubebuilder:validation:XValidation:rule="has(self.storageName) != (has(self.target) && self.target == 'volumeSnapshots')",message="storageName is required, except by target volumeSnapshots, which stores nothing on a backup storage"
+// +kubebuilder:validation:XValidation:rule="!has(self.volumeSnapshotClassName) || (has(self.target) && self.target == 'volumeSnapshots')",message="volumeSnapshotClassName is only used by target volumeSnapshots"
 type SeaweedBackupSpec struct {
-	// ClusterName is the Seaweed CR, in the same namespace, to back up.
-	// Immutable once set.
+	// ClusterName is the Seaweed CR to back up. Immutable once set.
 	// +kubebuilder:validation:MinLength=1
 	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="clusterName is immutable"
 	ClusterName string `json:"clusterName"`
 
+	// ClusterNamespace is the namespace of the Seaweed CR. Defaults to the
+	// SeaweedBackup's own namespace. A cluster in another namespace must
+	// grant the reference with a ResourceReferenceGrant; the snapshot Job
+	// then runs in the cluster's namespace. Immutable once set.
+	// +optional
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="clusterNamespace is immutable"
+	ClusterNamespace string `json:"clusterNamespace,omitempty"`
+
 	// StorageName references a key in the cluster's spec.backup.storages.
-	// Immutable once set.
+	// Required, except by target volumeSnapshots. Immutable once set.
+	// +optional
 	// +kubebuilder:validation:MinLength=1
 	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="storageName is immutable"
-	StorageName string `json:"storageName"`
+	StorageName string `json:"storageName,omitempty"`
 
-	// FilerPath is the filer subtree to snapshot. Defaults to "/".
+	// Target selects what is backed up: "metadata" (the default) snapshots
+	// the filer metadata under FilerPath; "volumes" copies the cluster's
+	// volume files, incrementally against the previous volumes backup;
+	// "volumeSnapshots" takes VolumeSnapshots of the cluster's claims.
+	// Immutable once set.
+	// +optional
+	// +kubebuilder:default:=metadata
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="target is immutable"
+	Target BackupTarget `json:"target,omitempty"`
+
+	// FilerPath is the filer subtree to snapshot. Defaults to "/". Not used
+	// by target volumes.
 	// +optional
 	// +kubebuilder:default:="/"
 	FilerPath string `json:"filerPath,omitempty"`
+
+	// Collections limits a volumes backup to the volumes of these
+	// collections; "" names the default collection. Empty copies every
+	// volume.
+	// +optional
+	// +kubebuilder:validation:MaxItems=64
+	Collections []string `json:"collections,omitempty"`
+
+	// VolumeSnapshotClassName is the VolumeSnapshotClass of the snapshots a
+	// volumeSnapshots backup takes. Empty uses the default class of each
+	// claim's CSI driver. A class that does not exist fails the backup
+	// before anything is frozen.
+	// +optional
+	VolumeSnapshotClassName string `json:"volumeSnapshotClassName,omitempty"`
+
+	// DeletionPolicy says whether deleting this SeaweedBackup also removes
+	// its snapshot from the storage. Defaults to Retain.
+	// +optional
+	// +kubebuilder:default:=Retain
+	DeletionPolicy BackupDeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// PreHooks run in order before the snapshot Job is created.
+	// +optional
+	// +kubebuilder:validation:MaxItems=16
+	PreHooks []BackupHook `json:"preHooks,omitempty"`
+
+	// PostHooks run in order once the snapshot Job finishes, whether it
+	// succeeded or not, after any filerReadOnly pre hook is released.
+	// +optional
+	// +kubebuilder:validation:MaxItems=16
+	// +kubebuilder:validation:XValidation:rule="self.all(h, !has(h.filerReadOnly))",message="filerReadOnly hooks are only allowed in preHooks"
+	PostHooks []BackupHook `json:"postHooks,omitempty"`
+}
+
+// BackupHookFailurePolicy says what a failed backup hook does to the backup.
+// +kubebuilder:validation:Enum=Fail;Ignore
+type BackupHookFailurePolicy string
+
+const (
+	// BackupHookFail fails the backup. A failed pre hook skips the
+	// snapshot; the post hooks still run.
+	BackupHookFail BackupHookFailurePolicy = "Fail"
+	// BackupHookIgnore records the failure in the hook condition and carries
+	// on.
+	BackupHookIgnore BackupHookFailurePolicy = "Ignore"
+)
+
+// BackupHook is one action run around a snapshot. Exactly one of exec, http
+// and filerReadOnly is set.
+// +kubebuilder:validation:XValidation:rule="[has(self.exec), has(self.http), has(self.filerReadOnly)].filter(x, x).size() == 1",message="exactly one of exec, http and filerReadOnly must be set"
+type BackupHook struct {
+	// Name identifies the hook in conditions and events.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+
+	// Exec runs a command in pods of the SeaweedBackup's namespace. The
+	// operator only runs exec hooks in namespaces its
+	// --backup-exec-hook-namespaces flag lists.
+	// +optional
+	Exec *BackupExecHook `json:"exec,omitempty"`
+
+	// HTTP calls a Service in the SeaweedBackup's namespace.
+	// +optional
+	HTTP *BackupHTTPHook `json:"http,omitempty"`
+
+	// FilerReadOnly switches a filer path of the cluster read-only until
+	// the snapshot Job finishes. Only allowed in preHooks.
+	// +optional
+	FilerReadOnly *BackupFilerReadOnlyHook `json:"filerReadOnly,omitempty"`
+
+	// Timeout bounds the hook. Defaults to 30s, at most 5m: hooks run
+	// inside the operator, one after the other.
+	// +optional
+	// +kubebuilder:validation:XValidation:rule="duration(self) <= duration('5m')",message="timeout must be at most 5m"
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// FailurePolicy says whether a failed hook fails the backup. Defaults to
+	// Fail.
+	// +optional
+	// +kubebuilder:default:=Fail
+	FailurePolicy BackupHookFailurePolicy `json:"failurePolicy,omitempty"`
+}
+
+// BackupExecHook runs a command in every running pod matching a selector.
+type BackupExecHook struct {
+	// Selector picks the pods; at least one running pod must match.
+	Selector metav1.LabelSelector `json:"selector"`
+
+	// Container to run the command in. Defaults to the pod's first
+	// container.
+	// +optional
+	Container string `json:"container,omitempty"`
+
+	// Command is run without a shell; a non-zero exit fails the hook.
+	// +kubebuilder:validation:MinItems=1
+	Command []string `json:"command"`
+}
+
+// BackupHTTPHook sends a request to a Service. Any 2xx response succeeds.
+type BackupHTTPHook struct {
+	// Service is the name of the Service, in the backup's namespace.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z]([-a-z0-9]*[a-z0-9])?$`
+	Service string `json:"service"`
+
+	// Port is the Service port.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+
+	// Path is the request path. Defaults to "/".
+	// +optional
+	// +kubebuilder:default:="/"
+	// +kubebuilder:validation:Pattern=`^/`
+	Path string `json:"path,omitempty"`
+
+	// Method is the request method. Defaults to POST.
+	// +optional
+	// +kubebuilder:default:=POST
+	// +kubebuilder:validation:Enum=GET;POST;PUT
+	Method string `json:"method,omitempty"`
+}
+
+// BackupFilerReadOnlyHook quiesces writes to a filer path with a readOnly
+// filer.conf rule, as `fs.configure -readOnly` sets.
+type BackupFilerReadOnlyHook struct {
+	// Path is the filer path to switch read-only. Defaults to "/".
+	// +optional
+	// +kubebuilder:default:="/"
+	// +kubebuilder:validation:Pattern=`^/`
+	Path string `json:"path,omitempty"`
 }
 
 // SeaweedBackupStatus reflects the observed state of a backup.
@@ -90,10 +304,68 @@ type SeaweedBackupStatus struct {
 	// +optional
 	JobName string `json:"jobName,omitempty"`
 
-	// Destination is the resolved location of the metadata snapshot.
+	// JobNamespace is the namespace of the snapshot Job: the cluster's
+	// namespace.
+	// +optional
+	JobNamespace string `json:"jobNamespace,omitempty"`
+
+	// Destination is the resolved location of the metadata snapshot, or of
+	// the manifest of a volumes backup.
 	// +optional
 	Destination string `json:"destination,omitempty"`
 
+	// Size is the stored snapshot's size in bytes; for a volumes backup, the
+	// size of the volume files it describes.
+	// +optional
+	Size int64 `json:"size,omitempty"`
+
+	// Checksum is the stored snapshot's digest as "sha256:<hex>", as written
+	// to its manifest; for a volumes backup, the manifest's digest. Restores
+	// check the snapshot against it.
+	// +optional
+	Checksum string `json:"checksum,omitempty"`
+
+	// Manifest is the location of the manifest written next to the snapshot.
+	// +optional
+	Manifest string `json:"manifest,omitempty"`
+
+	// Encryption names the scheme the snapshot is encrypted with; empty when
+	// it is not.
+	// +optional
+	Encryption string `json:"encryption,omitempty"`
+
+	// Volumes describes what a volumes backup copied.
+	// +optional
+	Volumes *BackupVolumesStatus `json:"volumes,omitempty"`
+
+	// VolumeSnapshots are the VolumeSnapshots a volumeSnapshots backup took,
+	// in the cluster's namespace.
+	// +optional
+	VolumeSnapshots []BackupVolumeSnapshot `json:"volumeSnapshots,omitempty"`
+
+	// ClusterSpec is the cluster's spec when a volumeSnapshots backup was
+	// taken; a restore creates the new cluster from it. Its schema is
+	// preserved as opaque, as the Seaweed CRD validates it.
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
+	ClusterSpec *SeaweedSpec `json:"clusterSpec,omitempty"`
+
+	// Verification is the outcome of the latest BackupVerification restore
+	// drill of this backup.
+	// +optional
+	Verification *BackupVerificationResult `json:"verification,omitempty"`
+
+	// ReadOnlyPaths are the filer.conf location prefixes filerReadOnly pre
+	// hooks switched read-only and that have not been released yet.
+	// +optional
+	ReadOnlyPaths []string `json:"readOnlyPaths,omitempty"`
+
+	// FrozenVolumes are the ids of the volumes a volumeSnapshots backup
+	// marked read-only and has not made writable again.
+	// +optional
+	FrozenVolumes []uint32 `json:"frozenVolumes,omitempty"`
+
 	// StartTime is when the snapshot Job started.
 	// +optional
 	StartTime *metav1.Time `json:"startTime,omitempty"`
@@ -111,17 +383,99 @@ type SeaweedBackupStatus struct {
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 }
 
+// BackupVolumesStatus counts the volumes a volumes backup copied.
+type BackupVolumesStatus struct {
+	// Base is the SeaweedBackup the copy is incremental against; empty for
+	// a full copy.
+	// +optional
+	Base string `json:"base,omitempty"`
+
+	// Count is the number of volumes in the backup.
+	// +optional
+	Count int32 `json:"count,omitempty"`
+
+	// Uploaded is the number of bytes this backup stored; the rest is shared
+	// with its base.
+	// +optional
+	Uploaded int64 `json:"uploaded,omitempty"`
+
+	// Skipped is the number of volumes that could not be copied: erasure
+	// coded volumes and volumes moved to a remote tier.
+	// +optional
+	Skipped int32 `json:"skipped,omitempty"`
+}
+
+// BackupVolumeSnapshot is a VolumeSnapshot of one claim of the cluster.
+type BackupVolumeSnapshot struct {
+	// Name is the VolumeSnapshot's name.
+	Name string `json:"name"`
+
+	// ClaimName is the PersistentVolumeClaim the snapshot was taken of.
+	ClaimName string `json:"claimName"`
+
+	// StatefulSet, Template and Ordinal place the claim: it was created from
+	// claim template Template for replica Ordinal of StatefulSet.
+	StatefulSet string `json:"statefulSet"`
+	Template    string `json:"template"`
+	Ordinal     int32  `json:"ordinal"`
+
+	// StorageClassName, AccessModes and Size are the claim's, for the claim
+	// a restore provisions from the snapshot.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+	// +optional
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// RestoreSize is the smallest claim the snapshot restores into, once
+	// the CSI driver reports it.
+	// +optional
+	RestoreSize *resource.Quantity `json:"restoreSize,omitempty"`
+
+	// ReadyToUse is true once claims can be provisioned from the snapshot.
+	// +optional
+	ReadyToUse bool `json:"readyToUse,omitempty"`
+}
+
+// BackupVerificationResult summarises a finished restore drill of a backup.
+type BackupVerificationResult struct {
+	// Name is the BackupVerification that ran the drill.
+	Name string `json:"name"`
+
+	// Phase is Passed or Failed.
+	Phase VerificationPhase `json:"phase"`
+
+	// Time is when the drill finished.
+	Time metav1.Time `json:"time"`
+
+	// Duration is how long the drill took.
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// Entries is the number of filer entries the restore produced.
+	// +optional
+	Entries int64 `json:"entries,omitempty"`
+
+	// Message says why the drill failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:shortName=swbk,categories=seaweedfs
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.clusterName`
 // +kubebuilder:printcolumn:name="Storage",type=string,JSONPath=`.spec.storageName`
+// +kubebuilder:printcolumn:name="Target",type=string,JSONPath=`.spec.target`,priority=1
 // +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
 // +kubebuilder:printcolumn:name="Completed",type=date,JSONPath=`.status.completionTime`
+// +kubebuilder:printcolumn:name="Verified",type=string,JSONPath=`.status.verification.phase`,priority=1
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
-// SeaweedBackup is a point-in-time filer metadata snapshot of a Seaweed
-// cluster, stored on one of the cluster's configured backup storages.
+// SeaweedBackup is a point-in-time filer metadata snapshot, or copy of the
+// volumes, of a Seaweed cluster, stored on one of the cluster's configured
+// backup storages, or a set of VolumeSnapshots of the cluster's claims.
 type SeaweedBackup struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
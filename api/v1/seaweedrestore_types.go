@@ -38,8 +38,73 @@ const (
 	// RestoreConditionSourceResolved reports whether the backup source
 	// (a SeaweedBackup or an explicit BackupSource) was resolvable.
 	RestoreConditionSourceResolved = "SourceResolved"
+	// RestoreConditionReferenceGranted is set False (reason
+	// ReferenceGrantMissing) while a required cross-namespace
+	// ResourceReferenceGrant is absent, and True (reason Granted) once one
+	// permits the reference. Same-namespace references do not carry it.
+	RestoreConditionReferenceGranted = "ReferenceGranted"
+	// RestoreConditionVerified reports whether the snapshot matched its
+	// manifest and recorded checksum, and decrypted, before it was loaded.
+	// False with reason IntegrityCheckFailed means the snapshot is corrupt or
+	// has been tampered with.
+	RestoreConditionVerified = "Verified"
+	// RestoreConditionReseeded reports, for a full restore, whether file
+	// content was copied back from the data mirror. True means the reseed
+	// finished; files the mirror had no copy of are counted in
+	// status.reseed.
+	RestoreConditionReseeded = "Reseeded"
 )
 
+// RestoreMode selects what a SeaweedRestore restores.
+// +kubebuilder:validation:Enum=metadata;full;volumes;volumeSnapshots
+type RestoreMode string
+
+const (
+	// RestoreModeMetadata loads the metadata snapshot only. File content is
+	// read from the volumes the snapshot's chunks reference, so this suits
+	// restoring into the cluster the snapshot was taken of.
+	RestoreModeMetadata RestoreMode = "metadata"
+	// RestoreModeFull loads the metadata snapshot, then copies the content
+	// of every file whose chunks are on volumes the cluster does not have
+	// back from the data mirror (spec.backup.dataMirror) on the storage.
+	RestoreModeFull RestoreMode = "full"
+	// RestoreModeVolumes restores the volume files of a SeaweedBackup with
+	// target volumes: the volumes the cluster does not have are assembled
+	// from the backup, served by a temporary volume server in the restore
+	// Job and moved onto the cluster's volume servers with
+	// `volumeServer.evacuate`.
+	RestoreModeVolumes RestoreMode = "volumes"
+	// RestoreModeVolumeSnapshots creates ClusterName as a new Seaweed from
+	// a SeaweedBackup with target volumeSnapshots: each claim the new
+	// cluster's StatefulSets will use is provisioned first from the
+	// VolumeSnapshot of its counterpart, then the Seaweed is created with
+	// the spec the backup recorded.
+	RestoreModeVolumeSnapshots RestoreMode = "volumeSnapshots"
+)
+
+// RestoreDataMirror locates the `weed filer.backup` sink a full restore
+// copies file content back from.
+type RestoreDataMirror struct {
+	// StorageName references a key in the target cluster's
+	// spec.backup.storages holding the mirror. Defaults to the snapshot's
+	// storage.
+	// +optional
+	StorageName string `json:"storageName,omitempty"`
+
+	// ClusterName is the cluster whose mirror wrote the sink, which is
+	// stored under its name. Defaults to the cluster the snapshot was taken
+	// of.
+	// +optional
+	ClusterName string `json:"clusterName,omitempty"`
+
+	// FilerPath is the filer subtree the mirror copied (its
+	// dataMirror.filerPath). Only files under it are reseeded. Defaults to
+	// "/".
+	// +optional
+	// +kubebuilder:default:="/"
+	FilerPath string `json:"filerPath,omitempty"`
+}
+
 // BackupSource locates a metadata snapshot directly, for restoring from a
 // backup whose SeaweedBackup CR no longer exists (or was taken by another
 // cluster).
@@ -50,21 +115,38 @@ type BackupSource struct {
 
 	// MetaPath is the snapshot location within the storage, relative to the
 	// storage's directory/mount root (e.g. "<cluster>/<backup>/filer.meta.gz").
+	// For mode volumes it is the backup's manifest
+	// ("<cluster>/volumes/<backup>/manifest.json").
 	// +kubebuilder:validation:MinLength=1
 	MetaPath string `json:"metaPath"`
 }
 
 // SeaweedRestoreSpec restores a filer metadata snapshot into a Seaweed cluster
-// via `fs.meta.load`. Exactly one of BackupName / BackupSource must be set.
+// via `fs.meta.load`, and in mode full its file content from a data mirror.
+// At most one of BackupName / BackupSource may be set, and one is required
+// unless PointInTime picks the snapshot.
 //
-// +kubebuilder:validation:XValidation:rule="has(self.backupName) != has(self.backupSource)",message="exactly one of backupName or backupSource must be set"
+// +kubebuilder:validation:XValidation:rule="!(has(self.backupName) && has(self.backupSource))",message="backupName and backupSource are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="has(self.backupName) || has(self.backupSource) || has(self.pointInTime)",message="one of backupName, backupSource or pointInTime must be set"
+// +kubebuilder:validation:XValidation:rule="!(has(self.pointInTime) && has(self.backupSource))",message="pointInTime needs a SeaweedBackup as its base snapshot and cannot be combined with backupSource"
+// +kubebuilder:validation:XValidation:rule="!has(self.dataMirror) || (has(self.mode) && self.mode == 'full')",message="dataMirror is only used by mode full"
+// +kubebuilder:validation:XValidation:rule="!has(self.pointInTime) || !has(self.mode) || !(self.mode in ['volumes', 'volumeSnapshots'])",message="pointInTime replays metadata and cannot be combined with mode volumes or volumeSnapshots"
+// +kubebuilder:validation:XValidation:rule="!has(self.mode) || self.mode != 'volumeSnapshots' || has(self.backupName)",message="mode volumeSnapshots restores a SeaweedBackup and needs backupName"
 type SeaweedRestoreSpec struct {
-	// ClusterName is the Seaweed CR, in the same namespace, to restore into.
-	// Immutable once set.
+	// ClusterName is the Seaweed CR to restore into; mode volumeSnapshots
+	// creates it. Immutable once set.
 	// +kubebuilder:validation:MinLength=1
 	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="clusterName is immutable"
 	ClusterName string `json:"clusterName"`
 
+	// ClusterNamespace is the namespace of the Seaweed CR. Defaults to the
+	// SeaweedRestore's own namespace. A cluster in another namespace must
+	// grant the reference with a ResourceReferenceGrant; the restore Job
+	// then runs in the cluster's namespace. Immutable once set.
+	// +optional
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="clusterNamespace is immutable"
+	ClusterNamespace string `json:"clusterNamespace,omitempty"`
+
 	// BackupName references a SeaweedBackup in the same namespace to restore.
 	// +optional
 	BackupName string `json:"backupName,omitempty"`
@@ -78,6 +160,91 @@ type SeaweedRestoreSpec struct {
 	// +optional
 	// +kubebuilder:default:="/"
 	FilerPath string `json:"filerPath,omitempty"`
+
+	// PointInTime restores the filer as it was at this instant: the base
+	// snapshot is loaded, then the storage's metadata log
+	// (spec.backup.metadataLog) is replayed from the snapshot's start up to
+	// PointInTime. The base snapshot is BackupName when set, otherwise the
+	// latest Completed SeaweedBackup of the cluster started at or before
+	// PointInTime on a storage with a metadata log. The restore waits, for
+	// about a segment interval, until the log holds every event up to
+	// PointInTime before loading anything, and fails with reason
+	// MetaLogIncomplete when it does not; on an idle filer the log reaches an
+	// instant only with the next event after it. Immutable once set.
+	// +optional
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="pointInTime is immutable"
+	PointInTime *metav1.Time `json:"pointInTime,omitempty"`
+
+	// Mode selects what is restored: "metadata" (the default) loads the
+	// snapshot only; "full" also reseeds file content from the data mirror,
+	// for restoring into a cluster that does not have the snapshot's
+	// volumes, such as a fresh cluster after losing the original; "volumes"
+	// restores the volume files of a volumes backup; "volumeSnapshots"
+	// creates a new cluster from a volumeSnapshots backup. Immutable once
+	// set.
+	// +optional
+	// +kubebuilder:default:=metadata
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="mode is immutable"
+	Mode RestoreMode `json:"mode,omitempty"`
+
+	// DataMirror locates the mirror a full restore reseeds from. Defaults
+	// to the mirror of the snapshot's cluster on the snapshot's storage.
+	// +optional
+	DataMirror *RestoreDataMirror `json:"dataMirror,omitempty"`
+}
+
+// RestoreReseedStatus counts what a full restore copied back from the data
+// mirror.
+type RestoreReseedStatus struct {
+	// Files is the number of files whose content was copied back.
+	// +optional
+	Files int64 `json:"files,omitempty"`
+
+	// Bytes is the content copied back, in bytes.
+	// +optional
+	Bytes int64 `json:"bytes,omitempty"`
+
+	// Present is the number of files whose chunks the cluster already had.
+	// +optional
+	Present int64 `json:"present,omitempty"`
+
+	// Missing is the number of files with lost chunks the mirror had no
+	// copy of; they stay unreadable.
+	// +optional
+	Missing int64 `json:"missing,omitempty"`
+
+	// MissingPaths lists some of them.
+	// +optional
+	MissingPaths []string `json:"missingPaths,omitempty"`
+}
+
+// RestoreVolumesStatus counts what a volumes restore put back.
+type RestoreVolumesStatus struct {
+	// Restored is the number of volumes copied back onto the cluster's
+	// volume servers.
+	// +optional
+	Restored int32 `json:"restored,omitempty"`
+
+	// Bytes is the size of their files, in bytes.
+	// +optional
+	Bytes int64 `json:"bytes,omitempty"`
+
+	// Present is the number of volumes in the backup the cluster already
+	// had; they are left alone.
+	// +optional
+	Present int32 `json:"present,omitempty"`
+}
+
+// RestoreSnapshotsStatus counts the claims a volumeSnapshots restore
+// provisioned.
+type RestoreSnapshotsStatus struct {
+	// Claims is the number of claims provisioned from VolumeSnapshots.
+	// +optional
+	Claims int32 `json:"claims,omitempty"`
+
+	// Bound is the number of them bound to a volume.
+	// +optional
+	Bound int32 `json:"bound,omitempty"`
 }
 
 // SeaweedRestoreStatus reflects the observed state of a restore.
@@ -94,6 +261,28 @@ type SeaweedRestoreStatus struct {
 	// +optional
 	JobName string `json:"jobName,omitempty"`
 
+	// JobNamespace is the namespace of the restore Job: the cluster's
+	// namespace.
+	// +optional
+	JobNamespace string `json:"jobNamespace,omitempty"`
+
+	// BaseBackup is the SeaweedBackup whose snapshot a point-in-time restore
+	// loads before replaying the metadata log.
+	// +optional
+	BaseBackup string `json:"baseBackup,omitempty"`
+
+	// Reseed is what a full restore copied back from the data mirror.
+	// +optional
+	Reseed *RestoreReseedStatus `json:"reseed,omitempty"`
+
+	// Volumes is what a volumes restore put back.
+	// +optional
+	Volumes *RestoreVolumesStatus `json:"volumes,omitempty"`
+
+	// Snapshots is what a volumeSnapshots restore provisioned.
+	// +optional
+	Snapshots *RestoreSnapshotsStatus `json:"snapshots,omitempty"`
+
 	// StartTime is when the restore Job started.
 	// +optional
 	StartTime *metav1.Time `json:"startTime,omitempty"`
@@ -116,7 +305,10 @@ type SeaweedRestoreStatus struct {
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.clusterName`
 // +kubebuilder:printcolumn:name="Backup",type=string,JSONPath=`.spec.backupName`
+// +kubebuilder:printcolumn:name="Mode",type=string,JSONPath=`.spec.mode`,priority=1
 // +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Files",type=integer,JSONPath=`.status.reseed.files`,priority=1
+// +kubebuilder:printcolumn:name="Bytes",type=integer,JSONPath=`.status.reseed.bytes`,priority=1
 // +kubebuilder:printcolumn:name="Completed",type=date,JSONPath=`.status.completionTime`
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
@@ -0,0 +1,183 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SFTPPermission is one action an SFTP user may perform under a path.
+// +kubebuilder:validation:Enum=read;write;list;delete;mkdir;traverse;readwrite;all
+type SFTPPermission string
+
+const (
+	SFTPPermissionRead      SFTPPermission = "read"
+	SFTPPermissionWrite     SFTPPermission = "write"
+	SFTPPermissionList      SFTPPermission = "list"
+	SFTPPermissionDelete    SFTPPermission = "delete"
+	SFTPPermissionMkdir     SFTPPermission = "mkdir"
+	SFTPPermissionTraverse  SFTPPermission = "traverse"
+	SFTPPermissionReadWrite SFTPPermission = "readwrite"
+	// SFTPPermissionAll grants every action under the path.
+	SFTPPermissionAll SFTPPermission = "all"
+)
+
+// SFTPUserPathPermission grants actions under one filer path.
+type SFTPUserPathPermission struct {
+	// Path is an absolute filer path. The grant covers the path and
+	// everything below it.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=1024
+	// +kubebuilder:validation:XValidation:rule="self.startsWith('/') && !self.contains('..')",message="path must be absolute and must not contain '..'"
+	Path string `json:"path"`
+
+	// Actions are the operations allowed under Path.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=8
+	// +listType=set
+	Actions []SFTPPermission `json:"actions"`
+}
+
+// SFTPUserSpec defines a user of a Seaweed cluster's SFTP gateway.
+//
+// The operator aggregates every SFTPUser that references a cluster into the
+// gateway's generated user store, so it only takes effect while the cluster
+// runs an SFTP gateway without spec.sftp.userStoreSecret.
+// +kubebuilder:validation:XValidation:rule="has(self.passwordSecret) || has(self.authorizedKeysSecret)",message="at least one of passwordSecret or authorizedKeysSecret must be set"
+type SFTPUserSpec struct {
+	// SeaweedRef points at the Seaweed cluster whose SFTP gateway serves
+	// this user. A cluster in another namespace must grant the reference
+	// with a ResourceReferenceGrant.
+	// +kubebuilder:validation:Required
+	SeaweedRef SeaweedReference `json:"seaweedRef"`
+
+	// Username is the SFTP login name. Usernames are global to the
+	// gateway: the oldest SFTPUser claiming a name owns it, and later
+	// claimants are marked Failed with a Conflict condition.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=64
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9._][a-zA-Z0-9._-]*$`
+	Username string `json:"username"`
+
+	// PasswordSecret selects the key of a Secret in this namespace that
+	// holds the user's password.
+	// +optional
+	PasswordSecret *corev1.SecretKeySelector `json:"passwordSecret,omitempty"`
+
+	// AuthorizedKeysSecret selects the key of a Secret in this namespace
+	// that holds the user's public keys, one per line in OpenSSH
+	// authorized_keys format.
+	// +optional
+	AuthorizedKeysSecret *corev1.SecretKeySelector `json:"authorizedKeysSecret,omitempty"`
+
+	// HomeDir is the user's home directory on the filer. The operator
+	// creates it when missing, owned by UID/GID; it is left in place when
+	// the SFTPUser is deleted. Defaults to /home/<username>.
+	// +optional
+	// +kubebuilder:validation:MaxLength=1024
+	// +kubebuilder:validation:XValidation:rule="self.startsWith('/') && !self.contains('..')",message="homeDir must be absolute and must not contain '..'"
+	HomeDir string `json:"homeDir,omitempty"`
+
+	// Permissions grant actions per filer path. Defaults to all actions
+	// under HomeDir.
+	// +optional
+	// +listType=map
+	// +listMapKey=path
+	// +kubebuilder:validation:MaxItems=32
+	Permissions []SFTPUserPathPermission `json:"permissions,omitempty"`
+
+	// UID is the numeric user ID files are created with.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	UID *int32 `json:"uid,omitempty"`
+
+	// GID is the numeric group ID files are created with.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	GID *int32 `json:"gid,omitempty"`
+}
+
+// Condition types emitted by the SFTP user controller.
+const (
+	// SFTPUserConditionReady reports whether the user is in the gateway's
+	// user store with its home directory in place.
+	SFTPUserConditionReady = "Ready"
+	// SFTPUserConditionConflict is set when another SFTPUser already owns
+	// the username on the same cluster.
+	SFTPUserConditionConflict = "Conflict"
+)
+
+// SFTPUserStatus reflects the observed state of an SFTP user.
+type SFTPUserStatus struct {
+	// ObservedGeneration is the .metadata.generation last reconciled.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Phase is a coarse summary of the user's lifecycle.
+	// +optional
+	Phase S3Phase `json:"phase,omitempty"`
+
+	// Conditions are the structured per-aspect state signals.
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// HomeDir echoes the resolved home directory.
+	// +optional
+	HomeDir string `json:"homeDir,omitempty"`
+
+	// UserStoreSecret names the generated user store Secret, in the
+	// cluster's namespace, that carries this user.
+	// +optional
+	UserStoreSecret string `json:"userStoreSecret,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=sftpu,categories=seaweedfs
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.seaweedRef.name`
+// +kubebuilder:printcolumn:name="User",type=string,JSONPath=`.spec.username`
+// +kubebuilder:printcolumn:name="Home",type=string,JSONPath=`.status.homeDir`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// SFTPUser is the Schema for declaratively provisioning a user of a Seaweed
+// cluster's SFTP gateway.
+type SFTPUser struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SFTPUserSpec   `json:"spec,omitempty"`
+	Status SFTPUserStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SFTPUserList contains a list of SFTPUser.
+type SFTPUserList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SFTPUser `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SFTPUser{}, &SFTPUserList{})
+}
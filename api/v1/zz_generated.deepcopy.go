@@ -27,6 +27,109 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSSQSNotification) DeepCopyInto(out *AWSSQSNotification) {
+	*out = *in
+	if in.CredentialsSecret != nil {
+		in, out := &in.CredentialsSecret, &out.CredentialsSecret
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSSQSNotification.
+func (in *AWSSQSNotification) DeepCopy() *AWSSQSNotification {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSSQSNotification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdminBalanceTask) DeepCopyInto(out *AdminBalanceTask) {
+	*out = *in
+	in.AdminTaskPolicy.DeepCopyInto(&out.AdminTaskPolicy)
+	if in.ImbalanceThresholdPercent != nil {
+		in, out := &in.ImbalanceThresholdPercent, &out.ImbalanceThresholdPercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MinServerCount != nil {
+		in, out := &in.MinServerCount, &out.MinServerCount
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdminBalanceTask.
+func (in *AdminBalanceTask) DeepCopy() *AdminBalanceTask {
+	if in == nil {
+		return nil
+	}
+	out := new(AdminBalanceTask)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdminErasureCodingTask) DeepCopyInto(out *AdminErasureCodingTask) {
+	*out = *in
+	in.AdminTaskPolicy.DeepCopyInto(&out.AdminTaskPolicy)
+	if in.FullnessPercent != nil {
+		in, out := &in.FullnessPercent, &out.FullnessPercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.QuietFor != nil {
+		in, out := &in.QuietFor, &out.QuietFor
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MinVolumeSize != nil {
+		in, out := &in.MinVolumeSize, &out.MinVolumeSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.CollectionFilter != nil {
+		in, out := &in.CollectionFilter, &out.CollectionFilter
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdminErasureCodingTask.
+func (in *AdminErasureCodingTask) DeepCopy() *AdminErasureCodingTask {
+	if in == nil {
+		return nil
+	}
+	out := new(AdminErasureCodingTask)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdminReplicationTask) DeepCopyInto(out *AdminReplicationTask) {
+	*out = *in
+	in.AdminTaskPolicy.DeepCopyInto(&out.AdminTaskPolicy)
+	if in.TargetReplicaCount != nil {
+		in, out := &in.TargetReplicaCount, &out.TargetReplicaCount
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdminReplicationTask.
+func (in *AdminReplicationTask) DeepCopy() *AdminReplicationTask {
+	if in == nil {
+		return nil
+	}
+	out := new(AdminReplicationTask)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AdminScript) DeepCopyInto(out *AdminScript) {
 	*out = *in
@@ -242,6 +345,11 @@ func (in *AdminSpec) DeepCopyInto(out *AdminSpec) {
 		*out = new(IngressSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Tasks != nil {
+		in, out := &in.Tasks, &out.Tasks
+		*out = new(AdminTasksSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdminSpec.
@@ -255,292 +363,938 @@ func (in *AdminSpec) DeepCopy() *AdminSpec {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *AzureBackupStore) DeepCopyInto(out *AzureBackupStore) {
+func (in *AdminTaskPolicy) DeepCopyInto(out *AdminTaskPolicy) {
 	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.MaxConcurrent != nil {
+		in, out := &in.MaxConcurrent, &out.MaxConcurrent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RepeatInterval != nil {
+		in, out := &in.RepeatInterval, &out.RepeatInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.CheckInterval != nil {
+		in, out := &in.CheckInterval, &out.CheckInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AzureBackupStore.
-func (in *AzureBackupStore) DeepCopy() *AzureBackupStore {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdminTaskPolicy.
+func (in *AdminTaskPolicy) DeepCopy() *AdminTaskPolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(AzureBackupStore)
+	out := new(AdminTaskPolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *B2BackupStore) DeepCopyInto(out *B2BackupStore) {
+func (in *AdminTasksSpec) DeepCopyInto(out *AdminTasksSpec) {
 	*out = *in
+	if in.Vacuum != nil {
+		in, out := &in.Vacuum, &out.Vacuum
+		*out = new(AdminVacuumTask)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ErasureCoding != nil {
+		in, out := &in.ErasureCoding, &out.ErasureCoding
+		*out = new(AdminErasureCodingTask)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Balance != nil {
+		in, out := &in.Balance, &out.Balance
+		*out = new(AdminBalanceTask)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Replication != nil {
+		in, out := &in.Replication, &out.Replication
+		*out = new(AdminReplicationTask)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new B2BackupStore.
-func (in *B2BackupStore) DeepCopy() *B2BackupStore {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdminTasksSpec.
+func (in *AdminTasksSpec) DeepCopy() *AdminTasksSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(B2BackupStore)
+	out := new(AdminTasksSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *BackupMirrorSpec) DeepCopyInto(out *BackupMirrorSpec) {
+func (in *AdminVacuumTask) DeepCopyInto(out *AdminVacuumTask) {
 	*out = *in
+	in.AdminTaskPolicy.DeepCopyInto(&out.AdminTaskPolicy)
+	if in.GarbageThresholdPercent != nil {
+		in, out := &in.GarbageThresholdPercent, &out.GarbageThresholdPercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MinVolumeAge != nil {
+		in, out := &in.MinVolumeAge, &out.MinVolumeAge
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MinInterval != nil {
+		in, out := &in.MinInterval, &out.MinInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupMirrorSpec.
-func (in *BackupMirrorSpec) DeepCopy() *BackupMirrorSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdminVacuumTask.
+func (in *AdminVacuumTask) DeepCopy() *AdminVacuumTask {
 	if in == nil {
 		return nil
 	}
-	out := new(BackupMirrorSpec)
+	out := new(AdminVacuumTask)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *BackupMirrorStatus) DeepCopyInto(out *BackupMirrorStatus) {
+func (in *AzureBackupStore) DeepCopyInto(out *AzureBackupStore) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupMirrorStatus.
-func (in *BackupMirrorStatus) DeepCopy() *BackupMirrorStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AzureBackupStore.
+func (in *AzureBackupStore) DeepCopy() *AzureBackupStore {
 	if in == nil {
 		return nil
 	}
-	out := new(BackupMirrorStatus)
+	out := new(AzureBackupStore)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *BackupScheduleSpec) DeepCopyInto(out *BackupScheduleSpec) {
+func (in *B2BackupStore) DeepCopyInto(out *B2BackupStore) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupScheduleSpec.
-func (in *BackupScheduleSpec) DeepCopy() *BackupScheduleSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new B2BackupStore.
+func (in *B2BackupStore) DeepCopy() *B2BackupStore {
 	if in == nil {
 		return nil
 	}
-	out := new(BackupScheduleSpec)
+	out := new(B2BackupStore)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *BackupSource) DeepCopyInto(out *BackupSource) {
+func (in *BackupEncryptionSpec) DeepCopyInto(out *BackupEncryptionSpec) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupSource.
-func (in *BackupSource) DeepCopy() *BackupSource {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupEncryptionSpec.
+func (in *BackupEncryptionSpec) DeepCopy() *BackupEncryptionSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(BackupSource)
+	out := new(BackupEncryptionSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *BackupSpec) DeepCopyInto(out *BackupSpec) {
+func (in *BackupExecHook) DeepCopyInto(out *BackupExecHook) {
 	*out = *in
-	if in.Image != nil {
-		in, out := &in.Image, &out.Image
-		*out = new(string)
-		**out = **in
-	}
-	if in.Storages != nil {
-		in, out := &in.Storages, &out.Storages
-		*out = make(map[string]BackupStorageSpec, len(*in))
-		for key, val := range *in {
-			(*out)[key] = *val.DeepCopy()
-		}
-	}
-	if in.Schedule != nil {
-		in, out := &in.Schedule, &out.Schedule
-		*out = make([]BackupScheduleSpec, len(*in))
-		copy(*out, *in)
-	}
-	if in.DataMirror != nil {
-		in, out := &in.DataMirror, &out.DataMirror
-		*out = make([]BackupMirrorSpec, len(*in))
+	in.Selector.DeepCopyInto(&out.Selector)
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupSpec.
-func (in *BackupSpec) DeepCopy() *BackupSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupExecHook.
+func (in *BackupExecHook) DeepCopy() *BackupExecHook {
 	if in == nil {
 		return nil
 	}
-	out := new(BackupSpec)
+	out := new(BackupExecHook)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *BackupStorageSpec) DeepCopyInto(out *BackupStorageSpec) {
+func (in *BackupFilerReadOnlyHook) DeepCopyInto(out *BackupFilerReadOnlyHook) {
 	*out = *in
-	if in.S3 != nil {
-		in, out := &in.S3, &out.S3
-		*out = new(S3BackupStore)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.GCS != nil {
-		in, out := &in.GCS, &out.GCS
-		*out = new(GCSBackupStore)
-		**out = **in
-	}
-	if in.Azure != nil {
-		in, out := &in.Azure, &out.Azure
-		*out = new(AzureBackupStore)
-		**out = **in
-	}
-	if in.B2 != nil {
-		in, out := &in.B2, &out.B2
-		*out = new(B2BackupStore)
-		**out = **in
-	}
-	if in.Filesystem != nil {
-		in, out := &in.Filesystem, &out.Filesystem
-		*out = new(FilesystemBackupStore)
-		**out = **in
-	}
-	if in.CredentialsSecret != nil {
-		in, out := &in.CredentialsSecret, &out.CredentialsSecret
-		*out = new(string)
-		**out = **in
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupStorageSpec.
-func (in *BackupStorageSpec) DeepCopy() *BackupStorageSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupFilerReadOnlyHook.
+func (in *BackupFilerReadOnlyHook) DeepCopy() *BackupFilerReadOnlyHook {
 	if in == nil {
 		return nil
 	}
-	out := new(BackupStorageSpec)
+	out := new(BackupFilerReadOnlyHook)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Bucket) DeepCopyInto(out *Bucket) {
+func (in *BackupHTTPHook) DeepCopyInto(out *BackupHTTPHook) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Bucket.
-func (in *Bucket) DeepCopy() *Bucket {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupHTTPHook.
+func (in *BackupHTTPHook) DeepCopy() *BackupHTTPHook {
 	if in == nil {
 		return nil
 	}
-	out := new(Bucket)
+	out := new(BackupHTTPHook)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Bucket) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *BucketAccessGrant) DeepCopyInto(out *BucketAccessGrant) {
+func (in *BackupHook) DeepCopyInto(out *BackupHook) {
 	*out = *in
-	if in.Actions != nil {
-		in, out := &in.Actions, &out.Actions
-		*out = make([]BucketAccessAction, len(*in))
-		copy(*out, *in)
+	if in.Exec != nil {
+		in, out := &in.Exec, &out.Exec
+		*out = new(BackupExecHook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = new(BackupHTTPHook)
+		**out = **in
+	}
+	if in.FilerReadOnly != nil {
+		in, out := &in.FilerReadOnly, &out.FilerReadOnly
+		*out = new(BackupFilerReadOnlyHook)
+		**out = **in
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketAccessGrant.
-func (in *BucketAccessGrant) DeepCopy() *BucketAccessGrant {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupHook.
+func (in *BackupHook) DeepCopy() *BackupHook {
 	if in == nil {
 		return nil
 	}
-	out := new(BucketAccessGrant)
+	out := new(BackupHook)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *BucketClusterRef) DeepCopyInto(out *BucketClusterRef) {
+func (in *BackupMetadataLogSpec) DeepCopyInto(out *BackupMetadataLogSpec) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketClusterRef.
-func (in *BucketClusterRef) DeepCopy() *BucketClusterRef {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupMetadataLogSpec.
+func (in *BackupMetadataLogSpec) DeepCopy() *BackupMetadataLogSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(BucketClusterRef)
+	out := new(BackupMetadataLogSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *BucketLifecycleAbortIncompleteMultipartUpload) DeepCopyInto(out *BucketLifecycleAbortIncompleteMultipartUpload) {
+func (in *BackupMirrorSpec) DeepCopyInto(out *BackupMirrorSpec) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketLifecycleAbortIncompleteMultipartUpload.
-func (in *BucketLifecycleAbortIncompleteMultipartUpload) DeepCopy() *BucketLifecycleAbortIncompleteMultipartUpload {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupMirrorSpec.
+func (in *BackupMirrorSpec) DeepCopy() *BackupMirrorSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(BucketLifecycleAbortIncompleteMultipartUpload)
+	out := new(BackupMirrorSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *BucketLifecycleExpiration) DeepCopyInto(out *BucketLifecycleExpiration) {
+func (in *BackupMirrorStatus) DeepCopyInto(out *BackupMirrorStatus) {
 	*out = *in
+	if in.LastSyncedTime != nil {
+		in, out := &in.LastSyncedTime, &out.LastSyncedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LagSeconds != nil {
+		in, out := &in.LagSeconds, &out.LagSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ResyncStartTime != nil {
+		in, out := &in.ResyncStartTime, &out.ResyncStartTime
+		*out = (*in).DeepCopy()
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketLifecycleExpiration.
-func (in *BucketLifecycleExpiration) DeepCopy() *BucketLifecycleExpiration {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupMirrorStatus.
+func (in *BackupMirrorStatus) DeepCopy() *BackupMirrorStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(BucketLifecycleExpiration)
+	out := new(BackupMirrorStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *BucketLifecycleNoncurrentVersionExpiration) DeepCopyInto(out *BucketLifecycleNoncurrentVersionExpiration) {
+func (in *BackupRetentionPolicy) DeepCopyInto(out *BackupRetentionPolicy) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketLifecycleNoncurrentVersionExpiration.
-func (in *BucketLifecycleNoncurrentVersionExpiration) DeepCopy() *BucketLifecycleNoncurrentVersionExpiration {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupRetentionPolicy.
+func (in *BackupRetentionPolicy) DeepCopy() *BackupRetentionPolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(BucketLifecycleNoncurrentVersionExpiration)
+	out := new(BackupRetentionPolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *BucketLifecyclePolicy) DeepCopyInto(out *BucketLifecyclePolicy) {
+func (in *BackupScheduleSpec) DeepCopyInto(out *BackupScheduleSpec) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
-}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(BackupRetentionPolicy)
+		**out = **in
+	}
+	if in.Collections != nil {
+		in, out := &in.Collections, &out.Collections
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(BackupScheduleVerification)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PreHooks != nil {
+		in, out := &in.PreHooks, &out.PreHooks
+		*out = make([]BackupHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PostHooks != nil {
+		in, out := &in.PostHooks, &out.PostHooks
+		*out = make([]BackupHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupScheduleSpec.
+func (in *BackupScheduleSpec) DeepCopy() *BackupScheduleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupScheduleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupScheduleVerification) DeepCopyInto(out *BackupScheduleVerification) {
+	*out = *in
+	if in.SamplePaths != nil {
+		in, out := &in.SamplePaths, &out.SamplePaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupScheduleVerification.
+func (in *BackupScheduleVerification) DeepCopy() *BackupScheduleVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupScheduleVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupSource) DeepCopyInto(out *BackupSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupSource.
+func (in *BackupSource) DeepCopy() *BackupSource {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupSpec) DeepCopyInto(out *BackupSpec) {
+	*out = *in
+	if in.Image != nil {
+		in, out := &in.Image, &out.Image
+		*out = new(string)
+		**out = **in
+	}
+	if in.Storages != nil {
+		in, out := &in.Storages, &out.Storages
+		*out = make(map[string]BackupStorageSpec, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.Schedule != nil {
+		in, out := &in.Schedule, &out.Schedule
+		*out = make([]BackupScheduleSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DataMirror != nil {
+		in, out := &in.DataMirror, &out.DataMirror
+		*out = make([]BackupMirrorSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.MetadataLog != nil {
+		in, out := &in.MetadataLog, &out.MetadataLog
+		*out = make([]BackupMetadataLogSpec, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupSpec.
+func (in *BackupSpec) DeepCopy() *BackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupStorageSpec) DeepCopyInto(out *BackupStorageSpec) {
+	*out = *in
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(S3BackupStore)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GCS != nil {
+		in, out := &in.GCS, &out.GCS
+		*out = new(GCSBackupStore)
+		**out = **in
+	}
+	if in.Azure != nil {
+		in, out := &in.Azure, &out.Azure
+		*out = new(AzureBackupStore)
+		**out = **in
+	}
+	if in.B2 != nil {
+		in, out := &in.B2, &out.B2
+		*out = new(B2BackupStore)
+		**out = **in
+	}
+	if in.Filesystem != nil {
+		in, out := &in.Filesystem, &out.Filesystem
+		*out = new(FilesystemBackupStore)
+		**out = **in
+	}
+	if in.CredentialsSecret != nil {
+		in, out := &in.CredentialsSecret, &out.CredentialsSecret
+		*out = new(string)
+		**out = **in
+	}
+	if in.Encryption != nil {
+		in, out := &in.Encryption, &out.Encryption
+		*out = new(BackupEncryptionSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupStorageSpec.
+func (in *BackupStorageSpec) DeepCopy() *BackupStorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupStorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupStorageStatus) DeepCopyInto(out *BackupStorageStatus) {
+	*out = *in
+	if in.LastProbeTime != nil {
+		in, out := &in.LastProbeTime, &out.LastProbeTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupStorageStatus.
+func (in *BackupStorageStatus) DeepCopy() *BackupStorageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupStorageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupVerification) DeepCopyInto(out *BackupVerification) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupVerification.
+func (in *BackupVerification) DeepCopy() *BackupVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BackupVerification) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupVerificationList) DeepCopyInto(out *BackupVerificationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BackupVerification, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupVerificationList.
+func (in *BackupVerificationList) DeepCopy() *BackupVerificationList {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupVerificationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BackupVerificationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupVerificationResult) DeepCopyInto(out *BackupVerificationResult) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupVerificationResult.
+func (in *BackupVerificationResult) DeepCopy() *BackupVerificationResult {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupVerificationResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupVerificationSpec) DeepCopyInto(out *BackupVerificationSpec) {
+	*out = *in
+	if in.SamplePaths != nil {
+		in, out := &in.SamplePaths, &out.SamplePaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupVerificationSpec.
+func (in *BackupVerificationSpec) DeepCopy() *BackupVerificationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupVerificationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupVerificationStatus) DeepCopyInto(out *BackupVerificationStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupVerificationStatus.
+func (in *BackupVerificationStatus) DeepCopy() *BackupVerificationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupVerificationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupVolumeSnapshot) DeepCopyInto(out *BackupVolumeSnapshot) {
+	*out = *in
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.AccessModes != nil {
+		in, out := &in.AccessModes, &out.AccessModes
+		*out = make([]corev1.PersistentVolumeAccessMode, len(*in))
+		copy(*out, *in)
+	}
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.RestoreSize != nil {
+		in, out := &in.RestoreSize, &out.RestoreSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupVolumeSnapshot.
+func (in *BackupVolumeSnapshot) DeepCopy() *BackupVolumeSnapshot {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupVolumeSnapshot)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupVolumesStatus) DeepCopyInto(out *BackupVolumesStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupVolumesStatus.
+func (in *BackupVolumesStatus) DeepCopy() *BackupVolumesStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupVolumesStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Bucket) DeepCopyInto(out *Bucket) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Bucket.
+func (in *Bucket) DeepCopy() *Bucket {
+	if in == nil {
+		return nil
+	}
+	out := new(Bucket)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Bucket) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketAccessGrant) DeepCopyInto(out *BucketAccessGrant) {
+	*out = *in
+	if in.Actions != nil {
+		in, out := &in.Actions, &out.Actions
+		*out = make([]BucketAccessAction, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketAccessGrant.
+func (in *BucketAccessGrant) DeepCopy() *BucketAccessGrant {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketAccessGrant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketCORSRule) DeepCopyInto(out *BucketCORSRule) {
+	*out = *in
+	if in.AllowedOrigins != nil {
+		in, out := &in.AllowedOrigins, &out.AllowedOrigins
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedMethods != nil {
+		in, out := &in.AllowedMethods, &out.AllowedMethods
+		*out = make([]BucketCORSMethod, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedHeaders != nil {
+		in, out := &in.AllowedHeaders, &out.AllowedHeaders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExposeHeaders != nil {
+		in, out := &in.ExposeHeaders, &out.ExposeHeaders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxAgeSeconds != nil {
+		in, out := &in.MaxAgeSeconds, &out.MaxAgeSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketCORSRule.
+func (in *BucketCORSRule) DeepCopy() *BucketCORSRule {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketCORSRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketClusterRef) DeepCopyInto(out *BucketClusterRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketClusterRef.
+func (in *BucketClusterRef) DeepCopy() *BucketClusterRef {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketClusterRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketErasureCoding) DeepCopyInto(out *BucketErasureCoding) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.QuietFor != nil {
+		in, out := &in.QuietFor, &out.QuietFor
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.FullnessPercent != nil {
+		in, out := &in.FullnessPercent, &out.FullnessPercent
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketErasureCoding.
+func (in *BucketErasureCoding) DeepCopy() *BucketErasureCoding {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketErasureCoding)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketLifecycleAbortIncompleteMultipartUpload) DeepCopyInto(out *BucketLifecycleAbortIncompleteMultipartUpload) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketLifecycleAbortIncompleteMultipartUpload.
+func (in *BucketLifecycleAbortIncompleteMultipartUpload) DeepCopy() *BucketLifecycleAbortIncompleteMultipartUpload {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketLifecycleAbortIncompleteMultipartUpload)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketLifecycleExpiration) DeepCopyInto(out *BucketLifecycleExpiration) {
+	*out = *in
+	if in.Date != nil {
+		in, out := &in.Date, &out.Date
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketLifecycleExpiration.
+func (in *BucketLifecycleExpiration) DeepCopy() *BucketLifecycleExpiration {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketLifecycleExpiration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketLifecycleFilter) DeepCopyInto(out *BucketLifecycleFilter) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]BucketLifecycleTag, len(*in))
+		copy(*out, *in)
+	}
+	if in.ObjectSizeGreaterThan != nil {
+		in, out := &in.ObjectSizeGreaterThan, &out.ObjectSizeGreaterThan
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ObjectSizeLessThan != nil {
+		in, out := &in.ObjectSizeLessThan, &out.ObjectSizeLessThan
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketLifecycleFilter.
+func (in *BucketLifecycleFilter) DeepCopy() *BucketLifecycleFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketLifecycleFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketLifecycleNoncurrentVersionExpiration) DeepCopyInto(out *BucketLifecycleNoncurrentVersionExpiration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketLifecycleNoncurrentVersionExpiration.
+func (in *BucketLifecycleNoncurrentVersionExpiration) DeepCopy() *BucketLifecycleNoncurrentVersionExpiration {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketLifecycleNoncurrentVersionExpiration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketLifecycleNoncurrentVersionTransition) DeepCopyInto(out *BucketLifecycleNoncurrentVersionTransition) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketLifecycleNoncurrentVersionTransition.
+func (in *BucketLifecycleNoncurrentVersionTransition) DeepCopy() *BucketLifecycleNoncurrentVersionTransition {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketLifecycleNoncurrentVersionTransition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketLifecyclePolicy) DeepCopyInto(out *BucketLifecyclePolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketLifecyclePolicy.
 func (in *BucketLifecyclePolicy) DeepCopy() *BucketLifecyclePolicy {
@@ -655,10 +1409,15 @@ func (in *BucketLifecycleRef) DeepCopy() *BucketLifecycleRef {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BucketLifecycleRule) DeepCopyInto(out *BucketLifecycleRule) {
 	*out = *in
+	if in.Filter != nil {
+		in, out := &in.Filter, &out.Filter
+		*out = new(BucketLifecycleFilter)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Expiration != nil {
 		in, out := &in.Expiration, &out.Expiration
 		*out = new(BucketLifecycleExpiration)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.NoncurrentVersionExpiration != nil {
 		in, out := &in.NoncurrentVersionExpiration, &out.NoncurrentVersionExpiration
@@ -670,6 +1429,18 @@ func (in *BucketLifecycleRule) DeepCopyInto(out *BucketLifecycleRule) {
 		*out = new(BucketLifecycleAbortIncompleteMultipartUpload)
 		**out = **in
 	}
+	if in.Transitions != nil {
+		in, out := &in.Transitions, &out.Transitions
+		*out = make([]BucketLifecycleTransition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NoncurrentVersionTransitions != nil {
+		in, out := &in.NoncurrentVersionTransitions, &out.NoncurrentVersionTransitions
+		*out = make([]BucketLifecycleNoncurrentVersionTransition, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketLifecycleRule.
@@ -682,6 +1453,45 @@ func (in *BucketLifecycleRule) DeepCopy() *BucketLifecycleRule {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketLifecycleTag) DeepCopyInto(out *BucketLifecycleTag) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketLifecycleTag.
+func (in *BucketLifecycleTag) DeepCopy() *BucketLifecycleTag {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketLifecycleTag)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketLifecycleTransition) DeepCopyInto(out *BucketLifecycleTransition) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Date != nil {
+		in, out := &in.Date, &out.Date
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketLifecycleTransition.
+func (in *BucketLifecycleTransition) DeepCopy() *BucketLifecycleTransition {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketLifecycleTransition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BucketList) DeepCopyInto(out *BucketList) {
 	*out = *in
@@ -714,6 +1524,51 @@ func (in *BucketList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketObjectLockConfiguration) DeepCopyInto(out *BucketObjectLockConfiguration) {
+	*out = *in
+	if in.DefaultRetention != nil {
+		in, out := &in.DefaultRetention, &out.DefaultRetention
+		*out = new(BucketObjectLockDefaultRetention)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketObjectLockConfiguration.
+func (in *BucketObjectLockConfiguration) DeepCopy() *BucketObjectLockConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketObjectLockConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketObjectLockDefaultRetention) DeepCopyInto(out *BucketObjectLockDefaultRetention) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Years != nil {
+		in, out := &in.Years, &out.Years
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketObjectLockDefaultRetention.
+func (in *BucketObjectLockDefaultRetention) DeepCopy() *BucketObjectLockDefaultRetention {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketObjectLockDefaultRetention)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BucketPlacement) DeepCopyInto(out *BucketPlacement) {
 	*out = *in
@@ -722,6 +1577,11 @@ func (in *BucketPlacement) DeepCopyInto(out *BucketPlacement) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.ErasureCoding != nil {
+		in, out := &in.ErasureCoding, &out.ErasureCoding
+		*out = new(BucketErasureCoding)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketPlacement.
@@ -750,10 +1610,187 @@ func (in *BucketQuota) DeepCopy() *BucketQuota {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketReplication) DeepCopyInto(out *BucketReplication) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketReplication.
+func (in *BucketReplication) DeepCopy() *BucketReplication {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketReplication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BucketReplication) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketReplicationBucketRef) DeepCopyInto(out *BucketReplicationBucketRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketReplicationBucketRef.
+func (in *BucketReplicationBucketRef) DeepCopy() *BucketReplicationBucketRef {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketReplicationBucketRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketReplicationDestination) DeepCopyInto(out *BucketReplicationDestination) {
+	*out = *in
+	if in.BucketRef != nil {
+		in, out := &in.BucketRef, &out.BucketRef
+		*out = new(BucketReplicationBucketRef)
+		**out = **in
+	}
+	if in.Remote != nil {
+		in, out := &in.Remote, &out.Remote
+		*out = new(BackupStorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketReplicationDestination.
+func (in *BucketReplicationDestination) DeepCopy() *BucketReplicationDestination {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketReplicationDestination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketReplicationList) DeepCopyInto(out *BucketReplicationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BucketReplication, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketReplicationList.
+func (in *BucketReplicationList) DeepCopy() *BucketReplicationList {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketReplicationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BucketReplicationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketReplicationSpec) DeepCopyInto(out *BucketReplicationSpec) {
+	*out = *in
+	out.SourceRef = in.SourceRef
+	in.Destination.DeepCopyInto(&out.Destination)
+	if in.ExcludePrefixes != nil {
+		in, out := &in.ExcludePrefixes, &out.ExcludePrefixes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketReplicationSpec.
+func (in *BucketReplicationSpec) DeepCopy() *BucketReplicationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketReplicationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketReplicationStatus) DeepCopyInto(out *BucketReplicationStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastCheckpointTime != nil {
+		in, out := &in.LastCheckpointTime, &out.LastCheckpointTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LagSeconds != nil {
+		in, out := &in.LagSeconds, &out.LagSeconds
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketReplicationStatus.
+func (in *BucketReplicationStatus) DeepCopy() *BucketReplicationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketReplicationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketReplicationSummary) DeepCopyInto(out *BucketReplicationSummary) {
+	*out = *in
+	if in.LagSeconds != nil {
+		in, out := &in.LagSeconds, &out.LagSeconds
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketReplicationSummary.
+func (in *BucketReplicationSummary) DeepCopy() *BucketReplicationSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketReplicationSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BucketSpec) DeepCopyInto(out *BucketSpec) {
 	*out = *in
 	out.ClusterRef = in.ClusterRef
+	if in.ObjectLockConfiguration != nil {
+		in, out := &in.ObjectLockConfiguration, &out.ObjectLockConfiguration
+		*out = new(BucketObjectLockConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Quota != nil {
 		in, out := &in.Quota, &out.Quota
 		*out = new(BucketQuota)
@@ -771,6 +1808,25 @@ func (in *BucketSpec) DeepCopyInto(out *BucketSpec) {
 		*out = new(BucketPlacement)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.RateLimits != nil {
+		in, out := &in.RateLimits, &out.RateLimits
+		*out = new(S3CircuitBreakerSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CORS != nil {
+		in, out := &in.CORS, &out.CORS
+		*out = make([]BucketCORSRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketSpec.
@@ -793,11 +1849,37 @@ func (in *BucketStatus) DeepCopyInto(out *BucketStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ObjectLockDefaultRetention != nil {
+		in, out := &in.ObjectLockDefaultRetention, &out.ObjectLockDefaultRetention
+		*out = new(BucketObjectLockDefaultRetention)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Quota != nil {
 		in, out := &in.Quota, &out.Quota
 		*out = new(BucketStatusQuota)
 		**out = **in
 	}
+	if in.RateLimits != nil {
+		in, out := &in.RateLimits, &out.RateLimits
+		*out = new(BucketStatusRateLimits)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RetentionExpiry != nil {
+		in, out := &in.RetentionExpiry, &out.RetentionExpiry
+		*out = (*in).DeepCopy()
+	}
+	if in.ManagedConfig != nil {
+		in, out := &in.ManagedConfig, &out.ManagedConfig
+		*out = make([]BucketConfigKind, len(*in))
+		copy(*out, *in)
+	}
+	if in.Replications != nil {
+		in, out := &in.Replications, &out.Replications
+		*out = make([]BucketReplicationSummary, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Usage != nil {
 		in, out := &in.Usage, &out.Usage
 		*out = new(BucketUsage)
@@ -816,16 +1898,38 @@ func (in *BucketStatus) DeepCopy() *BucketStatus {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *BucketStatusQuota) DeepCopyInto(out *BucketStatusQuota) {
+func (in *BucketStatusQuota) DeepCopyInto(out *BucketStatusQuota) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketStatusQuota.
+func (in *BucketStatusQuota) DeepCopy() *BucketStatusQuota {
+	if in == nil {
+		return nil
+	}
+	out := new(BucketStatusQuota)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BucketStatusRateLimits) DeepCopyInto(out *BucketStatusRateLimits) {
 	*out = *in
+	if in.Limits != nil {
+		in, out := &in.Limits, &out.Limits
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketStatusQuota.
-func (in *BucketStatusQuota) DeepCopy() *BucketStatusQuota {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BucketStatusRateLimits.
+func (in *BucketStatusRateLimits) DeepCopy() *BucketStatusRateLimits {
 	if in == nil {
 		return nil
 	}
-	out := new(BucketStatusQuota)
+	out := new(BucketStatusRateLimits)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -1204,6 +2308,134 @@ func (in *ComponentStatus) DeepCopy() *ComponentStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ErasureCodingCollectionStatus) DeepCopyInto(out *ErasureCodingCollectionStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ErasureCodingCollectionStatus.
+func (in *ErasureCodingCollectionStatus) DeepCopy() *ErasureCodingCollectionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ErasureCodingCollectionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ErasureCodingSpec) DeepCopyInto(out *ErasureCodingSpec) {
+	*out = *in
+	if in.Collections != nil {
+		in, out := &in.Collections, &out.Collections
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.QuietFor != nil {
+		in, out := &in.QuietFor, &out.QuietFor
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.FullnessPercent != nil {
+		in, out := &in.FullnessPercent, &out.FullnessPercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.DataShards != nil {
+		in, out := &in.DataShards, &out.DataShards
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ParityShards != nil {
+		in, out := &in.ParityShards, &out.ParityShards
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxVolumesPerPass != nil {
+		in, out := &in.MaxVolumesPerPass, &out.MaxVolumesPerPass
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ErasureCodingSpec.
+func (in *ErasureCodingSpec) DeepCopy() *ErasureCodingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ErasureCodingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ErasureCodingStatus) DeepCopyInto(out *ErasureCodingStatus) {
+	*out = *in
+	if in.Collections != nil {
+		in, out := &in.Collections, &out.Collections
+		*out = make([]ErasureCodingCollectionStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastEncodeTime != nil {
+		in, out := &in.LastEncodeTime, &out.LastEncodeTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastRebuildTime != nil {
+		in, out := &in.LastRebuildTime, &out.LastRebuildTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ErasureCodingStatus.
+func (in *ErasureCodingStatus) DeepCopy() *ErasureCodingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ErasureCodingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilerNotificationSpec) DeepCopyInto(out *FilerNotificationSpec) {
+	*out = *in
+	if in.Kafka != nil {
+		in, out := &in.Kafka, &out.Kafka
+		*out = new(KafkaNotification)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AWSSQS != nil {
+		in, out := &in.AWSSQS, &out.AWSSQS
+		*out = new(AWSSQSNotification)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GooglePubSub != nil {
+		in, out := &in.GooglePubSub, &out.GooglePubSub
+		*out = new(GooglePubSubNotification)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Webhook != nil {
+		in, out := &in.Webhook, &out.Webhook
+		*out = new(WebhookNotification)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilerNotificationSpec.
+func (in *FilerNotificationSpec) DeepCopy() *FilerNotificationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FilerNotificationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FilerSpec) DeepCopyInto(out *FilerSpec) {
 	*out = *in
@@ -1239,19 +2471,227 @@ func (in *FilerSpec) DeepCopyInto(out *FilerSpec) {
 		*out = new(PersistenceSpec)
 		(*in).DeepCopyInto(*out)
 	}
-	if in.MaxMB != nil {
-		in, out := &in.MaxMB, &out.MaxMB
-		*out = new(int32)
+	if in.MaxMB != nil {
+		in, out := &in.MaxMB, &out.MaxMB
+		*out = new(int32)
+		**out = **in
+	}
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(S3Config)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Iceberg != nil {
+		in, out := &in.Iceberg, &out.Iceberg
+		*out = new(IcebergConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Notification != nil {
+		in, out := &in.Notification, &out.Notification
+		*out = new(FilerNotificationSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = new(IngressSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.S3Ingress != nil {
+		in, out := &in.S3Ingress, &out.S3Ingress
+		*out = new(IngressSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GRPCIngress != nil {
+		in, out := &in.GRPCIngress, &out.GRPCIngress
+		*out = new(IngressSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilerSpec.
+func (in *FilerSpec) DeepCopy() *FilerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FilerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilesystemBackupStore) DeepCopyInto(out *FilesystemBackupStore) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilesystemBackupStore.
+func (in *FilesystemBackupStore) DeepCopy() *FilesystemBackupStore {
+	if in == nil {
+		return nil
+	}
+	out := new(FilesystemBackupStore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GCSBackupStore) DeepCopyInto(out *GCSBackupStore) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GCSBackupStore.
+func (in *GCSBackupStore) DeepCopy() *GCSBackupStore {
+	if in == nil {
+		return nil
+	}
+	out := new(GCSBackupStore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GooglePubSubNotification) DeepCopyInto(out *GooglePubSubNotification) {
+	*out = *in
+	if in.CredentialsSecret != nil {
+		in, out := &in.CredentialsSecret, &out.CredentialsSecret
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GooglePubSubNotification.
+func (in *GooglePubSubNotification) DeepCopy() *GooglePubSubNotification {
+	if in == nil {
+		return nil
+	}
+	out := new(GooglePubSubNotification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IcebergCatalog) DeepCopyInto(out *IcebergCatalog) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IcebergCatalog.
+func (in *IcebergCatalog) DeepCopy() *IcebergCatalog {
+	if in == nil {
+		return nil
+	}
+	out := new(IcebergCatalog)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IcebergCatalog) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IcebergCatalogAuth) DeepCopyInto(out *IcebergCatalogAuth) {
+	*out = *in
+	out.CredentialsRef = in.CredentialsRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IcebergCatalogAuth.
+func (in *IcebergCatalogAuth) DeepCopy() *IcebergCatalogAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(IcebergCatalogAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IcebergCatalogAuthStatus) DeepCopyInto(out *IcebergCatalogAuthStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IcebergCatalogAuthStatus.
+func (in *IcebergCatalogAuthStatus) DeepCopy() *IcebergCatalogAuthStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IcebergCatalogAuthStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IcebergCatalogCredentialsRef) DeepCopyInto(out *IcebergCatalogCredentialsRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IcebergCatalogCredentialsRef.
+func (in *IcebergCatalogCredentialsRef) DeepCopy() *IcebergCatalogCredentialsRef {
+	if in == nil {
+		return nil
+	}
+	out := new(IcebergCatalogCredentialsRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IcebergCatalogList) DeepCopyInto(out *IcebergCatalogList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]IcebergCatalog, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IcebergCatalogList.
+func (in *IcebergCatalogList) DeepCopy() *IcebergCatalogList {
+	if in == nil {
+		return nil
+	}
+	out := new(IcebergCatalogList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IcebergCatalogList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IcebergCatalogSpec) DeepCopyInto(out *IcebergCatalogSpec) {
+	*out = *in
+	out.WarehouseRef = in.WarehouseRef
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]IcebergNamespace, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Auth != nil {
+		in, out := &in.Auth, &out.Auth
+		*out = new(IcebergCatalogAuth)
 		**out = **in
 	}
-	if in.S3 != nil {
-		in, out := &in.S3, &out.S3
-		*out = new(S3Config)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Iceberg != nil {
-		in, out := &in.Iceberg, &out.Iceberg
-		*out = new(IcebergConfig)
+	if in.Service != nil {
+		in, out := &in.Service, &out.Service
+		*out = new(ServiceSpec)
 		(*in).DeepCopyInto(*out)
 	}
 	if in.Ingress != nil {
@@ -1259,74 +2699,103 @@ func (in *FilerSpec) DeepCopyInto(out *FilerSpec) {
 		*out = new(IngressSpec)
 		(*in).DeepCopyInto(*out)
 	}
-	if in.S3Ingress != nil {
-		in, out := &in.S3Ingress, &out.S3Ingress
-		*out = new(IngressSpec)
-		(*in).DeepCopyInto(*out)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IcebergCatalogSpec.
+func (in *IcebergCatalogSpec) DeepCopy() *IcebergCatalogSpec {
+	if in == nil {
+		return nil
 	}
-	if in.GRPCIngress != nil {
-		in, out := &in.GRPCIngress, &out.GRPCIngress
-		*out = new(IngressSpec)
-		(*in).DeepCopyInto(*out)
+	out := new(IcebergCatalogSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IcebergCatalogStatus) DeepCopyInto(out *IcebergCatalogStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Auth != nil {
+		in, out := &in.Auth, &out.Auth
+		*out = new(IcebergCatalogAuthStatus)
+		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilerSpec.
-func (in *FilerSpec) DeepCopy() *FilerSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IcebergCatalogStatus.
+func (in *IcebergCatalogStatus) DeepCopy() *IcebergCatalogStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(FilerSpec)
+	out := new(IcebergCatalogStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *FilesystemBackupStore) DeepCopyInto(out *FilesystemBackupStore) {
+func (in *IcebergConfig) DeepCopyInto(out *IcebergConfig) {
 	*out = *in
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(int32)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilesystemBackupStore.
-func (in *FilesystemBackupStore) DeepCopy() *FilesystemBackupStore {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IcebergConfig.
+func (in *IcebergConfig) DeepCopy() *IcebergConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(FilesystemBackupStore)
+	out := new(IcebergConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *GCSBackupStore) DeepCopyInto(out *GCSBackupStore) {
+func (in *IcebergNamespace) DeepCopyInto(out *IcebergNamespace) {
 	*out = *in
+	if in.Properties != nil {
+		in, out := &in.Properties, &out.Properties
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GCSBackupStore.
-func (in *GCSBackupStore) DeepCopy() *GCSBackupStore {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IcebergNamespace.
+func (in *IcebergNamespace) DeepCopy() *IcebergNamespace {
 	if in == nil {
 		return nil
 	}
-	out := new(GCSBackupStore)
+	out := new(IcebergNamespace)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *IcebergConfig) DeepCopyInto(out *IcebergConfig) {
+func (in *IcebergWarehouseRef) DeepCopyInto(out *IcebergWarehouseRef) {
 	*out = *in
-	if in.Port != nil {
-		in, out := &in.Port, &out.Port
-		*out = new(int32)
-		**out = **in
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IcebergConfig.
-func (in *IcebergConfig) DeepCopy() *IcebergConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IcebergWarehouseRef.
+func (in *IcebergWarehouseRef) DeepCopy() *IcebergWarehouseRef {
 	if in == nil {
 		return nil
 	}
-	out := new(IcebergConfig)
+	out := new(IcebergWarehouseRef)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -1420,6 +2889,26 @@ func (in *JWTSigningSpec) DeepCopy() *JWTSigningSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaNotification) DeepCopyInto(out *KafkaNotification) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaNotification.
+func (in *KafkaNotification) DeepCopy() *KafkaNotification {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaNotification)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LivenessProbeOverride) DeepCopyInto(out *LivenessProbeOverride) {
 	*out = *in
@@ -1682,6 +3171,243 @@ func (in *ReferenceGrantTo) DeepCopy() *ReferenceGrantTo {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemoteMount) DeepCopyInto(out *RemoteMount) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteMount.
+func (in *RemoteMount) DeepCopy() *RemoteMount {
+	if in == nil {
+		return nil
+	}
+	out := new(RemoteMount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RemoteMount) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemoteMountFileFilter) DeepCopyInto(out *RemoteMountFileFilter) {
+	*out = *in
+	if in.MinSize != nil {
+		in, out := &in.MinSize, &out.MinSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MaxSize != nil {
+		in, out := &in.MaxSize, &out.MaxSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MinAge != nil {
+		in, out := &in.MinAge, &out.MinAge
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxAge != nil {
+		in, out := &in.MaxAge, &out.MaxAge
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteMountFileFilter.
+func (in *RemoteMountFileFilter) DeepCopy() *RemoteMountFileFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(RemoteMountFileFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemoteMountList) DeepCopyInto(out *RemoteMountList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RemoteMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteMountList.
+func (in *RemoteMountList) DeepCopy() *RemoteMountList {
+	if in == nil {
+		return nil
+	}
+	out := new(RemoteMountList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RemoteMountList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemoteMountSpec) DeepCopyInto(out *RemoteMountSpec) {
+	*out = *in
+	out.SeaweedRef = in.SeaweedRef
+	in.Storage.DeepCopyInto(&out.Storage)
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Cache != nil {
+		in, out := &in.Cache, &out.Cache
+		*out = new(RemoteMountFileFilter)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Uncache != nil {
+		in, out := &in.Uncache, &out.Uncache
+		*out = new(RemoteMountFileFilter)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteMountSpec.
+func (in *RemoteMountSpec) DeepCopy() *RemoteMountSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RemoteMountSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemoteMountStatus) DeepCopyInto(out *RemoteMountStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteMountStatus.
+func (in *RemoteMountStatus) DeepCopy() *RemoteMountStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RemoteMountStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemoteTierPolicy) DeepCopyInto(out *RemoteTierPolicy) {
+	*out = *in
+	if in.Collections != nil {
+		in, out := &in.Collections, &out.Collections
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MinAge != nil {
+		in, out := &in.MinAge, &out.MinAge
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.FullnessPercent != nil {
+		in, out := &in.FullnessPercent, &out.FullnessPercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxVolumesPerPass != nil {
+		in, out := &in.MaxVolumesPerPass, &out.MaxVolumesPerPass
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteTierPolicy.
+func (in *RemoteTierPolicy) DeepCopy() *RemoteTierPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RemoteTierPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemoteTierSpec) DeepCopyInto(out *RemoteTierSpec) {
+	*out = *in
+	in.Storage.DeepCopyInto(&out.Storage)
+	if in.StorageClass != nil {
+		in, out := &in.StorageClass, &out.StorageClass
+		*out = new(string)
+		**out = **in
+	}
+	if in.Policy != nil {
+		in, out := &in.Policy, &out.Policy
+		*out = new(RemoteTierPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteTierSpec.
+func (in *RemoteTierSpec) DeepCopy() *RemoteTierSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RemoteTierSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemoteTierStatus) DeepCopyInto(out *RemoteTierStatus) {
+	*out = *in
+	if in.LastUploadTime != nil {
+		in, out := &in.LastUploadTime, &out.LastUploadTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteTierStatus.
+func (in *RemoteTierStatus) DeepCopy() *RemoteTierStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RemoteTierStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceReferenceGrant) DeepCopyInto(out *ResourceReferenceGrant) {
 	*out = *in
@@ -1767,6 +3493,71 @@ func (in *ResourceReferenceGrantSpec) DeepCopy() *ResourceReferenceGrantSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreDataMirror) DeepCopyInto(out *RestoreDataMirror) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreDataMirror.
+func (in *RestoreDataMirror) DeepCopy() *RestoreDataMirror {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreDataMirror)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreReseedStatus) DeepCopyInto(out *RestoreReseedStatus) {
+	*out = *in
+	if in.MissingPaths != nil {
+		in, out := &in.MissingPaths, &out.MissingPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreReseedStatus.
+func (in *RestoreReseedStatus) DeepCopy() *RestoreReseedStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreReseedStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreSnapshotsStatus) DeepCopyInto(out *RestoreSnapshotsStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreSnapshotsStatus.
+func (in *RestoreSnapshotsStatus) DeepCopy() *RestoreSnapshotsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreSnapshotsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreVolumesStatus) DeepCopyInto(out *RestoreVolumesStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreVolumesStatus.
+func (in *RestoreVolumesStatus) DeepCopy() *RestoreVolumesStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreVolumesStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *S3Account) DeepCopyInto(out *S3Account) {
 	*out = *in
@@ -1782,6 +3573,31 @@ func (in *S3Account) DeepCopy() *S3Account {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3ActionLimit) DeepCopyInto(out *S3ActionLimit) {
+	*out = *in
+	if in.MaxConcurrentRequests != nil {
+		in, out := &in.MaxConcurrentRequests, &out.MaxConcurrentRequests
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxConcurrentSize != nil {
+		in, out := &in.MaxConcurrentSize, &out.MaxConcurrentSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3ActionLimit.
+func (in *S3ActionLimit) DeepCopy() *S3ActionLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(S3ActionLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *S3BackupStore) DeepCopyInto(out *S3BackupStore) {
 	*out = *in
@@ -1802,6 +3618,33 @@ func (in *S3BackupStore) DeepCopy() *S3BackupStore {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3CircuitBreakerSpec) DeepCopyInto(out *S3CircuitBreakerSpec) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Limits != nil {
+		in, out := &in.Limits, &out.Limits
+		*out = make([]S3ActionLimit, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3CircuitBreakerSpec.
+func (in *S3CircuitBreakerSpec) DeepCopy() *S3CircuitBreakerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(S3CircuitBreakerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *S3Config) DeepCopyInto(out *S3Config) {
 	*out = *in
@@ -1810,6 +3653,11 @@ func (in *S3Config) DeepCopyInto(out *S3Config) {
 		*out = new(corev1.SecretKeySelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.CircuitBreaker != nil {
+		in, out := &in.CircuitBreaker, &out.CircuitBreaker
+		*out = new(S3CircuitBreakerSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3Config.
@@ -1956,6 +3804,11 @@ func (in *S3GatewaySpec) DeepCopyInto(out *S3GatewaySpec) {
 		*out = new(IngressSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.CircuitBreaker != nil {
+		in, out := &in.CircuitBreaker, &out.CircuitBreaker
+		*out = new(S3CircuitBreakerSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3GatewaySpec.
@@ -2412,121 +4265,337 @@ func (in *S3PolicyStatement) DeepCopyInto(out *S3PolicyStatement) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3PolicyStatement.
-func (in *S3PolicyStatement) DeepCopy() *S3PolicyStatement {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3PolicyStatement.
+func (in *S3PolicyStatement) DeepCopy() *S3PolicyStatement {
+	if in == nil {
+		return nil
+	}
+	out := new(S3PolicyStatement)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3PolicyStatus) DeepCopyInto(out *S3PolicyStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3PolicyStatus.
+func (in *S3PolicyStatus) DeepCopy() *S3PolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(S3PolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3SecretRef) DeepCopyInto(out *S3SecretRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3SecretRef.
+func (in *S3SecretRef) DeepCopy() *S3SecretRef {
+	if in == nil {
+		return nil
+	}
+	out := new(S3SecretRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3Subject) DeepCopyInto(out *S3Subject) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3Subject.
+func (in *S3Subject) DeepCopy() *S3Subject {
+	if in == nil {
+		return nil
+	}
+	out := new(S3Subject)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SFTPHostKey) DeepCopyInto(out *SFTPHostKey) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SFTPHostKey.
+func (in *SFTPHostKey) DeepCopy() *SFTPHostKey {
+	if in == nil {
+		return nil
+	}
+	out := new(SFTPHostKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SFTPHostKeyRotation) DeepCopyInto(out *SFTPHostKeyRotation) {
+	*out = *in
+	if in.OverlapWindow != nil {
+		in, out := &in.OverlapWindow, &out.OverlapWindow
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SFTPHostKeyRotation.
+func (in *SFTPHostKeyRotation) DeepCopy() *SFTPHostKeyRotation {
+	if in == nil {
+		return nil
+	}
+	out := new(SFTPHostKeyRotation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SFTPHostKeysStatus) DeepCopyInto(out *SFTPHostKeysStatus) {
+	*out = *in
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]SFTPHostKey, len(*in))
+		copy(*out, *in)
+	}
+	if in.GeneratedAt != nil {
+		in, out := &in.GeneratedAt, &out.GeneratedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.NextRotationTime != nil {
+		in, out := &in.NextRotationTime, &out.NextRotationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.PreviousKeysExpireAt != nil {
+		in, out := &in.PreviousKeysExpireAt, &out.PreviousKeysExpireAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SFTPHostKeysStatus.
+func (in *SFTPHostKeysStatus) DeepCopy() *SFTPHostKeysStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SFTPHostKeysStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SFTPSpec) DeepCopyInto(out *SFTPSpec) {
+	*out = *in
+	in.ComponentSpec.DeepCopyInto(&out.ComponentSpec)
+	in.ResourceRequirements.DeepCopyInto(&out.ResourceRequirements)
+	if in.Service != nil {
+		in, out := &in.Service, &out.Service
+		*out = new(ServiceSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MetricsPort != nil {
+		in, out := &in.MetricsPort, &out.MetricsPort
+		*out = new(int32)
+		**out = **in
+	}
+	if in.UserStoreSecret != nil {
+		in, out := &in.UserStoreSecret, &out.UserStoreSecret
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HostKeysSecret != nil {
+		in, out := &in.HostKeysSecret, &out.HostKeysSecret
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	if in.HostKeyRotation != nil {
+		in, out := &in.HostKeyRotation, &out.HostKeyRotation
+		*out = new(SFTPHostKeyRotation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AuthMethods != nil {
+		in, out := &in.AuthMethods, &out.AuthMethods
+		*out = new(string)
+		**out = **in
+	}
+	if in.MaxAuthTries != nil {
+		in, out := &in.MaxAuthTries, &out.MaxAuthTries
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = new(IngressSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SFTPSpec.
+func (in *SFTPSpec) DeepCopy() *SFTPSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SFTPSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SFTPUser) DeepCopyInto(out *SFTPUser) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SFTPUser.
+func (in *SFTPUser) DeepCopy() *SFTPUser {
 	if in == nil {
 		return nil
 	}
-	out := new(S3PolicyStatement)
+	out := new(SFTPUser)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SFTPUser) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *S3PolicyStatus) DeepCopyInto(out *S3PolicyStatus) {
+func (in *SFTPUserList) DeepCopyInto(out *SFTPUserList) {
 	*out = *in
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]metav1.Condition, len(*in))
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SFTPUser, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3PolicyStatus.
-func (in *S3PolicyStatus) DeepCopy() *S3PolicyStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SFTPUserList.
+func (in *SFTPUserList) DeepCopy() *SFTPUserList {
 	if in == nil {
 		return nil
 	}
-	out := new(S3PolicyStatus)
+	out := new(SFTPUserList)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *S3SecretRef) DeepCopyInto(out *S3SecretRef) {
-	*out = *in
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3SecretRef.
-func (in *S3SecretRef) DeepCopy() *S3SecretRef {
-	if in == nil {
-		return nil
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SFTPUserList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
 	}
-	out := new(S3SecretRef)
-	in.DeepCopyInto(out)
-	return out
+	return nil
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *S3Subject) DeepCopyInto(out *S3Subject) {
+func (in *SFTPUserPathPermission) DeepCopyInto(out *SFTPUserPathPermission) {
 	*out = *in
+	if in.Actions != nil {
+		in, out := &in.Actions, &out.Actions
+		*out = make([]SFTPPermission, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3Subject.
-func (in *S3Subject) DeepCopy() *S3Subject {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SFTPUserPathPermission.
+func (in *SFTPUserPathPermission) DeepCopy() *SFTPUserPathPermission {
 	if in == nil {
 		return nil
 	}
-	out := new(S3Subject)
+	out := new(SFTPUserPathPermission)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SFTPSpec) DeepCopyInto(out *SFTPSpec) {
+func (in *SFTPUserSpec) DeepCopyInto(out *SFTPUserSpec) {
 	*out = *in
-	in.ComponentSpec.DeepCopyInto(&out.ComponentSpec)
-	in.ResourceRequirements.DeepCopyInto(&out.ResourceRequirements)
-	if in.Service != nil {
-		in, out := &in.Service, &out.Service
-		*out = new(ServiceSpec)
+	out.SeaweedRef = in.SeaweedRef
+	if in.PasswordSecret != nil {
+		in, out := &in.PasswordSecret, &out.PasswordSecret
+		*out = new(corev1.SecretKeySelector)
 		(*in).DeepCopyInto(*out)
 	}
-	if in.Port != nil {
-		in, out := &in.Port, &out.Port
-		*out = new(int32)
-		**out = **in
-	}
-	if in.MetricsPort != nil {
-		in, out := &in.MetricsPort, &out.MetricsPort
-		*out = new(int32)
-		**out = **in
-	}
-	if in.UserStoreSecret != nil {
-		in, out := &in.UserStoreSecret, &out.UserStoreSecret
+	if in.AuthorizedKeysSecret != nil {
+		in, out := &in.AuthorizedKeysSecret, &out.AuthorizedKeysSecret
 		*out = new(corev1.SecretKeySelector)
 		(*in).DeepCopyInto(*out)
 	}
-	if in.HostKeysSecret != nil {
-		in, out := &in.HostKeysSecret, &out.HostKeysSecret
-		*out = new(corev1.LocalObjectReference)
-		**out = **in
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = make([]SFTPUserPathPermission, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
-	if in.AuthMethods != nil {
-		in, out := &in.AuthMethods, &out.AuthMethods
-		*out = new(string)
+	if in.UID != nil {
+		in, out := &in.UID, &out.UID
+		*out = new(int32)
 		**out = **in
 	}
-	if in.MaxAuthTries != nil {
-		in, out := &in.MaxAuthTries, &out.MaxAuthTries
+	if in.GID != nil {
+		in, out := &in.GID, &out.GID
 		*out = new(int32)
 		**out = **in
 	}
-	if in.Ingress != nil {
-		in, out := &in.Ingress, &out.Ingress
-		*out = new(IngressSpec)
-		(*in).DeepCopyInto(*out)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SFTPUserSpec.
+func (in *SFTPUserSpec) DeepCopy() *SFTPUserSpec {
+	if in == nil {
+		return nil
 	}
+	out := new(SFTPUserSpec)
+	in.DeepCopyInto(out)
+	return out
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SFTPSpec.
-func (in *SFTPSpec) DeepCopy() *SFTPSpec {
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SFTPUserStatus) DeepCopyInto(out *SFTPUserStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SFTPUserStatus.
+func (in *SFTPUserStatus) DeepCopy() *SFTPUserStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(SFTPSpec)
+	out := new(SFTPUserStatus)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -2563,7 +4632,7 @@ func (in *SeaweedBackup) DeepCopyInto(out *SeaweedBackup) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -2620,6 +4689,25 @@ func (in *SeaweedBackupList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SeaweedBackupSpec) DeepCopyInto(out *SeaweedBackupSpec) {
 	*out = *in
+	if in.Collections != nil {
+		in, out := &in.Collections, &out.Collections
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PreHooks != nil {
+		in, out := &in.PreHooks, &out.PreHooks
+		*out = make([]BackupHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PostHooks != nil {
+		in, out := &in.PostHooks, &out.PostHooks
+		*out = make([]BackupHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SeaweedBackupSpec.
@@ -2635,6 +4723,38 @@ func (in *SeaweedBackupSpec) DeepCopy() *SeaweedBackupSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SeaweedBackupStatus) DeepCopyInto(out *SeaweedBackupStatus) {
 	*out = *in
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = new(BackupVolumesStatus)
+		**out = **in
+	}
+	if in.VolumeSnapshots != nil {
+		in, out := &in.VolumeSnapshots, &out.VolumeSnapshots
+		*out = make([]BackupVolumeSnapshot, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ClusterSpec != nil {
+		in, out := &in.ClusterSpec, &out.ClusterSpec
+		*out = new(SeaweedSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(BackupVerificationResult)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReadOnlyPaths != nil {
+		in, out := &in.ReadOnlyPaths, &out.ReadOnlyPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FrozenVolumes != nil {
+		in, out := &in.FrozenVolumes, &out.FrozenVolumes
+		*out = make([]uint32, len(*in))
+		copy(*out, *in)
+	}
 	if in.StartTime != nil {
 		in, out := &in.StartTime, &out.StartTime
 		*out = (*in).DeepCopy()
@@ -2918,6 +5038,15 @@ func (in *SeaweedRestoreSpec) DeepCopyInto(out *SeaweedRestoreSpec) {
 		*out = new(BackupSource)
 		**out = **in
 	}
+	if in.PointInTime != nil {
+		in, out := &in.PointInTime, &out.PointInTime
+		*out = (*in).DeepCopy()
+	}
+	if in.DataMirror != nil {
+		in, out := &in.DataMirror, &out.DataMirror
+		*out = new(RestoreDataMirror)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SeaweedRestoreSpec.
@@ -2933,6 +5062,21 @@ func (in *SeaweedRestoreSpec) DeepCopy() *SeaweedRestoreSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SeaweedRestoreStatus) DeepCopyInto(out *SeaweedRestoreStatus) {
 	*out = *in
+	if in.Reseed != nil {
+		in, out := &in.Reseed, &out.Reseed
+		*out = new(RestoreReseedStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = new(RestoreVolumesStatus)
+		**out = **in
+	}
+	if in.Snapshots != nil {
+		in, out := &in.Snapshots, &out.Snapshots
+		*out = new(RestoreSnapshotsStatus)
+		**out = **in
+	}
 	if in.StartTime != nil {
 		in, out := &in.StartTime, &out.StartTime
 		*out = (*in).DeepCopy()
@@ -3029,6 +5173,18 @@ func (in *SeaweedSpec) DeepCopyInto(out *SeaweedSpec) {
 		*out = new(BackupSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ErasureCoding != nil {
+		in, out := &in.ErasureCoding, &out.ErasureCoding
+		*out = new(ErasureCodingSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RemoteTiers != nil {
+		in, out := &in.RemoteTiers, &out.RemoteTiers
+		*out = make([]RemoteTierSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.PVReclaimPolicy != nil {
 		in, out := &in.PVReclaimPolicy, &out.PVReclaimPolicy
 		*out = new(corev1.PersistentVolumeReclaimPolicy)
@@ -3126,10 +5282,43 @@ func (in *SeaweedStatus) DeepCopyInto(out *SeaweedStatus) {
 	out.Worker = in.Worker
 	out.S3 = in.S3
 	out.SFTP = in.SFTP
+	if in.SFTPHostKeys != nil {
+		in, out := &in.SFTPHostKeys, &out.SFTPHostKeys
+		*out = new(SFTPHostKeysStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.BackupMirrors != nil {
 		in, out := &in.BackupMirrors, &out.BackupMirrors
 		*out = make([]BackupMirrorStatus, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BackupMetadataLogs != nil {
+		in, out := &in.BackupMetadataLogs, &out.BackupMetadataLogs
+		*out = make([]BackupMirrorStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BackupStorages != nil {
+		in, out := &in.BackupStorages, &out.BackupStorages
+		*out = make([]BackupStorageStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ErasureCoding != nil {
+		in, out := &in.ErasureCoding, &out.ErasureCoding
+		*out = new(ErasureCodingStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RemoteTiers != nil {
+		in, out := &in.RemoteTiers, &out.RemoteTiers
+		*out = make([]RemoteTierStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
@@ -3395,6 +5584,100 @@ func (in *VolumeTopologySpec) DeepCopy() *VolumeTopologySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookNotification) DeepCopyInto(out *WebhookNotification) {
+	*out = *in
+	if in.BearerTokenSecret != nil {
+		in, out := &in.BearerTokenSecret, &out.BearerTokenSecret
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TimeoutSeconds != nil {
+		in, out := &in.TimeoutSeconds, &out.TimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxRetries != nil {
+		in, out := &in.MaxRetries, &out.MaxRetries
+		*out = new(int32)
+		**out = **in
+	}
+	if in.EventTypes != nil {
+		in, out := &in.EventTypes, &out.EventTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PathPrefixes != nil {
+		in, out := &in.PathPrefixes, &out.PathPrefixes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookNotification.
+func (in *WebhookNotification) DeepCopy() *WebhookNotification {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookNotification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkerPool) DeepCopyInto(out *WorkerPool) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.JobType != nil {
+		in, out := &in.JobType, &out.JobType
+		*out = new(string)
+		**out = **in
+	}
+	if in.MaxDetect != nil {
+		in, out := &in.MaxDetect, &out.MaxDetect
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxExecute != nil {
+		in, out := &in.MaxExecute, &out.MaxExecute
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkerPool.
+func (in *WorkerPool) DeepCopy() *WorkerPool {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkerPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkerSpec) DeepCopyInto(out *WorkerSpec) {
 	*out = *in
@@ -3425,6 +5708,13 @@ func (in *WorkerSpec) DeepCopyInto(out *WorkerSpec) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.Pools != nil {
+		in, out := &in.Pools, &out.Pools
+		*out = make([]WorkerPool, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkerSpec.
@@ -17,9 +17,11 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"os"
+	"strings"
 	"time"
 
 	monitorv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
@@ -39,6 +41,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/internal/backuptool"
 	"github.com/seaweedfs/seaweedfs-operator/internal/controller"
 	// +kubebuilder:scaffold:imports
 )
@@ -57,6 +60,11 @@ func init() {
 }
 
 func main() {
+	// Backup pods run this binary as `/manager backup-tool <subcommand>`.
+	if len(os.Args) > 1 && os.Args[1] == backuptool.Command {
+		os.Exit(backuptool.Main(os.Args[2:]))
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
@@ -83,6 +91,12 @@ func main() {
 		"Steady-state cadence for re-reconciling Ready Bucket and BucketLifecyclePolicy "+
 			"resources so filer state lost out-of-band (cluster rebuild, filer reset, manual "+
 			"delete) is reapplied without an operator restart. Set to 0 to disable. Defaults to 5m.")
+	var execHookNamespaces string
+	flag.StringVar(&execHookNamespaces, "backup-exec-hook-namespaces", "",
+		"Comma-separated namespaces whose SeaweedBackups may run exec hooks, or * for all. "+
+			"Exec hooks run commands in pods through the operator's pods/exec permission, so "+
+			"only list namespaces where everyone who can create a SeaweedBackup may exec into "+
+			"pods. Empty, the default, disables exec hooks.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -146,11 +160,24 @@ func main() {
 		os.Exit(1)
 	}
 
+	backupToolImage, err := controller.ResolveBackupToolImage(context.Background(), mgr.GetAPIReader())
+	if err != nil {
+		setupLog.Error(err, "unable to resolve the backup-tool image; metadata logs and point-in-time restores are disabled")
+	}
+
+	podLogs, err := controller.NewPodLogReader(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create the pod log reader for replication and mirror error counts")
+		os.Exit(1)
+	}
 	if err = (&controller.SeaweedReconciler{
 		Client:   mgr.GetClient(),
 		Log:      ctrl.Log.WithName("controller").WithName("Seaweed"),
 		Scheme:   mgr.GetScheme(),
 		Recorder: mgr.GetEventRecorderFor("seaweed-controller"),
+
+		BackupToolImage: backupToolImage,
+		PodLogs:         podLogs,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Seaweed")
 		os.Exit(1)
@@ -179,6 +206,46 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err = (&controller.BucketReplicationReconciler{
+		Client:         mgr.GetClient(),
+		Log:            ctrl.Log.WithName("controller").WithName("BucketReplication"),
+		Scheme:         mgr.GetScheme(),
+		Recorder:       mgr.GetEventRecorderFor("bucketreplication-controller"),
+		ResyncInterval: bucketResyncInterval,
+		PodLogs:        podLogs,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BucketReplication")
+		os.Exit(1)
+	}
+
+	if err = (&controller.IcebergCatalogReconciler{
+		Client:         mgr.GetClient(),
+		Log:            ctrl.Log.WithName("controller").WithName("IcebergCatalog"),
+		Scheme:         mgr.GetScheme(),
+		ResyncInterval: bucketResyncInterval,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "IcebergCatalog")
+		os.Exit(1)
+	}
+
+	if err = (&controller.SFTPUserReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controller").WithName("SFTPUser"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "SFTPUser")
+		os.Exit(1)
+	}
+
+	if err = (&controller.RemoteMountReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controller").WithName("RemoteMount"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RemoteMount")
+		os.Exit(1)
+	}
+
 	if err = (&controller.S3IdentityReconciler{
 		Client:   mgr.GetClient(),
 		Log:      ctrl.Log.WithName("controller").WithName("S3Identity"),
@@ -219,11 +286,20 @@ func main() {
 		os.Exit(1)
 	}
 
+	podExecutor, err := controller.NewPodExecutor(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create the pod executor for backup exec hooks")
+		os.Exit(1)
+	}
 	if err = (&controller.SeaweedBackupReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("controller").WithName("SeaweedBackup"),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor("seaweedbackup-controller"),
+		Client:          mgr.GetClient(),
+		Log:             ctrl.Log.WithName("controller").WithName("SeaweedBackup"),
+		Scheme:          mgr.GetScheme(),
+		Recorder:        mgr.GetEventRecorderFor("seaweedbackup-controller"),
+		BackupToolImage: backupToolImage,
+		PodExecutor:     podExecutor,
+
+		ExecHookNamespaces: splitList(execHookNamespaces),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "SeaweedBackup")
 		os.Exit(1)
@@ -234,11 +310,25 @@ func main() {
 		Log:      ctrl.Log.WithName("controller").WithName("SeaweedRestore"),
 		Scheme:   mgr.GetScheme(),
 		Recorder: mgr.GetEventRecorderFor("seaweedrestore-controller"),
+
+		BackupToolImage: backupToolImage,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "SeaweedRestore")
 		os.Exit(1)
 	}
 
+	if err = (&controller.BackupVerificationReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controller").WithName("BackupVerification"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("backupverification-controller"),
+
+		BackupToolImage: backupToolImage,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BackupVerification")
+		os.Exit(1)
+	}
+
 	if err = (&controller.BackupScheduler{
 		Client: mgr.GetClient(),
 		Log:    ctrl.Log.WithName("backup-scheduler"),
@@ -297,6 +387,10 @@ func main() {
 			setupLog.Error(err, "unable to create webhook", "webhook", "Seaweed")
 			os.Exit(1)
 		}
+		if err = (&seaweedv1.BucketLifecyclePolicy{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "BucketLifecyclePolicy")
+			os.Exit(1)
+		}
 	}
 	// +kubebuilder:scaffold:builder
 
@@ -315,3 +409,14 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,134 @@
+// Package backuptool implements the `backup-tool` subcommands the operator
+// image runs inside backup and restore pods, for the steps the weed CLI has
+// no primitive for. The manager binary switches into it when invoked as
+// `/manager backup-tool <subcommand> [flags]`.
+package backuptool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb"
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/seaweedfs/seaweedfs-operator/internal/controller/swadmin"
+)
+
+// Command is the first argument that switches the manager binary into the
+// backup tool.
+const Command = "backup-tool"
+
+// IntegrityExitCode is the exit code of a subcommand that rejected a snapshot
+// failing verification, so that controllers can tell tampering or corruption
+// from transient failures.
+const IntegrityExitCode = 3
+
+// LogIncompleteExitCode is the exit code of metalog-replay when the metadata
+// log does not reach the instant to restore to, which retrying soon after
+// will not change.
+const LogIncompleteExitCode = 4
+
+// subcommands maps each subcommand name to its entry point.
+var subcommands = map[string]func(ctx context.Context, args []string) error{
+	"metadata-check":    runMetadataCheck,
+	"metalog-capture":   runMetaLogCapture,
+	"metalog-replay":    runMetaLogReplay,
+	"reseed":            runReseed,
+	"snapshot-upload":   runSnapshotUpload,
+	"snapshot-download": runSnapshotDownload,
+	"snapshot-delete":   runSnapshotDelete,
+	"storage-probe":     runStorageProbe,
+	"volume-backup":     runVolumeBackup,
+	"volume-restore":    runVolumeRestore,
+	"volume-delete":     runVolumeDelete,
+}
+
+// Main runs the subcommand named by args[0] and returns the process exit
+// code. SIGTERM cancels the subcommand's context so long-running captures can
+// flush their open segment before the pod goes away.
+func Main(args []string) int {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return 2
+	}
+	run, ok := subcommands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "%s: unknown subcommand %q\n", Command, args[0])
+		usage(os.Stderr)
+		return 2
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	if err := run(ctx, args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s %s: %v\n", Command, args[0], err)
+		if errors.Is(err, errIntegrity) {
+			return IntegrityExitCode
+		}
+		if errors.Is(err, errLogIncomplete) {
+			return LogIncompleteExitCode
+		}
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: %s <subcommand> [flags]\n\nsubcommands:\n", Command)
+	fmt.Fprintln(w, "  metadata-check     count and sample a restored filer's metadata")
+	fmt.Fprintln(w, "  metalog-capture    stream filer metadata events into log segments")
+	fmt.Fprintln(w, "  metalog-replay     apply logged metadata events to a filer up to a point in time")
+	fmt.Fprintln(w, "  reseed             copy file content back into a restored filer from a data mirror")
+	fmt.Fprintln(w, "  snapshot-upload    upload a metadata snapshot to an object store")
+	fmt.Fprintln(w, "  snapshot-download  download a metadata snapshot from an object store")
+	fmt.Fprintln(w, "  snapshot-delete    delete a metadata snapshot from an object store")
+	fmt.Fprintln(w, "  storage-probe      write, read back and delete a marker object in a storage")
+	fmt.Fprintln(w, "  volume-backup      copy the cluster's volume files to an object store, incrementally")
+	fmt.Fprintln(w, "  volume-restore     assemble the volumes a cluster lacks from a volumes backup")
+	fmt.Fprintln(w, "  volume-delete      delete a volumes backup, keeping segments later backups share")
+}
+
+// dialFiler connects to the filer's gRPC endpoint. filer is the filer's HTTP
+// host:port; the gRPC port is derived from it the way weed does. tlsDir, when
+// set, holds the cluster's ca.crt / tls.crt / tls.key for mTLS.
+func dialFiler(filer, tlsDir string) (*grpc.ClientConn, filer_pb.SeaweedFilerClient, error) {
+	dialOption, err := tlsDialOption(tlsDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := grpc.NewClient(pb.ServerAddress(filer).ToGrpcAddress(), dialOption)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dial filer %s: %w", filer, err)
+	}
+	return conn, filer_pb.NewSeaweedFilerClient(conn), nil
+}
+
+// tlsDialOption returns the transport credentials of connections to the
+// cluster's servers: mTLS with the certificates in tlsDir, or plaintext when
+// tlsDir is empty.
+func tlsDialOption(tlsDir string) (grpc.DialOption, error) {
+	if tlsDir == "" {
+		return grpc.WithTransportCredentials(insecure.NewCredentials()), nil
+	}
+	ca, err := os.ReadFile(filepath.Join(tlsDir, "ca.crt"))
+	if err != nil {
+		return nil, err
+	}
+	cert, err := os.ReadFile(filepath.Join(tlsDir, "tls.crt"))
+	if err != nil {
+		return nil, err
+	}
+	key, err := os.ReadFile(filepath.Join(tlsDir, "tls.key"))
+	if err != nil {
+		return nil, err
+	}
+	return swadmin.ClientTLSDialOption(ca, cert, key)
+}
@@ -0,0 +1,191 @@
+package backuptool
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Snapshots are encrypted with AES-256-GCM envelope encryption. A random data
+// key seals the snapshot in fixed-size chunks; the storage's key (the
+// key-encryption key, from envEncryptionKey) wraps the data key in the file
+// header. The layout is
+//
+//	magic | wrap nonce (12) | wrapped data key (32+16) | chunk...
+//
+// Each chunk is sealed with the nonce 0000 || big-endian chunk counter and
+// one byte of additional data marking the final chunk, which is always
+// shorter than encChunkSize (possibly empty). Reordered, dropped or truncated
+// chunks therefore fail authentication.
+const (
+	// envEncryptionKey holds the base64 of a 32-byte key, under the same name
+	// as the key of the storage's encryption Secret.
+	envEncryptionKey = "BACKUP_ENCRYPTION_KEY"
+
+	// encryptionAlgorithm names the scheme in manifests.
+	encryptionAlgorithm = "aes-256-gcm"
+
+	encMagic     = "SWBKENC1"
+	encChunkSize = 64 << 10
+	encKeySize   = 32
+	gcmNonceSize = 12
+)
+
+// errIntegrity marks a snapshot that failed verification: a checksum
+// mismatch, a malformed manifest, or ciphertext that does not authenticate.
+var errIntegrity = errors.New("integrity check failed")
+
+// loadKey reads the key-encryption key from the environment. It returns nil
+// when the variable is unset.
+func loadKey() ([]byte, error) {
+	v := strings.TrimSpace(os.Getenv(envEncryptionKey))
+	if v == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(v)
+	if err != nil || len(key) != encKeySize {
+		return nil, fmt.Errorf("%s must be a base64-encoded %d-byte key", envEncryptionKey, encKeySize)
+	}
+	return key, nil
+}
+
+// keyID fingerprints a key-encryption key, so that restoring with the wrong
+// key says so instead of failing authentication.
+func keyID(kek []byte) string {
+	sum := sha256.Sum256(kek)
+	return hex.EncodeToString(sum[:8])
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkAAD is the additional data of a chunk.
+func chunkAAD(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// encryptStream writes src to dst encrypted under kek.
+func encryptStream(dst io.Writer, src io.Reader, kek []byte) error {
+	wrap, err := newGCM(kek)
+	if err != nil {
+		return err
+	}
+	dek := make([]byte, encKeySize)
+	wrapNonce := make([]byte, gcmNonceSize)
+	if _, err := rand.Read(dek); err != nil {
+		return err
+	}
+	if _, err := rand.Read(wrapNonce); err != nil {
+		return err
+	}
+	header := append([]byte(encMagic), wrapNonce...)
+	header = wrap.Seal(header, wrapNonce, dek, []byte(encMagic))
+	if _, err := dst.Write(header); err != nil {
+		return err
+	}
+
+	aead, err := newGCM(dek)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, encChunkSize)
+	nonce := make([]byte, gcmNonceSize)
+	for counter := uint64(0); ; counter++ {
+		n, err := io.ReadFull(src, buf)
+		final := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !final {
+			return err
+		}
+		binary.BigEndian.PutUint64(nonce[4:], counter)
+		if _, err := dst.Write(aead.Seal(nil, nonce, buf[:n], chunkAAD(final))); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+	}
+}
+
+// decryptStream writes the plaintext of src, encrypted by encryptStream, to
+// dst. Failures to authenticate are errIntegrity.
+func decryptStream(dst io.Writer, src io.Reader, kek []byte) error {
+	header := make([]byte, len(encMagic)+gcmNonceSize+encKeySize+16)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return fmt.Errorf("%w: truncated encryption header", errIntegrity)
+	}
+	if string(header[:len(encMagic)]) != encMagic {
+		return fmt.Errorf("%w: not an encrypted snapshot", errIntegrity)
+	}
+	wrap, err := newGCM(kek)
+	if err != nil {
+		return err
+	}
+	wrapNonce := header[len(encMagic) : len(encMagic)+gcmNonceSize]
+	dek, err := wrap.Open(nil, wrapNonce, header[len(encMagic)+gcmNonceSize:], []byte(encMagic))
+	if err != nil {
+		return fmt.Errorf("%w: the data key does not unwrap with this encryption key", errIntegrity)
+	}
+
+	aead, err := newGCM(dek)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, encChunkSize+aead.Overhead())
+	nonce := make([]byte, gcmNonceSize)
+	for counter := uint64(0); ; counter++ {
+		n, err := io.ReadFull(src, buf)
+		final := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !final {
+			return err
+		}
+		binary.BigEndian.PutUint64(nonce[4:], counter)
+		plain, err := aead.Open(nil, nonce, buf[:n], chunkAAD(final))
+		if err != nil {
+			return fmt.Errorf("%w: chunk %d does not authenticate", errIntegrity, counter)
+		}
+		if _, err := dst.Write(plain); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+	}
+}
+
+// transformFile writes the result of fn applied to src into dst.
+func transformFile(dst, src string, fn func(io.Writer, io.Reader) error) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	err = fn(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
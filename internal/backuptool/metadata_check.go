@@ -0,0 +1,205 @@
+package backuptool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+)
+
+// MetadataCheckReport is what metadata-check writes to its report file, the
+// container's termination message, for the controller to copy into the
+// BackupVerification's status. It is written whether or not the check
+// passes.
+type MetadataCheckReport struct {
+	// Entries is the number of entries, files and directories, under the
+	// checked subtree.
+	Entries int64 `json:"entries"`
+	// Directories is how many of Entries are directories.
+	Directories int64 `json:"directories"`
+	// Sampled is the number of paths looked up.
+	Sampled int `json:"sampled"`
+	// Missing lists sampled paths that were not found, up to
+	// maxReportedMissing of them.
+	Missing []string `json:"missing,omitempty"`
+	// Failure says why the check failed; empty when it passed.
+	Failure string `json:"failure,omitempty"`
+}
+
+const (
+	// checkPageSize is the ListEntries page size of the walk.
+	checkPageSize = 1024
+	// autoSamples is how many paths are sampled when none are given.
+	autoSamples = 5
+	// maxReportedMissing caps Missing so the report fits the 4 KiB
+	// termination message.
+	maxReportedMissing = 10
+)
+
+// errCheckFailed marks a restored filer that did not pass the check.
+var errCheckFailed = errors.New("metadata check failed")
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+func runMetadataCheck(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("metadata-check", flag.ContinueOnError)
+	filer := fs.String("filer", "", "filer HTTP address (host:port) to check")
+	tlsDir := fs.String("tlsDir", "", "directory holding ca.crt, tls.crt and tls.key for gRPC mTLS")
+	pathPrefix := fs.String("pathPrefix", "/", "filer subtree to check")
+	excludePrefix := fs.String("excludePrefix", "", "filer subtree left out of the check")
+	minEntries := fs.Int64("minEntries", 1, "fewest entries the subtree must hold")
+	var samples stringList
+	fs.Var(&samples, "sample", "path that must exist; repeatable. Without any, a few paths found by the walk are looked up")
+	report := fs.String("report", defaultReportFile, "where to write the JSON check report")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *filer == "" {
+		return errors.New("-filer is required")
+	}
+	conn, client, err := dialFiler(*filer, *tlsDir)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	s := scope{prefix: *pathPrefix, exclude: *excludePrefix}
+	rep, checkErr := checkMetadata(ctx, client, s, samples, *minEntries)
+	if checkErr != nil && !errors.Is(checkErr, errCheckFailed) {
+		rep.Failure = checkErr.Error()
+	}
+	data, err := json.Marshal(rep)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*report, data, 0o644); err != nil {
+		return err
+	}
+	if checkErr != nil {
+		return checkErr
+	}
+	log.Printf("checked %d entries (%d directories) and %d sampled paths under %s", rep.Entries, rep.Directories, rep.Sampled, *pathPrefix)
+	return nil
+}
+
+// checkMetadata walks the filer subtree in s, counting its entries, then
+// looks up each sample. Without samples it looks up the first file of each
+// of the first autoSamples directories the walk found one in, so that the
+// lookup path is exercised as well as the listing. A subtree with fewer than
+// minEntries entries, or a missing sample, is errCheckFailed.
+func checkMetadata(ctx context.Context, client filer_pb.SeaweedFilerClient, s scope, samples []string, minEntries int64) (MetadataCheckReport, error) {
+	var rep MetadataCheckReport
+	root := path.Clean("/" + s.prefix)
+	auto := len(samples) == 0
+	pending := []string{root}
+	for len(pending) > 0 {
+		dir := pending[0]
+		pending = pending[1:]
+		sampled := false
+		err := listEntries(ctx, client, dir, func(entry *filer_pb.Entry) {
+			full := path.Join(dir, entry.Name)
+			if !s.contains(full) {
+				return
+			}
+			rep.Entries++
+			if entry.IsDirectory {
+				rep.Directories++
+				pending = append(pending, full)
+				return
+			}
+			if auto && !sampled && len(samples) < autoSamples {
+				samples = append(samples, full)
+				sampled = true
+			}
+		})
+		if err != nil {
+			return rep, err
+		}
+	}
+
+	for _, p := range samples {
+		rep.Sampled++
+		found, err := lookupEntry(ctx, client, p)
+		if err != nil {
+			return rep, err
+		}
+		if !found && len(rep.Missing) < maxReportedMissing {
+			rep.Missing = append(rep.Missing, p)
+		}
+	}
+
+	switch {
+	case rep.Entries < minEntries:
+		rep.Failure = fmt.Sprintf("%s holds %d entries; at least %d expected", root, rep.Entries, minEntries)
+	case len(rep.Missing) > 0:
+		rep.Failure = fmt.Sprintf("sampled paths not found: %s", strings.Join(rep.Missing, ", "))
+	default:
+		return rep, nil
+	}
+	return rep, fmt.Errorf("%w: %s", errCheckFailed, rep.Failure)
+}
+
+// listEntries pages through dir's entries, calling fn for each.
+func listEntries(ctx context.Context, client filer_pb.SeaweedFilerClient, dir string, fn func(*filer_pb.Entry)) error {
+	startFrom := ""
+	for {
+		stream, err := client.ListEntries(ctx, &filer_pb.ListEntriesRequest{
+			Directory:         dir,
+			StartFromFileName: startFrom,
+			Limit:             checkPageSize,
+		})
+		if err != nil {
+			return fmt.Errorf("list %s: %w", dir, err)
+		}
+		n := 0
+		for {
+			resp, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("list %s: %w", dir, err)
+			}
+			n++
+			startFrom = resp.Entry.Name
+			fn(resp.Entry)
+		}
+		if n < checkPageSize {
+			return nil
+		}
+	}
+}
+
+// lookupEntry reports whether the entry at p exists.
+func lookupEntry(ctx context.Context, client filer_pb.SeaweedFilerClient, p string) (bool, error) {
+	p = path.Clean("/" + p)
+	if p == "/" {
+		return true, nil
+	}
+	dir, name := path.Split(p)
+	resp, err := client.LookupDirectoryEntry(ctx, &filer_pb.LookupDirectoryEntryRequest{
+		Directory: path.Clean(dir),
+		Name:      name,
+	})
+	if err != nil {
+		// The filer answers a missing entry with an error rather than an
+		// empty response.
+		if strings.Contains(err.Error(), filer_pb.ErrNotFound.Error()) {
+			return false, nil
+		}
+		return false, fmt.Errorf("look up %s: %w", p, err)
+	}
+	return resp.Entry != nil, nil
+}
@@ -0,0 +1,126 @@
+package backuptool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+	"google.golang.org/grpc"
+)
+
+// treeFiler serves listings and lookups of an in-memory tree, keyed by
+// directory.
+type treeFiler struct {
+	filer_pb.SeaweedFilerClient
+	tree map[string][]*filer_pb.Entry
+}
+
+type entryStream struct {
+	grpc.ClientStream
+	entries []*filer_pb.Entry
+}
+
+func (s *entryStream) Recv() (*filer_pb.ListEntriesResponse, error) {
+	if len(s.entries) == 0 {
+		return nil, io.EOF
+	}
+	e := s.entries[0]
+	s.entries = s.entries[1:]
+	return &filer_pb.ListEntriesResponse{Entry: e}, nil
+}
+
+func (f *treeFiler) ListEntries(_ context.Context, in *filer_pb.ListEntriesRequest, _ ...grpc.CallOption) (filer_pb.SeaweedFiler_ListEntriesClient, error) {
+	var page []*filer_pb.Entry
+	for _, e := range f.tree[in.Directory] {
+		if e.Name > in.StartFromFileName && len(page) < int(in.Limit) {
+			page = append(page, e)
+		}
+	}
+	return &entryStream{entries: page}, nil
+}
+
+func (f *treeFiler) LookupDirectoryEntry(_ context.Context, in *filer_pb.LookupDirectoryEntryRequest, _ ...grpc.CallOption) (*filer_pb.LookupDirectoryEntryResponse, error) {
+	for _, e := range f.tree[in.Directory] {
+		if e.Name == in.Name {
+			return &filer_pb.LookupDirectoryEntryResponse{Entry: e}, nil
+		}
+	}
+	return nil, filer_pb.ErrNotFound
+}
+
+func newTreeFiler() *treeFiler {
+	dir := func(name string) *filer_pb.Entry { return &filer_pb.Entry{Name: name, IsDirectory: true} }
+	file := func(name string) *filer_pb.Entry { return &filer_pb.Entry{Name: name} }
+	return &treeFiler{tree: map[string][]*filer_pb.Entry{
+		"/":                    {dir(".seaweedfs-operator"), dir("buckets"), file("readme")},
+		"/.seaweedfs-operator": {file("staged")},
+		"/buckets":             {dir("b1"), dir("b2")},
+		"/buckets/b1":          {file("k1"), file("k2")},
+		"/buckets/b2":          {dir("empty")},
+		"/buckets/b2/empty":    nil,
+	}}
+}
+
+func TestCheckMetadataCountsAndAutoSamples(t *testing.T) {
+	f := newTreeFiler()
+	s := scope{prefix: "/", exclude: "/.seaweedfs-operator"}
+	rep, err := checkMetadata(context.Background(), f, s, nil, 5)
+	if err != nil {
+		t.Fatalf("checkMetadata: %v", err)
+	}
+	// readme, buckets, b1, b2, k1, k2, empty; the operator's directory is
+	// left out.
+	if rep.Entries != 7 || rep.Directories != 4 {
+		t.Errorf("entries = %d (%d directories), want 7 (4)", rep.Entries, rep.Directories)
+	}
+	// One file from each directory holding one: / and /buckets/b1.
+	if rep.Sampled != 2 || rep.Failure != "" {
+		t.Errorf("report = %+v, want 2 samples and no failure", rep)
+	}
+}
+
+func TestCheckMetadataSubtreeAndMissingSamples(t *testing.T) {
+	f := newTreeFiler()
+	rep, err := checkMetadata(context.Background(), f, scope{prefix: "/buckets"}, []string{"/buckets/b1/k2", "/buckets/b1/gone", "/nowhere/x"}, 0)
+	if !errors.Is(err, errCheckFailed) {
+		t.Fatalf("err = %v, want errCheckFailed", err)
+	}
+	if rep.Entries != 5 {
+		t.Errorf("entries = %d, want the 5 under /buckets", rep.Entries)
+	}
+	if want := []string{"/buckets/b1/gone", "/nowhere/x"}; !reflect.DeepEqual(rep.Missing, want) {
+		t.Errorf("missing = %v, want %v", rep.Missing, want)
+	}
+	if rep.Sampled != 3 || rep.Failure == "" {
+		t.Errorf("report = %+v, want 3 samples and a failure", rep)
+	}
+}
+
+func TestCheckMetadataTooFewEntries(t *testing.T) {
+	f := newTreeFiler()
+	rep, err := checkMetadata(context.Background(), f, scope{prefix: "/buckets/b2"}, nil, 2)
+	if !errors.Is(err, errCheckFailed) {
+		t.Fatalf("err = %v, want errCheckFailed", err)
+	}
+	if rep.Entries != 1 || rep.Sampled != 0 {
+		t.Errorf("report = %+v, want one entry and nothing to sample", rep)
+	}
+}
+
+func TestListEntriesPages(t *testing.T) {
+	f := &treeFiler{tree: map[string][]*filer_pb.Entry{}}
+	for i := 0; i < checkPageSize+3; i++ {
+		f.tree["/big"] = append(f.tree["/big"], &filer_pb.Entry{Name: fmt.Sprintf("f%05d", i)})
+	}
+	n := 0
+	if err := listEntries(context.Background(), f, "/big", func(*filer_pb.Entry) { n++ }); err != nil {
+		t.Fatal(err)
+	}
+	if n != checkPageSize+3 {
+		t.Errorf("listed %d entries, want %d", n, checkPageSize+3)
+	}
+}
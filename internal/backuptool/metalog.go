@@ -0,0 +1,240 @@
+package backuptool
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// A metadata log is a directory of segments. Each segment is named after the
+// instant (Unix nanoseconds) from which it holds every event, and runs until
+// the next segment's start, so the sorted names describe a contiguous window.
+// A segment is a sequence of records: a 4-byte big-endian length followed by
+// a marshaled filer_pb.SubscribeMetadataResponse, the framing the filer uses
+// for its own persisted log.
+const segmentSuffix = ".metalog"
+
+// maxRecordSize bounds a single record so a corrupt length prefix cannot make
+// the reader allocate unbounded memory.
+const maxRecordSize = 64 << 20
+
+// segmentName is the file name of the segment starting at startNs. The
+// zero-padding keeps lexical and chronological order the same.
+func segmentName(startNs int64) string {
+	return fmt.Sprintf("%019d%s", startNs, segmentSuffix)
+}
+
+// parseSegmentName returns the start of the segment named name, or false when
+// name is not a segment.
+func parseSegmentName(name string) (int64, bool) {
+	base, ok := strings.CutSuffix(path.Base(name), segmentSuffix)
+	if !ok {
+		return 0, false
+	}
+	ns, err := strconv.ParseInt(base, 10, 64)
+	if err != nil || ns < 0 {
+		return 0, false
+	}
+	return ns, true
+}
+
+// sortedSegments keeps the segment names of names, ordered by start.
+func sortedSegments(names []string) []string {
+	var out []string
+	for _, n := range names {
+		if _, ok := parseSegmentName(n); ok {
+			out = append(out, n)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// writeRecord appends one event to a segment.
+func writeRecord(w io.Writer, event *filer_pb.SubscribeMetadataResponse) error {
+	data, err := proto.Marshal(event)
+	if err != nil {
+		return err
+	}
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(data)))
+	if _, err := w.Write(size[:]); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// readRecords calls fn for each event of a segment, in order. A record cut
+// short at the end of the segment is what a capture killed mid-write leaves
+// behind; it is ignored rather than reported, since the capture resumes from
+// the last complete record.
+func readRecords(r io.Reader, fn func(*filer_pb.SubscribeMetadataResponse) error) error {
+	var size [4]byte
+	for {
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil
+			}
+			return err
+		}
+		n := binary.BigEndian.Uint32(size[:])
+		if n > maxRecordSize {
+			return fmt.Errorf("record of %d bytes exceeds the %d byte limit", n, maxRecordSize)
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(r, data); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil
+			}
+			return err
+		}
+		event := &filer_pb.SubscribeMetadataResponse{}
+		if err := proto.Unmarshal(data, event); err != nil {
+			return fmt.Errorf("decode record: %w", err)
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+}
+
+// segmentsBetween returns the segments of sorted that may hold events in
+// [sinceNs, untilNs]. It fails when the log starts after sinceNs, because the
+// events between the base snapshot and the log's start are then missing.
+func segmentsBetween(sorted []string, sinceNs, untilNs int64) ([]string, error) {
+	if len(sorted) == 0 {
+		return nil, errors.New("the metadata log has no segments")
+	}
+	first, _ := parseSegmentName(sorted[0])
+	if first > sinceNs {
+		return nil, fmt.Errorf("the metadata log starts at %d, after the base snapshot at %d", first, sinceNs)
+	}
+	var out []string
+	for i, name := range sorted {
+		start, _ := parseSegmentName(name)
+		if start > untilNs {
+			break
+		}
+		if i+1 < len(sorted) {
+			if next, _ := parseSegmentName(sorted[i+1]); next <= sinceNs {
+				continue
+			}
+		}
+		out = append(out, name)
+	}
+	return out, nil
+}
+
+// errLogIncomplete marks a metadata log that does not yet reach the instant
+// a replay restores to.
+var errLogIncomplete = errors.New("metadata log incomplete")
+
+// checkCoverage fails with errLogIncomplete unless the log holds every event
+// up to untilNs: a later segment exists, which the capture only starts once
+// the previous one is complete, or the capture's mark (coveredNs) has
+// reached untilNs. The open segment of an object-store log is not in the
+// store yet, and a capture that stopped never moves its mark, so neither
+// counts.
+func checkCoverage(sorted []string, coveredNs, untilNs int64) error {
+	if coveredNs >= untilNs {
+		return nil
+	}
+	if len(sorted) > 0 {
+		if last, _ := parseSegmentName(sorted[len(sorted)-1]); last > untilNs {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: the capture has not recorded every event up to %s", errLogIncomplete, time.Unix(0, untilNs).UTC().Format(time.RFC3339Nano))
+}
+
+// scope decides which filer paths a capture records or a replay applies:
+// those under prefix, minus those under exclude.
+type scope struct {
+	prefix  string
+	exclude string
+}
+
+func (s scope) contains(fullPath string) bool {
+	if s.exclude != "" && underPath(fullPath, s.exclude) {
+		return false
+	}
+	return s.prefix == "" || s.prefix == "/" || underPath(fullPath, s.prefix)
+}
+
+// underPath reports whether p is dir or lies below it.
+func underPath(p, dir string) bool {
+	dir = strings.TrimSuffix(dir, "/")
+	return p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+// eventPaths returns the full paths an event removes and writes; either is
+// empty when the event has no such side. A rename removes the old path and
+// writes the new one.
+func eventPaths(event *filer_pb.SubscribeMetadataResponse) (oldPath, newPath string) {
+	n := event.EventNotification
+	if n == nil {
+		return "", ""
+	}
+	if n.OldEntry != nil {
+		oldPath = path.Join(event.Directory, n.OldEntry.Name)
+	}
+	if n.NewEntry != nil {
+		newDir := n.NewParentPath
+		if newDir == "" {
+			newDir = event.Directory
+		}
+		newPath = path.Join(newDir, n.NewEntry.Name)
+	}
+	return oldPath, newPath
+}
+
+// applyEvent replays one event against a filer. Updates are upserts and
+// deletes of missing entries succeed, so replaying an event the base
+// snapshot already contains is harmless. Chunk data is never deleted: the
+// restored entries may still reference it.
+func applyEvent(ctx context.Context, client filer_pb.SeaweedFilerClient, s scope, event *filer_pb.SubscribeMetadataResponse) (bool, error) {
+	oldPath, newPath := eventPaths(event)
+	applied := false
+	if oldPath != "" && oldPath != newPath && s.contains(oldPath) {
+		dir, name := path.Split(oldPath)
+		resp, err := client.DeleteEntry(ctx, &filer_pb.DeleteEntryRequest{
+			Directory:            path.Clean(dir),
+			Name:                 name,
+			IsDeleteData:         false,
+			IsRecursive:          true,
+			IgnoreRecursiveError: true,
+		})
+		if err != nil {
+			return false, fmt.Errorf("delete %s: %w", oldPath, err)
+		}
+		if resp.Error != "" && !strings.Contains(resp.Error, "not found") {
+			return false, fmt.Errorf("delete %s: %s", oldPath, resp.Error)
+		}
+		applied = true
+	}
+	if newPath != "" && s.contains(newPath) {
+		resp, err := client.CreateEntry(ctx, &filer_pb.CreateEntryRequest{
+			Directory: path.Dir(newPath),
+			Entry:     event.EventNotification.NewEntry,
+		})
+		if err != nil {
+			return false, fmt.Errorf("write %s: %w", newPath, err)
+		}
+		if resp.Error != "" {
+			return false, fmt.Errorf("write %s: %s", newPath, resp.Error)
+		}
+		applied = true
+	}
+	return applied, nil
+}
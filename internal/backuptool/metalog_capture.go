@@ -0,0 +1,268 @@
+package backuptool
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"os"
+	"time"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+)
+
+// captureClientName identifies the capture to the filer in its subscriber
+// list.
+const captureClientName = "seaweedfs-operator.metalog"
+
+// captureRetry is the pause before resubscribing after the stream drops.
+const captureRetry = 5 * time.Second
+
+// coverageInterval is how often an idle capture moves the log's coverage mark
+// forward (see segmentStore.Covered).
+const coverageInterval = time.Minute
+
+func runMetaLogCapture(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("metalog-capture", flag.ContinueOnError)
+	filer := fs.String("filer", "", "filer HTTP address (host:port)")
+	tlsDir := fs.String("tlsDir", "", "directory holding ca.crt, tls.crt and tls.key for gRPC mTLS")
+	pathPrefix := fs.String("pathPrefix", "/", "filer subtree whose events are captured")
+	excludePrefix := fs.String("excludePrefix", "", "filer subtree whose events are never captured")
+	var sf storeFlags
+	sf.register(fs)
+	prefix := fs.String("prefix", "", "key prefix (directory, for filesystem) segments are written under")
+	scratchDir := fs.String("scratchDir", os.TempDir(), "where segments bound for an object store are buffered")
+	segment := fs.Duration("segment", 10*time.Minute, "how long a segment stays open before the next one starts")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *filer == "" {
+		return errors.New("-filer is required")
+	}
+	if *segment <= 0 {
+		return errors.New("-segment must be positive")
+	}
+	store, err := newSegmentStore(ctx, &sf, *prefix, *scratchDir)
+	if err != nil {
+		return err
+	}
+	conn, client, err := dialFiler(*filer, *tlsDir)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	since, err := resumePoint(ctx, store, time.Now())
+	if err != nil {
+		return fmt.Errorf("find resume point: %w", err)
+	}
+	c := &capture{
+		client:  client,
+		store:   store,
+		scope:   scope{prefix: *pathPrefix, exclude: *excludePrefix},
+		segment: *segment,
+	}
+	return c.run(ctx, since)
+}
+
+// resumePoint is where a (re)started capture continues: just after the last
+// recorded event, so the filer's persisted log fills any gap left while the
+// capture was down. An empty log starts now.
+func resumePoint(ctx context.Context, store segmentStore, now time.Time) (int64, error) {
+	names, err := store.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sorted := sortedSegments(names)
+	if len(sorted) == 0 {
+		return now.UnixNano(), nil
+	}
+	last := sorted[len(sorted)-1]
+	next, _ := parseSegmentName(last)
+	r, err := store.Open(ctx, last)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	err = readRecords(r, func(event *filer_pb.SubscribeMetadataResponse) error {
+		if event.TsNs >= next {
+			next = event.TsNs + 1
+		}
+		return nil
+	})
+	return next, err
+}
+
+// capture streams filer metadata events into rotating segments.
+type capture struct {
+	client  filer_pb.SeaweedFilerClient
+	store   segmentStore
+	scope   scope
+	segment time.Duration
+
+	// nextStart is where the next segment's window begins; lastTsNs is the
+	// newest event seen, recorded or not.
+	nextStart int64
+	lastTsNs  int64
+
+	// covered is the coverage mark last stored, at markedAt.
+	covered  int64
+	markedAt time.Time
+
+	// Open segment state.
+	w       io.WriteCloser
+	opened  time.Time
+	records int
+}
+
+// run captures until ctx is cancelled, then closes the open segment.
+func (c *capture) run(ctx context.Context, sinceNs int64) error {
+	c.nextStart, c.lastTsNs, c.covered = sinceNs, sinceNs-1, sinceNs-1
+	// Open the first segment straight away so the log records that it covers
+	// the window from sinceNs even if no event arrives.
+	if err := c.openSegment(ctx); err != nil {
+		return err
+	}
+	events := make(chan *filer_pb.SubscribeMetadataResponse, 1024)
+	go c.subscribe(ctx, sinceNs, events)
+
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return c.closeSegment(ctx)
+		case event := <-events:
+			if err := c.record(ctx, event); err != nil {
+				c.closeSegment(ctx)
+				return err
+			}
+		case <-tick.C:
+			// Rotate an idle but non-empty segment on time, so a segment bound
+			// for an object store does not sit in the pod indefinitely.
+			if c.w != nil && c.records > 0 && time.Since(c.opened) >= c.segment {
+				if err := c.closeSegment(ctx); err != nil {
+					return err
+				}
+			}
+			// Events seen since the last mark that were not recorded need no
+			// segment, so with nothing unsaved the mark can move up to them.
+			if (c.w == nil || c.records == 0) && time.Since(c.markedAt) >= coverageInterval {
+				if err := c.markCovered(ctx); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// subscribe feeds events from since on into out, resubscribing from the last
+// delivered event whenever the stream drops.
+func (c *capture) subscribe(ctx context.Context, since int64, out chan<- *filer_pb.SubscribeMetadataResponse) {
+	clientID := rand.Int32()
+	for ctx.Err() == nil {
+		stream, err := c.client.SubscribeMetadata(ctx, &filer_pb.SubscribeMetadataRequest{
+			ClientName: captureClientName,
+			PathPrefix: c.scope.prefix,
+			SinceNs:    since,
+			ClientId:   clientID,
+		})
+		for err == nil {
+			var event *filer_pb.SubscribeMetadataResponse
+			if event, err = stream.Recv(); err != nil {
+				break
+			}
+			select {
+			case out <- event:
+				since = event.TsNs + 1
+			case <-ctx.Done():
+				return
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("metadata subscription interrupted, resuming from %d: %v", since, err)
+		select {
+		case <-time.After(captureRetry):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// record appends one event to the open segment, rotating first when the
+// segment has been open for a full interval.
+func (c *capture) record(ctx context.Context, event *filer_pb.SubscribeMetadataResponse) error {
+	if event.TsNs <= c.lastTsNs {
+		return nil
+	}
+	oldPath, newPath := eventPaths(event)
+	if !c.scope.contains(oldPath) && !c.scope.contains(newPath) {
+		c.lastTsNs = event.TsNs
+		return nil
+	}
+	if c.w != nil && time.Since(c.opened) >= c.segment {
+		if err := c.closeSegment(ctx); err != nil {
+			return err
+		}
+	}
+	if c.w == nil {
+		if err := c.openSegment(ctx); err != nil {
+			return err
+		}
+	}
+	if err := writeRecord(c.w, event); err != nil {
+		return err
+	}
+	c.records++
+	c.lastTsNs = event.TsNs
+	return nil
+}
+
+// openSegment starts the segment whose window begins at nextStart.
+func (c *capture) openSegment(ctx context.Context) error {
+	name := segmentName(c.nextStart)
+	// Closing uploads object-store segments, which must still work while
+	// shutting down.
+	w, err := c.store.Create(context.WithoutCancel(ctx), name)
+	if err != nil {
+		return fmt.Errorf("create segment %s: %w", name, err)
+	}
+	c.w, c.opened, c.records = w, time.Now(), 0
+	return nil
+}
+
+// closeSegment completes the open segment, if any, and marks the log
+// complete up to its last event. The next segment's window starts right after
+// the last event seen, keeping the windows contiguous.
+func (c *capture) closeSegment(ctx context.Context) error {
+	if c.w == nil {
+		return nil
+	}
+	err := c.w.Close()
+	c.w = nil
+	c.nextStart = c.lastTsNs + 1
+	if err != nil {
+		return err
+	}
+	log.Printf("closed segment with %d events up to %d", c.records, c.lastTsNs)
+	return c.markCovered(ctx)
+}
+
+// markCovered stores lastTsNs as the log's coverage mark, when it moved. The
+// caller ensures every event up to it is in the store.
+func (c *capture) markCovered(ctx context.Context) error {
+	c.markedAt = time.Now()
+	if c.lastTsNs <= c.covered {
+		return nil
+	}
+	// Like closing a segment, this must still work while shutting down.
+	if err := c.store.SetCovered(context.WithoutCancel(ctx), c.lastTsNs); err != nil {
+		return fmt.Errorf("record coverage: %w", err)
+	}
+	c.covered = c.lastTsNs
+	return nil
+}
@@ -0,0 +1,142 @@
+package backuptool
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+)
+
+func runMetaLogReplay(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("metalog-replay", flag.ContinueOnError)
+	filer := fs.String("filer", "", "filer HTTP address (host:port) events are applied to")
+	tlsDir := fs.String("tlsDir", "", "directory holding ca.crt, tls.crt and tls.key for gRPC mTLS")
+	pathPrefix := fs.String("pathPrefix", "/", "filer subtree whose events are applied")
+	excludePrefix := fs.String("excludePrefix", "", "filer subtree whose events are never applied")
+	var sf storeFlags
+	sf.register(fs)
+	prefix := fs.String("prefix", "", "key prefix (directory, for filesystem) holding the segments")
+	since := fs.String("since", "", "RFC 3339 instant the base snapshot was taken at; replay starts here")
+	until := fs.String("until", "", "RFC 3339 instant to restore to; later events are not applied")
+	wait := fs.Duration("wait", 0, "how long to wait for the log to reach -until before giving up")
+	checkOnly := fs.Bool("checkOnly", false, "only wait for the log to reach -until; apply nothing")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *filer == "" && !*checkOnly {
+		return errors.New("-filer is required")
+	}
+	sinceTime, err := time.Parse(time.RFC3339, *since)
+	if err != nil {
+		return fmt.Errorf("-since: %w", err)
+	}
+	untilTime, err := time.Parse(time.RFC3339, *until)
+	if err != nil {
+		return fmt.Errorf("-until: %w", err)
+	}
+	if untilTime.Before(sinceTime) {
+		return fmt.Errorf("-until %s is before -since %s", *until, *since)
+	}
+	store, err := newSegmentStore(ctx, &sf, *prefix, "")
+	if err != nil {
+		return err
+	}
+	if err := awaitCoverage(ctx, store, untilTime.UnixNano(), *wait); err != nil {
+		return err
+	}
+	if *checkOnly {
+		log.Printf("the metadata log reaches %s", *until)
+		return nil
+	}
+	conn, client, err := dialFiler(*filer, *tlsDir)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	s := scope{prefix: *pathPrefix, exclude: *excludePrefix}
+	applied, err := replay(ctx, client, store, s, sinceTime.UnixNano(), untilTime.UnixNano())
+	if err != nil {
+		return err
+	}
+	log.Printf("replayed %d metadata events from %s up to %s", applied, *since, *until)
+	return nil
+}
+
+// coveragePoll is how often awaitCoverage looks at the log again.
+const coveragePoll = 15 * time.Second
+
+// awaitCoverage waits up to wait for the log to hold every event up to
+// untilNs (see checkCoverage). Segments bound for an object store reach it
+// only when they rotate, so a recent -until can take a segment interval.
+func awaitCoverage(ctx context.Context, store segmentStore, untilNs int64, wait time.Duration) error {
+	deadline := time.Now().Add(wait)
+	for {
+		err := logCoverage(ctx, store, untilNs)
+		if !errors.Is(err, errLogIncomplete) || !time.Now().Before(deadline) {
+			return err
+		}
+		select {
+		case <-time.After(coveragePoll):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// logCoverage checks that store holds every event up to untilNs.
+func logCoverage(ctx context.Context, store segmentStore, untilNs int64) error {
+	names, err := store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("list segments: %w", err)
+	}
+	covered, err := store.Covered(ctx)
+	if err != nil {
+		return fmt.Errorf("read coverage: %w", err)
+	}
+	return checkCoverage(sortedSegments(names), covered, untilNs)
+}
+
+// replay applies the logged events in [sinceNs, untilNs], in order, and
+// returns how many changed the filer. It fails with errLogIncomplete,
+// applying nothing, unless the log reaches untilNs.
+func replay(ctx context.Context, client filer_pb.SeaweedFilerClient, store segmentStore, s scope, sinceNs, untilNs int64) (int, error) {
+	if err := logCoverage(ctx, store, untilNs); err != nil {
+		return 0, err
+	}
+	names, err := store.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list segments: %w", err)
+	}
+	segments, err := segmentsBetween(sortedSegments(names), sinceNs, untilNs)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, name := range segments {
+		r, err := store.Open(ctx, name)
+		if err != nil {
+			return applied, fmt.Errorf("open segment %s: %w", name, err)
+		}
+		err = readRecords(r, func(event *filer_pb.SubscribeMetadataResponse) error {
+			if event.TsNs < sinceNs || event.TsNs > untilNs {
+				return nil
+			}
+			changed, err := applyEvent(ctx, client, s, event)
+			if changed {
+				applied++
+			}
+			return err
+		})
+		r.Close()
+		if err != nil {
+			return applied, fmt.Errorf("segment %s: %w", name, err)
+		}
+	}
+	return applied, nil
+}
@@ -0,0 +1,265 @@
+package backuptool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// segmentIndexName is the object listing the segments under a metadata log's
+// prefix in an object store, which has no directory listing of its own.
+const segmentIndexName = "segments.json"
+
+// coveredName is the file recording how far a local metadata log is known to
+// be complete.
+const coveredName = "covered"
+
+// segmentStore is where a metadata log's segments live: a directory on a
+// mounted backup volume, or a prefix in an object store.
+type segmentStore interface {
+	// List returns the names of the segments in the store, in any order.
+	List(ctx context.Context) ([]string, error)
+	// Open reads a segment.
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	// Create starts (or, for a local store, appends to) a segment. The
+	// segment is complete in the store once the writer is closed.
+	Create(ctx context.Context, name string) (io.WriteCloser, error)
+	// Covered returns the instant up to which the capture has recorded
+	// every event in the store, or 0 when it has not said.
+	Covered(ctx context.Context) (int64, error)
+	// SetCovered records that every event up to ns is in the store; a mark
+	// at or before the current one is ignored.
+	SetCovered(ctx context.Context, ns int64) error
+}
+
+// newSegmentStore returns the store the storage flags select, with the
+// segments under prefix. scratchDir buffers segments bound for an object
+// store.
+func newSegmentStore(ctx context.Context, sf *storeFlags, prefix, scratchDir string) (segmentStore, error) {
+	if prefix == "" {
+		return nil, errors.New("-prefix is required")
+	}
+	if sf.kind == "filesystem" {
+		if sf.dir == "" {
+			return nil, errors.New("-dir is required for filesystem")
+		}
+		return localStore{dir: filepath.Join(sf.dir, filepath.FromSlash(objectKey(prefix)))}, nil
+	}
+	store, err := sf.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &objectSegmentStore{store: store, prefix: objectKey(prefix), scratchDir: scratchDir}, nil
+}
+
+// localStore keeps segments in a local directory, typically the mounted
+// backup PersistentVolumeClaim. Records are appended in place, so a segment
+// is readable while it is still being written.
+type localStore struct {
+	dir string
+}
+
+func (s localStore) List(_ context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+func (s localStore) Open(_ context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.dir, name))
+}
+
+func (s localStore) Create(_ context.Context, name string) (io.WriteCloser, error) {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(filepath.Join(s.dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+}
+
+func (s localStore) Covered(_ context.Context) (int64, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, coveredName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	ns, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed %s: %w", coveredName, err)
+	}
+	return ns, nil
+}
+
+// SetCovered replaces the mark through a rename, so a capture killed
+// mid-write leaves the previous mark rather than a torn one.
+func (s localStore) SetCovered(ctx context.Context, ns int64) error {
+	if covered, err := s.Covered(ctx); err != nil || ns <= covered {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(s.dir, coveredName+".tmp")
+	if err := os.WriteFile(tmp, []byte(strconv.FormatInt(ns, 10)+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, coveredName))
+}
+
+// objectSegmentStore keeps segments under a prefix of the storage's object
+// store, so a restore into a fresh cluster finds them. A segment is buffered
+// in scratchDir and uploaded whole when it is closed, then added to the
+// prefix's index. Only one capture writes a log, so the index is updated
+// without locking.
+type objectSegmentStore struct {
+	store      objectStore
+	prefix     string
+	scratchDir string
+}
+
+// segmentIndex is the JSON shape of a metadata log's index. CoveredNs is
+// the capture's mark (see segmentStore.Covered).
+type segmentIndex struct {
+	Segments  []string `json:"segments"`
+	CoveredNs int64    `json:"coveredNs,omitempty"`
+}
+
+func (s *objectSegmentStore) List(ctx context.Context) ([]string, error) {
+	idx, err := s.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return idx.Segments, nil
+}
+
+func (s *objectSegmentStore) index(ctx context.Context) (*segmentIndex, error) {
+	key := path.Join(s.prefix, segmentIndexName)
+	var idx segmentIndex
+	r, err := s.store.Get(ctx, key)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return &idx, nil
+	case err != nil:
+		return nil, fmt.Errorf("download %s: %w", s.store.URI(key), err)
+	}
+	defer r.Close()
+	if err := json.NewDecoder(io.LimitReader(r, 16<<20)).Decode(&idx); err != nil {
+		return nil, fmt.Errorf("malformed index %s: %w", s.store.URI(key), err)
+	}
+	return &idx, nil
+}
+
+func (s *objectSegmentStore) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	return s.store.Get(ctx, path.Join(s.prefix, name))
+}
+
+func (s *objectSegmentStore) Create(ctx context.Context, name string) (io.WriteCloser, error) {
+	f, err := os.CreateTemp(s.scratchDir, name+".*")
+	if err != nil {
+		return nil, err
+	}
+	return &segmentUpload{ctx: ctx, store: s, name: name, File: f}, nil
+}
+
+func (s *objectSegmentStore) Covered(ctx context.Context) (int64, error) {
+	idx, err := s.index(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return idx.CoveredNs, nil
+}
+
+func (s *objectSegmentStore) SetCovered(ctx context.Context, ns int64) error {
+	idx, err := s.index(ctx)
+	if err != nil {
+		return err
+	}
+	if ns <= idx.CoveredNs {
+		return nil
+	}
+	idx.CoveredNs = ns
+	return s.putIndex(ctx, idx)
+}
+
+// add records name in the index once its segment is in the store, so the
+// index never lists a segment that cannot be read.
+func (s *objectSegmentStore) add(ctx context.Context, name string) error {
+	idx, err := s.index(ctx)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(idx.Segments, name) {
+		return nil
+	}
+	idx.Segments = append(idx.Segments, name)
+	slices.Sort(idx.Segments)
+	return s.putIndex(ctx, idx)
+}
+
+func (s *objectSegmentStore) putIndex(ctx context.Context, idx *segmentIndex) error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	key := path.Join(s.prefix, segmentIndexName)
+	if err := s.store.Put(ctx, key, bytes.NewReader(data), int64(len(data))); err != nil {
+		return fmt.Errorf("upload %s: %w", s.store.URI(key), err)
+	}
+	return nil
+}
+
+// segmentUpload buffers a segment in a scratch file and uploads it on Close.
+type segmentUpload struct {
+	*os.File
+	ctx   context.Context
+	store *objectSegmentStore
+	name  string
+}
+
+func (u *segmentUpload) Close() error {
+	defer os.Remove(u.Name())
+	err := u.upload()
+	if cerr := u.File.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return u.store.add(u.ctx, u.name)
+}
+
+func (u *segmentUpload) upload() error {
+	size, err := u.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := u.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	key := path.Join(u.store.prefix, u.name)
+	if err := u.store.store.Put(u.ctx, key, u.File, size); err != nil {
+		return fmt.Errorf("upload %s: %w", u.store.store.URI(key), err)
+	}
+	return nil
+}
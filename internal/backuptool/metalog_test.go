@@ -0,0 +1,318 @@
+package backuptool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+	"google.golang.org/grpc"
+)
+
+func event(tsNs int64, dir string, oldName, newName, newParent string) *filer_pb.SubscribeMetadataResponse {
+	n := &filer_pb.EventNotification{NewParentPath: newParent}
+	if oldName != "" {
+		n.OldEntry = &filer_pb.Entry{Name: oldName}
+	}
+	if newName != "" {
+		n.NewEntry = &filer_pb.Entry{Name: newName}
+	}
+	return &filer_pb.SubscribeMetadataResponse{Directory: dir, EventNotification: n, TsNs: tsNs}
+}
+
+func TestSegmentName(t *testing.T) {
+	name := segmentName(1700000000123456789)
+	if name != "1700000000123456789.metalog" {
+		t.Fatalf("segmentName = %q", name)
+	}
+	if ns, ok := parseSegmentName(name); !ok || ns != 1700000000123456789 {
+		t.Fatalf("parseSegmentName(%q) = %d, %v", name, ns, ok)
+	}
+	for _, bad := range []string{"filer.meta.gz", "abc.metalog", "-1.metalog"} {
+		if _, ok := parseSegmentName(bad); ok {
+			t.Errorf("parseSegmentName(%q) accepted a non-segment", bad)
+		}
+	}
+	got := sortedSegments([]string{segmentName(30), "notes.txt", segmentName(4), segmentName(1000)})
+	want := []string{segmentName(4), segmentName(30), segmentName(1000)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sortedSegments = %v, want %v", got, want)
+	}
+}
+
+func TestRecordsRoundTripAndTruncatedTail(t *testing.T) {
+	var buf bytes.Buffer
+	for _, ts := range []int64{10, 20, 30} {
+		if err := writeRecord(&buf, event(ts, "/a", "", "f", "/a")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Simulate a capture killed mid-write of a fourth record.
+	full := buf.Len()
+	if err := writeRecord(&buf, event(40, "/a", "", "g", "/a")); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()[:full+6]
+
+	var seen []int64
+	err := readRecords(bytes.NewReader(data), func(e *filer_pb.SubscribeMetadataResponse) error {
+		seen = append(seen, e.TsNs)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readRecords: %v", err)
+	}
+	if !reflect.DeepEqual(seen, []int64{10, 20, 30}) {
+		t.Errorf("read %v, want the three complete records", seen)
+	}
+}
+
+func TestSegmentsBetween(t *testing.T) {
+	segs := []string{segmentName(100), segmentName(200), segmentName(300)}
+
+	got, err := segmentsBetween(segs, 250, 260)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{segmentName(200)}) {
+		t.Errorf("window inside one segment = %v", got)
+	}
+
+	got, err = segmentsBetween(segs, 150, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, segs) {
+		t.Errorf("window reaching the open segment = %v", got)
+	}
+
+	got, err = segmentsBetween(segs, 100, 199)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{segmentName(100)}) {
+		t.Errorf("window ending before the second segment = %v", got)
+	}
+
+	if _, err := segmentsBetween(segs, 50, 1000); err == nil {
+		t.Error("expected an error when the log starts after the base snapshot")
+	}
+	if _, err := segmentsBetween(nil, 50, 1000); err == nil {
+		t.Error("expected an error for an empty log")
+	}
+}
+
+func TestCheckCoverage(t *testing.T) {
+	segs := []string{segmentName(100), segmentName(200)}
+	if err := checkCoverage(segs, 0, 250); !errors.Is(err, errLogIncomplete) {
+		t.Errorf("open last segment, no mark: err = %v, want errLogIncomplete", err)
+	}
+	if err := checkCoverage(segs, 240, 250); !errors.Is(err, errLogIncomplete) {
+		t.Errorf("mark before until: err = %v, want errLogIncomplete", err)
+	}
+	if err := checkCoverage(segs, 250, 250); err != nil {
+		t.Errorf("mark at until: %v", err)
+	}
+	if err := checkCoverage(segs, 0, 150); err != nil {
+		t.Errorf("later segment started: %v", err)
+	}
+	if err := checkCoverage(nil, 0, 150); !errors.Is(err, errLogIncomplete) {
+		t.Errorf("empty log: err = %v, want errLogIncomplete", err)
+	}
+}
+
+func TestScope(t *testing.T) {
+	s := scope{prefix: "/buckets", exclude: "/buckets/.seaweedfs-operator"}
+	cases := map[string]bool{
+		"/buckets":                         true,
+		"/buckets/b1/key":                  true,
+		"/bucketsx/key":                    false,
+		"/etc/seaweedfs":                   false,
+		"/buckets/.seaweedfs-operator/x":   false,
+		"/buckets/.seaweedfs-operator":     false,
+		"/buckets/.seaweedfs-operator-not": true,
+	}
+	for p, want := range cases {
+		if got := s.contains(p); got != want {
+			t.Errorf("contains(%q) = %v, want %v", p, got, want)
+		}
+	}
+	if !(scope{prefix: "/"}).contains("/anything") {
+		t.Error("root scope must contain every path")
+	}
+}
+
+func TestEventPaths(t *testing.T) {
+	cases := []struct {
+		name     string
+		event    *filer_pb.SubscribeMetadataResponse
+		old, new string
+	}{
+		{"create", event(1, "/d", "", "f", "/d"), "", "/d/f"},
+		{"create without new parent", event(1, "/d", "", "f", ""), "", "/d/f"},
+		{"delete", event(1, "/d", "f", "", ""), "/d/f", ""},
+		{"update", event(1, "/d", "f", "f", "/d"), "/d/f", "/d/f"},
+		{"rename", event(1, "/d", "f", "g", "/e"), "/d/f", "/e/g"},
+	}
+	for _, c := range cases {
+		old, newPath := eventPaths(c.event)
+		if old != c.old || newPath != c.new {
+			t.Errorf("%s: eventPaths = (%q, %q), want (%q, %q)", c.name, old, newPath, c.old, c.new)
+		}
+	}
+}
+
+// recordingFiler records the mutations replay sends to a filer.
+type recordingFiler struct {
+	filer_pb.SeaweedFilerClient
+	calls []string
+}
+
+func (f *recordingFiler) CreateEntry(_ context.Context, in *filer_pb.CreateEntryRequest, _ ...grpc.CallOption) (*filer_pb.CreateEntryResponse, error) {
+	f.calls = append(f.calls, "create "+in.Directory+" "+in.Entry.Name)
+	return &filer_pb.CreateEntryResponse{}, nil
+}
+
+func (f *recordingFiler) DeleteEntry(_ context.Context, in *filer_pb.DeleteEntryRequest, _ ...grpc.CallOption) (*filer_pb.DeleteEntryResponse, error) {
+	if in.IsDeleteData {
+		f.calls = append(f.calls, "delete-with-data "+in.Directory+" "+in.Name)
+		return &filer_pb.DeleteEntryResponse{}, nil
+	}
+	f.calls = append(f.calls, "delete "+in.Directory+" "+in.Name)
+	return &filer_pb.DeleteEntryResponse{Error: "not found"}, nil
+}
+
+func TestReplayAppliesWindowInOrder(t *testing.T) {
+	ctx := context.Background()
+	store := localStore{dir: t.TempDir()}
+	write := func(name string, events ...*filer_pb.SubscribeMetadataResponse) {
+		w, err := store.Create(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range events {
+			if err := writeRecord(w, e); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(segmentName(100),
+		event(110, "/data", "", "before-snapshot", "/data"),
+		event(150, "/data", "", "a", "/data"),
+		event(160, "/data", "a", "b", "/data/sub"),
+	)
+	write(segmentName(200),
+		event(210, "/data", "x", "", ""),
+		event(220, "/other", "", "skipped", "/other"),
+		event(230, "/data/.seaweedfs-operator", "", "staged", "/data/.seaweedfs-operator"),
+		event(300, "/data", "", "after-target", "/data"),
+	)
+
+	f := &recordingFiler{}
+	s := scope{prefix: "/data", exclude: "/data/.seaweedfs-operator"}
+	if _, err := replay(ctx, f, store, s, 120, 250); !errors.Is(err, errLogIncomplete) || len(f.calls) != 0 {
+		t.Fatalf("replay before the log reaches until: err = %v, calls = %v; want errLogIncomplete and nothing applied", err, f.calls)
+	}
+	if err := store.SetCovered(ctx, 300); err != nil {
+		t.Fatal(err)
+	}
+	applied, err := replay(ctx, f, store, s, 120, 250)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	want := []string{
+		"create /data a",
+		"delete /data a",
+		"create /data/sub b",
+		"delete /data x",
+	}
+	if !reflect.DeepEqual(f.calls, want) {
+		t.Errorf("calls = %v, want %v", f.calls, want)
+	}
+	if applied != 3 {
+		t.Errorf("applied = %d, want 3", applied)
+	}
+}
+
+func TestResumePoint(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 5000)
+	store := localStore{dir: t.TempDir()}
+
+	got, err := resumePoint(ctx, store, now)
+	if err != nil || got != 5000 {
+		t.Fatalf("empty log: resumePoint = %d, %v; want now", got, err)
+	}
+
+	w, err := store.Create(ctx, segmentName(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if got, _ := resumePoint(ctx, store, now); got != 1000 {
+		t.Errorf("empty last segment: resumePoint = %d, want its start", got)
+	}
+
+	w, _ = store.Create(ctx, segmentName(1000))
+	writeRecord(w, event(1200, "/", "", "f", "/"))
+	writeRecord(w, event(1300, "/", "", "g", "/"))
+	w.Close()
+	if got, _ := resumePoint(ctx, store, now); got != 1301 {
+		t.Errorf("resumePoint = %d, want just after the last event", got)
+	}
+}
+
+func TestObjectSegmentStore(t *testing.T) {
+	ctx := context.Background()
+	bucket := &fsStore{root: t.TempDir()}
+	store := &objectSegmentStore{store: bucket, prefix: "base/c1/metalog", scratchDir: t.TempDir()}
+
+	w, err := store.Create(ctx, segmentName(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeRecord(w, event(1200, "/", "", "f", "/"))
+	if names, _ := store.List(ctx); len(names) != 0 {
+		t.Errorf("open segment listed: %v", names)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	w, _ = store.Create(ctx, segmentName(2000))
+	writeRecord(w, event(2100, "/", "", "g", "/"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	// A replay in another cluster only has the bucket.
+	fresh := &objectSegmentStore{store: bucket, prefix: "base/c1/metalog"}
+	names, err := fresh.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if want := []string{segmentName(1000), segmentName(2000)}; !reflect.DeepEqual(names, want) {
+		t.Errorf("List = %v, want %v", names, want)
+	}
+	if got, _ := resumePoint(ctx, fresh, time.Unix(0, 5000)); got != 2101 {
+		t.Errorf("resumePoint = %d, want just after the last event", got)
+	}
+
+	if err := store.SetCovered(ctx, 2500); err != nil {
+		t.Fatalf("SetCovered: %v", err)
+	}
+	if err := store.SetCovered(ctx, 2400); err != nil {
+		t.Fatalf("SetCovered: %v", err)
+	}
+	if got, err := fresh.Covered(ctx); err != nil || got != 2500 {
+		t.Errorf("Covered = %d, %v; want the highest mark", got, err)
+	}
+	if names, _ := fresh.List(ctx); len(names) != 2 {
+		t.Errorf("marking coverage changed the segments: %v", names)
+	}
+}
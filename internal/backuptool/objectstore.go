@@ -0,0 +1,307 @@
+package backuptool
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"google.golang.org/api/option"
+)
+
+// Credentials are read from the environment, under the same names as the
+// keys of a storage's CredentialsSecret. For s3 the AWS SDK reads
+// AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY itself, falling back to its
+// ambient chain; for gcs an unset key falls back to Application Default
+// Credentials.
+const (
+	envGCSCredentials  = "GOOGLE_APPLICATION_CREDENTIALS_JSON"
+	envAzureAccountKey = "AZURE_STORAGE_ACCOUNT_KEY"
+	envB2AccountID     = "B2_ACCOUNT_ID"
+	envB2AppKey        = "B2_MASTER_APPLICATION_KEY"
+)
+
+// objectStore is a bucket (or container) of one of the supported object
+// stores. Keys are relative to the bucket and never start with "/".
+type objectStore interface {
+	// Put uploads size bytes from r as key, replacing any existing object.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Get reads key. A missing key is an error wrapping os.ErrNotExist.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes key; deleting a key that does not exist succeeds.
+	Delete(ctx context.Context, key string) error
+	// URI is the canonical location of key, as reported in status.
+	URI(key string) string
+}
+
+// storeFlags are the flags selecting an object store; they mirror the fields
+// of the storage's sub-block. A filesystem storage is a store too: its mounted
+// PVC, under -dir.
+type storeFlags struct {
+	kind           string
+	dir            string
+	bucket         string
+	region         string
+	endpoint       string
+	forcePathStyle bool
+	account        string
+}
+
+func (f *storeFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.kind, "storeType", "", "object store type: s3, gcs, azure, b2 or filesystem")
+	fs.StringVar(&f.dir, "dir", "", "filesystem storage mount path")
+	fs.StringVar(&f.bucket, "bucket", "", "bucket (or azure container)")
+	fs.StringVar(&f.region, "region", "", "s3 region")
+	fs.StringVar(&f.endpoint, "endpoint", "", "s3 endpoint override for non-AWS providers")
+	fs.BoolVar(&f.forcePathStyle, "forcePathStyle", true, "s3 path-style addressing")
+	fs.StringVar(&f.account, "account", "", "azure storage account name")
+}
+
+// open returns the object store the flags describe.
+func (f *storeFlags) open(ctx context.Context) (objectStore, error) {
+	if f.kind == "filesystem" {
+		if f.dir == "" {
+			return nil, errors.New("-dir is required for filesystem")
+		}
+		return &fsStore{root: f.dir}, nil
+	}
+	if f.bucket == "" {
+		return nil, errors.New("-bucket is required")
+	}
+	switch f.kind {
+	case "s3":
+		return newS3Store(ctx, f)
+	case "gcs":
+		return newGCSStore(ctx, f.bucket)
+	case "azure":
+		return newAzureStore(f.account, f.bucket)
+	case "b2":
+		return newB2Store(ctx, f.bucket)
+	default:
+		return nil, fmt.Errorf("unsupported -storeType %q", f.kind)
+	}
+}
+
+// objectKey normalizes a slash-separated key.
+func objectKey(key string) string {
+	return strings.TrimPrefix(path.Clean("/"+key), "/")
+}
+
+// notFound is the error of a Get of a missing key.
+func notFound(key string) error {
+	return fmt.Errorf("%s: %w", key, os.ErrNotExist)
+}
+
+// fsStore is a directory: the mounted PVC of a filesystem storage.
+type fsStore struct {
+	root string
+}
+
+func (s *fsStore) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
+
+// Put writes key through a temporary file, so a reader never sees a partial
+// snapshot under its final name.
+func (s *fsStore) Put(_ context.Context, key string, r io.Reader, _ int64) error {
+	p := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(p + ".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
+
+func (s *fsStore) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(s.path(key))
+}
+
+func (s *fsStore) Delete(_ context.Context, key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *fsStore) URI(key string) string {
+	return path.Join(s.root, key)
+}
+
+type s3Store struct {
+	client *s3.Client
+	bucket string
+}
+
+func newS3Store(ctx context.Context, f *storeFlags) (*s3Store, error) {
+	region := f.region
+	if region == "" {
+		region = "us-east-2"
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(region))
+	if err != nil {
+		return nil, err
+	}
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if f.endpoint != "" {
+			o.BaseEndpoint = aws.String(f.endpoint)
+		}
+		o.UsePathStyle = f.forcePathStyle
+	})
+	return &s3Store{client: client, bucket: f.bucket}, nil
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          r,
+		ContentLength: aws.Int64(size),
+	})
+	return err
+}
+
+func (s *s3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(key)})
+	var missing *s3types.NoSuchKey
+	if errors.As(err, &missing) {
+		return nil, notFound(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+func (s *s3Store) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(key)})
+	return err
+}
+
+func (s *s3Store) URI(key string) string {
+	return "s3://" + s.bucket + "/" + key
+}
+
+type gcsStore struct {
+	bucket *storage.BucketHandle
+	name   string
+}
+
+func newGCSStore(ctx context.Context, bucket string) (*gcsStore, error) {
+	var opts []option.ClientOption
+	if key := os.Getenv(envGCSCredentials); key != "" {
+		opts = append(opts, option.WithCredentialsJSON([]byte(key)))
+	}
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &gcsStore{bucket: client.Bucket(bucket), name: bucket}, nil
+}
+
+func (s *gcsStore) Put(ctx context.Context, key string, r io.Reader, _ int64) error {
+	w := s.bucket.Object(key).NewWriter(ctx)
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (s *gcsStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	r, err := s.bucket.Object(key).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, notFound(key)
+	}
+	return r, err
+}
+
+func (s *gcsStore) Delete(ctx context.Context, key string) error {
+	if err := s.bucket.Object(key).Delete(ctx); err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *gcsStore) URI(key string) string {
+	return "gs://" + s.name + "/" + key
+}
+
+type azureStore struct {
+	client    *azblob.Client
+	account   string
+	container string
+}
+
+func newAzureStore(account, container string) (*azureStore, error) {
+	if account == "" {
+		return nil, errors.New("-account is required for azure")
+	}
+	key := os.Getenv(envAzureAccountKey)
+	if key == "" {
+		return nil, fmt.Errorf("%s is not set", envAzureAccountKey)
+	}
+	cred, err := azblob.NewSharedKeyCredential(account, key)
+	if err != nil {
+		return nil, err
+	}
+	client, err := azblob.NewClientWithSharedKeyCredential(azureServiceURL(account), cred, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &azureStore{client: client, account: account, container: container}, nil
+}
+
+func azureServiceURL(account string) string {
+	return fmt.Sprintf("https://%s.blob.core.windows.net/", account)
+}
+
+func (s *azureStore) Put(ctx context.Context, key string, r io.Reader, _ int64) error {
+	_, err := s.client.UploadStream(ctx, s.container, key, r, nil)
+	return err
+}
+
+func (s *azureStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.client.DownloadStream(ctx, s.container, key, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return nil, notFound(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *azureStore) Delete(ctx context.Context, key string) error {
+	if _, err := s.client.DeleteBlob(ctx, s.container, key, nil); err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (s *azureStore) URI(key string) string {
+	return azureServiceURL(s.account) + s.container + "/" + (&url.URL{Path: key}).EscapedPath()
+}
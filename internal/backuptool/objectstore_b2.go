@@ -0,0 +1,209 @@
+package backuptool
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// b2AuthorizeURL is the Backblaze B2 native API's account authorization
+// endpoint. The native API is used rather than B2's S3-compatible one because
+// the latter does not accept the master application key storages carry.
+const b2AuthorizeURL = "https://api.backblazeb2.com/b2api/v2/b2_authorize_account"
+
+// b2Store talks to a Backblaze B2 bucket over the native API.
+type b2Store struct {
+	client      *http.Client
+	apiURL      string
+	downloadURL string
+	token       string
+	bucket      string
+	bucketID    string
+}
+
+func newB2Store(ctx context.Context, bucket string) (*b2Store, error) {
+	accountID, appKey := os.Getenv(envB2AccountID), os.Getenv(envB2AppKey)
+	if accountID == "" || appKey == "" {
+		return nil, fmt.Errorf("%s and %s must be set", envB2AccountID, envB2AppKey)
+	}
+	return authorizeB2(ctx, http.DefaultClient, b2AuthorizeURL, accountID, appKey, bucket)
+}
+
+// authorizeB2 logs in to B2 and resolves bucket's id.
+func authorizeB2(ctx context.Context, client *http.Client, authorizeURL, accountID, appKey, bucket string) (*b2Store, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, authorizeURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(accountID, appKey)
+	var auth struct {
+		AccountID          string `json:"accountId"`
+		AuthorizationToken string `json:"authorizationToken"`
+		APIURL             string `json:"apiUrl"`
+		DownloadURL        string `json:"downloadUrl"`
+	}
+	if err := b2Do(client, req, &auth); err != nil {
+		return nil, fmt.Errorf("b2 authorize: %w", err)
+	}
+	s := &b2Store{
+		client:      client,
+		apiURL:      auth.APIURL,
+		downloadURL: auth.DownloadURL,
+		token:       auth.AuthorizationToken,
+		bucket:      bucket,
+	}
+
+	var buckets struct {
+		Buckets []struct {
+			BucketID string `json:"bucketId"`
+		} `json:"buckets"`
+	}
+	if err := s.call(ctx, "b2_list_buckets", map[string]string{"accountId": auth.AccountID, "bucketName": bucket}, &buckets); err != nil {
+		return nil, fmt.Errorf("b2 list buckets: %w", err)
+	}
+	if len(buckets.Buckets) == 0 {
+		return nil, fmt.Errorf("b2 bucket %q not found", bucket)
+	}
+	s.bucketID = buckets.Buckets[0].BucketID
+	return s, nil
+}
+
+// call POSTs a JSON request to a native API operation.
+func (s *b2Store) call(ctx context.Context, op string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.apiURL+"/b2api/v2/"+op, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", s.token)
+	return b2Do(s.client, req, out)
+}
+
+// b2Do sends req and decodes a JSON response into out, surfacing B2's error
+// message on failure.
+func b2Do(client *http.Client, req *http.Request, out any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(msg, &e) == nil && e.Message != "" {
+			return fmt.Errorf("%s: %s: %s", resp.Status, e.Code, e.Message)
+		}
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (s *b2Store) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	var upload struct {
+		UploadURL          string `json:"uploadUrl"`
+		AuthorizationToken string `json:"authorizationToken"`
+	}
+	if err := s.call(ctx, "b2_get_upload_url", map[string]string{"bucketId": s.bucketID}, &upload); err != nil {
+		return fmt.Errorf("b2 get upload url: %w", err)
+	}
+
+	// B2 requires the content's SHA-1; send it after the content rather than
+	// reading the file twice.
+	h := sha1.New()
+	body := io.MultiReader(io.TeeReader(r, h), &hexDigest{h: h})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, upload.UploadURL, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size + sha1.Size*2
+	req.Header.Set("Authorization", upload.AuthorizationToken)
+	req.Header.Set("X-Bz-File-Name", (&url.URL{Path: key}).EscapedPath())
+	req.Header.Set("Content-Type", "b2/x-auto")
+	req.Header.Set("X-Bz-Content-Sha1", "hex_digits_at_end")
+	if err := b2Do(s.client, req, nil); err != nil {
+		return fmt.Errorf("b2 upload %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *b2Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	u := s.downloadURL + "/file/" + url.PathEscape(s.bucket) + "/" + (&url.URL{Path: key}).EscapedPath()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", s.token)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, notFound(key)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("b2 download %s: %s", key, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// Delete removes every version of key: B2 keeps old versions of a file name
+// around until each is deleted explicitly.
+func (s *b2Store) Delete(ctx context.Context, key string) error {
+	var list struct {
+		Files []struct {
+			FileID   string `json:"fileId"`
+			FileName string `json:"fileName"`
+		} `json:"files"`
+	}
+	in := map[string]any{"bucketId": s.bucketID, "startFileName": key, "prefix": key, "maxFileCount": 100}
+	if err := s.call(ctx, "b2_list_file_versions", in, &list); err != nil {
+		return fmt.Errorf("b2 list versions of %s: %w", key, err)
+	}
+	for _, f := range list.Files {
+		if f.FileName != key {
+			continue
+		}
+		if err := s.call(ctx, "b2_delete_file_version", map[string]string{"fileId": f.FileID, "fileName": f.FileName}, nil); err != nil {
+			return fmt.Errorf("b2 delete %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func (s *b2Store) URI(key string) string {
+	return "b2://" + s.bucket + "/" + key
+}
+
+// hexDigest reads as the hex encoding of h's sum, computed on first read so
+// it covers everything written to h before.
+type hexDigest struct {
+	h   hash.Hash
+	buf *bytes.Reader
+}
+
+func (d *hexDigest) Read(p []byte) (int, error) {
+	if d.buf == nil {
+		d.buf = bytes.NewReader([]byte(hex.EncodeToString(d.h.Sum(nil))))
+	}
+	return d.buf.Read(p)
+}
@@ -0,0 +1,63 @@
+package backuptool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// corruptingStore is a memStore that flips the first byte of every object it
+// hands back.
+type corruptingStore struct{ memStore }
+
+func (s *corruptingStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	rc, err := s.memStore.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	data[0] ^= 0xff
+	return io.NopCloser(strings.NewReader(string(data))), nil
+}
+
+func TestProbeStore(t *testing.T) {
+	ctx := context.Background()
+	kek := bytes.Repeat([]byte{7}, encKeySize)
+	for _, tc := range []struct {
+		name string
+		kek  []byte
+	}{{"plain", nil}, {"encrypted", kek}} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &memStore{objects: map[string][]byte{}}
+			if err := probeStore(ctx, store, "c1/.probe", tc.kek); err != nil {
+				t.Fatalf("probe: %v", err)
+			}
+			if len(store.objects) != 0 {
+				t.Errorf("probe left objects behind: %v", store.objects)
+			}
+		})
+	}
+}
+
+func TestProbeStoreReportsMismatchAndCleansUp(t *testing.T) {
+	ctx := context.Background()
+	store := &corruptingStore{memStore{objects: map[string][]byte{}}}
+	err := probeStore(ctx, store, "c1/.probe", nil)
+	if err == nil || !strings.Contains(err.Error(), "content differs") {
+		t.Fatalf("probe error = %v, want a content mismatch", err)
+	}
+	if len(store.objects) != 0 {
+		t.Errorf("marker not deleted after a failed read: %v", store.objects)
+	}
+
+	err = probeStore(ctx, store, "c1/.probe", bytes.Repeat([]byte{7}, encKeySize))
+	if !errors.Is(err, errIntegrity) {
+		t.Errorf("encrypted probe error = %v, want errIntegrity", err)
+	}
+}
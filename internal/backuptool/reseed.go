@@ -0,0 +1,365 @@
+package backuptool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+)
+
+// ReseedReport is what reseed writes to its report file, the container's
+// termination message, for the controller to copy into the SeaweedRestore's
+// status. It is written whether or not the reseed succeeds.
+type ReseedReport struct {
+	// Files is the number of files whose content was copied back from the
+	// mirror.
+	Files int64 `json:"files"`
+	// Bytes is the content copied back, in bytes.
+	Bytes int64 `json:"bytes"`
+	// Present is the number of files left alone because their chunks are on
+	// volumes the cluster has.
+	Present int64 `json:"present"`
+	// Missing is the number of files with lost chunks the mirror holds no
+	// copy of.
+	Missing int64 `json:"missing"`
+	// MissingPaths lists up to maxReportedMissing of them, long paths cut
+	// short.
+	MissingPaths []string `json:"missingPaths,omitempty"`
+	// Failure says why the reseed stopped; empty when it finished.
+	Failure string `json:"failure,omitempty"`
+}
+
+const (
+	// maxReseedReportBytes bounds the serialized report so it fits the
+	// 4 KiB termination message; the kubelet truncates anything longer,
+	// leaving JSON the controller cannot read.
+	maxReseedReportBytes = 4000
+	// maxReportedPathBytes and maxReportedFailureBytes are where long
+	// missing paths and failures are cut.
+	maxReportedPathBytes    = 256
+	maxReportedFailureBytes = 1024
+)
+
+// marshalReport serializes rep within maxReseedReportBytes: long paths and
+// the failure are cut short, then missing paths are dropped from the end
+// until it fits. Missing still counts every one.
+func marshalReport(rep ReseedReport) ([]byte, error) {
+	rep.Failure = truncateString(rep.Failure, maxReportedFailureBytes)
+	paths := make([]string, len(rep.MissingPaths))
+	for i, p := range rep.MissingPaths {
+		paths[i] = truncateString(p, maxReportedPathBytes)
+	}
+	for {
+		rep.MissingPaths = paths
+		data, err := json.Marshal(rep)
+		if err != nil || len(data) <= maxReseedReportBytes || len(paths) == 0 {
+			return data, err
+		}
+		paths = paths[:len(paths)-1]
+	}
+}
+
+// truncateString cuts s to at most n bytes, on a rune boundary, marking the
+// cut with "...".
+func truncateString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := n - len("...")
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "..."
+}
+
+// defaultReseedChunkMB is the size, in MiB, of the chunks reseeded content is
+// uploaded in.
+const defaultReseedChunkMB = 8
+
+func runReseed(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("reseed", flag.ContinueOnError)
+	var sf storeFlags
+	sf.register(fs)
+	filer := fs.String("filer", "", "filer HTTP address (host:port) to reseed")
+	tlsDir := fs.String("tlsDir", "", "directory holding ca.crt, tls.crt and tls.key for gRPC mTLS")
+	pathPrefix := fs.String("pathPrefix", "/", "filer subtree to reseed")
+	excludePrefix := fs.String("excludePrefix", "", "filer subtree left out of the reseed")
+	sourceDir := fs.String("sourceDir", "/", "filer directory the mirror copied; -prefix holds its content")
+	prefix := fs.String("prefix", "", "key prefix the mirror wrote the content of -sourceDir under")
+	chunkMB := fs.Int("chunkSizeMB", defaultReseedChunkMB, "size of the chunks content is uploaded in, in MiB")
+	report := fs.String("report", defaultReportFile, "where to write the JSON reseed report")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *filer == "" {
+		return errors.New("-filer is required")
+	}
+	if *chunkMB <= 0 {
+		return errors.New("-chunkSizeMB must be positive")
+	}
+	s := scope{prefix: path.Clean("/" + *pathPrefix), exclude: *excludePrefix}
+	src := path.Clean("/" + *sourceDir)
+	if src != "/" && !underPath(s.prefix, src) {
+		return fmt.Errorf("-pathPrefix %s is not under -sourceDir %s", s.prefix, src)
+	}
+	store, err := sf.open(ctx)
+	if err != nil {
+		return err
+	}
+	conn, client, err := dialFiler(*filer, *tlsDir)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	rs := &reseeder{
+		client:     client,
+		store:      store,
+		sourceDir:  src,
+		prefix:     objectKey(*prefix),
+		chunkSize:  int64(*chunkMB) << 20,
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+		volumes:    map[string]bool{},
+	}
+	rep, reseedErr := rs.run(ctx, s)
+	if reseedErr != nil {
+		rep.Failure = reseedErr.Error()
+	}
+	data, err := marshalReport(rep)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*report, data, 0o644); err != nil {
+		return err
+	}
+	if reseedErr != nil {
+		return reseedErr
+	}
+	log.Printf("reseeded %d files (%d bytes) under %s; %d already present, %d not in the mirror", rep.Files, rep.Bytes, s.prefix, rep.Present, rep.Missing)
+	return nil
+}
+
+// reseeder copies file content back from a filer.backup sink into a filer
+// whose metadata was restored without its volumes.
+type reseeder struct {
+	client filer_pb.SeaweedFilerClient
+	store  objectStore
+	// sourceDir is the filer directory the mirror copied; the file at
+	// sourceDir/p is the object prefix/p.
+	sourceDir  string
+	prefix     string
+	chunkSize  int64
+	httpClient *http.Client
+	// volumes caches whether a volume id has a location.
+	volumes map[string]bool
+}
+
+// run walks the subtree in s and reseeds each file whose chunks are lost. A
+// file is left alone when every chunk's volume is known to the cluster, so a
+// retried reseed picks up where the last one stopped.
+func (rs *reseeder) run(ctx context.Context, s scope) (ReseedReport, error) {
+	var rep ReseedReport
+	pending := []string{s.prefix}
+	for len(pending) > 0 {
+		dir := pending[0]
+		pending = pending[1:]
+		var files []*filer_pb.Entry
+		err := listEntries(ctx, rs.client, dir, func(entry *filer_pb.Entry) {
+			full := path.Join(dir, entry.Name)
+			if !s.contains(full) {
+				return
+			}
+			if entry.IsDirectory {
+				pending = append(pending, full)
+				return
+			}
+			if len(entry.Chunks) > 0 && entry.RemoteEntry == nil {
+				files = append(files, entry)
+			}
+		})
+		if err != nil {
+			return rep, err
+		}
+		for _, entry := range files {
+			full := path.Join(dir, entry.Name)
+			lost, err := rs.chunksLost(ctx, entry)
+			if err != nil {
+				return rep, err
+			}
+			if !lost {
+				rep.Present++
+				continue
+			}
+			n, err := rs.reseedFile(ctx, dir, entry)
+			if errors.Is(err, os.ErrNotExist) {
+				rep.Missing++
+				if len(rep.MissingPaths) < maxReportedMissing {
+					rep.MissingPaths = append(rep.MissingPaths, full)
+				}
+				continue
+			}
+			if err != nil {
+				return rep, fmt.Errorf("reseed %s: %w", full, err)
+			}
+			rep.Files++
+			rep.Bytes += n
+		}
+	}
+	return rep, nil
+}
+
+// chunksLost reports whether any of entry's chunks is on a volume the
+// cluster has no location for.
+func (rs *reseeder) chunksLost(ctx context.Context, entry *filer_pb.Entry) (bool, error) {
+	var unknown []string
+	for _, c := range entry.Chunks {
+		vid := chunkVolumeID(c)
+		if _, ok := rs.volumes[vid]; !ok {
+			unknown = append(unknown, vid)
+		}
+	}
+	if len(unknown) > 0 {
+		resp, err := rs.client.LookupVolume(ctx, &filer_pb.LookupVolumeRequest{VolumeIds: unknown})
+		if err != nil {
+			return false, fmt.Errorf("look up volumes: %w", err)
+		}
+		for _, vid := range unknown {
+			locs := resp.LocationsMap[vid]
+			rs.volumes[vid] = locs != nil && len(locs.Locations) > 0
+		}
+	}
+	for _, c := range entry.Chunks {
+		if !rs.volumes[chunkVolumeID(c)] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// chunkVolumeID is the volume a chunk is stored on.
+func chunkVolumeID(c *filer_pb.FileChunk) string {
+	if c.FileId != "" {
+		vid, _, _ := strings.Cut(c.FileId, ",")
+		return vid
+	}
+	if c.Fid != nil {
+		return fmt.Sprint(c.Fid.VolumeId)
+	}
+	return ""
+}
+
+// reseedFile uploads the mirror's copy of the file entry in dir into new
+// chunks and points the entry at them, keeping its attributes. It returns
+// the bytes copied; a file the mirror has no copy of is an error wrapping
+// os.ErrNotExist.
+func (rs *reseeder) reseedFile(ctx context.Context, dir string, entry *filer_pb.Entry) (int64, error) {
+	full := path.Join(dir, entry.Name)
+	rel := strings.TrimPrefix(strings.TrimPrefix(full, rs.sourceDir), "/")
+	r, err := rs.store.Get(ctx, path.Join(rs.prefix, rel))
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	var chunks []*filer_pb.FileChunk
+	var offset int64
+	buf := make([]byte, rs.chunkSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			c, uerr := rs.uploadChunk(ctx, full, buf[:n], offset)
+			if uerr != nil {
+				return 0, uerr
+			}
+			chunks = append(chunks, c)
+			offset += int64(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	entry.Chunks = chunks
+	entry.Content = nil
+	if entry.Attributes != nil {
+		entry.Attributes.FileSize = uint64(offset)
+	}
+	if _, err := rs.client.UpdateEntry(ctx, &filer_pb.UpdateEntryRequest{Directory: dir, Entry: entry}); err != nil {
+		return 0, fmt.Errorf("update entry: %w", err)
+	}
+	return offset, nil
+}
+
+// uploadChunk assigns a file id for the file at p, so that the filer's
+// per-path collection and replication rules apply, and writes data to it.
+func (rs *reseeder) uploadChunk(ctx context.Context, p string, data []byte, offset int64) (*filer_pb.FileChunk, error) {
+	assign, err := rs.client.AssignVolume(ctx, &filer_pb.AssignVolumeRequest{Count: 1, Path: p})
+	if err != nil {
+		return nil, fmt.Errorf("assign volume: %w", err)
+	}
+	if assign.Error != "" {
+		return nil, fmt.Errorf("assign volume: %s", assign.Error)
+	}
+	if assign.Location == nil {
+		return nil, errors.New("assign volume: no location")
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", path.Base(p))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(data); err != nil {
+		return nil, err
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+assign.Location.Url+"/"+assign.FileId, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if assign.Auth != "" {
+		req.Header.Set("Authorization", "BEARER "+assign.Auth)
+	}
+	resp, err := rs.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("upload %s: %w", assign.FileId, err)
+	}
+	defer resp.Body.Close()
+	var out struct {
+		ETag  string `json:"eTag"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil && resp.StatusCode < 300 {
+		return nil, fmt.Errorf("upload %s: %w", assign.FileId, err)
+	}
+	if resp.StatusCode >= 300 || out.Error != "" {
+		return nil, fmt.Errorf("upload %s: %s %s", assign.FileId, resp.Status, out.Error)
+	}
+	return &filer_pb.FileChunk{
+		FileId:       assign.FileId,
+		Offset:       offset,
+		Size:         uint64(len(data)),
+		ModifiedTsNs: time.Now().UnixNano(),
+		ETag:         out.ETag,
+	}, nil
+}
@@ -0,0 +1,171 @@
+package backuptool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+	"google.golang.org/grpc"
+)
+
+// reseedFiler is a treeFiler whose cluster holds only the volumes in live,
+// assigning new file ids on volume 9.
+type reseedFiler struct {
+	*treeFiler
+	live     map[string]bool
+	volume   string
+	assigned int
+	updated  map[string]*filer_pb.Entry
+}
+
+func (f *reseedFiler) LookupVolume(_ context.Context, in *filer_pb.LookupVolumeRequest, _ ...grpc.CallOption) (*filer_pb.LookupVolumeResponse, error) {
+	resp := &filer_pb.LookupVolumeResponse{LocationsMap: map[string]*filer_pb.Locations{}}
+	for _, vid := range in.VolumeIds {
+		if f.live[vid] {
+			resp.LocationsMap[vid] = &filer_pb.Locations{Locations: []*filer_pb.Location{{Url: f.volume}}}
+		}
+	}
+	return resp, nil
+}
+
+func (f *reseedFiler) AssignVolume(_ context.Context, _ *filer_pb.AssignVolumeRequest, _ ...grpc.CallOption) (*filer_pb.AssignVolumeResponse, error) {
+	f.assigned++
+	return &filer_pb.AssignVolumeResponse{
+		FileId:   fmt.Sprintf("9,%02x", f.assigned),
+		Location: &filer_pb.Location{Url: f.volume},
+	}, nil
+}
+
+func (f *reseedFiler) UpdateEntry(_ context.Context, in *filer_pb.UpdateEntryRequest, _ ...grpc.CallOption) (*filer_pb.UpdateEntryResponse, error) {
+	f.updated[in.Directory+"/"+in.Entry.Name] = in.Entry
+	return &filer_pb.UpdateEntryResponse{}, nil
+}
+
+func TestReseedCopiesLostFilesFromTheMirror(t *testing.T) {
+	uploads := map[string]string{}
+	volume := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		uploads[r.URL.Path] = string(data)
+		fmt.Fprintf(w, `{"size":%d,"eTag":"e"}`, len(data))
+	}))
+	defer volume.Close()
+
+	lost := func(name string) *filer_pb.Entry {
+		return &filer_pb.Entry{Name: name, Chunks: []*filer_pb.FileChunk{{FileId: "3,01"}}, Attributes: &filer_pb.FuseAttributes{FileMode: 0o640}}
+	}
+	f := &reseedFiler{
+		treeFiler: &treeFiler{tree: map[string][]*filer_pb.Entry{
+			"/":                    {{Name: ".seaweedfs-operator", IsDirectory: true}, {Name: "buckets", IsDirectory: true}},
+			"/.seaweedfs-operator": {lost("staged")},
+			"/buckets":             {{Name: "b1", IsDirectory: true}},
+			"/buckets/b1": {
+				lost("big"), lost("gone"),
+				{Name: "kept", Chunks: []*filer_pb.FileChunk{{FileId: "7,01"}}},
+				{Name: "empty"},
+			},
+		}},
+		live:    map[string]bool{"7": true},
+		volume:  strings.TrimPrefix(volume.URL, "http://"),
+		updated: map[string]*filer_pb.Entry{},
+	}
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "c1", "data", "b1"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "c1", "data", "b1", "big"), []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rs := &reseeder{
+		client:     f,
+		store:      &fsStore{root: dir},
+		sourceDir:  "/buckets",
+		prefix:     "c1/data",
+		chunkSize:  4,
+		httpClient: volume.Client(),
+		volumes:    map[string]bool{},
+	}
+	rep, err := rs.run(context.Background(), scope{prefix: "/", exclude: "/.seaweedfs-operator"})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if rep.Files != 1 || rep.Bytes != 10 || rep.Present != 1 || rep.Missing != 1 {
+		t.Errorf("report = %+v, want 1 file of 10 bytes, 1 present, 1 missing", rep)
+	}
+	if want := []string{"/buckets/b1/gone"}; !reflect.DeepEqual(rep.MissingPaths, want) {
+		t.Errorf("missing paths = %v, want %v", rep.MissingPaths, want)
+	}
+
+	big := f.updated["/buckets/b1/big"]
+	if big == nil || len(f.updated) != 1 {
+		t.Fatalf("updated entries = %v, want just /buckets/b1/big", f.updated)
+	}
+	if len(big.Chunks) != 3 || big.Attributes.FileSize != 10 || big.Attributes.FileMode != 0o640 {
+		t.Errorf("entry = %+v, want 3 chunks of 10 bytes keeping its mode", big)
+	}
+	var got strings.Builder
+	for i, c := range big.Chunks {
+		if c.Offset != int64(4*i) {
+			t.Errorf("chunk %d offset = %d, want %d", i, c.Offset, 4*i)
+		}
+		got.WriteString(uploads["/"+c.FileId])
+	}
+	if got.String() != "0123456789" {
+		t.Errorf("uploaded content = %q", got.String())
+	}
+}
+
+func TestChunkVolumeID(t *testing.T) {
+	if got := chunkVolumeID(&filer_pb.FileChunk{FileId: "12,0a1b"}); got != "12" {
+		t.Errorf("FileId chunk volume = %q, want 12", got)
+	}
+	if got := chunkVolumeID(&filer_pb.FileChunk{Fid: &filer_pb.FileId{VolumeId: 5}}); got != "5" {
+		t.Errorf("Fid chunk volume = %q, want 5", got)
+	}
+}
+
+func TestReseedReportFitsTerminationMessage(t *testing.T) {
+	rep := ReseedReport{Missing: 12, Failure: strings.Repeat("e", 10000)}
+	for i := 0; i < maxReportedMissing; i++ {
+		// Control characters grow sixfold when escaped.
+		rep.MissingPaths = append(rep.MissingPaths, "/buckets/"+strings.Repeat("\x01", 240))
+	}
+	rep.MissingPaths[0] = "/buckets/" + strings.Repeat("é", 3000)
+
+	data, err := marshalReport(rep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) > maxReseedReportBytes {
+		t.Fatalf("report is %d bytes, want at most %d", len(data), maxReseedReportBytes)
+	}
+	var got ReseedReport
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("report is not valid JSON: %v", err)
+	}
+	if got.Missing != 12 || len(got.MissingPaths) == 0 || len(got.MissingPaths) == maxReportedMissing {
+		t.Errorf("missing = %d with %d paths, want 12 with some paths dropped", got.Missing, len(got.MissingPaths))
+	}
+	if p := got.MissingPaths[0]; len(p) > maxReportedPathBytes || !utf8.ValidString(p) || !strings.HasSuffix(p, "...") {
+		t.Errorf("long path not cut on a rune boundary: %d bytes, %q", len(p), p[len(p)-8:])
+	}
+	if len(got.Failure) != maxReportedFailureBytes {
+		t.Errorf("failure is %d bytes, want it cut to %d", len(got.Failure), maxReportedFailureBytes)
+	}
+}
@@ -0,0 +1,312 @@
+package backuptool
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"time"
+)
+
+// SnapshotReport is what snapshot-upload writes to its report file, the
+// container's termination message, for the controller to copy into the
+// SeaweedBackup's status.
+type SnapshotReport struct {
+	// URI is the uploaded snapshot's location.
+	URI string `json:"uri"`
+	// Size is the stored snapshot's size in bytes.
+	Size int64 `json:"size"`
+	// SHA256 is the hex SHA-256 of the stored snapshot.
+	SHA256 string `json:"sha256"`
+	// Manifest is the location of the snapshot's manifest.
+	Manifest string `json:"manifest"`
+	// Encryption names the encryption scheme; empty when not encrypted.
+	Encryption string `json:"encryption,omitempty"`
+	// Volumes describes the volumes copied by volume-backup, whose report
+	// is of the backup's manifest; nil for metadata snapshots.
+	Volumes *VolumeBackupReport `json:"volumes,omitempty"`
+}
+
+// Manifest is written next to each snapshot, as manifestName, once the
+// snapshot itself is stored. Restores verify the snapshot against it before
+// loading it.
+type Manifest struct {
+	Version int `json:"version"`
+	// File is the snapshot's name within the manifest's directory.
+	File string `json:"file"`
+	// Size and SHA256 describe the stored bytes, i.e. the ciphertext of an
+	// encrypted snapshot.
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	// Encryption and KeyID name the scheme and the key-encryption key of an
+	// encrypted snapshot.
+	Encryption string    `json:"encryption,omitempty"`
+	KeyID      string    `json:"keyID,omitempty"`
+	Created    time.Time `json:"created"`
+}
+
+const (
+	// manifestName is the manifest's name, next to the snapshot.
+	manifestName = "manifest.json"
+
+	// defaultReportFile is the Kubernetes termination-message path.
+	defaultReportFile = "/dev/termination-log"
+)
+
+// manifestKey is the key of the manifest describing the snapshot at key.
+func manifestKey(key string) string {
+	return path.Join(path.Dir(key), manifestName)
+}
+
+func runSnapshotUpload(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("snapshot-upload", flag.ContinueOnError)
+	var sf storeFlags
+	sf.register(fs)
+	file := fs.String("file", "", "local snapshot to upload")
+	key := fs.String("key", "", "object key to upload to")
+	encrypt := fs.Bool("encrypt", false, "encrypt the snapshot with the key in "+envEncryptionKey)
+	report := fs.String("report", defaultReportFile, "where to write the JSON upload report")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" || *key == "" {
+		return errors.New("-file and -key are required")
+	}
+	var kek []byte
+	if *encrypt {
+		k, err := loadKey()
+		if err != nil {
+			return err
+		}
+		if k == nil {
+			return fmt.Errorf("-encrypt needs %s", envEncryptionKey)
+		}
+		kek = k
+	}
+	store, err := sf.open(ctx)
+	if err != nil {
+		return err
+	}
+	rep, err := uploadSnapshot(ctx, store, *file, objectKey(*key), kek)
+	if err != nil {
+		return err
+	}
+	log.Printf("uploaded %d bytes to %s (sha256 %s)", rep.Size, rep.URI, rep.SHA256)
+	data, err := json.Marshal(rep)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*report, data, 0o644)
+}
+
+// uploadSnapshot stores file as key, encrypted under kek when it is set, then
+// writes its manifest.
+func uploadSnapshot(ctx context.Context, store objectStore, file, key string, kek []byte) (SnapshotReport, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return SnapshotReport{}, err
+	}
+	if info.Size() == 0 {
+		return SnapshotReport{}, fmt.Errorf("%s is empty", file)
+	}
+	manifest := Manifest{Version: 1, File: path.Base(key), Created: time.Now().UTC()}
+	if kek != nil {
+		enc := file + ".enc"
+		if err := transformFile(enc, file, func(w io.Writer, r io.Reader) error { return encryptStream(w, r, kek) }); err != nil {
+			return SnapshotReport{}, fmt.Errorf("encrypt %s: %w", file, err)
+		}
+		defer os.Remove(enc)
+		file = enc
+		manifest.Encryption, manifest.KeyID = encryptionAlgorithm, keyID(kek)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return SnapshotReport{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return SnapshotReport{}, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return SnapshotReport{}, err
+	}
+	if err := store.Put(ctx, key, f, size); err != nil {
+		return SnapshotReport{}, fmt.Errorf("upload %s: %w", store.URI(key), err)
+	}
+	manifest.Size, manifest.SHA256 = size, hex.EncodeToString(h.Sum(nil))
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return SnapshotReport{}, err
+	}
+	mkey := manifestKey(key)
+	if err := store.Put(ctx, mkey, bytes.NewReader(data), int64(len(data))); err != nil {
+		return SnapshotReport{}, fmt.Errorf("upload %s: %w", store.URI(mkey), err)
+	}
+	return SnapshotReport{
+		URI:        store.URI(key),
+		Size:       size,
+		SHA256:     manifest.SHA256,
+		Manifest:   store.URI(mkey),
+		Encryption: manifest.Encryption,
+	}, nil
+}
+
+func runSnapshotDownload(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("snapshot-download", flag.ContinueOnError)
+	var sf storeFlags
+	sf.register(fs)
+	key := fs.String("key", "", "object key to download")
+	out := fs.String("o", "", "local file to write the snapshot to")
+	expect := fs.String("sha256", "", "expected hex SHA-256 of the stored snapshot")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *key == "" || *out == "" {
+		return errors.New("-key and -o are required")
+	}
+	kek, err := loadKey()
+	if err != nil {
+		return err
+	}
+	store, err := sf.open(ctx)
+	if err != nil {
+		return err
+	}
+	n, err := downloadSnapshot(ctx, store, objectKey(*key), *out, *expect, kek)
+	if err != nil {
+		return err
+	}
+	log.Printf("downloaded and verified %d bytes from %s", n, store.URI(objectKey(*key)))
+	return nil
+}
+
+// downloadSnapshot writes the snapshot at key to file and returns its stored
+// size. The stored bytes are first checked against the snapshot's manifest
+// and, when set, expectSHA256; an encrypted snapshot is then decrypted with
+// kek. Nothing is written to file unless the snapshot verifies. Snapshots
+// taken before manifests were written are accepted unverified when
+// expectSHA256 is empty.
+func downloadSnapshot(ctx context.Context, store objectStore, key, file, expectSHA256 string, kek []byte) (int64, error) {
+	uri := store.URI(key)
+	manifest, err := fetchManifest(ctx, store, key)
+	if err != nil {
+		return 0, err
+	}
+	encrypted := manifest != nil && manifest.Encryption != ""
+	if encrypted {
+		if manifest.Encryption != encryptionAlgorithm {
+			return 0, fmt.Errorf("%s: unsupported encryption %q", uri, manifest.Encryption)
+		}
+		if kek == nil {
+			return 0, fmt.Errorf("%s is encrypted; %s is not set", uri, envEncryptionKey)
+		}
+		if id := keyID(kek); manifest.KeyID != "" && manifest.KeyID != id {
+			return 0, fmt.Errorf("%s is encrypted with key %s, not with the configured key %s", uri, manifest.KeyID, id)
+		}
+	}
+
+	tmp := file + ".download"
+	defer os.Remove(tmp)
+	n, sum, err := fetchObject(ctx, store, key, tmp)
+	if err != nil {
+		return 0, fmt.Errorf("download %s: %w", uri, err)
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("%s is empty", uri)
+	}
+	switch {
+	case manifest != nil && (n != manifest.Size || sum != manifest.SHA256):
+		return 0, fmt.Errorf("%w: %s is %d bytes with sha256 %s; its manifest says %d bytes with sha256 %s",
+			errIntegrity, uri, n, sum, manifest.Size, manifest.SHA256)
+	case expectSHA256 != "" && sum != expectSHA256:
+		return 0, fmt.Errorf("%w: %s has sha256 %s; the backup recorded %s", errIntegrity, uri, sum, expectSHA256)
+	case manifest == nil && expectSHA256 == "":
+		log.Printf("%s has no manifest; loading it unverified", uri)
+	}
+
+	if !encrypted {
+		return n, os.Rename(tmp, file)
+	}
+	if err := transformFile(file, tmp, func(w io.Writer, r io.Reader) error { return decryptStream(w, r, kek) }); err != nil {
+		return 0, fmt.Errorf("decrypt %s: %w", uri, err)
+	}
+	return n, nil
+}
+
+// fetchManifest returns the manifest of the snapshot at key, or nil when it
+// has none.
+func fetchManifest(ctx context.Context, store objectStore, key string) (*Manifest, error) {
+	mkey := manifestKey(key)
+	r, err := store.Get(ctx, mkey)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", store.URI(mkey), err)
+	}
+	defer r.Close()
+	var m Manifest
+	if err := json.NewDecoder(io.LimitReader(r, 1<<20)).Decode(&m); err != nil {
+		return nil, fmt.Errorf("%w: malformed manifest %s: %v", errIntegrity, store.URI(mkey), err)
+	}
+	if m.File != path.Base(key) {
+		return nil, fmt.Errorf("%w: manifest %s describes %q, not %q", errIntegrity, store.URI(mkey), m.File, path.Base(key))
+	}
+	return &m, nil
+}
+
+// fetchObject writes key to file and returns its size and hex SHA-256.
+func fetchObject(ctx context.Context, store objectStore, key, file string) (int64, string, error) {
+	r, err := store.Get(ctx, key)
+	if err != nil {
+		return 0, "", err
+	}
+	defer r.Close()
+	f, err := os.Create(file)
+	if err != nil {
+		return 0, "", err
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), err
+}
+
+func runSnapshotDelete(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("snapshot-delete", flag.ContinueOnError)
+	var sf storeFlags
+	sf.register(fs)
+	key := fs.String("key", "", "object key to delete")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *key == "" {
+		return errors.New("-key is required")
+	}
+	store, err := sf.open(ctx)
+	if err != nil {
+		return err
+	}
+	k := objectKey(*key)
+	for _, del := range []string{k, manifestKey(k)} {
+		if err := store.Delete(ctx, del); err != nil {
+			return fmt.Errorf("delete %s: %w", store.URI(del), err)
+		}
+		log.Printf("deleted %s", store.URI(del))
+	}
+	return nil
+}
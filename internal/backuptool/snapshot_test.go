@@ -0,0 +1,330 @@
+package backuptool
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memStore is an in-memory objectStore.
+type memStore struct {
+	objects map[string][]byte
+}
+
+func (s *memStore) Put(_ context.Context, key string, r io.Reader, size int64) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) != size {
+		return fmt.Errorf("read %d bytes, announced %d", len(data), size)
+	}
+	s.objects[key] = data
+	return nil
+}
+
+func (s *memStore) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memStore) Delete(_ context.Context, key string) error {
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *memStore) URI(key string) string { return "mem://" + key }
+
+func TestSnapshotUploadDownloadRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "filer.meta.gz")
+	content := []byte("snapshot bytes")
+	if err := os.WriteFile(src, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store := &memStore{objects: map[string][]byte{}}
+
+	rep, err := uploadSnapshot(ctx, store, src, objectKey("/backups/c1/bk1/filer.meta.gz"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	want := SnapshotReport{
+		URI:      "mem://backups/c1/bk1/filer.meta.gz",
+		Size:     int64(len(content)),
+		SHA256:   hex.EncodeToString(sum[:]),
+		Manifest: "mem://backups/c1/bk1/manifest.json",
+	}
+	if rep != want {
+		t.Errorf("report = %+v, want %+v", rep, want)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(store.objects["backups/c1/bk1/manifest.json"], &manifest); err != nil {
+		t.Fatalf("manifest: %v", err)
+	}
+	if manifest.File != "filer.meta.gz" || manifest.SHA256 != want.SHA256 || manifest.Size != want.Size {
+		t.Errorf("manifest = %+v", manifest)
+	}
+
+	dst := filepath.Join(dir, "restore.meta.gz")
+	n, err := downloadSnapshot(ctx, store, "backups/c1/bk1/filer.meta.gz", dst, want.SHA256, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(dst)
+	if n != int64(len(content)) || !bytes.Equal(got, content) {
+		t.Errorf("downloaded %q (%d bytes), want %q", got, n, content)
+	}
+
+	empty := filepath.Join(dir, "empty")
+	os.WriteFile(empty, nil, 0o644)
+	if _, err := uploadSnapshot(ctx, store, empty, "x", nil); err == nil {
+		t.Error("expected an empty snapshot to be rejected")
+	}
+}
+
+func TestSnapshotEncryptedRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "filer.meta.gz")
+	content := bytes.Repeat([]byte("metadata "), encChunkSize/3) // several chunks
+	if err := os.WriteFile(src, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	kek := bytes.Repeat([]byte{7}, encKeySize)
+	store := &memStore{objects: map[string][]byte{}}
+
+	rep, err := uploadSnapshot(ctx, store, src, "c1/bk1/filer.meta.gz", kek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Encryption != encryptionAlgorithm {
+		t.Errorf("report encryption = %q", rep.Encryption)
+	}
+	if bytes.Contains(store.objects["c1/bk1/filer.meta.gz"], []byte("metadata")) {
+		t.Fatal("stored snapshot is not encrypted")
+	}
+
+	dst := filepath.Join(dir, "restore.meta.gz")
+	if _, err := downloadSnapshot(ctx, store, "c1/bk1/filer.meta.gz", dst, rep.SHA256, kek); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dst); !bytes.Equal(got, content) {
+		t.Error("decrypted snapshot differs from the original")
+	}
+
+	if _, err := downloadSnapshot(ctx, store, "c1/bk1/filer.meta.gz", dst, "", nil); err == nil {
+		t.Error("expected an encrypted snapshot to need the key")
+	}
+	other := bytes.Repeat([]byte{8}, encKeySize)
+	if _, err := downloadSnapshot(ctx, store, "c1/bk1/filer.meta.gz", dst, "", other); err == nil || !strings.Contains(err.Error(), keyID(kek)) {
+		t.Errorf("wrong key should name the snapshot's key id, got %v", err)
+	}
+}
+
+func TestSnapshotDownloadRejectsTampering(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "filer.meta.gz")
+	os.WriteFile(src, []byte("snapshot bytes"), 0o644)
+	kek := bytes.Repeat([]byte{7}, encKeySize)
+	dst := filepath.Join(dir, "restore.meta.gz")
+
+	for name, tc := range map[string]struct {
+		kek    []byte
+		tamper func(store *memStore, key string)
+		expect string
+	}{
+		"flipped byte": {tamper: func(s *memStore, key string) { s.objects[key][0] ^= 1 }},
+		"flipped ciphertext with matching manifest": {kek: kek, tamper: func(s *memStore, key string) {
+			data := s.objects[key]
+			data[len(data)-1] ^= 1
+			sum := sha256.Sum256(data)
+			var m Manifest
+			json.Unmarshal(s.objects[manifestKey(key)], &m)
+			m.SHA256 = hex.EncodeToString(sum[:])
+			s.objects[manifestKey(key)], _ = json.Marshal(m)
+		}},
+		"malformed manifest":  {tamper: func(s *memStore, key string) { s.objects[manifestKey(key)] = []byte("{") }},
+		"differs from status": {tamper: func(*memStore, string) {}, expect: strings.Repeat("0", 64)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := &memStore{objects: map[string][]byte{}}
+			if _, err := uploadSnapshot(ctx, store, src, "c1/bk1/filer.meta.gz", tc.kek); err != nil {
+				t.Fatal(err)
+			}
+			tc.tamper(store, "c1/bk1/filer.meta.gz")
+			_, err := downloadSnapshot(ctx, store, "c1/bk1/filer.meta.gz", dst, tc.expect, tc.kek)
+			if !errors.Is(err, errIntegrity) {
+				t.Fatalf("err = %v, want an integrity error", err)
+			}
+			if _, err := os.Stat(dst); !os.IsNotExist(err) {
+				t.Error("a snapshot failing verification must not be written")
+			}
+		})
+	}
+
+	// Snapshots taken before manifests existed still restore.
+	store := &memStore{objects: map[string][]byte{"c1/old/filer.meta.gz": []byte("legacy")}}
+	if _, err := downloadSnapshot(ctx, store, "c1/old/filer.meta.gz", dst, "", nil); err != nil {
+		t.Errorf("legacy snapshot: %v", err)
+	}
+}
+
+func TestDecryptStreamDetectsTruncation(t *testing.T) {
+	kek := bytes.Repeat([]byte{1}, encKeySize)
+	for _, size := range []int{0, 10, encChunkSize, 2*encChunkSize + 5} {
+		plain := bytes.Repeat([]byte{'x'}, size)
+		var enc bytes.Buffer
+		if err := encryptStream(&enc, bytes.NewReader(plain), kek); err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		if err := decryptStream(&out, bytes.NewReader(enc.Bytes()), kek); err != nil || !bytes.Equal(out.Bytes(), plain) {
+			t.Fatalf("size %d: round trip failed: %v", size, err)
+		}
+		// Dropping the final chunk, even when it is empty, must not verify.
+		cut := enc.Len() - 16 - size%encChunkSize
+		if err := decryptStream(io.Discard, bytes.NewReader(enc.Bytes()[:cut]), kek); !errors.Is(err, errIntegrity) {
+			t.Errorf("size %d: truncated stream: err = %v", size, err)
+		}
+	}
+}
+
+func TestFilesystemStore(t *testing.T) {
+	ctx := context.Background()
+	s := &fsStore{root: t.TempDir()}
+	if err := s.Put(ctx, "c1/bk1/filer.meta.gz", strings.NewReader("x"), 1); err != nil {
+		t.Fatal(err)
+	}
+	r, err := s.Get(ctx, "c1/bk1/filer.meta.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if err := s.Delete(ctx, "c1/bk1/filer.meta.gz"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, "c1/bk1/filer.meta.gz"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("get after delete: %v", err)
+	}
+	if err := s.Delete(ctx, "c1/bk1/filer.meta.gz"); err != nil {
+		t.Errorf("deleting a missing key: %v", err)
+	}
+}
+
+func TestObjectKey(t *testing.T) {
+	for in, want := range map[string]string{
+		"/a/b":        "a/b",
+		"a//b/":       "a/b",
+		"/dir/../x":   "x",
+		"c1/bk1/file": "c1/bk1/file",
+	} {
+		if got := objectKey(in); got != want {
+			t.Errorf("objectKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestB2PutAndGet(t *testing.T) {
+	var stored []byte
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/b2api/v2/b2_authorize_account":
+			if user, pass, _ := r.BasicAuth(); user != "acct" || pass != "key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{
+				"accountId": "acct", "authorizationToken": "tok", "apiUrl": srv.URL, "downloadUrl": srv.URL,
+			})
+		case "/b2api/v2/b2_list_buckets":
+			json.NewEncoder(w).Encode(map[string]any{"buckets": []map[string]string{{"bucketId": "bid"}}})
+		case "/b2api/v2/b2_get_upload_url":
+			json.NewEncoder(w).Encode(map[string]string{"uploadUrl": srv.URL + "/upload", "authorizationToken": "utok"})
+		case "/upload":
+			body, _ := io.ReadAll(r.Body)
+			if r.Header.Get("X-Bz-Content-Sha1") != "hex_digits_at_end" || len(body) < 40 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			content, digest := body[:len(body)-40], string(body[len(body)-40:])
+			sum := sha1.Sum(content)
+			if digest != hex.EncodeToString(sum[:]) || r.Header.Get("X-Bz-File-Name") != "c1/bk1/filer.meta.gz" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			stored = content
+			w.Write([]byte("{}"))
+		case "/b2api/v2/b2_list_file_versions":
+			files := []map[string]string{{"fileId": "f2", "fileName": "c1/bk1/filer.meta.gz.old"}}
+			if stored != nil {
+				files = append([]map[string]string{{"fileId": "f1", "fileName": "c1/bk1/filer.meta.gz"}}, files...)
+			}
+			json.NewEncoder(w).Encode(map[string]any{"files": files})
+		case "/b2api/v2/b2_delete_file_version":
+			var in map[string]string
+			json.NewDecoder(r.Body).Decode(&in)
+			if in["fileId"] != "f1" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			stored = nil
+			w.Write([]byte("{}"))
+		case "/file/bkt/c1/bk1/filer.meta.gz":
+			if r.Header.Get("Authorization") != "tok" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write(stored)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	s, err := authorizeB2(ctx, srv.Client(), srv.URL+"/b2api/v2/b2_authorize_account", "acct", "key", "bkt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "snapshot bytes"
+	if err := s.Put(ctx, "c1/bk1/filer.meta.gz", strings.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	r, err := s.Get(ctx, "c1/bk1/filer.meta.gz")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	got, _ := io.ReadAll(r)
+	r.Close()
+	if string(got) != content {
+		t.Errorf("got %q, want %q", got, content)
+	}
+	if err := s.Delete(ctx, "c1/bk1/filer.meta.gz"); err != nil || stored != nil {
+		t.Fatalf("delete: %v (stored %q)", err, stored)
+	}
+	if err := s.Delete(ctx, "c1/bk1/filer.meta.gz"); err != nil {
+		t.Errorf("deleting a missing key: %v", err)
+	}
+	if uri := s.URI("c1/bk1/filer.meta.gz"); uri != "b2://bkt/c1/bk1/filer.meta.gz" {
+		t.Errorf("URI = %q", uri)
+	}
+}
@@ -0,0 +1,976 @@
+package backuptool
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb"
+	"github.com/seaweedfs/seaweedfs/weed/pb/master_pb"
+	"github.com/seaweedfs/seaweedfs/weed/pb/volume_server_pb"
+	"google.golang.org/grpc"
+)
+
+// A volumes backup copies the .dat and .idx file of each volume in fixed-size
+// segments. The backup's manifest lists every segment with its plaintext
+// SHA-256, so the next backup re-uploads only the segments that changed: a
+// volume that is only appended to shares all but its last segments with the
+// previous backup, while a compacted volume (a new compaction revision) is
+// copied whole. Under a cluster's volumes prefix the layout is
+//
+//	index.json                      the backups whose segments are live
+//	<backup>/manifest.json          one VolumeManifest per backup
+//	<backup>/<volume>.<ext>/<offset>  the segments the backup uploaded
+//
+// Segments are shared between backups, so they are only deleted once no
+// backup in the index references them.
+const (
+	volumeIndexName = "index.json"
+
+	// defaultVolumeSegmentMB is the size, in MiB, of the segments volume files
+	// are stored in. It bounds both the scratch space a backup needs and what
+	// an append to a volume costs the next backup.
+	defaultVolumeSegmentMB = 256
+)
+
+// VolumeBackupReport is the part of volume-backup's report describing the
+// volumes copied; the report itself is a SnapshotReport of the backup's
+// manifest.
+type VolumeBackupReport struct {
+	// Count is the number of volumes in the backup.
+	Count int `json:"count"`
+	// Size is the size of their files, in bytes.
+	Size int64 `json:"size"`
+	// Uploaded is the number of bytes this backup stored.
+	Uploaded int64 `json:"uploaded"`
+	// Skipped is the number of volumes that cannot be copied: erasure-coded
+	// volumes and volumes moved to a remote tier.
+	Skipped int `json:"skipped"`
+}
+
+// VolumeRestoreReport is what volume-restore writes to its report file, the
+// container's termination message, for the controller to copy into the
+// SeaweedRestore's status.
+type VolumeRestoreReport struct {
+	// Restored is the number of volumes assembled for the cluster.
+	Restored int `json:"restored"`
+	// Bytes is the size of their files, in bytes.
+	Bytes int64 `json:"bytes"`
+	// Present is the number of volumes left out because the cluster has
+	// them.
+	Present int `json:"present"`
+}
+
+// VolumeManifest describes one volumes backup.
+type VolumeManifest struct {
+	Version int `json:"version"`
+	// Backup is the backup's directory under the volumes prefix.
+	Backup string `json:"backup"`
+	// Encryption and KeyID name the scheme and the key-encryption key the
+	// segments are encrypted with.
+	Encryption string        `json:"encryption,omitempty"`
+	KeyID      string        `json:"keyID,omitempty"`
+	Created    time.Time     `json:"created"`
+	Volumes    []VolumeCopy  `json:"volumes"`
+	Skipped    []SkippedCopy `json:"skipped,omitempty"`
+}
+
+// VolumeCopy is one volume of a backup.
+type VolumeCopy struct {
+	ID                 uint32     `json:"id"`
+	Collection         string     `json:"collection,omitempty"`
+	CompactionRevision uint32     `json:"compactionRevision"`
+	Dat                VolumeFile `json:"dat"`
+	Idx                VolumeFile `json:"idx"`
+}
+
+// SkippedCopy is a volume a backup could not copy, and why.
+type SkippedCopy struct {
+	ID         uint32 `json:"id"`
+	Collection string `json:"collection,omitempty"`
+	Reason     string `json:"reason"`
+}
+
+// VolumeFile is a volume's .dat or .idx file: its plaintext size and SHA-256,
+// and the segments it is stored in, in order.
+type VolumeFile struct {
+	Size     int64           `json:"size"`
+	SHA256   string          `json:"sha256"`
+	Segments []VolumeSegment `json:"segments,omitempty"`
+}
+
+// VolumeSegment is a stored range of a volume file. Key is relative to the
+// volumes prefix and may belong to an earlier backup.
+type VolumeSegment struct {
+	Key    string `json:"key"`
+	Offset int64  `json:"offset"`
+	// Size and SHA256 describe the plaintext range.
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	// StoredSize and StoredSHA256 describe the stored object, i.e. the
+	// ciphertext of an encrypted segment.
+	StoredSize   int64  `json:"storedSize"`
+	StoredSHA256 string `json:"storedSHA256"`
+}
+
+// volumeIndex lists the backups whose manifests keep segments alive.
+type volumeIndex struct {
+	Backups []string `json:"backups"`
+}
+
+// volumeRef is a volume and the volume server a backup reads it from.
+type volumeRef struct {
+	id         uint32
+	collection string
+	// server is the volume server's gRPC address.
+	server string
+}
+
+// fileName is the volume's file name without extension, the way volume
+// servers name it on disk.
+func (v volumeRef) fileName() string {
+	if v.collection == "" {
+		return fmt.Sprint(v.id)
+	}
+	return fmt.Sprintf("%s_%d", v.collection, v.id)
+}
+
+// volumeStat is the state of a volume a backup copies.
+type volumeStat struct {
+	datSize, idxSize int64
+	revision         uint32
+}
+
+// volumeFiles reads volume files off the volume servers.
+type volumeFiles interface {
+	// stat returns the current sizes of v's files and its compaction
+	// revision.
+	stat(ctx context.Context, v volumeRef) (volumeStat, error)
+	// copy writes the first size bytes of v's file ext ("dat" or "idx") to
+	// w. It fails if v was compacted past revision.
+	copy(ctx context.Context, v volumeRef, ext string, revision uint32, size int64, w io.Writer) error
+}
+
+func runVolumeBackup(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("volume-backup", flag.ContinueOnError)
+	var sf storeFlags
+	sf.register(fs)
+	master := fs.String("master", "", "comma-separated master addresses (host:port)")
+	tlsDir := fs.String("tlsDir", "", "directory holding ca.crt, tls.crt and tls.key for gRPC mTLS")
+	var collections stringList
+	fs.Var(&collections, "collection", "collection to copy, \"\" for the default one; repeatable. Without any, every volume is copied")
+	prefix := fs.String("prefix", "", "key prefix of the cluster's volumes backups")
+	name := fs.String("name", "", "directory of this backup under -prefix")
+	previous := fs.String("previous", "", "directory of the backup to copy incrementally against")
+	encrypt := fs.Bool("encrypt", false, "encrypt the segments with the key in "+envEncryptionKey)
+	scratch := fs.String("scratch", os.TempDir(), "directory segments are staged in")
+	segmentMB := fs.Int("segmentSizeMB", defaultVolumeSegmentMB, "size of the segments volume files are stored in, in MiB")
+	report := fs.String("report", defaultReportFile, "where to write the JSON backup report")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *master == "" || *prefix == "" || *name == "" {
+		return errors.New("-master, -prefix and -name are required")
+	}
+	if *segmentMB <= 0 {
+		return errors.New("-segmentSizeMB must be positive")
+	}
+	var kek []byte
+	if *encrypt {
+		k, err := loadKey()
+		if err != nil {
+			return err
+		}
+		if k == nil {
+			return fmt.Errorf("-encrypt needs %s", envEncryptionKey)
+		}
+		kek = k
+	}
+	var only map[string]bool
+	if len(collections) > 0 {
+		only = map[string]bool{}
+		for _, c := range collections {
+			only[c] = true
+		}
+	}
+	store, err := sf.open(ctx)
+	if err != nil {
+		return err
+	}
+	dialOption, err := tlsDialOption(*tlsDir)
+	if err != nil {
+		return err
+	}
+	topo, err := masterTopology(ctx, strings.Split(*master, ","), dialOption)
+	if err != nil {
+		return err
+	}
+	files := &grpcVolumeFiles{dialOption: dialOption, conns: map[string]*grpc.ClientConn{}}
+	defer files.close()
+
+	vb := &volumeBackup{
+		store:       store,
+		files:       files,
+		prefix:      objectKey(*prefix),
+		name:        strings.Trim(*name, "/"),
+		scratch:     *scratch,
+		segmentSize: int64(*segmentMB) << 20,
+		kek:         kek,
+	}
+	vols, skipped := selectVolumes(topo, only)
+	var base *VolumeManifest
+	if *previous != "" {
+		if base, err = vb.loadManifest(ctx, strings.Trim(*previous, "/")); errors.Is(err, os.ErrNotExist) {
+			log.Printf("previous backup %s is gone; copying every volume whole", *previous)
+		} else if err != nil {
+			return err
+		}
+	}
+	rep, err := vb.run(ctx, vols, skipped, base)
+	if err != nil {
+		return err
+	}
+	log.Printf("backed up %d volumes (%d bytes, %d uploaded) to %s; %d skipped", rep.Volumes.Count, rep.Volumes.Size, rep.Volumes.Uploaded, rep.Manifest, rep.Volumes.Skipped)
+	data, err := json.Marshal(rep)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*report, data, 0o644)
+}
+
+// volumeBackup copies a cluster's volumes into a store.
+type volumeBackup struct {
+	store       objectStore
+	files       volumeFiles
+	prefix      string
+	name        string
+	scratch     string
+	segmentSize int64
+	kek         []byte
+}
+
+// run copies vols, sharing the segments that did not change since base when
+// it is set, then writes the backup's manifest and adds it to the index.
+func (vb *volumeBackup) run(ctx context.Context, vols []volumeRef, skipped []SkippedCopy, base *VolumeManifest) (SnapshotReport, error) {
+	manifest := VolumeManifest{Version: 1, Backup: vb.name, Created: time.Now().UTC(), Skipped: skipped}
+	if vb.kek != nil {
+		manifest.Encryption, manifest.KeyID = encryptionAlgorithm, keyID(vb.kek)
+	}
+	// Segments of another key, or of plaintext, cannot be mixed into this
+	// backup.
+	if base != nil && (base.Encryption != manifest.Encryption || base.KeyID != manifest.KeyID) {
+		log.Printf("previous backup %s is encrypted differently; copying every volume whole", base.Backup)
+		base = nil
+	}
+	previous := map[uint32]VolumeCopy{}
+	if base != nil {
+		for _, v := range base.Volumes {
+			previous[v.ID] = v
+		}
+	}
+
+	rep := VolumeBackupReport{Skipped: len(skipped)}
+	for _, v := range vols {
+		st, err := vb.files.stat(ctx, v)
+		if err != nil {
+			return SnapshotReport{}, fmt.Errorf("volume %d: %w", v.id, err)
+		}
+		vc := VolumeCopy{ID: v.id, Collection: v.collection, CompactionRevision: st.revision}
+		var prevDat, prevIdx []VolumeSegment
+		if p, ok := previous[v.id]; ok && p.Collection == v.collection && p.CompactionRevision == st.revision {
+			prevDat, prevIdx = p.Dat.Segments, p.Idx.Segments
+		}
+		var uploaded int64
+		if vc.Dat, uploaded, err = vb.copyFile(ctx, v, "dat", st.revision, st.datSize, prevDat); err != nil {
+			return SnapshotReport{}, fmt.Errorf("volume %d: %w", v.id, err)
+		}
+		rep.Uploaded += uploaded
+		if vc.Idx, uploaded, err = vb.copyFile(ctx, v, "idx", st.revision, st.idxSize, prevIdx); err != nil {
+			return SnapshotReport{}, fmt.Errorf("volume %d: %w", v.id, err)
+		}
+		rep.Uploaded += uploaded
+		rep.Count++
+		rep.Size += vc.Dat.Size + vc.Idx.Size
+		manifest.Volumes = append(manifest.Volumes, vc)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return SnapshotReport{}, err
+	}
+	mkey := path.Join(vb.prefix, vb.name, manifestName)
+	if err := vb.store.Put(ctx, mkey, bytes.NewReader(data), int64(len(data))); err != nil {
+		return SnapshotReport{}, fmt.Errorf("upload %s: %w", vb.store.URI(mkey), err)
+	}
+	if err := updateVolumeIndex(ctx, vb.store, vb.prefix, func(idx *volumeIndex) {
+		for _, b := range idx.Backups {
+			if b == vb.name {
+				return
+			}
+		}
+		idx.Backups = append(idx.Backups, vb.name)
+	}); err != nil {
+		return SnapshotReport{}, err
+	}
+	sum := sha256.Sum256(data)
+	return SnapshotReport{
+		URI:        vb.store.URI(mkey),
+		Size:       rep.Size,
+		SHA256:     hex.EncodeToString(sum[:]),
+		Manifest:   vb.store.URI(mkey),
+		Encryption: manifest.Encryption,
+		Volumes:    &rep,
+	}, nil
+}
+
+// copyFile copies the first size bytes of v's file ext and returns how it is
+// stored and how many bytes were uploaded. A segment of previous with the
+// same range and plaintext digest is shared instead of uploaded again.
+func (vb *volumeBackup) copyFile(ctx context.Context, v volumeRef, ext string, revision uint32, size int64, previous []VolumeSegment) (VolumeFile, int64, error) {
+	w := &segmentWriter{
+		ctx:      ctx,
+		vb:       vb,
+		dir:      path.Join(vb.name, v.fileName()+"."+ext),
+		previous: map[int64]VolumeSegment{},
+		whole:    sha256.New(),
+	}
+	for _, s := range previous {
+		w.previous[s.Offset] = s
+	}
+	defer w.discard()
+	if size > 0 {
+		if err := vb.files.copy(ctx, v, ext, revision, size, w); err != nil {
+			return VolumeFile{}, 0, fmt.Errorf("copy .%s: %w", ext, err)
+		}
+	}
+	if err := w.flush(); err != nil {
+		return VolumeFile{}, 0, err
+	}
+	if w.offset != size {
+		return VolumeFile{}, 0, fmt.Errorf("copy .%s: got %d bytes, expected %d", ext, w.offset, size)
+	}
+	return VolumeFile{Size: size, SHA256: hex.EncodeToString(w.whole.Sum(nil)), Segments: w.segments}, w.uploaded, nil
+}
+
+// segmentWriter cuts the volume file written to it into segments, staging
+// each in the scratch dir until it is complete.
+type segmentWriter struct {
+	ctx context.Context
+	vb  *volumeBackup
+	// dir is the key, relative to the volumes prefix, segments are uploaded
+	// under.
+	dir      string
+	previous map[int64]VolumeSegment
+	whole    hash.Hash
+
+	// offset is where the staged segment starts plus what it holds.
+	offset   int64
+	staged   *os.File
+	stagedAt int64
+	h        hash.Hash
+
+	segments []VolumeSegment
+	uploaded int64
+}
+
+func (w *segmentWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if w.staged == nil {
+			f, err := os.CreateTemp(w.vb.scratch, "segment-*")
+			if err != nil {
+				return written, err
+			}
+			w.staged, w.stagedAt, w.h = f, w.offset, sha256.New()
+		}
+		n := int64(len(p))
+		if room := w.vb.segmentSize - (w.offset - w.stagedAt); n > room {
+			n = room
+		}
+		if _, err := w.staged.Write(p[:n]); err != nil {
+			return written, err
+		}
+		w.h.Write(p[:n])
+		w.whole.Write(p[:n])
+		w.offset += n
+		written += int(n)
+		p = p[n:]
+		if w.offset-w.stagedAt == w.vb.segmentSize {
+			if err := w.flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// flush stores the staged segment, unless the previous backup has it.
+func (w *segmentWriter) flush() error {
+	if w.staged == nil {
+		return nil
+	}
+	file := w.staged.Name()
+	err := w.staged.Close()
+	w.staged = nil
+	defer os.Remove(file)
+	if err != nil {
+		return err
+	}
+	seg := VolumeSegment{Offset: w.stagedAt, Size: w.offset - w.stagedAt, SHA256: hex.EncodeToString(w.h.Sum(nil))}
+	if p, ok := w.previous[seg.Offset]; ok && p.Size == seg.Size && p.SHA256 == seg.SHA256 {
+		w.segments = append(w.segments, p)
+		return nil
+	}
+
+	if w.vb.kek != nil {
+		enc := file + ".enc"
+		if err := transformFile(enc, file, func(dst io.Writer, src io.Reader) error { return encryptStream(dst, src, w.vb.kek) }); err != nil {
+			return fmt.Errorf("encrypt segment: %w", err)
+		}
+		defer os.Remove(enc)
+		file = enc
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	stored, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	seg.Key = path.Join(w.dir, fmt.Sprint(seg.Offset))
+	key := path.Join(w.vb.prefix, seg.Key)
+	if err := w.vb.store.Put(w.ctx, key, f, stored); err != nil {
+		return fmt.Errorf("upload %s: %w", w.vb.store.URI(key), err)
+	}
+	seg.StoredSize, seg.StoredSHA256 = stored, hex.EncodeToString(h.Sum(nil))
+	w.segments = append(w.segments, seg)
+	w.uploaded += stored
+	return nil
+}
+
+// discard removes a segment left staged by a failed copy.
+func (w *segmentWriter) discard() {
+	if w.staged != nil {
+		w.staged.Close()
+		os.Remove(w.staged.Name())
+	}
+}
+
+// loadManifest reads the manifest of the backup in dir name.
+func (vb *volumeBackup) loadManifest(ctx context.Context, name string) (*VolumeManifest, error) {
+	m, _, err := fetchVolumeManifest(ctx, vb.store, path.Join(vb.prefix, name, manifestName))
+	return m, err
+}
+
+// fetchVolumeManifest reads the volumes manifest at key and returns it with
+// the hex SHA-256 of its bytes. A missing manifest is an error wrapping
+// os.ErrNotExist.
+func fetchVolumeManifest(ctx context.Context, store objectStore, key string) (*VolumeManifest, string, error) {
+	r, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, "", fmt.Errorf("download %s: %w", store.URI(key), err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, 64<<20))
+	if err != nil {
+		return nil, "", fmt.Errorf("download %s: %w", store.URI(key), err)
+	}
+	var m VolumeManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, "", fmt.Errorf("%w: malformed manifest %s: %v", errIntegrity, store.URI(key), err)
+	}
+	sum := sha256.Sum256(data)
+	return &m, hex.EncodeToString(sum[:]), nil
+}
+
+// updateVolumeIndex applies fn to the index under prefix and stores it.
+func updateVolumeIndex(ctx context.Context, store objectStore, prefix string, fn func(*volumeIndex)) error {
+	key := path.Join(prefix, volumeIndexName)
+	var idx volumeIndex
+	r, err := store.Get(ctx, key)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("download %s: %w", store.URI(key), err)
+	default:
+		err = json.NewDecoder(io.LimitReader(r, 16<<20)).Decode(&idx)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("malformed index %s: %w", store.URI(key), err)
+		}
+	}
+	fn(&idx)
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := store.Put(ctx, key, bytes.NewReader(data), int64(len(data))); err != nil {
+		return fmt.Errorf("upload %s: %w", store.URI(key), err)
+	}
+	return nil
+}
+
+// selectVolumes picks the volumes of topo a backup copies, those of the
+// collections in only when it is set, each from the replica reporting the
+// largest size. Erasure-coded and remote-tiered volumes are returned as
+// skipped. Both come back sorted by id.
+func selectVolumes(topo *master_pb.TopologyInfo, only map[string]bool) ([]volumeRef, []SkippedCopy) {
+	picked := map[uint32]volumeRef{}
+	sizes := map[uint32]uint64{}
+	skipped := map[uint32]SkippedCopy{}
+	for _, dc := range topo.GetDataCenterInfos() {
+		for _, rack := range dc.GetRackInfos() {
+			for _, dn := range rack.GetDataNodeInfos() {
+				server := pb.NewServerAddressFromDataNode(dn).ToGrpcAddress()
+				for _, disk := range dn.GetDiskInfos() {
+					for _, v := range disk.GetVolumeInfos() {
+						if only != nil && !only[v.GetCollection()] {
+							continue
+						}
+						if v.GetRemoteStorageName() != "" {
+							skipped[v.GetId()] = SkippedCopy{ID: v.GetId(), Collection: v.GetCollection(), Reason: "on remote tier " + v.GetRemoteStorageName()}
+							continue
+						}
+						if _, ok := picked[v.GetId()]; ok && v.GetSize() <= sizes[v.GetId()] {
+							continue
+						}
+						picked[v.GetId()] = volumeRef{id: v.GetId(), collection: v.GetCollection(), server: server}
+						sizes[v.GetId()] = v.GetSize()
+					}
+					for _, s := range disk.GetEcShardInfos() {
+						if only != nil && !only[s.GetCollection()] {
+							continue
+						}
+						skipped[s.GetId()] = SkippedCopy{ID: s.GetId(), Collection: s.GetCollection(), Reason: "erasure coded"}
+					}
+				}
+			}
+		}
+	}
+	var vols []volumeRef
+	for id, v := range picked {
+		// A replica on a remote tier takes the whole volume with it.
+		if _, ok := skipped[id]; !ok {
+			vols = append(vols, v)
+		}
+	}
+	sort.Slice(vols, func(i, j int) bool { return vols[i].id < vols[j].id })
+	var out []SkippedCopy
+	for _, s := range skipped {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return vols, out
+}
+
+// masterTopology asks the masters, in turn, for the cluster topology; only
+// the leader answers.
+func masterTopology(ctx context.Context, masters []string, dialOption grpc.DialOption) (*master_pb.TopologyInfo, error) {
+	var lastErr error
+	for _, m := range masters {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		conn, err := grpc.NewClient(pb.ServerAddress(m).ToGrpcAddress(), dialOption)
+		if err != nil {
+			lastErr = fmt.Errorf("dial master %s: %w", m, err)
+			continue
+		}
+		resp, err := master_pb.NewSeaweedClient(conn).VolumeList(ctx, &master_pb.VolumeListRequest{})
+		conn.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("list volumes on master %s: %w", m, err)
+			continue
+		}
+		return resp.GetTopologyInfo(), nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no master address")
+	}
+	return nil, lastErr
+}
+
+// grpcVolumeFiles reads volume files with the volume servers' CopyFile RPC.
+type grpcVolumeFiles struct {
+	dialOption grpc.DialOption
+	conns      map[string]*grpc.ClientConn
+}
+
+func (g *grpcVolumeFiles) client(server string) (volume_server_pb.VolumeServerClient, error) {
+	conn, ok := g.conns[server]
+	if !ok {
+		var err error
+		if conn, err = grpc.NewClient(server, g.dialOption); err != nil {
+			return nil, fmt.Errorf("dial volume server %s: %w", server, err)
+		}
+		g.conns[server] = conn
+	}
+	return volume_server_pb.NewVolumeServerClient(conn), nil
+}
+
+func (g *grpcVolumeFiles) stat(ctx context.Context, v volumeRef) (volumeStat, error) {
+	client, err := g.client(v.server)
+	if err != nil {
+		return volumeStat{}, err
+	}
+	resp, err := client.ReadVolumeFileStatus(ctx, &volume_server_pb.ReadVolumeFileStatusRequest{VolumeId: v.id})
+	if err != nil {
+		return volumeStat{}, err
+	}
+	return volumeStat{datSize: int64(resp.GetDatFileSize()), idxSize: int64(resp.GetIdxFileSize()), revision: resp.GetCompactionRevision()}, nil
+}
+
+func (g *grpcVolumeFiles) copy(ctx context.Context, v volumeRef, ext string, revision uint32, size int64, w io.Writer) error {
+	client, err := g.client(v.server)
+	if err != nil {
+		return err
+	}
+	stream, err := client.CopyFile(ctx, &volume_server_pb.CopyFileRequest{
+		VolumeId:           v.id,
+		Ext:                "." + ext,
+		CompactionRevision: revision,
+		StopOffset:         uint64(size),
+		Collection:         v.collection,
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(resp.GetFileContent()); err != nil {
+			return err
+		}
+	}
+}
+
+func (g *grpcVolumeFiles) close() {
+	for _, conn := range g.conns {
+		conn.Close()
+	}
+}
+
+func runVolumeRestore(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("volume-restore", flag.ContinueOnError)
+	var sf storeFlags
+	sf.register(fs)
+	manifestFlag := fs.String("manifest", "", "object key of the backup's manifest")
+	expect := fs.String("sha256", "", "expected hex SHA-256 of the manifest")
+	master := fs.String("master", "", "comma-separated master addresses (host:port) of the cluster restored into")
+	tlsDir := fs.String("tlsDir", "", "directory holding ca.crt, tls.crt and tls.key for gRPC mTLS")
+	dir := fs.String("o", "", "directory to assemble the volume files in")
+	report := fs.String("report", defaultReportFile, "where to write the JSON restore report")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *manifestFlag == "" || *master == "" || *dir == "" {
+		return errors.New("-manifest, -master and -o are required")
+	}
+	kek, err := loadKey()
+	if err != nil {
+		return err
+	}
+	store, err := sf.open(ctx)
+	if err != nil {
+		return err
+	}
+	dialOption, err := tlsDialOption(*tlsDir)
+	if err != nil {
+		return err
+	}
+	topo, err := masterTopology(ctx, strings.Split(*master, ","), dialOption)
+	if err != nil {
+		return err
+	}
+	rep, err := restoreVolumes(ctx, store, objectKey(*manifestFlag), *expect, kek, clusterVolumeIDs(topo), *dir)
+	if err != nil {
+		return err
+	}
+	log.Printf("assembled %d volumes (%d bytes) in %s; %d already on the cluster", rep.Restored, rep.Bytes, *dir, rep.Present)
+	data, err := json.Marshal(rep)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*report, data, 0o644)
+}
+
+// clusterVolumeIDs returns the ids of the plain and erasure-coded volumes in
+// topo.
+func clusterVolumeIDs(topo *master_pb.TopologyInfo) map[uint32]bool {
+	ids := map[uint32]bool{}
+	for _, dc := range topo.GetDataCenterInfos() {
+		for _, rack := range dc.GetRackInfos() {
+			for _, dn := range rack.GetDataNodeInfos() {
+				for _, disk := range dn.GetDiskInfos() {
+					for _, v := range disk.GetVolumeInfos() {
+						ids[v.GetId()] = true
+					}
+					for _, s := range disk.GetEcShardInfos() {
+						ids[s.GetId()] = true
+					}
+				}
+			}
+		}
+	}
+	return ids
+}
+
+// restoreVolumes assembles into dir the files of each volume of the backup
+// whose manifest is at key that is not in present, verifying the manifest
+// against expectSHA256 when set and every segment and file against the
+// manifest. A file is only given its final name once it verifies.
+func restoreVolumes(ctx context.Context, store objectStore, key, expectSHA256 string, kek []byte, present map[uint32]bool, dir string) (VolumeRestoreReport, error) {
+	uri := store.URI(key)
+	manifest, sum, err := fetchVolumeManifest(ctx, store, key)
+	if err != nil {
+		return VolumeRestoreReport{}, err
+	}
+	if expectSHA256 != "" && sum != expectSHA256 {
+		return VolumeRestoreReport{}, fmt.Errorf("%w: %s has sha256 %s; the backup recorded %s", errIntegrity, uri, sum, expectSHA256)
+	}
+	if manifest.Encryption != "" {
+		if manifest.Encryption != encryptionAlgorithm {
+			return VolumeRestoreReport{}, fmt.Errorf("%s: unsupported encryption %q", uri, manifest.Encryption)
+		}
+		if kek == nil {
+			return VolumeRestoreReport{}, fmt.Errorf("%s is encrypted; %s is not set", uri, envEncryptionKey)
+		}
+		if id := keyID(kek); manifest.KeyID != id {
+			return VolumeRestoreReport{}, fmt.Errorf("%s is encrypted with key %s, not with the configured key %s", uri, manifest.KeyID, id)
+		}
+	} else {
+		kek = nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return VolumeRestoreReport{}, err
+	}
+
+	// Segment keys are relative to the volumes prefix, the manifest's
+	// directory less the backup's.
+	dirKey := path.Dir(key)
+	prefix := strings.TrimSuffix(dirKey, "/"+manifest.Backup)
+	if manifest.Backup == "" || prefix == dirKey {
+		return VolumeRestoreReport{}, fmt.Errorf("%w: manifest %s describes backup %q", errIntegrity, uri, manifest.Backup)
+	}
+	var rep VolumeRestoreReport
+	for _, v := range manifest.Volumes {
+		if present[v.ID] {
+			rep.Present++
+			continue
+		}
+		name := volumeRef{id: v.ID, collection: v.Collection}.fileName()
+		for _, f := range []struct {
+			ext  string
+			file VolumeFile
+		}{{"idx", v.Idx}, {"dat", v.Dat}} {
+			out := filepath.Join(dir, name+"."+f.ext)
+			if err := assembleVolumeFile(ctx, store, prefix, f.file, kek, out); err != nil {
+				return rep, fmt.Errorf("volume %d .%s: %w", v.ID, f.ext, err)
+			}
+			rep.Bytes += f.file.Size
+		}
+		rep.Restored++
+	}
+	return rep, nil
+}
+
+// assembleVolumeFile writes the file stored in f's segments to out.
+func assembleVolumeFile(ctx context.Context, store objectStore, prefix string, f VolumeFile, kek []byte, out string) error {
+	tmp := out + ".download"
+	defer os.Remove(tmp)
+	dst, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	whole := sha256.New()
+	var offset int64
+	for _, seg := range f.Segments {
+		if seg.Offset != offset {
+			dst.Close()
+			return fmt.Errorf("%w: segment at %d follows %d bytes", errIntegrity, seg.Offset, offset)
+		}
+		if err := fetchSegment(ctx, store, prefix, seg, kek, io.MultiWriter(dst, whole), out); err != nil {
+			dst.Close()
+			return err
+		}
+		offset += seg.Size
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(whole.Sum(nil)); offset != f.Size || sum != f.SHA256 {
+		return fmt.Errorf("%w: assembled %d bytes with sha256 %s; the manifest says %d bytes with sha256 %s", errIntegrity, offset, sum, f.Size, f.SHA256)
+	}
+	return os.Rename(tmp, out)
+}
+
+// fetchSegment writes the plaintext of seg to dst once its stored bytes
+// verify. scratch names the file the segment is staged next to.
+func fetchSegment(ctx context.Context, store objectStore, prefix string, seg VolumeSegment, kek []byte, dst io.Writer, scratch string) error {
+	key := path.Join(prefix, seg.Key)
+	uri := store.URI(key)
+	staged := scratch + ".segment"
+	defer os.Remove(staged)
+	n, sum, err := fetchObject(ctx, store, key, staged)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: the manifest lists %s, which is missing", errIntegrity, uri)
+	}
+	if err != nil {
+		return fmt.Errorf("download %s: %w", uri, err)
+	}
+	if n != seg.StoredSize || sum != seg.StoredSHA256 {
+		return fmt.Errorf("%w: %s is %d bytes with sha256 %s; the manifest says %d bytes with sha256 %s",
+			errIntegrity, uri, n, sum, seg.StoredSize, seg.StoredSHA256)
+	}
+	f, err := os.Open(staged)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	w := &countingWriter{w: io.MultiWriter(dst, h)}
+	if kek != nil {
+		err = decryptStream(w, f, kek)
+	} else {
+		_, err = io.Copy(w, f)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", uri, err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); w.n != seg.Size || got != seg.SHA256 {
+		return fmt.Errorf("%w: %s holds %d bytes with sha256 %s; the manifest says %d bytes with sha256 %s",
+			errIntegrity, uri, w.n, got, seg.Size, seg.SHA256)
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func runVolumeDelete(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("volume-delete", flag.ContinueOnError)
+	var sf storeFlags
+	sf.register(fs)
+	prefix := fs.String("prefix", "", "key prefix of the cluster's volumes backups")
+	name := fs.String("name", "", "directory of the backup to delete under -prefix")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *prefix == "" || *name == "" {
+		return errors.New("-prefix and -name are required")
+	}
+	store, err := sf.open(ctx)
+	if err != nil {
+		return err
+	}
+	deleted, err := deleteVolumeBackup(ctx, store, objectKey(*prefix), strings.Trim(*name, "/"))
+	if err != nil {
+		return err
+	}
+	log.Printf("deleted volumes backup %s (%d segments)", *name, deleted)
+	return nil
+}
+
+// deleteVolumeBackup removes the backup name from the index under prefix,
+// then deletes its manifest and the segments it references that no backup
+// left in the index does. It returns the number of segments deleted.
+func deleteVolumeBackup(ctx context.Context, store objectStore, prefix, name string) (int, error) {
+	var others []string
+	if err := updateVolumeIndex(ctx, store, prefix, func(idx *volumeIndex) {
+		kept := idx.Backups[:0]
+		for _, b := range idx.Backups {
+			if b != name {
+				kept = append(kept, b)
+			}
+		}
+		idx.Backups = kept
+		others = append(others, kept...)
+	}); err != nil {
+		return 0, err
+	}
+
+	mkey := path.Join(prefix, name, manifestName)
+	manifest, _, err := fetchVolumeManifest(ctx, store, mkey)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	live := map[string]bool{}
+	for _, b := range others {
+		m, _, err := fetchVolumeManifest(ctx, store, path.Join(prefix, b, manifestName))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		for _, key := range m.segmentKeys() {
+			live[key] = true
+		}
+	}
+	deleted := 0
+	for _, key := range manifest.segmentKeys() {
+		if live[key] {
+			continue
+		}
+		if err := store.Delete(ctx, path.Join(prefix, key)); err != nil {
+			return deleted, fmt.Errorf("delete %s: %w", store.URI(path.Join(prefix, key)), err)
+		}
+		deleted++
+	}
+	if err := store.Delete(ctx, mkey); err != nil {
+		return deleted, fmt.Errorf("delete %s: %w", store.URI(mkey), err)
+	}
+	return deleted, nil
+}
+
+// segmentKeys returns the keys of every segment the manifest references.
+func (m *VolumeManifest) segmentKeys() []string {
+	var keys []string
+	for _, v := range m.Volumes {
+		for _, s := range v.Dat.Segments {
+			keys = append(keys, s.Key)
+		}
+		for _, s := range v.Idx.Segments {
+			keys = append(keys, s.Key)
+		}
+	}
+	return keys
+}
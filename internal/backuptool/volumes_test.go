@@ -0,0 +1,297 @@
+package backuptool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb/master_pb"
+)
+
+// fakeVolumes serves volume files from memory.
+type fakeVolumes struct {
+	dat, idx map[uint32][]byte
+	revision map[uint32]uint32
+}
+
+func (f *fakeVolumes) stat(_ context.Context, v volumeRef) (volumeStat, error) {
+	return volumeStat{datSize: int64(len(f.dat[v.id])), idxSize: int64(len(f.idx[v.id])), revision: f.revision[v.id]}, nil
+}
+
+func (f *fakeVolumes) copy(_ context.Context, v volumeRef, ext string, revision uint32, size int64, w io.Writer) error {
+	if revision != f.revision[v.id] {
+		return errors.New("volume compacted")
+	}
+	data := f.dat[v.id]
+	if ext == "idx" {
+		data = f.idx[v.id]
+	}
+	// Split the stream the way CopyFile does, in messages unaligned with the
+	// segments.
+	for data = data[:size]; len(data) > 0; {
+		n := min(len(data), 5)
+		if _, err := w.Write(data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+func newVolumeBackup(t *testing.T, store *memStore, files volumeFiles, name string, kek []byte) *volumeBackup {
+	return &volumeBackup{store: store, files: files, prefix: "c1/volumes", name: name, scratch: t.TempDir(), segmentSize: 8, kek: kek}
+}
+
+func TestVolumeBackupIsIncremental(t *testing.T) {
+	ctx := context.Background()
+	store := &memStore{objects: map[string][]byte{}}
+	files := &fakeVolumes{
+		dat:      map[uint32][]byte{1: []byte("0123456789abcdefXYZ"), 2: []byte("constant")},
+		idx:      map[uint32][]byte{1: []byte("idx1"), 2: {}},
+		revision: map[uint32]uint32{1: 0, 2: 3},
+	}
+	vols := []volumeRef{{id: 1}, {id: 2, collection: "logs"}}
+
+	first, err := newVolumeBackup(t, store, files, "b1", nil).run(ctx, vols, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Volumes.Count != 2 || first.Volumes.Size != 31 || first.Volumes.Uploaded != 31 {
+		t.Fatalf("first backup: %+v", *first.Volumes)
+	}
+
+	// Volume 1 grows; volume 2 is unchanged.
+	files.dat[1] = []byte("0123456789abcdefXYZ-more")
+	vb := newVolumeBackup(t, store, files, "b2", nil)
+	base, err := vb.loadManifest(ctx, "b1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := vb.run(ctx, vols, nil, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only volume 1's last segment, which grew, is uploaded again.
+	if second.Volumes.Uploaded != 8 {
+		t.Fatalf("second backup uploaded %d bytes, want the one changed segment", second.Volumes.Uploaded)
+	}
+	m, err := vb.loadManifest(ctx, "b2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, s := range m.Volumes[0].Dat.Segments {
+		keys = append(keys, s.Key)
+	}
+	want := []string{"b1/1.dat/0", "b1/1.dat/8", "b2/1.dat/16"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("segments = %v, want %v", keys, want)
+	}
+	if got := m.Volumes[1].Dat.Segments[0].Key; got != "b1/logs_2.dat/0" {
+		t.Fatalf("unchanged volume stored at %s", got)
+	}
+
+	// Compaction rewrites the volume: nothing of it is shared.
+	files.dat[1], files.revision[1] = []byte("compacted"), 1
+	vb = newVolumeBackup(t, store, files, "b3", nil)
+	if base, err = vb.loadManifest(ctx, "b2"); err != nil {
+		t.Fatal(err)
+	}
+	third, err := vb.run(ctx, vols, nil, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Volumes.Uploaded != int64(len("compacted")+len("idx1")) {
+		t.Fatalf("third backup uploaded %d bytes", third.Volumes.Uploaded)
+	}
+	idx := store.objects["c1/volumes/"+volumeIndexName]
+	if !bytes.Contains(idx, []byte(`"b1"`)) || !bytes.Contains(idx, []byte(`"b3"`)) {
+		t.Fatalf("index = %s", idx)
+	}
+}
+
+func TestVolumeBackupDoesNotShareAcrossKeys(t *testing.T) {
+	ctx := context.Background()
+	store := &memStore{objects: map[string][]byte{}}
+	files := &fakeVolumes{dat: map[uint32][]byte{1: []byte("data")}, idx: map[uint32][]byte{1: []byte("i")}, revision: map[uint32]uint32{}}
+	vols := []volumeRef{{id: 1}}
+	if _, err := newVolumeBackup(t, store, files, "b1", nil).run(ctx, vols, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	vb := newVolumeBackup(t, store, files, "b2", bytes.Repeat([]byte{7}, encKeySize))
+	base, err := vb.loadManifest(ctx, "b1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep, err := vb.run(ctx, vols, nil, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.objects["c1/volumes/b2/1.dat/0"]; !ok || rep.Encryption != encryptionAlgorithm {
+		t.Fatalf("encrypted backup reused plaintext segments: %+v", rep)
+	}
+}
+
+func TestVolumeRestoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	for name, kek := range map[string][]byte{"plain": nil, "encrypted": bytes.Repeat([]byte{7}, encKeySize)} {
+		t.Run(name, func(t *testing.T) {
+			store := &memStore{objects: map[string][]byte{}}
+			files := &fakeVolumes{
+				dat:      map[uint32][]byte{1: []byte("0123456789abcdefXYZ"), 2: []byte("two"), 3: []byte("three")},
+				idx:      map[uint32][]byte{1: []byte("idx1"), 2: []byte("idx2"), 3: {}},
+				revision: map[uint32]uint32{},
+			}
+			vols := []volumeRef{{id: 1}, {id: 2, collection: "logs"}, {id: 3}}
+			if _, err := newVolumeBackup(t, store, files, "b1", kek).run(ctx, vols, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			files.dat[1] = append(files.dat[1], "appended"...)
+			vb := newVolumeBackup(t, store, files, "ns/b2", kek)
+			base, err := vb.loadManifest(ctx, "b1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			rep, err := vb.run(ctx, vols, nil, base)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dir := t.TempDir()
+			got, err := restoreVolumes(ctx, store, "c1/volumes/ns/b2/manifest.json", rep.SHA256, kek, map[uint32]bool{3: true}, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Restored != 2 || got.Present != 1 || got.Bytes != int64(len(files.dat[1])+4+3+4) {
+				t.Fatalf("report = %+v", got)
+			}
+			for file, want := range map[string][]byte{"1.dat": files.dat[1], "1.idx": files.idx[1], "logs_2.dat": files.dat[2], "logs_2.idx": files.idx[2]} {
+				data, err := os.ReadFile(filepath.Join(dir, file))
+				if err != nil || !bytes.Equal(data, want) {
+					t.Fatalf("%s = %q, %v; want %q", file, data, err, want)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(dir, "3.dat")); !os.IsNotExist(err) {
+				t.Fatalf("volume the cluster has was restored: %v", err)
+			}
+		})
+	}
+}
+
+func TestVolumeRestoreRejectsTampering(t *testing.T) {
+	ctx := context.Background()
+	cases := map[string]struct {
+		tamper func(store *memStore)
+		expect string
+	}{
+		"flipped segment byte": {tamper: func(s *memStore) { s.objects["c1/volumes/b1/1.dat/8"][0] ^= 1 }},
+		"dropped segment":      {tamper: func(s *memStore) { delete(s.objects, "c1/volumes/b1/1.dat/0") }},
+		"manifest differs from status": {
+			tamper: func(*memStore) {}, expect: strings.Repeat("0", 64),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			store := &memStore{objects: map[string][]byte{}}
+			files := &fakeVolumes{dat: map[uint32][]byte{1: []byte("0123456789abcdef")}, idx: map[uint32][]byte{1: []byte("i")}, revision: map[uint32]uint32{}}
+			if _, err := newVolumeBackup(t, store, files, "b1", nil).run(ctx, []volumeRef{{id: 1}}, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			tc.tamper(store)
+			dir := t.TempDir()
+			_, err := restoreVolumes(ctx, store, "c1/volumes/b1/manifest.json", tc.expect, nil, nil, dir)
+			if err == nil {
+				t.Fatal("tampered backup restored")
+			}
+			if !errors.Is(err, errIntegrity) {
+				t.Fatalf("err = %v, want an integrity failure", err)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "1.dat")); !os.IsNotExist(err) {
+				t.Fatalf("unverified volume file left in place: %v", err)
+			}
+		})
+	}
+}
+
+func TestDeleteVolumeBackupKeepsSharedSegments(t *testing.T) {
+	ctx := context.Background()
+	store := &memStore{objects: map[string][]byte{}}
+	files := &fakeVolumes{dat: map[uint32][]byte{1: []byte("0123456789abcdef")}, idx: map[uint32][]byte{1: []byte("i")}, revision: map[uint32]uint32{}}
+	vols := []volumeRef{{id: 1}}
+	if _, err := newVolumeBackup(t, store, files, "b1", nil).run(ctx, vols, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	files.dat[1] = []byte("0123456789abcdefGH")
+	vb := newVolumeBackup(t, store, files, "b2", nil)
+	base, err := vb.loadManifest(ctx, "b1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vb.run(ctx, vols, nil, base); err != nil {
+		t.Fatal(err)
+	}
+
+	// b2 still uses b1's segments of volume 1.
+	deleted, err := deleteVolumeBackup(ctx, store, "c1/volumes", "b1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 0 {
+		t.Fatalf("deleted %d shared segments", deleted)
+	}
+	if _, ok := store.objects["c1/volumes/b1/manifest.json"]; ok {
+		t.Fatal("manifest of the deleted backup left behind")
+	}
+	if _, err := restoreVolumes(ctx, store, "c1/volumes/b2/manifest.json", "", nil, nil, t.TempDir()); err != nil {
+		t.Fatalf("restore after deleting its base: %v", err)
+	}
+
+	if deleted, err = deleteVolumeBackup(ctx, store, "c1/volumes", "b2"); err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 4 {
+		t.Fatalf("deleted %d segments, want 4", deleted)
+	}
+	for key := range store.objects {
+		if key != "c1/volumes/"+volumeIndexName {
+			t.Errorf("left behind %s", key)
+		}
+	}
+}
+
+func TestSelectVolumes(t *testing.T) {
+	node := func(id string, port uint32, vols []*master_pb.VolumeInformationMessage, ec []*master_pb.VolumeEcShardInformationMessage) *master_pb.DataNodeInfo {
+		return &master_pb.DataNodeInfo{Id: id, GrpcPort: port, DiskInfos: map[string]*master_pb.DiskInfo{"": {VolumeInfos: vols, EcShardInfos: ec}}}
+	}
+	topo := &master_pb.TopologyInfo{DataCenterInfos: []*master_pb.DataCenterInfo{{RackInfos: []*master_pb.RackInfo{{DataNodeInfos: []*master_pb.DataNodeInfo{
+		node("vs-0:8080", 18080, []*master_pb.VolumeInformationMessage{
+			{Id: 2, Size: 10},
+			{Id: 1, Collection: "logs", Size: 5},
+			{Id: 4, RemoteStorageName: "s3.cold"},
+		}, []*master_pb.VolumeEcShardInformationMessage{{Id: 7, Collection: "logs"}}),
+		node("vs-1:8080", 18080, []*master_pb.VolumeInformationMessage{
+			{Id: 2, Size: 12},
+			{Id: 4, Size: 3},
+		}, nil),
+	}}}}}}
+
+	vols, skipped := selectVolumes(topo, nil)
+	want := []volumeRef{{id: 1, collection: "logs", server: "vs-0:18080"}, {id: 2, server: "vs-1:18080"}}
+	if !reflect.DeepEqual(vols, want) {
+		t.Fatalf("volumes = %+v, want %+v", vols, want)
+	}
+	if len(skipped) != 2 || skipped[0].ID != 4 || skipped[1].ID != 7 {
+		t.Fatalf("skipped = %+v", skipped)
+	}
+
+	vols, skipped = selectVolumes(topo, map[string]bool{"logs": true})
+	if len(vols) != 1 || vols[0].id != 1 || len(skipped) != 1 || skipped[0].ID != 7 {
+		t.Fatalf("logs only: volumes %+v, skipped %+v", vols, skipped)
+	}
+}
@@ -0,0 +1,167 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// AdminMaintenanceClient reads and writes the admin server's maintenance
+// configuration: the worker_pb.MaintenanceConfig message as JSON, with proto
+// field names. It is handled as a generic document so fields the operator
+// does not manage survive a round trip.
+type AdminMaintenanceClient interface {
+	GetMaintenanceConfig(ctx context.Context) (map[string]interface{}, error)
+	UpdateMaintenanceConfig(ctx context.Context, config map[string]interface{}) error
+}
+
+// AdminCredentials log the client in when the admin server requires it.
+type AdminCredentials struct {
+	Username string
+	Password string
+}
+
+// AdminMaintenanceClientFactory builds a client for the admin server at
+// endpoint (scheme, host, port and any -urlPrefix). A nil creds skips the
+// login.
+type AdminMaintenanceClientFactory func(endpoint string, creds *AdminCredentials) AdminMaintenanceClient
+
+// adminRequestTimeout bounds a single admin server request.
+const adminRequestTimeout = 30 * time.Second
+
+// NewHTTPAdminMaintenanceClient returns a client for the admin server's
+// /api/maintenance/config endpoint. With creds it logs in through the login
+// form first and reuses the session cookie.
+func NewHTTPAdminMaintenanceClient(endpoint string, creds *AdminCredentials) AdminMaintenanceClient {
+	jar, _ := cookiejar.New(nil)
+	return &httpAdminMaintenanceClient{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		creds:    creds,
+		http: &http.Client{
+			Timeout: adminRequestTimeout,
+			Jar:     jar,
+			// A redirect means the session is missing or the login
+			// failed; report it instead of following it to an HTML page.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+type httpAdminMaintenanceClient struct {
+	endpoint string
+	creds    *AdminCredentials
+	http     *http.Client
+	loggedIn bool
+}
+
+const adminMaintenanceConfigPath = "/api/maintenance/config"
+
+func (c *httpAdminMaintenanceClient) GetMaintenanceConfig(ctx context.Context) (map[string]interface{}, error) {
+	config := map[string]interface{}{}
+	if err := c.do(ctx, http.MethodGet, adminMaintenanceConfigPath, nil, &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (c *httpAdminMaintenanceClient) UpdateMaintenanceConfig(ctx context.Context, config map[string]interface{}) error {
+	return c.do(ctx, http.MethodPut, adminMaintenanceConfigPath, config, nil)
+}
+
+// login posts the login form. The admin server answers a good login with a
+// redirect to the dashboard and a bad one with a redirect back to the form.
+func (c *httpAdminMaintenanceClient) login(ctx context.Context) error {
+	form := url.Values{"username": {c.creds.Username}, "password": {c.creds.Password}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/login", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode/100 != 2 && resp.StatusCode/100 != 3 {
+		return fmt.Errorf("admin login: %s", resp.Status)
+	}
+	if loc := resp.Header.Get("Location"); strings.Contains(loc, "/login") {
+		return errors.New("admin login rejected: check adminUser and adminPassword in the credentials secret")
+	}
+	c.loggedIn = true
+	return nil
+}
+
+// do sends one JSON request and decodes a 2xx response into out.
+func (c *httpAdminMaintenanceClient) do(ctx context.Context, method, path string, in, out interface{}) error {
+	if c.creds != nil && !c.loggedIn {
+		if err := c.login(ctx); err != nil {
+			return err
+		}
+	}
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &e) == nil && e.Error != "" {
+			return fmt.Errorf("%s %s: %s", method, path, e.Error)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("%s %s: decode response: %w", method, path, err)
+		}
+	}
+	return nil
+}
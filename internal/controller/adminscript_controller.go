@@ -33,6 +33,7 @@ import (
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -44,6 +45,16 @@ import (
 // cluster does not yet exist; the CronJob cannot be rendered until it does.
 const adminScriptRequeueAfter = 30 * time.Second
 
+// AdminScriptFinalizer keeps an AdminScript that references a cluster in
+// another namespace around until its CronJob, which lives next to the cluster
+// and so cannot be garbage-collected through an owner reference, is removed.
+const AdminScriptFinalizer = "seaweed.seaweedfs.com/adminscript-protection"
+
+// adminScriptNamespaceLabel marks a CronJob rendered into a cluster's
+// namespace with the namespace of the AdminScript it belongs to; the
+// instance label carries the AdminScript's name.
+const adminScriptNamespaceLabel = "seaweed.seaweedfs.com/adminscript-namespace"
+
 // AdminScriptReconciler reconciles an AdminScript into a native batch/v1
 // CronJob whose pod runs `weed shell` against the referenced cluster's
 // masters/filer with the spec'd script piped to stdin.
@@ -69,11 +80,58 @@ func (r *AdminScriptReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	// Resolve the referenced cluster in the AdminScript's own namespace. The
-	// owned CronJob is garbage-collected automatically when the AdminScript
-	// is deleted, so no finalizer is needed.
+	if !script.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.handleDeletion(ctx, &script)
+	}
+
+	// A CronJob in the AdminScript's own namespace is owned by it and
+	// garbage-collected with it. One rendered into another namespace needs
+	// the finalizer instead.
+	clusterNS := adminScriptClusterNamespace(&script)
+	crossNamespace := clusterNS != script.Namespace
+	if crossNamespace {
+		ref := seaweedv1.SeaweedReference{Name: script.Spec.ClusterRef.Name, Namespace: clusterNS}
+		permitted, err := seaweedRefPermitted(ctx, r.Client, ref, kindAdminScript, script.Namespace)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !permitted {
+			// A revoked grant stops the CronJob rendered while it held.
+			if err := r.deleteRenderedCronJob(ctx, &script); err != nil {
+				return ctrl.Result{}, err
+			}
+			msg := seaweedRefDeniedMessage(ref, kindAdminScript, script.Namespace)
+			r.setCondition(&script, seaweedv1.AdminScriptConditionReferenceGranted, metav1.ConditionFalse, "ReferenceGrantMissing", msg)
+			if err := r.patchStatus(ctx, &script, seaweedv1.AdminScriptPhasePending, metav1.ConditionFalse, "ReferenceGrantMissing", msg); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: adminScriptRequeueAfter}, nil
+		}
+		r.setCondition(&script, seaweedv1.AdminScriptConditionReferenceGranted, metav1.ConditionTrue, "ReferenceGranted",
+			fmt.Sprintf("a ResourceReferenceGrant in namespace %q permits the reference to cluster %q", clusterNS, ref.Name))
+		if field := adminScriptNamespacedOverride(&script); field != "" {
+			if err := r.deleteRenderedCronJob(ctx, &script); err != nil {
+				return ctrl.Result{}, err
+			}
+			msg := fmt.Sprintf("spec.%s names an object in the AdminScript's namespace, but the CronJob runs in the cluster's namespace %q; leave it unset", field, clusterNS)
+			if err := r.patchStatus(ctx, &script, seaweedv1.AdminScriptPhasePending, metav1.ConditionFalse, "UnsupportedCrossNamespace", msg); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		}
+		if !controllerutil.ContainsFinalizer(&script, AdminScriptFinalizer) {
+			controllerutil.AddFinalizer(&script, AdminScriptFinalizer)
+			if err := r.Update(ctx, &script); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{Requeue: true}, nil
+		}
+	} else {
+		meta.RemoveStatusCondition(&script.Status.Conditions, seaweedv1.AdminScriptConditionReferenceGranted)
+	}
+
 	var cluster seaweedv1.Seaweed
-	clusterKey := client.ObjectKey{Namespace: script.Namespace, Name: script.Spec.ClusterRef.Name}
+	clusterKey := client.ObjectKey{Namespace: clusterNS, Name: script.Spec.ClusterRef.Name}
 	if err := r.Get(ctx, clusterKey, &cluster); err != nil {
 		if apierrors.IsNotFound(err) {
 			log.Info("referenced Seaweed cluster not found; waiting", "cluster", clusterKey)
@@ -87,6 +145,11 @@ func (r *AdminScriptReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 
 	desired := r.buildCronJob(&script, &cluster)
+	// The cluster reference is mutable: drop the CronJob rendered for the
+	// previous one.
+	if err := r.deleteStaleCronJob(ctx, &script, desired); err != nil {
+		return ctrl.Result{}, err
+	}
 	cronJob, err := r.createOrUpdateCronJob(ctx, &script, desired)
 	if err != nil {
 		r.Recorder.Eventf(&script, corev1.EventTypeWarning, "CronJobReconcileFailed", "failed to reconcile CronJob: %v", err)
@@ -186,10 +249,13 @@ func (r *AdminScriptReconciler) buildCronJob(script *seaweedv1.AdminScript, clus
 		},
 	}
 
+	if cluster.Namespace != script.Namespace {
+		labels[adminScriptNamespaceLabel] = script.Namespace
+	}
 	return &batchv1.CronJob{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      script.Name,
-			Namespace: script.Namespace,
+			Name:      adminScriptCronJobName(script, cluster.Namespace),
+			Namespace: cluster.Namespace,
 			Labels:    labels,
 		},
 		Spec: batchv1.CronJobSpec{
@@ -222,11 +288,14 @@ func (r *AdminScriptReconciler) createOrUpdateCronJob(ctx context.Context, owner
 	}
 	desired.Annotations[LastAppliedConfigAnnotation] = string(specJSON)
 
+	owned := desired.Namespace == owner.Namespace
 	existing := &batchv1.CronJob{}
 	getErr := r.Get(ctx, client.ObjectKeyFromObject(desired), existing)
 	if apierrors.IsNotFound(getErr) {
-		if err := ctrl.SetControllerReference(owner, desired, r.Scheme); err != nil {
-			return nil, err
+		if owned {
+			if err := ctrl.SetControllerReference(owner, desired, r.Scheme); err != nil {
+				return nil, err
+			}
 		}
 		if err := r.Create(ctx, desired); err != nil {
 			return nil, err
@@ -249,8 +318,10 @@ func (r *AdminScriptReconciler) createOrUpdateCronJob(ctx context.Context, owner
 	}
 	existing.Annotations[LastAppliedConfigAnnotation] = string(specJSON)
 	existing.Spec = desired.Spec
-	if err := ctrl.SetControllerReference(owner, existing, r.Scheme); err != nil {
-		return nil, err
+	if owned {
+		if err := ctrl.SetControllerReference(owner, existing, r.Scheme); err != nil {
+			return nil, err
+		}
 	}
 	if err := r.Update(ctx, existing); err != nil {
 		return nil, err
@@ -262,6 +333,7 @@ func (r *AdminScriptReconciler) createOrUpdateCronJob(ctx context.Context, owner
 // applyCronJobStatus mirrors the CronJob's schedule status onto the AdminScript.
 func (r *AdminScriptReconciler) applyCronJobStatus(script *seaweedv1.AdminScript, cronJob *batchv1.CronJob) {
 	script.Status.CronJobName = cronJob.Name
+	script.Status.CronJobNamespace = cronJob.Namespace
 	script.Status.Active = int32(len(cronJob.Status.Active))
 	script.Status.LastScheduleTime = cronJob.Status.LastScheduleTime
 	script.Status.LastSuccessfulTime = cronJob.Status.LastSuccessfulTime
@@ -282,6 +354,104 @@ func (r *AdminScriptReconciler) patchStatus(ctx context.Context, script *seaweed
 	return r.Status().Update(ctx, script)
 }
 
+// setCondition sets a condition other than Ready; patchStatus writes it.
+func (r *AdminScriptReconciler) setCondition(script *seaweedv1.AdminScript, condType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&script.Status.Conditions, metav1.Condition{
+		Type:               condType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: script.Generation,
+	})
+}
+
+// handleDeletion removes a CronJob rendered into another namespace, then
+// drops the finalizer.
+func (r *AdminScriptReconciler) handleDeletion(ctx context.Context, script *seaweedv1.AdminScript) error {
+	if !controllerutil.ContainsFinalizer(script, AdminScriptFinalizer) {
+		return nil
+	}
+	if ns, name := script.Status.CronJobNamespace, script.Status.CronJobName; ns != "" && ns != script.Namespace && name != "" {
+		cron := &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}}
+		if err := r.Delete(ctx, cron, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	controllerutil.RemoveFinalizer(script, AdminScriptFinalizer)
+	return r.Update(ctx, script)
+}
+
+// deleteStaleCronJob removes the CronJob recorded on status when the desired
+// one has moved to another namespace or name.
+func (r *AdminScriptReconciler) deleteStaleCronJob(ctx context.Context, script *seaweedv1.AdminScript, desired *batchv1.CronJob) error {
+	ns, name := script.Status.CronJobNamespace, script.Status.CronJobName
+	if ns == "" || name == "" || (ns == desired.Namespace && name == desired.Name) {
+		return nil
+	}
+	cron := &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}}
+	if err := r.Delete(ctx, cron, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// deleteRenderedCronJob removes the CronJob recorded on status, for an
+// AdminScript that may no longer run, and forgets it.
+func (r *AdminScriptReconciler) deleteRenderedCronJob(ctx context.Context, script *seaweedv1.AdminScript) error {
+	ns, name := script.Status.CronJobNamespace, script.Status.CronJobName
+	if ns == "" || name == "" {
+		return nil
+	}
+	cron := &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}}
+	if err := r.Delete(ctx, cron, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	r.Recorder.Eventf(script, corev1.EventTypeNormal, "CronJobRemoved", "removed CronJob %s/%s", ns, name)
+	script.Status.CronJobNamespace, script.Status.CronJobName = "", ""
+	script.Status.Active = 0
+	return nil
+}
+
+// adminScriptClusterNamespace is the namespace of the referenced cluster.
+func adminScriptClusterNamespace(script *seaweedv1.AdminScript) string {
+	if ns := script.Spec.ClusterRef.Namespace; ns != "" {
+		return ns
+	}
+	return script.Namespace
+}
+
+// adminScriptCronJobName is the AdminScript's name for a CronJob next to it.
+// In the cluster's namespace it is prefixed with the AdminScript's namespace
+// so scripts from different tenants do not collide, and kept within the
+// 52-character CronJob name limit.
+func adminScriptCronJobName(script *seaweedv1.AdminScript, clusterNamespace string) string {
+	if clusterNamespace == script.Namespace {
+		return script.Name
+	}
+	const max = 52
+	name := script.Namespace + "-" + script.Name
+	if len(name) <= max {
+		return name
+	}
+	h := fnvHash(name)
+	return name[:max-len(h)-1] + "-" + h
+}
+
+// adminScriptNamespacedOverride returns the first spec field naming an object
+// in the AdminScript's own namespace, which a CronJob in the cluster's
+// namespace cannot use.
+func adminScriptNamespacedOverride(script *seaweedv1.AdminScript) string {
+	switch {
+	case script.Spec.ServiceAccountName != "":
+		return "serviceAccountName"
+	case script.Spec.CredentialsSecret != nil:
+		return "credentialsSecret"
+	case len(script.Spec.ImagePullSecrets) > 0:
+		return "imagePullSecrets"
+	}
+	return ""
+}
+
 func labelsForAdminScript(name string) map[string]string {
 	return map[string]string{
 		label.ManagedByLabelKey: "seaweedfs-operator",
@@ -342,25 +512,38 @@ func (r *AdminScriptReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&seaweedv1.AdminScript{}).
 		Owns(&batchv1.CronJob{}).
+		Watches(&batchv1.CronJob{}, handler.EnqueueRequestsFromMapFunc(mapCrossNamespaceCronJobToAdminScript)).
 		Watches(&seaweedv1.Seaweed{}, handler.EnqueueRequestsFromMapFunc(r.mapSeaweedToAdminScripts)).
 		Complete(r)
 }
 
-// mapSeaweedToAdminScripts enqueues every AdminScript in the changed cluster's
-// namespace that references it, so a cluster mutation (image upgrade, mTLS
-// toggle, filer add/remove) re-renders the affected CronJobs.
+// mapCrossNamespaceCronJobToAdminScript enqueues the AdminScript of a CronJob
+// rendered into a cluster's namespace, which has no owner reference.
+func mapCrossNamespaceCronJobToAdminScript(_ context.Context, obj client.Object) []reconcile.Request {
+	labels := obj.GetLabels()
+	ns, name := labels[adminScriptNamespaceLabel], labels[label.InstanceLabelKey]
+	if ns == "" || name == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: ns, Name: name}}}
+}
+
+// mapSeaweedToAdminScripts enqueues every AdminScript that references the
+// changed cluster, so a cluster mutation (image upgrade, mTLS toggle, filer
+// add/remove) re-renders the affected CronJobs.
 func (r *AdminScriptReconciler) mapSeaweedToAdminScripts(ctx context.Context, obj client.Object) []reconcile.Request {
 	cluster, ok := obj.(*seaweedv1.Seaweed)
 	if !ok {
 		return nil
 	}
 	var list seaweedv1.AdminScriptList
-	if err := r.List(ctx, &list, client.InNamespace(cluster.Namespace)); err != nil {
+	if err := r.List(ctx, &list); err != nil {
 		return nil
 	}
 	var reqs []reconcile.Request
 	for i := range list.Items {
-		if list.Items[i].Spec.ClusterRef.Name == cluster.Name {
+		s := &list.Items[i]
+		if s.Spec.ClusterRef.Name == cluster.Name && adminScriptClusterNamespace(s) == cluster.Namespace {
 			reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
 		}
 	}
@@ -1,12 +1,20 @@
 package controller
 
 import (
+	"context"
 	"strings"
 	"testing"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
 	"github.com/seaweedfs/seaweedfs-operator/internal/controller/label"
@@ -186,3 +194,186 @@ func TestBuildCronJobCredentialsSecret(t *testing.T) {
 		}
 	})
 }
+
+func TestBuildCronJobCrossNamespace(t *testing.T) {
+	r := &AdminScriptReconciler{}
+	script := testAdminScript()
+	script.Namespace = "team"
+	script.Spec.ClusterRef.Namespace = "default"
+	cron := r.buildCronJob(script, testCluster())
+
+	if cron.Name != "team-nightly-balance" || cron.Namespace != "default" {
+		t.Fatalf("unexpected CronJob meta: %s/%s", cron.Namespace, cron.Name)
+	}
+	if cron.Labels[adminScriptNamespaceLabel] != "team" || cron.Labels[label.InstanceLabelKey] != "nightly-balance" {
+		t.Errorf("labels do not point back at the AdminScript: %v", cron.Labels)
+	}
+	reqs := mapCrossNamespaceCronJobToAdminScript(context.Background(), cron)
+	if len(reqs) != 1 || reqs[0].Namespace != "team" || reqs[0].Name != "nightly-balance" {
+		t.Errorf("CronJob maps to %v", reqs)
+	}
+
+	script.Name = strings.Repeat("x", 60)
+	if name := adminScriptCronJobName(script, "default"); len(name) > 52 {
+		t.Errorf("CronJob name %q exceeds 52 characters", name)
+	}
+}
+
+func newAdminScriptReconciler(t *testing.T, objs ...client.Object) *AdminScriptReconciler {
+	t.Helper()
+	scheme := backupTestScheme(t)
+	cli := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&seaweedv1.AdminScript{}).
+		Build()
+	return &AdminScriptReconciler{
+		Client:   cli,
+		Log:      logf.Log,
+		Scheme:   scheme,
+		Recorder: record.NewFakeRecorder(20),
+	}
+}
+
+func TestAdminScriptReconcileCrossNamespace(t *testing.T) {
+	script := testAdminScript()
+	script.Namespace = "team"
+	script.Spec.ClusterRef.Namespace = "default"
+	grant := &seaweedv1.ResourceReferenceGrant{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-team", Namespace: "default"},
+		Spec: seaweedv1.ResourceReferenceGrantSpec{
+			From: []seaweedv1.ReferenceGrantFrom{{Group: groupSeaweed, Kind: kindAdminScript, Namespace: "team"}},
+			To:   []seaweedv1.ReferenceGrantTo{{Group: groupSeaweed, Kind: kindSeaweed}},
+		},
+	}
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "team", Name: "nightly-balance"}}
+	cronKey := types.NamespacedName{Namespace: "default", Name: "team-nightly-balance"}
+
+	t.Run("denied without a grant", func(t *testing.T) {
+		r := newAdminScriptReconciler(t, testCluster(), script.DeepCopy())
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+		var got seaweedv1.AdminScript
+		if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+			t.Fatal(err)
+		}
+		cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.AdminScriptConditionReferenceGranted)
+		if got.Status.Phase != seaweedv1.AdminScriptPhasePending || cond == nil || cond.Reason != "ReferenceGrantMissing" {
+			t.Fatalf("phase = %q, ReferenceGranted = %+v; want Pending with ReferenceGrantMissing", got.Status.Phase, cond)
+		}
+		var cron batchv1.CronJob
+		if err := r.Get(ctx, cronKey, &cron); err == nil {
+			t.Error("CronJob created without a grant")
+		}
+	})
+
+	t.Run("granted renders into the cluster namespace", func(t *testing.T) {
+		r := newAdminScriptReconciler(t, testCluster(), script.DeepCopy(), grant)
+		for i := 0; i < 2; i++ {
+			if _, err := r.Reconcile(ctx, req); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var cron batchv1.CronJob
+		if err := r.Get(ctx, cronKey, &cron); err != nil {
+			t.Fatalf("expected CronJob %s: %v", cronKey, err)
+		}
+		if len(cron.OwnerReferences) != 0 {
+			t.Errorf("cross-namespace CronJob has owner references: %+v", cron.OwnerReferences)
+		}
+		var got seaweedv1.AdminScript
+		if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Status.CronJobNamespace != "default" {
+			t.Errorf("status.cronJobNamespace = %q", got.Status.CronJobNamespace)
+		}
+
+		if err := r.Delete(ctx, &got); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Get(ctx, cronKey, &cron); err == nil {
+			t.Error("CronJob not deleted with the AdminScript")
+		}
+	})
+
+	t.Run("revoking the grant removes the CronJob", func(t *testing.T) {
+		r := newAdminScriptReconciler(t, testCluster(), script.DeepCopy(), grant.DeepCopy())
+		for i := 0; i < 2; i++ {
+			if _, err := r.Reconcile(ctx, req); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var got seaweedv1.AdminScript
+		if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !meta.IsStatusConditionTrue(got.Status.Conditions, seaweedv1.AdminScriptConditionReferenceGranted) {
+			t.Errorf("ReferenceGranted not True while the grant holds: %+v", got.Status.Conditions)
+		}
+
+		if err := r.Delete(ctx, grant.DeepCopy()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+		var cron batchv1.CronJob
+		if err := r.Get(ctx, cronKey, &cron); err == nil {
+			t.Error("CronJob kept after the grant was revoked")
+		}
+		if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+			t.Fatal(err)
+		}
+		cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.AdminScriptConditionReferenceGranted)
+		if cond == nil || cond.Status != metav1.ConditionFalse || got.Status.CronJobName != "" {
+			t.Errorf("ReferenceGranted = %+v, cronJobName = %q; want False and no CronJob", cond, got.Status.CronJobName)
+		}
+	})
+
+	t.Run("rejects namespaced overrides", func(t *testing.T) {
+		s := script.DeepCopy()
+		s.Spec.ServiceAccountName = "runner"
+		r := newAdminScriptReconciler(t, testCluster(), s, grant)
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+		var got seaweedv1.AdminScript
+		if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+			t.Fatal(err)
+		}
+		cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.AdminScriptConditionReady)
+		if cond == nil || cond.Reason != "UnsupportedCrossNamespace" {
+			t.Errorf("Ready = %+v, want UnsupportedCrossNamespace", cond)
+		}
+	})
+
+	t.Run("a namespaced override removes the CronJob", func(t *testing.T) {
+		r := newAdminScriptReconciler(t, testCluster(), script.DeepCopy(), grant)
+		for i := 0; i < 2; i++ {
+			if _, err := r.Reconcile(ctx, req); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var got seaweedv1.AdminScript
+		if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+			t.Fatal(err)
+		}
+		got.Spec.ServiceAccountName = "runner"
+		if err := r.Update(ctx, &got); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+		var cron batchv1.CronJob
+		if err := r.Get(ctx, cronKey, &cron); err == nil {
+			t.Error("CronJob kept after an unsupported override")
+		}
+	})
+}
@@ -17,18 +17,55 @@ limitations under the License.
 package controller
 
 import (
+	"context"
 	"fmt"
 	"hash/fnv"
 	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
 )
 
 // backupRequeue is the backoff used when a backup/restore is waiting on an
 // external dependency (the cluster, its storage, or the snapshot Job).
 const backupRequeue = 15 * time.Second
 
+// Finalizers held by a SeaweedBackup or SeaweedRestore whose Job runs in
+// another namespace, where an owner reference cannot garbage-collect it.
+const (
+	SeaweedBackupFinalizer  = "seaweed.seaweedfs.com/seaweedbackup-protection"
+	SeaweedRestoreFinalizer = "seaweed.seaweedfs.com/seaweedrestore-protection"
+)
+
+// backupJobName names the Job of a SeaweedBackup or SeaweedRestore. A Job in
+// the cluster's namespace is prefixed with the CR's namespace so CRs from
+// different namespaces do not collide.
+func backupJobName(ownNamespace, clusterNamespace, name, suffix string) string {
+	if clusterNamespace == ownNamespace {
+		return boundedName(name, suffix)
+	}
+	return boundedName(ownNamespace+"-"+name, suffix)
+}
+
+// deleteCrossNamespaceJob deletes the Job a SeaweedBackup or SeaweedRestore in
+// ownNamespace created in another namespace. Jobs next to the CR are owned by
+// it and left to garbage collection.
+func deleteCrossNamespaceJob(ctx context.Context, c client.Client, ownNamespace, jobNamespace, jobName string) error {
+	if jobNamespace == "" || jobNamespace == ownNamespace || jobName == "" {
+		return nil
+	}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: jobNamespace, Name: jobName}}
+	if err := c.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
 // fnvHash returns an 8-hex-char FNV-1a hash of s, used to keep generated Job
 // names unique after truncation.
 func fnvHash(s string) string {
@@ -69,3 +106,9 @@ func jobFinished(job *batchv1.Job) (done bool, success bool) {
 	}
 	return false, false
 }
+
+// metadataBackup reports whether backup is a filer metadata snapshot, the
+// kind metadata restores, restore drills and point-in-time replays load.
+func metadataBackup(backup *seaweedv1.SeaweedBackup) bool {
+	return backup.Spec.Target == "" || backup.Spec.Target == seaweedv1.BackupTargetMetadata
+}
@@ -86,6 +86,35 @@ func TestCELScheduleStorageMustExist(t *testing.T) {
 	}
 }
 
+func TestCELScheduleKeepXorRetention(t *testing.T) {
+	_, cli := mustEnvtest(t)
+	ctx := context.Background()
+	bad := &seaweedv1.Seaweed{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "cel-retention-", Namespace: "default"},
+		Spec: seaweedv1.SeaweedSpec{
+			Backup: &seaweedv1.BackupSpec{
+				Storages: map[string]seaweedv1.BackupStorageSpec{
+					"pvc": {Type: seaweedv1.BackupStorageFilesystem, Filesystem: &seaweedv1.FilesystemBackupStore{ExistingClaim: "c"}},
+				},
+				Schedule: []seaweedv1.BackupScheduleSpec{{
+					Name: "n", Schedule: "0 2 * * *", StorageName: "pvc", Keep: 3,
+					Retention: &seaweedv1.BackupRetentionPolicy{KeepDaily: 7},
+				}},
+			},
+		},
+	}
+	if err := cli.Create(ctx, bad); err == nil {
+		_ = cli.Delete(ctx, bad)
+		t.Fatal("expected CEL to reject a schedule setting both keep and retention")
+	}
+	good := bad.DeepCopy()
+	good.Spec.Backup.Schedule[0].Keep = 0
+	if err := cli.Create(ctx, good); err != nil {
+		t.Fatalf("retention-only schedule rejected: %v", err)
+	}
+	_ = cli.Delete(ctx, good)
+}
+
 func TestCELRestoreBackupNameXorSource(t *testing.T) {
 	_, cli := mustEnvtest(t)
 	ctx := context.Background()
@@ -125,6 +154,36 @@ func TestCELRestoreBackupNameXorSource(t *testing.T) {
 	_ = cli.Delete(ctx, ok)
 }
 
+func TestCELRestorePointInTime(t *testing.T) {
+	_, cli := mustEnvtest(t)
+	ctx := context.Background()
+	pit := metav1.Now()
+
+	// pointInTime alone — accepted; the base snapshot is picked for it.
+	alone := &seaweedv1.SeaweedRestore{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "cel-rst-", Namespace: "default"},
+		Spec:       seaweedv1.SeaweedRestoreSpec{ClusterName: "c1", PointInTime: &pit},
+	}
+	if err := cli.Create(ctx, alone); err != nil {
+		t.Fatalf("pointInTime-only restore rejected: %v", err)
+	}
+	_ = cli.Delete(ctx, alone)
+
+	// pointInTime with an explicit backupSource — rejected.
+	withSource := &seaweedv1.SeaweedRestore{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "cel-rst-", Namespace: "default"},
+		Spec: seaweedv1.SeaweedRestoreSpec{
+			ClusterName:  "c1",
+			PointInTime:  &pit,
+			BackupSource: &seaweedv1.BackupSource{StorageName: "pvc", MetaPath: "x"},
+		},
+	}
+	if err := cli.Create(ctx, withSource); err == nil {
+		_ = cli.Delete(ctx, withSource)
+		t.Fatal("expected CEL to reject pointInTime combined with backupSource")
+	}
+}
+
 func TestCELBackupImmutability(t *testing.T) {
 	_, cli := mustEnvtest(t)
 	ctx := context.Background()
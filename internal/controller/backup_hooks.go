@@ -0,0 +1,436 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+const (
+	// defaultHookTimeout bounds a backup hook that sets no timeout.
+	defaultHookTimeout = 30 * time.Second
+
+	// maxHookTimeout caps a hook's timeout, as the CRD does, for hooks
+	// stored before the cap.
+	maxHookTimeout = 5 * time.Minute
+)
+
+// PodExecutor runs a command in a container of a running pod and returns its
+// stdout. A non-zero exit is an error. Tests inject a fake.
+type PodExecutor interface {
+	Exec(ctx context.Context, namespace, pod, container string, command []string) (string, error)
+}
+
+// NewPodExecutor returns a PodExecutor that streams through the API server's
+// pods/exec subresource.
+func NewPodExecutor(config *rest.Config) (PodExecutor, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &spdyPodExecutor{config: config, clientset: clientset}, nil
+}
+
+type spdyPodExecutor struct {
+	config    *rest.Config
+	clientset kubernetes.Interface
+}
+
+func (e *spdyPodExecutor) Exec(ctx context.Context, namespace, pod, container string, command []string) (string, error) {
+	req := e.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(namespace).Name(pod).SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	exec, err := remotecommand.NewSPDYExecutor(e.config, http.MethodPost, req.URL())
+	if err != nil {
+		return "", err
+	}
+	var stdout, stderr bytes.Buffer
+	if err := exec.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr}); err != nil {
+		if msg := lastLine(stderr.String()); msg != "" {
+			return stdout.String(), fmt.Errorf("%w: %s", err, msg)
+		}
+		return stdout.String(), err
+	}
+	return stdout.String(), nil
+}
+
+// lastLine is the last non-empty line of s.
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// hookRun is the outcome of running a list of backup hooks.
+type hookRun struct {
+	// results holds one "<name>: <outcome>" entry per hook that ran.
+	results []string
+	// ignored counts the failed hooks with failurePolicy Ignore.
+	ignored int
+	// failed is the failure of a hook with failurePolicy Fail, which stopped
+	// the run.
+	failed error
+}
+
+// condition summarises the run as a condition of type condType.
+func (h hookRun) condition(condType string, generation int64) metav1.Condition {
+	c := metav1.Condition{
+		Type: condType, Status: metav1.ConditionTrue, ObservedGeneration: generation,
+		Reason: "Succeeded", Message: strings.Join(h.results, "; "),
+	}
+	switch {
+	case h.failed != nil:
+		c.Status, c.Reason = metav1.ConditionFalse, "HookFailed"
+	case h.ignored > 0:
+		c.Reason = "FailuresIgnored"
+	}
+	return c
+}
+
+// runHooks runs hooks in order, each bounded by its timeout. A failed hook
+// with failurePolicy Fail stops the run and is reported in the result; the
+// returned error is for API failures, which leave the run to be retried.
+func (r *SeaweedBackupReconciler) runHooks(ctx context.Context, backup *seaweedv1.SeaweedBackup, cluster *seaweedv1.Seaweed, hooks []seaweedv1.BackupHook) (hookRun, error) {
+	var run hookRun
+	for i := range hooks {
+		h := &hooks[i]
+		err := r.runHook(ctx, backup, cluster, h)
+		var apiErr *hookAPIError
+		if errors.As(err, &apiErr) {
+			return run, apiErr.err
+		}
+		switch {
+		case err == nil:
+			run.results = append(run.results, h.Name+": succeeded")
+		case h.FailurePolicy == seaweedv1.BackupHookIgnore:
+			run.ignored++
+			run.results = append(run.results, h.Name+": failed (ignored): "+err.Error())
+			r.Recorder.Event(backup, "Warning", "BackupHookFailed", "hook "+h.Name+" failed (ignored): "+err.Error())
+		default:
+			run.results = append(run.results, h.Name+": failed: "+err.Error())
+			run.failed = fmt.Errorf("hook %s: %w", h.Name, err)
+			r.Recorder.Event(backup, "Warning", "BackupHookFailed", "hook "+h.Name+" failed: "+err.Error())
+			return run, nil
+		}
+	}
+	return run, nil
+}
+
+// runPreHooks runs the backup's pre hooks once, before the snapshot starts.
+// The PreHooks condition they leave is persisted straight away, ahead of
+// anything that can fail, so a retried reconcile does not run hooks that may
+// not be idempotent again.
+func (r *SeaweedBackupReconciler) runPreHooks(ctx context.Context, backup *seaweedv1.SeaweedBackup, cluster *seaweedv1.Seaweed) error {
+	if len(backup.Spec.PreHooks) == 0 || meta.FindStatusCondition(backup.Status.Conditions, seaweedv1.BackupConditionPreHooks) != nil {
+		return nil
+	}
+	run, err := r.runHooks(ctx, backup, cluster, backup.Spec.PreHooks)
+	if err != nil {
+		return err
+	}
+	meta.SetStatusCondition(&backup.Status.Conditions, run.condition(seaweedv1.BackupConditionPreHooks, backup.Generation))
+	return r.Status().Update(ctx, backup)
+}
+
+// hookAPIError marks a hook error that came from recording the hook's state
+// rather than from the hook itself.
+type hookAPIError struct{ err error }
+
+func (e *hookAPIError) Error() string { return e.err.Error() }
+
+func (r *SeaweedBackupReconciler) runHook(ctx context.Context, backup *seaweedv1.SeaweedBackup, cluster *seaweedv1.Seaweed, h *seaweedv1.BackupHook) error {
+	timeout := defaultHookTimeout
+	if h.Timeout != nil {
+		timeout = min(h.Timeout.Duration, maxHookTimeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var err error
+	switch {
+	case h.Exec != nil:
+		err = r.execHook(ctx, backup.Namespace, h.Exec)
+	case h.HTTP != nil:
+		err = r.httpHook(ctx, backup.Namespace, h.HTTP)
+	case h.FilerReadOnly != nil:
+		err = r.filerReadOnlyHook(ctx, backup, cluster, h.FilerReadOnly)
+	default:
+		err = errors.New("no action set")
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return err
+}
+
+// execHook runs the hook's command in every running pod its selector matches
+// in namespace.
+func (r *SeaweedBackupReconciler) execHook(ctx context.Context, namespace string, h *seaweedv1.BackupExecHook) error {
+	if !r.execHooksAllowed(namespace) {
+		return fmt.Errorf("exec hooks are not enabled for namespace %s; see the operator's --backup-exec-hook-namespaces", namespace)
+	}
+	if r.PodExecutor == nil {
+		return errors.New("pod exec is not configured on the operator")
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&h.Selector)
+	if err != nil {
+		return fmt.Errorf("invalid selector: %w", err)
+	}
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return &hookAPIError{err: err}
+	}
+	ran := 0
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase != corev1.PodRunning || !pod.DeletionTimestamp.IsZero() || len(pod.Spec.Containers) == 0 {
+			continue
+		}
+		container := h.Container
+		if container == "" {
+			container = pod.Spec.Containers[0].Name
+		}
+		if _, err := r.PodExecutor.Exec(ctx, namespace, pod.Name, container, h.Command); err != nil {
+			return fmt.Errorf("pod %s: %w", pod.Name, err)
+		}
+		ran++
+	}
+	if ran == 0 {
+		return errors.New("no running pod matches the selector")
+	}
+	return nil
+}
+
+// execHooksAllowed reports whether backups in namespace may run exec hooks.
+func (r *SeaweedBackupReconciler) execHooksAllowed(namespace string) bool {
+	return slices.Contains(r.ExecHookNamespaces, "*") || slices.Contains(r.ExecHookNamespaces, namespace)
+}
+
+// httpHook sends the hook's request to a Service in namespace; any 2xx
+// response succeeds.
+func (r *SeaweedBackupReconciler) httpHook(ctx context.Context, namespace string, h *seaweedv1.BackupHTTPHook) error {
+	method := h.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	p := h.Path
+	if p == "" {
+		p = "/"
+	}
+	// The CRD restricts Service to a DNS label; building the URL from parts
+	// also keeps anything else in it from escaping the host.
+	reqPath, query, _ := strings.Cut(p, "?")
+	u := url.URL{
+		Scheme:   "http",
+		Host:     net.JoinHostPort(h.Service+"."+namespace, strconv.Itoa(int(h.Port))),
+		Path:     reqPath,
+		RawQuery: query,
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	httpClient := r.HookHTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s", method, u.String(), resp.Status)
+	}
+	return nil
+}
+
+// filerReadOnlyHook switches a filer path read-only. The location prefix is
+// recorded in status.readOnlyPaths before filer.conf is changed, so it is
+// released even when the operator restarts mid-backup; a path that was
+// already read-only is left to whoever set it.
+func (r *SeaweedBackupReconciler) filerReadOnlyHook(ctx context.Context, backup *seaweedv1.SeaweedBackup, cluster *seaweedv1.Seaweed, h *seaweedv1.BackupFilerReadOnlyHook) error {
+	prefix := readOnlyPrefix(h.Path)
+	admin, err := r.adminFor(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	defer closeBucketAdmin(admin, r.Log)
+
+	// A retried hook finds its prefix recorded already and re-applies it.
+	recorded := slices.Contains(backup.Status.ReadOnlyPaths, prefix)
+	if !recorded {
+		backup.Status.ReadOnlyPaths = append(backup.Status.ReadOnlyPaths, prefix)
+		meta.SetStatusCondition(&backup.Status.Conditions, metav1.Condition{
+			Type: seaweedv1.BackupConditionFilerReadOnly, Status: metav1.ConditionTrue,
+			ObservedGeneration: backup.Generation, Reason: "Applied",
+			Message: "read-only until the snapshot finishes: " + strings.Join(backup.Status.ReadOnlyPaths, ", "),
+		})
+		if err := r.Status().Update(ctx, backup); err != nil {
+			return &hookAPIError{err: err}
+		}
+	}
+	changed, err := admin.SetPathReadOnly(ctx, prefix, true)
+	if err != nil || changed || recorded {
+		return err
+	}
+	backup.Status.ReadOnlyPaths = slices.DeleteFunc(backup.Status.ReadOnlyPaths, func(p string) bool { return p == prefix })
+	if len(backup.Status.ReadOnlyPaths) == 0 {
+		meta.RemoveStatusCondition(&backup.Status.Conditions, seaweedv1.BackupConditionFilerReadOnly)
+	}
+	if err := r.Status().Update(ctx, backup); err != nil {
+		return &hookAPIError{err: err}
+	}
+	return nil
+}
+
+// readOnlyPrefix is the filer.conf location prefix covering the filer path
+// p: with a trailing slash, so that read-only /data leaves /database alone.
+func readOnlyPrefix(p string) string {
+	p = path.Clean("/" + p)
+	if p == "/" {
+		return p
+	}
+	return p + "/"
+}
+
+// releaseReadOnly clears the read-only flag of every path a filerReadOnly hook
+// switched, and records that they were released.
+func (r *SeaweedBackupReconciler) releaseReadOnly(ctx context.Context, backup *seaweedv1.SeaweedBackup, cluster *seaweedv1.Seaweed) error {
+	if len(backup.Status.ReadOnlyPaths) == 0 {
+		return nil
+	}
+	admin, err := r.adminFor(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	defer closeBucketAdmin(admin, r.Log)
+	for _, prefix := range backup.Status.ReadOnlyPaths {
+		if _, err := admin.SetPathReadOnly(ctx, prefix, false); err != nil {
+			r.Recorder.Event(backup, "Warning", "ReadOnlyReleaseFailed", "releasing "+prefix+": "+err.Error())
+			return fmt.Errorf("release read-only %s: %w", prefix, err)
+		}
+	}
+	released := strings.Join(backup.Status.ReadOnlyPaths, ", ")
+	backup.Status.ReadOnlyPaths = nil
+	meta.SetStatusCondition(&backup.Status.Conditions, metav1.Condition{
+		Type: seaweedv1.BackupConditionFilerReadOnly, Status: metav1.ConditionFalse,
+		ObservedGeneration: backup.Generation, Reason: "Released", Message: "writable again: " + released,
+	})
+	return r.Status().Update(ctx, backup)
+}
+
+// releaseOnDelete releases the read-only paths and volumes of a backup deleted
+// before its snapshot finished. A cluster that is gone has nothing left to
+// release.
+func (r *SeaweedBackupReconciler) releaseOnDelete(ctx context.Context, backup *seaweedv1.SeaweedBackup) error {
+	if len(backup.Status.ReadOnlyPaths) == 0 && !volumesFrozen(backup) {
+		return nil
+	}
+	clusterNS := backup.Spec.ClusterNamespace
+	if clusterNS == "" {
+		clusterNS = backup.Namespace
+	}
+	var cluster seaweedv1.Seaweed
+	if err := r.Get(ctx, types.NamespacedName{Namespace: clusterNS, Name: backup.Spec.ClusterName}, &cluster); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if err := r.releaseReadOnly(ctx, backup, &cluster); err != nil {
+		return err
+	}
+	return r.releaseFrozenVolumes(ctx, backup, &cluster)
+}
+
+// finishHooks runs once the snapshot is over, or was skipped: it releases the
+// read-only paths and volumes and then runs the post hooks, once. It returns
+// why a post hook with failurePolicy Fail failed; empty when none did.
+func (r *SeaweedBackupReconciler) finishHooks(ctx context.Context, backup *seaweedv1.SeaweedBackup, cluster *seaweedv1.Seaweed) (string, error) {
+	if err := r.releaseReadOnly(ctx, backup, cluster); err != nil {
+		return "", err
+	}
+	if err := r.releaseFrozenVolumes(ctx, backup, cluster); err != nil {
+		return "", err
+	}
+	if len(backup.Spec.PostHooks) == 0 {
+		return "", nil
+	}
+	if c := meta.FindStatusCondition(backup.Status.Conditions, seaweedv1.BackupConditionPostHooks); c != nil {
+		if c.Status == metav1.ConditionFalse {
+			return c.Message, nil
+		}
+		return "", nil
+	}
+	run, err := r.runHooks(ctx, backup, cluster, backup.Spec.PostHooks)
+	if err != nil {
+		return "", err
+	}
+	meta.SetStatusCondition(&backup.Status.Conditions, run.condition(seaweedv1.BackupConditionPostHooks, backup.Generation))
+	if err := r.Status().Update(ctx, backup); err != nil {
+		return "", err
+	}
+	if run.failed != nil {
+		return run.failed.Error(), nil
+	}
+	return "", nil
+}
+
+// hasFilerReadOnlyHook reports whether any of hooks switches a filer path
+// read-only.
+func hasFilerReadOnlyHook(hooks []seaweedv1.BackupHook) bool {
+	return slices.ContainsFunc(hooks, func(h seaweedv1.BackupHook) bool { return h.FilerReadOnly != nil })
+}
+
+func (r *SeaweedBackupReconciler) adminFor(ctx context.Context, cluster *seaweedv1.Seaweed) (BucketAdmin, error) {
+	adminKey, err := loadFilerAdminSigningKey(ctx, r.Client, cluster)
+	if err != nil {
+		return nil, err
+	}
+	dialOption, _, err := loadSeaweedGrpcDialOption(ctx, r.Client, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return r.AdminFactory(getMasterPeersString(cluster), getFilerAddress(cluster), adminKey, dialOption, r.Log)
+}
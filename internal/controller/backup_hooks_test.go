@@ -0,0 +1,339 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// fakePodExecutor records exec calls as "<ns>/<pod>/<container>: <command>".
+type fakePodExecutor struct {
+	calls []string
+	err   error
+}
+
+func (f *fakePodExecutor) Exec(_ context.Context, namespace, pod, container string, command []string) (string, error) {
+	f.calls = append(f.calls, namespace+"/"+pod+"/"+container+": "+strings.Join(command, " "))
+	return "", f.err
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// hookTestServer answers http hooks with status and records each request as
+// "<method> <host><path>", with the host the hook addressed.
+func hookTestServer(t *testing.T, status int) (*http.Client, *[]string) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	target, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen []string
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		seen = append(seen, req.Method+" "+req.URL.Host+req.URL.Path)
+		req.URL.Host = target.Host
+		return http.DefaultTransport.RoundTrip(req)
+	})}, &seen
+}
+
+func hookTestPod(name string, labels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns1", Labels: labels},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "db"}, {Name: "sidecar"}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func newHookedBackupReconciler(t *testing.T, admin *fakeBucketAdmin, objs ...client.Object) (*SeaweedBackupReconciler, *fakePodExecutor) {
+	t.Helper()
+	r := newBackupReconciler(t, objs...)
+	exec := &fakePodExecutor{}
+	r.PodExecutor = exec
+	r.ExecHookNamespaces = []string{"ns1"}
+	r.AdminFactory = func(_, _ string, _ []byte, _ grpc.DialOption, _ logr.Logger) (BucketAdmin, error) {
+		return admin, nil
+	}
+	return r, exec
+}
+
+func TestBackupHooksQuiesceAroundSnapshot(t *testing.T) {
+	backup := &seaweedv1.SeaweedBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "ns1"},
+		Spec: seaweedv1.SeaweedBackupSpec{
+			ClusterName: "c1", StorageName: "pvc", FilerPath: "/",
+			PreHooks: []seaweedv1.BackupHook{
+				{Name: "flush", Exec: &seaweedv1.BackupExecHook{
+					Selector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+					Command:  []string{"sync"},
+				}},
+				{Name: "quiesce", FilerReadOnly: &seaweedv1.BackupFilerReadOnlyHook{Path: "/buckets"}},
+			},
+			PostHooks: []seaweedv1.BackupHook{
+				{Name: "resume", HTTP: &seaweedv1.BackupHTTPHook{Service: "app", Port: 8080, Path: "/resume", Method: http.MethodPost}},
+			},
+		},
+	}
+	admin := newFakeAdmin()
+	r, exec := newHookedBackupReconciler(t, admin, clusterWithFilesystemStorage(), backup,
+		hookTestPod("db-0", map[string]string{"app": "db"}), hookTestPod("web-0", map[string]string{"app": "web"}))
+	httpClient, requests := hookTestServer(t, http.StatusOK)
+	r.HookHTTPClient = httpClient
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "bk1"}}
+
+	// The first pass adds the finalizer that guarantees the release.
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile %d: %v", i, err)
+		}
+	}
+	if want := []string{"ns1/db-0/db: sync"}; !reflect.DeepEqual(exec.calls, want) {
+		t.Errorf("exec calls = %v, want %v", exec.calls, want)
+	}
+	if !admin.readOnly["/buckets/"] {
+		t.Errorf("/buckets/ not switched read-only: %v", admin.calls)
+	}
+	var got seaweedv1.SeaweedBackup
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != seaweedv1.BackupPhaseRunning || !reflect.DeepEqual(got.Status.ReadOnlyPaths, []string{"/buckets/"}) {
+		t.Fatalf("status = %+v, want Running holding /buckets/ read-only", got.Status)
+	}
+	if !meta.IsStatusConditionTrue(got.Status.Conditions, seaweedv1.BackupConditionPreHooks) {
+		t.Errorf("PreHooks condition not True: %+v", got.Status.Conditions)
+	}
+	if len(*requests) != 0 {
+		t.Errorf("post hook ran before the snapshot finished: %v", *requests)
+	}
+
+	var job batchv1.Job
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: got.Status.JobName}, &job); err != nil {
+		t.Fatal(err)
+	}
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	if err := r.Status().Update(ctx, &job); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != seaweedv1.BackupPhaseCompleted {
+		t.Fatalf("phase = %q, want Completed", got.Status.Phase)
+	}
+	if admin.readOnly["/buckets/"] || len(got.Status.ReadOnlyPaths) != 0 {
+		t.Errorf("/buckets/ not released: admin %v, status %v", admin.readOnly, got.Status.ReadOnlyPaths)
+	}
+	if want := []string{"POST app.ns1:8080/resume"}; !reflect.DeepEqual(*requests, want) {
+		t.Errorf("http hook requests = %v, want %v", *requests, want)
+	}
+	if !meta.IsStatusConditionTrue(got.Status.Conditions, seaweedv1.BackupConditionPostHooks) {
+		t.Errorf("PostHooks condition not True: %+v", got.Status.Conditions)
+	}
+	if meta.IsStatusConditionTrue(got.Status.Conditions, seaweedv1.BackupConditionFilerReadOnly) {
+		t.Errorf("FilerReadOnly condition still True")
+	}
+}
+
+func TestBackupPreHookFailureSkipsSnapshot(t *testing.T) {
+	backup := &seaweedv1.SeaweedBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "ns1"},
+		Spec: seaweedv1.SeaweedBackupSpec{
+			ClusterName: "c1", StorageName: "pvc",
+			PreHooks: []seaweedv1.BackupHook{
+				{Name: "notify", HTTP: &seaweedv1.BackupHTTPHook{Service: "hooks", Port: 80}, FailurePolicy: seaweedv1.BackupHookIgnore},
+				{Name: "flush", Exec: &seaweedv1.BackupExecHook{
+					Selector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "missing"}},
+					Command:  []string{"sync"},
+				}},
+			},
+			PostHooks: []seaweedv1.BackupHook{
+				{Name: "resume", Exec: &seaweedv1.BackupExecHook{
+					Selector:  metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+					Container: "sidecar",
+					Command:   []string{"resume"},
+				}},
+			},
+		},
+	}
+	r, exec := newHookedBackupReconciler(t, newFakeAdmin(), clusterWithFilesystemStorage(), backup,
+		hookTestPod("db-0", map[string]string{"app": "db"}))
+	httpClient, _ := hookTestServer(t, http.StatusServiceUnavailable)
+	r.HookHTTPClient = httpClient
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "bk1"}}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	var job batchv1.Job
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: boundedName("bk1", "-bkp")}, &job); !apierrors.IsNotFound(err) {
+		t.Fatalf("snapshot job created despite the failed pre hook (err=%v)", err)
+	}
+	var got seaweedv1.SeaweedBackup
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != seaweedv1.BackupPhaseFailed {
+		t.Fatalf("phase = %q, want Failed", got.Status.Phase)
+	}
+	complete := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.BackupConditionComplete)
+	if complete == nil || complete.Reason != "PreHookFailed" {
+		t.Errorf("Complete condition = %+v, want reason PreHookFailed", complete)
+	}
+	pre := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.BackupConditionPreHooks)
+	if pre == nil || pre.Status != metav1.ConditionFalse || !strings.Contains(pre.Message, "notify: failed (ignored)") || !strings.Contains(pre.Message, "no running pod") {
+		t.Errorf("PreHooks condition = %+v", pre)
+	}
+	if want := []string{"ns1/db-0/sidecar: resume"}; !reflect.DeepEqual(exec.calls, want) {
+		t.Errorf("post hooks after a failed pre hook: exec calls = %v, want %v", exec.calls, want)
+	}
+}
+
+func TestBackupPreHooksRunOnceWhenJobCreateFails(t *testing.T) {
+	backup := &seaweedv1.SeaweedBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "ns1"},
+		Spec: seaweedv1.SeaweedBackupSpec{
+			ClusterName: "c1", StorageName: "pvc",
+			PreHooks: []seaweedv1.BackupHook{
+				{Name: "pause", Exec: &seaweedv1.BackupExecHook{
+					Selector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+					Command:  []string{"pause"},
+				}},
+			},
+		},
+	}
+	r, exec := newHookedBackupReconciler(t, newFakeAdmin(), clusterWithFilesystemStorage(), backup,
+		hookTestPod("db-0", map[string]string{"app": "db"}))
+	r.Client = interceptor.NewClient(r.Client.(client.WithWatch), interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			if _, ok := obj.(*batchv1.Job); ok {
+				return errors.New("quota exceeded")
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+	})
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "bk1"}}
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(ctx, req); err == nil {
+			t.Fatalf("reconcile %d: want the Job create error", i)
+		}
+	}
+
+	if want := []string{"ns1/db-0/db: pause"}; !reflect.DeepEqual(exec.calls, want) {
+		t.Errorf("exec calls = %v, want the pre hook run once", exec.calls)
+	}
+	var got seaweedv1.SeaweedBackup
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !meta.IsStatusConditionTrue(got.Status.Conditions, seaweedv1.BackupConditionPreHooks) {
+		t.Errorf("PreHooks condition not persisted: %+v", got.Status.Conditions)
+	}
+}
+
+func TestBackupExecHookNeedsAllowedNamespace(t *testing.T) {
+	backup := &seaweedv1.SeaweedBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "ns1"},
+		Spec: seaweedv1.SeaweedBackupSpec{
+			ClusterName: "c1", StorageName: "pvc",
+			PreHooks: []seaweedv1.BackupHook{
+				{Name: "flush", Exec: &seaweedv1.BackupExecHook{
+					Selector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+					Command:  []string{"sync"},
+				}},
+			},
+		},
+	}
+	r, exec := newHookedBackupReconciler(t, newFakeAdmin(), clusterWithFilesystemStorage(), backup,
+		hookTestPod("db-0", map[string]string{"app": "db"}))
+	r.ExecHookNamespaces = []string{"ns2"}
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "bk1"}}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(exec.calls) != 0 {
+		t.Errorf("exec hook ran outside the allowed namespaces: %v", exec.calls)
+	}
+	var got seaweedv1.SeaweedBackup
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	pre := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.BackupConditionPreHooks)
+	if pre == nil || pre.Status != metav1.ConditionFalse || !strings.Contains(pre.Message, "--backup-exec-hook-namespaces") {
+		t.Errorf("PreHooks condition = %+v", pre)
+	}
+}
+
+func TestReadOnlyPrefix(t *testing.T) {
+	for in, want := range map[string]string{"/": "/", "": "/", "/buckets": "/buckets/", "/buckets/": "/buckets/"} {
+		if got := readOnlyPrefix(in); got != want {
+			t.Errorf("readOnlyPrefix(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestHTTPHookKeepsServiceInHost(t *testing.T) {
+	var seen []string
+	r := &SeaweedBackupReconciler{HookHTTPClient: &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		seen = append(seen, req.URL.String())
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})}}
+	ctx := context.Background()
+
+	if err := r.httpHook(ctx, "ns1", &seaweedv1.BackupHTTPHook{Service: "app", Port: 8080, Path: "/resume?now=1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.httpHook(ctx, "ns1", &seaweedv1.BackupHTTPHook{Service: "evil.example/x?", Port: 80}); err == nil {
+		t.Error("a Service name with a path reached the transport")
+	}
+	if want := []string{"http://app.ns1:8080/resume?now=1"}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("requests = %v, want %v", seen, want)
+	}
+}
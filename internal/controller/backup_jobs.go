@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"path"
 	"strings"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
@@ -27,6 +28,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/internal/backuptool"
 )
 
 // backupBackoffLimit caps Job retries before the snapshot/restore is marked
@@ -37,6 +39,22 @@ const backupBackoffLimit int32 = 3
 // snapshot/restore/mirror pods.
 const backupStorageVolumeName = "backup-storage"
 
+// snapshotScratchFile is where a snapshot Job saves the snapshot before
+// uploading it.
+const snapshotScratchFile = backupScratchDir + "/filer.meta.gz"
+
+// snapshotUploadContainer is the snapshot Job container storing the snapshot;
+// its termination message carries the upload report.
+const snapshotUploadContainer = "upload"
+
+// snapshotManifestName is the manifest backup-tool writes next to each
+// snapshot.
+const snapshotManifestName = "manifest.json"
+
+// snapshotDownloadContainer is the restore Job init container fetching and
+// verifying the snapshot.
+const snapshotDownloadContainer = "download"
+
 // weedCmd joins the standard `weed <flags> <subcommand> <args...>` invocation,
 // reusing weedPreamble so TLS clusters get -config_dir wired in.
 func weedCmd(m *seaweedv1.Seaweed, subcommand string, args ...string) string {
@@ -74,73 +92,37 @@ func filesystemPVCVolume(fs *seaweedv1.FilesystemBackupStore) (corev1.Volume, co
 	return vol, mount
 }
 
-// snapshotScript returns the shell program a snapshot Job runs. For filesystem
-// storages it writes the .meta.gz straight onto the PVC; for object stores it
-// stages the snapshot into a reserved filer path that the data mirror carries
-// off-cluster. `test -s` guards against `weed shell` swallowing a save error.
+// snapshotScript returns the shell program a snapshot Job runs: it saves the
+// .meta.gz into the scratch dir, from where the Job's upload container stores
+// it on the storage. `test -s` guards against `weed shell` swallowing a save
+// error. destination is the snapshot's final location.
 func snapshotScript(m *seaweedv1.Seaweed, st seaweedv1.BackupStorageSpec, cluster, backupName, filerPath string) (script, destination string) {
 	masters := getMasterPeersString(m)
 	filer := getFilerAddress(m)
 	fp := filerPathOrDefault(filerPath)
 	shell := weedCmd(m, "shell", "-master="+masters, "-filer="+filer)
 
-	if st.Type == seaweedv1.BackupStorageFilesystem {
-		out := path.Join(filesystemMountPath(st.Filesystem), metaRelPath(cluster, backupName))
-		script = strings.Join([]string{
-			"set -euo pipefail",
-			fmt.Sprintf("mkdir -p %s", path.Dir(out)),
-			fmt.Sprintf("echo 'fs.meta.save -o %s %s' | %s", out, fp, shell),
-			fmt.Sprintf("test -s %s", out),
-			fmt.Sprintf("echo 'metadata snapshot written to %s'", out),
-			"",
-		}, "\n")
-		return script, out
-	}
-
-	scratch := path.Join(backupScratchDir, "filer.meta.gz")
-	// http:// is intentional even on TLS clusters: the operator's TLS is gRPC
-	// mTLS (security.toml), and filer.copy talks to the filer over gRPC (wired
-	// via -config_dir); the filer's HTTP endpoint stays plain HTTP.
-	dstURL := fmt.Sprintf("http://%s%s/%s/", filer, reservedBackupFilerDir, backupName)
-	copyCmd := weedCmd(m, "filer.copy", scratch, dstURL)
-	staged := reservedFilerMetaPath(backupName)
 	script = strings.Join([]string{
 		"set -euo pipefail",
-		fmt.Sprintf("echo 'fs.meta.save -o %s %s' | %s", scratch, fp, shell),
-		fmt.Sprintf("test -s %s", scratch),
-		copyCmd,
-		fmt.Sprintf("echo 'metadata snapshot staged at filer %s; data mirror replicates it to storage %q'", staged, mirrorSinkDirectory("", st, cluster)),
+		fmt.Sprintf("echo 'fs.meta.save -o %s %s' | %s", snapshotScratchFile, fp, shell),
+		fmt.Sprintf("test -s %s", snapshotScratchFile),
 		"",
 	}, "\n")
-	return script, staged
+	return script, objectStoreURI(st, snapshotObjectKey(st, metaRelPath(cluster, backupName)))
 }
 
-// restoreScript returns the shell program a restore Job runs. localPath is set
-// for filesystem sources (read straight off the PVC); filerURL is set for
-// object-store sources (read back from the reserved filer path via filer.cat).
-func restoreScript(m *seaweedv1.Seaweed, localPath, filerURL, filerPath string) string {
+// restoreScript returns the shell program a restore Job runs: `fs.meta.load`
+// of the snapshot at localPath, in the scratch dir where the download
+// container left it once verified.
+func restoreScript(m *seaweedv1.Seaweed, localPath, filerPath string) string {
 	masters := getMasterPeersString(m)
 	filer := getFilerAddress(m)
 	shell := weedCmd(m, "shell", "-master="+masters, "-filer="+filer)
-
-	if localPath != "" {
-		return strings.Join([]string{
-			"set -euo pipefail",
-			fmt.Sprintf("test -s %s", localPath),
-			fmt.Sprintf("echo '%s' | %s", metaLoadStatement(localPath, filerPath), shell),
-			fmt.Sprintf("echo 'metadata restored from %s'", localPath),
-			"",
-		}, "\n")
-	}
-
-	scratch := path.Join(backupScratchDir, "restore.meta.gz")
-	catCmd := weedCmd(m, "filer.cat", "-o", scratch, filerURL)
 	return strings.Join([]string{
 		"set -euo pipefail",
-		catCmd,
-		fmt.Sprintf("test -s %s", scratch),
-		fmt.Sprintf("echo '%s' | %s", metaLoadStatement(scratch, filerPath), shell),
-		fmt.Sprintf("echo 'metadata restored from %s'", filerURL),
+		fmt.Sprintf("test -s %s", localPath),
+		fmt.Sprintf("echo '%s' | %s", metaLoadStatement(localPath, filerPath), shell),
+		fmt.Sprintf("echo 'metadata restored from %s'", localPath),
 		"",
 	}, "\n")
 }
@@ -182,26 +164,266 @@ func backupPodSpec(m *seaweedv1.Seaweed, name, command string, st seaweedv1.Back
 	}
 }
 
-// buildSnapshotJob returns the metadata-snapshot Job for a SeaweedBackup.
-func buildSnapshotJob(m *seaweedv1.Seaweed, jobName string, backup *seaweedv1.SeaweedBackup, st seaweedv1.BackupStorageSpec) (*batchv1.Job, string) {
-	script, dest := snapshotScript(m, st, backup.Spec.ClusterName, backup.Name, backup.Spec.FilerPath)
-	pod := backupPodSpec(m, "snapshot", script, st, true)
+// buildSnapshotJob returns the metadata-snapshot Job for a SeaweedBackup and
+// the snapshot's destination. The weed save runs as an init container and
+// backup-tool (toolImage) stores the result with its manifest, encrypting it
+// when the storage asks for it.
+func buildSnapshotJob(m *seaweedv1.Seaweed, jobName string, backup *seaweedv1.SeaweedBackup, st seaweedv1.BackupStorageSpec, toolImage string) (*batchv1.Job, string) {
+	rel := metaRelPath(backup.Spec.ClusterName, backupSnapshotName(backup))
+	script, dest := snapshotScript(m, st, backup.Spec.ClusterName, backupSnapshotName(backup), backup.Spec.FilerPath)
+	pod := backupPodSpec(m, "snapshot", script, st, false)
+	args := append(objectStoreArgs(st), "-file="+snapshotScratchFile, "-key="+snapshotObjectKey(st, rel))
+	if st.Encryption != nil {
+		args = append(args, "-encrypt")
+	}
+	upload, volumes := backupToolContainer(m, toolImage, snapshotUploadContainer, "snapshot-upload", args, st, false)
+	upload.Env = objectStoreEnv(st)
+	pod.InitContainers = pod.Containers
+	pod.Containers = []corev1.Container{upload}
+	pod.Volumes = appendMissingVolumes(pod.Volumes, volumes)
 	labels := map[string]string{
 		seaweedv1.LabelBackupCluster: backup.Spec.ClusterName,
 	}
 	if sched := backup.Labels[seaweedv1.LabelBackupSchedule]; sched != "" {
 		labels[seaweedv1.LabelBackupSchedule] = sched
 	}
-	return newJob(backup.Namespace, jobName, labels, pod), dest
+	return newJob(m.Namespace, jobName, labels, pod), dest
+}
+
+// snapshotRef locates the snapshot a restore loads.
+type snapshotRef struct {
+	// rel is the snapshot's path relative to the storage root (see
+	// metaRelPath).
+	rel string
+	// sha256 is the hex SHA-256 the backup recorded for it, if any.
+	sha256 string
+}
+
+// buildRestoreJob returns the restore Job for a SeaweedRestore loading the
+// snapshot snap in st. A backup-tool (toolImage) init container first
+// downloads it into the scratch dir, verifying it against its manifest and
+// recorded checksum and decrypting it; a snapshot that fails verification
+// fails the Job at once instead of being retried or loaded.
+func buildRestoreJob(m *seaweedv1.Seaweed, jobName string, restore *seaweedv1.SeaweedRestore, st seaweedv1.BackupStorageSpec, snap snapshotRef, toolImage string) *batchv1.Job {
+	local := path.Join(backupScratchDir, "restore.meta.gz")
+	pod := backupPodSpec(m, "restore", restoreScript(m, local, restore.Spec.FilerPath), st, false)
+	args := append(objectStoreArgs(st), "-key="+snapshotObjectKey(st, snap.rel), "-o="+local)
+	if snap.sha256 != "" {
+		args = append(args, "-sha256="+snap.sha256)
+	}
+	download, volumes := backupToolContainer(m, toolImage, snapshotDownloadContainer, "snapshot-download", args, st, false)
+	download.Env = objectStoreEnv(st)
+	download.TerminationMessagePolicy = corev1.TerminationMessageFallbackToLogsOnError
+	pod.InitContainers = []corev1.Container{download}
+	pod.Volumes = appendMissingVolumes(pod.Volumes, volumes)
+
+	job := newJob(m.Namespace, jobName, restoreJobLabels(restore), pod)
+	job.Spec.PodFailurePolicy = downloadFailurePolicy()
+	return job
+}
+
+// downloadFailurePolicy fails a restore Job at once when its download
+// container rejects the backup as corrupt or tampered with; retrying would
+// only fetch the same bytes.
+func downloadFailurePolicy() *batchv1.PodFailurePolicy {
+	downloadContainer := snapshotDownloadContainer
+	return &batchv1.PodFailurePolicy{Rules: []batchv1.PodFailurePolicyRule{{
+		Action: batchv1.PodFailurePolicyActionFailJob,
+		OnExitCodes: &batchv1.PodFailurePolicyOnExitCodesRequirement{
+			ContainerName: &downloadContainer,
+			Operator:      batchv1.PodFailurePolicyOnExitCodesOpIn,
+			Values:        []int32{backuptool.IntegrityExitCode},
+		},
+	}}}
+}
+
+// buildVolumeBackupJob returns the Job copying the volumes of m for a
+// SeaweedBackup with target volumes, and the backup's manifest location.
+// backup-tool (toolImage) reads each volume's files off a volume server and
+// stores them in segments, sharing those unchanged since previous, the
+// snapshot name of the cluster's last volumes backup on st ("" for a full
+// copy).
+func buildVolumeBackupJob(m *seaweedv1.Seaweed, jobName string, backup *seaweedv1.SeaweedBackup, st seaweedv1.BackupStorageSpec, previous, toolImage string) (*batchv1.Job, string) {
+	name := backupSnapshotName(backup)
+	args := append(objectStoreArgs(st),
+		"-master="+getMasterPeersString(m),
+		"-prefix="+snapshotObjectKey(st, volumesRelDir(backup.Spec.ClusterName)),
+		"-name="+name,
+		"-scratch="+backupScratchDir,
+	)
+	if previous != "" {
+		args = append(args, "-previous="+previous)
+	}
+	for _, c := range backup.Spec.Collections {
+		args = append(args, "-collection="+c)
+	}
+	if st.Encryption != nil {
+		args = append(args, "-encrypt")
+	}
+	upload, volumes := backupToolContainer(m, toolImage, snapshotUploadContainer, "volume-backup", args, st, true)
+	upload.Env = objectStoreEnv(st)
+	enableServiceLinks := false
+	pod := corev1.PodSpec{
+		RestartPolicy:      corev1.RestartPolicyNever,
+		ImagePullSecrets:   m.Spec.ImagePullSecrets,
+		EnableServiceLinks: &enableServiceLinks,
+		Containers:         []corev1.Container{upload},
+		Volumes:            volumes,
+	}
+	labels := map[string]string{
+		seaweedv1.LabelBackupCluster: backup.Spec.ClusterName,
+	}
+	if sched := backup.Labels[seaweedv1.LabelBackupSchedule]; sched != "" {
+		labels[seaweedv1.LabelBackupSchedule] = sched
+	}
+	dest := objectStoreURI(st, snapshotObjectKey(st, volumesManifestRelPath(backup.Spec.ClusterName, name)))
+	return newJob(m.Namespace, jobName, labels, pod), dest
+}
+
+// Volume files a restore assembles are served by a temporary volume server in
+// the restore pod, on these ports, until they are moved to the cluster's.
+const (
+	restoreVolumeDir      = backupScratchDir + "/volumes"
+	restoreVolumePort     = 8080
+	restoreVolumeGRPCPort = 18080
+)
+
+// volumeRestoreScript returns the shell program a volumes restore Job runs
+// once its download container assembled the missing volumes in
+// restoreVolumeDir: it serves them from a temporary volume server registered
+// with the cluster's masters, then moves them onto the cluster's volume
+// servers with `volumeServer.evacuate`. The volume server only takes as many
+// volumes as it was given, so the masters grow no new ones on it, and the
+// Job fails unless every volume was moved off.
+func volumeRestoreScript(m *seaweedv1.Seaweed) string {
+	masters := getMasterPeersString(m)
+	shell := weedCmd(m, "shell", "-master="+masters)
+	server := weedCmd(m, "volume",
+		"-dir="+restoreVolumeDir,
+		`-max="$count"`,
+		"-mserver="+masters,
+		`-ip="$POD_IP"`,
+		fmt.Sprintf("-port=%d", restoreVolumePort),
+		fmt.Sprintf("-port.grpc=%d", restoreVolumeGRPCPort),
+	)
+	return strings.Join([]string{
+		"set -euo pipefail",
+		fmt.Sprintf("count=$(ls %s | grep -c '\\.dat$' || true)", restoreVolumeDir),
+		`if [ "$count" -eq 0 ]; then echo 'the cluster has every volume of the backup'; exit 0; fi`,
+		server + " &",
+		"server=$!",
+		`trap 'kill $server 2>/dev/null || true' EXIT`,
+		fmt.Sprintf(`node="$POD_IP:%d"`, restoreVolumePort),
+		"tries=0",
+		fmt.Sprintf(`until echo 'volume.list' | %s | grep -F "$node" >/dev/null; do`, shell),
+		`  tries=$((tries+1)); if [ "$tries" -ge 60 ]; then echo "volume server $node did not register"; exit 1; fi; sleep 5`,
+		"done",
+		fmt.Sprintf(`printf 'lock\nvolumeServer.evacuate -node %%s -apply\nunlock\n' "$node" | %s`, shell),
+		fmt.Sprintf("left=$(ls %s | grep -c '\\.dat$' || true)", restoreVolumeDir),
+		`if [ "$left" -ne 0 ]; then echo "$left of $count volumes were not moved off $node"; exit 1; fi`,
+		`echo "restored $count volumes"`,
+		"",
+	}, "\n")
+}
+
+// buildVolumeRestoreJob returns the restore Job for a SeaweedRestore with mode
+// volumes restoring the volumes backup whose manifest is snap.rel. A
+// backup-tool (toolImage) init container assembles the volumes m does not
+// have, verifying each against the manifest and the manifest against the
+// checksum the backup recorded; the main container then hands them to the
+// cluster (see volumeRestoreScript).
+func buildVolumeRestoreJob(m *seaweedv1.Seaweed, jobName string, restore *seaweedv1.SeaweedRestore, st seaweedv1.BackupStorageSpec, snap snapshotRef, toolImage string) *batchv1.Job {
+	pod := backupPodSpec(m, "restore", volumeRestoreScript(m), st, false)
+	pod.Containers[0].Env = []corev1.EnvVar{{
+		Name:      "POD_IP",
+		ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.podIP"}},
+	}}
+	pod.Containers[0].Ports = []corev1.ContainerPort{
+		{Name: "volume-http", ContainerPort: restoreVolumePort},
+		{Name: "volume-grpc", ContainerPort: restoreVolumeGRPCPort},
+	}
+	args := append(objectStoreArgs(st),
+		"-manifest="+snapshotObjectKey(st, snap.rel),
+		"-master="+getMasterPeersString(m),
+		"-o="+restoreVolumeDir,
+	)
+	if snap.sha256 != "" {
+		args = append(args, "-sha256="+snap.sha256)
+	}
+	download, volumes := backupToolContainer(m, toolImage, snapshotDownloadContainer, "volume-restore", args, st, true)
+	download.Env = objectStoreEnv(st)
+	download.TerminationMessagePolicy = corev1.TerminationMessageFallbackToLogsOnError
+	pod.InitContainers = []corev1.Container{download}
+	pod.Volumes = appendMissingVolumes(pod.Volumes, volumes)
+
+	job := newJob(m.Namespace, jobName, restoreJobLabels(restore), pod)
+	job.Spec.PodFailurePolicy = downloadFailurePolicy()
+	return job
+}
+
+// legacyStagedSnapshotDir is the filer directory object-store snapshots were
+// staged in before they were uploaded directly; backups taken then record a
+// destination below it.
+const legacyStagedSnapshotDir = "/.seaweedfs-operator/backups"
+
+// buildArtifactCleanupJob returns the Job removing a SeaweedBackup's snapshot
+// from st: an rm on the PVC, an `fs.rm` for snapshots staged in the filer, or
+// backup-tool (toolImage) deleting the object, or the segments of a volumes
+// backup no later backup shares.
+func buildArtifactCleanupJob(m *seaweedv1.Seaweed, jobName string, backup *seaweedv1.SeaweedBackup, st seaweedv1.BackupStorageSpec, toolImage string) *batchv1.Job {
+	rel := metaRelPath(backup.Spec.ClusterName, backupSnapshotName(backup))
+	labels := map[string]string{seaweedv1.LabelBackupCluster: backup.Spec.ClusterName}
+	dest := backup.Status.Destination
+
+	var args []string
+	subcommand := "snapshot-delete"
+	switch {
+	case backup.Spec.Target == seaweedv1.BackupTargetVolumes:
+		// Segments shared with later backups stay; backup-tool works that
+		// out from the index, on a PVC as in an object store.
+		subcommand = "volume-delete"
+		args = append(objectStoreArgs(st),
+			"-prefix="+snapshotObjectKey(st, volumesRelDir(backup.Spec.ClusterName)),
+			"-name="+backupSnapshotName(backup),
+		)
+	case st.Type == seaweedv1.BackupStorageFilesystem:
+		file := path.Join(filesystemMountPath(st.Filesystem), rel)
+		script := strings.Join([]string{
+			"set -euo pipefail",
+			fmt.Sprintf("rm -f %s %s", file, path.Join(path.Dir(file), snapshotManifestName)),
+			fmt.Sprintf("rmdir %s 2>/dev/null || true", path.Dir(file)),
+			fmt.Sprintf("echo 'deleted %s'", file),
+			"",
+		}, "\n")
+		return newJob(m.Namespace, jobName, labels, backupPodSpec(m, "cleanup", script, st, true))
+	case strings.HasPrefix(dest, legacyStagedSnapshotDir+"/"):
+		shell := weedCmd(m, "shell", "-master="+getMasterPeersString(m), "-filer="+getFilerAddress(m))
+		script := strings.Join([]string{
+			"set -euo pipefail",
+			fmt.Sprintf("echo 'fs.rm -r %s' | %s", path.Dir(dest), shell),
+			fmt.Sprintf("echo 'deleted %s'", dest),
+			"",
+		}, "\n")
+		return newJob(m.Namespace, jobName, labels, backupPodSpec(m, "cleanup", script, st, false))
+	default:
+		args = append(objectStoreArgs(st), "-key="+snapshotObjectKey(st, rel))
+	}
+	container, volumes := backupToolContainer(m, toolImage, "cleanup", subcommand, args, st, false)
+	container.Env = objectStoreEnv(st)
+	enableServiceLinks := false
+	pod := corev1.PodSpec{
+		RestartPolicy:      corev1.RestartPolicyNever,
+		ImagePullSecrets:   m.Spec.ImagePullSecrets,
+		EnableServiceLinks: &enableServiceLinks,
+		Containers:         []corev1.Container{container},
+		Volumes:            volumes,
+	}
+	return newJob(m.Namespace, jobName, labels, pod)
 }
 
-// buildRestoreJob returns the restore Job for a SeaweedRestore. Exactly one of
-// localPath / filerURL is set by the caller depending on the storage type.
-func buildRestoreJob(m *seaweedv1.Seaweed, jobName string, restore *seaweedv1.SeaweedRestore, st seaweedv1.BackupStorageSpec, localPath, filerURL string) *batchv1.Job {
-	script := restoreScript(m, localPath, filerURL, restore.Spec.FilerPath)
-	pod := backupPodSpec(m, "restore", script, st, localPath != "")
-	labels := map[string]string{seaweedv1.LabelBackupCluster: restore.Spec.ClusterName}
-	return newJob(restore.Namespace, jobName, labels, pod)
+// restoreJobLabels are the labels of a restore Job.
+func restoreJobLabels(restore *seaweedv1.SeaweedRestore) map[string]string {
+	return map[string]string{seaweedv1.LabelBackupCluster: restore.Spec.ClusterName}
 }
 
 // newJob wraps a pod spec in a one-shot Job.
@@ -223,10 +445,17 @@ func newJob(namespace, name string, labels map[string]string, pod corev1.PodSpec
 	}
 }
 
+// mirrorResyncAnnotation carries spec.backup.dataMirror[].resync on the
+// mirror's pod template, so that a new value restarts the mirror even while
+// it is already walking the tree.
+const mirrorResyncAnnotation = "seaweed.seaweedfs.com/mirror-resync"
+
 // buildMirrorDeployment returns the continuous `weed filer.backup` Deployment
 // for a data mirror. The rendered replication.toml (and, on TLS clusters,
-// security.toml + the GCS key) are projected into backupConfigDir.
-func buildMirrorDeployment(m *seaweedv1.Seaweed, name, replicationSecret string, mirror seaweedv1.BackupMirrorSpec, st seaweedv1.BackupStorageSpec, hasGCSKey bool) *appsv1.Deployment {
+// security.toml + the GCS key) are projected into backupConfigDir. With
+// initialSnapshot the mirror first walks the whole subtree; without it, it
+// resumes from the checkpoint it persists on the filer.
+func buildMirrorDeployment(m *seaweedv1.Seaweed, name, replicationSecret string, mirror seaweedv1.BackupMirrorSpec, st seaweedv1.BackupStorageSpec, hasGCSKey, initialSnapshot bool) *appsv1.Deployment {
 	filer := getFilerAddress(m)
 	fp := filerPathOrDefault(mirror.FilerPath)
 
@@ -241,10 +470,16 @@ func buildMirrorDeployment(m *seaweedv1.Seaweed, name, replicationSecret string,
 		"filer.backup",
 		"-filer="+filer,
 		"-filerPath="+fp,
-		"-initialSnapshot",
 	)
+	if initialSnapshot {
+		cmd = append(cmd, "-initialSnapshot")
+	}
 
 	labels := labelsForBackupMirror(m.Name, mirror.StorageName)
+	var annotations map[string]string
+	if mirror.Resync != "" {
+		annotations = map[string]string{mirrorResyncAnnotation: mirror.Resync}
+	}
 
 	// Project replication.toml (+ security.toml + gcs.json on demand) into one
 	// config dir so `weed -config_dir` finds everything it needs.
@@ -264,34 +499,7 @@ func buildMirrorDeployment(m *seaweedv1.Seaweed, name, replicationSecret string,
 		configSources[0].Secret.Items = append(configSources[0].Secret.Items, corev1.KeyToPath{Key: gcsKeyFileName, Path: gcsKeyFileName})
 	}
 
-	volumes := []corev1.Volume{}
-	var mounts []corev1.VolumeMount
-	if securityConfigNeeded(m) {
-		configSources = append(configSources, corev1.VolumeProjection{
-			Secret: &corev1.SecretProjection{
-				LocalObjectReference: corev1.LocalObjectReference{Name: SecurityConfigSecretName(m)},
-				Items:                []corev1.KeyToPath{{Key: "security.toml", Path: "security.toml"}},
-			},
-		})
-	}
-	volumes = append(volumes, corev1.Volume{
-		Name:         "weed-config",
-		VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{Sources: configSources}},
-	})
-	mounts = append(mounts, corev1.VolumeMount{Name: "weed-config", ReadOnly: true, MountPath: backupConfigDir})
-
-	// The TLS certs that security.toml references still mount at their canonical
-	// path; reuse the shared helper and drop its security-config mount (we
-	// project security.toml above instead).
-	if tlsEffective(m) {
-		volumes = append(volumes, corev1.Volume{
-			Name: tlsVolumeName,
-			VolumeSource: corev1.VolumeSource{
-				Secret: &corev1.SecretVolumeSource{SecretName: TLSServerSecretName(m)},
-			},
-		})
-		mounts = append(mounts, corev1.VolumeMount{Name: tlsVolumeName, ReadOnly: true, MountPath: tlsMountPath})
-	}
+	volumes, mounts := backupConfigVolumes(m, configSources)
 
 	if st.Type == seaweedv1.BackupStorageFilesystem && st.Filesystem != nil {
 		vol, mount := filesystemPVCVolume(st.Filesystem)
@@ -310,15 +518,15 @@ func buildMirrorDeployment(m *seaweedv1.Seaweed, name, replicationSecret string,
 		Spec: appsv1.DeploymentSpec{
 			Replicas: &replicas,
 			Selector: &metav1.LabelSelector{MatchLabels: labels},
-			// filer.backup keeps a local checkpoint; never run two at once.
+			// filer.backup keeps one checkpoint per sink; never run two at once.
 			Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
 			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				ObjectMeta: metav1.ObjectMeta{Labels: labels, Annotations: annotations},
 				Spec: corev1.PodSpec{
 					ImagePullSecrets:   m.Spec.ImagePullSecrets,
 					EnableServiceLinks: &enableServiceLinks,
 					Containers: []corev1.Container{{
-						Name:            "filer-backup",
+						Name:            mirrorContainerName,
 						Image:           backupImage(m),
 						ImagePullPolicy: m.Spec.ImagePullPolicy,
 						Command:         cmd,
@@ -331,6 +539,46 @@ func buildMirrorDeployment(m *seaweedv1.Seaweed, name, replicationSecret string,
 	}
 }
 
+// backupConfigVolumes projects configSources, plus security.toml on clusters
+// that need it, into backupConfigDir and mounts the TLS certs security.toml
+// references at their canonical path.
+func backupConfigVolumes(m *seaweedv1.Seaweed, configSources []corev1.VolumeProjection) ([]corev1.Volume, []corev1.VolumeMount) {
+	var volumes []corev1.Volume
+	var mounts []corev1.VolumeMount
+	if securityConfigNeeded(m) {
+		configSources = append(configSources, corev1.VolumeProjection{
+			Secret: &corev1.SecretProjection{
+				LocalObjectReference: corev1.LocalObjectReference{Name: SecurityConfigSecretName(m)},
+				Items:                []corev1.KeyToPath{{Key: "security.toml", Path: "security.toml"}},
+			},
+		})
+	}
+	if len(configSources) > 0 {
+		volumes = append(volumes, corev1.Volume{
+			Name:         "weed-config",
+			VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{Sources: configSources}},
+		})
+		mounts = append(mounts, corev1.VolumeMount{Name: "weed-config", ReadOnly: true, MountPath: backupConfigDir})
+	}
+
+	// Reuse the shared TLS secret but not its security-config mount; the
+	// projection above already carries security.toml.
+	if tlsEffective(m) {
+		volumes = append(volumes, corev1.Volume{
+			Name: tlsVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: TLSServerSecretName(m)},
+			},
+		})
+		mounts = append(mounts, corev1.VolumeMount{Name: tlsVolumeName, ReadOnly: true, MountPath: tlsMountPath})
+	}
+	return volumes, mounts
+}
+
+// mirrorContainerName is the container running filer.backup in a mirror
+// Deployment.
+const mirrorContainerName = "filer-backup"
+
 // mirrorDeploymentName is the deterministic name of a mirror Deployment.
 func mirrorDeploymentName(cluster, storage string) string {
 	return fmt.Sprintf("%s-backup-mirror-%s", cluster, storage)
@@ -353,3 +601,229 @@ func labelsForBackupMirror(cluster, storage string) map[string]string {
 		"seaweed.seaweedfs.com/backup-storage": storage,
 	}
 }
+
+// metaLogStoreArgs are the backup-tool flags locating a cluster's metadata-log
+// segments in the storage itself, so capture and replay use the same store
+// and a restore into a fresh cluster finds them.
+func metaLogStoreArgs(st seaweedv1.BackupStorageSpec, cluster string) []string {
+	return append(objectStoreArgs(st), "-prefix="+snapshotObjectKey(st, metaLogRelDir(cluster)))
+}
+
+// buildMetaLogDeployment returns the continuous metadata-log Deployment for
+// one spec.backup.metadataLog entry.
+func buildMetaLogDeployment(m *seaweedv1.Seaweed, name, toolImage string, l seaweedv1.BackupMetadataLogSpec, st seaweedv1.BackupStorageSpec) *appsv1.Deployment {
+	args := []string{
+		"-filer=" + getFilerAddress(m),
+		"-pathPrefix=" + filerPathOrDefault(l.FilerPath),
+		"-excludePrefix=" + reservedOperatorFilerDir,
+		fmt.Sprintf("-segment=%dm", metaLogSegmentMinutes(l)),
+	}
+	args = append(args, metaLogStoreArgs(st, m.Name)...)
+	if st.Type != seaweedv1.BackupStorageFilesystem {
+		args = append(args, "-scratchDir="+backupScratchDir)
+	}
+	container, volumes := backupToolContainer(m, toolImage, "metalog", "metalog-capture", args, st, true)
+	container.Env = objectStoreEnv(st)
+
+	labels := labelsForBackupMetaLog(m.Name, l.StorageName)
+	replicas := int32(1)
+	enableServiceLinks := false
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: m.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			// Two captures would append to the same open segment.
+			Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					ImagePullSecrets:   m.Spec.ImagePullSecrets,
+					EnableServiceLinks: &enableServiceLinks,
+					Containers:         []corev1.Container{container},
+					Volumes:            volumes,
+				},
+			},
+		},
+	}
+}
+
+// metaLogCoverageContainer is the point-in-time restore Job init container
+// waiting for the metadata log to reach the instant to restore to.
+const metaLogCoverageContainer = "coverage"
+
+// pointInTimeReplay describes the metadata-log replay a point-in-time restore
+// runs after loading its base snapshot.
+type pointInTimeReplay struct {
+	toolImage string
+	// sourceCluster is the cluster whose log is replayed.
+	sourceCluster string
+	since, until  metav1.Time
+	// segmentMinutes is how long the log's segments stay open.
+	segmentMinutes int32
+}
+
+// buildPointInTimeRestoreJob returns the restore Job for a point-in-time
+// restore: the snapshot load of buildRestoreJob runs as an init container,
+// then backup-tool replays the metadata log up to the requested instant.
+// Before anything is loaded, a first init container waits for the log to
+// reach that instant, for up to a segment interval and a few minutes; a log
+// that still falls short fails the Job at once.
+func buildPointInTimeRestoreJob(m *seaweedv1.Seaweed, jobName string, restore *seaweedv1.SeaweedRestore, st seaweedv1.BackupStorageSpec, snap snapshotRef, replay pointInTimeReplay) *batchv1.Job {
+	job := buildRestoreJob(m, jobName, restore, st, snap, replay.toolImage)
+	pod := &job.Spec.Template.Spec
+
+	window := []string{
+		"-since=" + replay.since.UTC().Format(time.RFC3339),
+		"-until=" + replay.until.UTC().Format(time.RFC3339),
+	}
+	checkArgs := append([]string{"-checkOnly", fmt.Sprintf("-wait=%dm", replay.segmentMinutes+3)}, window...)
+	checkArgs = append(checkArgs, metaLogStoreArgs(st, replay.sourceCluster)...)
+	check, checkVolumes := backupToolContainer(m, replay.toolImage, metaLogCoverageContainer, "metalog-replay", checkArgs, st, false)
+	check.Env = objectStoreEnv(st)
+	check.TerminationMessagePolicy = corev1.TerminationMessageFallbackToLogsOnError
+
+	args := append([]string{
+		"-filer=" + getFilerAddress(m),
+		"-pathPrefix=" + filerPathOrDefault(restore.Spec.FilerPath),
+		"-excludePrefix=" + reservedOperatorFilerDir,
+	}, window...)
+	args = append(args, metaLogStoreArgs(st, replay.sourceCluster)...)
+	container, volumes := backupToolContainer(m, replay.toolImage, "replay", "metalog-replay", args, st, true)
+	container.Env = objectStoreEnv(st)
+
+	pod.InitContainers = append(append([]corev1.Container{check}, pod.InitContainers...), pod.Containers...)
+	pod.Containers = []corev1.Container{container}
+	pod.Volumes = appendMissingVolumes(appendMissingVolumes(pod.Volumes, checkVolumes), volumes)
+
+	coverage := metaLogCoverageContainer
+	job.Spec.PodFailurePolicy.Rules = append(job.Spec.PodFailurePolicy.Rules, batchv1.PodFailurePolicyRule{
+		Action: batchv1.PodFailurePolicyActionFailJob,
+		OnExitCodes: &batchv1.PodFailurePolicyOnExitCodesRequirement{
+			ContainerName: &coverage,
+			Operator:      batchv1.PodFailurePolicyOnExitCodesOpIn,
+			Values:        []int32{backuptool.LogIncompleteExitCode},
+		},
+	})
+	return job
+}
+
+// restoreReseedContainer is the container of a full restore's Job copying
+// file content back from the data mirror; its termination message carries
+// the reseed report.
+const restoreReseedContainer = "reseed"
+
+// reseedSource is the data mirror a full restore copies file content back
+// from.
+type reseedSource struct {
+	storageName string
+	storage     seaweedv1.BackupStorageSpec
+	// cluster is the cluster whose mirror wrote the sink.
+	cluster string
+	// sourceDir is the filer subtree the mirror copied, and walk the subtree
+	// reseeded: the deeper of sourceDir and the restore's filer path.
+	sourceDir string
+	walk      string
+}
+
+// mirrorSinkKey is the backup-tool key prefix a cluster's data mirror writes
+// under in st (see mirrorSinkDirectory).
+func mirrorSinkKey(st seaweedv1.BackupStorageSpec, cluster string) string {
+	return snapshotObjectKey(st, path.Join(cluster, "data"))
+}
+
+// withReseed appends the reseed step of a full restore to its restore Job:
+// the Job's main container becomes an init container, then backup-tool
+// (toolImage) copies the content of each file whose chunks are on volumes m
+// does not have back from the data mirror in src.
+func withReseed(job *batchv1.Job, m *seaweedv1.Seaweed, src reseedSource, toolImage string) {
+	args := append(objectStoreArgs(src.storage),
+		"-filer="+getFilerAddress(m),
+		"-pathPrefix="+src.walk,
+		"-excludePrefix="+reservedOperatorFilerDir,
+		"-sourceDir="+src.sourceDir,
+		"-prefix="+mirrorSinkKey(src.storage, src.cluster),
+	)
+	container, volumes := backupToolContainer(m, toolImage, restoreReseedContainer, "reseed", args, src.storage, true)
+	container.Env = objectStoreEnv(src.storage)
+	container.TerminationMessagePolicy = corev1.TerminationMessageFallbackToLogsOnError
+
+	pod := &job.Spec.Template.Spec
+	pod.InitContainers = append(pod.InitContainers, pod.Containers...)
+	pod.Containers = []corev1.Container{container}
+	pod.Volumes = appendMissingVolumes(pod.Volumes, volumes)
+}
+
+// verificationCheckContainer is the check Job container of a
+// BackupVerification; its termination message carries the check report.
+const verificationCheckContainer = "check"
+
+// buildVerificationCheckJob returns the Job checking the metadata a
+// BackupVerification restored into its scratch cluster m: backup-tool
+// (toolImage) counts the entries under filerPath, the backup's subtree, and
+// looks up the sample paths.
+func buildVerificationCheckJob(m *seaweedv1.Seaweed, jobName string, v *seaweedv1.BackupVerification, filerPath, toolImage string) *batchv1.Job {
+	args := []string{
+		"-filer=" + getFilerAddress(m),
+		"-pathPrefix=" + filerPathOrDefault(filerPath),
+		"-excludePrefix=" + reservedOperatorFilerDir,
+		fmt.Sprintf("-minEntries=%d", v.Spec.MinEntries),
+	}
+	for _, p := range v.Spec.SamplePaths {
+		args = append(args, "-sample="+p)
+	}
+	container, volumes := backupToolContainer(m, toolImage, verificationCheckContainer, "metadata-check", args, seaweedv1.BackupStorageSpec{}, true)
+	container.TerminationMessagePolicy = corev1.TerminationMessageFallbackToLogsOnError
+	enableServiceLinks := false
+	pod := corev1.PodSpec{
+		RestartPolicy:      corev1.RestartPolicyNever,
+		ImagePullSecrets:   m.Spec.ImagePullSecrets,
+		EnableServiceLinks: &enableServiceLinks,
+		Containers:         []corev1.Container{container},
+		Volumes:            volumes,
+	}
+	return newJob(m.Namespace, jobName, map[string]string{seaweedv1.LabelBackupVerification: v.Name}, pod)
+}
+
+// appendMissingVolumes appends the volumes not already in volumes, by name.
+func appendMissingVolumes(volumes, more []corev1.Volume) []corev1.Volume {
+	for _, v := range more {
+		if !hasVolume(volumes, v.Name) {
+			volumes = append(volumes, v)
+		}
+	}
+	return volumes
+}
+
+// hasVolume reports whether volumes contains one named name.
+func hasVolume(volumes []corev1.Volume, name string) bool {
+	for _, v := range volumes {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// metaLogDeploymentName is the deterministic name of a metadata-log
+// Deployment.
+func metaLogDeploymentName(cluster, storage string) string {
+	return fmt.Sprintf("%s-backup-metalog-%s", cluster, storage)
+}
+
+// labelsForBackupMetaLog are the selector labels for a metadata-log
+// Deployment.
+func labelsForBackupMetaLog(cluster, storage string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":               "seaweedfs",
+		"app.kubernetes.io/component":          "backup-metalog",
+		"app.kubernetes.io/instance":           cluster,
+		"app.kubernetes.io/managed-by":         "seaweedfs-operator",
+		seaweedv1.LabelBackupCluster:           cluster,
+		"seaweed.seaweedfs.com/backup-storage": storage,
+	}
+}
@@ -33,6 +33,10 @@ import (
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
 )
 
+// verificationHistory is how many finished BackupVerifications a schedule
+// keeps; older ones are deleted.
+const verificationHistory = 3
+
 // backupSchedulerInterval is how often the scheduler evaluates cron schedules.
 // A schedule fires at most once per tick, so this also bounds how late a
 // backup can start relative to its cron time.
@@ -40,7 +44,9 @@ const backupSchedulerInterval = 30 * time.Second
 
 // BackupScheduler is a leader-elected Runnable that evaluates every Seaweed
 // cluster's spec.backup.schedule and creates SeaweedBackup CRs when due,
-// then prunes completed backups beyond each schedule's Keep. The due time is
+// then prunes backups beyond each schedule's Keep or Retention. Schedules
+// with a verification cron get BackupVerification restore drills the same
+// way. The due time is
 // derived from the most recent existing SeaweedBackup for the schedule; when a
 // schedule has no history yet, the scheduler anchors on the first time it
 // observed the schedule (held in memory) so the first run fires at the next
@@ -136,13 +142,20 @@ func (s *BackupScheduler) tick(ctx context.Context) error {
 			if err := s.reconcileSchedule(ctx, m, sched, now); err != nil {
 				s.Log.Error(err, "reconcile schedule", "cluster", m.Name, "schedule", sched.Name)
 			}
+			if sched.Verification == nil {
+				continue
+			}
+			if err := s.reconcileVerification(ctx, m, sched, now); err != nil {
+				s.Log.Error(err, "reconcile schedule verification", "cluster", m.Name, "schedule", sched.Name)
+			}
 		}
 	}
 	return nil
 }
 
 // reconcileSchedule fires a backup when the cron is due (relative to the most
-// recent existing backup) and enforces retention.
+// recent existing backup) and its storage is not known to be unreachable, and
+// enforces retention.
 func (s *BackupScheduler) reconcileSchedule(ctx context.Context, m *seaweedv1.Seaweed, sched seaweedv1.BackupScheduleSpec, now time.Time) error {
 	cronSched, err := cron.ParseStandard(sched.Schedule)
 	if err != nil {
@@ -166,14 +179,134 @@ func (s *BackupScheduler) reconcileSchedule(ctx context.Context, m *seaweedv1.Se
 	}
 
 	if !cronSched.Next(lastRun).After(now) {
-		if err := s.createScheduledBackup(ctx, m, sched, now); err != nil {
-			return err
+		// A due backup waits out an unreachable storage rather than fail;
+		// it fires on the first tick after a probe succeeds.
+		if ok, why := storageReachable(m, sched.StorageName); !ok {
+			s.Log.Info("skipping scheduled backup to unreachable storage", "cluster", m.Name, "schedule", sched.Name, "storage", sched.StorageName, "reason", why)
+		} else {
+			if err := s.createScheduledBackup(ctx, m, sched, now); err != nil {
+				return err
+			}
+			// The new backup is now the schedule's history; drop the anchor.
+			s.clearAnchor(key)
 		}
-		// The new backup is now the schedule's history; drop the anchor.
+	}
+
+	return s.pruneBackups(ctx, existing, scheduleRetention(sched))
+}
+
+// reconcileVerification starts a restore drill of the schedule's latest
+// completed backup when its verification cron is due, and prunes finished
+// drills beyond verificationHistory. A due drill waits for the schedule's
+// first completed backup.
+func (s *BackupScheduler) reconcileVerification(ctx context.Context, m *seaweedv1.Seaweed, sched seaweedv1.BackupScheduleSpec, now time.Time) error {
+	cronSched, err := cron.ParseStandard(sched.Verification.Schedule)
+	if err != nil {
+		return fmt.Errorf("invalid verification cron %q: %w", sched.Verification.Schedule, err)
+	}
+	var list seaweedv1.BackupVerificationList
+	if err := s.List(ctx, &list, client.InNamespace(m.Namespace), client.MatchingLabels{
+		seaweedv1.LabelBackupCluster:  m.Name,
+		seaweedv1.LabelBackupSchedule: sched.Name,
+	}); err != nil {
+		return err
+	}
+	verifications := list.Items
+	sort.Slice(verifications, func(i, j int) bool {
+		return verifications[i].CreationTimestamp.After(verifications[j].CreationTimestamp.Time)
+	})
+
+	key := m.Namespace + "/" + m.Name + "/" + sched.Name + "/verification"
+	var lastRun time.Time
+	if len(verifications) > 0 {
+		lastRun = verifications[0].CreationTimestamp.Time
 		s.clearAnchor(key)
+	} else {
+		lastRun = s.anchorFor(key, now)
+	}
+
+	if !cronSched.Next(lastRun).After(now) {
+		backups, err := s.listScheduleBackups(ctx, m, sched.Name)
+		if err != nil {
+			return err
+		}
+		if latest := latestCompletedBackup(backups); latest != nil {
+			if err := s.createScheduledVerification(ctx, m, sched, latest.Name, now); err != nil {
+				return err
+			}
+			s.clearAnchor(key)
+		}
 	}
 
-	return s.pruneBackups(ctx, existing, sched.Keep)
+	finished := 0
+	for i := range verifications {
+		v := &verifications[i]
+		if v.Status.Phase != seaweedv1.VerificationPhasePassed && v.Status.Phase != seaweedv1.VerificationPhaseFailed {
+			continue
+		}
+		if finished++; finished <= verificationHistory {
+			continue
+		}
+		if err := s.Delete(ctx, v); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// createScheduledVerification creates one BackupVerification of backup for a
+// fired verification cron.
+func (s *BackupScheduler) createScheduledVerification(ctx context.Context, m *seaweedv1.Seaweed, sched seaweedv1.BackupScheduleSpec, backup string, fireTime time.Time) error {
+	name := boundedName(fmt.Sprintf("%s-%s", m.Name, sched.Name), "-verify-"+fireTime.UTC().Format("20060102150405"))
+	verification := &seaweedv1.BackupVerification{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: m.Namespace,
+			Labels: map[string]string{
+				seaweedv1.LabelBackupCluster:  m.Name,
+				seaweedv1.LabelBackupSchedule: sched.Name,
+			},
+		},
+		Spec: seaweedv1.BackupVerificationSpec{
+			ClusterName: m.Name,
+			BackupName:  backup,
+			SamplePaths: sched.Verification.SamplePaths,
+			MinEntries:  sched.Verification.MinEntries,
+			Timeout:     sched.Verification.Timeout,
+		},
+	}
+	if err := s.Create(ctx, verification); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return nil
+		}
+		return err
+	}
+	s.Log.Info("created scheduled backup verification", "verification", name, "backup", backup, "cluster", m.Name, "schedule", sched.Name)
+	return nil
+}
+
+// latestCompletedBackup returns the newest Completed backup, or nil.
+func latestCompletedBackup(backups []seaweedv1.SeaweedBackup) *seaweedv1.SeaweedBackup {
+	var latest *seaweedv1.SeaweedBackup
+	for i := range backups {
+		b := &backups[i]
+		if b.Status.Phase != seaweedv1.BackupPhaseCompleted {
+			continue
+		}
+		if latest == nil || b.CreationTimestamp.After(latest.CreationTimestamp.Time) {
+			latest = b
+		}
+	}
+	return latest
+}
+
+// scheduleRetention is the retention policy a schedule enforces: Retention
+// when set, else Keep as a keep-last count.
+func scheduleRetention(sched seaweedv1.BackupScheduleSpec) seaweedv1.BackupRetentionPolicy {
+	if sched.Retention != nil {
+		return *sched.Retention
+	}
+	return seaweedv1.BackupRetentionPolicy{KeepLast: sched.Keep}
 }
 
 // createScheduledBackup creates one SeaweedBackup for a fired schedule.
@@ -194,9 +327,18 @@ func (s *BackupScheduler) createScheduledBackup(ctx context.Context, m *seaweedv
 		Spec: seaweedv1.SeaweedBackupSpec{
 			ClusterName: m.Name,
 			StorageName: sched.StorageName,
+			Target:      sched.Target,
 			FilerPath:   sched.FilerPath,
+			Collections: sched.Collections,
+			PreHooks:    sched.PreHooks,
+			PostHooks:   sched.PostHooks,
 		},
 	}
+	// Retention manages artifacts, so pruning a backup also removes its
+	// snapshot; plain Keep only prunes the CRs, as it always has.
+	if sched.Retention != nil {
+		backup.Spec.DeletionPolicy = seaweedv1.BackupDeletionDelete
+	}
 	if err := s.Create(ctx, backup); err != nil {
 		if apierrors.IsAlreadyExists(err) {
 			return nil
@@ -207,35 +349,85 @@ func (s *BackupScheduler) createScheduledBackup(ctx context.Context, m *seaweedv
 	return nil
 }
 
-// pruneBackups keeps the Keep most-recent terminal (Completed/Failed) backups
-// and deletes older terminal ones. Keep == 0 retains everything. Non-terminal
-// backups are never deleted and — importantly — never counted toward Keep, so a
-// burst of still-Running snapshots can't push completed ones out of retention.
-func (s *BackupScheduler) pruneBackups(ctx context.Context, backups []seaweedv1.SeaweedBackup, keep int32) error {
-	if keep <= 0 {
+// pruneBackups deletes the terminal (Completed/Failed) backups policy does not
+// retain. An empty policy retains everything. Non-terminal backups are never
+// deleted and — importantly — never counted toward any tier, so a burst of
+// still-Running snapshots can't push completed ones out of retention. Whether
+// a pruned backup's artifact goes with it is up to its deletionPolicy.
+func (s *BackupScheduler) pruneBackups(ctx context.Context, backups []seaweedv1.SeaweedBackup, policy seaweedv1.BackupRetentionPolicy) error {
+	if policy == (seaweedv1.BackupRetentionPolicy{}) {
 		return nil
 	}
-	sort.Slice(backups, func(i, j int) bool {
-		return backups[i].CreationTimestamp.After(backups[j].CreationTimestamp.Time)
-	})
-	terminal := int32(0)
+	retained := retainedBackups(backups, policy)
 	for i := range backups {
 		b := &backups[i]
-		if b.Status.Phase != seaweedv1.BackupPhaseCompleted && b.Status.Phase != seaweedv1.BackupPhaseFailed {
-			continue
-		}
-		terminal++
-		if terminal <= keep {
+		if !backupTerminal(b) || retained[b.Name] {
 			continue
 		}
 		if err := s.Delete(ctx, b); err != nil && !apierrors.IsNotFound(err) {
 			return err
 		}
-		s.Log.Info("pruned backup beyond retention", "backup", b.Name, "keep", keep)
+		s.Log.Info("pruned backup beyond retention", "backup", b.Name, "deletionPolicy", b.Spec.DeletionPolicy)
 	}
 	return nil
 }
 
+// retainedBackups returns the names of the terminal backups policy keeps:
+// the KeepLast newest terminal ones, plus the newest completed backup of each
+// of the KeepDaily / KeepWeekly / KeepMonthly most recent periods that have
+// one. Periods are calendar days, ISO weeks and months in UTC.
+func retainedBackups(backups []seaweedv1.SeaweedBackup, policy seaweedv1.BackupRetentionPolicy) map[string]bool {
+	sorted := append([]seaweedv1.SeaweedBackup(nil), backups...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].CreationTimestamp.After(sorted[j].CreationTimestamp.Time)
+	})
+	tiers := []struct {
+		keep   int32
+		period func(time.Time) string
+		seen   map[string]bool
+	}{
+		{policy.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }, map[string]bool{}},
+		{policy.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}, map[string]bool{}},
+		{policy.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") }, map[string]bool{}},
+	}
+
+	retained := map[string]bool{}
+	last := int32(0)
+	for i := range sorted {
+		b := &sorted[i]
+		if !backupTerminal(b) {
+			continue
+		}
+		if last < policy.KeepLast {
+			retained[b.Name] = true
+			last++
+		}
+		// A failed backup has no snapshot to stand for its period.
+		if b.Status.Phase != seaweedv1.BackupPhaseCompleted {
+			continue
+		}
+		created := b.CreationTimestamp.UTC()
+		for j := range tiers {
+			tier := &tiers[j]
+			p := tier.period(created)
+			if tier.seen[p] || int32(len(tier.seen)) >= tier.keep {
+				continue
+			}
+			tier.seen[p] = true
+			retained[b.Name] = true
+		}
+	}
+	return retained
+}
+
+// backupTerminal reports whether a backup has finished, either way.
+func backupTerminal(b *seaweedv1.SeaweedBackup) bool {
+	return b.Status.Phase == seaweedv1.BackupPhaseCompleted || b.Status.Phase == seaweedv1.BackupPhaseFailed
+}
+
 // listScheduleBackups returns the SeaweedBackups created by one schedule.
 func (s *BackupScheduler) listScheduleBackups(ctx context.Context, m *seaweedv1.Seaweed, schedule string) ([]seaweedv1.SeaweedBackup, error) {
 	var list seaweedv1.SeaweedBackupList
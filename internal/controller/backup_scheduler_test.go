@@ -18,6 +18,7 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -89,6 +90,45 @@ func TestSchedulerFiresWhenDue(t *testing.T) {
 	}
 }
 
+func TestSchedulerHoldsBackupForUnreachableStorage(t *testing.T) {
+	now := time.Date(2026, 6, 16, 2, 5, 0, 0, time.UTC)
+	sched := seaweedv1.BackupScheduleSpec{Name: "nightly", Schedule: "* * * * *", StorageName: "pvc"}
+	prior := backupWithSchedule("c1-nightly-old", "c1", "nightly", now.Add(-2*time.Minute), seaweedv1.BackupPhaseCompleted)
+	cli := newSchedulerClient(t, prior)
+	s := schedulerWith(cli, now)
+
+	m := &seaweedv1.Seaweed{ObjectMeta: metav1.ObjectMeta{Name: "c1", Namespace: "ns1"}}
+	m.Status.BackupStorages = []seaweedv1.BackupStorageStatus{{
+		Name: "pvc",
+		Conditions: []metav1.Condition{{
+			Type: seaweedv1.BackupStorageConditionReachable, Status: metav1.ConditionFalse,
+			Reason: "ProbeFailed", Message: "permission denied",
+		}},
+	}}
+	if err := s.reconcileSchedule(context.Background(), m, sched, now); err != nil {
+		t.Fatalf("reconcileSchedule: %v", err)
+	}
+	var list seaweedv1.SeaweedBackupList
+	if err := cli.List(context.Background(), &list, client.InNamespace("ns1")); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 {
+		t.Fatalf("expected no backup to an unreachable storage, got %d total", len(list.Items))
+	}
+
+	// Once a probe succeeds the overdue backup fires.
+	m.Status.BackupStorages[0].Conditions[0].Status = metav1.ConditionTrue
+	if err := s.reconcileSchedule(context.Background(), m, sched, now); err != nil {
+		t.Fatalf("reconcileSchedule: %v", err)
+	}
+	if err := cli.List(context.Background(), &list, client.InNamespace("ns1")); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 2 {
+		t.Fatalf("expected the held backup to fire, got %d total", len(list.Items))
+	}
+}
+
 func TestSchedulerDoesNotFireOnFirstObservation(t *testing.T) {
 	now := time.Date(2026, 6, 16, 2, 5, 0, 0, time.UTC)
 	sched := seaweedv1.BackupScheduleSpec{Name: "nightly", Schedule: "0 2 * * *", StorageName: "pvc"}
@@ -168,7 +208,7 @@ func TestPruneBackupsRetainsMostRecentCompleted(t *testing.T) {
 	cli := newSchedulerClient(t, objs...)
 	s := schedulerWith(cli, base)
 
-	if err := s.pruneBackups(context.Background(), backups, 2); err != nil {
+	if err := s.pruneBackups(context.Background(), backups, seaweedv1.BackupRetentionPolicy{KeepLast: 2}); err != nil {
 		t.Fatalf("pruneBackups: %v", err)
 	}
 
@@ -201,7 +241,7 @@ func TestPruneBackupsRunningDoesNotEvictCompleted(t *testing.T) {
 	cli := newSchedulerClient(t, run1, run2, done1, done2)
 	s := schedulerWith(cli, base)
 
-	if err := s.pruneBackups(context.Background(), []seaweedv1.SeaweedBackup{*run1, *run2, *done1, *done2}, 2); err != nil {
+	if err := s.pruneBackups(context.Background(), []seaweedv1.SeaweedBackup{*run1, *run2, *done1, *done2}, seaweedv1.BackupRetentionPolicy{KeepLast: 2}); err != nil {
 		t.Fatalf("pruneBackups: %v", err)
 	}
 	var list seaweedv1.SeaweedBackupList
@@ -223,7 +263,7 @@ func TestPruneBackupsSkipsRunning(t *testing.T) {
 
 	// Keep=1: only the newest completed is retained, but the Running one is
 	// never deleted even though it sorts oldest.
-	if err := s.pruneBackups(context.Background(), []seaweedv1.SeaweedBackup{*running, *old1, *old2}, 1); err != nil {
+	if err := s.pruneBackups(context.Background(), []seaweedv1.SeaweedBackup{*running, *old1, *old2}, seaweedv1.BackupRetentionPolicy{KeepLast: 1}); err != nil {
 		t.Fatalf("pruneBackups: %v", err)
 	}
 	var list seaweedv1.SeaweedBackupList
@@ -241,3 +281,189 @@ func TestPruneBackupsSkipsRunning(t *testing.T) {
 		t.Errorf("newest completed must be retained: %v", got)
 	}
 }
+
+func TestRetainedBackupsGFS(t *testing.T) {
+	// Backups at 00:00 and 12:00 every day from Sat 2026-05-30 through
+	// Wed 2026-06-10, newest last; the 12:00 one on 06-09 failed.
+	var backups []seaweedv1.SeaweedBackup
+	start := time.Date(2026, 5, 30, 0, 0, 0, 0, time.UTC)
+	for d := 0; d < 12; d++ {
+		for _, h := range []int{0, 12} {
+			at := start.AddDate(0, 0, d).Add(time.Duration(h) * time.Hour)
+			phase := seaweedv1.BackupPhaseCompleted
+			if at.Format("01-02 15") == "06-09 12" {
+				phase = seaweedv1.BackupPhaseFailed
+			}
+			backups = append(backups, *backupWithSchedule(at.Format("c1-nightly-0102-15"), "c1", "nightly", at, phase))
+		}
+	}
+	// A running backup is neither kept by a tier nor counted toward one.
+	backups = append(backups, *backupWithSchedule("c1-nightly-running", "c1", "nightly", start.AddDate(0, 0, 12), seaweedv1.BackupPhaseRunning))
+
+	got := retainedBackups(backups, seaweedv1.BackupRetentionPolicy{KeepLast: 2, KeepDaily: 3, KeepWeekly: 2, KeepMonthly: 2})
+	want := map[string]bool{
+		// KeepLast: the two newest terminal backups, the failed one included.
+		"c1-nightly-0610-12": true,
+		"c1-nightly-0610-00": true,
+		// KeepDaily: newest completed of 06-10, 06-09 (skipping the failed
+		// 12:00) and 06-08.
+		"c1-nightly-0609-00": true,
+		"c1-nightly-0608-12": true,
+		// KeepWeekly: ISO week 24 is covered by 06-10; week 23 ends Sun 06-07.
+		"c1-nightly-0607-12": true,
+		// KeepMonthly: June is covered by 06-10; May's newest is 05-31.
+		"c1-nightly-0531-12": true,
+	}
+	if len(got) != len(want) {
+		t.Errorf("retained %v, want %v", got, want)
+	}
+	for name := range want {
+		if !got[name] {
+			t.Errorf("%s not retained; retained %v", name, got)
+		}
+	}
+}
+
+func TestScheduleRetentionCreatesDeletableBackups(t *testing.T) {
+	now := time.Date(2026, 6, 16, 2, 0, 30, 0, time.UTC)
+	m := &seaweedv1.Seaweed{ObjectMeta: metav1.ObjectMeta{Name: "c1", Namespace: "ns1"}}
+	cli := newSchedulerClient(t, m)
+	s := schedulerWith(cli, now)
+
+	kept := seaweedv1.BackupScheduleSpec{Name: "nightly", Schedule: "0 2 * * *", StorageName: "pvc", Keep: 3}
+	managed := seaweedv1.BackupScheduleSpec{Name: "gfs", Schedule: "0 2 * * *", StorageName: "pvc",
+		Retention: &seaweedv1.BackupRetentionPolicy{KeepDaily: 7}}
+	for _, sched := range []seaweedv1.BackupScheduleSpec{kept, managed} {
+		if err := s.createScheduledBackup(context.Background(), m, sched, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for schedule, want := range map[string]seaweedv1.BackupDeletionPolicy{"nightly": "", "gfs": seaweedv1.BackupDeletionDelete} {
+		list, err := s.listScheduleBackups(context.Background(), m, schedule)
+		if err != nil || len(list) != 1 {
+			t.Fatalf("%s: %v backups, err %v", schedule, len(list), err)
+		}
+		if got := list[0].Spec.DeletionPolicy; got != want {
+			t.Errorf("%s: deletionPolicy = %q, want %q", schedule, got, want)
+		}
+	}
+	if got := scheduleRetention(kept); got != (seaweedv1.BackupRetentionPolicy{KeepLast: 3}) {
+		t.Errorf("keep maps to %+v", got)
+	}
+}
+
+func TestScheduleVolumesBackupCarriesTarget(t *testing.T) {
+	now := time.Date(2026, 6, 16, 2, 0, 30, 0, time.UTC)
+	m := &seaweedv1.Seaweed{ObjectMeta: metav1.ObjectMeta{Name: "c1", Namespace: "ns1"}}
+	cli := newSchedulerClient(t, m)
+	s := schedulerWith(cli, now)
+
+	sched := seaweedv1.BackupScheduleSpec{Name: "volumes", Schedule: "0 2 * * *", StorageName: "pvc", Keep: 7,
+		Target: seaweedv1.BackupTargetVolumes, Collections: []string{"photos"}}
+	if err := s.createScheduledBackup(context.Background(), m, sched, now); err != nil {
+		t.Fatal(err)
+	}
+	list, err := s.listScheduleBackups(context.Background(), m, "volumes")
+	if err != nil || len(list) != 1 {
+		t.Fatalf("%v backups, err %v", len(list), err)
+	}
+	spec := list[0].Spec
+	if spec.Target != seaweedv1.BackupTargetVolumes || len(spec.Collections) != 1 || spec.Collections[0] != "photos" {
+		t.Errorf("scheduled backup spec = %+v", spec)
+	}
+}
+
+func verificationWithSchedule(name string, created time.Time, phase seaweedv1.VerificationPhase) *seaweedv1.BackupVerification {
+	return &seaweedv1.BackupVerification{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "ns1",
+			CreationTimestamp: metav1.NewTime(created),
+			Labels: map[string]string{
+				seaweedv1.LabelBackupCluster:  "c1",
+				seaweedv1.LabelBackupSchedule: "nightly",
+			},
+		},
+		Spec:   seaweedv1.BackupVerificationSpec{ClusterName: "c1"},
+		Status: seaweedv1.BackupVerificationStatus{Phase: phase},
+	}
+}
+
+func TestScheduleVerificationDrillsLatestBackupAndPrunes(t *testing.T) {
+	now := time.Date(2026, 6, 21, 6, 0, 30, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+	objs := []client.Object{
+		backupWithSchedule("c1-nightly-old", "c1", "nightly", now.Add(-2*24*time.Hour), seaweedv1.BackupPhaseCompleted),
+		backupWithSchedule("c1-nightly-new", "c1", "nightly", now.Add(-24*time.Hour), seaweedv1.BackupPhaseCompleted),
+		backupWithSchedule("c1-nightly-running", "c1", "nightly", now.Add(-time.Minute), seaweedv1.BackupPhaseRunning),
+	}
+	for i := 1; i <= 4; i++ {
+		objs = append(objs, verificationWithSchedule(fmt.Sprintf("v%d", i), now.Add(-time.Duration(i)*week), seaweedv1.VerificationPhasePassed))
+	}
+	cli := newSchedulerClient(t, objs...)
+	s := schedulerWith(cli, now)
+
+	m := &seaweedv1.Seaweed{ObjectMeta: metav1.ObjectMeta{Name: "c1", Namespace: "ns1"}}
+	sched := seaweedv1.BackupScheduleSpec{Name: "nightly", Schedule: "0 2 * * *", StorageName: "pvc",
+		Verification: &seaweedv1.BackupScheduleVerification{Schedule: "0 6 * * 0", MinEntries: 10}}
+	if err := s.reconcileVerification(context.Background(), m, sched, now); err != nil {
+		t.Fatalf("reconcileVerification: %v", err)
+	}
+
+	var list seaweedv1.BackupVerificationList
+	if err := cli.List(context.Background(), &list, client.InNamespace("ns1")); err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]seaweedv1.BackupVerificationSpec{}
+	for _, v := range list.Items {
+		names[v.Name] = v.Spec
+	}
+	if _, ok := names["v4"]; ok {
+		t.Error("the oldest finished verification beyond the history was not pruned")
+	}
+	created, ok := names[boundedName("c1-nightly", "-verify-"+now.Format("20060102150405"))]
+	if !ok {
+		t.Fatalf("no verification created; have %v", names)
+	}
+	if created.BackupName != "c1-nightly-new" || created.MinEntries != 10 {
+		t.Errorf("verification spec = %+v, want the latest completed backup", created)
+	}
+}
+
+func TestScheduleVerificationWaitsForABackup(t *testing.T) {
+	now := time.Date(2026, 6, 21, 6, 0, 30, 0, time.UTC)
+	cli := newSchedulerClient(t)
+	s := schedulerWith(cli, now.Add(-8*24*time.Hour))
+	m := &seaweedv1.Seaweed{ObjectMeta: metav1.ObjectMeta{Name: "c1", Namespace: "ns1"}}
+	sched := seaweedv1.BackupScheduleSpec{Name: "nightly", Schedule: "0 2 * * *", StorageName: "pvc",
+		Verification: &seaweedv1.BackupScheduleVerification{Schedule: "0 6 * * 0"}}
+
+	// Anchor on first observation, then let the weekly cron come due with
+	// no completed backup yet.
+	if err := s.reconcileVerification(context.Background(), m, sched, now.Add(-8*24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.reconcileVerification(context.Background(), m, sched, now); err != nil {
+		t.Fatal(err)
+	}
+	var list seaweedv1.BackupVerificationList
+	if err := cli.List(context.Background(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 0 {
+		t.Fatalf("created %d verifications with no backup to verify", len(list.Items))
+	}
+
+	if err := cli.Create(context.Background(), backupWithSchedule("c1-nightly-1", "c1", "nightly", now, seaweedv1.BackupPhaseCompleted)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.reconcileVerification(context.Background(), m, sched, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.List(context.Background(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Spec.BackupName != "c1-nightly-1" {
+		t.Errorf("verifications = %+v, want one drill of the first backup", list.Items)
+	}
+}
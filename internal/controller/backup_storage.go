@@ -21,6 +21,8 @@ import (
 	"path"
 	"strings"
 
+	corev1 "k8s.io/api/core/v1"
+
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
 )
 
@@ -37,10 +39,13 @@ const (
 	// path resolves inside the pod.
 	gcsKeyFileName = "gcs.json"
 
-	// reservedBackupFilerDir is the hidden filer subtree a snapshot Job stages
-	// its .meta.gz into when the storage is an object store, so the storage's
-	// data mirror carries it off-cluster. Restore reads it back from here.
-	reservedBackupFilerDir = "/.seaweedfs-operator/backups"
+	// reservedOperatorFilerDir is the hidden filer subtree the operator stages
+	// backup artifacts under. Metadata logs never record changes inside it.
+	reservedOperatorFilerDir = "/.seaweedfs-operator"
+
+	// defaultMetaLogSegmentMinutes is how long a metadata-log segment stays
+	// open when spec.backup.metadataLog[].segmentMinutes is unset.
+	defaultMetaLogSegmentMinutes = 10
 
 	// defaultFilerPath is the filer subtree backed up when none is given.
 	defaultFilerPath = "/"
@@ -73,21 +78,170 @@ func filerPathOrDefault(p string) string {
 }
 
 // metaRelPath is the snapshot's location relative to a storage root:
-// <cluster>/<backup>/filer.meta.gz. Used for filesystem reads/writes and as
-// the suffix under reservedBackupFilerDir for object stores.
+// <cluster>/<backup>/filer.meta.gz, under the PVC mount for filesystem
+// storages and under the storage's directory for object stores.
 func metaRelPath(cluster, backupName string) string {
 	return path.Join(cluster, backupName, "filer.meta.gz")
 }
 
-// reservedFilerMetaPath is where a snapshot Job stages its .meta.gz inside the
-// source filer for object-store storages.
-func reservedFilerMetaPath(backupName string) string {
-	return path.Join(reservedBackupFilerDir, backupName, "filer.meta.gz")
+// volumesRelDir is the directory, relative to the storage root, a cluster's
+// volumes backups share: their index and, per backup, the manifest and the
+// segments it uploaded.
+func volumesRelDir(cluster string) string {
+	return path.Join(cluster, "volumes")
+}
+
+// volumesManifestRelPath is the manifest of a volumes backup relative to the
+// storage root.
+func volumesManifestRelPath(cluster, backupName string) string {
+	return path.Join(volumesRelDir(cluster), backupName, snapshotManifestName)
+}
+
+// backupSnapshotName is the directory a backup's snapshot is stored under.
+// Backups of a cluster in another namespace are nested under their own
+// namespace so same-named backups from different namespaces stay apart.
+func backupSnapshotName(backup *seaweedv1.SeaweedBackup) string {
+	if backup.Spec.ClusterNamespace == "" || backup.Spec.ClusterNamespace == backup.Namespace {
+		return backup.Name
+	}
+	return path.Join(backup.Namespace, backup.Name)
+}
+
+// snapshotObjectKey is the backup-tool key of a snapshot at rel (see
+// metaRelPath): below the storage's directory in an object store, or rel
+// itself below the PVC mount backup-tool is rooted at for filesystem storages.
+func snapshotObjectKey(st seaweedv1.BackupStorageSpec, rel string) string {
+	if st.Type == seaweedv1.BackupStorageFilesystem {
+		return strings.TrimPrefix(rel, "/")
+	}
+	return strings.TrimPrefix(path.Join(storageBaseDirectory(st), rel), "/")
+}
+
+// objectStoreURI is the canonical location of key in a storage, in the form
+// backup-tool reports it.
+func objectStoreURI(st seaweedv1.BackupStorageSpec, key string) string {
+	switch st.Type {
+	case seaweedv1.BackupStorageFilesystem:
+		return path.Join(filesystemMountPath(st.Filesystem), key)
+	case seaweedv1.BackupStorageS3:
+		return "s3://" + st.S3.Bucket + "/" + key
+	case seaweedv1.BackupStorageGCS:
+		return "gs://" + st.GCS.Bucket + "/" + key
+	case seaweedv1.BackupStorageAzure:
+		return fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s", st.Azure.AccountName, st.Azure.Container, key)
+	case seaweedv1.BackupStorageB2:
+		return "b2://" + st.B2.Bucket + "/" + key
+	}
+	return key
+}
+
+// objectStoreArgs are the backup-tool flags selecting a storage's bucket, or
+// its PVC mount for filesystem storages.
+func objectStoreArgs(st seaweedv1.BackupStorageSpec) []string {
+	args := []string{"-storeType=" + string(st.Type)}
+	switch st.Type {
+	case seaweedv1.BackupStorageFilesystem:
+		args = append(args, "-dir="+filesystemMountPath(st.Filesystem))
+	case seaweedv1.BackupStorageS3:
+		forcePath := true
+		if st.S3.ForcePathStyle != nil {
+			forcePath = *st.S3.ForcePathStyle
+		}
+		args = append(args, "-bucket="+st.S3.Bucket, fmt.Sprintf("-forcePathStyle=%t", forcePath))
+		if st.S3.Region != "" {
+			args = append(args, "-region="+st.S3.Region)
+		}
+		if st.S3.Endpoint != "" {
+			args = append(args, "-endpoint="+st.S3.Endpoint)
+		}
+	case seaweedv1.BackupStorageGCS:
+		args = append(args, "-bucket="+st.GCS.Bucket)
+	case seaweedv1.BackupStorageAzure:
+		args = append(args, "-account="+st.Azure.AccountName, "-bucket="+st.Azure.Container)
+	case seaweedv1.BackupStorageB2:
+		args = append(args, "-bucket="+st.B2.Bucket)
+	}
+	return args
+}
+
+// objectStoreEnv exposes a storage's CredentialsSecret to backup-tool as
+// environment variables named after the secret's keys. Keys are optional for
+// s3/gcs, which fall back to ambient credentials, and required for azure/b2.
+// The encryption key, when the storage has one, is always required.
+func objectStoreEnv(st seaweedv1.BackupStorageSpec) []corev1.EnvVar {
+	var env []corev1.EnvVar
+	if st.Encryption != nil {
+		env = append(env, secretKeyEnv(st.Encryption.KeySecret, seaweedv1.BackupSecretKeyEncryptionKey, false))
+	}
+	if st.CredentialsSecret == nil || *st.CredentialsSecret == "" {
+		return env
+	}
+	var keys []string
+	optional := false
+	switch st.Type {
+	case seaweedv1.BackupStorageS3:
+		keys, optional = []string{seaweedv1.BackupSecretKeyAWSAccessKeyID, seaweedv1.BackupSecretKeyAWSSecretAccessKey}, true
+	case seaweedv1.BackupStorageGCS:
+		keys, optional = []string{seaweedv1.BackupSecretKeyGCSCredentials}, true
+	case seaweedv1.BackupStorageAzure:
+		keys = []string{seaweedv1.BackupSecretKeyAzureAccountKey}
+	case seaweedv1.BackupStorageB2:
+		keys = []string{seaweedv1.BackupSecretKeyB2AccountID, seaweedv1.BackupSecretKeyB2AppKey}
+	}
+	for _, k := range keys {
+		env = append(env, secretKeyEnv(*st.CredentialsSecret, k, optional))
+	}
+	return env
+}
+
+// secretKeyEnv is an environment variable named after, and set from, key in
+// secret.
+func secretKeyEnv(secret, key string, optional bool) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: key,
+		ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: secret},
+			Key:                  key,
+			Optional:             &optional,
+		}},
+	}
+}
+
+// metaLogRelDir is a metadata log's segment directory relative to a storage
+// root: <cluster>/metalog.
+func metaLogRelDir(cluster string) string {
+	return path.Join(cluster, "metalog")
+}
+
+// metaLogSegmentMinutes returns a metadata log's segment length, defaulted.
+func metaLogSegmentMinutes(l seaweedv1.BackupMetadataLogSpec) int32 {
+	if l.SegmentMinutes > 0 {
+		return l.SegmentMinutes
+	}
+	return defaultMetaLogSegmentMinutes
+}
+
+// metaLogFor returns the metadata log a cluster keeps on storage, if any.
+func metaLogFor(m *seaweedv1.Seaweed, storage string) (seaweedv1.BackupMetadataLogSpec, bool) {
+	if m.Spec.Backup == nil {
+		return seaweedv1.BackupMetadataLogSpec{}, false
+	}
+	for _, l := range m.Spec.Backup.MetadataLog {
+		if l.StorageName == storage {
+			return l, true
+		}
+	}
+	return seaweedv1.BackupMetadataLogSpec{}, false
 }
 
 // mirrorSinkDirectory is the destination prefix the data mirror writes file
 // content under, isolating each cluster's data within a shared storage.
 func mirrorSinkDirectory(storageName string, st seaweedv1.BackupStorageSpec, cluster string) string {
+	return path.Join(storageBaseDirectory(st), cluster, "data")
+}
+
+// storageBaseDirectory is the in-store root a storage's sinks write under.
+func storageBaseDirectory(st seaweedv1.BackupStorageSpec) string {
 	base := "/"
 	switch st.Type {
 	case seaweedv1.BackupStorageS3:
@@ -114,7 +268,22 @@ func mirrorSinkDirectory(storageName string, st seaweedv1.BackupStorageSpec, clu
 	if base == "" {
 		base = "/"
 	}
-	return path.Join(base, cluster, "data")
+	return base
+}
+
+// sinkName is the name weed's replication sink for a storage type reports;
+// filer.backup keys its checkpoint by it together with the sink directory.
+func sinkName(t seaweedv1.BackupStorageType) string {
+	switch t {
+	case seaweedv1.BackupStorageGCS:
+		return "google_cloud_storage"
+	case seaweedv1.BackupStorageB2:
+		return "backblaze"
+	case seaweedv1.BackupStorageFilesystem:
+		return "local"
+	default:
+		return string(t)
+	}
 }
 
 // tomlString renders a value as a quoted TOML basic string.
@@ -129,13 +298,17 @@ func tomlString(s string) string {
 // storage's CredentialsSecret). For object stores without a secret, the
 // credential fields are left empty so weed falls back to its ambient chain.
 func renderReplicationToml(storageName string, st seaweedv1.BackupStorageSpec, cluster string, creds map[string][]byte) (string, error) {
+	return renderSinkToml(storageName, st, mirrorSinkDirectory(storageName, st, cluster), creds)
+}
+
+// renderSinkToml emits the [sink.*] section for a storage writing under dir.
+func renderSinkToml(storageName string, st seaweedv1.BackupStorageSpec, dir string, creds map[string][]byte) (string, error) {
 	get := func(k string) string {
 		if creds == nil {
 			return ""
 		}
 		return string(creds[k])
 	}
-	dir := mirrorSinkDirectory(storageName, st, cluster)
 
 	var b strings.Builder
 	switch st.Type {
@@ -20,9 +20,11 @@ import (
 	"strings"
 	"testing"
 
+	batchv1 "k8s.io/api/batch/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/internal/backuptool"
 )
 
 func TestRenderReplicationTomlS3(t *testing.T) {
@@ -178,8 +180,31 @@ func TestMetaPaths(t *testing.T) {
 	if got := metaRelPath("c1", "bk1"); got != "c1/bk1/filer.meta.gz" {
 		t.Errorf("metaRelPath = %q", got)
 	}
-	if got := reservedFilerMetaPath("bk1"); got != "/.seaweedfs-operator/backups/bk1/filer.meta.gz" {
-		t.Errorf("reservedFilerMetaPath = %q", got)
+	st := seaweedv1.BackupStorageSpec{
+		Type: seaweedv1.BackupStorageS3,
+		S3:   &seaweedv1.S3BackupStore{Bucket: "b", Directory: "/backups"},
+	}
+	key := snapshotObjectKey(st, metaRelPath("c1", "bk1"))
+	if key != "backups/c1/bk1/filer.meta.gz" {
+		t.Errorf("snapshotObjectKey = %q", key)
+	}
+	if got := objectStoreURI(st, key); got != "s3://b/backups/c1/bk1/filer.meta.gz" {
+		t.Errorf("objectStoreURI = %q", got)
+	}
+
+	fs := seaweedv1.BackupStorageSpec{
+		Type:       seaweedv1.BackupStorageFilesystem,
+		Filesystem: &seaweedv1.FilesystemBackupStore{ExistingClaim: "pvc", MountPath: "/mnt/bk"},
+	}
+	key = snapshotObjectKey(fs, metaRelPath("c1", "bk1"))
+	if key != "c1/bk1/filer.meta.gz" {
+		t.Errorf("filesystem snapshotObjectKey = %q", key)
+	}
+	if got := objectStoreURI(fs, key); got != "/mnt/bk/c1/bk1/filer.meta.gz" {
+		t.Errorf("filesystem objectStoreURI = %q", got)
+	}
+	if got := volumesManifestRelPath("c1", "ns2/bk1"); got != "c1/volumes/ns2/bk1/manifest.json" {
+		t.Errorf("volumesManifestRelPath = %q", got)
 	}
 }
 
@@ -214,9 +239,9 @@ func TestSnapshotScriptFilesystem(t *testing.T) {
 	}
 	for _, want := range []string{
 		"set -euo pipefail",
-		"fs.meta.save -o /backup/c1/bk1/filer.meta.gz /",
+		"fs.meta.save -o /scratch/filer.meta.gz /",
 		"weed", "shell", "-master=", "-filer=",
-		"test -s /backup/c1/bk1/filer.meta.gz",
+		"test -s /scratch/filer.meta.gz",
 	} {
 		if !strings.Contains(script, want) {
 			t.Errorf("filesystem snapshot script missing %q:\n%s", want, script)
@@ -231,30 +256,281 @@ func TestSnapshotScriptObjectStore(t *testing.T) {
 		S3:   &seaweedv1.S3BackupStore{Bucket: "b", Directory: "/"},
 	}
 	script, dest := snapshotScript(m, st, "c1", "bk1", "/")
-	if dest != "/.seaweedfs-operator/backups/bk1/filer.meta.gz" {
+	if dest != "s3://b/c1/bk1/filer.meta.gz" {
 		t.Errorf("destination = %q", dest)
 	}
 	for _, want := range []string{
 		"fs.meta.save -o /scratch/filer.meta.gz /",
-		"filer.copy /scratch/filer.meta.gz http://c1-filer.ns1:8888/.seaweedfs-operator/backups/bk1/",
+		"test -s /scratch/filer.meta.gz",
 	} {
 		if !strings.Contains(script, want) {
 			t.Errorf("object-store snapshot script missing %q:\n%s", want, script)
 		}
 	}
+	if strings.Contains(script, "filer.copy") {
+		t.Errorf("object-store snapshot should not stage through the filer:\n%s", script)
+	}
+}
+
+func TestSnapshotJobObjectStoreUploads(t *testing.T) {
+	m := testSeaweedForBackup()
+	secret := "creds"
+	st := seaweedv1.BackupStorageSpec{
+		Type:              seaweedv1.BackupStorageS3,
+		CredentialsSecret: &secret,
+		S3:                &seaweedv1.S3BackupStore{Bucket: "b", Region: "eu-west-1", Directory: "/backups"},
+	}
+	backup := &seaweedv1.SeaweedBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "ns1"},
+		Spec:       seaweedv1.SeaweedBackupSpec{ClusterName: "c1", StorageName: "s3", FilerPath: "/"},
+	}
+	job, dest := buildSnapshotJob(m, "bk1-bkp", backup, st, "operator:test")
+	if dest != "s3://b/backups/c1/bk1/filer.meta.gz" {
+		t.Errorf("destination = %q", dest)
+	}
+	pod := job.Spec.Template.Spec
+	if len(pod.InitContainers) != 1 || len(pod.Containers) != 1 {
+		t.Fatalf("want weed save as init container and upload as main container, got %d/%d", len(pod.InitContainers), len(pod.Containers))
+	}
+	upload := pod.Containers[0]
+	if upload.Name != snapshotUploadContainer || upload.Image != "operator:test" {
+		t.Errorf("upload container = %s (%s)", upload.Name, upload.Image)
+	}
+	cmd := strings.Join(upload.Command, " ")
+	if !containsAll(cmd, "backup-tool snapshot-upload", "-storeType=s3", "-bucket=b", "-region=eu-west-1",
+		"-file=/scratch/filer.meta.gz", "-key=backups/c1/bk1/filer.meta.gz") {
+		t.Errorf("upload command wrong: %s", cmd)
+	}
+	if len(upload.Env) != 2 || upload.Env[0].ValueFrom.SecretKeyRef.Name != "creds" {
+		t.Errorf("upload env does not reference the credentials secret: %+v", upload.Env)
+	}
+	if strings.Contains(cmd, "-encrypt") {
+		t.Errorf("unencrypted storage uploads with -encrypt: %s", cmd)
+	}
+}
+
+func TestSnapshotJobFilesystemEncrypts(t *testing.T) {
+	m := testSeaweedForBackup()
+	st := seaweedv1.BackupStorageSpec{
+		Type:       seaweedv1.BackupStorageFilesystem,
+		Filesystem: &seaweedv1.FilesystemBackupStore{ExistingClaim: "pvc"},
+		Encryption: &seaweedv1.BackupEncryptionSpec{KeySecret: "bk-key"},
+	}
+	backup := &seaweedv1.SeaweedBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "ns1"},
+		Spec:       seaweedv1.SeaweedBackupSpec{ClusterName: "c1", StorageName: "pvc", FilerPath: "/"},
+	}
+	job, dest := buildSnapshotJob(m, "bk1-bkp", backup, st, "operator:test")
+	if dest != "/backup/c1/bk1/filer.meta.gz" {
+		t.Errorf("destination = %q", dest)
+	}
+	pod := job.Spec.Template.Spec
+	if len(pod.InitContainers) != 1 || len(pod.Containers) != 1 {
+		t.Fatalf("want weed save as init container and upload as main container, got %d/%d", len(pod.InitContainers), len(pod.Containers))
+	}
+	upload := pod.Containers[0]
+	cmd := strings.Join(upload.Command, " ")
+	if !containsAll(cmd, "backup-tool snapshot-upload", "-storeType=filesystem", "-dir=/backup",
+		"-key=c1/bk1/filer.meta.gz", "-encrypt") {
+		t.Errorf("upload command wrong: %s", cmd)
+	}
+	if len(upload.Env) != 1 || upload.Env[0].Name != seaweedv1.BackupSecretKeyEncryptionKey ||
+		upload.Env[0].ValueFrom.SecretKeyRef.Name != "bk-key" || *upload.Env[0].ValueFrom.SecretKeyRef.Optional {
+		t.Errorf("upload env does not require the encryption key: %+v", upload.Env)
+	}
+	if !hasVolume(pod.Volumes, backupStorageVolumeName) {
+		t.Error("snapshot pod does not mount the backup PVC")
+	}
 }
 
 func TestRestoreScript(t *testing.T) {
 	m := testSeaweedForBackup()
-	fs := restoreScript(m, "/backup/c1/bk1/filer.meta.gz", "", "/")
-	if !strings.Contains(fs, "fs.meta.load /backup/c1/bk1/filer.meta.gz") {
-		t.Errorf("filesystem restore script wrong:\n%s", fs)
+	script := restoreScript(m, "/scratch/restore.meta.gz", "/")
+	for _, want := range []string{
+		"test -s /scratch/restore.meta.gz",
+		"fs.meta.load /scratch/restore.meta.gz",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("restore script missing %q:\n%s", want, script)
+		}
+	}
+}
+
+func TestRestoreJobObjectStoreDownloads(t *testing.T) {
+	m := testSeaweedForBackup()
+	st := seaweedv1.BackupStorageSpec{
+		Type: seaweedv1.BackupStorageB2,
+		B2:   &seaweedv1.B2BackupStore{Bucket: "bkt", Directory: "/"},
+	}
+	restore := &seaweedv1.SeaweedRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "rs1", Namespace: "ns1"},
+		Spec:       seaweedv1.SeaweedRestoreSpec{ClusterName: "c1", FilerPath: "/"},
+	}
+	job := buildRestoreJob(m, "rs1-rst", restore, st, snapshotRef{rel: "c1/bk1/filer.meta.gz", sha256: "abcd"}, "operator:test")
+	pod := job.Spec.Template.Spec
+	if len(pod.InitContainers) != 1 || len(pod.Containers) != 1 {
+		t.Fatalf("want download as init container and load as main container, got %d/%d", len(pod.InitContainers), len(pod.Containers))
+	}
+	download := strings.Join(pod.InitContainers[0].Command, " ")
+	if !containsAll(download, "backup-tool snapshot-download", "-storeType=b2", "-bucket=bkt",
+		"-key=c1/bk1/filer.meta.gz", "-o=/scratch/restore.meta.gz", "-sha256=abcd") {
+		t.Errorf("download command wrong: %s", download)
+	}
+	rules := job.Spec.PodFailurePolicy
+	if rules == nil || len(rules.Rules) != 1 || rules.Rules[0].Action != batchv1.PodFailurePolicyActionFailJob ||
+		*rules.Rules[0].OnExitCodes.ContainerName != snapshotDownloadContainer ||
+		rules.Rules[0].OnExitCodes.Values[0] != backuptool.IntegrityExitCode {
+		t.Errorf("integrity failures do not fail the job at once: %+v", rules)
+	}
+	load := pod.Containers[0].Command
+	if !containsAll(load[len(load)-1], "fs.meta.load /scratch/restore.meta.gz") {
+		t.Errorf("main container does not load the downloaded snapshot:\n%s", load[len(load)-1])
+	}
+}
+
+func TestVolumeBackupJobIsIncremental(t *testing.T) {
+	m := testSeaweedForBackup()
+	st := seaweedv1.BackupStorageSpec{
+		Type:       seaweedv1.BackupStorageS3,
+		S3:         &seaweedv1.S3BackupStore{Bucket: "b", Directory: "/backups"},
+		Encryption: &seaweedv1.BackupEncryptionSpec{KeySecret: "bk-key"},
+	}
+	backup := &seaweedv1.SeaweedBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "bk2", Namespace: "ns1"},
+		Spec: seaweedv1.SeaweedBackupSpec{ClusterName: "c1", StorageName: "s3",
+			Target: seaweedv1.BackupTargetVolumes, Collections: []string{"photos", "logs"}},
+	}
+	job, dest := buildVolumeBackupJob(m, "bk2-bkp", backup, st, "bk1", "operator:test")
+	if dest != "s3://b/backups/c1/volumes/bk2/manifest.json" {
+		t.Errorf("destination = %q", dest)
+	}
+	pod := job.Spec.Template.Spec
+	if len(pod.InitContainers) != 0 || len(pod.Containers) != 1 || pod.Containers[0].Name != snapshotUploadContainer {
+		t.Fatalf("want a single upload container, got %d/%d", len(pod.InitContainers), len(pod.Containers))
+	}
+	cmd := strings.Join(pod.Containers[0].Command, " ")
+	if !containsAll(cmd, "backup-tool volume-backup", "-storeType=s3", "-prefix=backups/c1/volumes",
+		"-name=bk2", "-previous=bk1", "-collection=photos", "-collection=logs", "-scratch=/scratch", "-encrypt") {
+		t.Errorf("volume-backup command wrong: %s", cmd)
+	}
+
+	job, _ = buildVolumeBackupJob(m, "bk1-bkp", backup, seaweedv1.BackupStorageSpec{
+		Type:       seaweedv1.BackupStorageFilesystem,
+		Filesystem: &seaweedv1.FilesystemBackupStore{ExistingClaim: "pvc"},
+	}, "", "operator:test")
+	cmd = strings.Join(job.Spec.Template.Spec.Containers[0].Command, " ")
+	if strings.Contains(cmd, "-previous") || !strings.Contains(cmd, "-prefix=c1/volumes") {
+		t.Errorf("first volumes backup command wrong: %s", cmd)
+	}
+}
+
+func TestVolumeRestoreJobHandsVolumesToTheCluster(t *testing.T) {
+	m := testSeaweedForBackup()
+	st := seaweedv1.BackupStorageSpec{
+		Type: seaweedv1.BackupStorageGCS,
+		GCS:  &seaweedv1.GCSBackupStore{Bucket: "g", Directory: "/"},
+	}
+	restore := &seaweedv1.SeaweedRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "rs1", Namespace: "ns1"},
+		Spec:       seaweedv1.SeaweedRestoreSpec{ClusterName: "c1", BackupName: "bk2", Mode: seaweedv1.RestoreModeVolumes},
+	}
+	job := buildVolumeRestoreJob(m, "rs1-rst", restore, st,
+		snapshotRef{rel: volumesManifestRelPath("c1", "bk2"), sha256: "abcd"}, "operator:test")
+	pod := job.Spec.Template.Spec
+	if len(pod.InitContainers) != 1 || pod.InitContainers[0].Name != snapshotDownloadContainer {
+		t.Fatalf("want volume-restore as the download init container, got %+v", pod.InitContainers)
+	}
+	download := strings.Join(pod.InitContainers[0].Command, " ")
+	if !containsAll(download, "backup-tool volume-restore", "-storeType=gcs",
+		"-manifest=c1/volumes/bk2/manifest.json", "-sha256=abcd", "-o=/scratch/volumes") {
+		t.Errorf("download command wrong: %s", download)
+	}
+	if job.Spec.PodFailurePolicy == nil || *job.Spec.PodFailurePolicy.Rules[0].OnExitCodes.ContainerName != snapshotDownloadContainer {
+		t.Errorf("integrity failures do not fail the job at once: %+v", job.Spec.PodFailurePolicy)
+	}
+	main := pod.Containers[0]
+	if len(main.Env) != 1 || main.Env[0].Name != "POD_IP" || len(main.Ports) != 2 {
+		t.Errorf("volume server container lacks its address: env %+v ports %+v", main.Env, main.Ports)
+	}
+	script := main.Command[len(main.Command)-1]
+	if !containsAll(script, "volume -dir=/scratch/volumes", `-ip="$POD_IP"`, "-port=8080", "-port.grpc=18080",
+		"volumeServer.evacuate -node %s -apply") {
+		t.Errorf("restore script does not serve and evacuate the volumes:\n%s", script)
+	}
+}
+
+func TestVolumeBackupCleanupJobKeepsSharedSegments(t *testing.T) {
+	m := testSeaweedForBackup()
+	st := seaweedv1.BackupStorageSpec{
+		Type:       seaweedv1.BackupStorageFilesystem,
+		Filesystem: &seaweedv1.FilesystemBackupStore{ExistingClaim: "pvc"},
+	}
+	backup := &seaweedv1.SeaweedBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "ns2"},
+		Spec: seaweedv1.SeaweedBackupSpec{ClusterName: "c1", ClusterNamespace: "ns1", StorageName: "pvc",
+			Target: seaweedv1.BackupTargetVolumes},
+	}
+	job := buildArtifactCleanupJob(m, "bk1-del", backup, st, "operator:test")
+	cmd := strings.Join(job.Spec.Template.Spec.Containers[0].Command, " ")
+	if !containsAll(cmd, "backup-tool volume-delete", "-storeType=filesystem", "-prefix=c1/volumes", "-name=ns2/bk1") {
+		t.Errorf("cleanup command wrong: %s", cmd)
+	}
+}
+
+func TestMetaLogDeployment(t *testing.T) {
+	m := testSeaweedForBackup()
+	l := seaweedv1.BackupMetadataLogSpec{StorageName: "s3", FilerPath: "/buckets", SegmentMinutes: 5}
+	secret := "s3-creds"
+	st := seaweedv1.BackupStorageSpec{
+		Type:              seaweedv1.BackupStorageS3,
+		S3:                &seaweedv1.S3BackupStore{Bucket: "b", Directory: "/"},
+		CredentialsSecret: &secret,
+	}
+	dep := buildMetaLogDeployment(m, metaLogDeploymentName("c1", "s3"), "operator:test", l, st)
+	if dep.Name != "c1-backup-metalog-s3" {
+		t.Errorf("name = %q", dep.Name)
+	}
+	c := dep.Spec.Template.Spec.Containers[0]
+	if c.Image != "operator:test" {
+		t.Errorf("image = %q", c.Image)
+	}
+	cmd := strings.Join(c.Command, " ")
+	for _, want := range []string{
+		"/manager backup-tool metalog-capture",
+		"-filer=c1-filer.ns1:8888",
+		"-pathPrefix=/buckets",
+		"-excludePrefix=/.seaweedfs-operator",
+		"-segment=5m",
+		"-storeType=s3",
+		"-bucket=b",
+		"-prefix=c1/metalog",
+		"-scratchDir=/scratch",
+	} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("object-store capture command missing %q: %s", want, cmd)
+		}
+	}
+	if c.SecurityContext != nil {
+		t.Error("object-store capture needs no root")
+	}
+	if len(c.Env) != 2 || c.Env[0].Name != seaweedv1.BackupSecretKeyAWSAccessKeyID {
+		t.Errorf("object-store capture does not get the storage credentials: %+v", c.Env)
+	}
+
+	fs := seaweedv1.BackupStorageSpec{
+		Type:       seaweedv1.BackupStorageFilesystem,
+		Filesystem: &seaweedv1.FilesystemBackupStore{ExistingClaim: "pvc", MountPath: "/backup"},
+	}
+	dep = buildMetaLogDeployment(m, metaLogDeploymentName("c1", "pvc"), "operator:test", seaweedv1.BackupMetadataLogSpec{StorageName: "pvc"}, fs)
+	c = dep.Spec.Template.Spec.Containers[0]
+	cmd = strings.Join(c.Command, " ")
+	if !containsAll(cmd, "-storeType=filesystem", "-dir=/backup", "-prefix=c1/metalog", "-segment=10m") {
+		t.Errorf("filesystem capture command wrong: %s", cmd)
 	}
-	obj := restoreScript(m, "", "http://c1-filer.ns1:8888/.seaweedfs-operator/backups/bk1/filer.meta.gz", "/")
-	if !strings.Contains(obj, "filer.cat -o /scratch/restore.meta.gz http://c1-filer.ns1:8888/") {
-		t.Errorf("object-store restore script wrong:\n%s", obj)
+	if c.SecurityContext == nil || c.SecurityContext.RunAsUser == nil || *c.SecurityContext.RunAsUser != 0 {
+		t.Error("filesystem capture must run as root to write the shared backup PVC")
 	}
-	if !strings.Contains(obj, "fs.meta.load /scratch/restore.meta.gz") {
-		t.Errorf("object-store restore missing load:\n%s", obj)
+	if !hasVolume(dep.Spec.Template.Spec.Volumes, backupStorageVolumeName) {
+		t.Error("filesystem capture does not mount the backup PVC")
 	}
 }
@@ -0,0 +1,112 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/internal/backuptool"
+)
+
+// Backup steps the weed CLI has no primitive for (metadata-log capture and
+// replay) run the operator's own image as `/manager backup-tool ...`.
+const (
+	// BackupToolImageEnv overrides the image backup-tool pods run. Without it
+	// the operator uses the image of its own pod.
+	BackupToolImageEnv = "BACKUP_TOOL_IMAGE"
+
+	// backupToolBinary is the manager binary inside the operator image.
+	backupToolBinary = "/manager"
+)
+
+// ResolveBackupToolImage returns the image backup-tool pods run:
+// BACKUP_TOOL_IMAGE when set, else the image of the operator's own manager
+// container, found through the POD_NAMESPACE / POD_NAME downward-API env. It
+// returns "" when neither is available (e.g. running outside the cluster);
+// features that need the tool then report it as unavailable.
+func ResolveBackupToolImage(ctx context.Context, reader client.Reader) (string, error) {
+	if img := os.Getenv(BackupToolImageEnv); img != "" {
+		return img, nil
+	}
+	namespace, name := os.Getenv("POD_NAMESPACE"), os.Getenv("POD_NAME")
+	if namespace == "" || name == "" {
+		return "", nil
+	}
+	var pod corev1.Pod
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &pod); err != nil {
+		return "", err
+	}
+	for _, c := range pod.Spec.Containers {
+		if len(c.Command) > 0 && c.Command[0] == backupToolBinary {
+			return c.Image, nil
+		}
+	}
+	if len(pod.Spec.Containers) > 0 {
+		return pod.Spec.Containers[0].Image, nil
+	}
+	return "", nil
+}
+
+// backupToolContainer returns a container running one backup-tool
+// subcommand, with the scratch dir, (for filesystem storages) the backup PVC
+// and, for subcommands that dial the filer on TLS clusters, the gRPC client
+// certificates mounted. The volumes it mounts are returned for the caller's
+// pod spec.
+func backupToolContainer(m *seaweedv1.Seaweed, image, name, subcommand string, args []string, st seaweedv1.BackupStorageSpec, dialsFiler bool) (corev1.Container, []corev1.Volume) {
+	volumes := []corev1.Volume{{
+		Name:         "scratch",
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	}}
+	mounts := []corev1.VolumeMount{{Name: "scratch", MountPath: backupScratchDir}}
+
+	var securityContext *corev1.SecurityContext
+	if st.Type == seaweedv1.BackupStorageFilesystem && st.Filesystem != nil {
+		vol, mount := filesystemPVCVolume(st.Filesystem)
+		volumes = append(volumes, vol)
+		mounts = append(mounts, mount)
+		// The operator image runs as a non-root user, but the weed pods that
+		// share the backup PVC write it as root.
+		root := int64(0)
+		securityContext = &corev1.SecurityContext{RunAsUser: &root}
+	}
+	if dialsFiler && tlsEffective(m) {
+		volumes = append(volumes, corev1.Volume{
+			Name: tlsVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: TLSServerSecretName(m)},
+			},
+		})
+		mounts = append(mounts, corev1.VolumeMount{Name: tlsVolumeName, ReadOnly: true, MountPath: tlsMountPath})
+		args = append(args, "-tlsDir="+tlsMountPath)
+	}
+
+	command := append([]string{backupToolBinary, backuptool.Command, subcommand}, args...)
+	return corev1.Container{
+		Name:            name,
+		Image:           image,
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		Command:         command,
+		VolumeMounts:    mounts,
+		SecurityContext: securityContext,
+	}, volumes
+}
@@ -0,0 +1,637 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// VolumeSnapshots go through unstructured.Unstructured, as cert-manager
+// objects do (see controller_tls.go): the external-snapshotter types are not
+// a dependency, and their CRDs need not be installed unless volumeSnapshots
+// backups are used.
+const (
+	volumeSnapshotGroup = "snapshot.storage.k8s.io"
+	volumeSnapshotKind  = "VolumeSnapshot"
+)
+
+var (
+	volumeSnapshotGVK      = schema.GroupVersionKind{Group: volumeSnapshotGroup, Version: "v1", Kind: volumeSnapshotKind}
+	volumeSnapshotClassGVK = schema.GroupVersionKind{Group: volumeSnapshotGroup, Version: "v1", Kind: "VolumeSnapshotClass"}
+)
+
+const (
+	// volumeFreezeTimeout bounds how long a volumeSnapshots backup holds the
+	// cluster's volumes read-only waiting for the CSI driver to cut its
+	// snapshots.
+	volumeFreezeTimeout = 5 * time.Minute
+	// volumeFreezePoll is how often a backup holding the volumes read-only
+	// checks its snapshots; VolumeSnapshots are not watched.
+	volumeFreezePoll = 2 * time.Second
+)
+
+// unsupportedClaimError reports a claim a volumeSnapshots backup cannot
+// capture in a form a restore could recreate: one a StatefulSet mounts
+// directly instead of through a claim template.
+type unsupportedClaimError struct {
+	statefulSet, claim string
+}
+
+func (e *unsupportedClaimError) Error() string {
+	return "statefulset " + e.statefulSet + " mounts claim " + e.claim +
+		" outside its claim templates; only claim templates can be restored from snapshots"
+}
+
+// reconcileVolumeSnapshots drives a backup with target volumeSnapshots: it
+// holds the cluster's volumes read-only, takes a VolumeSnapshot of every claim
+// of the cluster's StatefulSets, makes the volumes writable again once the
+// snapshots are cut and completes when all of them are ready to use.
+func (r *SeaweedBackupReconciler) reconcileVolumeSnapshots(ctx context.Context, backup *seaweedv1.SeaweedBackup, cluster *seaweedv1.Seaweed) (ctrl.Result, error) {
+	if backup.Status.Phase == seaweedv1.BackupPhaseRunning {
+		return r.trackVolumeSnapshots(ctx, backup, cluster)
+	}
+
+	claims, waiting, err := r.clusterClaims(ctx, cluster)
+	var unsupported *unsupportedClaimError
+	switch {
+	case errors.As(err, &unsupported):
+		return r.volumeSnapshotsFailed(ctx, backup, cluster, "UnsupportedClaims", err.Error())
+	case err != nil:
+		return ctrl.Result{}, err
+	case waiting != "":
+		return r.pending(ctx, backup, "ClaimNotReady", waiting)
+	case len(claims) == 0:
+		return r.volumeSnapshotsFailed(ctx, backup, cluster, "NoClaims",
+			"cluster "+cluster.Name+" has no persistent volume claims to snapshot")
+	}
+
+	// Nothing is quiesced or frozen for snapshots that cannot be created.
+	reason, msg, err := r.volumeSnapshotsUnavailable(ctx, backup, cluster.Namespace)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if reason != "" {
+		return r.volumeSnapshotsFailed(ctx, backup, cluster, reason, msg)
+	}
+
+	if err := r.runPreHooks(ctx, backup, cluster); err != nil {
+		return ctrl.Result{}, err
+	}
+	if c := meta.FindStatusCondition(backup.Status.Conditions, seaweedv1.BackupConditionPreHooks); c != nil && c.Status == metav1.ConditionFalse {
+		return r.preHooksFailed(ctx, backup, cluster, c.Message)
+	}
+
+	// From here on a failure fails the backup, which makes the volumes
+	// writable again, rather than retrying with them held read-only.
+	if err := r.freezeVolumes(ctx, backup, cluster); err != nil {
+		r.Recorder.Event(backup, "Warning", "VolumesFreezeFailed", err.Error())
+		return r.volumeSnapshotsFailed(ctx, backup, cluster, "FreezeFailed", "holding the volumes read-only failed: "+err.Error())
+	}
+
+	clusterNS := cluster.Namespace
+	for i := range claims {
+		claims[i].Name = volumeSnapshotName(backup, clusterNS, claims[i].ClaimName)
+	}
+	// Recorded first, so a failure deletes the snapshots already created.
+	backup.Status.VolumeSnapshots = claims
+	for i := range claims {
+		snap := buildVolumeSnapshot(backup, cluster, claims[i])
+		if err := r.Create(ctx, snap); err != nil && !apierrors.IsAlreadyExists(err) {
+			return r.volumeSnapshotsFailed(ctx, backup, cluster, "SnapshotCreateFailed",
+				"creating volume snapshot "+snap.GetName()+" of claim "+claims[i].ClaimName+" failed: "+err.Error())
+		}
+	}
+	r.Log.Info("created volume snapshots", "seaweedbackup", client.ObjectKeyFromObject(backup), "namespace", clusterNS, "count", len(claims))
+
+	now := metav1.Now()
+	backup.Status.Phase = seaweedv1.BackupPhaseRunning
+	backup.Status.ClusterSpec = cluster.Spec.DeepCopy()
+	backup.Status.StartTime = &now
+	backup.Status.ObservedGeneration = backup.Generation
+	meta.SetStatusCondition(&backup.Status.Conditions, metav1.Condition{
+		Type: seaweedv1.BackupConditionClusterReachable, Status: metav1.ConditionTrue,
+		ObservedGeneration: backup.Generation, Reason: "Reachable",
+		Message: fmt.Sprintf("%d volume snapshots created", len(claims)),
+	})
+	if err := r.Status().Update(ctx, backup); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: volumeFreezePoll}, nil
+}
+
+// trackVolumeSnapshots reflects the state of a running backup's snapshots:
+// the volumes are released once every snapshot is cut, and the backup
+// completes once every snapshot is ready to use. A snapshot that fails or
+// disappears, or is not cut within volumeFreezeTimeout, fails the backup.
+func (r *SeaweedBackupReconciler) trackVolumeSnapshots(ctx context.Context, backup *seaweedv1.SeaweedBackup, cluster *seaweedv1.Seaweed) (ctrl.Result, error) {
+	cut, ready := true, true
+	for i := range backup.Status.VolumeSnapshots {
+		s := &backup.Status.VolumeSnapshots[i]
+		snap := &unstructured.Unstructured{}
+		snap.SetGroupVersionKind(volumeSnapshotGVK)
+		if err := r.Get(ctx, types.NamespacedName{Namespace: cluster.Namespace, Name: s.Name}, snap); err != nil {
+			if apierrors.IsNotFound(err) {
+				return r.volumeSnapshotsFailed(ctx, backup, cluster, "SnapshotMissing", "volume snapshot "+s.Name+" disappeared")
+			}
+			return ctrl.Result{}, err
+		}
+		if msg, _, _ := unstructured.NestedString(snap.Object, "status", "error", "message"); msg != "" {
+			return r.volumeSnapshotsFailed(ctx, backup, cluster, "SnapshotFailed", "volume snapshot "+s.Name+" of claim "+s.ClaimName+" failed: "+msg)
+		}
+		if created, _, _ := unstructured.NestedString(snap.Object, "status", "creationTime"); created == "" {
+			cut = false
+		}
+		s.ReadyToUse, _, _ = unstructured.NestedBool(snap.Object, "status", "readyToUse")
+		ready = ready && s.ReadyToUse
+		if size, _, _ := unstructured.NestedString(snap.Object, "status", "restoreSize"); size != "" {
+			if q, err := resource.ParseQuantity(size); err == nil {
+				s.RestoreSize = &q
+			}
+		}
+	}
+
+	frozen := volumesFrozen(backup)
+	if frozen && cut {
+		if err := r.releaseFrozenVolumes(ctx, backup, cluster); err != nil {
+			return ctrl.Result{}, err
+		}
+		frozen = false
+	}
+	if frozen && backup.Status.StartTime != nil && time.Since(backup.Status.StartTime.Time) > volumeFreezeTimeout {
+		return r.volumeSnapshotsFailed(ctx, backup, cluster, "SnapshotTimeout",
+			"volume snapshots were not cut within "+volumeFreezeTimeout.String()+"; the volumes were made writable again")
+	}
+	if !ready {
+		if err := r.Status().Update(ctx, backup); err != nil {
+			return ctrl.Result{}, err
+		}
+		if frozen {
+			return ctrl.Result{RequeueAfter: volumeFreezePoll}, nil
+		}
+		return ctrl.Result{RequeueAfter: backupRequeue}, nil
+	}
+
+	postFailure, err := r.finishHooks(ctx, backup, cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	now := metav1.Now()
+	backup.Status.CompletionTime = &now
+	what := fmt.Sprintf("%d volume snapshots", len(backup.Status.VolumeSnapshots))
+	if postFailure != "" {
+		backup.Status.Phase = seaweedv1.BackupPhaseFailed
+		meta.SetStatusCondition(&backup.Status.Conditions, metav1.Condition{
+			Type: seaweedv1.BackupConditionComplete, Status: metav1.ConditionFalse,
+			ObservedGeneration: backup.Generation, Reason: "PostHookFailed", Message: what + " taken, but a post hook failed: " + postFailure,
+		})
+		r.Recorder.Event(backup, "Warning", "BackupFailed", "post hook failed: "+postFailure)
+		return ctrl.Result{}, r.Status().Update(ctx, backup)
+	}
+	backup.Status.Phase = seaweedv1.BackupPhaseCompleted
+	meta.SetStatusCondition(&backup.Status.Conditions, metav1.Condition{
+		Type: seaweedv1.BackupConditionComplete, Status: metav1.ConditionTrue,
+		ObservedGeneration: backup.Generation, Reason: "SnapshotComplete", Message: what + " ready to use",
+	})
+	r.Recorder.Event(backup, "Normal", "BackupCompleted", what+" ready to use")
+	return ctrl.Result{}, r.Status().Update(ctx, backup)
+}
+
+// volumeSnapshotsUnavailable reports why backup's VolumeSnapshots cannot be
+// created in clusterNS, as a condition reason and message: the snapshot API
+// is not installed, or the VolumeSnapshotClass the backup names does not
+// exist. Both are checked before anything is quiesced.
+func (r *SeaweedBackupReconciler) volumeSnapshotsUnavailable(ctx context.Context, backup *seaweedv1.SeaweedBackup, clusterNS string) (string, string, error) {
+	const apiMissing = "the VolumeSnapshot API (" + volumeSnapshotGroup + "/v1) is not installed; install the CSI external-snapshotter CRDs"
+	snaps := &unstructured.UnstructuredList{}
+	snaps.SetGroupVersionKind(volumeSnapshotGVK.GroupVersion().WithKind(volumeSnapshotKind + "List"))
+	if err := r.List(ctx, snaps, client.InNamespace(clusterNS), client.Limit(1)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return "SnapshotAPIMissing", apiMissing, nil
+		}
+		return "", "", err
+	}
+	name := backup.Spec.VolumeSnapshotClassName
+	if name == "" {
+		return "", "", nil
+	}
+	class := &unstructured.Unstructured{}
+	class.SetGroupVersionKind(volumeSnapshotClassGVK)
+	if err := r.Get(ctx, types.NamespacedName{Name: name}, class); err != nil {
+		switch {
+		case meta.IsNoMatchError(err):
+			return "SnapshotAPIMissing", apiMissing, nil
+		case apierrors.IsNotFound(err):
+			return "SnapshotClassNotFound", "VolumeSnapshotClass " + name + " does not exist", nil
+		}
+		return "", "", err
+	}
+	return "", "", nil
+}
+
+// volumeSnapshotsFailed ends a volumeSnapshots backup: the volumes are made
+// writable again, the post hooks run when the pre hooks did, and the
+// snapshots already taken are deleted, as no restore can use an incomplete
+// set.
+func (r *SeaweedBackupReconciler) volumeSnapshotsFailed(ctx context.Context, backup *seaweedv1.SeaweedBackup, cluster *seaweedv1.Seaweed, reason, msg string) (ctrl.Result, error) {
+	if meta.FindStatusCondition(backup.Status.Conditions, seaweedv1.BackupConditionPreHooks) != nil {
+		postFailure, err := r.finishHooks(ctx, backup, cluster)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if postFailure != "" {
+			msg += "; post hooks failed: " + postFailure
+		}
+	} else if err := r.releaseFrozenVolumes(ctx, backup, cluster); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.deleteVolumeSnapshots(ctx, backup, cluster.Namespace); err != nil {
+		return ctrl.Result{}, err
+	}
+	now := metav1.Now()
+	backup.Status.Phase = seaweedv1.BackupPhaseFailed
+	backup.Status.CompletionTime = &now
+	backup.Status.ObservedGeneration = backup.Generation
+	meta.SetStatusCondition(&backup.Status.Conditions, metav1.Condition{
+		Type: seaweedv1.BackupConditionComplete, Status: metav1.ConditionFalse,
+		ObservedGeneration: backup.Generation, Reason: reason, Message: msg,
+	})
+	r.Recorder.Event(backup, "Warning", "BackupFailed", msg)
+	return ctrl.Result{}, r.Status().Update(ctx, backup)
+}
+
+// clusterClaims lists the claims of the StatefulSets cluster controls, one
+// per claim template and replica, sorted by StatefulSet, template and
+// ordinal. It returns why the backup has to wait when a claim does not exist
+// or is not bound yet.
+func (r *SeaweedBackupReconciler) clusterClaims(ctx context.Context, cluster *seaweedv1.Seaweed) ([]seaweedv1.BackupVolumeSnapshot, string, error) {
+	var sets appsv1.StatefulSetList
+	if err := r.List(ctx, &sets, client.InNamespace(cluster.Namespace)); err != nil {
+		return nil, "", err
+	}
+	sort.Slice(sets.Items, func(i, j int) bool { return sets.Items[i].Name < sets.Items[j].Name })
+
+	var claims []seaweedv1.BackupVolumeSnapshot
+	for i := range sets.Items {
+		sts := &sets.Items[i]
+		if !metav1.IsControlledBy(sts, cluster) {
+			continue
+		}
+		for _, v := range sts.Spec.Template.Spec.Volumes {
+			if v.PersistentVolumeClaim != nil {
+				return nil, "", &unsupportedClaimError{statefulSet: sts.Name, claim: v.PersistentVolumeClaim.ClaimName}
+			}
+		}
+		replicas := int32(1)
+		if sts.Spec.Replicas != nil {
+			replicas = *sts.Spec.Replicas
+		}
+		for _, tpl := range sts.Spec.VolumeClaimTemplates {
+			for ordinal := int32(0); ordinal < replicas; ordinal++ {
+				name := statefulSetClaimName(tpl.Name, sts.Name, ordinal)
+				var pvc corev1.PersistentVolumeClaim
+				if err := r.Get(ctx, types.NamespacedName{Namespace: cluster.Namespace, Name: name}, &pvc); err != nil {
+					if apierrors.IsNotFound(err) {
+						return nil, "claim " + name + " of statefulset " + sts.Name + " does not exist yet", nil
+					}
+					return nil, "", err
+				}
+				if pvc.Status.Phase != corev1.ClaimBound {
+					return nil, "claim " + name + " of statefulset " + sts.Name + " is not bound yet", nil
+				}
+				claim := seaweedv1.BackupVolumeSnapshot{
+					ClaimName:        name,
+					StatefulSet:      sts.Name,
+					Template:         tpl.Name,
+					Ordinal:          ordinal,
+					StorageClassName: pvc.Spec.StorageClassName,
+					AccessModes:      pvc.Spec.AccessModes,
+				}
+				if size, ok := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+					claim.Size = &size
+				}
+				claims = append(claims, claim)
+			}
+		}
+	}
+	return claims, "", nil
+}
+
+// statefulSetClaimName is the claim a StatefulSet creates from claim template
+// tpl for the replica with ordinal.
+func statefulSetClaimName(tpl, sts string, ordinal int32) string {
+	return tpl + "-" + sts + "-" + strconv.Itoa(int(ordinal))
+}
+
+// volumeSnapshotName names the VolumeSnapshot backup takes of claim, in the
+// cluster's namespace.
+func volumeSnapshotName(backup *seaweedv1.SeaweedBackup, clusterNS, claim string) string {
+	return boundedName(backupJobName(backup.Namespace, clusterNS, backup.Name, "")+"-"+claim, "")
+}
+
+// buildVolumeSnapshot is the VolumeSnapshot of claim. It is not owned by the
+// backup, which may live in another namespace; deleting the backup with
+// deletionPolicy Delete removes it.
+func buildVolumeSnapshot(backup *seaweedv1.SeaweedBackup, cluster *seaweedv1.Seaweed, claim seaweedv1.BackupVolumeSnapshot) *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"source": map[string]interface{}{"persistentVolumeClaimName": claim.ClaimName},
+	}
+	if backup.Spec.VolumeSnapshotClassName != "" {
+		spec["volumeSnapshotClassName"] = backup.Spec.VolumeSnapshotClassName
+	}
+	u := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	u.SetGroupVersionKind(volumeSnapshotGVK)
+	u.SetName(claim.Name)
+	u.SetNamespace(cluster.Namespace)
+	u.SetLabels(map[string]string{seaweedv1.LabelBackupCluster: cluster.Name})
+	return u
+}
+
+// deleteVolumeSnapshots deletes the VolumeSnapshots backup recorded. Ones
+// already gone, or whose CRD was removed, are skipped.
+func (r *SeaweedBackupReconciler) deleteVolumeSnapshots(ctx context.Context, backup *seaweedv1.SeaweedBackup, clusterNS string) error {
+	for _, s := range backup.Status.VolumeSnapshots {
+		snap := &unstructured.Unstructured{}
+		snap.SetGroupVersionKind(volumeSnapshotGVK)
+		snap.SetNamespace(clusterNS)
+		snap.SetName(s.Name)
+		if err := r.Delete(ctx, snap); err != nil && !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			return fmt.Errorf("delete volume snapshot %s: %w", s.Name, err)
+		}
+	}
+	return nil
+}
+
+// freezeVolumes holds the cluster's writable volumes read-only while their
+// snapshots are cut. The ids are recorded in status.frozenVolumes before the
+// volumes are marked, so they are made writable again even when the operator
+// restarts mid-backup; volumes that were already read-only are left to
+// whoever set them.
+//
+// This is not a write freeze: the master still grows new writable volumes
+// when assignments find none, and writes to them during the cut may be caught
+// by some snapshots and not others. Quiesce writers with pre hooks (a
+// filerReadOnly hook, for one) where that matters.
+func (r *SeaweedBackupReconciler) freezeVolumes(ctx context.Context, backup *seaweedv1.SeaweedBackup, cluster *seaweedv1.Seaweed) error {
+	if c := meta.FindStatusCondition(backup.Status.Conditions, seaweedv1.BackupConditionVolumesFrozen); c != nil && c.Status == metav1.ConditionFalse {
+		return nil
+	}
+	admin, err := r.volumeAdminFor(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	// A retried freeze finds its volumes recorded already and re-applies it.
+	if !volumesFrozen(backup) {
+		ids, err := admin.WritableVolumes(ctx)
+		if err != nil {
+			return err
+		}
+		backup.Status.FrozenVolumes = ids
+		meta.SetStatusCondition(&backup.Status.Conditions, metav1.Condition{
+			Type: seaweedv1.BackupConditionVolumesFrozen, Status: metav1.ConditionTrue,
+			ObservedGeneration: backup.Generation, Reason: "Applied",
+			Message: fmt.Sprintf("%d volumes read-only until their snapshots are cut", len(ids)),
+		})
+		if err := r.Status().Update(ctx, backup); err != nil {
+			return err
+		}
+	}
+	if len(backup.Status.FrozenVolumes) == 0 {
+		return nil
+	}
+	return admin.SetVolumesReadOnly(ctx, backup.Status.FrozenVolumes, true)
+}
+
+// releaseFrozenVolumes makes the volumes freezeVolumes held read-only
+// writable again, and records that they were released.
+func (r *SeaweedBackupReconciler) releaseFrozenVolumes(ctx context.Context, backup *seaweedv1.SeaweedBackup, cluster *seaweedv1.Seaweed) error {
+	if !volumesFrozen(backup) {
+		return nil
+	}
+	if ids := backup.Status.FrozenVolumes; len(ids) > 0 {
+		admin, err := r.volumeAdminFor(ctx, cluster)
+		if err != nil {
+			return err
+		}
+		defer admin.Close()
+		if err := admin.SetVolumesReadOnly(ctx, ids, false); err != nil {
+			r.Recorder.Event(backup, "Warning", "VolumesReleaseFailed", "making volumes writable: "+err.Error())
+			return fmt.Errorf("release frozen volumes: %w", err)
+		}
+	}
+	released := len(backup.Status.FrozenVolumes)
+	backup.Status.FrozenVolumes = nil
+	meta.SetStatusCondition(&backup.Status.Conditions, metav1.Condition{
+		Type: seaweedv1.BackupConditionVolumesFrozen, Status: metav1.ConditionFalse,
+		ObservedGeneration: backup.Generation, Reason: "Released",
+		Message: fmt.Sprintf("%d volumes writable again", released),
+	})
+	return r.Status().Update(ctx, backup)
+}
+
+// volumesFrozen reports whether backup holds volumes read-only.
+func volumesFrozen(backup *seaweedv1.SeaweedBackup) bool {
+	return meta.IsStatusConditionTrue(backup.Status.Conditions, seaweedv1.BackupConditionVolumesFrozen)
+}
+
+func (r *SeaweedBackupReconciler) volumeAdminFor(ctx context.Context, cluster *seaweedv1.Seaweed) (VolumeAdmin, error) {
+	dialOption, _, err := loadSeaweedGrpcDialOption(ctx, r.Client, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return r.VolumeAdminFactory(getMasterPeersString(cluster), dialOption, r.Log)
+}
+
+// restoredFromAnnotation marks the claims and the Seaweed a volumeSnapshots
+// restore created with the restore's <namespace>/<name>, so a requeue tells
+// them apart from objects it must not take over.
+const restoredFromAnnotation = "seaweed.seaweedfs.com/restored-from"
+
+// reconcileVolumeSnapshots drives a restore with mode volumeSnapshots: it
+// provisions, from each VolumeSnapshot of the backup, the claim the new
+// cluster's StatefulSet will use in place of its counterpart's, then creates
+// the cluster with the spec the backup recorded. StatefulSets find existing
+// claims by name, which is how the snapshots become the new cluster's data;
+// claim templates themselves cannot differ per replica. The restore completes
+// once every claim is bound.
+func (r *SeaweedRestoreReconciler) reconcileVolumeSnapshots(ctx context.Context, restore *seaweedv1.SeaweedRestore, clusterNS string) (ctrl.Result, error) {
+	var backup seaweedv1.SeaweedBackup
+	if err := r.Get(ctx, types.NamespacedName{Namespace: restore.Namespace, Name: restore.Spec.BackupName}, &backup); err != nil {
+		if apierrors.IsNotFound(err) {
+			return r.pending(ctx, restore, "SourceUnresolved", fmt.Sprintf("backup %q not found in namespace %q", restore.Spec.BackupName, restore.Namespace))
+		}
+		return ctrl.Result{}, err
+	}
+	snapshotNS := backup.Spec.ClusterNamespace
+	if snapshotNS == "" {
+		snapshotNS = backup.Namespace
+	}
+	switch {
+	case backup.Status.Phase != seaweedv1.BackupPhaseCompleted:
+		return r.pending(ctx, restore, "SourceUnresolved", fmt.Sprintf("backup %q is not Completed (phase %q)", backup.Name, backup.Status.Phase))
+	case backup.Spec.Target != seaweedv1.BackupTargetVolumeSnapshots:
+		return r.pending(ctx, restore, "SourceUnresolved", fmt.Sprintf("backup %q holds no VolumeSnapshots; mode volumeSnapshots restores backups with target volumeSnapshots", backup.Name))
+	case snapshotNS != clusterNS:
+		return r.pending(ctx, restore, "SourceUnresolved", fmt.Sprintf("backup %q has its VolumeSnapshots in namespace %q; claims can only be provisioned from them there", backup.Name, snapshotNS))
+	case backup.Status.ClusterSpec == nil || len(backup.Status.VolumeSnapshots) == 0:
+		return r.pending(ctx, restore, "SourceUnresolved", fmt.Sprintf("backup %q recorded no cluster spec or VolumeSnapshots", backup.Name))
+	}
+
+	origin := restore.Namespace + "/" + restore.Name
+	var cluster seaweedv1.Seaweed
+	clusterExists := true
+	if err := r.Get(ctx, types.NamespacedName{Namespace: clusterNS, Name: restore.Spec.ClusterName}, &cluster); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		clusterExists = false
+	}
+	if clusterExists && cluster.Annotations[restoredFromAnnotation] != origin {
+		return r.pending(ctx, restore, "ClusterExists",
+			"Seaweed "+restore.Spec.ClusterName+" already exists in namespace "+clusterNS+"; mode volumeSnapshots creates the cluster")
+	}
+
+	var bound int32
+	for _, s := range backup.Status.VolumeSnapshots {
+		want := buildRestoredClaim(restore, &backup, clusterNS, s)
+		var pvc corev1.PersistentVolumeClaim
+		err := r.Get(ctx, client.ObjectKeyFromObject(want), &pvc)
+		switch {
+		case apierrors.IsNotFound(err):
+			if err := r.Create(ctx, want); err != nil && !apierrors.IsAlreadyExists(err) {
+				return ctrl.Result{}, fmt.Errorf("create claim %s: %w", want.Name, err)
+			}
+		case err != nil:
+			return ctrl.Result{}, err
+		case pvc.Spec.DataSource == nil || pvc.Spec.DataSource.Kind != volumeSnapshotKind || pvc.Spec.DataSource.Name != s.Name:
+			return r.pending(ctx, restore, "ClaimExists",
+				"claim "+pvc.Name+" already exists in namespace "+clusterNS+" and is not provisioned from volume snapshot "+s.Name)
+		case pvc.Status.Phase == corev1.ClaimBound:
+			bound++
+		}
+	}
+
+	if !clusterExists {
+		spec := backup.Status.ClusterSpec.DeepCopy()
+		// The restored cluster is new; backups of it are set up anew rather
+		// than inherited from its source.
+		spec.Backup = nil
+		created := &seaweedv1.Seaweed{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        restore.Spec.ClusterName,
+				Namespace:   clusterNS,
+				Annotations: map[string]string{restoredFromAnnotation: origin},
+			},
+			Spec: *spec,
+		}
+		if err := r.Create(ctx, created); err != nil && !apierrors.IsAlreadyExists(err) {
+			return ctrl.Result{}, fmt.Errorf("create cluster %s: %w", created.Name, err)
+		}
+		r.Log.Info("created cluster from volume snapshots", "seaweedrestore", client.ObjectKeyFromObject(restore), "cluster", created.Name, "backup", backup.Name)
+	}
+
+	claims := int32(len(backup.Status.VolumeSnapshots))
+	restore.Status.Snapshots = &seaweedv1.RestoreSnapshotsStatus{Claims: claims, Bound: bound}
+	restore.Status.ObservedGeneration = restore.Generation
+	if restore.Status.Phase != seaweedv1.RestorePhaseRunning {
+		now := metav1.Now()
+		restore.Status.Phase = seaweedv1.RestorePhaseRunning
+		restore.Status.StartTime = &now
+		meta.SetStatusCondition(&restore.Status.Conditions, metav1.Condition{
+			Type: seaweedv1.RestoreConditionSourceResolved, Status: metav1.ConditionTrue,
+			ObservedGeneration: restore.Generation, Reason: "Resolved",
+			Message: fmt.Sprintf("%d claims provisioned from the VolumeSnapshots of backup %s", claims, backup.Name),
+		})
+	}
+	if bound < claims {
+		if err := r.Status().Update(ctx, restore); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: backupRequeue}, nil
+	}
+
+	now := metav1.Now()
+	msg := fmt.Sprintf("cluster %s created from %d volume snapshots", restore.Spec.ClusterName, claims)
+	restore.Status.Phase = seaweedv1.RestorePhaseCompleted
+	restore.Status.CompletionTime = &now
+	meta.SetStatusCondition(&restore.Status.Conditions, metav1.Condition{
+		Type: seaweedv1.RestoreConditionComplete, Status: metav1.ConditionTrue,
+		ObservedGeneration: restore.Generation, Reason: "RestoreComplete", Message: msg,
+	})
+	r.Recorder.Event(restore, "Normal", "RestoreCompleted", msg)
+	return ctrl.Result{}, r.Status().Update(ctx, restore)
+}
+
+// buildRestoredClaim is the claim the restored cluster's StatefulSet uses for
+// the replica and claim template snapshot s was taken of, provisioned from s.
+// StatefulSets are named after their cluster, so the source cluster's name
+// prefix is swapped for the restored one's.
+func buildRestoredClaim(restore *seaweedv1.SeaweedRestore, backup *seaweedv1.SeaweedBackup, namespace string, s seaweedv1.BackupVolumeSnapshot) *corev1.PersistentVolumeClaim {
+	sts := restore.Spec.ClusterName + strings.TrimPrefix(s.StatefulSet, backup.Spec.ClusterName)
+	size := resource.Quantity{}
+	if s.Size != nil {
+		size = s.Size.DeepCopy()
+	}
+	if s.RestoreSize != nil && s.RestoreSize.Cmp(size) > 0 {
+		size = s.RestoreSize.DeepCopy()
+	}
+	accessModes := s.AccessModes
+	if len(accessModes) == 0 {
+		accessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	}
+	group := volumeSnapshotGroup
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        statefulSetClaimName(s.Template, sts, s.Ordinal),
+			Namespace:   namespace,
+			Annotations: map[string]string{restoredFromAnnotation: restore.Namespace + "/" + restore.Name},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      accessModes,
+			StorageClassName: s.StorageClassName,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: size},
+			},
+			DataSource: &corev1.TypedLocalObjectReference{
+				APIGroup: &group,
+				Kind:     volumeSnapshotKind,
+				Name:     s.Name,
+			},
+		},
+	}
+}
@@ -0,0 +1,531 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// snapshotCluster is cluster c1 with a two-replica volume StatefulSet whose
+// claims from template "mount0" are bound.
+func snapshotCluster() []client.Object {
+	cluster := &seaweedv1.Seaweed{
+		ObjectMeta: metav1.ObjectMeta{Name: "c1", Namespace: "ns1", UID: "c1-uid"},
+		Spec: seaweedv1.SeaweedSpec{
+			Image:  "chrislusf/seaweedfs:test",
+			Master: &seaweedv1.MasterSpec{Replicas: 1},
+			Backup: &seaweedv1.BackupSpec{},
+		},
+	}
+	class := "csi"
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "c1-volume", Namespace: "ns1",
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: seaweedv1.GroupVersion.String(), Kind: "Seaweed",
+				Name: "c1", UID: "c1-uid", Controller: ptr.To(true),
+			}},
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: ptr.To[int32](2),
+			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{
+				ObjectMeta: metav1.ObjectMeta{Name: "mount0"},
+			}},
+		},
+	}
+	objs := []client.Object{cluster, sts}
+	for _, name := range []string{"mount0-c1-volume-0", "mount0-c1-volume-1"} {
+		objs = append(objs, &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns1"},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				StorageClassName: &class,
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+				},
+			},
+			Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+		})
+	}
+	return objs
+}
+
+func newSnapshotBackupReconciler(t *testing.T, admin *fakeVolumeAdmin, objs ...client.Object) *SeaweedBackupReconciler {
+	t.Helper()
+	r := newBackupReconciler(t, objs...)
+	r.VolumeAdminFactory = func(string, grpc.DialOption, logr.Logger) (VolumeAdmin, error) { return admin, nil }
+	return r
+}
+
+// volumeSnapshotClass is the cluster-scoped VolumeSnapshotClass name.
+func volumeSnapshotClass(name string) *unstructured.Unstructured {
+	class := &unstructured.Unstructured{Object: map[string]interface{}{"driver": "csi", "deletionPolicy": "Delete"}}
+	class.SetGroupVersionKind(volumeSnapshotClassGVK)
+	class.SetName(name)
+	return class
+}
+
+// setSnapshotStatus sets the status fields of VolumeSnapshot name the way the
+// snapshot controller would.
+func setSnapshotStatus(t *testing.T, c client.Client, name string, status map[string]interface{}) {
+	t.Helper()
+	snap := &unstructured.Unstructured{}
+	snap.SetGroupVersionKind(volumeSnapshotGVK)
+	if err := c.Get(context.Background(), types.NamespacedName{Namespace: "ns1", Name: name}, snap); err != nil {
+		t.Fatalf("volume snapshot %s: %v", name, err)
+	}
+	snap.Object["status"] = status
+	if err := c.Update(context.Background(), snap); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVolumeSnapshotBackupFreezesUntilSnapshotsAreCut(t *testing.T) {
+	backup := &seaweedv1.SeaweedBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "ns1"},
+		Spec: seaweedv1.SeaweedBackupSpec{
+			ClusterName: "c1", Target: seaweedv1.BackupTargetVolumeSnapshots,
+			VolumeSnapshotClassName: "csi-snap",
+		},
+	}
+	admin := &fakeVolumeAdmin{writable: []uint32{1, 2, 3}, readOnly: map[uint32]bool{3: true}}
+	r := newSnapshotBackupReconciler(t, admin, append(snapshotCluster(), backup, volumeSnapshotClass("csi-snap"))...)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "bk1"}}
+
+	for i := 0; i < 2; i++ { // finalizer, then snapshots
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile %d: %v", i, err)
+		}
+	}
+	var got seaweedv1.SeaweedBackup
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != seaweedv1.BackupPhaseRunning {
+		t.Fatalf("phase = %q, want Running", got.Status.Phase)
+	}
+	if len(got.Status.FrozenVolumes) != 2 || !admin.readOnly[1] || !admin.readOnly[2] {
+		t.Errorf("frozen volumes = %v, read-only = %v; want 1 and 2 frozen", got.Status.FrozenVolumes, admin.readOnly)
+	}
+	if got.Status.ClusterSpec == nil || got.Status.ClusterSpec.Image != "chrislusf/seaweedfs:test" {
+		t.Errorf("cluster spec not recorded: %+v", got.Status.ClusterSpec)
+	}
+	if len(got.Status.VolumeSnapshots) != 2 {
+		t.Fatalf("volumeSnapshots = %+v, want one per claim", got.Status.VolumeSnapshots)
+	}
+	for i, s := range got.Status.VolumeSnapshots {
+		if s.StatefulSet != "c1-volume" || s.Template != "mount0" || s.Ordinal != int32(i) || s.Size == nil || s.Size.String() != "10Gi" {
+			t.Errorf("snapshot %d = %+v", i, s)
+		}
+		snap := &unstructured.Unstructured{}
+		snap.SetGroupVersionKind(volumeSnapshotGVK)
+		if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: s.Name}, snap); err != nil {
+			t.Fatalf("volume snapshot %s: %v", s.Name, err)
+		}
+		source, _, _ := unstructured.NestedString(snap.Object, "spec", "source", "persistentVolumeClaimName")
+		class, _, _ := unstructured.NestedString(snap.Object, "spec", "volumeSnapshotClassName")
+		if source != s.ClaimName || class != "csi-snap" {
+			t.Errorf("volume snapshot %s spec = %v", s.Name, snap.Object["spec"])
+		}
+	}
+
+	// Cut but not ready: the volumes are released, the backup keeps running.
+	for _, s := range got.Status.VolumeSnapshots {
+		setSnapshotStatus(t, r.Client, s.Name, map[string]interface{}{"creationTime": "2026-01-01T00:00:00Z", "readyToUse": false})
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if admin.readOnly[1] || admin.readOnly[2] || !admin.readOnly[3] {
+		t.Errorf("read-only after cut = %v, want only volume 3 left alone", admin.readOnly)
+	}
+	if c := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.BackupConditionVolumesFrozen); c == nil || c.Reason != "Released" {
+		t.Errorf("VolumesFrozen condition = %+v", c)
+	}
+	if got.Status.Phase != seaweedv1.BackupPhaseRunning {
+		t.Fatalf("phase = %q, want Running until the snapshots are ready", got.Status.Phase)
+	}
+
+	for _, s := range got.Status.VolumeSnapshots {
+		setSnapshotStatus(t, r.Client, s.Name, map[string]interface{}{
+			"creationTime": "2026-01-01T00:00:00Z", "readyToUse": true, "restoreSize": "12Gi",
+		})
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != seaweedv1.BackupPhaseCompleted {
+		t.Fatalf("phase = %q, want Completed", got.Status.Phase)
+	}
+	for _, s := range got.Status.VolumeSnapshots {
+		if !s.ReadyToUse || s.RestoreSize == nil || s.RestoreSize.String() != "12Gi" {
+			t.Errorf("snapshot status not recorded: %+v", s)
+		}
+	}
+}
+
+func TestVolumeSnapshotBackupFailureReleasesAndDeletes(t *testing.T) {
+	backup := &seaweedv1.SeaweedBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "ns1"},
+		Spec:       seaweedv1.SeaweedBackupSpec{ClusterName: "c1", Target: seaweedv1.BackupTargetVolumeSnapshots},
+	}
+	admin := &fakeVolumeAdmin{writable: []uint32{1}}
+	r := newSnapshotBackupReconciler(t, admin, append(snapshotCluster(), backup)...)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "bk1"}}
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile %d: %v", i, err)
+		}
+	}
+	var got seaweedv1.SeaweedBackup
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Status.VolumeSnapshots) != 2 {
+		t.Fatalf("volumeSnapshots = %+v", got.Status.VolumeSnapshots)
+	}
+	failed := got.Status.VolumeSnapshots[1].Name
+	setSnapshotStatus(t, r.Client, failed, map[string]interface{}{"error": map[string]interface{}{"message": "quota exceeded"}})
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	c := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.BackupConditionComplete)
+	if got.Status.Phase != seaweedv1.BackupPhaseFailed || c == nil || c.Reason != "SnapshotFailed" || !strings.Contains(c.Message, "quota exceeded") {
+		t.Fatalf("phase = %q, Complete condition = %+v", got.Status.Phase, c)
+	}
+	if admin.readOnly[1] {
+		t.Error("a failed backup must make its volumes writable again")
+	}
+	for _, s := range got.Status.VolumeSnapshots {
+		snap := &unstructured.Unstructured{}
+		snap.SetGroupVersionKind(volumeSnapshotGVK)
+		if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: s.Name}, snap); !apierrors.IsNotFound(err) {
+			t.Errorf("volume snapshot %s of a failed backup should be deleted, got err %v", s.Name, err)
+		}
+	}
+}
+
+func TestVolumeSnapshotBackupChecksSnapshotsBeforeFreezing(t *testing.T) {
+	noAPI := interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			if u, ok := list.(*unstructured.UnstructuredList); ok && u.GroupVersionKind().Group == volumeSnapshotGroup {
+				return &meta.NoKindMatchError{GroupKind: volumeSnapshotGVK.GroupKind(), SearchedVersions: []string{"v1"}}
+			}
+			return c.List(ctx, list, opts...)
+		},
+	}
+	cases := []struct {
+		name   string
+		class  string
+		funcs  *interceptor.Funcs
+		reason string
+	}{
+		{"missing class", "absent", nil, "SnapshotClassNotFound"},
+		{"no snapshot API", "", &noAPI, "SnapshotAPIMissing"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			backup := &seaweedv1.SeaweedBackup{
+				ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "ns1"},
+				Spec: seaweedv1.SeaweedBackupSpec{
+					ClusterName: "c1", Target: seaweedv1.BackupTargetVolumeSnapshots,
+					VolumeSnapshotClassName: c.class,
+				},
+			}
+			admin := &fakeVolumeAdmin{writable: []uint32{1}}
+			r := newSnapshotBackupReconciler(t, admin, append(snapshotCluster(), backup)...)
+			if c.funcs != nil {
+				r.Client = interceptor.NewClient(r.Client.(client.WithWatch), *c.funcs)
+			}
+			ctx := context.Background()
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "bk1"}}
+			for i := 0; i < 2; i++ {
+				if _, err := r.Reconcile(ctx, req); err != nil {
+					t.Fatalf("reconcile %d: %v", i, err)
+				}
+			}
+			var got seaweedv1.SeaweedBackup
+			if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+				t.Fatal(err)
+			}
+			cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.BackupConditionComplete)
+			if got.Status.Phase != seaweedv1.BackupPhaseFailed || cond == nil || cond.Reason != c.reason {
+				t.Fatalf("phase = %q, Complete condition = %+v; want Failed with %s", got.Status.Phase, cond, c.reason)
+			}
+			if len(admin.readOnly) != 0 || len(got.Status.FrozenVolumes) != 0 {
+				t.Errorf("volumes frozen for snapshots that cannot be created: %v", admin.readOnly)
+			}
+		})
+	}
+}
+
+func TestVolumeSnapshotBackupCreateFailureReleases(t *testing.T) {
+	backup := &seaweedv1.SeaweedBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "ns1"},
+		Spec:       seaweedv1.SeaweedBackupSpec{ClusterName: "c1", Target: seaweedv1.BackupTargetVolumeSnapshots},
+	}
+	admin := &fakeVolumeAdmin{writable: []uint32{1}}
+	r := newSnapshotBackupReconciler(t, admin, append(snapshotCluster(), backup)...)
+	r.Client = interceptor.NewClient(r.Client.(client.WithWatch), interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			if strings.HasSuffix(obj.GetName(), "mount0-c1-volume-1") {
+				return errors.New("admission webhook denied the request")
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+	})
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "bk1"}}
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile %d: %v", i, err)
+		}
+	}
+
+	var got seaweedv1.SeaweedBackup
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	c := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.BackupConditionComplete)
+	if got.Status.Phase != seaweedv1.BackupPhaseFailed || c == nil || c.Reason != "SnapshotCreateFailed" || !strings.Contains(c.Message, "webhook denied") {
+		t.Fatalf("phase = %q, Complete condition = %+v", got.Status.Phase, c)
+	}
+	if admin.readOnly[1] || len(got.Status.FrozenVolumes) != 0 {
+		t.Errorf("volumes still frozen after the create failed: %v", admin.readOnly)
+	}
+	var snaps unstructured.UnstructuredList
+	snaps.SetGroupVersionKind(volumeSnapshotGVK.GroupVersion().WithKind(volumeSnapshotKind + "List"))
+	if err := r.List(ctx, &snaps, client.InNamespace("ns1")); err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps.Items) != 0 {
+		t.Errorf("snapshot created before the failure was left behind: %d", len(snaps.Items))
+	}
+}
+
+func TestVolumeSnapshotBackupRejectsDirectClaims(t *testing.T) {
+	objs := snapshotCluster()
+	sts := objs[1].(*appsv1.StatefulSet)
+	sts.Spec.Template.Spec.Volumes = []corev1.Volume{{
+		Name:         "data",
+		VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "shared"}},
+	}}
+	backup := &seaweedv1.SeaweedBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "ns1"},
+		Spec:       seaweedv1.SeaweedBackupSpec{ClusterName: "c1", Target: seaweedv1.BackupTargetVolumeSnapshots},
+	}
+	admin := &fakeVolumeAdmin{writable: []uint32{1}}
+	r := newSnapshotBackupReconciler(t, admin, append(objs, backup)...)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "bk1"}}
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile %d: %v", i, err)
+		}
+	}
+	var got seaweedv1.SeaweedBackup
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	c := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.BackupConditionComplete)
+	if got.Status.Phase != seaweedv1.BackupPhaseFailed || c == nil || c.Reason != "UnsupportedClaims" {
+		t.Fatalf("phase = %q, Complete condition = %+v", got.Status.Phase, c)
+	}
+	if len(admin.readOnly) != 0 {
+		t.Errorf("volumes frozen for a backup that cannot be taken: %v", admin.readOnly)
+	}
+}
+
+func TestVolumeSnapshotBackupDeletionPolicyDeletesSnapshots(t *testing.T) {
+	snapName := volumeSnapshotName(&seaweedv1.SeaweedBackup{ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "ns1"}}, "ns1", "mount0-c1-volume-0")
+	backup := &seaweedv1.SeaweedBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "ns1", Finalizers: []string{SeaweedBackupFinalizer}},
+		Spec: seaweedv1.SeaweedBackupSpec{
+			ClusterName: "c1", Target: seaweedv1.BackupTargetVolumeSnapshots,
+			DeletionPolicy: seaweedv1.BackupDeletionDelete,
+		},
+		Status: seaweedv1.SeaweedBackupStatus{
+			Phase:           seaweedv1.BackupPhaseCompleted,
+			VolumeSnapshots: []seaweedv1.BackupVolumeSnapshot{{Name: snapName, ClaimName: "mount0-c1-volume-0"}},
+		},
+	}
+	cluster := snapshotCluster()[0].(*seaweedv1.Seaweed)
+	snap := buildVolumeSnapshot(backup, cluster, backup.Status.VolumeSnapshots[0])
+	r := newSnapshotBackupReconciler(t, &fakeVolumeAdmin{}, backup, snap)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "bk1"}}
+
+	if err := r.Delete(ctx, backup); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile after delete: %v", err)
+	}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(snap), snap); !apierrors.IsNotFound(err) {
+		t.Errorf("volume snapshot should be deleted with the backup, got err %v", err)
+	}
+	var got seaweedv1.SeaweedBackup
+	if err := r.Get(ctx, req.NamespacedName, &got); !apierrors.IsNotFound(err) {
+		t.Errorf("backup should be gone, got err %v", err)
+	}
+}
+
+// snapshotBackup is a completed volumeSnapshots backup of c1 taken of claims
+// mount0-c1-volume-{0,1}.
+func snapshotBackup() *seaweedv1.SeaweedBackup {
+	class := "csi"
+	size, restoreSize := resource.MustParse("10Gi"), resource.MustParse("12Gi")
+	b := &seaweedv1.SeaweedBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "ns1"},
+		Spec:       seaweedv1.SeaweedBackupSpec{ClusterName: "c1", Target: seaweedv1.BackupTargetVolumeSnapshots},
+		Status: seaweedv1.SeaweedBackupStatus{
+			Phase: seaweedv1.BackupPhaseCompleted,
+			ClusterSpec: &seaweedv1.SeaweedSpec{
+				Image:  "chrislusf/seaweedfs:test",
+				Master: &seaweedv1.MasterSpec{Replicas: 1},
+				Backup: &seaweedv1.BackupSpec{},
+			},
+		},
+	}
+	for i, claim := range []string{"mount0-c1-volume-0", "mount0-c1-volume-1"} {
+		b.Status.VolumeSnapshots = append(b.Status.VolumeSnapshots, seaweedv1.BackupVolumeSnapshot{
+			Name: volumeSnapshotName(b, "ns1", claim), ClaimName: claim,
+			StatefulSet: "c1-volume", Template: "mount0", Ordinal: int32(i),
+			StorageClassName: &class, Size: &size, RestoreSize: &restoreSize, ReadyToUse: true,
+		})
+	}
+	return b
+}
+
+func TestVolumeSnapshotRestoreCreatesClusterOnRestoredClaims(t *testing.T) {
+	restore := &seaweedv1.SeaweedRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "rs1", Namespace: "ns1"},
+		Spec: seaweedv1.SeaweedRestoreSpec{
+			ClusterName: "c2", BackupName: "bk1", Mode: seaweedv1.RestoreModeVolumeSnapshots,
+		},
+	}
+	r := newRestoreReconciler(t, snapshotBackup(), restore)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "rs1"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"mount0-c2-volume-0", "mount0-c2-volume-1"} {
+		var pvc corev1.PersistentVolumeClaim
+		if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: name}, &pvc); err != nil {
+			t.Fatalf("expected claim %s: %v", name, err)
+		}
+		ds := pvc.Spec.DataSource
+		if ds == nil || ds.Kind != volumeSnapshotKind || ds.APIGroup == nil || *ds.APIGroup != volumeSnapshotGroup || !strings.HasSuffix(ds.Name, strings.Replace(name, "c2", "c1", 1)) {
+			t.Errorf("claim %s dataSource = %+v", name, ds)
+		}
+		if got := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; got.String() != "12Gi" {
+			t.Errorf("claim %s requests %s, want the snapshot's restoreSize", name, got.String())
+		}
+		pvc.Status.Phase = corev1.ClaimBound
+		if err := r.Update(ctx, &pvc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var cluster seaweedv1.Seaweed
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "c2"}, &cluster); err != nil {
+		t.Fatalf("expected restored cluster: %v", err)
+	}
+	if cluster.Spec.Backup != nil || cluster.Spec.Image != "chrislusf/seaweedfs:test" {
+		t.Errorf("restored cluster spec = %+v", cluster.Spec)
+	}
+	var got seaweedv1.SeaweedRestore
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != seaweedv1.RestorePhaseRunning || got.Status.Snapshots == nil || got.Status.Snapshots.Claims != 2 || got.Status.Snapshots.Bound != 0 {
+		t.Fatalf("status = %+v, want Running with 2 unbound claims", got.Status)
+	}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != seaweedv1.RestorePhaseCompleted || got.Status.Snapshots.Bound != 2 {
+		t.Fatalf("status = %+v, want Completed with 2 bound claims", got.Status)
+	}
+}
+
+func TestVolumeSnapshotRestoreRefusesExistingCluster(t *testing.T) {
+	existing := &seaweedv1.Seaweed{ObjectMeta: metav1.ObjectMeta{Name: "c2", Namespace: "ns1"}}
+	restore := &seaweedv1.SeaweedRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "rs1", Namespace: "ns1"},
+		Spec: seaweedv1.SeaweedRestoreSpec{
+			ClusterName: "c2", BackupName: "bk1", Mode: seaweedv1.RestoreModeVolumeSnapshots,
+		},
+	}
+	r := newRestoreReconciler(t, snapshotBackup(), existing, restore)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "rs1"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	var got seaweedv1.SeaweedRestore
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	c := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.RestoreConditionSourceResolved)
+	if got.Status.Phase != seaweedv1.RestorePhasePending || c == nil || c.Reason != "ClusterExists" {
+		t.Fatalf("phase = %q, SourceResolved condition = %+v", got.Status.Phase, c)
+	}
+	var pvc corev1.PersistentVolumeClaim
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "mount0-c2-volume-0"}, &pvc); !apierrors.IsNotFound(err) {
+		t.Errorf("no claim should be provisioned for an existing cluster, got err %v", err)
+	}
+}
@@ -0,0 +1,511 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/internal/backuptool"
+)
+
+// defaultVerificationTimeout bounds a restore drill whose spec.timeout is
+// unset.
+const defaultVerificationTimeout = 30 * time.Minute
+
+// BackupVerificationReconciler runs a BackupVerification restore drill: it
+// brings up a minimal scratch Seaweed cluster, restores the backup into it
+// with a SeaweedRestore, checks the restored metadata with a backup-tool
+// Job, records the outcome on the verification and the backup, and deletes
+// the scratch cluster again.
+type BackupVerificationReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// BackupToolImage is the image the check Job runs (see
+	// ResolveBackupToolImage). Empty leaves verifications Pending.
+	BackupToolImage string
+	// Now returns the current time when enforcing spec.timeout. Tests pin
+	// it; nil uses time.Now.
+	Now func() time.Time
+}
+
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=backupverifications,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=backupverifications/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=backupverifications/finalizers,verbs=update
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=seaweeds,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=seaweedrestores,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=seaweedbackups/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+
+// Reconcile implements the BackupVerification lifecycle.
+func (r *BackupVerificationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("backupverification", req.NamespacedName)
+
+	var v seaweedv1.BackupVerification
+	if err := r.Get(ctx, req.NamespacedName, &v); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	// The scratch cluster, restore and check Job are owned by the
+	// verification and garbage-collected with it.
+	if !v.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	if v.Status.Phase == seaweedv1.VerificationPhasePassed || v.Status.Phase == seaweedv1.VerificationPhaseFailed {
+		if err := r.recordResult(ctx, &v); err != nil {
+			return ctrl.Result{}, err
+		}
+		return r.teardown(ctx, &v)
+	}
+
+	if v.Status.StartTime != nil && r.now().Sub(v.Status.StartTime.Time) > verificationTimeout(&v) {
+		return r.finish(ctx, &v, seaweedv1.VerificationPhaseFailed, seaweedv1.VerificationConditionChecked,
+			"TimedOut", fmt.Sprintf("the drill did not finish within %s", verificationTimeout(&v)))
+	}
+	if r.BackupToolImage == "" {
+		return r.pending(ctx, &v, "BackupToolUnavailable",
+			"checking the restored metadata needs the operator's backup-tool image; set "+BackupToolImageEnv+" on the operator")
+	}
+
+	backup, err := r.resolveBackup(ctx, &v)
+	if err != nil {
+		return r.pending(ctx, &v, "BackupNotFound", err.Error())
+	}
+	switch backup.Status.Phase {
+	case seaweedv1.BackupPhaseCompleted:
+	case seaweedv1.BackupPhaseFailed:
+		v.Status.BackupName = backup.Name
+		return r.finish(ctx, &v, seaweedv1.VerificationPhaseFailed, seaweedv1.VerificationConditionBackupResolved,
+			"BackupFailed", "backup "+backup.Name+" failed; there is nothing to restore")
+	default:
+		return r.pending(ctx, &v, "BackupNotCompleted", "backup "+backup.Name+" has not completed yet")
+	}
+	if ns := backup.Spec.ClusterNamespace; ns != "" && ns != backup.Namespace {
+		v.Status.BackupName = backup.Name
+		return r.finish(ctx, &v, seaweedv1.VerificationPhaseFailed, seaweedv1.VerificationConditionBackupResolved,
+			"Unsupported", "backup "+backup.Name+" is of a cluster in namespace "+ns+"; run the verification in that namespace")
+	}
+	if !metadataBackup(&backup) {
+		v.Status.BackupName = backup.Name
+		return r.finish(ctx, &v, seaweedv1.VerificationPhaseFailed, seaweedv1.VerificationConditionBackupResolved,
+			"Unsupported", "backup "+backup.Name+" has target "+string(backup.Spec.Target)+"; restore drills load metadata snapshots")
+	}
+
+	var cluster seaweedv1.Seaweed
+	if err := r.Get(ctx, types.NamespacedName{Namespace: v.Namespace, Name: v.Spec.ClusterName}, &cluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			return r.pending(ctx, &v, "ClusterNotFound", "Seaweed cluster "+v.Spec.ClusterName+" not found in namespace "+v.Namespace)
+		}
+		return ctrl.Result{}, err
+	}
+	st, err := resolveStorage(&cluster, backup.Spec.StorageName)
+	if err != nil {
+		return r.pending(ctx, &v, "StorageNotFound", err.Error())
+	}
+
+	if v.Status.StartTime == nil {
+		start := metav1.NewTime(r.now())
+		v.Status.Phase = seaweedv1.VerificationPhaseRunning
+		v.Status.StartTime = &start
+		v.Status.BackupName = backup.Name
+		v.Status.ScratchCluster = scratchClusterName(&v)
+		v.Status.RestoreName = boundedName(v.Name, "-restore")
+		v.Status.JobName = boundedName(v.Name, "-check")
+		v.Status.ObservedGeneration = v.Generation
+		meta.SetStatusCondition(&v.Status.Conditions, metav1.Condition{
+			Type: seaweedv1.VerificationConditionBackupResolved, Status: metav1.ConditionTrue,
+			ObservedGeneration: v.Generation, Reason: "Resolved", Message: "verifying backup " + backup.Name,
+		})
+		if err := r.Status().Update(ctx, &v); err != nil {
+			return ctrl.Result{}, err
+		}
+		log.Info("started restore drill", "backup", backup.Name, "scratchCluster", v.Status.ScratchCluster)
+	}
+
+	// Scratch cluster.
+	var scratch seaweedv1.Seaweed
+	err = r.Get(ctx, types.NamespacedName{Namespace: v.Namespace, Name: v.Status.ScratchCluster}, &scratch)
+	if apierrors.IsNotFound(err) {
+		built := buildScratchCluster(&v, &cluster, backup.Spec.StorageName, st)
+		if err := controllerutil.SetControllerReference(&v, built, r.Scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.Create(ctx, built); err != nil && !apierrors.IsAlreadyExists(err) {
+			return ctrl.Result{}, err
+		}
+		return r.progress(ctx, &v, seaweedv1.VerificationConditionScratchReady, metav1.ConditionFalse,
+			"Creating", "scratch cluster "+built.Name+" created")
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+	if scratch.Status.Master.ReadyReplicas == 0 || scratch.Status.Filer.ReadyReplicas == 0 {
+		return r.progress(ctx, &v, seaweedv1.VerificationConditionScratchReady, metav1.ConditionFalse,
+			"Starting", "waiting for the scratch cluster's master and filer")
+	}
+	meta.SetStatusCondition(&v.Status.Conditions, metav1.Condition{
+		Type: seaweedv1.VerificationConditionScratchReady, Status: metav1.ConditionTrue,
+		ObservedGeneration: v.Generation, Reason: "Ready", Message: "scratch cluster " + scratch.Name + " is ready",
+	})
+
+	// Restore.
+	var restore seaweedv1.SeaweedRestore
+	err = r.Get(ctx, types.NamespacedName{Namespace: v.Namespace, Name: v.Status.RestoreName}, &restore)
+	if apierrors.IsNotFound(err) {
+		built := &seaweedv1.SeaweedRestore{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      v.Status.RestoreName,
+				Namespace: v.Namespace,
+				Labels:    map[string]string{seaweedv1.LabelBackupVerification: v.Name},
+			},
+			Spec: seaweedv1.SeaweedRestoreSpec{
+				ClusterName: scratch.Name,
+				BackupName:  backup.Name,
+				FilerPath:   "/",
+			},
+		}
+		if err := controllerutil.SetControllerReference(&v, built, r.Scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.Create(ctx, built); err != nil && !apierrors.IsAlreadyExists(err) {
+			return ctrl.Result{}, err
+		}
+		return r.progress(ctx, &v, seaweedv1.VerificationConditionRestored, metav1.ConditionFalse,
+			"Restoring", "restore "+built.Name+" created")
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+	switch restore.Status.Phase {
+	case seaweedv1.RestorePhaseCompleted:
+	case seaweedv1.RestorePhaseFailed:
+		msg := "restore " + restore.Name + " failed"
+		if c := meta.FindStatusCondition(restore.Status.Conditions, seaweedv1.RestoreConditionComplete); c != nil && c.Message != "" {
+			msg += ": " + c.Message
+		}
+		return r.finish(ctx, &v, seaweedv1.VerificationPhaseFailed, seaweedv1.VerificationConditionRestored, "RestoreFailed", msg)
+	default:
+		return r.progress(ctx, &v, seaweedv1.VerificationConditionRestored, metav1.ConditionFalse,
+			"Restoring", "waiting for restore "+restore.Name)
+	}
+	meta.SetStatusCondition(&v.Status.Conditions, metav1.Condition{
+		Type: seaweedv1.VerificationConditionRestored, Status: metav1.ConditionTrue,
+		ObservedGeneration: v.Generation, Reason: "Restored", Message: "backup " + backup.Name + " restored",
+	})
+
+	// Check.
+	var job batchv1.Job
+	err = r.Get(ctx, types.NamespacedName{Namespace: v.Namespace, Name: v.Status.JobName}, &job)
+	if apierrors.IsNotFound(err) {
+		built := buildVerificationCheckJob(&scratch, v.Status.JobName, &v, backup.Spec.FilerPath, r.BackupToolImage)
+		if err := controllerutil.SetControllerReference(&v, built, r.Scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.Create(ctx, built); err != nil && !apierrors.IsAlreadyExists(err) {
+			return ctrl.Result{}, err
+		}
+		return r.progress(ctx, &v, seaweedv1.VerificationConditionChecked, metav1.ConditionFalse,
+			"Checking", "check job "+built.Name+" created")
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+	done, success := jobFinished(&job)
+	if !done {
+		return r.progress(ctx, &v, seaweedv1.VerificationConditionChecked, metav1.ConditionFalse,
+			"Checking", "waiting for check job "+job.Name)
+	}
+	report, err := r.checkReport(ctx, &job, success)
+	if err != nil {
+		log.Error(err, "reading metadata check report", "job", job.Name)
+	}
+	v.Status.Entries = report.Entries
+	if !success {
+		msg := report.Failure
+		if msg == "" {
+			msg = "check job " + job.Name + " failed"
+		}
+		return r.finish(ctx, &v, seaweedv1.VerificationPhaseFailed, seaweedv1.VerificationConditionChecked, "CheckFailed", msg)
+	}
+	return r.finish(ctx, &v, seaweedv1.VerificationPhasePassed, seaweedv1.VerificationConditionChecked, "CheckPassed",
+		fmt.Sprintf("%d entries restored; %d sampled paths found", report.Entries, report.Sampled))
+}
+
+// resolveBackup returns the backup a verification drills: the one it already
+// picked, spec.backupName, or else the latest Completed metadata backup of
+// the cluster in the verification's namespace.
+func (r *BackupVerificationReconciler) resolveBackup(ctx context.Context, v *seaweedv1.BackupVerification) (*seaweedv1.SeaweedBackup, error) {
+	name := v.Status.BackupName
+	if name == "" {
+		name = v.Spec.BackupName
+	}
+	if name != "" {
+		var backup seaweedv1.SeaweedBackup
+		if err := r.Get(ctx, types.NamespacedName{Namespace: v.Namespace, Name: name}, &backup); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("backup %q not found in namespace %q", name, v.Namespace)
+			}
+			return nil, err
+		}
+		return &backup, nil
+	}
+
+	var backups seaweedv1.SeaweedBackupList
+	if err := r.List(ctx, &backups, client.InNamespace(v.Namespace)); err != nil {
+		return nil, err
+	}
+	var latest *seaweedv1.SeaweedBackup
+	for i := range backups.Items {
+		b := &backups.Items[i]
+		if b.Spec.ClusterName != v.Spec.ClusterName || b.Status.Phase != seaweedv1.BackupPhaseCompleted || b.Status.CompletionTime == nil {
+			continue
+		}
+		if !metadataBackup(b) {
+			continue
+		}
+		if ns := b.Spec.ClusterNamespace; ns != "" && ns != v.Namespace {
+			continue
+		}
+		if latest == nil || b.Status.CompletionTime.After(latest.Status.CompletionTime.Time) {
+			latest = b
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no Completed backup of cluster %q in namespace %q", v.Spec.ClusterName, v.Namespace)
+	}
+	return latest, nil
+}
+
+// scratchClusterName is the name of a verification's scratch cluster. It is
+// short and fixed-length so that the names derived from it for the
+// cluster's StatefulSets and Services stay within their limits.
+func scratchClusterName(v *seaweedv1.BackupVerification) string {
+	return "verify-" + fnvHash(v.Namespace+"/"+v.Name)
+}
+
+// buildScratchCluster returns the minimal Seaweed cluster a drill restores
+// into: one master and one filer on source's image, with no volume servers,
+// since a metadata restore stores no file data, and with only the storage
+// holding the backup.
+func buildScratchCluster(v *seaweedv1.BackupVerification, source *seaweedv1.Seaweed, storageName string, st seaweedv1.BackupStorageSpec) *seaweedv1.Seaweed {
+	scratch := &seaweedv1.Seaweed{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      scratchClusterName(v),
+			Namespace: v.Namespace,
+			Labels:    map[string]string{seaweedv1.LabelBackupVerification: v.Name},
+		},
+		Spec: seaweedv1.SeaweedSpec{
+			Image:            source.Spec.Image,
+			Version:          source.Spec.Version,
+			ImagePullPolicy:  source.Spec.ImagePullPolicy,
+			ImagePullSecrets: source.Spec.ImagePullSecrets,
+			NodeSelector:     source.Spec.NodeSelector,
+			Tolerations:      source.Spec.Tolerations,
+			Master:           &seaweedv1.MasterSpec{Replicas: 1},
+			Filer:            &seaweedv1.FilerSpec{Replicas: 1},
+			Backup: &seaweedv1.BackupSpec{
+				Storages: map[string]seaweedv1.BackupStorageSpec{storageName: st},
+			},
+		},
+	}
+	if source.Spec.Backup != nil {
+		scratch.Spec.Backup.Image = source.Spec.Backup.Image
+	}
+	return scratch
+}
+
+// verificationTimeout is how long a drill may run.
+func verificationTimeout(v *seaweedv1.BackupVerification) time.Duration {
+	if v.Spec.Timeout != nil && v.Spec.Timeout.Duration > 0 {
+		return v.Spec.Timeout.Duration
+	}
+	return defaultVerificationTimeout
+}
+
+// checkReport reads the report a check Job's container left as its
+// termination message. A failed check leaves its report too; a check that
+// crashed before writing one leaves its log, whose last line becomes the
+// report's Failure.
+func (r *BackupVerificationReconciler) checkReport(ctx context.Context, job *batchv1.Job, success bool) (backuptool.MetadataCheckReport, error) {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return backuptool.MetadataCheckReport{}, err
+	}
+	for _, pod := range pods.Items {
+		for _, cs := range pod.Status.ContainerStatuses {
+			t := cs.State.Terminated
+			if cs.Name != verificationCheckContainer || t == nil || t.Message == "" || (success && t.ExitCode != 0) {
+				continue
+			}
+			var report backuptool.MetadataCheckReport
+			if err := json.Unmarshal([]byte(t.Message), &report); err != nil {
+				msg := strings.TrimSpace(t.Message)
+				if i := strings.LastIndex(msg, "\n"); i >= 0 {
+					msg = msg[i+1:]
+				}
+				return backuptool.MetadataCheckReport{Failure: msg}, nil
+			}
+			return report, nil
+		}
+	}
+	return backuptool.MetadataCheckReport{}, fmt.Errorf("no check report found for job %s", job.Name)
+}
+
+// recordResult copies a finished drill's outcome onto the verified backup,
+// unless the backup already carries a later drill's.
+func (r *BackupVerificationReconciler) recordResult(ctx context.Context, v *seaweedv1.BackupVerification) error {
+	if v.Status.BackupName == "" || v.Status.CompletionTime == nil {
+		return nil
+	}
+	var backup seaweedv1.SeaweedBackup
+	if err := r.Get(ctx, types.NamespacedName{Namespace: v.Namespace, Name: v.Status.BackupName}, &backup); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if cur := backup.Status.Verification; cur != nil && (cur.Name == v.Name || cur.Time.After(v.Status.CompletionTime.Time)) {
+		return nil
+	}
+	result := &seaweedv1.BackupVerificationResult{
+		Name:     v.Name,
+		Phase:    v.Status.Phase,
+		Time:     *v.Status.CompletionTime,
+		Duration: v.Status.Duration,
+		Entries:  v.Status.Entries,
+	}
+	if v.Status.Phase == seaweedv1.VerificationPhaseFailed {
+		result.Message = v.Status.Message
+	}
+	backup.Status.Verification = result
+	return r.Status().Update(ctx, &backup)
+}
+
+// teardown deletes a finished drill's scratch cluster and restore. The check
+// Job is kept, for its logs, until the verification is deleted.
+func (r *BackupVerificationReconciler) teardown(ctx context.Context, v *seaweedv1.BackupVerification) (ctrl.Result, error) {
+	gone := true
+	for _, obj := range []client.Object{
+		&seaweedv1.SeaweedRestore{ObjectMeta: metav1.ObjectMeta{Namespace: v.Namespace, Name: v.Status.RestoreName}},
+		&seaweedv1.Seaweed{ObjectMeta: metav1.ObjectMeta{Namespace: v.Namespace, Name: v.Status.ScratchCluster}},
+	} {
+		if obj.GetName() == "" {
+			continue
+		}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return ctrl.Result{}, err
+		}
+		gone = false
+		if obj.GetDeletionTimestamp().IsZero() {
+			if err := r.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+	if !gone {
+		return r.progress(ctx, v, seaweedv1.VerificationConditionCleanedUp, metav1.ConditionFalse,
+			"Deleting", "deleting scratch cluster "+v.Status.ScratchCluster)
+	}
+	if meta.IsStatusConditionTrue(v.Status.Conditions, seaweedv1.VerificationConditionCleanedUp) {
+		return ctrl.Result{}, nil
+	}
+	meta.SetStatusCondition(&v.Status.Conditions, metav1.Condition{
+		Type: seaweedv1.VerificationConditionCleanedUp, Status: metav1.ConditionTrue,
+		ObservedGeneration: v.Generation, Reason: "Deleted", Message: "scratch cluster deleted",
+	})
+	return ctrl.Result{}, r.Status().Update(ctx, v)
+}
+
+// finish ends the drill as phase, recording reason and msg on the condition
+// cond. The result is copied to the backup, and the scratch cluster torn
+// down, on the next reconcile.
+func (r *BackupVerificationReconciler) finish(ctx context.Context, v *seaweedv1.BackupVerification, phase seaweedv1.VerificationPhase, cond, reason, msg string) (ctrl.Result, error) {
+	now := metav1.NewTime(r.now())
+	v.Status.Phase = phase
+	v.Status.CompletionTime = &now
+	v.Status.Message = msg
+	if v.Status.StartTime != nil {
+		v.Status.Duration = &metav1.Duration{Duration: now.Sub(v.Status.StartTime.Time).Round(time.Second)}
+	}
+	status := metav1.ConditionFalse
+	if phase == seaweedv1.VerificationPhasePassed {
+		status = metav1.ConditionTrue
+	}
+	meta.SetStatusCondition(&v.Status.Conditions, metav1.Condition{
+		Type: cond, Status: status, ObservedGeneration: v.Generation, Reason: reason, Message: msg,
+	})
+	if status == metav1.ConditionTrue {
+		r.Recorder.Event(v, "Normal", "VerificationPassed", msg)
+	} else {
+		r.Recorder.Event(v, "Warning", "VerificationFailed", msg)
+	}
+	if err := r.Status().Update(ctx, v); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// progress records the drill's current step on cond and requeues.
+func (r *BackupVerificationReconciler) progress(ctx context.Context, v *seaweedv1.BackupVerification, cond string, status metav1.ConditionStatus, reason, msg string) (ctrl.Result, error) {
+	meta.SetStatusCondition(&v.Status.Conditions, metav1.Condition{
+		Type: cond, Status: status, ObservedGeneration: v.Generation, Reason: reason, Message: msg,
+	})
+	if err := r.Status().Update(ctx, v); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: backupRequeue}, nil
+}
+
+// pending records why the drill cannot start yet and requeues.
+func (r *BackupVerificationReconciler) pending(ctx context.Context, v *seaweedv1.BackupVerification, reason, msg string) (ctrl.Result, error) {
+	v.Status.Phase = seaweedv1.VerificationPhasePending
+	return r.progress(ctx, v, seaweedv1.VerificationConditionBackupResolved, metav1.ConditionFalse, reason, msg)
+}
+
+func (r *BackupVerificationReconciler) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+// SetupWithManager wires the reconciler into the manager.
+func (r *BackupVerificationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&seaweedv1.BackupVerification{}).
+		Owns(&seaweedv1.Seaweed{}).
+		Owns(&seaweedv1.SeaweedRestore{}).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
@@ -0,0 +1,243 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+func newVerificationReconciler(t *testing.T, now *time.Time, objs ...client.Object) *BackupVerificationReconciler {
+	t.Helper()
+	scheme := backupTestScheme(t)
+	cli := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&seaweedv1.BackupVerification{}, &seaweedv1.SeaweedBackup{},
+			&seaweedv1.SeaweedRestore{}, &seaweedv1.Seaweed{}, &batchv1.Job{}).
+		Build()
+	return &BackupVerificationReconciler{
+		Client:          cli,
+		Log:             logf.Log,
+		Scheme:          scheme,
+		Recorder:        record.NewFakeRecorder(20),
+		BackupToolImage: "operator:test",
+		Now:             func() time.Time { return *now },
+	}
+}
+
+func completedBackupAt(name string, at time.Time) *seaweedv1.SeaweedBackup {
+	b := completedBackup(name)
+	completed := metav1.NewTime(at)
+	b.Status.CompletionTime = &completed
+	return b
+}
+
+func TestVerificationDrillPassesAndTearsDown(t *testing.T) {
+	now := time.Date(2026, 6, 21, 6, 0, 0, 0, time.UTC)
+	v := &seaweedv1.BackupVerification{
+		ObjectMeta: metav1.ObjectMeta{Name: "vf1", Namespace: "ns1"},
+		Spec:       seaweedv1.BackupVerificationSpec{ClusterName: "c1", SamplePaths: []string{"/buckets/b1"}, MinEntries: 1},
+	}
+	r := newVerificationReconciler(t, &now, clusterWithFilesystemStorage(), v,
+		completedBackupAt("bk-old", now.Add(-48*time.Hour)), completedBackupAt("bk-new", now.Add(-24*time.Hour)))
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "vf1"}}
+	reconcile := func() seaweedv1.BackupVerification {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+		var got seaweedv1.BackupVerification
+		if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	got := reconcile()
+	if got.Status.Phase != seaweedv1.VerificationPhaseRunning || got.Status.BackupName != "bk-new" {
+		t.Fatalf("status = %+v, want Running against the latest completed backup", got.Status)
+	}
+	var scratch seaweedv1.Seaweed
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: got.Status.ScratchCluster}, &scratch); err != nil {
+		t.Fatalf("expected scratch cluster: %v", err)
+	}
+	if scratch.Spec.Master.Replicas != 1 || scratch.Spec.Filer.Replicas != 1 || scratch.Spec.Volume != nil {
+		t.Errorf("scratch cluster is not minimal: %+v", scratch.Spec)
+	}
+	if _, ok := scratch.Spec.Backup.Storages["pvc"]; !ok || len(scratch.Spec.Backup.Storages) != 1 {
+		t.Errorf("scratch storages = %v, want just the backup's", scratch.Spec.Backup.Storages)
+	}
+	if len(scratch.OwnerReferences) != 1 || scratch.OwnerReferences[0].Name != "vf1" {
+		t.Errorf("scratch cluster owners = %+v", scratch.OwnerReferences)
+	}
+
+	// The restore waits for the scratch cluster to come up.
+	reconcile()
+	var restore seaweedv1.SeaweedRestore
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: got.Status.RestoreName}, &restore); !apierrors.IsNotFound(err) {
+		t.Fatalf("restore created before the scratch cluster was ready: %v", err)
+	}
+	scratch.Status.Master.ReadyReplicas = 1
+	scratch.Status.Filer.ReadyReplicas = 1
+	if err := r.Status().Update(ctx, &scratch); err != nil {
+		t.Fatal(err)
+	}
+	reconcile()
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: got.Status.RestoreName}, &restore); err != nil {
+		t.Fatalf("expected restore: %v", err)
+	}
+	if restore.Spec.ClusterName != scratch.Name || restore.Spec.BackupName != "bk-new" {
+		t.Errorf("restore spec = %+v, want bk-new into the scratch cluster", restore.Spec)
+	}
+
+	restore.Status.Phase = seaweedv1.RestorePhaseCompleted
+	if err := r.Status().Update(ctx, &restore); err != nil {
+		t.Fatal(err)
+	}
+	reconcile()
+	var job batchv1.Job
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: got.Status.JobName}, &job); err != nil {
+		t.Fatalf("expected check job: %v", err)
+	}
+	cmd := strings.Join(job.Spec.Template.Spec.Containers[0].Command, " ")
+	if !containsAll(cmd, "metadata-check", "-filer="+getFilerAddress(&scratch), "-sample=/buckets/b1", "-minEntries=1", "-excludePrefix=/.seaweedfs-operator") {
+		t.Errorf("check command = %s", cmd)
+	}
+
+	now = now.Add(4 * time.Minute)
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	if err := r.Status().Update(ctx, &job); err != nil {
+		t.Fatal(err)
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: job.Name + "-x", Namespace: "ns1", Labels: map[string]string{"job-name": job.Name}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name:  verificationCheckContainer,
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: `{"entries":42,"directories":5,"sampled":1}`}},
+		}}},
+	}
+	if err := r.Create(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	got = reconcile()
+	if got.Status.Phase != seaweedv1.VerificationPhasePassed || got.Status.Entries != 42 {
+		t.Fatalf("status = %+v, want Passed with 42 entries", got.Status)
+	}
+	if got.Status.Duration == nil || got.Status.Duration.Duration != 4*time.Minute {
+		t.Errorf("duration = %v, want 4m", got.Status.Duration)
+	}
+
+	// Record on the backup, then tear the scratch cluster down.
+	reconcile()
+	got = reconcile()
+	var backup seaweedv1.SeaweedBackup
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "bk-new"}, &backup); err != nil {
+		t.Fatal(err)
+	}
+	if res := backup.Status.Verification; res == nil || res.Name != "vf1" || res.Phase != seaweedv1.VerificationPhasePassed || res.Entries != 42 {
+		t.Errorf("backup verification = %+v", backup.Status.Verification)
+	}
+	for _, obj := range []client.Object{&seaweedv1.Seaweed{}, &seaweedv1.SeaweedRestore{}} {
+		name := got.Status.ScratchCluster
+		if _, ok := obj.(*seaweedv1.SeaweedRestore); ok {
+			name = got.Status.RestoreName
+		}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: name}, obj); !apierrors.IsNotFound(err) {
+			t.Errorf("%s was not deleted: %v", name, err)
+		}
+	}
+	if !meta.IsStatusConditionTrue(got.Status.Conditions, seaweedv1.VerificationConditionCleanedUp) {
+		t.Errorf("CleanedUp condition = %+v", meta.FindStatusCondition(got.Status.Conditions, seaweedv1.VerificationConditionCleanedUp))
+	}
+}
+
+func TestVerificationTimesOut(t *testing.T) {
+	now := time.Date(2026, 6, 21, 6, 0, 0, 0, time.UTC)
+	v := &seaweedv1.BackupVerification{
+		ObjectMeta: metav1.ObjectMeta{Name: "vf1", Namespace: "ns1"},
+		Spec: seaweedv1.BackupVerificationSpec{ClusterName: "c1", BackupName: "bk1",
+			Timeout: &metav1.Duration{Duration: 10 * time.Minute}},
+	}
+	r := newVerificationReconciler(t, &now, clusterWithFilesystemStorage(), v, completedBackupAt("bk1", now))
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "vf1"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(11 * time.Minute)
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got seaweedv1.BackupVerification
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	c := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.VerificationConditionChecked)
+	if got.Status.Phase != seaweedv1.VerificationPhaseFailed || c == nil || c.Reason != "TimedOut" {
+		t.Fatalf("status = %+v, want Failed with reason TimedOut", got.Status)
+	}
+	var backup seaweedv1.SeaweedBackup
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: "bk1"}, &backup); err != nil {
+		t.Fatal(err)
+	}
+	if res := backup.Status.Verification; res == nil || res.Phase != seaweedv1.VerificationPhaseFailed || res.Message == "" {
+		t.Errorf("backup verification = %+v, want a recorded failure", backup.Status.Verification)
+	}
+}
+
+func TestVerificationOfFailedBackup(t *testing.T) {
+	now := time.Date(2026, 6, 21, 6, 0, 0, 0, time.UTC)
+	failed := completedBackup("bk1")
+	failed.Status.Phase = seaweedv1.BackupPhaseFailed
+	v := &seaweedv1.BackupVerification{
+		ObjectMeta: metav1.ObjectMeta{Name: "vf1", Namespace: "ns1"},
+		Spec:       seaweedv1.BackupVerificationSpec{ClusterName: "c1", BackupName: "bk1"},
+	}
+	r := newVerificationReconciler(t, &now, clusterWithFilesystemStorage(), v, failed)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "vf1"}}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	var got seaweedv1.BackupVerification
+	if err := r.Get(context.Background(), req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != seaweedv1.VerificationPhaseFailed || got.Status.ScratchCluster != "" {
+		t.Errorf("status = %+v, want Failed without a scratch cluster", got.Status)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	"google.golang.org/grpc"
@@ -72,6 +73,44 @@ type BucketAdmin interface {
 	// buckets that exist on the filer but have no objects yet may be
 	// absent from the map.
 	ListCollectionStats(ctx context.Context) (map[string]BucketCollectionStats, error)
+	// GetBucketConfig returns the bucket's stored CORS XML, policy JSON and
+	// tags.
+	GetBucketConfig(ctx context.Context, name string) (BucketConfig, error)
+	// SetBucketCORS stores the CORS configuration XML; empty xml clears it.
+	SetBucketCORS(ctx context.Context, name string, xml []byte) error
+	// SetBucketPolicy stores the bucket policy JSON; empty policy deletes it.
+	SetBucketPolicy(ctx context.Context, name string, policy []byte) error
+	// SetBucketTags replaces the bucket's tags; empty tags clears them.
+	SetBucketTags(ctx context.Context, name string, tags map[string]string) error
+	// GetCircuitBreaker returns the S3 gateways' circuit breaker
+	// configuration; the zero value when none is stored.
+	GetCircuitBreaker(ctx context.Context) (S3CircuitBreakerConfig, error)
+	// SetCircuitBreaker replaces the circuit breaker scope for the bucket,
+	// or the global scope when name is empty. nil opts deletes the scope.
+	SetCircuitBreaker(ctx context.Context, name string, opts *S3CircuitBreakerOptions) error
+	// FilerSignature returns the filer's signature, which filer.sync keys
+	// its per-source checkpoints by.
+	FilerSignature(ctx context.Context) (int32, error)
+	// GetFilerKV reads a key from the filer's key-value store; nil when the
+	// key is unset.
+	GetFilerKV(ctx context.Context, key []byte) ([]byte, error)
+	// GetObjectLockRetention returns the default retention of the bucket's
+	// object-lock configuration, or nil when none is set.
+	GetObjectLockRetention(ctx context.Context, name string) (*ObjectLockRetention, error)
+	// SetObjectLockRetention stores the bucket's default retention; nil
+	// removes it.
+	SetObjectLockRetention(ctx context.Context, name string, ret *ObjectLockRetention) error
+	// ScanRetention summarises the Object Lock retention and legal holds
+	// still in force on the bucket's objects.
+	ScanRetention(ctx context.Context, name string) (RetentionSummary, error)
+	// EnsureDirectory creates the filer directory path, owned by uid/gid,
+	// when it is missing and reports whether it did. Existing directories
+	// are left untouched.
+	EnsureDirectory(ctx context.Context, path string, uid, gid uint32) (bool, error)
+	// SetPathReadOnly sets or clears the readOnly flag of the filer.conf
+	// rule for the location prefix, keeping the rule's other settings, and
+	// reports whether the flag changed.
+	SetPathReadOnly(ctx context.Context, prefix string, readOnly bool) (bool, error)
 }
 
 // BucketCollectionStats is the subset of `collection.list` output the
@@ -279,6 +318,92 @@ func (a *swadminBucketAdmin) ListCollectionStats(ctx context.Context) (map[strin
 	return parseCollectionListOutput(out), nil
 }
 
+func (a *swadminBucketAdmin) GetBucketConfig(ctx context.Context, name string) (BucketConfig, error) {
+	cfg, err := a.sa.GetBucketConfig(ctx, name)
+	if errors.Is(err, swadmin.ErrBucketNotFound) {
+		return BucketConfig{}, ErrBucketNotFound
+	}
+	return BucketConfig{CORS: cfg.CORS, Policy: cfg.Policy, Tags: cfg.Tags}, err
+}
+
+func (a *swadminBucketAdmin) SetBucketCORS(ctx context.Context, name string, xml []byte) error {
+	return bucketNotFoundErr(a.sa.SetBucketCORS(ctx, name, xml))
+}
+
+func (a *swadminBucketAdmin) SetBucketPolicy(ctx context.Context, name string, policy []byte) error {
+	return bucketNotFoundErr(a.sa.SetBucketPolicy(ctx, name, policy))
+}
+
+func (a *swadminBucketAdmin) SetBucketTags(ctx context.Context, name string, tags map[string]string) error {
+	return bucketNotFoundErr(a.sa.SetBucketTags(ctx, name, tags))
+}
+
+// bucketNotFoundErr maps swadmin's missing-bucket sentinel onto ours.
+func bucketNotFoundErr(err error) error {
+	if errors.Is(err, swadmin.ErrBucketNotFound) {
+		return ErrBucketNotFound
+	}
+	return err
+}
+
+func (a *swadminBucketAdmin) GetCircuitBreaker(ctx context.Context) (S3CircuitBreakerConfig, error) {
+	data, err := a.sa.GetS3CircuitBreakerConfig(ctx)
+	if err != nil {
+		return S3CircuitBreakerConfig{}, err
+	}
+	return parseS3CircuitBreakerConfig(data)
+}
+
+func (a *swadminBucketAdmin) SetCircuitBreaker(ctx context.Context, name string, opts *S3CircuitBreakerOptions) error {
+	return a.sa.UpdateS3CircuitBreakerConfig(ctx, func(current []byte) ([]byte, error) {
+		cfg, err := parseS3CircuitBreakerConfig(current)
+		if err != nil {
+			return nil, err
+		}
+		return marshalS3CircuitBreakerConfig(withCircuitBreakerScope(cfg, name, opts))
+	})
+}
+
+func (a *swadminBucketAdmin) FilerSignature(ctx context.Context) (int32, error) {
+	return a.sa.FilerSignature(ctx)
+}
+
+func (a *swadminBucketAdmin) GetFilerKV(ctx context.Context, key []byte) ([]byte, error) {
+	return a.sa.KvGet(ctx, key)
+}
+
+func (a *swadminBucketAdmin) GetObjectLockRetention(ctx context.Context, name string) (*ObjectLockRetention, error) {
+	ret, err := a.sa.GetObjectLockRetention(ctx, name)
+	if err != nil || ret == nil {
+		return nil, bucketNotFoundErr(err)
+	}
+	return &ObjectLockRetention{Mode: ret.Mode, Days: ret.Days, Years: ret.Years}, nil
+}
+
+func (a *swadminBucketAdmin) SetObjectLockRetention(ctx context.Context, name string, ret *ObjectLockRetention) error {
+	var stored *swadmin.ObjectLockRetention
+	if ret != nil {
+		stored = &swadmin.ObjectLockRetention{Mode: ret.Mode, Days: ret.Days, Years: ret.Years}
+	}
+	return bucketNotFoundErr(a.sa.SetObjectLockRetention(ctx, name, stored))
+}
+
+func (a *swadminBucketAdmin) ScanRetention(ctx context.Context, name string) (RetentionSummary, error) {
+	sum, err := a.sa.ScanRetention(ctx, name, time.Now())
+	if err != nil {
+		return RetentionSummary{}, bucketNotFoundErr(err)
+	}
+	return RetentionSummary{EarliestExpiry: sum.EarliestExpiry, LegalHold: sum.LegalHold, Truncated: sum.Truncated}, nil
+}
+
+func (a *swadminBucketAdmin) EnsureDirectory(ctx context.Context, path string, uid, gid uint32) (bool, error) {
+	return a.sa.EnsureDirectory(ctx, path, uid, gid)
+}
+
+func (a *swadminBucketAdmin) SetPathReadOnly(ctx context.Context, prefix string, readOnly bool) (bool, error) {
+	return a.sa.SetPathReadOnly(ctx, prefix, readOnly)
+}
+
 // collectionListLine matches the three fields the usage refresher cares
 // about — collection name, total size in bytes, total file count — out of
 // a single line of `collection.list` stdout. The `Total N collections.`
@@ -0,0 +1,329 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// Bucket CORS, policy and tags reconciliation.
+//
+// These live as extended attributes on the bucket's filer entry, written
+// directly rather than through the S3 API — so the checks the S3 gateway
+// would run on Put* happen here instead, and a spec that fails them is
+// reported on a *Rejected condition and left unapplied.
+
+// BucketConfig is a bucket's CORS configuration XML, policy JSON and tags as
+// stored on the filer. Unset sub-resources are nil.
+type BucketConfig struct {
+	CORS   []byte
+	Policy []byte
+	Tags   map[string]string
+}
+
+// The structs below mirror the S3 CORSConfiguration XML.
+
+type corsConfiguration struct {
+	XMLName xml.Name   `xml:"CORSConfiguration"`
+	Rules   []corsRule `xml:"CORSRule"`
+}
+
+type corsRule struct {
+	ID             string   `xml:"ID,omitempty"`
+	AllowedHeaders []string `xml:"AllowedHeader,omitempty"`
+	AllowedMethods []string `xml:"AllowedMethod"`
+	AllowedOrigins []string `xml:"AllowedOrigin"`
+	ExposeHeaders  []string `xml:"ExposeHeader,omitempty"`
+	MaxAgeSeconds  *int32   `xml:"MaxAgeSeconds,omitempty"`
+}
+
+// buildCORSXML renders the CORS rules as a CORSConfiguration document, or nil
+// for no rules. Origins and allowed headers may carry at most one "*".
+func buildCORSXML(rules []seaweedv1.BucketCORSRule) ([]byte, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	cfg := corsConfiguration{Rules: make([]corsRule, 0, len(rules))}
+	for i, r := range rules {
+		for _, origin := range r.AllowedOrigins {
+			if strings.Count(origin, "*") > 1 {
+				return nil, fmt.Errorf("rule %d: allowed origin %q has more than one wildcard", i, origin)
+			}
+		}
+		for _, header := range r.AllowedHeaders {
+			if strings.Count(header, "*") > 1 {
+				return nil, fmt.Errorf("rule %d: allowed header %q has more than one wildcard", i, header)
+			}
+		}
+		methods := make([]string, len(r.AllowedMethods))
+		for j, m := range r.AllowedMethods {
+			methods[j] = string(m)
+		}
+		cfg.Rules = append(cfg.Rules, corsRule{
+			ID:             r.ID,
+			AllowedHeaders: r.AllowedHeaders,
+			AllowedMethods: methods,
+			AllowedOrigins: r.AllowedOrigins,
+			ExposeHeaders:  r.ExposeHeaders,
+			MaxAgeSeconds:  r.MaxAgeSeconds,
+		})
+	}
+	return xml.Marshal(cfg)
+}
+
+// corsConfigEqual compares two CORS documents by their parsed rules, so
+// formatting differences (e.g. one written through the S3 API) are not drift.
+// An unparsable current document is always drift.
+func corsConfigEqual(current, desired []byte) bool {
+	if len(current) == 0 || len(desired) == 0 {
+		return len(current) == len(desired)
+	}
+	var a, b corsConfiguration
+	if xml.Unmarshal(current, &a) != nil || xml.Unmarshal(desired, &b) != nil {
+		return false
+	}
+	a.XMLName, b.XMLName = xml.Name{}, xml.Name{}
+	return reflect.DeepEqual(a, b)
+}
+
+// normalizeBucketPolicy validates a bucket policy document for bucket and
+// returns it in canonical (compact, key-sorted) JSON.
+func normalizeBucketPolicy(doc, bucket string) ([]byte, error) {
+	var policy map[string]interface{}
+	if err := json.Unmarshal([]byte(doc), &policy); err != nil {
+		return nil, fmt.Errorf("not a JSON object: %w", err)
+	}
+	if v, ok := policy["Version"]; ok && v != "2012-10-17" && v != "2008-10-17" {
+		return nil, fmt.Errorf("unsupported Version %v", v)
+	}
+	var statements []interface{}
+	switch s := policy["Statement"].(type) {
+	case []interface{}:
+		statements = s
+	case map[string]interface{}:
+		statements = []interface{}{s}
+	}
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("Statement must list at least one statement")
+	}
+	bucketARN := "arn:aws:s3:::" + bucket
+	for i, raw := range statements {
+		st, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("statement %d is not an object", i)
+		}
+		if e := st["Effect"]; e != "Allow" && e != "Deny" {
+			return nil, fmt.Errorf("statement %d: Effect must be Allow or Deny", i)
+		}
+		if _, ok := st["Principal"]; !ok {
+			return nil, fmt.Errorf("statement %d: Principal is required", i)
+		}
+		if _, ok := st["Action"]; !ok {
+			return nil, fmt.Errorf("statement %d: Action is required", i)
+		}
+		resources, err := stringOrList(st["Resource"])
+		if err != nil || len(resources) == 0 {
+			return nil, fmt.Errorf("statement %d: Resource must name this bucket", i)
+		}
+		for _, res := range resources {
+			if res != bucketARN && !strings.HasPrefix(res, bucketARN+"/") {
+				return nil, fmt.Errorf("statement %d: resource %q is outside bucket %q", i, res, bucket)
+			}
+		}
+	}
+	return json.Marshal(policy)
+}
+
+// stringOrList reads a policy field that is either a string or a string list.
+func stringOrList(v interface{}) ([]string, error) {
+	switch t := v.(type) {
+	case string:
+		return []string{t}, nil
+	case []interface{}:
+		out := make([]string, 0, len(t))
+		for _, item := range t {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a string, got %T", item)
+			}
+			out = append(out, s)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("expected a string or list, got %T", v)
+}
+
+// bucketPolicyEqual compares the stored policy with the canonical desired
+// one. An unparsable stored policy is always drift.
+func bucketPolicyEqual(current, desired []byte) bool {
+	if len(current) == 0 || len(desired) == 0 {
+		return len(current) == len(desired)
+	}
+	var parsed interface{}
+	if json.Unmarshal(current, &parsed) != nil {
+		return false
+	}
+	canonical, err := json.Marshal(parsed)
+	return err == nil && string(canonical) == string(desired)
+}
+
+// bucketTagPattern is the character set S3 allows in tag keys and values.
+var bucketTagPattern = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
+// validateBucketTags applies the S3 tagging rules the CRD schema can't.
+func validateBucketTags(tags map[string]string) error {
+	for k, v := range tags {
+		if n := utf8.RuneCountInString(k); n == 0 || n > 128 {
+			return fmt.Errorf("tag key %q must be 1-128 characters", k)
+		}
+		if strings.HasPrefix(strings.ToLower(k), "aws:") {
+			return fmt.Errorf("tag key %q uses the reserved aws: prefix", k)
+		}
+		if utf8.RuneCountInString(v) > 256 {
+			return fmt.Errorf("tag %q value exceeds 256 characters", k)
+		}
+		if !bucketTagPattern.MatchString(k) || !bucketTagPattern.MatchString(v) {
+			return fmt.Errorf("tag %q contains characters S3 does not allow", k)
+		}
+	}
+	return nil
+}
+
+// bucketTagsEqual compares tag sets; nil and empty are equal.
+func bucketTagsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
+
+// reconcileBucketConfig applies spec.cors, spec.policy and spec.tags. Each
+// is written only on drift; one removed from spec is cleared only when
+// status.managedConfig shows the controller applied it. A sub-resource that
+// fails validation sets its *Rejected condition, keeps whatever is stored,
+// and fails the pass once the others are applied.
+func (r *BucketReconciler) reconcileBucketConfig(ctx context.Context, bucket *seaweedv1.Bucket, bucketName string, admin BucketAdmin) (bool, ctrl.Result, error) {
+	managed := map[seaweedv1.BucketConfigKind]bool{}
+	for _, kind := range bucket.Status.ManagedConfig {
+		managed[kind] = true
+	}
+	spec := bucket.Spec
+	if len(spec.CORS) == 0 && spec.Policy == "" && len(spec.Tags) == 0 && len(managed) == 0 {
+		// Nothing to manage — also the way out of a rejection whose spec
+		// was dropped before it ever applied.
+		r.clearCondition(bucket, seaweedv1.BucketConditionCORSRejected)
+		r.clearCondition(bucket, seaweedv1.BucketConditionPolicyRejected)
+		r.clearCondition(bucket, seaweedv1.BucketConditionTagsRejected)
+		return false, ctrl.Result{}, nil
+	}
+
+	current, err := admin.GetBucketConfig(ctx, bucketName)
+	if err != nil {
+		res, err := r.failPhase(ctx, bucket, seaweedv1.BucketPhaseFailed, "BucketConfigReadFailed", err.Error())
+		return true, res, err
+	}
+	var rejected []string
+	reject := func(kind seaweedv1.BucketConfigKind, condType string, err error) {
+		r.setCondition(bucket, condType, metav1.ConditionTrue, "Invalid", err.Error())
+		rejected = append(rejected, fmt.Sprintf("%s: %v", kind, err))
+	}
+
+	// CORS.
+	switch desired, err := buildCORSXML(spec.CORS); {
+	case err != nil:
+		reject(seaweedv1.BucketConfigCORS, seaweedv1.BucketConditionCORSRejected, err)
+	case desired != nil || managed[seaweedv1.BucketConfigCORS]:
+		if !corsConfigEqual(current.CORS, desired) {
+			if err := admin.SetBucketCORS(ctx, bucketName, desired); err != nil {
+				res, err := r.failPhase(ctx, bucket, seaweedv1.BucketPhaseFailed, "CORSFailed", err.Error())
+				return true, res, err
+			}
+		}
+		managed[seaweedv1.BucketConfigCORS] = desired != nil
+		r.clearCondition(bucket, seaweedv1.BucketConditionCORSRejected)
+	default:
+		r.clearCondition(bucket, seaweedv1.BucketConditionCORSRejected)
+	}
+
+	// Policy.
+	var desiredPolicy []byte
+	var policyErr error
+	if spec.Policy != "" {
+		desiredPolicy, policyErr = normalizeBucketPolicy(spec.Policy, bucketName)
+	}
+	switch {
+	case policyErr != nil:
+		reject(seaweedv1.BucketConfigPolicy, seaweedv1.BucketConditionPolicyRejected, policyErr)
+	case desiredPolicy != nil || managed[seaweedv1.BucketConfigPolicy]:
+		if !bucketPolicyEqual(current.Policy, desiredPolicy) {
+			if err := admin.SetBucketPolicy(ctx, bucketName, desiredPolicy); err != nil {
+				res, err := r.failPhase(ctx, bucket, seaweedv1.BucketPhaseFailed, "PolicyFailed", err.Error())
+				return true, res, err
+			}
+		}
+		managed[seaweedv1.BucketConfigPolicy] = desiredPolicy != nil
+		r.clearCondition(bucket, seaweedv1.BucketConditionPolicyRejected)
+	default:
+		r.clearCondition(bucket, seaweedv1.BucketConditionPolicyRejected)
+	}
+
+	// Tags.
+	switch err := validateBucketTags(spec.Tags); {
+	case err != nil:
+		reject(seaweedv1.BucketConfigTags, seaweedv1.BucketConditionTagsRejected, err)
+	case len(spec.Tags) > 0 || managed[seaweedv1.BucketConfigTags]:
+		if !bucketTagsEqual(current.Tags, spec.Tags) {
+			if err := admin.SetBucketTags(ctx, bucketName, spec.Tags); err != nil {
+				res, err := r.failPhase(ctx, bucket, seaweedv1.BucketPhaseFailed, "TagsFailed", err.Error())
+				return true, res, err
+			}
+		}
+		managed[seaweedv1.BucketConfigTags] = len(spec.Tags) > 0
+		r.clearCondition(bucket, seaweedv1.BucketConditionTagsRejected)
+	default:
+		r.clearCondition(bucket, seaweedv1.BucketConditionTagsRejected)
+	}
+
+	bucket.Status.ManagedConfig = nil
+	for _, kind := range []seaweedv1.BucketConfigKind{seaweedv1.BucketConfigCORS, seaweedv1.BucketConfigPolicy, seaweedv1.BucketConfigTags} {
+		if managed[kind] {
+			bucket.Status.ManagedConfig = append(bucket.Status.ManagedConfig, kind)
+		}
+	}
+	if len(rejected) > 0 {
+		res, err := r.failPhase(ctx, bucket, seaweedv1.BucketPhaseFailed, "ConfigurationRejected", strings.Join(rejected, "; "))
+		return true, res, err
+	}
+	return false, ctrl.Result{}, nil
+}
@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+func TestBuildCORSXML(t *testing.T) {
+	maxAge := int32(3000)
+	got, err := buildCORSXML([]seaweedv1.BucketCORSRule{{
+		AllowedOrigins: []string{"https://*.example.com"},
+		AllowedMethods: []seaweedv1.BucketCORSMethod{"GET", "PUT"},
+		AllowedHeaders: []string{"*"},
+		MaxAgeSeconds:  &maxAge,
+	}})
+	if err != nil {
+		t.Fatalf("buildCORSXML: %v", err)
+	}
+	want := `<CORSConfiguration><CORSRule><AllowedHeader>*</AllowedHeader><AllowedMethod>GET</AllowedMethod><AllowedMethod>PUT</AllowedMethod>` +
+		`<AllowedOrigin>https://*.example.com</AllowedOrigin><MaxAgeSeconds>3000</MaxAgeSeconds></CORSRule></CORSConfiguration>`
+	if string(got) != want {
+		t.Errorf("xml =\n%s\nwant\n%s", got, want)
+	}
+
+	// Reformatted but equivalent XML is not drift.
+	if !corsConfigEqual([]byte(strings.ReplaceAll(want, "><", ">\n  <")), got) {
+		t.Errorf("equivalent CORS documents reported as drift")
+	}
+
+	if _, err := buildCORSXML([]seaweedv1.BucketCORSRule{{
+		AllowedOrigins: []string{"https://*.*.example.com"},
+		AllowedMethods: []seaweedv1.BucketCORSMethod{"GET"},
+	}}); err == nil {
+		t.Errorf("expected an error for an origin with two wildcards")
+	}
+}
+
+func TestNormalizeBucketPolicy(t *testing.T) {
+	valid := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::photos/*"}]}`
+	got, err := normalizeBucketPolicy(valid, "photos")
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	// Key order and whitespace do not count as drift.
+	stored := `{
+  "Statement": [{"Resource": "arn:aws:s3:::photos/*", "Action": "s3:GetObject", "Principal": "*", "Effect": "Allow"}],
+  "Version": "2012-10-17"
+}`
+	if !bucketPolicyEqual([]byte(stored), got) {
+		t.Errorf("reformatted policy reported as drift: %s", got)
+	}
+
+	rejected := map[string]string{
+		"not json":         `{`,
+		"other bucket":     `{"Statement":{"Effect":"Allow","Principal":"*","Action":"s3:*","Resource":"arn:aws:s3:::videos/*"}}`,
+		"bad effect":       `{"Statement":[{"Effect":"Maybe","Principal":"*","Action":"s3:*","Resource":"arn:aws:s3:::photos"}]}`,
+		"no principal":     `{"Statement":[{"Effect":"Allow","Action":"s3:*","Resource":"arn:aws:s3:::photos"}]}`,
+		"no statements":    `{"Version":"2012-10-17","Statement":[]}`,
+		"bad version":      `{"Version":"2020-01-01","Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:*","Resource":"arn:aws:s3:::photos"}]}`,
+		"prefix lookalike": `{"Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:*","Resource":"arn:aws:s3:::photos-private/*"}]}`,
+	}
+	for name, doc := range rejected {
+		if _, err := normalizeBucketPolicy(doc, "photos"); err == nil {
+			t.Errorf("%s: expected rejection", name)
+		}
+	}
+}
+
+func TestValidateBucketTags(t *testing.T) {
+	if err := validateBucketTags(map[string]string{"team": "media", "cost-center": "42 / eu"}); err != nil {
+		t.Errorf("valid tags rejected: %v", err)
+	}
+	for name, tags := range map[string]map[string]string{
+		"reserved prefix": {"aws:createdBy": "x"},
+		"long value":      {"k": strings.Repeat("v", 257)},
+		"bad character":   {"k": "a;b"},
+	} {
+		if err := validateBucketTags(tags); err == nil {
+			t.Errorf("%s: expected rejection", name)
+		}
+	}
+}
+
+func TestReconcile_BucketConfigAppliedAndDriftHealed(t *testing.T) {
+	bucket := newTestBucket("photos")
+	bucket.Spec.CORS = []seaweedv1.BucketCORSRule{{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []seaweedv1.BucketCORSMethod{"GET"},
+	}}
+	bucket.Spec.Policy = `{"Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::photos/*"}]}`
+	bucket.Spec.Tags = map[string]string{"team": "media"}
+
+	fa := newFakeAdmin()
+	r, cli := testReconciler(t, fa, newTestSeaweed(), bucket)
+	key := types.NamespacedName{Namespace: bucket.Namespace, Name: bucket.Name}
+
+	reconcileUntilStable(t, r, key, 5)
+	for _, want := range []string{"SetBucketCORS:photos:t", "SetBucketPolicy:photos:t", "SetBucketTags:photos:1"} {
+		if got := callsFor(fa.calls, want); len(got) != 1 {
+			t.Errorf("%s called %d times; calls=%v", want, len(got), fa.calls)
+		}
+	}
+	got := &seaweedv1.Bucket{}
+	if err := cli.Get(context.Background(), key, got); err != nil {
+		t.Fatalf("get bucket: %v", err)
+	}
+	wantManaged := []seaweedv1.BucketConfigKind{seaweedv1.BucketConfigCORS, seaweedv1.BucketConfigPolicy, seaweedv1.BucketConfigTags}
+	if !reflect.DeepEqual(got.Status.ManagedConfig, wantManaged) {
+		t.Errorf("managedConfig = %v want %v", got.Status.ManagedConfig, wantManaged)
+	}
+
+	// In sync: no writes.
+	fa.calls = nil
+	reconcileUntilStable(t, r, key, 5)
+	if got := callsFor(fa.calls, "SetBucket"); len(got) != 0 {
+		t.Errorf("unexpected writes without drift: %v", got)
+	}
+
+	// Tags edited out of band: only tags are rewritten.
+	fa.bucketConfig["photos"] = BucketConfig{
+		CORS:   fa.bucketConfig["photos"].CORS,
+		Policy: fa.bucketConfig["photos"].Policy,
+		Tags:   map[string]string{"team": "someone-else"},
+	}
+	reconcileUntilStable(t, r, key, 5)
+	if got := callsFor(fa.calls, "SetBucket"); !reflect.DeepEqual(got, []string{"SetBucketTags:photos:1"}) {
+		t.Errorf("drift writes = %v", got)
+	}
+}
+
+func TestReconcile_BucketConfigRemovedOnlyWhenManaged(t *testing.T) {
+	bucket := newTestBucket("photos")
+	bucket.Finalizers = []string{BucketFinalizer}
+	bucket.Status.BucketName = "photos"
+	bucket.Status.ManagedConfig = []seaweedv1.BucketConfigKind{seaweedv1.BucketConfigTags}
+
+	fa := newFakeAdmin()
+	fa.existsResp["photos"] = true
+	fa.bucketConfig = map[string]BucketConfig{"photos": {
+		CORS: []byte("<CORSConfiguration/>"), // set through the S3 API
+		Tags: map[string]string{"team": "media"},
+	}}
+	r, cli := testReconciler(t, fa, newTestSeaweed(), bucket)
+	key := types.NamespacedName{Namespace: bucket.Namespace, Name: bucket.Name}
+
+	reconcileUntilStable(t, r, key, 5)
+	if got := callsFor(fa.calls, "SetBucket"); !reflect.DeepEqual(got, []string{"SetBucketTags:photos:0"}) {
+		t.Errorf("writes = %v want only the managed tags cleared", got)
+	}
+	got := &seaweedv1.Bucket{}
+	if err := cli.Get(context.Background(), key, got); err != nil {
+		t.Fatalf("get bucket: %v", err)
+	}
+	if len(got.Status.ManagedConfig) != 0 {
+		t.Errorf("managedConfig = %v want empty", got.Status.ManagedConfig)
+	}
+}
+
+func TestReconcile_BucketPolicyRejected(t *testing.T) {
+	bucket := newTestBucket("photos")
+	bucket.Spec.Policy = `{"Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:*","Resource":"arn:aws:s3:::videos/*"}]}`
+	bucket.Spec.Tags = map[string]string{"team": "media"}
+
+	fa := newFakeAdmin()
+	r, cli := testReconciler(t, fa, newTestSeaweed(), bucket)
+	key := types.NamespacedName{Namespace: bucket.Namespace, Name: bucket.Name}
+
+	for i := 0; i < 3; i++ {
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+	}
+	if got := callsFor(fa.calls, "SetBucketPolicy"); len(got) != 0 {
+		t.Errorf("rejected policy was written: %v", got)
+	}
+	if got := callsFor(fa.calls, "SetBucketTags"); len(got) == 0 {
+		t.Errorf("valid tags should still apply alongside a rejected policy")
+	}
+	got := &seaweedv1.Bucket{}
+	if err := cli.Get(context.Background(), key, got); err != nil {
+		t.Fatalf("get bucket: %v", err)
+	}
+	if !meta.IsStatusConditionTrue(got.Status.Conditions, seaweedv1.BucketConditionPolicyRejected) {
+		t.Errorf("expected %s=True, got %+v", seaweedv1.BucketConditionPolicyRejected, got.Status.Conditions)
+	}
+	if got.Status.Phase != seaweedv1.BucketPhaseFailed {
+		t.Errorf("phase = %s want Failed", got.Status.Phase)
+	}
+
+	// Fixing the policy clears the condition.
+	got.Spec.Policy = `{"Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:*","Resource":"arn:aws:s3:::photos/*"}]}`
+	if err := cli.Update(context.Background(), got); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	reconcileUntilStable(t, r, key, 5)
+	if err := cli.Get(context.Background(), key, got); err != nil {
+		t.Fatalf("get bucket: %v", err)
+	}
+	if meta.FindStatusCondition(got.Status.Conditions, seaweedv1.BucketConditionPolicyRejected) != nil {
+		t.Errorf("PolicyRejected not cleared after the fix")
+	}
+}
@@ -278,6 +278,11 @@ func (r *BucketReconciler) reconcileBucket(ctx context.Context, bucket *seaweedv
 		}
 	}
 
+	// Default retention of the object-lock configuration.
+	if done, res, err := r.reconcileObjectLockRetention(ctx, bucket, bucketName, admin); done {
+		return res, err
+	}
+
 	// Quota.
 	if bucket.Spec.Quota != nil {
 		sizeMiB, err := quantityToMiB(bucket.Spec.Quota.Size)
@@ -359,6 +364,30 @@ func (r *BucketReconciler) reconcileBucket(ctx context.Context, bucket *seaweedv
 		}
 	}
 
+	// Rate limits (s3.circuitBreaker). Read the stored scope back and only
+	// rewrite it on drift; a removed spec deletes the scope once.
+	if bucket.Spec.RateLimits != nil || bucket.Status.RateLimits != nil {
+		want, err := circuitBreakerOptions(bucket.Spec.RateLimits)
+		if err != nil {
+			return r.failPhase(ctx, bucket, seaweedv1.BucketPhaseFailed, "RateLimitsInvalid", err.Error())
+		}
+		current, err := admin.GetCircuitBreaker(ctx)
+		if err != nil {
+			return r.failPhase(ctx, bucket, seaweedv1.BucketPhaseFailed, "RateLimitsFailed", err.Error())
+		}
+		if !circuitBreakerEqual(current.Buckets[bucketName], want) {
+			if err := admin.SetCircuitBreaker(ctx, bucketName, want); err != nil {
+				return r.failPhase(ctx, bucket, seaweedv1.BucketPhaseFailed, "RateLimitsFailed", err.Error())
+			}
+		}
+		bucket.Status.RateLimits = bucketStatusRateLimits(want)
+	}
+
+	// CORS, policy and tags (bucket entry extended attributes).
+	if done, res, err := r.reconcileBucketConfig(ctx, bucket, bucketName, admin); done {
+		return res, err
+	}
+
 	// Status: record observed state and mark Ready.
 	bucket.Status.BucketName = bucketName
 	bucket.Status.ObservedGeneration = bucket.Generation
@@ -413,14 +442,45 @@ func (r *BucketReconciler) handleDeletion(ctx context.Context, bucket *seaweedv1
 		err := admin.DeleteBucket(ctx, bucketName)
 		switch {
 		case err == nil, errors.Is(err, ErrBucketNotFound):
-			// Clean exit, fall through to finalizer removal.
+			// Clean exit, fall through to finalizer removal. The rate-limit
+			// scope would outlive the bucket and bind a future one of the
+			// same name, so drop it too.
+			if bucket.Status.RateLimits != nil {
+				if err := admin.SetCircuitBreaker(ctx, bucketName, nil); err != nil {
+					log.Error(err, "remove bucket rate limits")
+				}
+			}
 		case errors.Is(err, ErrRetentionBlocksDelete):
-			r.setCondition(bucket, seaweedv1.BucketConditionDeleteBlockedByRetention, metav1.ConditionTrue, "RetentionActive",
-				"bucket has objects under Object Lock retention or legal hold; flip reclaimPolicy to Retain and clear retention manually if you intend to delete")
+			// The block is already reported for this generation; the retry
+			// only waits for it to lift, and the scan below walks the whole
+			// bucket, so it runs again only once the reported expiry has
+			// passed and the report would be stale.
+			now := time.Now()
+			expiry := bucket.Status.RetentionExpiry
+			if cond := meta.FindStatusCondition(bucket.Status.Conditions, seaweedv1.BucketConditionDeleteBlockedByRetention); cond != nil &&
+				cond.Status == metav1.ConditionTrue && cond.ObservedGeneration == bucket.Generation &&
+				(expiry == nil || now.Before(expiry.Time)) {
+				return ctrl.Result{RequeueAfter: retentionRetryAfter(now, expiry)}, nil
+			}
+			msg := "bucket has objects under Object Lock retention or legal hold; flip reclaimPolicy to Retain and clear retention manually if you intend to delete"
+			// Say when the block lifts, best effort: the scan walks the
+			// whole bucket and a failure only costs the detail.
+			bucket.Status.RetentionExpiry = nil
+			if sum, scanErr := admin.ScanRetention(ctx, bucketName); scanErr != nil {
+				log.Error(scanErr, "scan object retention")
+			} else {
+				if detail := describeRetention(sum); detail != "" {
+					msg += "; " + detail
+				}
+				if !sum.EarliestExpiry.IsZero() {
+					bucket.Status.RetentionExpiry = &metav1.Time{Time: sum.EarliestExpiry}
+				}
+			}
+			r.setCondition(bucket, seaweedv1.BucketConditionDeleteBlockedByRetention, metav1.ConditionTrue, "RetentionActive", msg)
 			if updateErr := r.Status().Update(ctx, bucket); updateErr != nil {
 				log.Error(updateErr, "status update during deletion")
 			}
-			return ctrl.Result{RequeueAfter: requeueAfterTransient}, nil
+			return ctrl.Result{RequeueAfter: retentionRetryAfter(now, bucket.Status.RetentionExpiry)}, nil
 		default:
 			r.setCondition(bucket, seaweedv1.BucketConditionReady, metav1.ConditionFalse, "DeleteFailed", err.Error())
 			if updateErr := r.Status().Update(ctx, bucket); updateErr != nil {
@@ -437,6 +497,17 @@ func (r *BucketReconciler) handleDeletion(ctx context.Context, bucket *seaweedv1
 	return ctrl.Result{}, nil
 }
 
+// retentionRetryAfter is when to retry a delete blocked by retention: on the
+// usual transient cadence, or at the reported expiry when that comes sooner.
+func retentionRetryAfter(now time.Time, expiry *metav1.Time) time.Duration {
+	if expiry != nil {
+		if until := expiry.Sub(now); until > 0 && until < requeueAfterTransient {
+			return until
+		}
+	}
+	return requeueAfterTransient
+}
+
 // failPhase records the failure on Status, persists, and returns a
 // requeue with a nil error. Returning a non-nil error alongside
 // RequeueAfter would make controller-runtime ignore RequeueAfter and
@@ -66,6 +66,26 @@ type fakeBucketAdmin struct {
 	lifecycle    map[string][]byte
 	lifecycleErr error
 	ttlErr       error
+
+	circuitBreaker    S3CircuitBreakerConfig
+	circuitBreakerErr error
+
+	bucketConfig    map[string]BucketConfig
+	bucketConfigErr error
+
+	filerSignature int32
+	filerKV        map[string][]byte
+	filerKVErr     error
+
+	objectLockRetention map[string]*ObjectLockRetention
+	retentionSummary    RetentionSummary
+	retentionScanErr    error
+
+	directories  map[string]string
+	directoryErr error
+
+	readOnly    map[string]bool
+	readOnlyErr error
 }
 
 type closingFakeBucketAdmin struct {
@@ -171,6 +191,113 @@ func (f *fakeBucketAdmin) ListCollectionStats(_ context.Context) (map[string]Buc
 	}
 	return f.collectionStats, nil
 }
+func (f *fakeBucketAdmin) GetBucketConfig(_ context.Context, name string) (BucketConfig, error) {
+	f.record("GetBucketConfig:" + name)
+	return f.bucketConfig[name], f.bucketConfigErr
+}
+func (f *fakeBucketAdmin) updateBucketConfig(name string, mutate func(*BucketConfig)) error {
+	if f.bucketConfigErr != nil {
+		return f.bucketConfigErr
+	}
+	if f.bucketConfig == nil {
+		f.bucketConfig = map[string]BucketConfig{}
+	}
+	cfg := f.bucketConfig[name]
+	mutate(&cfg)
+	f.bucketConfig[name] = cfg
+	return nil
+}
+func (f *fakeBucketAdmin) SetBucketCORS(_ context.Context, name string, xml []byte) error {
+	f.record("SetBucketCORS:" + name + ":" + boolStr(len(xml) > 0))
+	return f.updateBucketConfig(name, func(c *BucketConfig) { c.CORS = xml })
+}
+func (f *fakeBucketAdmin) SetBucketPolicy(_ context.Context, name string, policy []byte) error {
+	f.record("SetBucketPolicy:" + name + ":" + boolStr(len(policy) > 0))
+	return f.updateBucketConfig(name, func(c *BucketConfig) { c.Policy = policy })
+}
+func (f *fakeBucketAdmin) SetBucketTags(_ context.Context, name string, tags map[string]string) error {
+	f.record("SetBucketTags:" + name + ":" + intStr(int64(len(tags))))
+	return f.updateBucketConfig(name, func(c *BucketConfig) { c.Tags = tags })
+}
+func (f *fakeBucketAdmin) GetCircuitBreaker(_ context.Context) (S3CircuitBreakerConfig, error) {
+	f.record("GetCircuitBreaker")
+	return f.circuitBreaker, f.circuitBreakerErr
+}
+func (f *fakeBucketAdmin) SetCircuitBreaker(_ context.Context, name string, opts *S3CircuitBreakerOptions) error {
+	f.record("SetCircuitBreaker:" + name + ":" + boolStr(opts != nil))
+	if f.circuitBreakerErr != nil {
+		return f.circuitBreakerErr
+	}
+	if name == "" {
+		f.circuitBreaker.Global = opts
+		return nil
+	}
+	if opts == nil {
+		delete(f.circuitBreaker.Buckets, name)
+		return nil
+	}
+	if f.circuitBreaker.Buckets == nil {
+		f.circuitBreaker.Buckets = map[string]*S3CircuitBreakerOptions{}
+	}
+	f.circuitBreaker.Buckets[name] = opts
+	return nil
+}
+
+func (f *fakeBucketAdmin) FilerSignature(_ context.Context) (int32, error) {
+	f.record("FilerSignature")
+	return f.filerSignature, nil
+}
+func (f *fakeBucketAdmin) GetFilerKV(_ context.Context, key []byte) ([]byte, error) {
+	f.record("GetFilerKV")
+	return f.filerKV[string(key)], f.filerKVErr
+}
+
+func (f *fakeBucketAdmin) GetObjectLockRetention(_ context.Context, name string) (*ObjectLockRetention, error) {
+	f.record("GetObjectLockRetention:" + name)
+	return f.objectLockRetention[name], nil
+}
+func (f *fakeBucketAdmin) SetObjectLockRetention(_ context.Context, name string, ret *ObjectLockRetention) error {
+	f.record("SetObjectLockRetention:" + name + ":" + boolStr(ret != nil))
+	if f.objectLockRetention == nil {
+		f.objectLockRetention = map[string]*ObjectLockRetention{}
+	}
+	f.objectLockRetention[name] = ret
+	return nil
+}
+func (f *fakeBucketAdmin) ScanRetention(_ context.Context, name string) (RetentionSummary, error) {
+	f.record("ScanRetention:" + name)
+	return f.retentionSummary, f.retentionScanErr
+}
+
+func (f *fakeBucketAdmin) EnsureDirectory(_ context.Context, path string, uid, gid uint32) (bool, error) {
+	f.record("EnsureDirectory:" + path)
+	if f.directoryErr != nil {
+		return false, f.directoryErr
+	}
+	if _, ok := f.directories[path]; ok {
+		return false, nil
+	}
+	if f.directories == nil {
+		f.directories = map[string]string{}
+	}
+	f.directories[path] = intStr(int64(uid)) + ":" + intStr(int64(gid))
+	return true, nil
+}
+
+func (f *fakeBucketAdmin) SetPathReadOnly(_ context.Context, prefix string, readOnly bool) (bool, error) {
+	f.record("SetPathReadOnly:" + prefix + ":" + boolStr(readOnly))
+	if f.readOnlyErr != nil {
+		return false, f.readOnlyErr
+	}
+	if f.readOnly[prefix] == readOnly {
+		return false, nil
+	}
+	if f.readOnly == nil {
+		f.readOnly = map[string]bool{}
+	}
+	f.readOnly[prefix] = readOnly
+	return true, nil
+}
 
 func boolStr(b bool) string {
 	if b {
@@ -983,6 +1110,7 @@ func TestReconcile_DeleteBlockedByRetention_KeepsFinalizer(t *testing.T) {
 	fa := newFakeAdmin()
 	fa.existsResp["photos"] = true
 	fa.deleteErr = ErrRetentionBlocksDelete
+	fa.retentionSummary = RetentionSummary{EarliestExpiry: time.Date(2031, 3, 1, 12, 0, 0, 0, time.UTC), LegalHold: true}
 	r, cli := testReconciler(t, fa, newTestSeaweed(), bucket)
 	key := types.NamespacedName{Namespace: bucket.Namespace, Name: bucket.Name}
 
@@ -1004,6 +1132,58 @@ func TestReconcile_DeleteBlockedByRetention_KeepsFinalizer(t *testing.T) {
 	cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.BucketConditionDeleteBlockedByRetention)
 	if cond == nil || cond.Status != metav1.ConditionTrue {
 		t.Errorf("expected DeleteBlockedByRetention=True, got %+v", cond)
+	} else if !strings.Contains(cond.Message, "earliest retention expires at 2031-03-01T12:00:00Z") ||
+		!strings.Contains(cond.Message, "legal hold") {
+		t.Errorf("condition message should report the earliest expiry and legal hold: %q", cond.Message)
+	}
+
+	// Retries keep waiting on the block without walking the bucket again.
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := callsFor(fa.calls, "ScanRetention:"); len(got) != 1 {
+		t.Errorf("expected a single retention scan across retries, got %v", got)
+	}
+
+	// Once the reported expiry passes, the next retry scans again and
+	// reports the block that remains.
+	if err := cli.Get(context.Background(), key, got); err != nil {
+		t.Fatalf("get bucket: %v", err)
+	}
+	if got.Status.RetentionExpiry == nil || !got.Status.RetentionExpiry.Equal(&metav1.Time{Time: fa.retentionSummary.EarliestExpiry}) {
+		t.Fatalf("retentionExpiry = %v, want the scanned expiry", got.Status.RetentionExpiry)
+	}
+	got.Status.RetentionExpiry = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+	if err := cli.Status().Update(context.Background(), got); err != nil {
+		t.Fatalf("update status: %v", err)
+	}
+	fa.retentionSummary = RetentionSummary{EarliestExpiry: time.Date(2032, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := callsFor(fa.calls, "ScanRetention:"); len(got) != 2 {
+		t.Errorf("expected a rescan once the expiry passed, got %v", got)
+	}
+	if err := cli.Get(context.Background(), key, got); err != nil {
+		t.Fatalf("get bucket: %v", err)
+	}
+	cond = meta.FindStatusCondition(got.Status.Conditions, seaweedv1.BucketConditionDeleteBlockedByRetention)
+	if cond == nil || !strings.Contains(cond.Message, "2032-01-01T00:00:00Z") {
+		t.Errorf("condition should report the new expiry: %+v", cond)
+	}
+}
+
+func TestRetentionRetryAfter(t *testing.T) {
+	now := time.Now()
+	soon := &metav1.Time{Time: now.Add(time.Second)}
+	if got := retentionRetryAfter(now, soon); got != time.Second {
+		t.Errorf("expiry within the transient interval: got %v, want 1s", got)
+	}
+	if got := retentionRetryAfter(now, nil); got != requeueAfterTransient {
+		t.Errorf("no expiry: got %v, want %v", got, requeueAfterTransient)
+	}
+	if got := retentionRetryAfter(now, &metav1.Time{Time: now.Add(-time.Hour)}); got != requeueAfterTransient {
+		t.Errorf("past expiry: got %v, want %v", got, requeueAfterTransient)
 	}
 }
 
@@ -0,0 +1,165 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// daysPerRetentionYear converts a retention period in years to days when the
+// two forms are compared, matching the CEL guard on BucketSpec.
+const daysPerRetentionYear = 365
+
+// ObjectLockRetention is a bucket's default Object Lock retention as stored
+// on the filer. Exactly one of Days or Years is non-zero.
+type ObjectLockRetention struct {
+	Mode  string
+	Days  int32
+	Years int32
+}
+
+// periodDays is the retention period in days.
+func (r ObjectLockRetention) periodDays() int64 {
+	if r.Days > 0 {
+		return int64(r.Days)
+	}
+	return int64(r.Years) * daysPerRetentionYear
+}
+
+// String renders the retention as e.g. "COMPLIANCE for 7 years".
+func (r ObjectLockRetention) String() string {
+	if r.Days > 0 {
+		return fmt.Sprintf("%s for %d days", r.Mode, r.Days)
+	}
+	return fmt.Sprintf("%s for %d years", r.Mode, r.Years)
+}
+
+// RetentionSummary describes the objects in a bucket still protected by
+// Object Lock.
+type RetentionSummary struct {
+	// EarliestExpiry is the soonest retain-until date still in the future;
+	// zero when no object is under retention.
+	EarliestExpiry time.Time
+	// LegalHold reports whether any object is under legal hold.
+	LegalHold bool
+	// Truncated reports that the scan stopped before visiting every object,
+	// so EarliestExpiry covers only part of the bucket.
+	Truncated bool
+}
+
+// objectLockRetentionFromSpec converts the spec's default retention; nil when
+// unset.
+func objectLockRetentionFromSpec(cfg *seaweedv1.BucketObjectLockConfiguration) *ObjectLockRetention {
+	if cfg == nil || cfg.DefaultRetention == nil {
+		return nil
+	}
+	dr := cfg.DefaultRetention
+	ret := &ObjectLockRetention{Mode: string(dr.Mode)}
+	if dr.Days != nil {
+		ret.Days = *dr.Days
+	} else if dr.Years != nil {
+		ret.Years = *dr.Years
+	}
+	return ret
+}
+
+// objectLockRetentionEqual compares two possibly-unset retentions.
+func objectLockRetentionEqual(a, b *ObjectLockRetention) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// checkComplianceRetention refuses a desired default retention that would
+// weaken a COMPLIANCE one already on the bucket: removing it, switching it to
+// GOVERNANCE, or shortening its period. Admission enforces the same against
+// the previous spec; this also covers adopted buckets and out-of-band writes.
+func checkComplianceRetention(current, desired *ObjectLockRetention) error {
+	if current == nil || current.Mode != string(seaweedv1.ObjectLockCompliance) {
+		return nil
+	}
+	switch {
+	case desired == nil:
+		return fmt.Errorf("bucket has a default retention of %s, which cannot be removed", current)
+	case desired.Mode != string(seaweedv1.ObjectLockCompliance):
+		return fmt.Errorf("bucket has a default retention of %s, which cannot be switched to %s", current, desired.Mode)
+	case desired.periodDays() < current.periodDays():
+		return fmt.Errorf("bucket has a default retention of %s, which cannot be shortened to %s", current, desired)
+	}
+	return nil
+}
+
+// reconcileObjectLockRetention applies spec.objectLockConfiguration's default
+// retention, writing it only on drift. A retention removed from spec is
+// cleared only when status shows the controller applied it.
+func (r *BucketReconciler) reconcileObjectLockRetention(ctx context.Context, bucket *seaweedv1.Bucket, bucketName string, admin BucketAdmin) (bool, ctrl.Result, error) {
+	desired := objectLockRetentionFromSpec(bucket.Spec.ObjectLockConfiguration)
+	if desired == nil && bucket.Status.ObjectLockDefaultRetention == nil {
+		r.clearCondition(bucket, seaweedv1.BucketConditionObjectLockRetentionRejected)
+		return false, ctrl.Result{}, nil
+	}
+
+	current, err := admin.GetObjectLockRetention(ctx, bucketName)
+	if err != nil {
+		res, err := r.failPhase(ctx, bucket, seaweedv1.BucketPhaseFailed, "ObjectLockRetentionReadFailed", err.Error())
+		return true, res, err
+	}
+	if err := checkComplianceRetention(current, desired); err != nil {
+		r.setCondition(bucket, seaweedv1.BucketConditionObjectLockRetentionRejected, metav1.ConditionTrue, "ComplianceRetention", err.Error())
+		res, err := r.failPhase(ctx, bucket, seaweedv1.BucketPhaseFailed, "ObjectLockRetentionRejected", err.Error())
+		return true, res, err
+	}
+	if !objectLockRetentionEqual(current, desired) {
+		if err := admin.SetObjectLockRetention(ctx, bucketName, desired); err != nil {
+			res, err := r.failPhase(ctx, bucket, seaweedv1.BucketPhaseFailed, "ObjectLockRetentionFailed", err.Error())
+			return true, res, err
+		}
+	}
+	if desired == nil {
+		bucket.Status.ObjectLockDefaultRetention = nil
+	} else {
+		bucket.Status.ObjectLockDefaultRetention = bucket.Spec.ObjectLockConfiguration.DefaultRetention.DeepCopy()
+	}
+	r.clearCondition(bucket, seaweedv1.BucketConditionObjectLockRetentionRejected)
+	return false, ctrl.Result{}, nil
+}
+
+// describeRetention renders a retention scan for the DeleteBlockedByRetention
+// message; empty when the scan found nothing in force.
+func describeRetention(sum RetentionSummary) string {
+	var parts []string
+	if !sum.EarliestExpiry.IsZero() {
+		expiry := "earliest retention expires at " + sum.EarliestExpiry.UTC().Format(time.RFC3339)
+		if sum.Truncated {
+			expiry += " (among the objects scanned; the bucket is too large to scan fully)"
+		}
+		parts = append(parts, expiry)
+	}
+	if sum.LegalHold {
+		parts = append(parts, "some objects are under legal hold, which does not expire")
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+func TestCheckComplianceRetention(t *testing.T) {
+	compliance := &ObjectLockRetention{Mode: "COMPLIANCE", Years: 1}
+	cases := []struct {
+		name             string
+		current, desired *ObjectLockRetention
+		wantErr          bool
+	}{
+		{"nothing stored", nil, nil, false},
+		{"governance can be removed", &ObjectLockRetention{Mode: "GOVERNANCE", Days: 30}, nil, false},
+		{"governance can be shortened", &ObjectLockRetention{Mode: "GOVERNANCE", Days: 30}, &ObjectLockRetention{Mode: "GOVERNANCE", Days: 1}, false},
+		{"compliance kept", compliance, compliance, false},
+		{"compliance lengthened", compliance, &ObjectLockRetention{Mode: "COMPLIANCE", Days: 400}, false},
+		{"compliance same period in days", compliance, &ObjectLockRetention{Mode: "COMPLIANCE", Days: 365}, false},
+		{"compliance shortened", compliance, &ObjectLockRetention{Mode: "COMPLIANCE", Days: 364}, true},
+		{"compliance to governance", compliance, &ObjectLockRetention{Mode: "GOVERNANCE", Years: 2}, true},
+		{"compliance removed", compliance, nil, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := checkComplianceRetention(tc.current, tc.desired); (err != nil) != tc.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func newObjectLockBucket(name string, retention *seaweedv1.BucketObjectLockDefaultRetention) *seaweedv1.Bucket {
+	bucket := newTestBucket(name)
+	bucket.Spec.Versioning = seaweedv1.VersioningEnabled
+	bucket.Spec.ObjectLock = true
+	bucket.Spec.ObjectLockConfiguration = &seaweedv1.BucketObjectLockConfiguration{DefaultRetention: retention}
+	return bucket
+}
+
+func TestReconcile_ObjectLockRetentionAppliedAndDriftHealed(t *testing.T) {
+	bucket := newObjectLockBucket("records", &seaweedv1.BucketObjectLockDefaultRetention{
+		Mode: seaweedv1.ObjectLockGovernance, Days: ptr.To[int32](30),
+	})
+	fa := newFakeAdmin()
+	r, cli := testReconciler(t, fa, newTestSeaweed(), bucket)
+	key := types.NamespacedName{Namespace: bucket.Namespace, Name: bucket.Name}
+
+	reconcileUntilStable(t, r, key, 5)
+	want := &ObjectLockRetention{Mode: "GOVERNANCE", Days: 30}
+	if got := fa.objectLockRetention["records"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("stored retention = %+v want %+v", got, want)
+	}
+	got := &seaweedv1.Bucket{}
+	if err := cli.Get(context.Background(), key, got); err != nil {
+		t.Fatalf("get bucket: %v", err)
+	}
+	if got.Status.ObjectLockDefaultRetention == nil || *got.Status.ObjectLockDefaultRetention.Days != 30 {
+		t.Errorf("status.objectLockDefaultRetention = %+v", got.Status.ObjectLockDefaultRetention)
+	}
+
+	// In sync: no writes.
+	fa.calls = nil
+	reconcileUntilStable(t, r, key, 5)
+	if got := callsFor(fa.calls, "SetObjectLockRetention"); len(got) != 0 {
+		t.Errorf("unexpected writes without drift: %v", got)
+	}
+
+	// Edited out of band: rewritten.
+	fa.objectLockRetention["records"] = &ObjectLockRetention{Mode: "GOVERNANCE", Days: 1}
+	reconcileUntilStable(t, r, key, 5)
+	if got := fa.objectLockRetention["records"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("drift not healed: %+v", got)
+	}
+}
+
+func TestReconcile_ObjectLockRetentionRemovedOnlyWhenManaged(t *testing.T) {
+	bucket := newObjectLockBucket("records", nil)
+	bucket.Spec.ObjectLockConfiguration = nil
+	fa := newFakeAdmin()
+	fa.objectLockRetention = map[string]*ObjectLockRetention{"records": {Mode: "GOVERNANCE", Days: 5}}
+	r, _ := testReconciler(t, fa, newTestSeaweed(), bucket)
+	key := types.NamespacedName{Namespace: bucket.Namespace, Name: bucket.Name}
+
+	reconcileUntilStable(t, r, key, 5)
+	if got := callsFor(fa.calls, "SetObjectLockRetention"); len(got) != 0 {
+		t.Errorf("retention set through the S3 API must be left alone: %v", got)
+	}
+}
+
+func TestReconcile_ObjectLockRetentionRefusesToWeakenCompliance(t *testing.T) {
+	bucket := newObjectLockBucket("records", &seaweedv1.BucketObjectLockDefaultRetention{
+		Mode: seaweedv1.ObjectLockCompliance, Days: ptr.To[int32](30),
+	})
+	fa := newFakeAdmin()
+	fa.objectLockRetention = map[string]*ObjectLockRetention{"records": {Mode: "COMPLIANCE", Years: 7}}
+	r, cli := testReconciler(t, fa, newTestSeaweed(), bucket)
+	key := types.NamespacedName{Namespace: bucket.Namespace, Name: bucket.Name}
+
+	for i := 0; i < 3; i++ {
+		_, _ = r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+	}
+	if got := callsFor(fa.calls, "SetObjectLockRetention"); len(got) != 0 {
+		t.Errorf("a shorter COMPLIANCE retention must not be written: %v", got)
+	}
+	got := &seaweedv1.Bucket{}
+	if err := cli.Get(context.Background(), key, got); err != nil {
+		t.Fatalf("get bucket: %v", err)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.BucketConditionObjectLockRetentionRejected)
+	if cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Errorf("expected ObjectLockRetentionRejected=True, got %+v", cond)
+	}
+	if got.Status.Phase != seaweedv1.BucketPhaseFailed {
+		t.Errorf("phase = %q want Failed", got.Status.Phase)
+	}
+}
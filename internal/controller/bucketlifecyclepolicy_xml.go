@@ -19,8 +19,12 @@ package controller
 import (
 	"encoding/xml"
 	"reflect"
+	"sort"
+	"time"
 
 	"github.com/seaweedfs/seaweedfs/weed/s3api/lifecycle_xml"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
 )
@@ -36,17 +40,49 @@ type lifecycleConfiguration struct {
 }
 
 type lifecycleRule struct {
-	ID                             string                      `xml:"ID,omitempty"`
-	Status                         string                      `xml:"Status"`
-	Prefix                         string                      `xml:"Prefix"`
-	Expiration                     *lifecycleExpiration        `xml:"Expiration,omitempty"`
-	NoncurrentVersionExpiration    *lifecycleNoncurrentVersion `xml:"NoncurrentVersionExpiration,omitempty"`
-	AbortIncompleteMultipartUpload *lifecycleAbortMultipart    `xml:"AbortIncompleteMultipartUpload,omitempty"`
+	ID                             string                          `xml:"ID,omitempty"`
+	Status                         string                          `xml:"Status"`
+	Prefix                         *string                         `xml:"Prefix,omitempty"`
+	Filter                         *lifecycleFilter                `xml:"Filter,omitempty"`
+	Expiration                     *lifecycleExpiration            `xml:"Expiration,omitempty"`
+	Transitions                    []lifecycleTransition           `xml:"Transition,omitempty"`
+	NoncurrentVersionExpiration    *lifecycleNoncurrentVersion     `xml:"NoncurrentVersionExpiration,omitempty"`
+	NoncurrentVersionTransitions   []lifecycleNoncurrentTransition `xml:"NoncurrentVersionTransition,omitempty"`
+	AbortIncompleteMultipartUpload *lifecycleAbortMultipart        `xml:"AbortIncompleteMultipartUpload,omitempty"`
+}
+
+// lifecycleFilter is the S3 Filter element: exactly one of its children, with
+// And combining several conditions.
+type lifecycleFilter struct {
+	Prefix                *string             `xml:"Prefix,omitempty"`
+	Tag                   *lifecycleTag       `xml:"Tag,omitempty"`
+	ObjectSizeGreaterThan *int64              `xml:"ObjectSizeGreaterThan,omitempty"`
+	ObjectSizeLessThan    *int64              `xml:"ObjectSizeLessThan,omitempty"`
+	And                   *lifecycleFilterAnd `xml:"And,omitempty"`
+}
+
+type lifecycleFilterAnd struct {
+	Prefix                string         `xml:"Prefix,omitempty"`
+	Tags                  []lifecycleTag `xml:"Tag,omitempty"`
+	ObjectSizeGreaterThan *int64         `xml:"ObjectSizeGreaterThan,omitempty"`
+	ObjectSizeLessThan    *int64         `xml:"ObjectSizeLessThan,omitempty"`
+}
+
+type lifecycleTag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
 }
 
 type lifecycleExpiration struct {
-	Days                      int32 `xml:"Days,omitempty"`
-	ExpiredObjectDeleteMarker bool  `xml:"ExpiredObjectDeleteMarker,omitempty"`
+	Days                      int32  `xml:"Days,omitempty"`
+	Date                      string `xml:"Date,omitempty"`
+	ExpiredObjectDeleteMarker bool   `xml:"ExpiredObjectDeleteMarker,omitempty"`
+}
+
+type lifecycleTransition struct {
+	Days         *int32 `xml:"Days,omitempty"`
+	Date         string `xml:"Date,omitempty"`
+	StorageClass string `xml:"StorageClass"`
 }
 
 type lifecycleNoncurrentVersion struct {
@@ -54,32 +90,65 @@ type lifecycleNoncurrentVersion struct {
 	NewerNoncurrentVersions int32 `xml:"NewerNoncurrentVersions,omitempty"`
 }
 
+type lifecycleNoncurrentTransition struct {
+	NoncurrentDays          int32  `xml:"NoncurrentDays"`
+	NewerNoncurrentVersions int32  `xml:"NewerNoncurrentVersions,omitempty"`
+	StorageClass            string `xml:"StorageClass"`
+}
+
 type lifecycleAbortMultipart struct {
 	DaysAfterInitiation int32 `xml:"DaysAfterInitiation"`
 }
 
+// lifecycleDateLayout is the ISO 8601 form S3 lifecycle dates are written in.
+const lifecycleDateLayout = "2006-01-02T15:04:05Z"
+
 // buildLifecycleXML renders the desired rules into S3 lifecycle configuration
-// XML. An empty rule set yields nil, signalling "no configuration".
+// XML. An empty rule set yields nil, signalling "no configuration". Rules are
+// validated first, so combinations S3 rejects never reach the bucket.
 func buildLifecycleXML(rules []seaweedv1.BucketLifecycleRule) ([]byte, error) {
 	if len(rules) == 0 {
 		return nil, nil
 	}
+	if err := seaweedv1.ValidateBucketLifecycleRules(rules); err != nil {
+		return nil, err
+	}
 	cfg := lifecycleConfiguration{Rules: make([]lifecycleRule, 0, len(rules))}
 	for i := range rules {
 		r := &rules[i]
-		out := lifecycleRule{ID: r.ID, Status: string(r.Status), Prefix: r.Prefix}
+		out := lifecycleRule{ID: r.ID, Status: string(r.Status)}
+		if r.Filter != nil {
+			out.Filter = buildLifecycleFilter(r.Filter)
+		} else {
+			out.Prefix = ptr.To(r.Prefix)
+		}
 		if r.Expiration != nil {
 			out.Expiration = &lifecycleExpiration{
 				Days:                      r.Expiration.Days,
+				Date:                      lifecycleDate(r.Expiration.Date),
 				ExpiredObjectDeleteMarker: r.Expiration.ExpiredObjectDeleteMarker,
 			}
 		}
+		for _, t := range r.Transitions {
+			out.Transitions = append(out.Transitions, lifecycleTransition{
+				Days:         t.Days,
+				Date:         lifecycleDate(t.Date),
+				StorageClass: t.StorageClass,
+			})
+		}
 		if r.NoncurrentVersionExpiration != nil {
 			out.NoncurrentVersionExpiration = &lifecycleNoncurrentVersion{
 				NoncurrentDays:          r.NoncurrentVersionExpiration.NoncurrentDays,
 				NewerNoncurrentVersions: r.NoncurrentVersionExpiration.NewerNoncurrentVersions,
 			}
 		}
+		for _, t := range r.NoncurrentVersionTransitions {
+			out.NoncurrentVersionTransitions = append(out.NoncurrentVersionTransitions, lifecycleNoncurrentTransition{
+				NoncurrentDays:          t.NoncurrentDays,
+				NewerNoncurrentVersions: t.NewerNoncurrentVersions,
+				StorageClass:            t.StorageClass,
+			})
+		}
 		if r.AbortIncompleteMultipartUpload != nil {
 			out.AbortIncompleteMultipartUpload = &lifecycleAbortMultipart{
 				DaysAfterInitiation: r.AbortIncompleteMultipartUpload.DaysAfterInitiation,
@@ -90,10 +159,53 @@ func buildLifecycleXML(rules []seaweedv1.BucketLifecycleRule) ([]byte, error) {
 	return xml.Marshal(cfg)
 }
 
+// buildLifecycleFilter renders a spec filter. A single condition becomes the
+// Filter's own child; several are wrapped in And, as S3 requires. An empty
+// filter renders as an empty prefix, matching every object.
+func buildLifecycleFilter(f *seaweedv1.BucketLifecycleFilter) *lifecycleFilter {
+	and := lifecycleFilterAnd{
+		Prefix:                f.Prefix,
+		ObjectSizeGreaterThan: f.ObjectSizeGreaterThan,
+		ObjectSizeLessThan:    f.ObjectSizeLessThan,
+	}
+	for _, t := range f.Tags {
+		and.Tags = append(and.Tags, lifecycleTag{Key: t.Key, Value: t.Value})
+	}
+	n := len(and.Tags)
+	for _, set := range []bool{and.Prefix != "", and.ObjectSizeGreaterThan != nil, and.ObjectSizeLessThan != nil} {
+		if set {
+			n++
+		}
+	}
+	switch {
+	case n > 1:
+		return &lifecycleFilter{And: &and}
+	case len(and.Tags) == 1:
+		return &lifecycleFilter{Tag: &and.Tags[0]}
+	case and.ObjectSizeGreaterThan != nil:
+		return &lifecycleFilter{ObjectSizeGreaterThan: and.ObjectSizeGreaterThan}
+	case and.ObjectSizeLessThan != nil:
+		return &lifecycleFilter{ObjectSizeLessThan: and.ObjectSizeLessThan}
+	default:
+		return &lifecycleFilter{Prefix: ptr.To(and.Prefix)}
+	}
+}
+
+// lifecycleDate formats a lifecycle date; empty when unset.
+func lifecycleDate(t *metav1.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(lifecycleDateLayout)
+}
+
 // lifecycleConfigEqual reports whether two lifecycle configuration XML blobs
 // describe the same rules. Comparison is on the canonical parse so formatting
 // differences (and rules written by the S3 gateway) don't trigger needless
 // rewrites; an unparsable current config compares unequal so it is rewritten.
+// The upstream canonical form does not carry every element this operator
+// emits (transitions among them), so the rules are also compared on this
+// package's own normalized parse.
 func lifecycleConfigEqual(current, desired []byte) bool {
 	if len(current) == 0 && len(desired) == 0 {
 		return true
@@ -106,5 +218,82 @@ func lifecycleConfigEqual(current, desired []byte) bool {
 	if err != nil {
 		return false
 	}
-	return reflect.DeepEqual(currentRules, desiredRules)
+	if !reflect.DeepEqual(currentRules, desiredRules) {
+		return false
+	}
+	currentNorm, err := normalizedLifecycleRules(current)
+	if err != nil {
+		return false
+	}
+	desiredNorm, err := normalizedLifecycleRules(desired)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(currentNorm, desiredNorm)
+}
+
+// normalizedLifecycleRules parses lifecycle XML into rules whose equivalent
+// spellings coincide: the filter is always an And, dates are re-rendered,
+// and rules, tags and transitions are sorted.
+func normalizedLifecycleRules(data []byte) ([]lifecycleRule, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var cfg lifecycleConfiguration
+	if err := xml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	for i := range cfg.Rules {
+		r := &cfg.Rules[i]
+		and := &lifecycleFilterAnd{}
+		if r.Prefix != nil {
+			and.Prefix = *r.Prefix
+		}
+		if f := r.Filter; f != nil {
+			switch {
+			case f.And != nil:
+				and = f.And
+			case f.Tag != nil:
+				and.Tags = []lifecycleTag{*f.Tag}
+			case f.ObjectSizeGreaterThan != nil:
+				and.ObjectSizeGreaterThan = f.ObjectSizeGreaterThan
+			case f.ObjectSizeLessThan != nil:
+				and.ObjectSizeLessThan = f.ObjectSizeLessThan
+			case f.Prefix != nil:
+				and.Prefix = *f.Prefix
+			}
+		}
+		sort.Slice(and.Tags, func(a, b int) bool { return and.Tags[a].Key < and.Tags[b].Key })
+		if len(and.Tags) == 0 {
+			and.Tags = nil
+		}
+		r.Prefix, r.Filter = nil, &lifecycleFilter{And: and}
+
+		if r.Expiration != nil {
+			r.Expiration.Date = normalizeLifecycleDate(r.Expiration.Date)
+		}
+		for j := range r.Transitions {
+			r.Transitions[j].Date = normalizeLifecycleDate(r.Transitions[j].Date)
+		}
+		sort.Slice(r.Transitions, func(a, b int) bool { return r.Transitions[a].StorageClass < r.Transitions[b].StorageClass })
+		sort.Slice(r.NoncurrentVersionTransitions, func(a, b int) bool {
+			return r.NoncurrentVersionTransitions[a].StorageClass < r.NoncurrentVersionTransitions[b].StorageClass
+		})
+	}
+	sort.Slice(cfg.Rules, func(a, b int) bool { return cfg.Rules[a].ID < cfg.Rules[b].ID })
+	return cfg.Rules, nil
+}
+
+// normalizeLifecycleDate re-renders an ISO 8601 date in lifecycleDateLayout,
+// so fractional seconds or a numeric UTC offset compare equal. Unparsable
+// values are returned as-is.
+func normalizeLifecycleDate(v string) string {
+	if v == "" {
+		return ""
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return v
+	}
+	return t.UTC().Format(lifecycleDateLayout)
 }
@@ -17,9 +17,13 @@ limitations under the License.
 package controller
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/seaweedfs/seaweedfs/weed/s3api/lifecycle_xml"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
 )
@@ -122,3 +126,109 @@ func TestLifecycleConfigEqual(t *testing.T) {
 		t.Error("malformed current config should compare unequal")
 	}
 }
+
+func TestBuildLifecycleXMLFilterAndTransitions(t *testing.T) {
+	date := metav1.NewTime(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	rules := []seaweedv1.BucketLifecycleRule{
+		{
+			ID:     "tiered-logs",
+			Status: seaweedv1.BucketLifecycleRuleEnabled,
+			Filter: &seaweedv1.BucketLifecycleFilter{
+				Prefix:                "logs/",
+				Tags:                  []seaweedv1.BucketLifecycleTag{{Key: "tier", Value: "cold"}},
+				ObjectSizeGreaterThan: ptr.To[int64](1024),
+			},
+			Transitions: []seaweedv1.BucketLifecycleTransition{{Days: ptr.To[int32](30), StorageClass: "hdd"}},
+			Expiration:  &seaweedv1.BucketLifecycleExpiration{Days: 365},
+			NoncurrentVersionTransitions: []seaweedv1.BucketLifecycleNoncurrentVersionTransition{
+				{NoncurrentDays: 7, StorageClass: "STANDARD_IA"},
+			},
+		},
+		{
+			ID:         "expire-tagged",
+			Status:     seaweedv1.BucketLifecycleRuleEnabled,
+			Filter:     &seaweedv1.BucketLifecycleFilter{Tags: []seaweedv1.BucketLifecycleTag{{Key: "temp", Value: "true"}}},
+			Expiration: &seaweedv1.BucketLifecycleExpiration{Date: &date},
+		},
+	}
+	out, err := buildLifecycleXML(rules)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	for _, want := range []string{
+		"<Filter><And><Prefix>logs/</Prefix><Tag><Key>tier</Key><Value>cold</Value></Tag><ObjectSizeGreaterThan>1024</ObjectSizeGreaterThan></And></Filter>",
+		"<Transition><Days>30</Days><StorageClass>hdd</StorageClass></Transition>",
+		"<NoncurrentVersionTransition><NoncurrentDays>7</NoncurrentDays><StorageClass>STANDARD_IA</StorageClass></NoncurrentVersionTransition>",
+		"<Filter><Tag><Key>temp</Key><Value>true</Value></Tag></Filter>",
+		"<Expiration><Date>2030-01-01T00:00:00Z</Date></Expiration>",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("generated xml lacks %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(string(out), "<Rule><ID>tiered-logs</ID><Status>Enabled</Status><Prefix>") {
+		t.Errorf("a rule with a filter must not also carry a top-level Prefix:\n%s", out)
+	}
+
+	norm, err := normalizedLifecycleRules(out)
+	if err != nil {
+		t.Fatalf("parse generated xml: %v", err)
+	}
+	if len(norm) != 2 || norm[1].ID != "tiered-logs" || norm[1].Filter.And.Prefix != "logs/" || len(norm[1].Filter.And.Tags) != 1 {
+		t.Errorf("round trip mismatch: %+v", norm)
+	}
+}
+
+func TestBuildLifecycleXMLRejectsInvalidCombination(t *testing.T) {
+	_, err := buildLifecycleXML([]seaweedv1.BucketLifecycleRule{{
+		ID:                             "bad",
+		Status:                         seaweedv1.BucketLifecycleRuleEnabled,
+		Filter:                         &seaweedv1.BucketLifecycleFilter{Tags: []seaweedv1.BucketLifecycleTag{{Key: "k", Value: "v"}}},
+		AbortIncompleteMultipartUpload: &seaweedv1.BucketLifecycleAbortIncompleteMultipartUpload{DaysAfterInitiation: 1},
+	}})
+	if err == nil {
+		t.Fatal("expected a tag filter with abortIncompleteMultipartUpload to be rejected")
+	}
+}
+
+func TestLifecycleConfigEqualNewElements(t *testing.T) {
+	desired, err := buildLifecycleXML([]seaweedv1.BucketLifecycleRule{{
+		ID:          "r1",
+		Status:      seaweedv1.BucketLifecycleRuleEnabled,
+		Filter:      &seaweedv1.BucketLifecycleFilter{Prefix: "logs/"},
+		Transitions: []seaweedv1.BucketLifecycleTransition{{Days: ptr.To[int32](30), StorageClass: "hdd"}},
+	}})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	// A filter prefix and a legacy top-level prefix mean the same thing, and
+	// dates may carry fractional seconds.
+	legacy := []byte("<LifecycleConfiguration><Rule><ID>r1</ID><Status>Enabled</Status><Prefix>logs/</Prefix>" +
+		"<Transition><Days>30</Days><StorageClass>hdd</StorageClass></Transition></Rule></LifecycleConfiguration>")
+	if !lifecycleConfigEqual(legacy, desired) {
+		t.Error("filter prefix and rule prefix should compare equal")
+	}
+	if normalizeLifecycleDate("2030-01-01T00:00:00.000Z") != "2030-01-01T00:00:00Z" {
+		t.Error("fractional seconds should normalize away")
+	}
+
+	for name, current := range map[string]string{
+		"transition storage class": "<Transition><Days>30</Days><StorageClass>ssd</StorageClass></Transition>",
+		"transition days":          "<Transition><Days>60</Days><StorageClass>hdd</StorageClass></Transition>",
+		"transition removed":       "",
+	} {
+		drifted := []byte("<LifecycleConfiguration><Rule><ID>r1</ID><Status>Enabled</Status><Filter><Prefix>logs/</Prefix></Filter>" +
+			current + "</Rule></LifecycleConfiguration>")
+		if lifecycleConfigEqual(drifted, desired) {
+			t.Errorf("%s drift not detected", name)
+		}
+	}
+
+	tagged := []byte("<LifecycleConfiguration><Rule><ID>r1</ID><Status>Enabled</Status><Filter><And><Prefix>logs/</Prefix>" +
+		"<Tag><Key>a</Key><Value>b</Value></Tag></And></Filter>" +
+		"<Transition><Days>30</Days><StorageClass>hdd</StorageClass></Transition></Rule></LifecycleConfiguration>")
+	if lifecycleConfigEqual(tagged, desired) {
+		t.Error("an added tag filter should be detected as drift")
+	}
+}
@@ -0,0 +1,552 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// BucketReplicationFinalizer keeps the CR around until its Deployment, which
+// may live in another namespace and so cannot be garbage-collected through
+// an owner reference, has been removed.
+const BucketReplicationFinalizer = "seaweed.seaweedfs.com/bucketreplication-protection"
+
+// BucketReplicationReconciler runs a `weed filer.sync` (bucket destination) or
+// `weed filer.backup` (remote destination) Deployment per BucketReplication,
+// scoped to the source bucket, and reports its lag and error count on the
+// replication and on both Buckets.
+type BucketReplicationReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// AdminFactory creates a BucketAdmin used to read the replication
+	// checkpoints. Tests inject a fake; production wires NewSwadminBucketAdmin.
+	AdminFactory BucketAdminFactory
+
+	// ResyncInterval is how often a Ready replication re-reads its
+	// checkpoint to refresh the reported lag. Zero disables the requeue.
+	ResyncInterval time.Duration
+
+	// PodLogs reads the replication container's log to count the errors
+	// weed reports. Nil leaves ErrorCount unreported.
+	PodLogs PodLogReader
+
+	// Now returns the current time when computing lag. Tests pin it;
+	// nil uses time.Now.
+	Now func() time.Time
+}
+
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=bucketreplications,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=bucketreplications/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=bucketreplications/finalizers,verbs=update
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=buckets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=buckets/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=pods/log,verbs=get
+
+// Reconcile implements the bucket replication reconciliation logic.
+func (r *BucketReplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := r.Log.WithValues("bucketreplication", req.NamespacedName)
+
+	var br seaweedv1.BucketReplication
+	if err := r.Get(ctx, req.NamespacedName, &br); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !br.DeletionTimestamp.IsZero() {
+		return r.handleDeletion(ctx, &br, log)
+	}
+
+	if !controllerutil.ContainsFinalizer(&br, BucketReplicationFinalizer) {
+		controllerutil.AddFinalizer(&br, BucketReplicationFinalizer)
+		if err := r.Update(ctx, &br); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	base := br.Status.DeepCopy()
+	defer func() {
+		if err != nil || reflect.DeepEqual(*base, br.Status) {
+			return
+		}
+		if uerr := r.Status().Update(ctx, &br); uerr != nil {
+			result, err = ctrl.Result{}, uerr
+		}
+	}()
+
+	src, srcCluster, reason, message, err := resolveProvisionedBucket(ctx, r.Client, br.Namespace, br.Spec.SourceRef.Name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if reason != "" {
+		return r.pending(&br, reason, message), nil
+	}
+	srcBucket := src.Status.BucketName
+
+	depName := bucketReplicationDeploymentName(&br)
+	var (
+		command    []string
+		sources    []corev1.VolumeProjection
+		remote     *seaweedv1.BackupStorageSpec
+		dst        *seaweedv1.Bucket
+		checkpoint func(ctx context.Context) ([]byte, error)
+	)
+	// The Deployment, and for a remote destination a copy of its
+	// credentials, run next to the source cluster, so a cluster in another
+	// namespace has to grant the reference.
+	if srcCluster.Namespace != br.Namespace {
+		ref := seaweedv1.SeaweedReference{Name: srcCluster.Name, Namespace: srcCluster.Namespace}
+		permitted, err := seaweedRefPermitted(ctx, r.Client, ref, kindBucketReplication, br.Namespace)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !permitted {
+			if err := r.removeReplicationDeployment(ctx, &br); err != nil {
+				return ctrl.Result{}, err
+			}
+			return r.pending(&br, "ReferenceGrantMissing", seaweedRefDeniedMessage(ref, kindBucketReplication, br.Namespace)), nil
+		}
+	}
+	switch {
+	case br.Spec.Destination.Remote != nil:
+		st := *br.Spec.Destination.Remote
+		creds, err := readBackupCredentials(ctx, r.Client, br.Namespace, st)
+		if err != nil {
+			return r.failPhase(&br, "CredentialsUnavailable", err.Error()), nil
+		}
+		sinkDir := remoteReplicationDirectory(st, srcBucket, br.Spec.Prefix)
+		toml, err := renderSinkToml("remote", st, sinkDir, creds)
+		if err != nil {
+			return r.failPhase(&br, "InvalidDestination", err.Error()), nil
+		}
+		secretName := bucketReplicationSecretName(&br)
+		hasGCSKey, err := r.ensureConfigSecret(ctx, srcCluster.Namespace, secretName, depName, st, toml, creds)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		items := []corev1.KeyToPath{{Key: "replication.toml", Path: "replication.toml"}}
+		if hasGCSKey {
+			items = append(items, corev1.KeyToPath{Key: gcsKeyFileName, Path: gcsKeyFileName})
+		}
+		sources = []corev1.VolumeProjection{{Secret: &corev1.SecretProjection{
+			LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+			Items:                items,
+		}}}
+		command = bucketBackupCommand(srcCluster, &br, srcBucket)
+		remote = &st
+		key := backupCheckpointKey(st, sinkDir)
+		checkpoint = func(ctx context.Context) ([]byte, error) {
+			admin, err := r.adminFor(ctx, srcCluster, log)
+			if err != nil {
+				return nil, err
+			}
+			defer closeBucketAdmin(admin, log)
+			return admin.GetFilerKV(ctx, key)
+		}
+
+	default:
+		if br.Spec.Destination.BucketRef.Name == br.Spec.SourceRef.Name {
+			return r.failPhase(&br, "InvalidDestination", "source and destination are the same Bucket"), nil
+		}
+		var dstCluster *seaweedv1.Seaweed
+		dst, dstCluster, reason, message, err = resolveProvisionedBucket(ctx, r.Client, br.Namespace, br.Spec.Destination.BucketRef.Name)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if reason != "" {
+			return r.pending(&br, reason, message), nil
+		}
+		if dstCluster.Namespace == srcCluster.Namespace && dstCluster.Name == srcCluster.Name && dst.Status.BucketName == srcBucket {
+			return r.failPhase(&br, "InvalidDestination", "source and destination resolve to the same bucket"), nil
+		}
+		command = bucketSyncCommand(srcCluster, dstCluster, &br, srcBucket, dst.Status.BucketName)
+		srcPath := bucketFilerPath(srcBucket, br.Spec.Prefix)
+		checkpoint = func(ctx context.Context) ([]byte, error) {
+			srcAdmin, err := r.adminFor(ctx, srcCluster, log)
+			if err != nil {
+				return nil, err
+			}
+			defer closeBucketAdmin(srcAdmin, log)
+			signature, err := srcAdmin.FilerSignature(ctx)
+			if err != nil {
+				return nil, err
+			}
+			dstAdmin, err := r.adminFor(ctx, dstCluster, log)
+			if err != nil {
+				return nil, err
+			}
+			defer closeBucketAdmin(dstAdmin, log)
+			return dstAdmin.GetFilerKV(ctx, syncCheckpointKey(srcPath, signature))
+		}
+	}
+	r.setCondition(&br, seaweedv1.BucketReplicationConditionResolved, metav1.ConditionTrue, "Resolved", "")
+
+	dep := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: depName, Namespace: srcCluster.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, dep, func() error {
+		buildBucketReplicationDeployment(srcCluster, &br, dep, command, sources, remote)
+		return nil
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+	br.Status.DeploymentName = dep.Name
+	br.Status.DeploymentNamespace = dep.Namespace
+	br.Status.ObservedGeneration = br.Generation
+	br.Status.Ready = !br.Spec.Suspend && dep.Status.AvailableReplicas > 0
+
+	if err := r.observePods(ctx, &br, dep, log); err != nil {
+		return ctrl.Result{}, err
+	}
+	r.observeCheckpoint(ctx, &br, checkpoint, log)
+
+	switch {
+	case br.Spec.Suspend:
+		br.Status.Phase = seaweedv1.BucketPhasePending
+		r.setCondition(&br, seaweedv1.BucketReplicationConditionReady, metav1.ConditionFalse, "Suspended", "spec.suspend is set")
+	case !br.Status.Ready:
+		br.Status.Phase = seaweedv1.BucketPhasePending
+		r.setCondition(&br, seaweedv1.BucketReplicationConditionReady, metav1.ConditionFalse, "DeploymentUnavailable",
+			fmt.Sprintf("replication Deployment %s/%s has no available replica", dep.Namespace, dep.Name))
+	default:
+		br.Status.Phase = seaweedv1.BucketPhaseReady
+		r.setCondition(&br, seaweedv1.BucketReplicationConditionReady, metav1.ConditionTrue, "Replicating", "")
+	}
+
+	summary := seaweedv1.BucketReplicationSummary{
+		Name:       br.Name,
+		Ready:      br.Status.Ready,
+		LagSeconds: br.Status.LagSeconds,
+		Restarts:   br.Status.Restarts,
+		ErrorCount: br.Status.ErrorCount,
+	}
+	summary.Role = seaweedv1.BucketReplicationRoleSource
+	if err := r.setBucketSummary(ctx, src, br.Name, &summary); err != nil {
+		return ctrl.Result{}, err
+	}
+	if dst != nil {
+		dstSummary := summary
+		dstSummary.Role = seaweedv1.BucketReplicationRoleDestination
+		if err := r.setBucketSummary(ctx, dst, br.Name, &dstSummary); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if br.Status.Phase != seaweedv1.BucketPhaseReady {
+		return ctrl.Result{RequeueAfter: requeueAfterTransient}, nil
+	}
+	return ctrl.Result{RequeueAfter: r.ResyncInterval}, nil
+}
+
+// resolveProvisionedBucket returns a provisioned Bucket in namespace and its
+// Seaweed cluster. A non-empty reason means a dependency is missing and the
+// caller should wait.
+func resolveProvisionedBucket(ctx context.Context, c client.Client, namespace, name string) (*seaweedv1.Bucket, *seaweedv1.Seaweed, string, string, error) {
+	var bucket seaweedv1.Bucket
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &bucket); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil, "BucketNotFound", fmt.Sprintf("Bucket %q not found in namespace %q", name, namespace), nil
+		}
+		return nil, nil, "", "", err
+	}
+	if bucket.Status.BucketName == "" {
+		return nil, nil, "BucketNotReady", fmt.Sprintf("Bucket %q is not provisioned yet", name), nil
+	}
+	seaweedNS := bucket.Spec.ClusterRef.Namespace
+	if seaweedNS == "" {
+		seaweedNS = bucket.Namespace
+	}
+	var seaweed seaweedv1.Seaweed
+	if err := c.Get(ctx, types.NamespacedName{Namespace: seaweedNS, Name: bucket.Spec.ClusterRef.Name}, &seaweed); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil, "ClusterRefNotFound", fmt.Sprintf("Seaweed %q not found in namespace %q", bucket.Spec.ClusterRef.Name, seaweedNS), nil
+		}
+		return nil, nil, "", "", err
+	}
+	return &bucket, &seaweed, "", "", nil
+}
+
+// ensureConfigSecret renders the replication.toml Secret for a remote
+// destination and reports whether it carries a GCS key file.
+func (r *BucketReplicationReconciler) ensureConfigSecret(ctx context.Context, namespace, name, depName string, st seaweedv1.BackupStorageSpec, toml string, creds map[string][]byte) (bool, error) {
+	data := map[string][]byte{"replication.toml": []byte(toml)}
+	hasGCSKey := false
+	if st.Type == seaweedv1.BackupStorageGCS {
+		if json := creds[seaweedv1.BackupSecretKeyGCSCredentials]; len(json) > 0 {
+			data[gcsKeyFileName] = json
+			hasGCSKey = true
+		}
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		secret.Labels = labelsForBucketReplication(depName)
+		secret.Type = corev1.SecretTypeOpaque
+		secret.Data = data
+		return nil
+	})
+	return hasGCSKey, err
+}
+
+// observePods refreshes Restarts and ErrorCount from the replication pods:
+// the restarts of their containers, and the error lines the running
+// containers have logged. A log that cannot be read keeps the previous
+// ErrorCount.
+func (r *BucketReplicationReconciler) observePods(ctx context.Context, br *seaweedv1.BucketReplication, dep *appsv1.Deployment, log logr.Logger) error {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(dep.Namespace), client.MatchingLabels(labelsForBucketReplication(dep.Name))); err != nil {
+		return err
+	}
+	var restarts int32
+	for i := range pods.Items {
+		for _, cs := range pods.Items[i].Status.ContainerStatuses {
+			restarts += cs.RestartCount
+		}
+	}
+	br.Status.Restarts = restarts
+	if r.PodLogs == nil {
+		return nil
+	}
+	errs, err := scrapeContainerErrors(ctx, r.PodLogs, pods.Items, bucketReplicationContainerName)
+	if err != nil {
+		log.V(1).Info("read replication logs", "err", err.Error())
+		return nil
+	}
+	br.Status.ErrorCount = errs
+	return nil
+}
+
+// observeCheckpoint refreshes LastCheckpointTime and LagSeconds from the
+// checkpoint weed persists on the filer. A failed read keeps the previous
+// observation; lag is informational and must not block reconciliation.
+func (r *BucketReplicationReconciler) observeCheckpoint(ctx context.Context, br *seaweedv1.BucketReplication, read func(ctx context.Context) ([]byte, error), log logr.Logger) {
+	value, err := read(ctx)
+	if err != nil {
+		log.V(1).Info("read replication checkpoint", "err", err.Error())
+		return
+	}
+	ts, ok := parseFilerCheckpoint(value)
+	if !ok {
+		br.Status.LastCheckpointTime = nil
+		br.Status.LagSeconds = nil
+		return
+	}
+	lag := int64(r.now().Sub(ts) / time.Second)
+	if lag < 0 {
+		lag = 0
+	}
+	br.Status.LastCheckpointTime = &metav1.Time{Time: ts}
+	br.Status.LagSeconds = &lag
+}
+
+// setBucketSummary upserts (or, with a nil summary, removes) this
+// replication's entry on a Bucket's status, writing only on change.
+func (r *BucketReplicationReconciler) setBucketSummary(ctx context.Context, bucket *seaweedv1.Bucket, name string, summary *seaweedv1.BucketReplicationSummary) error {
+	var list []seaweedv1.BucketReplicationSummary
+	for _, s := range bucket.Status.Replications {
+		if s.Name != name {
+			list = append(list, s)
+		}
+	}
+	if summary != nil {
+		list = append(list, *summary)
+		sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	}
+	if reflect.DeepEqual(list, bucket.Status.Replications) {
+		return nil
+	}
+	patch := client.MergeFrom(bucket.DeepCopy())
+	bucket.Status.Replications = list
+	return r.Status().Patch(ctx, bucket, patch)
+}
+
+// handleDeletion removes the replication Deployment and config Secret and
+// this replication's entries on both Buckets, then drops the finalizer.
+func (r *BucketReplicationReconciler) handleDeletion(ctx context.Context, br *seaweedv1.BucketReplication, log logr.Logger) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(br, BucketReplicationFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	depName := br.Status.DeploymentName
+	if err := r.removeReplicationDeployment(ctx, br); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	names := []string{br.Spec.SourceRef.Name}
+	if ref := br.Spec.Destination.BucketRef; ref != nil {
+		names = append(names, ref.Name)
+	}
+	for _, name := range names {
+		var bucket seaweedv1.Bucket
+		if err := r.Get(ctx, types.NamespacedName{Namespace: br.Namespace, Name: name}, &bucket); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return ctrl.Result{}, err
+		}
+		if err := r.setBucketSummary(ctx, &bucket, br.Name, nil); err != nil && !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+	}
+
+	log.Info("removed bucket replication", "deployment", depName)
+	controllerutil.RemoveFinalizer(br, BucketReplicationFinalizer)
+	if err := r.Update(ctx, br); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// removeReplicationDeployment deletes the replication Deployment and config
+// Secret recorded in status, if any, and forgets them.
+func (r *BucketReplicationReconciler) removeReplicationDeployment(ctx context.Context, br *seaweedv1.BucketReplication) error {
+	ns := br.Status.DeploymentNamespace
+	if ns == "" {
+		return nil
+	}
+	for _, obj := range []client.Object{
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: br.Status.DeploymentName}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: bucketReplicationSecretName(br)}},
+	} {
+		if err := r.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	br.Status.DeploymentName = ""
+	br.Status.DeploymentNamespace = ""
+	return nil
+}
+
+// adminFor builds a BucketAdmin for the given Seaweed cluster.
+func (r *BucketReplicationReconciler) adminFor(ctx context.Context, seaweed *seaweedv1.Seaweed, log logr.Logger) (BucketAdmin, error) {
+	adminKey, err := loadFilerAdminSigningKey(ctx, r.Client, seaweed)
+	if err != nil {
+		return nil, err
+	}
+	dialOption, _, err := loadSeaweedGrpcDialOption(ctx, r.Client, seaweed)
+	if err != nil {
+		return nil, err
+	}
+	return r.AdminFactory(getMasterPeersString(seaweed), getFilerAddress(seaweed), adminKey, dialOption, log)
+}
+
+func (r *BucketReplicationReconciler) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+// pending records a Pending phase with the dependency that is missing and
+// requeues on the transient cadence.
+func (r *BucketReplicationReconciler) pending(br *seaweedv1.BucketReplication, reason, message string) ctrl.Result {
+	br.Status.Phase = seaweedv1.BucketPhasePending
+	br.Status.Ready = false
+	r.setCondition(br, seaweedv1.BucketReplicationConditionResolved, metav1.ConditionFalse, reason, message)
+	r.setCondition(br, seaweedv1.BucketReplicationConditionReady, metav1.ConditionFalse, reason, message)
+	return ctrl.Result{RequeueAfter: requeueAfterTransient}
+}
+
+func (r *BucketReplicationReconciler) failPhase(br *seaweedv1.BucketReplication, reason, message string) ctrl.Result {
+	r.Log.Info("reconcile failed", "reason", reason, "message", message)
+	br.Status.Phase = seaweedv1.BucketPhaseFailed
+	br.Status.Ready = false
+	r.setCondition(br, seaweedv1.BucketReplicationConditionReady, metav1.ConditionFalse, reason, message)
+	return ctrl.Result{RequeueAfter: requeueAfterTransient}
+}
+
+func (r *BucketReplicationReconciler) setCondition(br *seaweedv1.BucketReplication, condType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&br.Status.Conditions, metav1.Condition{
+		Type:               condType,
+		Status:             status,
+		ObservedGeneration: br.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// mapBucketToReplications enqueues the replications that use a Bucket as
+// their source or destination.
+func (r *BucketReplicationReconciler) mapBucketToReplications(ctx context.Context, obj client.Object) []reconcile.Request {
+	bucket, ok := obj.(*seaweedv1.Bucket)
+	if !ok {
+		return nil
+	}
+	var list seaweedv1.BucketReplicationList
+	if err := r.List(ctx, &list, client.InNamespace(bucket.Namespace)); err != nil {
+		return nil
+	}
+	var reqs []reconcile.Request
+	for i := range list.Items {
+		br := &list.Items[i]
+		dst := br.Spec.Destination.BucketRef
+		if br.Spec.SourceRef.Name == bucket.Name || (dst != nil && dst.Name == bucket.Name) {
+			reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(br)})
+		}
+	}
+	return reqs
+}
+
+// specOrDeletionChanged passes spec edits and the start of deletion but not
+// status-only updates. Every pass refreshes the lag on status, so reacting to
+// status writes would spin the controller.
+var specOrDeletionChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return e.ObjectNew.GetGeneration() != e.ObjectOld.GetGeneration() ||
+			!e.ObjectNew.GetDeletionTimestamp().Equal(e.ObjectOld.GetDeletionTimestamp())
+	},
+}
+
+// SetupWithManager wires the reconciler into the controller-runtime manager.
+func (r *BucketReplicationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.AdminFactory == nil {
+		r.AdminFactory = NewSwadminBucketAdmin
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&seaweedv1.BucketReplication{}, builder.WithPredicates(specOrDeletionChanged)).
+		// Spec changes only: this controller writes the Buckets' status, and
+		// provisioning is picked up by the transient requeue.
+		Watches(&seaweedv1.Bucket{}, handler.EnqueueRequestsFromMapFunc(r.mapBucketToReplications),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
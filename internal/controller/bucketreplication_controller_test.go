@@ -0,0 +1,417 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+var (
+	replicationKey = types.NamespacedName{Namespace: "media", Name: "photos-offsite"}
+	replicationNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+)
+
+func testReplicationReconciler(t *testing.T, fa *fakeBucketAdmin, objs ...client.Object) (*BucketReplicationReconciler, client.Client) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("clientgoscheme: %v", err)
+	}
+	if err := seaweedv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("seaweedv1: %v", err)
+	}
+	cli := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&seaweedv1.BucketReplication{}, &seaweedv1.Bucket{}).
+		Build()
+	r := &BucketReplicationReconciler{
+		Client: cli,
+		Log:    logf.FromContext(context.Background()),
+		Scheme: scheme,
+		AdminFactory: func(_, _ string, _ []byte, _ grpc.DialOption, _ logr.Logger) (BucketAdmin, error) {
+			return fa, nil
+		},
+		Now: func() time.Time { return replicationNow },
+	}
+	return r, cli
+}
+
+func newReplicationBucket(name, cluster string) *seaweedv1.Bucket {
+	return &seaweedv1.Bucket{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "media"},
+		Spec:       seaweedv1.BucketSpec{ClusterRef: seaweedv1.BucketClusterRef{Name: cluster, Namespace: "storage"}},
+		Status:     seaweedv1.BucketStatus{BucketName: name},
+	}
+}
+
+func newReplicationCluster(name string) *seaweedv1.Seaweed {
+	return &seaweedv1.Seaweed{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "storage"},
+		Spec:       seaweedv1.SeaweedSpec{Master: &seaweedv1.MasterSpec{Replicas: 3}},
+	}
+}
+
+func newRemoteReplication() *seaweedv1.BucketReplication {
+	secret := "offsite-s3-credentials"
+	return &seaweedv1.BucketReplication{
+		ObjectMeta: metav1.ObjectMeta{Name: replicationKey.Name, Namespace: replicationKey.Namespace},
+		Spec: seaweedv1.BucketReplicationSpec{
+			SourceRef: seaweedv1.BucketReplicationBucketRef{Name: "photos"},
+			Destination: seaweedv1.BucketReplicationDestination{Remote: &seaweedv1.BackupStorageSpec{
+				Type:              seaweedv1.BackupStorageS3,
+				S3:                &seaweedv1.S3BackupStore{Bucket: "offsite", Directory: "/seaweedfs"},
+				CredentialsSecret: &secret,
+			}},
+			Prefix:          "originals/",
+			ExcludePrefixes: []string{"originals/tmp/"},
+		},
+	}
+}
+
+// replicationGrant lets BucketReplications in "media" reference the
+// clusters in "storage".
+func replicationGrant() *seaweedv1.ResourceReferenceGrant {
+	return &seaweedv1.ResourceReferenceGrant{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-media-replication", Namespace: "storage"},
+		Spec: seaweedv1.ResourceReferenceGrantSpec{
+			From: []seaweedv1.ReferenceGrantFrom{{Group: groupSeaweed, Kind: kindBucketReplication, Namespace: "media"}},
+			To:   []seaweedv1.ReferenceGrantTo{{Group: groupSeaweed, Kind: kindSeaweed}},
+		},
+	}
+}
+
+func reconcileReplicationN(t *testing.T, r *BucketReplicationReconciler, n int) ctrl.Result {
+	t.Helper()
+	var res ctrl.Result
+	for i := 0; i < n; i++ {
+		var err error
+		res, err = r.Reconcile(context.Background(), ctrl.Request{NamespacedName: replicationKey})
+		if err != nil {
+			t.Fatalf("reconcile step %d: %v", i, err)
+		}
+	}
+	return res
+}
+
+func checkpointValue(ts time.Time) []byte {
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, uint64(ts.UnixNano()))
+	return v
+}
+
+func TestFilerCheckpointKeys(t *testing.T) {
+	// md5("") = d41d8cd98f00b204...
+	if got := uint64(hashStringToLong("")); got != 0xd41d8cd98f00b204 {
+		t.Errorf("hashStringToLong(\"\") = %x", got)
+	}
+	if got := filerCheckpointKey("backup.", -1); string(got) != "backup.\xff\xff\xff\xff" {
+		t.Errorf("backup key = %q", got)
+	}
+	if got := syncCheckpointKey("/buckets/photos", 0x01020304); string(got) != "sync./buckets/photos\x01\x02\x03\x04" {
+		t.Errorf("sync key = %q", got)
+	}
+	if got := syncCheckpointKey("/", 1); string(got) != "sync.\x00\x00\x00\x01" {
+		t.Errorf("root sync key = %q", got)
+	}
+
+	if _, ok := parseFilerCheckpoint(nil); ok {
+		t.Errorf("empty value parsed as a checkpoint")
+	}
+	if ts, ok := parseFilerCheckpoint(checkpointValue(replicationNow)); !ok || !ts.Equal(replicationNow) {
+		t.Errorf("checkpoint = %v, %v", ts, ok)
+	}
+}
+
+func TestBucketReplicationRemote(t *testing.T) {
+	br := newRemoteReplication()
+	creds := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "offsite-s3-credentials", Namespace: "media"},
+		Data: map[string][]byte{
+			seaweedv1.BackupSecretKeyAWSAccessKeyID:     []byte("AKID"),
+			seaweedv1.BackupSecretKeyAWSSecretAccessKey: []byte("SECRET"),
+		},
+	}
+	fa := newFakeAdmin()
+	sinkDir := "/seaweedfs/photos/originals"
+	fa.filerKV = map[string][]byte{
+		string(backupCheckpointKey(*br.Spec.Destination.Remote, sinkDir)): checkpointValue(replicationNow.Add(-90 * time.Second)),
+	}
+	depName := bucketReplicationDeploymentName(br)
+	pod := runningPod("storage", depName+"-0", labelsForBucketReplication(depName), bucketReplicationContainerName, 1)
+	r, cli := testReplicationReconciler(t, fa, br, creds, replicationGrant(), pod, newReplicationBucket("photos", "prod"), newReplicationCluster("prod"))
+	r.PodLogs = fakePodLogs{"storage/" + pod.Name + "/" + bucketReplicationContainerName: weedErrorLog}
+	reconcileReplicationN(t, r, 3)
+
+	var dep appsv1.Deployment
+	if err := cli.Get(context.Background(), types.NamespacedName{Namespace: "storage", Name: depName}, &dep); err != nil {
+		t.Fatalf("get deployment: %v", err)
+	}
+	cmd := strings.Join(dep.Spec.Template.Spec.Containers[0].Command, " ")
+	for _, want := range []string{
+		"filer.backup",
+		"-filer=prod-filer.storage:8888",
+		"-filerPath=/buckets/photos/originals",
+		"-doDeleteFiles=false",
+		"-filerExcludePaths=/buckets/photos/originals/tmp",
+	} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("command %q missing %q", cmd, want)
+		}
+	}
+
+	var secret corev1.Secret
+	if err := cli.Get(context.Background(), types.NamespacedName{Namespace: "storage", Name: bucketReplicationSecretName(br)}, &secret); err != nil {
+		t.Fatalf("get config secret: %v", err)
+	}
+	toml := string(secret.Data["replication.toml"])
+	if !strings.Contains(toml, `directory = "`+sinkDir+`"`) || !strings.Contains(toml, `aws_access_key_id = "AKID"`) {
+		t.Errorf("replication.toml = %s", toml)
+	}
+
+	// Not yet available: Pending, but the lag is already reported.
+	got := &seaweedv1.BucketReplication{}
+	if err := cli.Get(context.Background(), replicationKey, got); err != nil {
+		t.Fatalf("get replication: %v", err)
+	}
+	if got.Status.Phase != seaweedv1.BucketPhasePending || got.Status.LagSeconds == nil || *got.Status.LagSeconds != 90 {
+		t.Errorf("status = %+v want Pending with 90s lag", got.Status)
+	}
+
+	dep.Status.AvailableReplicas = 1
+	if err := cli.Status().Update(context.Background(), &dep); err != nil {
+		t.Fatalf("update deployment status: %v", err)
+	}
+	reconcileReplicationN(t, r, 1)
+	if err := cli.Get(context.Background(), replicationKey, got); err != nil {
+		t.Fatalf("get replication: %v", err)
+	}
+	if got.Status.Phase != seaweedv1.BucketPhaseReady || !meta.IsStatusConditionTrue(got.Status.Conditions, seaweedv1.BucketReplicationConditionReady) {
+		t.Errorf("status = %+v want Ready", got.Status)
+	}
+
+	bucket := &seaweedv1.Bucket{}
+	if err := cli.Get(context.Background(), types.NamespacedName{Namespace: "media", Name: "photos"}, bucket); err != nil {
+		t.Fatalf("get bucket: %v", err)
+	}
+	if len(bucket.Status.Replications) != 1 {
+		t.Fatalf("bucket replications = %+v", bucket.Status.Replications)
+	}
+	s := bucket.Status.Replications[0]
+	if s.Name != br.Name || s.Role != seaweedv1.BucketReplicationRoleSource || !s.Ready || s.LagSeconds == nil || *s.LagSeconds != 90 {
+		t.Errorf("bucket summary = %+v", s)
+	}
+	if got.Status.ErrorCount != 2 || got.Status.Restarts != 1 || s.ErrorCount != 2 || s.Restarts != 1 {
+		t.Errorf("errors/restarts = %d/%d, summary %d/%d; want the 2 logged errors and 1 restart",
+			got.Status.ErrorCount, got.Status.Restarts, s.ErrorCount, s.Restarts)
+	}
+}
+
+func TestBucketReplicationRemoteNeedsGrant(t *testing.T) {
+	br := newRemoteReplication()
+	creds := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "offsite-s3-credentials", Namespace: "media"},
+		Data:       map[string][]byte{seaweedv1.BackupSecretKeyAWSAccessKeyID: []byte("AKID")},
+	}
+	grant := replicationGrant()
+	r, cli := testReplicationReconciler(t, newFakeAdmin(), br, creds, newReplicationBucket("photos", "prod"), newReplicationCluster("prod"))
+	ctx := context.Background()
+	secretKey := types.NamespacedName{Namespace: "storage", Name: bucketReplicationSecretName(br)}
+	depKey := types.NamespacedName{Namespace: "storage", Name: bucketReplicationDeploymentName(br)}
+
+	reconcileReplicationN(t, r, 2)
+	got := &seaweedv1.BucketReplication{}
+	if err := cli.Get(ctx, replicationKey, got); err != nil {
+		t.Fatalf("get replication: %v", err)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.BucketReplicationConditionResolved)
+	if got.Status.Phase != seaweedv1.BucketPhasePending || cond == nil || cond.Reason != "ReferenceGrantMissing" {
+		t.Errorf("status = %+v want Pending/ReferenceGrantMissing", got.Status)
+	}
+	if err := cli.Get(ctx, secretKey, &corev1.Secret{}); !apierrors.IsNotFound(err) {
+		t.Errorf("credentials copied without a grant: %v", err)
+	}
+
+	if err := cli.Create(ctx, grant); err != nil {
+		t.Fatalf("create grant: %v", err)
+	}
+	reconcileReplicationN(t, r, 1)
+	if err := cli.Get(ctx, secretKey, &corev1.Secret{}); err != nil {
+		t.Fatalf("expected the config secret once granted: %v", err)
+	}
+
+	// Revoking the grant removes the copied credentials and the Deployment.
+	if err := cli.Delete(ctx, grant); err != nil {
+		t.Fatalf("delete grant: %v", err)
+	}
+	reconcileReplicationN(t, r, 1)
+	if err := cli.Get(ctx, secretKey, &corev1.Secret{}); !apierrors.IsNotFound(err) {
+		t.Errorf("config secret kept after revocation: %v", err)
+	}
+	if err := cli.Get(ctx, depKey, &appsv1.Deployment{}); !apierrors.IsNotFound(err) {
+		t.Errorf("deployment kept after revocation: %v", err)
+	}
+}
+
+func TestBucketReplicationToBucket(t *testing.T) {
+	br := &seaweedv1.BucketReplication{
+		ObjectMeta: metav1.ObjectMeta{Name: replicationKey.Name, Namespace: replicationKey.Namespace},
+		Spec: seaweedv1.BucketReplicationSpec{
+			SourceRef:               seaweedv1.BucketReplicationBucketRef{Name: "photos"},
+			Destination:             seaweedv1.BucketReplicationDestination{BucketRef: &seaweedv1.BucketReplicationBucketRef{Name: "photos-dr"}},
+			DeleteMarkerReplication: seaweedv1.BucketReplicationDeleteMarkersEnabled,
+		},
+	}
+	fa := newFakeAdmin()
+	fa.filerSignature = 42
+	fa.filerKV = map[string][]byte{
+		string(syncCheckpointKey("/buckets/photos", 42)): checkpointValue(replicationNow.Add(-5 * time.Second)),
+	}
+	r, cli := testReplicationReconciler(t, fa, br, replicationGrant(),
+		newReplicationBucket("photos", "prod"), newReplicationBucket("photos-dr", "dr"),
+		newReplicationCluster("prod"), newReplicationCluster("dr"))
+	reconcileReplicationN(t, r, 3)
+
+	var dep appsv1.Deployment
+	if err := cli.Get(context.Background(), types.NamespacedName{Namespace: "storage", Name: bucketReplicationDeploymentName(br)}, &dep); err != nil {
+		t.Fatalf("get deployment: %v", err)
+	}
+	cmd := strings.Join(dep.Spec.Template.Spec.Containers[0].Command, " ")
+	for _, want := range []string{
+		"filer.sync -a=prod-filer.storage:8888 -b=dr-filer.storage:8888",
+		"-a.path=/buckets/photos -b.path=/buckets/photos-dr",
+		"-isActivePassive",
+		"-b.doDeleteFiles=true",
+	} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("command %q missing %q", cmd, want)
+		}
+	}
+
+	got := &seaweedv1.BucketReplication{}
+	if err := cli.Get(context.Background(), replicationKey, got); err != nil {
+		t.Fatalf("get replication: %v", err)
+	}
+	if got.Status.LagSeconds == nil || *got.Status.LagSeconds != 5 {
+		t.Errorf("lag = %v want 5", got.Status.LagSeconds)
+	}
+
+	for name, role := range map[string]seaweedv1.BucketReplicationRole{
+		"photos":    seaweedv1.BucketReplicationRoleSource,
+		"photos-dr": seaweedv1.BucketReplicationRoleDestination,
+	} {
+		bucket := &seaweedv1.Bucket{}
+		if err := cli.Get(context.Background(), types.NamespacedName{Namespace: "media", Name: name}, bucket); err != nil {
+			t.Fatalf("get bucket: %v", err)
+		}
+		if len(bucket.Status.Replications) != 1 || bucket.Status.Replications[0].Role != role {
+			t.Errorf("%s replications = %+v want role %s", name, bucket.Status.Replications, role)
+		}
+	}
+
+	// Deletion removes the Deployment and both summaries.
+	if err := cli.Delete(context.Background(), got); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	reconcileReplicationN(t, r, 1)
+	if err := cli.Get(context.Background(), replicationKey, got); !apierrors.IsNotFound(err) {
+		t.Errorf("replication still present: %v", err)
+	}
+	if err := cli.Get(context.Background(), client.ObjectKeyFromObject(&dep), &dep); !apierrors.IsNotFound(err) {
+		t.Errorf("deployment still present: %v", err)
+	}
+	for _, name := range []string{"photos", "photos-dr"} {
+		bucket := &seaweedv1.Bucket{}
+		if err := cli.Get(context.Background(), types.NamespacedName{Namespace: "media", Name: name}, bucket); err != nil {
+			t.Fatalf("get bucket: %v", err)
+		}
+		if len(bucket.Status.Replications) != 0 {
+			t.Errorf("%s replications = %+v want none", name, bucket.Status.Replications)
+		}
+	}
+}
+
+func TestBucketReplicationToBucketNeedsGrant(t *testing.T) {
+	br := &seaweedv1.BucketReplication{
+		ObjectMeta: metav1.ObjectMeta{Name: replicationKey.Name, Namespace: replicationKey.Namespace},
+		Spec: seaweedv1.BucketReplicationSpec{
+			SourceRef:   seaweedv1.BucketReplicationBucketRef{Name: "photos"},
+			Destination: seaweedv1.BucketReplicationDestination{BucketRef: &seaweedv1.BucketReplicationBucketRef{Name: "photos-dr"}},
+		},
+	}
+	r, cli := testReplicationReconciler(t, newFakeAdmin(), br,
+		newReplicationBucket("photos", "prod"), newReplicationBucket("photos-dr", "dr"),
+		newReplicationCluster("prod"), newReplicationCluster("dr"))
+	reconcileReplicationN(t, r, 2)
+
+	got := &seaweedv1.BucketReplication{}
+	if err := cli.Get(context.Background(), replicationKey, got); err != nil {
+		t.Fatalf("get replication: %v", err)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.BucketReplicationConditionResolved)
+	if cond == nil || cond.Reason != "ReferenceGrantMissing" {
+		t.Errorf("status = %+v want ReferenceGrantMissing", got.Status)
+	}
+	var deps appsv1.DeploymentList
+	if err := cli.List(context.Background(), &deps); err != nil || len(deps.Items) != 0 {
+		t.Errorf("deployments = %d, %v; want none without a grant", len(deps.Items), err)
+	}
+}
+
+func TestBucketReplicationPendingUntilBucketProvisioned(t *testing.T) {
+	br := newRemoteReplication()
+	src := newReplicationBucket("photos", "prod")
+	src.Status.BucketName = ""
+	r, cli := testReplicationReconciler(t, newFakeAdmin(), br, src, newReplicationCluster("prod"))
+
+	if res := reconcileReplicationN(t, r, 2); res.RequeueAfter == 0 {
+		t.Errorf("expected a requeue while the bucket is unprovisioned")
+	}
+	got := &seaweedv1.BucketReplication{}
+	if err := cli.Get(context.Background(), replicationKey, got); err != nil {
+		t.Fatalf("get replication: %v", err)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.BucketReplicationConditionResolved)
+	if got.Status.Phase != seaweedv1.BucketPhasePending || cond == nil || cond.Reason != "BucketNotReady" {
+		t.Errorf("status = %+v want Pending/BucketNotReady", got.Status)
+	}
+	var deps appsv1.DeploymentList
+	if err := cli.List(context.Background(), &deps); err != nil || len(deps.Items) != 0 {
+		t.Errorf("deployments = %d, %v; want none", len(deps.Items), err)
+	}
+}
@@ -0,0 +1,221 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// Key prefixes weed uses for the offsets it persists in the filer KV store:
+// filer.backup on the source filer, filer.sync on the target filer.
+const (
+	backupCheckpointPrefix = "backup."
+	syncCheckpointPrefix   = "sync."
+)
+
+// bucketsDir is the filer directory S3 buckets live under.
+const bucketsDir = "/buckets"
+
+// bucketReplicationContainerName is the container running weed in a
+// replication Deployment.
+const bucketReplicationContainerName = "bucket-replication"
+
+// bucketReplicationDeploymentName is the name of the replication Deployment.
+// It runs in the source cluster's namespace, so the replication's own
+// namespace is folded in to keep names from different namespaces apart.
+func bucketReplicationDeploymentName(br *seaweedv1.BucketReplication) string {
+	return boundedName(br.Namespace+"-"+br.Name, "-replication")
+}
+
+// bucketReplicationSecretName is the name of the Secret holding the rendered
+// replication.toml for a remote destination.
+func bucketReplicationSecretName(br *seaweedv1.BucketReplication) string {
+	return boundedName(br.Namespace+"-"+br.Name, "-replication-config")
+}
+
+// labelsForBucketReplication are the selector labels for a replication
+// Deployment and its config Secret.
+func labelsForBucketReplication(name string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       "seaweedfs",
+		"app.kubernetes.io/component":  "bucket-replication",
+		"app.kubernetes.io/instance":   name,
+		"app.kubernetes.io/managed-by": "seaweedfs-operator",
+	}
+}
+
+// bucketFilerPath is the filer directory holding bucket's objects under the
+// directory-style prefix (which may be empty).
+func bucketFilerPath(bucket, prefix string) string {
+	return path.Join(bucketsDir, bucket, strings.TrimSuffix(prefix, "/"))
+}
+
+// bucketExcludePaths renders excludePrefixes as the comma-joined filer
+// directories weed's -filerExcludePaths / -a.excludePaths expect.
+func bucketExcludePaths(bucket string, prefixes []string) string {
+	paths := make([]string, 0, len(prefixes))
+	for _, p := range prefixes {
+		paths = append(paths, bucketFilerPath(bucket, p))
+	}
+	return strings.Join(paths, ",")
+}
+
+// remoteReplicationDirectory is the sink directory a remote replication
+// writes under: the store's base directory, the bucket, then the prefix.
+func remoteReplicationDirectory(st seaweedv1.BackupStorageSpec, bucket, prefix string) string {
+	return path.Join(storageBaseDirectory(st), bucket, strings.TrimSuffix(prefix, "/"))
+}
+
+// weedLogArgs returns the cluster's logging flags, defaulting to stderr.
+func weedLogArgs(m *seaweedv1.Seaweed) []string {
+	if la := m.Spec.LoggingArgs; len(la) > 0 {
+		return la
+	}
+	return []string{"-logtostderr=true"}
+}
+
+// bucketBackupCommand is the `weed filer.backup` invocation replicating the
+// source bucket into the sink rendered in replication.toml.
+func bucketBackupCommand(src *seaweedv1.Seaweed, br *seaweedv1.BucketReplication, srcBucket string) []string {
+	cmd := append([]string{"weed"}, weedLogArgs(src)...)
+	cmd = append(cmd,
+		"-config_dir="+backupConfigDir,
+		"filer.backup",
+		"-filer="+getFilerAddress(src),
+		"-filerPath="+bucketFilerPath(srcBucket, br.Spec.Prefix),
+		"-doDeleteFiles="+strconv.FormatBool(br.Spec.DeleteMarkerReplication == seaweedv1.BucketReplicationDeleteMarkersEnabled),
+	)
+	if len(br.Spec.ExcludePrefixes) > 0 {
+		cmd = append(cmd, "-filerExcludePaths="+bucketExcludePaths(srcBucket, br.Spec.ExcludePrefixes))
+	}
+	return append(cmd, "-initialSnapshot")
+}
+
+// bucketSyncCommand is the one-way `weed filer.sync` invocation replicating
+// the source bucket into the destination bucket on another cluster.
+func bucketSyncCommand(src, dst *seaweedv1.Seaweed, br *seaweedv1.BucketReplication, srcBucket, dstBucket string) []string {
+	cmd := append([]string{"weed"}, weedLogArgs(src)...)
+	if securityConfigNeeded(src) {
+		cmd = append(cmd, "-config_dir="+backupConfigDir)
+	}
+	cmd = append(cmd,
+		"filer.sync",
+		"-a="+getFilerAddress(src),
+		"-b="+getFilerAddress(dst),
+		"-a.path="+bucketFilerPath(srcBucket, br.Spec.Prefix),
+		"-b.path="+bucketFilerPath(dstBucket, br.Spec.Prefix),
+		"-isActivePassive",
+		"-b.doDeleteFiles="+strconv.FormatBool(br.Spec.DeleteMarkerReplication == seaweedv1.BucketReplicationDeleteMarkersEnabled),
+	)
+	if len(br.Spec.ExcludePrefixes) > 0 {
+		cmd = append(cmd, "-a.excludePaths="+bucketExcludePaths(srcBucket, br.Spec.ExcludePrefixes))
+	}
+	return cmd
+}
+
+// buildBucketReplicationDeployment fills dep with the replication Deployment
+// running command next to the source cluster. configSources are projected
+// into backupConfigDir together with the cluster's security.toml.
+func buildBucketReplicationDeployment(src *seaweedv1.Seaweed, br *seaweedv1.BucketReplication, dep *appsv1.Deployment, command []string, configSources []corev1.VolumeProjection, remote *seaweedv1.BackupStorageSpec) {
+	labels := labelsForBucketReplication(dep.Name)
+	volumes, mounts := backupConfigVolumes(src, configSources)
+	if remote != nil && remote.Type == seaweedv1.BackupStorageFilesystem && remote.Filesystem != nil {
+		vol, mount := filesystemPVCVolume(remote.Filesystem)
+		volumes = append(volumes, vol)
+		mounts = append(mounts, mount)
+	}
+
+	replicas := int32(1)
+	if br.Spec.Suspend {
+		replicas = 0
+	}
+	enableServiceLinks := false
+	dep.Labels = labels
+	dep.Spec.Replicas = &replicas
+	dep.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+	// Both weed commands checkpoint on the filer; never run two at once.
+	dep.Spec.Strategy = appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+	dep.Spec.Template.ObjectMeta.Labels = labels
+	dep.Spec.Template.Spec = corev1.PodSpec{
+		ImagePullSecrets:   src.Spec.ImagePullSecrets,
+		EnableServiceLinks: &enableServiceLinks,
+		Containers: []corev1.Container{{
+			Name:            bucketReplicationContainerName,
+			Image:           backupImage(src),
+			ImagePullPolicy: src.Spec.ImagePullPolicy,
+			Command:         command,
+			VolumeMounts:    mounts,
+		}},
+		Volumes: volumes,
+	}
+}
+
+// hashStringToLong matches weed's util.HashStringToLong: the first eight
+// bytes of the MD5 digest, big-endian.
+func hashStringToLong(s string) int64 {
+	sum := md5.Sum([]byte(s))
+	return int64(binary.BigEndian.Uint64(sum[:8]))
+}
+
+// filerCheckpointKey is the KV key weed stores an offset under: the prefix
+// followed by the big-endian 32-bit id.
+func filerCheckpointKey(prefix string, id int32) []byte {
+	key := make([]byte, len(prefix)+4)
+	copy(key, prefix)
+	binary.BigEndian.PutUint32(key[len(prefix):], uint32(id))
+	return key
+}
+
+// backupCheckpointKey is the source-filer key filer.backup persists its
+// offset under, derived from the sink's name and directory.
+func backupCheckpointKey(st seaweedv1.BackupStorageSpec, sinkDir string) []byte {
+	return filerCheckpointKey(backupCheckpointPrefix, int32(hashStringToLong(sinkName(st.Type)+sinkDir)))
+}
+
+// syncCheckpointKey is the target-filer key filer.sync persists its offset
+// for a source filer and path under.
+func syncCheckpointKey(sourcePath string, sourceSignature int32) []byte {
+	prefix := syncCheckpointPrefix
+	if sourcePath != "" && sourcePath != "/" {
+		prefix += sourcePath
+	}
+	return filerCheckpointKey(prefix, sourceSignature)
+}
+
+// parseFilerCheckpoint decodes a stored offset (big-endian nanoseconds since
+// the epoch). ok is false when no checkpoint has been written yet.
+func parseFilerCheckpoint(value []byte) (t time.Time, ok bool) {
+	if len(value) < 8 {
+		return time.Time{}, false
+	}
+	ns := int64(binary.BigEndian.Uint64(value))
+	if ns <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, ns), true
+}
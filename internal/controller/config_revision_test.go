@@ -0,0 +1,38 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import "testing"
+
+func TestConfigRevision(t *testing.T) {
+	a := configRevision(map[string][]byte{"a": []byte("1"), "b": []byte("2")})
+	if a != configRevision(map[string][]byte{"b": []byte("2"), "a": []byte("1")}) {
+		t.Errorf("revision depends on map order")
+	}
+	if a == configRevision(map[string][]byte{"a": []byte("12"), "b": nil}) {
+		t.Errorf("moving bytes between keys must change the revision")
+	}
+
+	user := map[string]string{"team": "storage"}
+	got := withRevisionAnnotation(user, "seaweed.seaweedfs.com/config", a)
+	if got["team"] != "storage" || got["seaweed.seaweedfs.com/config"] != a {
+		t.Errorf("annotations = %v", got)
+	}
+	if len(user) != 1 {
+		t.Errorf("withRevisionAnnotation modified its input")
+	}
+}
@@ -0,0 +1,258 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// Admin task policy reconciliation.
+//
+// The admin server keeps one policy per maintenance task type under
+// policy.task_policies in its maintenance config, each holding the shared
+// scheduling fields plus a "<task>_config" block. The operator reads the
+// config back, overlays the fields spec.admin.tasks sets and writes it only
+// when something changed, so fields it does not manage are left alone.
+
+// Task types, the keys of policy.task_policies.
+const (
+	adminTaskVacuum        = "vacuum"
+	adminTaskErasureCoding = "erasure_coding"
+	adminTaskBalance       = "balance"
+	adminTaskReplication   = "replication"
+)
+
+// adminTaskPolicies converts spec.admin.tasks to the task policy fields it
+// sets, keyed by task type. Numbers are float64, as they decode from JSON.
+func adminTaskPolicies(spec *seaweedv1.AdminTasksSpec) (map[string]map[string]interface{}, error) {
+	out := map[string]map[string]interface{}{}
+	if spec == nil {
+		return out, nil
+	}
+	if t := spec.Vacuum; t != nil {
+		cfg := map[string]interface{}{}
+		setPercent(cfg, "garbage_threshold", t.GarbageThresholdPercent)
+		setSeconds(cfg, "min_volume_age_seconds", t.MinVolumeAge)
+		setSeconds(cfg, "min_interval_seconds", t.MinInterval)
+		out[adminTaskVacuum] = adminTaskPolicyFields(&t.AdminTaskPolicy, adminTaskVacuum, cfg)
+	}
+	if t := spec.ErasureCoding; t != nil {
+		cfg := map[string]interface{}{}
+		setPercent(cfg, "fullness_ratio", t.FullnessPercent)
+		setSeconds(cfg, "quiet_for_seconds", t.QuietFor)
+		if t.MinVolumeSize != nil {
+			bytes, ok := t.MinVolumeSize.AsInt64()
+			if !ok || bytes < 0 {
+				return nil, fmt.Errorf("erasureCoding minVolumeSize %s must be a byte count", t.MinVolumeSize.String())
+			}
+			const mib = int64(1024 * 1024)
+			cfg["min_volume_size_mb"] = float64((bytes + mib - 1) / mib)
+		}
+		if t.CollectionFilter != nil {
+			cfg["collection_filter"] = *t.CollectionFilter
+		}
+		out[adminTaskErasureCoding] = adminTaskPolicyFields(&t.AdminTaskPolicy, adminTaskErasureCoding, cfg)
+	}
+	if t := spec.Balance; t != nil {
+		cfg := map[string]interface{}{}
+		setPercent(cfg, "imbalance_threshold", t.ImbalanceThresholdPercent)
+		setInt(cfg, "min_server_count", t.MinServerCount)
+		out[adminTaskBalance] = adminTaskPolicyFields(&t.AdminTaskPolicy, adminTaskBalance, cfg)
+	}
+	if t := spec.Replication; t != nil {
+		cfg := map[string]interface{}{}
+		setInt(cfg, "target_replica_count", t.TargetReplicaCount)
+		out[adminTaskReplication] = adminTaskPolicyFields(&t.AdminTaskPolicy, adminTaskReplication, cfg)
+	}
+	return out, nil
+}
+
+// adminTaskPolicyFields returns the shared policy fields p sets plus the
+// task's config block, when it sets anything.
+func adminTaskPolicyFields(p *seaweedv1.AdminTaskPolicy, task string, cfg map[string]interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if p.Enabled != nil {
+		fields["enabled"] = *p.Enabled
+	}
+	setInt(fields, "max_concurrent", p.MaxConcurrent)
+	setSeconds(fields, "repeat_interval_seconds", p.RepeatInterval)
+	setSeconds(fields, "check_interval_seconds", p.CheckInterval)
+	if len(cfg) > 0 {
+		fields[task+"_config"] = cfg
+	}
+	return fields
+}
+
+func setInt(m map[string]interface{}, key string, v *int32) {
+	if v != nil {
+		m[key] = float64(*v)
+	}
+}
+
+// setPercent stores a percentage as the ratio the admin server expects.
+func setPercent(m map[string]interface{}, key string, v *int32) {
+	if v != nil {
+		m[key] = float64(*v) / 100
+	}
+}
+
+func setSeconds(m map[string]interface{}, key string, d *metav1.Duration) {
+	if d != nil {
+		m[key] = float64(d.Duration / time.Second)
+	}
+}
+
+// mergeAdminTaskPolicies overlays want onto config's task policies and
+// reports whether any value changed.
+func mergeAdminTaskPolicies(config map[string]interface{}, want map[string]map[string]interface{}) bool {
+	policy := childObject(config, "policy")
+	policies := childObject(policy, "task_policies")
+	changed := false
+	for task, fields := range want {
+		if overlayObject(childObject(policies, task), fields) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// childObject returns m[key] as an object, replacing anything else stored
+// there with an empty one.
+func childObject(m map[string]interface{}, key string) map[string]interface{} {
+	if child, ok := m[key].(map[string]interface{}); ok {
+		return child
+	}
+	child := map[string]interface{}{}
+	m[key] = child
+	return child
+}
+
+// overlayObject copies src into dst, recursing into nested objects, and
+// reports whether dst changed.
+func overlayObject(dst, src map[string]interface{}) bool {
+	changed := false
+	for k, v := range src {
+		if nested, ok := v.(map[string]interface{}); ok {
+			if overlayObject(childObject(dst, k), nested) {
+				changed = true
+			}
+			continue
+		}
+		if !reflect.DeepEqual(dst[k], v) {
+			dst[k] = v
+			changed = true
+		}
+	}
+	return changed
+}
+
+// adminEndpoint is the admin server's in-cluster base URL, including any
+// -urlPrefix it is mounted under.
+func adminEndpoint(m *seaweedv1.Seaweed) string {
+	return fmt.Sprintf("http://%s-admin.%s:%d%s", m.Name, m.Namespace, seaweedv1.AdminHTTPPort,
+		adminURLPrefix(m.BaseAdminSpec().ExtraArgs()))
+}
+
+// loadAdminCredentials reads the admin login from spec.admin.credentialsSecret,
+// or returns nil when the admin server runs without one.
+func (r *SeaweedReconciler) loadAdminCredentials(ctx context.Context, m *seaweedv1.Seaweed) (*AdminCredentials, error) {
+	ref := m.Spec.Admin.CredentialsSecret
+	if ref == nil || ref.Name == "" {
+		return nil, nil
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: m.Namespace, Name: ref.Name}, secret); err != nil {
+		return nil, fmt.Errorf("read admin credentials secret %s: %w", ref.Name, err)
+	}
+	user, password := string(secret.Data["adminUser"]), string(secret.Data["adminPassword"])
+	if user == "" || password == "" {
+		return nil, nil
+	}
+	return &AdminCredentials{Username: user, Password: password}, nil
+}
+
+// ensureAdminTasks pushes spec.admin.tasks to the admin server and reports
+// the outcome on the AdminTasksApplied condition. It waits until the admin
+// server is ready; failures are surfaced on the condition rather than
+// blocking the rest of the reconcile. Removing the block leaves the applied
+// policies in place.
+func (r *SeaweedReconciler) ensureAdminTasks(ctx context.Context, m *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+	if r.AdminMaintenanceFactory == nil {
+		return ReconcileResult(nil)
+	}
+	if m.Spec.Admin == nil || m.Spec.Admin.Tasks == nil {
+		meta.RemoveStatusCondition(&m.Status.Conditions, seaweedv1.SeaweedConditionAdminTasksApplied)
+		return ReconcileResult(nil)
+	}
+	if m.Status.Admin.ReadyReplicas == 0 {
+		return ReconcileResult(nil)
+	}
+
+	want, err := adminTaskPolicies(m.Spec.Admin.Tasks)
+	if err == nil {
+		err = r.applyAdminTasks(ctx, m, want)
+	}
+	if err != nil {
+		r.Log.Error(err, "apply admin task policies", "seaweed", m.Name)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(m, corev1.EventTypeWarning, "AdminTasksFailed", "%v", err)
+		}
+		meta.SetStatusCondition(&m.Status.Conditions, metav1.Condition{
+			Type:               seaweedv1.SeaweedConditionAdminTasksApplied,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: m.Generation,
+			Reason:             "ApplyFailed",
+			Message:            err.Error(),
+		})
+		return ReconcileResult(nil)
+	}
+	meta.SetStatusCondition(&m.Status.Conditions, metav1.Condition{
+		Type:               seaweedv1.SeaweedConditionAdminTasksApplied,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: m.Generation,
+		Reason:             "Applied",
+	})
+	return ReconcileResult(nil)
+}
+
+// applyAdminTasks rewrites the maintenance config when want differs from it.
+func (r *SeaweedReconciler) applyAdminTasks(ctx context.Context, m *seaweedv1.Seaweed, want map[string]map[string]interface{}) error {
+	creds, err := r.loadAdminCredentials(ctx, m)
+	if err != nil {
+		return err
+	}
+	client := r.AdminMaintenanceFactory(adminEndpoint(m), creds)
+	config, err := client.GetMaintenanceConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if !mergeAdminTaskPolicies(config, want) {
+		return nil
+	}
+	return client.UpdateMaintenanceConfig(ctx, config)
+}
@@ -0,0 +1,252 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+type fakeAdminMaintenance struct {
+	config  map[string]interface{}
+	getErr  error
+	updates int
+	creds   *AdminCredentials
+}
+
+func (f *fakeAdminMaintenance) GetMaintenanceConfig(context.Context) (map[string]interface{}, error) {
+	if f.getErr != nil {
+		return nil, f.getErr
+	}
+	// Hand out a copy, as a JSON round trip would.
+	data, _ := json.Marshal(f.config)
+	out := map[string]interface{}{}
+	_ = json.Unmarshal(data, &out)
+	return out, nil
+}
+
+func (f *fakeAdminMaintenance) UpdateMaintenanceConfig(_ context.Context, config map[string]interface{}) error {
+	f.updates++
+	f.config = config
+	return nil
+}
+
+func TestAdminTaskPolicies(t *testing.T) {
+	enabled := true
+	size := resource.MustParse("1500Ki")
+	got, err := adminTaskPolicies(&seaweedv1.AdminTasksSpec{
+		Vacuum: &seaweedv1.AdminVacuumTask{
+			AdminTaskPolicy:         seaweedv1.AdminTaskPolicy{Enabled: &enabled, CheckInterval: &metav1.Duration{Duration: 90 * time.Minute}},
+			GarbageThresholdPercent: int32Ptr(30),
+		},
+		ErasureCoding: &seaweedv1.AdminErasureCodingTask{
+			AdminTaskPolicy: seaweedv1.AdminTaskPolicy{MaxConcurrent: int32Ptr(2)},
+			FullnessPercent: int32Ptr(95),
+			QuietFor:        &metav1.Duration{Duration: time.Hour},
+			MinVolumeSize:   &size,
+		},
+		Replication: &seaweedv1.AdminReplicationTask{},
+	})
+	if err != nil {
+		t.Fatalf("adminTaskPolicies: %v", err)
+	}
+	want := map[string]map[string]interface{}{
+		"vacuum": {
+			"enabled":                true,
+			"check_interval_seconds": float64(5400),
+			"vacuum_config":          map[string]interface{}{"garbage_threshold": 0.3},
+		},
+		"erasure_coding": {
+			"max_concurrent": float64(2),
+			"erasure_coding_config": map[string]interface{}{
+				"fullness_ratio":     0.95,
+				"quiet_for_seconds":  float64(3600),
+				"min_volume_size_mb": float64(2), // rounded up to whole MiB
+			},
+		},
+		// An empty block sets nothing but is still tracked.
+		"replication": {},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("policies = %#v\nwant %#v", got, want)
+	}
+}
+
+func TestMergeAdminTaskPolicies(t *testing.T) {
+	config := map[string]interface{}{
+		"enabled": true,
+		"policy": map[string]interface{}{
+			"global_max_concurrent": float64(4),
+			"task_policies": map[string]interface{}{
+				"vacuum": map[string]interface{}{
+					"enabled":        true,
+					"max_concurrent": float64(2),
+					"vacuum_config":  map[string]interface{}{"garbage_threshold": 0.3, "min_interval_seconds": float64(600)},
+				},
+			},
+		},
+	}
+	want := map[string]map[string]interface{}{
+		"vacuum": {"vacuum_config": map[string]interface{}{"garbage_threshold": 0.3}},
+	}
+	if mergeAdminTaskPolicies(config, want) {
+		t.Errorf("an already applied policy reported a change")
+	}
+
+	want["vacuum"]["max_concurrent"] = float64(1)
+	want["balance"] = map[string]interface{}{"enabled": false}
+	if !mergeAdminTaskPolicies(config, want) {
+		t.Fatalf("changed policies reported no change")
+	}
+	policies := config["policy"].(map[string]interface{})["task_policies"].(map[string]interface{})
+	vacuum := policies["vacuum"].(map[string]interface{})
+	if vacuum["max_concurrent"] != float64(1) || vacuum["enabled"] != true {
+		t.Errorf("vacuum = %v", vacuum)
+	}
+	if got := vacuum["vacuum_config"].(map[string]interface{})["min_interval_seconds"]; got != float64(600) {
+		t.Errorf("unmanaged field lost: min_interval_seconds = %v", got)
+	}
+	if got := policies["balance"]; !reflect.DeepEqual(got, map[string]interface{}{"enabled": false}) {
+		t.Errorf("balance = %v", got)
+	}
+	if got := config["policy"].(map[string]interface{})["global_max_concurrent"]; got != float64(4) {
+		t.Errorf("unmanaged field lost: global_max_concurrent = %v", got)
+	}
+}
+
+func TestEnsureAdminTasks(t *testing.T) {
+	sw := newTestSeaweed()
+	sw.Spec.Admin = &seaweedv1.AdminSpec{
+		CredentialsSecret: &corev1.LocalObjectReference{Name: "admin-creds"},
+		Tasks: &seaweedv1.AdminTasksSpec{
+			Balance: &seaweedv1.AdminBalanceTask{ImbalanceThresholdPercent: int32Ptr(20)},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "admin-creds", Namespace: sw.Namespace},
+		Data:       map[string][]byte{"adminUser": []byte("admin"), "adminPassword": []byte("s3cret")},
+	}
+	r, _ := newNotificationReconciler(t, secret)
+	fa := &fakeAdminMaintenance{config: map[string]interface{}{}}
+	var endpoint string
+	r.AdminMaintenanceFactory = func(e string, creds *AdminCredentials) AdminMaintenanceClient {
+		endpoint, fa.creds = e, creds
+		return fa
+	}
+
+	// Nothing is pushed until the admin server is ready.
+	if done, _, err := r.ensureAdminTasks(context.Background(), sw); done || err != nil {
+		t.Fatalf("ensureAdminTasks = %v, %v", done, err)
+	}
+	if fa.updates != 0 || meta.FindStatusCondition(sw.Status.Conditions, seaweedv1.SeaweedConditionAdminTasksApplied) != nil {
+		t.Fatalf("applied before the admin server was ready")
+	}
+
+	sw.Status.Admin.ReadyReplicas = 1
+	if _, _, err := r.ensureAdminTasks(context.Background(), sw); err != nil {
+		t.Fatalf("ensureAdminTasks: %v", err)
+	}
+	if endpoint != "http://prod-admin.seaweedfs:23646" {
+		t.Errorf("endpoint = %q", endpoint)
+	}
+	if fa.creds == nil || fa.creds.Username != "admin" || fa.creds.Password != "s3cret" {
+		t.Errorf("creds = %+v", fa.creds)
+	}
+	if fa.updates != 1 {
+		t.Fatalf("updates = %d want 1", fa.updates)
+	}
+	if !meta.IsStatusConditionTrue(sw.Status.Conditions, seaweedv1.SeaweedConditionAdminTasksApplied) {
+		t.Errorf("expected %s=True, got %+v", seaweedv1.SeaweedConditionAdminTasksApplied, sw.Status.Conditions)
+	}
+
+	// In sync: read but not rewritten.
+	if _, _, err := r.ensureAdminTasks(context.Background(), sw); err != nil || fa.updates != 1 {
+		t.Errorf("unexpected rewrite without drift: updates=%d err=%v", fa.updates, err)
+	}
+
+	// Failures surface on the condition without failing the reconcile.
+	fa.getErr = errors.New("admin unavailable")
+	if done, _, err := r.ensureAdminTasks(context.Background(), sw); done || err != nil {
+		t.Fatalf("ensureAdminTasks on failure = %v, %v", done, err)
+	}
+	cond := meta.FindStatusCondition(sw.Status.Conditions, seaweedv1.SeaweedConditionAdminTasksApplied)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != "ApplyFailed" {
+		t.Errorf("condition = %+v want False/ApplyFailed", cond)
+	}
+
+	// Removing the block drops the condition and leaves the admin alone.
+	fa.getErr = nil
+	sw.Spec.Admin.Tasks = nil
+	if _, _, err := r.ensureAdminTasks(context.Background(), sw); err != nil {
+		t.Fatalf("ensureAdminTasks on removal: %v", err)
+	}
+	if fa.updates != 1 || meta.FindStatusCondition(sw.Status.Conditions, seaweedv1.SeaweedConditionAdminTasksApplied) != nil {
+		t.Errorf("removal: updates=%d conditions=%+v", fa.updates, sw.Status.Conditions)
+	}
+}
+
+func TestHTTPAdminMaintenanceClient(t *testing.T) {
+	stored := map[string]interface{}{"enabled": true}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == "/admin-ui/login" && req.Method == http.MethodPost:
+			if req.FormValue("username") != "admin" || req.FormValue("password") != "s3cret" {
+				http.Redirect(w, req, "/admin-ui/login?error=invalid", http.StatusSeeOther)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "ok", Path: "/"})
+			http.Redirect(w, req, "/admin-ui/admin", http.StatusSeeOther)
+		case req.URL.Path == "/admin-ui/api/maintenance/config":
+			if c, err := req.Cookie("session"); err != nil || c.Value != "ok" {
+				http.Redirect(w, req, "/admin-ui/login", http.StatusSeeOther)
+				return
+			}
+			if req.Method == http.MethodPut {
+				stored = map[string]interface{}{}
+				if err := json.NewDecoder(req.Body).Decode(&stored); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					_, _ = w.Write([]byte(`{"error":"bad config"}`))
+					return
+				}
+			}
+			_ = json.NewEncoder(w).Encode(stored)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := NewHTTPAdminMaintenanceClient(srv.URL+"/admin-ui", &AdminCredentials{Username: "admin", Password: "s3cret"})
+	config, err := c.GetMaintenanceConfig(ctx)
+	if err != nil {
+		t.Fatalf("GetMaintenanceConfig: %v", err)
+	}
+	config["scan_interval_seconds"] = float64(60)
+	if err := c.UpdateMaintenanceConfig(ctx, config); err != nil {
+		t.Fatalf("UpdateMaintenanceConfig: %v", err)
+	}
+	if stored["scan_interval_seconds"] != float64(60) || stored["enabled"] != true {
+		t.Errorf("stored = %v", stored)
+	}
+
+	bad := NewHTTPAdminMaintenanceClient(srv.URL+"/admin-ui", &AdminCredentials{Username: "admin", Password: "wrong"})
+	if _, err := bad.GetMaintenanceConfig(ctx); err == nil {
+		t.Errorf("expected a rejected login to fail")
+	}
+	anon := NewHTTPAdminMaintenanceClient(srv.URL+"/admin-ui", nil)
+	if _, err := anon.GetMaintenanceConfig(ctx); err == nil {
+		t.Errorf("expected a request without a session to fail")
+	}
+}
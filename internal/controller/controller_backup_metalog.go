@@ -0,0 +1,108 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// ensureBackupMetaLogs reconciles the continuous metadata-log Deployments
+// declared in spec.backup.metadataLog and prunes any that are no longer
+// declared. Like ensureBackupMirrors it records per-log status in memory for
+// updateStatus to persist.
+func (r *SeaweedReconciler) ensureBackupMetaLogs(ctx context.Context, m *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+	var logs []seaweedv1.BackupMetadataLogSpec
+	if m.Spec.Backup != nil {
+		logs = m.Spec.Backup.MetadataLog
+	}
+	if len(logs) > 0 && r.BackupToolImage == "" {
+		if r.Recorder != nil {
+			r.Recorder.Event(m, corev1.EventTypeWarning, "BackupMetadataLogUnavailable",
+				"spec.backup.metadataLog needs the operator's backup-tool image; set "+BackupToolImageEnv+" on the operator")
+		}
+		logs = nil
+	}
+
+	desired := map[string]bool{}
+	var statuses []seaweedv1.BackupMirrorStatus
+
+	for _, l := range logs {
+		st, err := resolveStorage(m, l.StorageName)
+		if err != nil {
+			r.Log.Error(err, "backup metadata log references unknown storage", "storage", l.StorageName)
+			if r.Recorder != nil {
+				r.Recorder.Eventf(m, corev1.EventTypeWarning, "BackupMetadataLogInvalid", "%v", err)
+			}
+			continue
+		}
+
+		depName := metaLogDeploymentName(m.Name, l.StorageName)
+		dep := buildMetaLogDeployment(m, depName, r.BackupToolImage, l, st)
+		if err := controllerutil.SetControllerReference(m, dep, r.Scheme); err != nil {
+			return ReconcileResult(err)
+		}
+		if _, err := r.CreateOrUpdateDeployment(dep); err != nil {
+			return ReconcileResult(err)
+		}
+
+		desired[depName] = true
+		statuses = append(statuses, seaweedv1.BackupMirrorStatus{
+			StorageName:    l.StorageName,
+			DeploymentName: depName,
+			Ready:          r.mirrorReady(ctx, m.Namespace, depName),
+		})
+	}
+
+	if err := r.pruneBackupMetaLogs(ctx, m, desired); err != nil {
+		return ReconcileResult(err)
+	}
+
+	m.Status.BackupMetadataLogs = statuses
+	return ReconcileResult(nil)
+}
+
+// pruneBackupMetaLogs deletes metadata-log Deployments that are no longer
+// declared in spec.backup.metadataLog. Segments already written are left on
+// the storage for restores to use.
+func (r *SeaweedReconciler) pruneBackupMetaLogs(ctx context.Context, m *seaweedv1.Seaweed, keep map[string]bool) error {
+	var deps appsv1.DeploymentList
+	if err := r.List(ctx, &deps, client.InNamespace(m.Namespace), client.MatchingLabels{
+		"app.kubernetes.io/component": "backup-metalog",
+		"app.kubernetes.io/instance":  m.Name,
+	}); err != nil {
+		return err
+	}
+	for i := range deps.Items {
+		d := &deps.Items[i]
+		if keep[d.Name] {
+			continue
+		}
+		if err := r.Delete(ctx, d); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
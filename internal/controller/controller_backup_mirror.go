@@ -19,6 +19,7 @@ package controller
 import (
 	"context"
 	"fmt"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -71,9 +72,16 @@ func (r *SeaweedReconciler) ensureBackupMirrors(ctx context.Context, m *seaweedv
 			return done, res, err
 		}
 
+		var pods corev1.PodList
+		if err := r.List(ctx, &pods, client.InNamespace(m.Namespace), client.MatchingLabels(labelsForBackupMirror(m.Name, mirror.StorageName))); err != nil {
+			return ReconcileResult(err)
+		}
 		depName := mirrorDeploymentName(m.Name, mirror.StorageName)
+		status := r.observeMirrorCheckpoint(ctx, m, mirror, st, pods.Items)
+		status.DeploymentName = depName
+
 		hasGCSKey := len(creds[seaweedv1.BackupSecretKeyGCSCredentials]) > 0
-		dep := buildMirrorDeployment(m, depName, secretName, mirror, st, hasGCSKey)
+		dep := buildMirrorDeployment(m, depName, secretName, mirror, st, hasGCSKey, status.InitialSnapshot)
 		if err := controllerutil.SetControllerReference(m, dep, r.Scheme); err != nil {
 			return ReconcileResult(err)
 		}
@@ -82,11 +90,14 @@ func (r *SeaweedReconciler) ensureBackupMirrors(ctx context.Context, m *seaweedv
 		}
 
 		desired[depName] = true
-		statuses = append(statuses, seaweedv1.BackupMirrorStatus{
-			StorageName:    mirror.StorageName,
-			DeploymentName: depName,
-			Ready:          r.mirrorReady(ctx, m.Namespace, depName),
-		})
+		status.Ready = r.mirrorReady(ctx, m.Namespace, depName)
+		for i := range pods.Items {
+			for _, cs := range pods.Items[i].Status.ContainerStatuses {
+				status.Restarts += cs.RestartCount
+			}
+		}
+		status.ErrorCount = r.mirrorErrorCount(ctx, m, mirror.StorageName, pods.Items)
+		statuses = append(statuses, status)
 	}
 
 	if err := r.pruneBackupMirrors(ctx, m, desired); err != nil {
@@ -97,6 +108,115 @@ func (r *SeaweedReconciler) ensureBackupMirrors(ctx context.Context, m *seaweedv
 	return ReconcileResult(nil)
 }
 
+// mirrorErrorCount is the number of errors the mirror's running containers
+// have logged. A log that cannot be read keeps the previous count.
+func (r *SeaweedReconciler) mirrorErrorCount(ctx context.Context, m *seaweedv1.Seaweed, storage string, pods []corev1.Pod) int32 {
+	var prev int32
+	for _, s := range m.Status.BackupMirrors {
+		if s.StorageName == storage {
+			prev = s.ErrorCount
+		}
+	}
+	if r.PodLogs == nil {
+		return prev
+	}
+	n, err := scrapeContainerErrors(ctx, r.PodLogs, pods, mirrorContainerName)
+	if err != nil {
+		r.Log.V(1).Info("read backup mirror logs", "storage", storage, "err", err.Error())
+		return prev
+	}
+	return n
+}
+
+// observeMirrorCheckpoint returns a data mirror's status carrying its sync
+// progress: the checkpoint filer.backup persists on the filer for the mirror's
+// sink, and whether the mirror must walk the whole subtree. It walks while the
+// sink has no checkpoint yet, and after a new spec resync value until the
+// checkpoint moves past the start of a pod created for the resync; otherwise
+// it resumes from the checkpoint. A checkpoint that cannot be read keeps the
+// previous observation.
+func (r *SeaweedReconciler) observeMirrorCheckpoint(ctx context.Context, m *seaweedv1.Seaweed, mirror seaweedv1.BackupMirrorSpec, st seaweedv1.BackupStorageSpec, pods []corev1.Pod) seaweedv1.BackupMirrorStatus {
+	status := seaweedv1.BackupMirrorStatus{StorageName: mirror.StorageName}
+	for _, prev := range m.Status.BackupMirrors {
+		if prev.StorageName == mirror.StorageName {
+			status.LastSyncedTime = prev.LastSyncedTime
+			status.Resync = prev.Resync
+			status.ResyncStartTime = prev.ResyncStartTime
+		}
+	}
+	if r.BucketAdminFactory != nil && m.Status.Filer.ReadyReplicas > 0 {
+		synced, err := r.mirrorCheckpoint(ctx, m, mirror.StorageName, st)
+		if err != nil {
+			r.Log.V(1).Info("read backup mirror checkpoint", "storage", mirror.StorageName, "err", err.Error())
+		} else {
+			status.LastSyncedTime = synced
+		}
+	}
+	now := r.now()
+	if status.LastSyncedTime != nil {
+		lag := int64(now.Sub(status.LastSyncedTime.Time) / time.Second)
+		if lag < 0 {
+			lag = 0
+		}
+		status.LagSeconds = &lag
+	}
+
+	if mirror.Resync != "" && mirror.Resync != status.Resync {
+		status.Resync = mirror.Resync
+		status.ResyncStartTime = &metav1.Time{Time: now}
+	} else if status.ResyncStartTime != nil && resyncDone(status.ResyncStartTime, status.LastSyncedTime, pods) {
+		status.ResyncStartTime = nil
+	}
+	status.InitialSnapshot = status.LastSyncedTime == nil || status.ResyncStartTime != nil
+	return status
+}
+
+// resyncDone reports whether the walk a resync started has finished: every
+// mirror pod was created after the resync started, so none of them resumed
+// from the old checkpoint, and the checkpoint has moved past their creation.
+// Checkpoints a pod still stopping for the resync writes are older than the
+// pods that replace it.
+func resyncDone(start, synced *metav1.Time, pods []corev1.Pod) bool {
+	if synced == nil || len(pods) == 0 {
+		return false
+	}
+	for i := range pods {
+		created := pods[i].CreationTimestamp
+		if created.Before(start) || !synced.After(created.Time) {
+			return false
+		}
+	}
+	return true
+}
+
+// mirrorCheckpoint reads the checkpoint filer.backup persists on the filer
+// for a data mirror's sink; nil when none has been written yet.
+func (r *SeaweedReconciler) mirrorCheckpoint(ctx context.Context, m *seaweedv1.Seaweed, storageName string, st seaweedv1.BackupStorageSpec) (*metav1.Time, error) {
+	adminKey, err := loadFilerAdminSigningKey(ctx, r.Client, m)
+	if err != nil {
+		return nil, err
+	}
+	dialOption, _, err := loadSeaweedGrpcDialOption(ctx, r.Client, m)
+	if err != nil {
+		return nil, err
+	}
+	admin, err := r.BucketAdminFactory(getMasterPeersString(m), getFilerAddress(m), adminKey, dialOption, r.Log)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBucketAdmin(admin, r.Log)
+
+	value, err := admin.GetFilerKV(ctx, backupCheckpointKey(st, mirrorSinkDirectory(storageName, st, m.Name)))
+	if err != nil {
+		return nil, err
+	}
+	ts, ok := parseFilerCheckpoint(value)
+	if !ok {
+		return nil, nil
+	}
+	return &metav1.Time{Time: ts}, nil
+}
+
 // ensureMirrorSecret renders the replication config Secret backing a mirror.
 func (r *SeaweedReconciler) ensureMirrorSecret(ctx context.Context, m *seaweedv1.Seaweed, name, storage string, st seaweedv1.BackupStorageSpec, toml string, creds map[string][]byte) (bool, ctrl.Result, error) {
 	data := map[string][]byte{"replication.toml": []byte(toml)}
@@ -125,13 +245,19 @@ func (r *SeaweedReconciler) ensureMirrorSecret(ctx context.Context, m *seaweedv1
 // secret is tolerated for s3/gcs (ambient credentials) but is an error for
 // azure/b2, whose keys must be baked into replication.toml.
 func (r *SeaweedReconciler) resolveBackupCredentials(ctx context.Context, m *seaweedv1.Seaweed, st seaweedv1.BackupStorageSpec) (map[string][]byte, error) {
+	return readBackupCredentials(ctx, r.Client, m.Namespace, st)
+}
+
+// readBackupCredentials returns the data of st's CredentialsSecret in
+// namespace, or an empty map when the storage names none.
+func readBackupCredentials(ctx context.Context, c client.Client, namespace string, st seaweedv1.BackupStorageSpec) (map[string][]byte, error) {
 	if st.CredentialsSecret == nil || *st.CredentialsSecret == "" {
 		return map[string][]byte{}, nil
 	}
 	var secret corev1.Secret
-	if err := r.Get(ctx, types.NamespacedName{Namespace: m.Namespace, Name: *st.CredentialsSecret}, &secret); err != nil {
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: *st.CredentialsSecret}, &secret); err != nil {
 		if apierrors.IsNotFound(err) {
-			return nil, fmt.Errorf("backup credentials secret %q not found in namespace %q", *st.CredentialsSecret, m.Namespace)
+			return nil, fmt.Errorf("backup credentials secret %q not found in namespace %q", *st.CredentialsSecret, namespace)
 		}
 		return nil, err
 	}
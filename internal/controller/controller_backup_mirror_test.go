@@ -0,0 +1,163 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+var mirrorNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func mirrorPod(name string, created time.Time, restarts int32) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "ns1",
+			Labels:            labelsForBackupMirror("c1", "pvc"),
+			CreationTimestamp: metav1.Time{Time: created},
+		},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name:         mirrorContainerName,
+			RestartCount: restarts,
+			State:        corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+		}}},
+	}
+}
+
+// reconcileMirror runs ensureBackupMirrors for m and returns the mirror
+// Deployment's command and the mirror's status.
+func reconcileMirror(t *testing.T, r *SeaweedReconciler, m *seaweedv1.Seaweed) ([]string, seaweedv1.BackupMirrorStatus) {
+	t.Helper()
+	if done, _, err := r.ensureBackupMirrors(context.Background(), m); done || err != nil {
+		t.Fatalf("ensureBackupMirrors = %v, %v", done, err)
+	}
+	var dep appsv1.Deployment
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: "ns1", Name: mirrorDeploymentName("c1", "pvc")}, &dep); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Status.BackupMirrors) != 1 {
+		t.Fatalf("mirror statuses = %+v, want one", m.Status.BackupMirrors)
+	}
+	return dep.Spec.Template.Spec.Containers[0].Command, m.Status.BackupMirrors[0]
+}
+
+func TestBackupMirrorResumesFromCheckpoint(t *testing.T) {
+	m := clusterWithFilesystemStorage()
+	m.Spec.Filer = &seaweedv1.FilerSpec{Replicas: 1}
+	m.Spec.Backup.DataMirror = []seaweedv1.BackupMirrorSpec{{StorageName: "pvc", FilerPath: "/"}}
+	m.Status.Filer.ReadyReplicas = 1
+	r, cli := newNotificationReconciler(t)
+	fa := newFakeAdmin()
+	r.BucketAdminFactory = func(_, _ string, _ []byte, _ grpc.DialOption, _ logr.Logger) (BucketAdmin, error) {
+		return fa, nil
+	}
+	now := mirrorNow
+	r.Now = func() time.Time { return now }
+	st := m.Spec.Backup.Storages["pvc"]
+	key := string(backupCheckpointKey(st, mirrorSinkDirectory("pvc", st, "c1")))
+
+	// A fresh sink has no checkpoint: the mirror seeds it with a full walk.
+	cmd, status := reconcileMirror(t, r, m)
+	if !slices.Contains(cmd, "-initialSnapshot") || !status.InitialSnapshot {
+		t.Errorf("fresh sink: command %v, status %+v, want an initial snapshot", cmd, status)
+	}
+	if status.LastSyncedTime != nil || status.LagSeconds != nil {
+		t.Errorf("fresh sink reported sync progress: %+v", status)
+	}
+
+	// Once filer.backup has checkpointed, restarts resume from the checkpoint.
+	fa.filerKV = map[string][]byte{key: checkpointValue(now.Add(-90 * time.Second))}
+	r.PodLogs = fakePodLogs{
+		"ns1/mirror-a/" + mirrorContainerName: weedErrorLog,
+		"ns1/mirror-b/" + mirrorContainerName: "",
+	}
+	if err := cli.Create(context.Background(), mirrorPod("mirror-a", now.Add(-time.Hour), 3)); err != nil {
+		t.Fatal(err)
+	}
+	cmd, status = reconcileMirror(t, r, m)
+	if slices.Contains(cmd, "-initialSnapshot") || status.InitialSnapshot {
+		t.Errorf("checkpointed sink: command %v, status %+v, want a resume", cmd, status)
+	}
+	if status.LastSyncedTime == nil || !status.LastSyncedTime.Equal(now.Add(-90*time.Second)) {
+		t.Errorf("lastSyncedTime = %v", status.LastSyncedTime)
+	}
+	if status.LagSeconds == nil || *status.LagSeconds != 90 || status.ErrorCount != 2 || status.Restarts != 3 {
+		t.Errorf("lag %v, errors %d, restarts %d, want 90s, 2 and 3", status.LagSeconds, status.ErrorCount, status.Restarts)
+	}
+
+	// An unreadable checkpoint keeps the last observation.
+	fa.filerKVErr = errors.New("filer unavailable")
+	if cmd, status = reconcileMirror(t, r, m); slices.Contains(cmd, "-initialSnapshot") || status.LastSyncedTime == nil {
+		t.Errorf("unreadable checkpoint: command %v, status %+v, want the resume kept", cmd, status)
+	}
+	fa.filerKVErr = nil
+
+	// A new resync value walks again and restarts the pod.
+	m.Spec.Backup.DataMirror[0].Resync = "1"
+	now = now.Add(time.Minute)
+	cmd, status = reconcileMirror(t, r, m)
+	if !slices.Contains(cmd, "-initialSnapshot") || status.Resync != "1" || status.ResyncStartTime == nil || !status.ResyncStartTime.Equal(now) {
+		t.Errorf("resync: command %v, status %+v", cmd, status)
+	}
+	var dep appsv1.Deployment
+	if err := cli.Get(context.Background(), types.NamespacedName{Namespace: "ns1", Name: status.DeploymentName}, &dep); err != nil {
+		t.Fatal(err)
+	}
+	if dep.Spec.Template.Annotations[mirrorResyncAnnotation] != "1" {
+		t.Errorf("pod template annotations = %v, want the resync value", dep.Spec.Template.Annotations)
+	}
+
+	// The stopping pod's checkpoint does not end the resync.
+	fa.filerKV[key] = checkpointValue(now.Add(10 * time.Second))
+	now = now.Add(time.Minute)
+	if cmd, status = reconcileMirror(t, r, m); !slices.Contains(cmd, "-initialSnapshot") || status.ResyncStartTime == nil {
+		t.Errorf("resync ended by the old pod: command %v, status %+v", cmd, status)
+	}
+
+	// The resync pod's checkpoint does, once its walk is done.
+	if err := cli.DeleteAllOf(context.Background(), &corev1.Pod{}, client.InNamespace("ns1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.Create(context.Background(), mirrorPod("mirror-b", now.Add(-30*time.Second), 0)); err != nil {
+		t.Fatal(err)
+	}
+	if cmd, status = reconcileMirror(t, r, m); !slices.Contains(cmd, "-initialSnapshot") {
+		t.Errorf("resync ended before the new pod checkpointed: status %+v", status)
+	}
+	fa.filerKV[key] = checkpointValue(now.Add(-5 * time.Second))
+	cmd, status = reconcileMirror(t, r, m)
+	if slices.Contains(cmd, "-initialSnapshot") || status.InitialSnapshot || status.ResyncStartTime != nil || status.Resync != "1" {
+		t.Errorf("finished resync: command %v, status %+v", cmd, status)
+	}
+	if status.ErrorCount != 0 || status.Restarts != 0 {
+		t.Errorf("errors/restarts = %d/%d, want the new pod's 0", status.ErrorCount, status.Restarts)
+	}
+}
@@ -0,0 +1,402 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+const (
+	// storageProbeContainer is the probe Job's only container; its last
+	// log line says why a probe failed.
+	storageProbeContainer = "probe"
+
+	// storageProbeDeadline bounds a probe Job. A pod that cannot start, on a
+	// missing key of a required Secret say, fails the probe once it passes.
+	storageProbeDeadline = 2 * time.Minute
+
+	// storageProbeMarker is the marker object's name under the cluster's
+	// directory of a storage.
+	storageProbeMarker = ".seaweedfs-operator-probe"
+
+	// storageProbeRevisionAnnotation carries the revision a probe Job checks,
+	// so that a Job for settings since changed is replaced.
+	storageProbeRevisionAnnotation = "seaweed.seaweedfs.com/storage-probe-revision"
+
+	// storageProbeRefreshInterval is how long a reachable storage goes
+	// before it is probed again, so that one that breaks later is noticed.
+	storageProbeRefreshInterval = 6 * time.Hour
+
+	// storageProbeRetryMin and storageProbeRetryMax bound the wait before
+	// an unreachable storage is probed again. The wait is as long as the
+	// storage has been unreachable, so it roughly doubles with each failed
+	// probe and a transient failure clears within a minute or two.
+	storageProbeRetryMin = time.Minute
+	storageProbeRetryMax = 30 * time.Minute
+)
+
+// ensureBackupStorageProbes checks every storage in spec.backup.storages with
+// a short-lived probe Job that writes, reads back and deletes a marker object
+// in it, the way snapshot Jobs reach it. A storage is probed again whenever
+// its settings or the Secrets it names change, on a backoff while it is
+// unreachable, and every storageProbeRefreshInterval while it is reachable;
+// the reconciler's periodic requeue picks up probes that come due. Results
+// land on a Reachable condition per storage in status.backupStorages, which
+// updateStatus persists; probe Jobs of removed storages are pruned.
+func (r *SeaweedReconciler) ensureBackupStorageProbes(ctx context.Context, m *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+	var names []string
+	if m.Spec.Backup != nil {
+		for name := range m.Spec.Backup.Storages {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) > 0 && r.BackupToolImage == "" && r.Recorder != nil {
+		r.Recorder.Event(m, corev1.EventTypeWarning, "BackupStorageProbeUnavailable",
+			"probing spec.backup.storages needs the operator's backup-tool image; set "+BackupToolImageEnv+" on the operator")
+	}
+
+	previous := map[string]seaweedv1.BackupStorageStatus{}
+	for _, s := range m.Status.BackupStorages {
+		previous[s.Name] = s
+	}
+	desired := map[string]bool{}
+	var statuses []seaweedv1.BackupStorageStatus
+	for _, name := range names {
+		status, ok := previous[name]
+		if !ok {
+			status = seaweedv1.BackupStorageStatus{Name: name}
+		}
+		if err := r.probeBackupStorage(ctx, m, name, m.Spec.Backup.Storages[name], &status); err != nil {
+			return ReconcileResult(err)
+		}
+		if status.JobName != "" {
+			desired[status.JobName] = true
+		}
+		statuses = append(statuses, status)
+	}
+
+	if err := r.pruneStorageProbes(ctx, m, desired); err != nil {
+		return ReconcileResult(err)
+	}
+	m.Status.BackupStorages = statuses
+	return ReconcileResult(nil)
+}
+
+// probeBackupStorage advances the probe of storage name and records its
+// outcome in status. A finished probe is kept until the storage's revision
+// moves or storageProbeDue says it is time to check again; while a storage
+// with an unchanged revision is checked again its last outcome stands, so an
+// unreachable storage keeps holding schedules back.
+func (r *SeaweedReconciler) probeBackupStorage(ctx context.Context, m *seaweedv1.Seaweed, name string, st seaweedv1.BackupStorageSpec, status *seaweedv1.BackupStorageStatus) error {
+	revision, missing, err := r.storageProbeRevision(ctx, m, st)
+	if err != nil {
+		return err
+	}
+	now := r.now()
+	reachable := meta.FindStatusCondition(status.Conditions, seaweedv1.BackupStorageConditionReachable)
+	probed := status.ProbedRevision == revision && reachable != nil && reachable.Status != metav1.ConditionUnknown
+	if probed && !storageProbeDue(status, reachable, now) {
+		return nil
+	}
+	setReachable := func(s metav1.ConditionStatus, reason, msg string) {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type: seaweedv1.BackupStorageConditionReachable, Status: s,
+			ObservedGeneration: m.Generation, Reason: reason, Message: msg,
+			LastTransitionTime: metav1.NewTime(now),
+		})
+	}
+	probing := func(msg string) {
+		if !probed {
+			setReachable(metav1.ConditionUnknown, "Probing", msg)
+		}
+	}
+
+	if missing != "" {
+		status.ProbedRevision = revision
+		status.LastProbeTime = &metav1.Time{Time: now}
+		setReachable(metav1.ConditionFalse, "SecretMissing", missing)
+		return nil
+	}
+	if r.BackupToolImage == "" {
+		setReachable(metav1.ConditionUnknown, "BackupToolUnavailable",
+			"probing the storage needs the operator's backup-tool image")
+		return nil
+	}
+
+	jobName := storageProbeJobName(m.Name, name)
+	status.JobName = jobName
+	var job batchv1.Job
+	err = r.Get(ctx, types.NamespacedName{Namespace: m.Namespace, Name: jobName}, &job)
+	switch {
+	case apierrors.IsNotFound(err):
+		built := buildStorageProbeJob(m, jobName, name, st, revision, r.BackupToolImage)
+		if err := controllerutil.SetControllerReference(m, built, r.Scheme); err != nil {
+			return err
+		}
+		if err := r.Create(ctx, built); err != nil && !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("create storage probe job %s: %w", jobName, err)
+		}
+		probing("probe job " + jobName + " is running")
+		return nil
+	case err != nil:
+		return err
+	case job.Annotations[storageProbeRevisionAnnotation] != revision:
+		// The Job checks settings since changed; the next pass probes anew.
+		if err := r.Delete(ctx, &job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		probing("storage settings changed; restarting probe job " + jobName)
+		return nil
+	}
+
+	done, success := jobFinished(&job)
+	if !done {
+		probing("probe job " + jobName + " is running")
+		return nil
+	}
+	finished := storageProbeFinishTime(&job, now)
+	if probed && status.LastProbeTime != nil && !finished.After(status.LastProbeTime.Time) {
+		// The outcome of this Job is recorded already: a probe is due, so
+		// replace it and let the next pass start a new one.
+		return client.IgnoreNotFound(r.Delete(ctx, &job, client.PropagationPolicy(metav1.DeletePropagationBackground)))
+	}
+	status.ProbedRevision = revision
+	status.LastProbeTime = &finished
+	if success {
+		setReachable(metav1.ConditionTrue, "ProbeSucceeded",
+			"wrote, read back and deleted "+objectStoreURI(st, storageProbeKey(m, st)))
+		return nil
+	}
+	msg := r.storageProbeFailure(ctx, &job)
+	setReachable(metav1.ConditionFalse, "ProbeFailed", msg)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(m, corev1.EventTypeWarning, "BackupStorageUnreachable", "storage %s: %s", name, msg)
+	}
+	return nil
+}
+
+// storageProbeDue reports whether a storage with a finished probe should be
+// probed again: storageProbeRefreshInterval after a success, and after a
+// failure once it has waited as long as the storage has been unreachable,
+// within storageProbeRetryMin and storageProbeRetryMax.
+func storageProbeDue(status *seaweedv1.BackupStorageStatus, reachable *metav1.Condition, now time.Time) bool {
+	if status.LastProbeTime == nil {
+		return true
+	}
+	last := status.LastProbeTime.Time
+	wait := storageProbeRefreshInterval
+	if reachable.Status == metav1.ConditionFalse {
+		wait = min(max(last.Sub(reachable.LastTransitionTime.Time), storageProbeRetryMin), storageProbeRetryMax)
+	}
+	return !now.Before(last.Add(wait))
+}
+
+// storageProbeFinishTime is when a finished probe Job finished, as its
+// terminal condition records it. Without one it falls back to the Job's
+// creation, which stays put across passes, and only then to now.
+func storageProbeFinishTime(job *batchv1.Job, now time.Time) metav1.Time {
+	for _, c := range job.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue && !c.LastTransitionTime.IsZero() {
+			return c.LastTransitionTime
+		}
+	}
+	if !job.CreationTimestamp.IsZero() {
+		return job.CreationTimestamp
+	}
+	return metav1.Time{Time: now}
+}
+
+// storageProbeRevision digests what a probe of st depends on: the flags
+// backup-tool reaches the storage with and the data of the Secrets it names.
+// It also reports, as a message, a named Secret that does not exist; a probe
+// pod could not start without it.
+func (r *SeaweedReconciler) storageProbeRevision(ctx context.Context, m *seaweedv1.Seaweed, st seaweedv1.BackupStorageSpec) (string, string, error) {
+	h := sha256.New()
+	for _, arg := range storageProbeArgs(m, st) {
+		_, _ = h.Write([]byte(arg))
+		_, _ = h.Write([]byte{0})
+	}
+	var secrets []string
+	if st.CredentialsSecret != nil && *st.CredentialsSecret != "" && st.Type != seaweedv1.BackupStorageFilesystem {
+		secrets = append(secrets, *st.CredentialsSecret)
+	}
+	if st.Encryption != nil {
+		secrets = append(secrets, st.Encryption.KeySecret)
+	}
+	var missing []string
+	for _, name := range secrets {
+		var secret corev1.Secret
+		if err := r.Get(ctx, types.NamespacedName{Namespace: m.Namespace, Name: name}, &secret); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return "", "", err
+			}
+			missing = append(missing, name)
+			_, _ = h.Write([]byte("missing:" + name + "\x00"))
+			continue
+		}
+		_, _ = h.Write([]byte(name + ":" + configRevision(secret.Data) + "\x00"))
+	}
+	revision := hex.EncodeToString(h.Sum(nil))[:16]
+	if len(missing) > 0 {
+		return revision, "secret " + strings.Join(missing, ", ") + " not found in namespace " + m.Namespace, nil
+	}
+	return revision, "", nil
+}
+
+// storageProbeFailure is why a failed probe Job failed: the last line the
+// probe container logged, or the Job's own failure when the pod never ran
+// to completion.
+func (r *SeaweedReconciler) storageProbeFailure(ctx context.Context, job *batchv1.Job) string {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err == nil {
+		for _, pod := range pods.Items {
+			for _, cs := range pod.Status.ContainerStatuses {
+				t := cs.State.Terminated
+				if cs.Name != storageProbeContainer || t == nil || t.ExitCode == 0 {
+					continue
+				}
+				msg := strings.TrimSpace(t.Message)
+				if i := strings.LastIndex(msg, "\n"); i >= 0 {
+					msg = msg[i+1:]
+				}
+				if msg != "" {
+					return msg
+				}
+			}
+		}
+	}
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue && c.Reason == batchv1.JobReasonDeadlineExceeded {
+			return "probe job " + job.Name + " did not finish within " + storageProbeDeadline.String() + "; see its pod's events"
+		}
+	}
+	return "probe job " + job.Name + " failed; see its logs"
+}
+
+// pruneStorageProbes deletes the probe Jobs of storages no longer declared.
+func (r *SeaweedReconciler) pruneStorageProbes(ctx context.Context, m *seaweedv1.Seaweed, keep map[string]bool) error {
+	var jobs batchv1.JobList
+	if err := r.List(ctx, &jobs, client.InNamespace(m.Namespace), client.MatchingLabels{
+		"app.kubernetes.io/component": "backup-probe",
+		"app.kubernetes.io/instance":  m.Name,
+	}); err != nil {
+		return err
+	}
+	for i := range jobs.Items {
+		j := &jobs.Items[i]
+		if keep[j.Name] {
+			continue
+		}
+		if err := r.Delete(ctx, j, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// storageProbeJobName is the deterministic name of a storage's probe Job.
+func storageProbeJobName(cluster, storage string) string {
+	return boundedName(cluster+"-backup-probe-"+storage, "")
+}
+
+// storageProbeKey is the backup-tool key of a cluster's marker object in st.
+func storageProbeKey(m *seaweedv1.Seaweed, st seaweedv1.BackupStorageSpec) string {
+	return snapshotObjectKey(st, path.Join(m.Name, storageProbeMarker))
+}
+
+// storageProbeArgs are the storage-probe flags for a cluster's marker in st:
+// the storage's flags as snapshot Jobs pass them, sealing the marker when the
+// storage encrypts.
+func storageProbeArgs(m *seaweedv1.Seaweed, st seaweedv1.BackupStorageSpec) []string {
+	args := append(objectStoreArgs(st), "-key="+storageProbeKey(m, st))
+	if st.Encryption != nil {
+		args = append(args, "-encrypt")
+	}
+	return args
+}
+
+// buildStorageProbeJob returns the Job probing storage: backup-tool
+// storage-probe, with the storage's credentials and, for filesystem storages,
+// its claim mounted. It is not retried; a failed attempt is the answer.
+func buildStorageProbeJob(m *seaweedv1.Seaweed, name, storage string, st seaweedv1.BackupStorageSpec, revision, toolImage string) *batchv1.Job {
+	container, volumes := backupToolContainer(m, toolImage, storageProbeContainer, "storage-probe", storageProbeArgs(m, st), st, false)
+	container.Env = objectStoreEnv(st)
+	container.TerminationMessagePolicy = corev1.TerminationMessageFallbackToLogsOnError
+	enableServiceLinks := false
+	pod := corev1.PodSpec{
+		RestartPolicy:      corev1.RestartPolicyNever,
+		ImagePullSecrets:   m.Spec.ImagePullSecrets,
+		EnableServiceLinks: &enableServiceLinks,
+		Containers:         []corev1.Container{container},
+		Volumes:            volumes,
+	}
+	job := newJob(m.Namespace, name, labelsForBackupProbe(m.Name, storage), pod)
+	job.Annotations = map[string]string{storageProbeRevisionAnnotation: revision}
+	backoff := int32(0)
+	deadline := int64(storageProbeDeadline / time.Second)
+	job.Spec.BackoffLimit = &backoff
+	job.Spec.ActiveDeadlineSeconds = &deadline
+	return job
+}
+
+// labelsForBackupProbe are the labels of a storage's probe Job.
+func labelsForBackupProbe(cluster, storage string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":               "seaweedfs",
+		"app.kubernetes.io/component":          "backup-probe",
+		"app.kubernetes.io/instance":           cluster,
+		"app.kubernetes.io/managed-by":         "seaweedfs-operator",
+		seaweedv1.LabelBackupCluster:           cluster,
+		"seaweed.seaweedfs.com/backup-storage": storage,
+	}
+}
+
+// storageReachable reports whether a cluster's schedules may back up to
+// storage: false only once a probe found it unreachable. Storages not probed
+// yet, or being probed again, are given the benefit of the doubt.
+func storageReachable(m *seaweedv1.Seaweed, storage string) (bool, string) {
+	for _, s := range m.Status.BackupStorages {
+		if s.Name != storage {
+			continue
+		}
+		c := meta.FindStatusCondition(s.Conditions, seaweedv1.BackupStorageConditionReachable)
+		if c != nil && c.Status == metav1.ConditionFalse {
+			return false, c.Message
+		}
+	}
+	return true, ""
+}
@@ -0,0 +1,330 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+var probeNow = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+// newProbeReconciler returns a reconciler with the backup-tool image set and
+// its clock reading *now.
+func newProbeReconciler(t *testing.T, now *time.Time, objs ...runtime.Object) (*SeaweedReconciler, client.Client) {
+	t.Helper()
+	r, cli := newNotificationReconciler(t, objs...)
+	r.BackupToolImage = "backup-tool:test"
+	r.Now = func() time.Time { return *now }
+	return r, cli
+}
+
+func clusterWithS3Storage() *seaweedv1.Seaweed {
+	return &seaweedv1.Seaweed{
+		ObjectMeta: metav1.ObjectMeta{Name: "c1", Namespace: "ns1"},
+		Spec: seaweedv1.SeaweedSpec{
+			Image:  "chrislusf/seaweedfs:test",
+			Master: &seaweedv1.MasterSpec{Replicas: 1},
+			Backup: &seaweedv1.BackupSpec{
+				Storages: map[string]seaweedv1.BackupStorageSpec{
+					"s3": {
+						Type:              seaweedv1.BackupStorageS3,
+						S3:                &seaweedv1.S3BackupStore{Bucket: "backups", Endpoint: "http://minio:9000"},
+						CredentialsSecret: ptr.To("s3-creds"),
+					},
+				},
+			},
+		},
+	}
+}
+
+func s3CredentialsSecret(secretKey string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "s3-creds", Namespace: "ns1"},
+		Data: map[string][]byte{
+			seaweedv1.BackupSecretKeyAWSAccessKeyID:     []byte("AKIA"),
+			seaweedv1.BackupSecretKeyAWSSecretAccessKey: []byte(secretKey),
+		},
+	}
+}
+
+// reconcileProbe runs ensureBackupStorageProbes for m and returns the
+// storage's status.
+func reconcileProbe(t *testing.T, r *SeaweedReconciler, m *seaweedv1.Seaweed) seaweedv1.BackupStorageStatus {
+	t.Helper()
+	if done, _, err := r.ensureBackupStorageProbes(context.Background(), m); done || err != nil {
+		t.Fatalf("ensureBackupStorageProbes = %v, %v", done, err)
+	}
+	if len(m.Status.BackupStorages) != 1 {
+		t.Fatalf("storage statuses = %+v, want one", m.Status.BackupStorages)
+	}
+	return m.Status.BackupStorages[0]
+}
+
+func reachableCondition(t *testing.T, s seaweedv1.BackupStorageStatus) metav1.Condition {
+	t.Helper()
+	c := meta.FindStatusCondition(s.Conditions, seaweedv1.BackupStorageConditionReachable)
+	if c == nil {
+		t.Fatalf("no Reachable condition in %+v", s.Conditions)
+	}
+	return *c
+}
+
+// finishProbe marks the probe Job finished at at, failing it with msg as the
+// probe container's termination message unless msg is empty.
+func finishProbe(t *testing.T, cli client.Client, name, msg string, at time.Time) {
+	t.Helper()
+	ctx := context.Background()
+	var job batchv1.Job
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: name}, &job); err != nil {
+		t.Fatal(err)
+	}
+	cond := batchv1.JobComplete
+	if msg != "" {
+		cond = batchv1.JobFailed
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%d", name, at.Unix()), Namespace: "ns1", Labels: map[string]string{"job-name": name}},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  storageProbeContainer,
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Message: "probing\n" + msg}},
+			}}},
+		}
+		if err := cli.Create(ctx, pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	job.Status.Conditions = []batchv1.JobCondition{{Type: cond, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(at)}}
+	if err := cli.Update(ctx, &job); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBackupStorageProbeSucceeds(t *testing.T) {
+	m := clusterWithS3Storage()
+	now := probeNow
+	r, cli := newProbeReconciler(t, &now, s3CredentialsSecret("secret"))
+
+	status := reconcileProbe(t, r, m)
+	if c := reachableCondition(t, status); c.Status != metav1.ConditionUnknown || c.Reason != "Probing" {
+		t.Fatalf("condition while probing = %+v", c)
+	}
+	var job batchv1.Job
+	if err := cli.Get(context.Background(), types.NamespacedName{Namespace: "ns1", Name: status.JobName}, &job); err != nil {
+		t.Fatalf("probe job: %v", err)
+	}
+	if job.Annotations[storageProbeRevisionAnnotation] == "" {
+		t.Errorf("probe job carries no revision: %v", job.Annotations)
+	}
+	cmd := job.Spec.Template.Spec.Containers[0].Command
+	for _, want := range []string{"storage-probe", "-bucket=backups", "-endpoint=http://minio:9000", "-key=c1/" + storageProbeMarker} {
+		if !slices.Contains(cmd, want) {
+			t.Errorf("command %v lacks %q", cmd, want)
+		}
+	}
+	if ok, _ := storageReachable(m, "s3"); !ok {
+		t.Errorf("a storage being probed must not block schedules")
+	}
+
+	finishProbe(t, cli, status.JobName, "", now)
+	status = reconcileProbe(t, r, m)
+	if c := reachableCondition(t, status); c.Status != metav1.ConditionTrue || c.Reason != "ProbeSucceeded" {
+		t.Fatalf("condition after success = %+v", c)
+	}
+	if status.LastProbeTime == nil || status.ProbedRevision == "" {
+		t.Errorf("finished probe not recorded: %+v", status)
+	}
+
+	// An unchanged storage is not probed again before it is due.
+	if err := cli.Delete(context.Background(), &job); err != nil {
+		t.Fatal(err)
+	}
+	reconcileProbe(t, r, m)
+	if err := cli.Get(context.Background(), types.NamespacedName{Namespace: "ns1", Name: status.JobName}, &job); !apierrors.IsNotFound(err) {
+		t.Errorf("unchanged storage was probed again: %v", err)
+	}
+}
+
+func TestBackupStorageProbeFailureBlocksSchedules(t *testing.T) {
+	m := clusterWithS3Storage()
+	now := probeNow
+	r, cli := newProbeReconciler(t, &now, s3CredentialsSecret("secret"))
+
+	status := reconcileProbe(t, r, m)
+	finishProbe(t, cli, status.JobName, "put c1/.seaweedfs-operator-probe: NoSuchBucket", now)
+	status = reconcileProbe(t, r, m)
+	c := reachableCondition(t, status)
+	if c.Status != metav1.ConditionFalse || c.Reason != "ProbeFailed" || !strings.Contains(c.Message, "NoSuchBucket") {
+		t.Fatalf("condition after failure = %+v", c)
+	}
+	if ok, why := storageReachable(m, "s3"); ok || !strings.Contains(why, "NoSuchBucket") {
+		t.Errorf("storageReachable = %v, %q", ok, why)
+	}
+}
+
+func TestBackupStorageProbeRestartsOnCredentialChange(t *testing.T) {
+	m := clusterWithS3Storage()
+	secret := s3CredentialsSecret("wrong")
+	now := probeNow
+	r, cli := newProbeReconciler(t, &now, secret)
+
+	status := reconcileProbe(t, r, m)
+	finishProbe(t, cli, status.JobName, "access denied", now)
+	reconcileProbe(t, r, m)
+
+	ctx := context.Background()
+	secret.Data[seaweedv1.BackupSecretKeyAWSSecretAccessKey] = []byte("right")
+	if err := cli.Update(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	status = reconcileProbe(t, r, m)
+	if c := reachableCondition(t, status); c.Status != metav1.ConditionUnknown {
+		t.Fatalf("condition after credential change = %+v", c)
+	}
+	var job batchv1.Job
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: status.JobName}, &job); !apierrors.IsNotFound(err) {
+		t.Fatalf("stale probe job not deleted: %v", err)
+	}
+
+	status = reconcileProbe(t, r, m)
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: status.JobName}, &job); err != nil {
+		t.Fatalf("probe job not recreated: %v", err)
+	}
+	if len(job.Status.Conditions) != 0 {
+		t.Errorf("recreated probe job already finished: %+v", job.Status.Conditions)
+	}
+}
+
+func TestBackupStorageProbeMissingSecret(t *testing.T) {
+	m := clusterWithS3Storage()
+	now := probeNow
+	r, cli := newProbeReconciler(t, &now)
+
+	status := reconcileProbe(t, r, m)
+	c := reachableCondition(t, status)
+	if c.Status != metav1.ConditionFalse || c.Reason != "SecretMissing" || !strings.Contains(c.Message, "s3-creds") {
+		t.Fatalf("condition without secret = %+v", c)
+	}
+	var jobs batchv1.JobList
+	if err := cli.List(context.Background(), &jobs, client.InNamespace("ns1")); err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs.Items) != 0 {
+		t.Errorf("probe job created without its secret: %d", len(jobs.Items))
+	}
+}
+
+func TestBackupStorageProbePrunesRemovedStorages(t *testing.T) {
+	m := clusterWithS3Storage()
+	stale := buildStorageProbeJob(m, storageProbeJobName("c1", "old"), "old", m.Spec.Backup.Storages["s3"], "r", "backup-tool:test")
+	now := probeNow
+	r, cli := newProbeReconciler(t, &now, s3CredentialsSecret("secret"), stale)
+
+	reconcileProbe(t, r, m)
+	var job batchv1.Job
+	if err := cli.Get(context.Background(), types.NamespacedName{Namespace: "ns1", Name: stale.Name}, &job); !apierrors.IsNotFound(err) {
+		t.Errorf("probe job of a removed storage not pruned: %v", err)
+	}
+}
+
+// probeJob returns the storage's probe Job, or nil when there is none.
+func probeJob(t *testing.T, cli client.Client, name string) *batchv1.Job {
+	t.Helper()
+	var job batchv1.Job
+	err := cli.Get(context.Background(), types.NamespacedName{Namespace: "ns1", Name: name}, &job)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &job
+}
+
+func TestBackupStorageProbeRetriesUnreachableStorage(t *testing.T) {
+	m := clusterWithS3Storage()
+	now := probeNow
+	r, cli := newProbeReconciler(t, &now, s3CredentialsSecret("secret"))
+
+	status := reconcileProbe(t, r, m)
+	finishProbe(t, cli, status.JobName, "dial tcp: i/o timeout", now)
+	reconcileProbe(t, r, m)
+
+	// Within the backoff the failed Job stands.
+	now = now.Add(30 * time.Second)
+	reconcileProbe(t, r, m)
+	if job := probeJob(t, cli, status.JobName); job == nil || len(job.Status.Conditions) == 0 {
+		t.Fatalf("failed probe replaced before its backoff: %+v", job)
+	}
+
+	// Once due it is replaced, while the storage keeps blocking schedules.
+	now = now.Add(time.Minute)
+	status = reconcileProbe(t, r, m)
+	if job := probeJob(t, cli, status.JobName); job != nil {
+		t.Fatalf("failed probe not replaced once due")
+	}
+	status = reconcileProbe(t, r, m)
+	if job := probeJob(t, cli, status.JobName); job == nil || len(job.Status.Conditions) != 0 {
+		t.Fatalf("no new probe job once due: %+v", job)
+	}
+	if ok, _ := storageReachable(m, "s3"); ok {
+		t.Errorf("an unreachable storage unblocked schedules while it is probed again")
+	}
+
+	finishProbe(t, cli, status.JobName, "", now)
+	status = reconcileProbe(t, r, m)
+	if c := reachableCondition(t, status); c.Status != metav1.ConditionTrue {
+		t.Fatalf("condition after the retry succeeded = %+v", c)
+	}
+}
+
+func TestBackupStorageProbeRefreshesReachableStorage(t *testing.T) {
+	m := clusterWithS3Storage()
+	now := probeNow
+	r, cli := newProbeReconciler(t, &now, s3CredentialsSecret("secret"))
+
+	status := reconcileProbe(t, r, m)
+	finishProbe(t, cli, status.JobName, "", now)
+	reconcileProbe(t, r, m)
+
+	now = now.Add(storageProbeRefreshInterval)
+	reconcileProbe(t, r, m)
+	status = reconcileProbe(t, r, m)
+	if job := probeJob(t, cli, status.JobName); job == nil || len(job.Status.Conditions) != 0 {
+		t.Fatalf("reachable storage not probed again after %s: %+v", storageProbeRefreshInterval, job)
+	}
+	finishProbe(t, cli, status.JobName, "403 Forbidden", now)
+	status = reconcileProbe(t, r, m)
+	if c := reachableCondition(t, status); c.Status != metav1.ConditionFalse || !strings.Contains(c.Message, "Forbidden") {
+		t.Fatalf("storage that broke later not noticed: %+v", c)
+	}
+}
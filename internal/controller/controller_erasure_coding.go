@@ -0,0 +1,336 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/internal/controller/swadmin"
+)
+
+// Erasure coding reconciliation.
+//
+// Every pass reads the volume layout from the master to count EC volumes and
+// their shards; volumes missing shards are repaired with ec.rebuild when the
+// policy allows it, one collection per pass, the most degraded first. Once
+// per spec.erasureCoding.interval the operator also picks the plain volumes
+// of the selected collections that are full and quiet enough and runs
+// ec.encode for up to maxVolumesPerPass of them, so a large backlog never
+// holds up a reconcile; the rest wait for the next interval.
+
+// erasureCodingRebuildBackoff spaces out ec.rebuild attempts, so shards that
+// cannot be placed (too few servers left) are not retried every reconcile.
+const erasureCodingRebuildBackoff = 5 * time.Minute
+
+// Defaults for spec.erasureCoding fields the API server did not default
+// (objects created before the field existed, or built in tests).
+const (
+	defaultErasureCodingQuietFor    = time.Hour
+	defaultErasureCodingFullPercent = 95
+	defaultErasureCodingInterval    = time.Hour
+	defaultErasureCodingMaxVolumes  = 4
+)
+
+// erasureCodingTarget is a collection to encode with its resolved thresholds.
+type erasureCodingTarget struct {
+	collection string
+	opts       ErasureCodingEncodeOptions
+}
+
+// erasureCodingTargets picks the collections spec encodes, among those
+// holding plain volumes, with bucket overrides (keyed by collection) applied.
+// The result is sorted by collection.
+func erasureCodingTargets(spec *seaweedv1.ErasureCodingSpec, collections []string, overrides map[string]*seaweedv1.BucketErasureCoding) []erasureCodingTarget {
+	base := ErasureCodingEncodeOptions{
+		FullPercent: defaultErasureCodingFullPercent,
+		QuietFor:    defaultErasureCodingQuietFor,
+	}
+	if spec.FullnessPercent != nil {
+		base.FullPercent = *spec.FullnessPercent
+	}
+	if spec.QuietFor != nil {
+		base.QuietFor = spec.QuietFor.Duration
+	}
+	if spec.DataShards != nil || spec.ParityShards != nil {
+		data, parity := spec.EffectiveShards()
+		base.DataShards, base.ParityShards = &data, &parity
+	}
+
+	var targets []erasureCodingTarget
+	for _, c := range collections {
+		selected := collectionSelected(spec.Collections, c)
+		opts := base
+		if o := overrides[c]; o != nil {
+			if o.Enabled != nil {
+				selected = *o.Enabled
+			}
+			if o.FullnessPercent != nil {
+				opts.FullPercent = *o.FullnessPercent
+			}
+			if o.QuietFor != nil {
+				opts.QuietFor = o.QuietFor.Duration
+			}
+		}
+		if selected {
+			targets = append(targets, erasureCodingTarget{collection: c, opts: opts})
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].collection < targets[j].collection })
+	return targets
+}
+
+// erasureCodingCandidates returns the local plain volumes of targets that
+// are full and quiet enough to encode, by collection and then volume id.
+func erasureCodingCandidates(targets []erasureCodingTarget, layout swadmin.VolumeLayout, now time.Time) []erasureCodingCandidate {
+	if layout.VolumeSizeLimit == 0 {
+		// Fullness cannot be judged without the limit.
+		return nil
+	}
+	var out []erasureCodingCandidate
+	for _, t := range targets {
+		for _, v := range layout.Volumes {
+			if v.Collection != t.collection || v.RemoteStorage != "" {
+				continue
+			}
+			if v.Size*100 < layout.VolumeSizeLimit*uint64(t.opts.FullPercent) || now.Sub(v.ModifiedAt) < t.opts.QuietFor {
+				continue
+			}
+			out = append(out, erasureCodingCandidate{erasureCodingTarget: t, id: v.ID})
+		}
+	}
+	return out
+}
+
+// erasureCodingCandidate is a volume to encode.
+type erasureCodingCandidate struct {
+	erasureCodingTarget
+	id uint32
+}
+
+// collectionSelected matches collection against glob patterns; no patterns
+// selects everything.
+func collectionSelected(patterns []string, collection string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, collection); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// erasureCodingStatus counts EC volumes and their shard health against the
+// data+parity layout. It also returns the collections with volumes a rebuild
+// can repair, the most degraded first.
+func erasureCodingStatus(layout swadmin.VolumeLayout, data, parity int32) (*seaweedv1.ErasureCodingStatus, []string) {
+	status := &seaweedv1.ErasureCodingStatus{}
+	byCollection := map[string]*seaweedv1.ErasureCodingCollectionStatus{}
+	repairable := map[string]int{}
+	for _, v := range layout.ECVolumes {
+		c := byCollection[v.Collection]
+		if c == nil {
+			c = &seaweedv1.ErasureCodingCollectionStatus{Name: v.Collection}
+			byCollection[v.Collection] = c
+		}
+		status.EncodedVolumes++
+		c.EncodedVolumes++
+		shards := int32(v.Shards())
+		switch {
+		case shards < data:
+			status.UnrecoverableVolumes++
+			c.DegradedVolumes++
+		case shards < data+parity:
+			status.DegradedVolumes++
+			c.DegradedVolumes++
+			repairable[v.Collection]++
+		}
+	}
+	for _, c := range byCollection {
+		status.Collections = append(status.Collections, *c)
+	}
+	sort.Slice(status.Collections, func(i, j int) bool { return status.Collections[i].Name < status.Collections[j].Name })
+	var rebuild []string
+	for c := range repairable {
+		rebuild = append(rebuild, c)
+	}
+	sort.Slice(rebuild, func(i, j int) bool {
+		if repairable[rebuild[i]] != repairable[rebuild[j]] {
+			return repairable[rebuild[i]] > repairable[rebuild[j]]
+		}
+		return rebuild[i] < rebuild[j]
+	})
+	return status, rebuild
+}
+
+// bucketErasureCodingOverrides returns the placement.erasureCoding of every
+// provisioned Bucket on m, keyed by bucket (collection) name.
+func (r *SeaweedReconciler) bucketErasureCodingOverrides(ctx context.Context, m *seaweedv1.Seaweed) (map[string]*seaweedv1.BucketErasureCoding, error) {
+	var buckets seaweedv1.BucketList
+	if err := r.List(ctx, &buckets); err != nil {
+		return nil, err
+	}
+	out := map[string]*seaweedv1.BucketErasureCoding{}
+	for i := range buckets.Items {
+		b := &buckets.Items[i]
+		ns := b.Spec.ClusterRef.Namespace
+		if ns == "" {
+			ns = b.Namespace
+		}
+		if b.Spec.ClusterRef.Name != m.Name || ns != m.Namespace || b.Status.BucketName == "" {
+			continue
+		}
+		if b.Spec.Placement != nil && b.Spec.Placement.ErasureCoding != nil {
+			out[b.Status.BucketName] = b.Spec.Placement.ErasureCoding
+		}
+	}
+	return out, nil
+}
+
+// ensureErasureCoding applies spec.erasureCoding and reports EC volume counts
+// on status and shard health on the ErasureCodingHealthy condition. It waits
+// for ready volume servers; failures are surfaced rather than blocking the
+// rest of the reconcile.
+func (r *SeaweedReconciler) ensureErasureCoding(ctx context.Context, m *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+	spec := m.Spec.ErasureCoding
+	if spec == nil {
+		m.Status.ErasureCoding = nil
+		meta.RemoveStatusCondition(&m.Status.Conditions, seaweedv1.SeaweedConditionErasureCodingHealthy)
+		return ReconcileResult(nil)
+	}
+	if r.VolumeAdminFactory == nil || m.Spec.Master == nil || m.Status.Volume.ReadyReplicas == 0 {
+		return ReconcileResult(nil)
+	}
+
+	if err := r.reconcileErasureCoding(ctx, m, spec); err != nil {
+		r.Log.Error(err, "erasure coding", "seaweed", m.Name)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(m, corev1.EventTypeWarning, "ErasureCodingFailed", "%v", err)
+		}
+		meta.SetStatusCondition(&m.Status.Conditions, metav1.Condition{
+			Type:               seaweedv1.SeaweedConditionErasureCodingHealthy,
+			Status:             metav1.ConditionUnknown,
+			ObservedGeneration: m.Generation,
+			Reason:             "CheckFailed",
+			Message:            err.Error(),
+		})
+	}
+	return ReconcileResult(nil)
+}
+
+func (r *SeaweedReconciler) reconcileErasureCoding(ctx context.Context, m *seaweedv1.Seaweed, spec *seaweedv1.ErasureCodingSpec) error {
+	dialOption, _, err := loadSeaweedGrpcDialOption(ctx, r.Client, m)
+	if err != nil {
+		return err
+	}
+	admin, err := r.VolumeAdminFactory(getMasterPeersString(m), dialOption, r.Log)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	layout, err := admin.VolumeLayout(ctx)
+	if err != nil {
+		return err
+	}
+	data, parity := spec.EffectiveShards()
+	status, repairable := erasureCodingStatus(layout, data, parity)
+	if prev := m.Status.ErasureCoding; prev != nil {
+		status.LastEncodeTime, status.LastRebuildTime = prev.LastEncodeTime, prev.LastRebuildTime
+	}
+	m.Status.ErasureCoding = status
+	now := r.now()
+
+	cond := metav1.Condition{
+		Type:               seaweedv1.SeaweedConditionErasureCodingHealthy,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: m.Generation,
+		Reason:             "AllShardsPresent",
+	}
+	if status.DegradedVolumes > 0 || status.UnrecoverableVolumes > 0 {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "ShardsMissing"
+		cond.Message = fmt.Sprintf("%d EC volumes missing shards, %d beyond repair", status.DegradedVolumes, status.UnrecoverableVolumes)
+	}
+
+	rebuildPolicy := spec.Rebuild
+	if rebuildPolicy == "" {
+		rebuildPolicy = seaweedv1.ErasureCodingRebuildAutomatic
+	}
+	rebuildDue := status.LastRebuildTime == nil || now.Sub(status.LastRebuildTime.Time) >= erasureCodingRebuildBackoff
+	if rebuildPolicy == seaweedv1.ErasureCodingRebuildAutomatic && len(repairable) > 0 && rebuildDue {
+		// One collection per pass; the others follow after the backoff.
+		c := repairable[0]
+		status.LastRebuildTime = &metav1.Time{Time: now}
+		if err := admin.RebuildErasureCoding(ctx, c); err != nil {
+			r.Log.Error(err, "ec.rebuild", "seaweed", m.Name, "collection", c)
+			cond.Reason = "RebuildFailed"
+			cond.Message += fmt.Sprintf("; ec.rebuild failed for collection %q", c)
+		} else if r.Recorder != nil {
+			r.Recorder.Eventf(m, corev1.EventTypeNormal, "ErasureCodingRebuilt", "Rebuilt missing EC shards in collection %q", c)
+		}
+	}
+	meta.SetStatusCondition(&m.Status.Conditions, cond)
+
+	overrides, err := r.bucketErasureCodingOverrides(ctx, m)
+	if err != nil {
+		return err
+	}
+	candidates := erasureCodingCandidates(erasureCodingTargets(spec, layout.Collections, overrides), layout, now)
+	status.PendingVolumes = int32(len(candidates))
+	interval := defaultErasureCodingInterval
+	if spec.Interval != nil {
+		interval = spec.Interval.Duration
+	}
+	if len(candidates) == 0 || (status.LastEncodeTime != nil && now.Sub(status.LastEncodeTime.Time) < interval) {
+		return nil
+	}
+	budget := int32(defaultErasureCodingMaxVolumes)
+	if spec.MaxVolumesPerPass != nil {
+		budget = *spec.MaxVolumesPerPass
+	}
+	// Encode failures are reported as events: the condition tracks shard
+	// health, and the next interval retries.
+	status.LastEncodeTime = &metav1.Time{Time: now}
+	for _, v := range candidates {
+		if budget == 0 {
+			break
+		}
+		budget--
+		if err := admin.EncodeErasureCoding(ctx, v.collection, v.id, v.opts); err != nil {
+			r.Log.Error(err, "ec.encode", "seaweed", m.Name, "collection", v.collection, "volume", v.id)
+			if r.Recorder != nil {
+				r.Recorder.Eventf(m, corev1.EventTypeWarning, "ErasureCodingEncodeFailed", "Encoding volume %d (collection %q): %v", v.id, v.collection, err)
+			}
+			continue
+		}
+		// Counted as encoded now; the next pass reads it back.
+		status.PendingVolumes--
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/internal/controller/swadmin"
+)
+
+func TestErasureCodingTargets(t *testing.T) {
+	spec := &seaweedv1.ErasureCodingSpec{
+		Collections:     []string{"logs-*", "archive"},
+		QuietFor:        &metav1.Duration{Duration: 2 * time.Hour},
+		FullnessPercent: ptr.To[int32](90),
+	}
+	overrides := map[string]*seaweedv1.BucketErasureCoding{
+		"media":       {Enabled: ptr.To(true), FullnessPercent: ptr.To[int32](80)},
+		"logs-secret": {Enabled: ptr.To(false)},
+		"archive":     {QuietFor: &metav1.Duration{Duration: 24 * time.Hour}},
+	}
+	got := erasureCodingTargets(spec, []string{"", "archive", "logs-app", "logs-secret", "media", "tmp"}, overrides)
+
+	var thresholds []string
+	for _, target := range got {
+		thresholds = append(thresholds, fmt.Sprintf("%s full=%d quiet=%s", target.collection, target.opts.FullPercent, target.opts.QuietFor))
+	}
+	want := []string{
+		"archive full=90 quiet=24h0m0s",
+		"logs-app full=90 quiet=2h0m0s",
+		"media full=80 quiet=2h0m0s",
+	}
+	if !reflect.DeepEqual(thresholds, want) {
+		t.Errorf("targets = %q\nwant %q", thresholds, want)
+	}
+
+	// No selectors encode every collection; custom shards are passed on.
+	all := erasureCodingTargets(&seaweedv1.ErasureCodingSpec{DataShards: ptr.To[int32](6), ParityShards: ptr.To[int32](3)}, []string{"", "tmp"}, nil)
+	if len(all) != 2 {
+		t.Fatalf("targets = %+v, want both collections", all)
+	}
+	if cmd := all[0].opts.encodeCommand(all[0].collection, 7); cmd != "ec.encode -collection= -volumeId=7 -dataShards=6 -parityShards=3" {
+		t.Errorf("default collection command = %q", cmd)
+	}
+}
+
+func TestErasureCodingCandidates(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	quiet := now.Add(-2 * time.Hour)
+	layout := swadmin.VolumeLayout{
+		VolumeSizeLimit: 100,
+		Volumes: []swadmin.Volume{
+			{ID: 1, Collection: "logs", Size: 96, ModifiedAt: quiet},
+			{ID: 2, Collection: "logs", Size: 50, ModifiedAt: quiet}, // not full
+			{ID: 3, Collection: "logs", Size: 99, ModifiedAt: now},   // still written
+			{ID: 4, Collection: "logs", Size: 99, ModifiedAt: quiet, RemoteStorage: "s3.cold"},
+			{ID: 5, Collection: "media", Size: 99, ModifiedAt: quiet}, // not selected
+			{ID: 6, Collection: "archive", Size: 60, ModifiedAt: quiet},
+		},
+	}
+	targets := erasureCodingTargets(&seaweedv1.ErasureCodingSpec{Collections: []string{"logs", "archive"}}, []string{"archive", "logs", "media"},
+		map[string]*seaweedv1.BucketErasureCoding{"archive": {FullnessPercent: ptr.To[int32](50)}})
+	var ids []uint32
+	for _, c := range erasureCodingCandidates(targets, layout, now) {
+		ids = append(ids, c.id)
+	}
+	if want := []uint32{6, 1}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("candidates = %v, want %v", ids, want)
+	}
+
+	layout.VolumeSizeLimit = 0
+	if got := erasureCodingCandidates(targets, layout, now); len(got) != 0 {
+		t.Errorf("candidates without a size limit = %+v", got)
+	}
+}
+
+func TestErasureCodingStatus(t *testing.T) {
+	layout := swadmin.VolumeLayout{ECVolumes: []swadmin.ECVolume{
+		{ID: 1, Collection: "logs", ShardBits: 0x3fff},  // all 14
+		{ID: 2, Collection: "logs", ShardBits: 0x3ffe},  // 13: degraded
+		{ID: 3, Collection: "media", ShardBits: 0x01ff}, // 9: beyond repair
+		{ID: 4, Collection: "media", ShardBits: 0x3fff},
+	}}
+	status, repairable := erasureCodingStatus(layout, 10, 4)
+	want := &seaweedv1.ErasureCodingStatus{
+		EncodedVolumes:       4,
+		DegradedVolumes:      1,
+		UnrecoverableVolumes: 1,
+		Collections: []seaweedv1.ErasureCodingCollectionStatus{
+			{Name: "logs", EncodedVolumes: 2, DegradedVolumes: 1},
+			{Name: "media", EncodedVolumes: 2, DegradedVolumes: 1},
+		},
+	}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("status = %+v\nwant %+v", status, want)
+	}
+	if !reflect.DeepEqual(repairable, []string{"logs"}) {
+		t.Errorf("repairable = %v, want [logs]", repairable)
+	}
+}
+
+func TestEnsureErasureCoding(t *testing.T) {
+	sw := evacTestSeaweed()
+	sw.Spec.ErasureCoding = &seaweedv1.ErasureCodingSpec{Collections: []string{"logs"}}
+	sw.Status.Volume.ReadyReplicas = 1
+	bucket := newTestBucket("logs")
+	bucket.Spec.ClusterRef = seaweedv1.BucketClusterRef{Name: sw.Name, Namespace: sw.Namespace}
+	bucket.Spec.Placement = &seaweedv1.BucketPlacement{ErasureCoding: &seaweedv1.BucketErasureCoding{FullnessPercent: ptr.To[int32](50)}}
+	bucket.Status.BucketName = "logs"
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	fa := &fakeVolumeAdmin{layout: swadmin.VolumeLayout{
+		Collections:     []string{"logs", "media"},
+		VolumeSizeLimit: 100,
+		Volumes: []swadmin.Volume{
+			{ID: 1, Collection: "logs", Size: 60, ModifiedAt: now.Add(-2 * time.Hour)},
+			{ID: 2, Collection: "media", Size: 99, ModifiedAt: now.Add(-2 * time.Hour)},
+		},
+		ECVolumes: []swadmin.ECVolume{{ID: 9, Collection: "logs", ShardBits: 0x3ffe}},
+	}}
+	r := newEvacTestReconciler(t, fa, bucket)
+	r.Now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if done, _, err := r.ensureErasureCoding(ctx, sw); done || err != nil {
+		t.Fatalf("ensureErasureCoding = %v, %v", done, err)
+	}
+	if want := []string{"ec.encode -collection=logs -volumeId=1"}; !reflect.DeepEqual(fa.encoded, want) {
+		t.Errorf("encoded = %q, want %q", fa.encoded, want)
+	}
+	if !reflect.DeepEqual(fa.rebuilt, []string{"logs"}) {
+		t.Errorf("rebuilt = %v, want [logs]", fa.rebuilt)
+	}
+	st := sw.Status.ErasureCoding
+	if st == nil || st.EncodedVolumes != 1 || st.DegradedVolumes != 1 || st.LastEncodeTime == nil || st.LastRebuildTime == nil {
+		t.Fatalf("status = %+v", st)
+	}
+	cond := meta.FindStatusCondition(sw.Status.Conditions, seaweedv1.SeaweedConditionErasureCodingHealthy)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != "ShardsMissing" {
+		t.Errorf("condition = %+v want False/ShardsMissing", cond)
+	}
+
+	// Within the interval and the rebuild backoff nothing runs again.
+	now = now.Add(time.Minute)
+	if _, _, err := r.ensureErasureCoding(ctx, sw); err != nil {
+		t.Fatalf("ensureErasureCoding: %v", err)
+	}
+	if len(fa.encoded) != 1 || len(fa.rebuilt) != 1 {
+		t.Errorf("reran early: encoded=%v rebuilt=%v", fa.encoded, fa.rebuilt)
+	}
+
+	// Shards restored: healthy. A failed rebuild is reported on the condition.
+	fa.layout.ECVolumes[0].ShardBits = 0x3fff
+	if _, _, err := r.ensureErasureCoding(ctx, sw); err != nil {
+		t.Fatalf("ensureErasureCoding: %v", err)
+	}
+	if !meta.IsStatusConditionTrue(sw.Status.Conditions, seaweedv1.SeaweedConditionErasureCodingHealthy) {
+		t.Errorf("expected healthy, got %+v", sw.Status.Conditions)
+	}
+	fa.layout.ECVolumes[0].ShardBits = 0x0fff
+	fa.rebuildErr = errors.New("no free slots")
+	now = now.Add(erasureCodingRebuildBackoff)
+	if _, _, err := r.ensureErasureCoding(ctx, sw); err != nil {
+		t.Fatalf("ensureErasureCoding: %v", err)
+	}
+	cond = meta.FindStatusCondition(sw.Status.Conditions, seaweedv1.SeaweedConditionErasureCodingHealthy)
+	if cond == nil || cond.Reason != "RebuildFailed" || len(fa.rebuilt) != 2 {
+		t.Errorf("condition = %+v rebuilt=%v", cond, fa.rebuilt)
+	}
+
+	// Manual rebuild only reports.
+	sw.Spec.ErasureCoding.Rebuild = seaweedv1.ErasureCodingRebuildManual
+	now = now.Add(erasureCodingRebuildBackoff)
+	if _, _, err := r.ensureErasureCoding(ctx, sw); err != nil {
+		t.Fatalf("ensureErasureCoding: %v", err)
+	}
+	if len(fa.rebuilt) != 2 {
+		t.Errorf("manual policy rebuilt: %v", fa.rebuilt)
+	}
+
+	// Removing the policy clears status and condition.
+	sw.Spec.ErasureCoding = nil
+	if _, _, err := r.ensureErasureCoding(ctx, sw); err != nil {
+		t.Fatalf("ensureErasureCoding: %v", err)
+	}
+	if sw.Status.ErasureCoding != nil || meta.FindStatusCondition(sw.Status.Conditions, seaweedv1.SeaweedConditionErasureCodingHealthy) != nil {
+		t.Errorf("status not cleared: %+v %+v", sw.Status.ErasureCoding, sw.Status.Conditions)
+	}
+}
+
+func TestErasureCodingEncodesWithinBudget(t *testing.T) {
+	sw := evacTestSeaweed()
+	sw.Spec.ErasureCoding = &seaweedv1.ErasureCodingSpec{MaxVolumesPerPass: ptr.To[int32](2)}
+	sw.Status.Volume.ReadyReplicas = 1
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	fa := &fakeVolumeAdmin{layout: swadmin.VolumeLayout{
+		Collections:     []string{"logs"},
+		VolumeSizeLimit: 100,
+	}}
+	for id := uint32(1); id <= 5; id++ {
+		fa.layout.Volumes = append(fa.layout.Volumes, swadmin.Volume{ID: id, Collection: "logs", Size: 99, ModifiedAt: now.Add(-2 * time.Hour)})
+	}
+	r := newEvacTestReconciler(t, fa)
+	r.Now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// pass encodes what the budget allows and drops the encoded volumes from
+	// the layout, as the master would once they are EC volumes.
+	pass := func() {
+		t.Helper()
+		before := len(fa.encoded)
+		if _, _, err := r.ensureErasureCoding(ctx, sw); err != nil {
+			t.Fatalf("ensureErasureCoding: %v", err)
+		}
+		encoded := map[string]bool{}
+		for _, cmd := range fa.encoded[before:] {
+			encoded[cmd] = true
+		}
+		var left []swadmin.Volume
+		for _, v := range fa.layout.Volumes {
+			if !encoded[fmt.Sprintf("ec.encode -collection=logs -volumeId=%d", v.ID)] {
+				left = append(left, v)
+			}
+		}
+		fa.layout.Volumes = left
+	}
+
+	pass()
+	if want := []string{"ec.encode -collection=logs -volumeId=1", "ec.encode -collection=logs -volumeId=2"}; !reflect.DeepEqual(fa.encoded, want) {
+		t.Fatalf("encoded = %q, want %q", fa.encoded, want)
+	}
+	if got := sw.Status.ErasureCoding.PendingVolumes; got != 3 {
+		t.Errorf("pending = %d after the first pass, want 3", got)
+	}
+
+	// The backlog waits for the next interval.
+	now = now.Add(time.Minute)
+	pass()
+	if len(fa.encoded) != 2 || sw.Status.ErasureCoding.PendingVolumes != 3 {
+		t.Errorf("encoded %q, pending %d within the interval", fa.encoded, sw.Status.ErasureCoding.PendingVolumes)
+	}
+	for i := 0; i < 2; i++ {
+		now = now.Add(defaultErasureCodingInterval)
+		pass()
+	}
+	if len(fa.encoded) != 5 || sw.Status.ErasureCoding.PendingVolumes != 0 {
+		t.Errorf("encoded %q, pending %d after the backlog drained", fa.encoded, sw.Status.ErasureCoding.PendingVolumes)
+	}
+}
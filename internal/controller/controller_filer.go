@@ -29,6 +29,10 @@ func (r *SeaweedReconciler) ensureFilerServers(ctx context.Context, seaweedCR *s
 		return
 	}
 
+	if done, result, err = r.ensureFilerNotification(ctx, seaweedCR); done {
+		return
+	}
+
 	if done, result, err = r.ensureFilerStatefulSet(ctx, seaweedCR); done {
 		return
 	}
@@ -46,6 +50,14 @@ func (r *SeaweedReconciler) ensureFilerStatefulSet(ctx context.Context, seaweedC
 	log := r.Log.WithValues("sw-filer-statefulset", seaweedCR.Name)
 
 	filerStatefulSet := r.createFilerStatefulSet(seaweedCR)
+	if filerNotificationEnabled(seaweedCR) {
+		data, err := r.filerNotificationData(ctx, seaweedCR)
+		if err != nil {
+			return ReconcileResult(err)
+		}
+		template := &filerStatefulSet.Spec.Template
+		template.Annotations = withRevisionAnnotation(template.Annotations, filerNotificationAnnotation, configRevision(data))
+	}
 	if err := controllerutil.SetControllerReference(seaweedCR, filerStatefulSet, r.Scheme); err != nil {
 		return ReconcileResult(err)
 	}
@@ -0,0 +1,262 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+const (
+	// filerNotificationDir is where the filer finds notification.toml. weed
+	// searches it after -config_dir and before /etc/seaweedfs, so the file can
+	// sit next to a mounted filer.toml and security.toml without sharing
+	// either volume.
+	filerNotificationDir = "/usr/local/etc/seaweedfs"
+
+	filerNotificationVolumeName = "filer-notification"
+
+	// filerNotificationAnnotation carries a digest of the rendered
+	// notification Secret. weed reads notification.toml only at startup, so
+	// a changed sink or a rotated credential has to roll the filers.
+	filerNotificationAnnotation = "seaweed.seaweedfs.com/notification-config"
+)
+
+func filerNotificationSecretName(m *seaweedv1.Seaweed) string {
+	return m.Name + "-filer-notification"
+}
+
+func filerNotificationEnabled(m *seaweedv1.Seaweed) bool {
+	return m.Spec.Filer != nil && m.Spec.Filer.Notification != nil
+}
+
+// renderNotificationToml builds the notification.toml for a sink. Exactly one
+// [notification.*] section is emitted, with credentials baked in from creds
+// (see resolveNotificationCredentials). Credential fields are left empty when
+// no Secret was given so weed falls back to its ambient chain.
+func renderNotificationToml(n *seaweedv1.FilerNotificationSpec, creds map[string][]byte) (string, error) {
+	get := func(k string) string {
+		if creds == nil {
+			return ""
+		}
+		return string(creds[k])
+	}
+
+	var b strings.Builder
+	switch n.Type {
+	case seaweedv1.FilerNotificationLog:
+		fmt.Fprintf(&b, "[notification.log]\nenabled = true\n")
+	case seaweedv1.FilerNotificationKafka:
+		if n.Kafka == nil {
+			return "", fmt.Errorf("notification type kafka requires the kafka block")
+		}
+		fmt.Fprintf(&b, "[notification.kafka]\nenabled = true\n")
+		fmt.Fprintf(&b, "hosts = %s\n", tomlStringList(n.Kafka.Hosts))
+		fmt.Fprintf(&b, "topic = %s\n", tomlString(n.Kafka.Topic))
+	case seaweedv1.FilerNotificationAWSSQS:
+		if n.AWSSQS == nil {
+			return "", fmt.Errorf("notification type awsSqs requires the awsSqs block")
+		}
+		region := n.AWSSQS.Region
+		if region == "" {
+			region = "us-east-2"
+		}
+		fmt.Fprintf(&b, "[notification.aws_sqs]\nenabled = true\n")
+		fmt.Fprintf(&b, "aws_access_key_id = %s\n", tomlString(get(seaweedv1.BackupSecretKeyAWSAccessKeyID)))
+		fmt.Fprintf(&b, "aws_secret_access_key = %s\n", tomlString(get(seaweedv1.BackupSecretKeyAWSSecretAccessKey)))
+		fmt.Fprintf(&b, "region = %s\n", tomlString(region))
+		fmt.Fprintf(&b, "sqs_queue_name = %s\n", tomlString(n.AWSSQS.QueueName))
+	case seaweedv1.FilerNotificationGooglePubSub:
+		if n.GooglePubSub == nil {
+			return "", fmt.Errorf("notification type googlePubSub requires the googlePubSub block")
+		}
+		fmt.Fprintf(&b, "[notification.google_pub_sub]\nenabled = true\n")
+		// As with the GCS backup sink, only name the key file when one was
+		// supplied; a path to a file that isn't there fails client setup.
+		if get(seaweedv1.BackupSecretKeyGCSCredentials) != "" {
+			fmt.Fprintf(&b, "google_application_credentials = %s\n", tomlString(path.Join(filerNotificationDir, gcsKeyFileName)))
+		}
+		fmt.Fprintf(&b, "project_id = %s\n", tomlString(n.GooglePubSub.ProjectID))
+		fmt.Fprintf(&b, "topic = %s\n", tomlString(n.GooglePubSub.Topic))
+	case seaweedv1.FilerNotificationWebhook:
+		if n.Webhook == nil {
+			return "", fmt.Errorf("notification type webhook requires the webhook block")
+		}
+		wh := n.Webhook
+		fmt.Fprintf(&b, "[notification.webhook]\nenabled = true\n")
+		fmt.Fprintf(&b, "endpoint = %s\n", tomlString(wh.Endpoint))
+		if token := get(seaweedv1.NotificationSecretKeyBearerToken); token != "" {
+			fmt.Fprintf(&b, "bearer_token = %s\n", tomlString(token))
+		}
+		if wh.TimeoutSeconds != nil {
+			fmt.Fprintf(&b, "timeout_seconds = %d\n", *wh.TimeoutSeconds)
+		}
+		if wh.MaxRetries != nil {
+			fmt.Fprintf(&b, "max_retries = %d\n", *wh.MaxRetries)
+		}
+		if len(wh.EventTypes) > 0 {
+			fmt.Fprintf(&b, "event_types = %s\n", tomlStringList(wh.EventTypes))
+		}
+		if len(wh.PathPrefixes) > 0 {
+			fmt.Fprintf(&b, "path_prefixes = %s\n", tomlStringList(wh.PathPrefixes))
+		}
+	default:
+		return "", fmt.Errorf("unsupported notification type %q", n.Type)
+	}
+	return b.String(), nil
+}
+
+// tomlStringList renders values as a TOML array of basic strings.
+func tomlStringList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = tomlString(v)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// resolveNotificationCredentials reads whatever Secret the sink references,
+// keyed like a backup storage's CredentialsSecret (the webhook token is
+// re-keyed under NotificationSecretKeyBearerToken). A referenced Secret that
+// is missing is an error rather than a silent fall back to ambient
+// credentials: the user asked for these, and a filer started without them
+// would drop every event.
+func (r *SeaweedReconciler) resolveNotificationCredentials(ctx context.Context, m *seaweedv1.Seaweed, n *seaweedv1.FilerNotificationSpec) (map[string][]byte, error) {
+	var ref *corev1.LocalObjectReference
+	switch n.Type {
+	case seaweedv1.FilerNotificationAWSSQS:
+		if n.AWSSQS != nil {
+			ref = n.AWSSQS.CredentialsSecret
+		}
+	case seaweedv1.FilerNotificationGooglePubSub:
+		if n.GooglePubSub != nil {
+			ref = n.GooglePubSub.CredentialsSecret
+		}
+	case seaweedv1.FilerNotificationWebhook:
+		if n.Webhook == nil || n.Webhook.BearerTokenSecret == nil || n.Webhook.BearerTokenSecret.Name == "" {
+			return map[string][]byte{}, nil
+		}
+		sel := n.Webhook.BearerTokenSecret
+		key := sel.Key
+		if key == "" {
+			key = seaweedv1.NotificationSecretKeyBearerToken
+		}
+		data, err := r.notificationSecretData(ctx, m, sel.Name)
+		if err != nil {
+			return nil, err
+		}
+		token, ok := data[key]
+		if !ok {
+			return nil, fmt.Errorf("notification bearer token secret %q has no key %q", sel.Name, key)
+		}
+		return map[string][]byte{seaweedv1.NotificationSecretKeyBearerToken: token}, nil
+	}
+	if ref == nil || ref.Name == "" {
+		return map[string][]byte{}, nil
+	}
+	return r.notificationSecretData(ctx, m, ref.Name)
+}
+
+func (r *SeaweedReconciler) notificationSecretData(ctx context.Context, m *seaweedv1.Seaweed, name string) (map[string][]byte, error) {
+	var secret corev1.Secret
+	if err := r.Get(ctx, types.NamespacedName{Namespace: m.Namespace, Name: name}, &secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("notification credentials secret %q not found in namespace %q", name, m.Namespace)
+		}
+		return nil, err
+	}
+	return secret.Data, nil
+}
+
+// filerNotificationData renders the contents of the notification Secret:
+// notification.toml, plus the GCS key file for a Pub/Sub sink that has one.
+func (r *SeaweedReconciler) filerNotificationData(ctx context.Context, m *seaweedv1.Seaweed) (map[string][]byte, error) {
+	n := m.Spec.Filer.Notification
+	creds, err := r.resolveNotificationCredentials(ctx, m, n)
+	if err != nil {
+		return nil, err
+	}
+	toml, err := renderNotificationToml(n, creds)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string][]byte{"notification.toml": []byte(toml)}
+	if n.Type == seaweedv1.FilerNotificationGooglePubSub {
+		if json := creds[seaweedv1.BackupSecretKeyGCSCredentials]; len(json) > 0 {
+			data[gcsKeyFileName] = json
+		}
+	}
+	return data, nil
+}
+
+// ensureFilerNotification reconciles the Secret holding notification.toml,
+// and removes it once spec.filer.notification is unset.
+func (r *SeaweedReconciler) ensureFilerNotification(ctx context.Context, m *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+	if !filerNotificationEnabled(m) {
+		return ReconcileResult(r.pruneOwnedSecret(ctx, m, filerNotificationSecretName(m)))
+	}
+	data, err := r.filerNotificationData(ctx, m)
+	if err != nil {
+		if r.Recorder != nil {
+			r.Recorder.Eventf(m, corev1.EventTypeWarning, "FilerNotificationInvalid", "%v", err)
+		}
+		return ReconcileResult(err)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      filerNotificationSecretName(m),
+			Namespace: m.Namespace,
+			Labels:    labelsForFiler(m.Name),
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+	if err := controllerutil.SetControllerReference(m, secret, r.Scheme); err != nil {
+		return ReconcileResult(err)
+	}
+	_, err = r.CreateOrUpdateSecret(secret)
+	r.Log.Info("ensure filer notification secret "+secret.Name, "type", m.Spec.Filer.Notification.Type)
+	return ReconcileResult(err)
+}
+
+// filerNotificationVolumeAndMount projects the rendered Secret into
+// filerNotificationDir.
+func filerNotificationVolumeAndMount(m *seaweedv1.Seaweed) (corev1.Volume, corev1.VolumeMount) {
+	return corev1.Volume{
+		Name: filerNotificationVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: filerNotificationSecretName(m),
+			},
+		},
+	}, corev1.VolumeMount{
+		Name:      filerNotificationVolumeName,
+		ReadOnly:  true,
+		MountPath: filerNotificationDir,
+	}
+}
@@ -0,0 +1,253 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+func newNotificationCluster(n *seaweedv1.FilerNotificationSpec) *seaweedv1.Seaweed {
+	return &seaweedv1.Seaweed{
+		ObjectMeta: metav1.ObjectMeta{Name: "sw", Namespace: "ns", UID: "test-uid"},
+		Spec: seaweedv1.SeaweedSpec{
+			Master: &seaweedv1.MasterSpec{Replicas: 1},
+			Filer:  &seaweedv1.FilerSpec{Replicas: 1, Notification: n},
+		},
+	}
+}
+
+func newNotificationReconciler(t *testing.T, objs ...runtime.Object) (*SeaweedReconciler, client.Client) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("clientgoscheme: %v", err)
+	}
+	if err := seaweedv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("seaweedv1: %v", err)
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build()
+	return &SeaweedReconciler{Client: cli, Scheme: scheme, Log: logr.Discard()}, cli
+}
+
+func TestRenderNotificationToml(t *testing.T) {
+	timeout := int32(5)
+	cases := []struct {
+		name  string
+		spec  seaweedv1.FilerNotificationSpec
+		creds map[string][]byte
+		want  []string
+	}{
+		{
+			name: "log",
+			spec: seaweedv1.FilerNotificationSpec{Type: seaweedv1.FilerNotificationLog},
+			want: []string{"[notification.log]", "enabled = true"},
+		},
+		{
+			name: "kafka",
+			spec: seaweedv1.FilerNotificationSpec{
+				Type:  seaweedv1.FilerNotificationKafka,
+				Kafka: &seaweedv1.KafkaNotification{Hosts: []string{"k0:9092", "k1:9092"}, Topic: "filer"},
+			},
+			want: []string{"[notification.kafka]", `hosts = ["k0:9092", "k1:9092"]`, `topic = "filer"`},
+		},
+		{
+			name: "aws sqs defaults the region",
+			spec: seaweedv1.FilerNotificationSpec{
+				Type:   seaweedv1.FilerNotificationAWSSQS,
+				AWSSQS: &seaweedv1.AWSSQSNotification{QueueName: "q"},
+			},
+			creds: map[string][]byte{
+				seaweedv1.BackupSecretKeyAWSAccessKeyID:     []byte("AKIA"),
+				seaweedv1.BackupSecretKeyAWSSecretAccessKey: []byte("secret"),
+			},
+			want: []string{
+				"[notification.aws_sqs]",
+				`aws_access_key_id = "AKIA"`,
+				`aws_secret_access_key = "secret"`,
+				`region = "us-east-2"`,
+				`sqs_queue_name = "q"`,
+			},
+		},
+		{
+			name: "google pub/sub points at the projected key",
+			spec: seaweedv1.FilerNotificationSpec{
+				Type:         seaweedv1.FilerNotificationGooglePubSub,
+				GooglePubSub: &seaweedv1.GooglePubSubNotification{ProjectID: "p", Topic: "t"},
+			},
+			creds: map[string][]byte{seaweedv1.BackupSecretKeyGCSCredentials: []byte("{}")},
+			want: []string{
+				"[notification.google_pub_sub]",
+				`google_application_credentials = "/usr/local/etc/seaweedfs/gcs.json"`,
+				`project_id = "p"`,
+				`topic = "t"`,
+			},
+		},
+		{
+			name: "webhook",
+			spec: seaweedv1.FilerNotificationSpec{
+				Type: seaweedv1.FilerNotificationWebhook,
+				Webhook: &seaweedv1.WebhookNotification{
+					Endpoint:       "http://sink.ns.svc:8080/events",
+					TimeoutSeconds: &timeout,
+					EventTypes:     []string{"create", "delete"},
+					PathPrefixes:   []string{"/buckets"},
+				},
+			},
+			creds: map[string][]byte{seaweedv1.NotificationSecretKeyBearerToken: []byte("tok")},
+			want: []string{
+				"[notification.webhook]",
+				`endpoint = "http://sink.ns.svc:8080/events"`,
+				`bearer_token = "tok"`,
+				"timeout_seconds = 5",
+				`event_types = ["create", "delete"]`,
+				`path_prefixes = ["/buckets"]`,
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := renderNotificationToml(&tc.spec, tc.creds)
+			if err != nil {
+				t.Fatalf("render: %v", err)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("rendered toml missing %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
+
+// Without a key the Pub/Sub client must use ambient credentials; naming a
+// file that is not mounted fails its setup instead.
+func TestRenderNotificationTomlPubSubAmbientCredentials(t *testing.T) {
+	out, err := renderNotificationToml(&seaweedv1.FilerNotificationSpec{
+		Type:         seaweedv1.FilerNotificationGooglePubSub,
+		GooglePubSub: &seaweedv1.GooglePubSubNotification{ProjectID: "p", Topic: "t"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if strings.Contains(out, "google_application_credentials") {
+		t.Errorf("expected no credentials path without a key:\n%s", out)
+	}
+}
+
+func TestRenderNotificationTomlMissingSubBlock(t *testing.T) {
+	if _, err := renderNotificationToml(&seaweedv1.FilerNotificationSpec{Type: seaweedv1.FilerNotificationKafka}, nil); err == nil {
+		t.Fatal("expected an error for type kafka without the kafka block")
+	}
+}
+
+func TestFilerStatefulSetMountsNotificationConfig(t *testing.T) {
+	r := &SeaweedReconciler{}
+
+	pod := &r.createFilerStatefulSet(newNotificationCluster(nil)).Spec.Template.Spec
+	for _, v := range pod.Volumes {
+		if v.Name == filerNotificationVolumeName {
+			t.Fatalf("expected no notification volume without spec.filer.notification")
+		}
+	}
+
+	m := newNotificationCluster(&seaweedv1.FilerNotificationSpec{Type: seaweedv1.FilerNotificationLog})
+	pod = &r.createFilerStatefulSet(m).Spec.Template.Spec
+	vol := podVolume(t, pod, filerNotificationVolumeName)
+	if vol.Secret == nil || vol.Secret.SecretName != "sw-filer-notification" {
+		t.Errorf("expected the sw-filer-notification Secret, got %+v", vol.VolumeSource)
+	}
+	if mount := containerMount(t, filerContainer(t, pod), filerNotificationVolumeName); mount.MountPath != filerNotificationDir {
+		t.Errorf("expected mount at %s, got %q", filerNotificationDir, mount.MountPath)
+	}
+}
+
+// A rotated credential changes only the Secret; the filer reads
+// notification.toml once at startup, so the pod template has to change too.
+func TestEnsureFilerNotificationRollsOnCredentialChange(t *testing.T) {
+	creds := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "sqs-creds", Namespace: "ns"},
+		Data: map[string][]byte{
+			seaweedv1.BackupSecretKeyAWSAccessKeyID:     []byte("AKIA"),
+			seaweedv1.BackupSecretKeyAWSSecretAccessKey: []byte("one"),
+		},
+	}
+	m := newNotificationCluster(&seaweedv1.FilerNotificationSpec{
+		Type: seaweedv1.FilerNotificationAWSSQS,
+		AWSSQS: &seaweedv1.AWSSQSNotification{
+			QueueName:         "q",
+			CredentialsSecret: &corev1.LocalObjectReference{Name: "sqs-creds"},
+		},
+	})
+	r, cli := newNotificationReconciler(t, m, creds)
+	ctx := context.Background()
+
+	revision := func() string {
+		t.Helper()
+		if _, _, err := r.ensureFilerNotification(ctx, m); err != nil {
+			t.Fatalf("ensureFilerNotification: %v", err)
+		}
+		var rendered corev1.Secret
+		if err := cli.Get(ctx, client.ObjectKey{Namespace: "ns", Name: "sw-filer-notification"}, &rendered); err != nil {
+			t.Fatalf("get rendered secret: %v", err)
+		}
+		if !strings.Contains(string(rendered.Data["notification.toml"]), `aws_secret_access_key = "`+string(creds.Data[seaweedv1.BackupSecretKeyAWSSecretAccessKey])+`"`) {
+			t.Errorf("rendered toml does not carry the current credentials:\n%s", rendered.Data["notification.toml"])
+		}
+		return configRevision(rendered.Data)
+	}
+
+	first := revision()
+	creds.Data[seaweedv1.BackupSecretKeyAWSSecretAccessKey] = []byte("two")
+	if err := cli.Update(ctx, creds); err != nil {
+		t.Fatalf("rotate credentials: %v", err)
+	}
+	if second := revision(); second == first {
+		t.Errorf("expected the revision to change after a credential rotation, stayed %q", first)
+	}
+}
+
+func TestEnsureFilerNotificationMissingSecret(t *testing.T) {
+	m := newNotificationCluster(&seaweedv1.FilerNotificationSpec{
+		Type: seaweedv1.FilerNotificationWebhook,
+		Webhook: &seaweedv1.WebhookNotification{
+			Endpoint: "http://sink",
+			BearerTokenSecret: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "absent"},
+			},
+		},
+	})
+	r, _ := newNotificationReconciler(t, m)
+	if done, _, err := r.ensureFilerNotification(context.Background(), m); !done || err == nil {
+		t.Fatalf("expected a missing token Secret to stop the reconcile, got done=%v err=%v", done, err)
+	}
+}
+
+// Dropping spec.filer.notification removes the rendered Secret so the
+// credentials baked into it do not outlive the sink.
+func TestEnsureFilerNotificationPrunesWhenUnset(t *testing.T) {
+	m := newNotificationCluster(&seaweedv1.FilerNotificationSpec{Type: seaweedv1.FilerNotificationLog})
+	r, cli := newNotificationReconciler(t, m)
+	ctx := context.Background()
+	if _, _, err := r.ensureFilerNotification(ctx, m); err != nil {
+		t.Fatalf("ensureFilerNotification: %v", err)
+	}
+
+	m.Spec.Filer.Notification = nil
+	if _, _, err := r.ensureFilerNotification(ctx, m); err != nil {
+		t.Fatalf("ensureFilerNotification: %v", err)
+	}
+	err := cli.Get(ctx, client.ObjectKey{Namespace: "ns", Name: "sw-filer-notification"}, &corev1.Secret{})
+	if client.IgnoreNotFound(err) != nil || err == nil {
+		t.Errorf("expected the notification Secret to be deleted, got %v", err)
+	}
+}
@@ -124,6 +124,11 @@ func (r *SeaweedReconciler) createFilerStatefulSet(m *seaweedv1.Seaweed) *appsv1
 			MountPath: componentConfigDir,
 		})
 	}
+	if filerNotificationEnabled(m) {
+		vol, mount := filerNotificationVolumeAndMount(m)
+		filerPodSpec.Volumes = append(filerPodSpec.Volumes, vol)
+		volumeMounts = append(volumeMounts, mount)
+	}
 	if tlsVols, tlsMounts := tlsVolumesAndMounts(m); len(tlsVols) > 0 {
 		filerPodSpec.Volumes = append(filerPodSpec.Volumes, tlsVols...)
 		volumeMounts = append(volumeMounts, tlsMounts...)
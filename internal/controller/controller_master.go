@@ -30,7 +30,11 @@ func (r *SeaweedReconciler) ensureMaster(ctx context.Context, seaweedCR *seaweed
 		return
 	}
 
-	if done, result, err = r.ensureMasterStatefulSet(seaweedCR); done {
+	if done, result, err = r.ensureRemoteTierConfig(ctx, seaweedCR); done {
+		return
+	}
+
+	if done, result, err = r.ensureMasterStatefulSet(ctx, seaweedCR); done {
 		return
 	}
 
@@ -87,10 +91,18 @@ func (r *SeaweedReconciler) waitForMasterStatefulSet(seaweedCR *seaweedv1.Seawee
 
 }
 
-func (r *SeaweedReconciler) ensureMasterStatefulSet(seaweedCR *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+func (r *SeaweedReconciler) ensureMasterStatefulSet(ctx context.Context, seaweedCR *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
 	log := r.Log.WithValues("sw-master-statefulset", seaweedCR.Name)
 
 	masterStatefulSet := r.createMasterStatefulSet(seaweedCR)
+	if remoteTiersEnabled(seaweedCR) {
+		data, err := r.remoteTierData(ctx, seaweedCR)
+		if err != nil {
+			return ReconcileResult(err)
+		}
+		template := &masterStatefulSet.Spec.Template
+		template.Annotations = withRevisionAnnotation(template.Annotations, remoteTierConfigAnnotation, configRevision(data))
+	}
 	if err := controllerutil.SetControllerReference(seaweedCR, masterStatefulSet, r.Scheme); err != nil {
 		return ReconcileResult(err)
 	}
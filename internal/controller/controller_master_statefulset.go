@@ -104,8 +104,13 @@ func (r *SeaweedReconciler) createMasterStatefulSet(m *seaweedv1.Seaweed) *appsv
 	// master.toml comes from a Secret when ConfigSecret is set, otherwise from
 	// the ConfigMap — and only when the user supplied non-blank content.
 	// Mirrors the filer path; see hasMasterConfig for why whitespace-only
-	// counts as "no override".
-	if sel := masterConfigSecret(m); sel != nil {
+	// counts as "no override". With remote tiers the operator renders
+	// master.toml, folding in the user's config, into its own Secret.
+	if remoteTiersEnabled(m) {
+		vol, mount := remoteTierMasterConfigVolumeAndMount(m)
+		masterPodSpec.Volumes = append(masterPodSpec.Volumes, vol)
+		masterConfigMounts = append(masterConfigMounts, mount)
+	} else if sel := masterConfigSecret(m); sel != nil {
 		vol, mount := configSecretVolumeAndMount("master-config", "master.toml", sel)
 		masterPodSpec.Volumes = append(masterPodSpec.Volumes, vol)
 		masterConfigMounts = append(masterConfigMounts, mount)
@@ -130,6 +135,9 @@ func (r *SeaweedReconciler) createMasterStatefulSet(m *seaweedv1.Seaweed) *appsv
 		masterPodSpec.Volumes = append(masterPodSpec.Volumes, tlsVols...)
 		masterConfigMounts = append(masterConfigMounts, tlsMounts...)
 	}
+	rcloneVols, rcloneMounts, rcloneEnv := remoteTierRcloneVolumesAndMounts(m)
+	masterPodSpec.Volumes = append(masterPodSpec.Volumes, rcloneVols...)
+	masterConfigMounts = append(masterConfigMounts, rcloneMounts...)
 
 	var persistentVolumeClaims []corev1.PersistentVolumeClaim
 	if dataDir, ok := masterDataDir(m); ok {
@@ -187,7 +195,7 @@ func (r *SeaweedReconciler) createMasterStatefulSet(m *seaweedv1.Seaweed) *appsv
 		Image:           m.BaseMasterSpec().Image(),
 		ImagePullPolicy: m.BaseMasterSpec().ImagePullPolicy(),
 		SecurityContext: m.BaseMasterSpec().ContainerSecurityContext(),
-		Env:             append(m.BaseMasterSpec().Env(), kubernetesEnvVars...),
+		Env:             append(append(m.BaseMasterSpec().Env(), kubernetesEnvVars...), rcloneEnv...),
 		Resources:       filterContainerResources(m.Spec.Master.ResourceRequirements),
 		VolumeMounts:    mergeVolumeMounts(masterConfigMounts, m.BaseMasterSpec().VolumeMounts()),
 		Command: []string{
@@ -0,0 +1,523 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/internal/controller/swadmin"
+)
+
+// Remote tier reconciliation.
+//
+// weed reads remote storage backends from the [storage.backend] section of
+// master.toml, and the master hands them to the volume servers on heartbeat.
+// The operator therefore renders the user's master.toml plus one section per
+// spec.remoteTiers entry into a Secret the masters mount in place of the
+// plain config. Non-S3 tiers go through weed's rclone backend, whose
+// rclone.conf is mounted into the master and volume pods alike.
+//
+// Separately, once per policy interval, the operator picks the local volumes
+// a tier's policy selects and moves them with volume.tier.upload.
+
+const (
+	// remoteTierRcloneDir holds rclone.conf (and GCS keys) in master and
+	// volume pods; RCLONE_CONFIG points at it.
+	remoteTierRcloneDir = "/etc/seaweedfs-rclone"
+
+	remoteTierRcloneVolumeName = "remote-tiers-rclone"
+
+	// remoteTierConfigAnnotation carries a digest of the rendered tier
+	// Secret. The master reads master.toml only at startup, so a changed
+	// tier or a rotated credential has to roll the masters.
+	remoteTierConfigAnnotation = "seaweed.seaweedfs.com/remote-tiers-config"
+)
+
+// Defaults for spec.remoteTiers[].policy fields the API server did not
+// default (objects created before the field existed, or built in tests).
+const (
+	defaultRemoteTierMinAge            = 7 * 24 * time.Hour
+	defaultRemoteTierFullPercent       = 95
+	defaultRemoteTierInterval          = time.Hour
+	defaultRemoteTierMaxVolumesPerPass = 1
+)
+
+func remoteTiersEnabled(m *seaweedv1.Seaweed) bool {
+	return len(m.Spec.RemoteTiers) > 0
+}
+
+func remoteTiersSecretName(m *seaweedv1.Seaweed) string {
+	return m.Name + "-remote-tiers"
+}
+
+// remoteTierBackend is the weed storage backend type serving a tier.
+func remoteTierBackend(t *seaweedv1.RemoteTierSpec) string {
+	if t.Storage.Type == seaweedv1.BackupStorageS3 {
+		return "s3"
+	}
+	return "rclone"
+}
+
+// remoteTierDest is the backend name volume.tier.upload takes as -dest and
+// the master reports offloaded volumes under.
+func remoteTierDest(t *seaweedv1.RemoteTierSpec) string {
+	return remoteTierBackend(t) + "." + t.Name
+}
+
+func remoteTiersUseRclone(m *seaweedv1.Seaweed) bool {
+	for i := range m.Spec.RemoteTiers {
+		if remoteTierBackend(&m.Spec.RemoteTiers[i]) == "rclone" {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteTierGCSKeyFile is the basename a GCS tier's service-account key is
+// mounted under in remoteTierRcloneDir.
+func remoteTierGCSKeyFile(t *seaweedv1.RemoteTierSpec) string {
+	return t.Name + "-" + gcsKeyFileName
+}
+
+// remoteTierHasGCSKey reports whether a tier ships a GCS key file, which is
+// the case for every GCS tier naming a credentials Secret.
+func remoteTierHasGCSKey(t *seaweedv1.RemoteTierSpec) bool {
+	return t.Storage.Type == seaweedv1.BackupStorageGCS && t.Storage.CredentialsSecret != nil && *t.Storage.CredentialsSecret != ""
+}
+
+// renderRemoteTierToml emits the [storage.backend.*] section for a tier, with
+// S3 credentials baked in from creds. Credential fields are left empty when
+// no Secret was given so weed falls back to its ambient chain.
+func renderRemoteTierToml(t *seaweedv1.RemoteTierSpec, creds map[string][]byte) (string, error) {
+	var b strings.Builder
+	switch t.Storage.Type {
+	case seaweedv1.BackupStorageS3:
+		s3 := t.Storage.S3
+		if s3 == nil {
+			return "", fmt.Errorf("remote tier %q: type s3 requires the s3 block", t.Name)
+		}
+		region := s3.Region
+		if region == "" {
+			region = "us-east-2"
+		}
+		storageClass := "STANDARD_IA"
+		if t.StorageClass != nil && *t.StorageClass != "" {
+			storageClass = *t.StorageClass
+		}
+		forcePath := true
+		if s3.ForcePathStyle != nil {
+			forcePath = *s3.ForcePathStyle
+		}
+		fmt.Fprintf(&b, "[storage.backend.s3.%s]\nenabled = true\n", t.Name)
+		fmt.Fprintf(&b, "aws_access_key_id = %s\n", tomlString(string(creds[seaweedv1.BackupSecretKeyAWSAccessKeyID])))
+		fmt.Fprintf(&b, "aws_secret_access_key = %s\n", tomlString(string(creds[seaweedv1.BackupSecretKeyAWSSecretAccessKey])))
+		fmt.Fprintf(&b, "region = %s\n", tomlString(region))
+		fmt.Fprintf(&b, "bucket = %s\n", tomlString(s3.Bucket))
+		fmt.Fprintf(&b, "endpoint = %s\n", tomlString(s3.Endpoint))
+		fmt.Fprintf(&b, "storage_class = %s\n", tomlString(storageClass))
+		fmt.Fprintf(&b, "force_path_style = %t\n", forcePath)
+	case seaweedv1.BackupStorageGCS, seaweedv1.BackupStorageAzure, seaweedv1.BackupStorageB2:
+		fmt.Fprintf(&b, "[storage.backend.rclone.%s]\nenabled = true\n", t.Name)
+		fmt.Fprintf(&b, "remote_name = %s\n", tomlString(t.Name))
+	default:
+		return "", fmt.Errorf("remote tier %q: unsupported storage type %q", t.Name, t.Storage.Type)
+	}
+	return b.String(), nil
+}
+
+// renderRcloneRemote emits the rclone.conf remotes behind a non-S3 tier: the
+// store itself, named <tier>_store (tier names cannot hold underscores, so it
+// never collides with another tier), and an alias named after the tier that
+// roots it at the configured bucket and directory.
+func renderRcloneRemote(t *seaweedv1.RemoteTierSpec, creds map[string][]byte) (string, error) {
+	get := func(k string) (string, error) {
+		v := strings.TrimSpace(string(creds[k]))
+		if v == "" {
+			return "", fmt.Errorf("remote tier %q: credentials secret has no %s", t.Name, k)
+		}
+		if strings.ContainsAny(v, "\r\n") {
+			return "", fmt.Errorf("remote tier %q: %s spans several lines", t.Name, k)
+		}
+		return v, nil
+	}
+
+	store := t.Name + "_store"
+	var b strings.Builder
+	var root string
+	switch t.Storage.Type {
+	case seaweedv1.BackupStorageGCS:
+		gcs := t.Storage.GCS
+		if gcs == nil {
+			return "", fmt.Errorf("remote tier %q: type gcs requires the gcs block", t.Name)
+		}
+		fmt.Fprintf(&b, "[%s]\ntype = google cloud storage\nbucket_policy_only = true\n", store)
+		if remoteTierHasGCSKey(t) {
+			fmt.Fprintf(&b, "service_account_file = %s\n", path.Join(remoteTierRcloneDir, remoteTierGCSKeyFile(t)))
+		} else {
+			fmt.Fprintf(&b, "env_auth = true\n")
+		}
+		root = path.Join(gcs.Bucket, gcs.Directory)
+	case seaweedv1.BackupStorageAzure:
+		az := t.Storage.Azure
+		if az == nil {
+			return "", fmt.Errorf("remote tier %q: type azure requires the azure block", t.Name)
+		}
+		key, err := get(seaweedv1.BackupSecretKeyAzureAccountKey)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "[%s]\ntype = azureblob\naccount = %s\nkey = %s\n", store, az.AccountName, key)
+		root = path.Join(az.Container, az.Directory)
+	case seaweedv1.BackupStorageB2:
+		b2 := t.Storage.B2
+		if b2 == nil {
+			return "", fmt.Errorf("remote tier %q: type b2 requires the b2 block", t.Name)
+		}
+		account, err := get(seaweedv1.BackupSecretKeyB2AccountID)
+		if err != nil {
+			return "", err
+		}
+		key, err := get(seaweedv1.BackupSecretKeyB2AppKey)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "[%s]\ntype = b2\naccount = %s\nkey = %s\n", store, account, key)
+		root = path.Join(b2.Bucket, b2.Directory)
+	default:
+		return "", fmt.Errorf("remote tier %q: storage type %q is not served by rclone", t.Name, t.Storage.Type)
+	}
+	fmt.Fprintf(&b, "\n[%s]\ntype = alias\nremote = %s:%s\n", t.Name, store, strings.TrimSuffix(root, "/"))
+	return b.String(), nil
+}
+
+// masterBaseConfig returns the user's own master.toml, from spec.master.config
+// or spec.master.configSecret, which the tier sections are appended to.
+func (r *SeaweedReconciler) masterBaseConfig(ctx context.Context, m *seaweedv1.Seaweed) (string, error) {
+	if sel := masterConfigSecret(m); sel != nil {
+		var secret corev1.Secret
+		if err := r.Get(ctx, types.NamespacedName{Namespace: m.Namespace, Name: sel.Name}, &secret); err != nil {
+			if apierrors.IsNotFound(err) && sel.Optional != nil && *sel.Optional {
+				return "", nil
+			}
+			return "", fmt.Errorf("read master config secret %q: %w", sel.Name, err)
+		}
+		return string(secret.Data[sel.Key]), nil
+	}
+	if hasMasterConfig(m) {
+		return *m.Spec.Master.Config, nil
+	}
+	return "", nil
+}
+
+// remoteTierData renders the contents of the tier Secret: master.toml, plus
+// rclone.conf and GCS keys when a tier goes through rclone.
+func (r *SeaweedReconciler) remoteTierData(ctx context.Context, m *seaweedv1.Seaweed) (map[string][]byte, error) {
+	base, err := r.masterBaseConfig(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	master := strings.TrimRight(base, "\n")
+	if master != "" {
+		master += "\n\n"
+	}
+	master += "# Remote tiers rendered from spec.remoteTiers.\n"
+
+	data := map[string][]byte{}
+	var rclone strings.Builder
+	for i := range m.Spec.RemoteTiers {
+		t := &m.Spec.RemoteTiers[i]
+		creds, err := readBackupCredentials(ctx, r.Client, m.Namespace, t.Storage)
+		if err != nil {
+			return nil, fmt.Errorf("remote tier %q: %w", t.Name, err)
+		}
+		section, err := renderRemoteTierToml(t, creds)
+		if err != nil {
+			return nil, err
+		}
+		master += "\n" + section
+		if remoteTierBackend(t) != "rclone" {
+			continue
+		}
+		remote, err := renderRcloneRemote(t, creds)
+		if err != nil {
+			return nil, err
+		}
+		if rclone.Len() > 0 {
+			rclone.WriteString("\n")
+		}
+		rclone.WriteString(remote)
+		if remoteTierHasGCSKey(t) {
+			key := creds[seaweedv1.BackupSecretKeyGCSCredentials]
+			if len(key) == 0 {
+				return nil, fmt.Errorf("remote tier %q: credentials secret has no %s", t.Name, seaweedv1.BackupSecretKeyGCSCredentials)
+			}
+			data[remoteTierGCSKeyFile(t)] = key
+		}
+	}
+	data["master.toml"] = []byte(master)
+	if rclone.Len() > 0 {
+		data["rclone.conf"] = []byte(rclone.String())
+	}
+	return data, nil
+}
+
+// ensureRemoteTierConfig reconciles the Secret holding the tiered
+// master.toml, and removes it once spec.remoteTiers is empty.
+func (r *SeaweedReconciler) ensureRemoteTierConfig(ctx context.Context, m *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+	if !remoteTiersEnabled(m) {
+		return ReconcileResult(r.pruneOwnedSecret(ctx, m, remoteTiersSecretName(m)))
+	}
+	data, err := r.remoteTierData(ctx, m)
+	if err != nil {
+		if r.Recorder != nil {
+			r.Recorder.Eventf(m, corev1.EventTypeWarning, "RemoteTiersInvalid", "%v", err)
+		}
+		return ReconcileResult(err)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remoteTiersSecretName(m),
+			Namespace: m.Namespace,
+			Labels:    labelsForMaster(m.Name),
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+	if err := controllerutil.SetControllerReference(m, secret, r.Scheme); err != nil {
+		return ReconcileResult(err)
+	}
+	_, err = r.CreateOrUpdateSecret(secret)
+	r.Log.Info("ensure remote tiers secret "+secret.Name, "tiers", len(m.Spec.RemoteTiers))
+	return ReconcileResult(err)
+}
+
+// remoteTierMasterConfigVolumeAndMount projects the tiered master.toml into
+// componentConfigDir, where the plain config would otherwise go.
+func remoteTierMasterConfigVolumeAndMount(m *seaweedv1.Seaweed) (corev1.Volume, corev1.VolumeMount) {
+	return configSecretVolumeAndMount("master-config", "master.toml", &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: remoteTiersSecretName(m)},
+		Key:                  "master.toml",
+	})
+}
+
+// remoteTierRcloneVolumesAndMounts returns the rclone.conf volume, mount and
+// RCLONE_CONFIG variable master and volume pods need when a tier goes through
+// rclone, or nothing otherwise.
+func remoteTierRcloneVolumesAndMounts(m *seaweedv1.Seaweed) ([]corev1.Volume, []corev1.VolumeMount, []corev1.EnvVar) {
+	if !remoteTiersUseRclone(m) {
+		return nil, nil, nil
+	}
+	items := []corev1.KeyToPath{{Key: "rclone.conf", Path: "rclone.conf"}}
+	for i := range m.Spec.RemoteTiers {
+		if t := &m.Spec.RemoteTiers[i]; remoteTierHasGCSKey(t) {
+			items = append(items, corev1.KeyToPath{Key: remoteTierGCSKeyFile(t), Path: remoteTierGCSKeyFile(t)})
+		}
+	}
+	vol := corev1.Volume{
+		Name: remoteTierRcloneVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: remoteTiersSecretName(m),
+				Items:      items,
+			},
+		},
+	}
+	mount := corev1.VolumeMount{
+		Name:      remoteTierRcloneVolumeName,
+		ReadOnly:  true,
+		MountPath: remoteTierRcloneDir,
+	}
+	env := corev1.EnvVar{Name: "RCLONE_CONFIG", Value: path.Join(remoteTierRcloneDir, "rclone.conf")}
+	return []corev1.Volume{vol}, []corev1.VolumeMount{mount}, []corev1.EnvVar{env}
+}
+
+// remoteTierCandidates returns the local volumes a tier's policy selects for
+// upload at now, in volume id order, skipping those in claimed (already
+// selected by an earlier tier).
+func remoteTierCandidates(p *seaweedv1.RemoteTierPolicy, layout swadmin.VolumeLayout, now time.Time, claimed map[uint32]bool) []swadmin.Volume {
+	if layout.VolumeSizeLimit == 0 {
+		// Fullness cannot be judged without the limit.
+		return nil
+	}
+	minAge := defaultRemoteTierMinAge
+	if p.MinAge != nil {
+		minAge = p.MinAge.Duration
+	}
+	full := uint64(defaultRemoteTierFullPercent)
+	if p.FullnessPercent != nil {
+		full = uint64(*p.FullnessPercent)
+	}
+	var out []swadmin.Volume
+	for _, v := range layout.Volumes {
+		if v.RemoteStorage != "" || claimed[v.ID] || !collectionSelected(p.Collections, v.Collection) {
+			continue
+		}
+		if v.Size*100 < layout.VolumeSizeLimit*full || now.Sub(v.ModifiedAt) < minAge {
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// ensureRemoteTiers runs the upload policies of spec.remoteTiers and reports
+// what each tier holds on status and the outcome on the RemoteTiersReady
+// condition. It waits for ready volume servers; failures are surfaced rather
+// than blocking the rest of the reconcile.
+func (r *SeaweedReconciler) ensureRemoteTiers(ctx context.Context, m *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+	if !remoteTiersEnabled(m) {
+		m.Status.RemoteTiers = nil
+		meta.RemoveStatusCondition(&m.Status.Conditions, seaweedv1.SeaweedConditionRemoteTiersReady)
+		return ReconcileResult(nil)
+	}
+	if r.VolumeAdminFactory == nil || m.Spec.Master == nil || m.Status.Volume.ReadyReplicas == 0 {
+		return ReconcileResult(nil)
+	}
+
+	if err := r.reconcileRemoteTiers(ctx, m); err != nil {
+		r.Log.Error(err, "remote tiers", "seaweed", m.Name)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(m, corev1.EventTypeWarning, "RemoteTiersFailed", "%v", err)
+		}
+		meta.SetStatusCondition(&m.Status.Conditions, metav1.Condition{
+			Type:               seaweedv1.SeaweedConditionRemoteTiersReady,
+			Status:             metav1.ConditionUnknown,
+			ObservedGeneration: m.Generation,
+			Reason:             "CheckFailed",
+			Message:            err.Error(),
+		})
+	}
+	return ReconcileResult(nil)
+}
+
+func (r *SeaweedReconciler) reconcileRemoteTiers(ctx context.Context, m *seaweedv1.Seaweed) error {
+	dialOption, _, err := loadSeaweedGrpcDialOption(ctx, r.Client, m)
+	if err != nil {
+		return err
+	}
+	admin, err := r.VolumeAdminFactory(getMasterPeersString(m), dialOption, r.Log)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	layout, err := admin.VolumeLayout(ctx)
+	if err != nil {
+		return err
+	}
+	previous := map[string]*metav1.Time{}
+	for _, st := range m.Status.RemoteTiers {
+		previous[st.Name] = st.LastUploadTime
+	}
+
+	now := r.now()
+	claimed := map[uint32]bool{}
+	attempted := false
+	var pending int32
+	var failures []string
+	statuses := make([]seaweedv1.RemoteTierStatus, 0, len(m.Spec.RemoteTiers))
+	for i := range m.Spec.RemoteTiers {
+		t := &m.Spec.RemoteTiers[i]
+		dest := remoteTierDest(t)
+		status := seaweedv1.RemoteTierStatus{Name: t.Name, LastUploadTime: previous[t.Name]}
+		for _, v := range layout.Volumes {
+			if v.RemoteStorage == dest {
+				status.OffloadedVolumes++
+				status.OffloadedBytes += int64(v.Size)
+			}
+		}
+
+		if p := t.Policy; p != nil {
+			candidates := remoteTierCandidates(p, layout, now, claimed)
+			for _, v := range candidates {
+				claimed[v.ID] = true
+			}
+			interval := defaultRemoteTierInterval
+			if p.Interval != nil {
+				interval = p.Interval.Duration
+			}
+			budget := int32(defaultRemoteTierMaxVolumesPerPass)
+			if p.MaxVolumesPerPass != nil {
+				budget = *p.MaxVolumesPerPass
+			}
+			uploaded := 0
+			if len(candidates) > 0 && (status.LastUploadTime == nil || now.Sub(status.LastUploadTime.Time) >= interval) {
+				attempted = true
+				status.LastUploadTime = &metav1.Time{Time: now}
+				for _, v := range candidates {
+					if budget == 0 {
+						break
+					}
+					budget--
+					if err := admin.UploadToRemoteTier(ctx, dest, v.Collection, v.ID); err != nil {
+						r.Log.Error(err, "volume.tier.upload", "seaweed", m.Name, "tier", t.Name, "volume", v.ID)
+						failures = append(failures, fmt.Sprintf("volume %d to %s: %v", v.ID, t.Name, err))
+						continue
+					}
+					// Counted as offloaded now; the next pass reads it back.
+					status.OffloadedVolumes++
+					status.OffloadedBytes += int64(v.Size)
+					uploaded++
+					if r.Recorder != nil {
+						r.Recorder.Eventf(m, corev1.EventTypeNormal, "VolumeOffloaded",
+							"Moved volume %d (collection %q) to remote tier %s", v.ID, v.Collection, t.Name)
+					}
+				}
+			}
+			status.PendingVolumes = int32(len(candidates) - uploaded)
+			pending += status.PendingVolumes
+		}
+		statuses = append(statuses, status)
+	}
+	m.Status.RemoteTiers = statuses
+
+	// Between upload passes a failure stays reported until the next pass.
+	existing := meta.FindStatusCondition(m.Status.Conditions, seaweedv1.SeaweedConditionRemoteTiersReady)
+	if !attempted && existing != nil && existing.Reason == "UploadFailed" {
+		return nil
+	}
+	cond := metav1.Condition{
+		Type:               seaweedv1.SeaweedConditionRemoteTiersReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: m.Generation,
+		Reason:             "UpToDate",
+	}
+	switch {
+	case len(failures) > 0:
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "UploadFailed"
+		cond.Message = "volume.tier.upload failed for " + strings.Join(failures, "; ")
+	case pending > 0:
+		cond.Reason = "Offloading"
+		cond.Message = fmt.Sprintf("%d volumes waiting for upload", pending)
+	}
+	meta.SetStatusCondition(&m.Status.Conditions, cond)
+	return nil
+}
@@ -0,0 +1,290 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/internal/controller/swadmin"
+)
+
+func newTestSeaweedWithRemoteTiers() *seaweedv1.Seaweed {
+	sw := newTestSeaweed()
+	sw.Spec.Master.Config = ptr.To("[master.maintenance]\nsleep_minutes = 17\n")
+	sw.Spec.Volume = &seaweedv1.VolumeSpec{Replicas: 1}
+	sw.Spec.RemoteTiers = []seaweedv1.RemoteTierSpec{
+		{
+			Name: "cold",
+			Storage: seaweedv1.BackupStorageSpec{
+				Type:              seaweedv1.BackupStorageS3,
+				S3:                &seaweedv1.S3BackupStore{Bucket: "volumes", Endpoint: "https://s3.example.com"},
+				CredentialsSecret: ptr.To("s3-creds"),
+			},
+			StorageClass: ptr.To("GLACIER_IR"),
+		},
+		{
+			Name: "archive",
+			Storage: seaweedv1.BackupStorageSpec{
+				Type:              seaweedv1.BackupStorageAzure,
+				Azure:             &seaweedv1.AzureBackupStore{AccountName: "acct", Container: "tier", Directory: "/prod/"},
+				CredentialsSecret: ptr.To("azure-creds"),
+			},
+		},
+		{
+			Name: "gcs",
+			Storage: seaweedv1.BackupStorageSpec{
+				Type:              seaweedv1.BackupStorageGCS,
+				GCS:               &seaweedv1.GCSBackupStore{Bucket: "gvol", Directory: "/"},
+				CredentialsSecret: ptr.To("gcs-creds"),
+			},
+		},
+	}
+	return sw
+}
+
+func remoteTierCredentialSecrets(ns string) []*corev1.Secret {
+	return []*corev1.Secret{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "s3-creds", Namespace: ns},
+			Data: map[string][]byte{
+				seaweedv1.BackupSecretKeyAWSAccessKeyID:     []byte("AKID"),
+				seaweedv1.BackupSecretKeyAWSSecretAccessKey: []byte("SECRET"),
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "azure-creds", Namespace: ns},
+			Data:       map[string][]byte{seaweedv1.BackupSecretKeyAzureAccountKey: []byte("azkey")},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "gcs-creds", Namespace: ns},
+			Data:       map[string][]byte{seaweedv1.BackupSecretKeyGCSCredentials: []byte(`{"type":"service_account"}`)},
+		},
+	}
+}
+
+func TestEnsureRemoteTierConfig(t *testing.T) {
+	sw := newTestSeaweedWithRemoteTiers()
+	secrets := remoteTierCredentialSecrets(sw.Namespace)
+	r, cli := newNotificationReconciler(t, secrets[0], secrets[1], secrets[2])
+	ctx := context.Background()
+
+	if _, _, err := r.ensureRemoteTierConfig(ctx, sw); err != nil {
+		t.Fatalf("ensureRemoteTierConfig: %v", err)
+	}
+	var got corev1.Secret
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: sw.Namespace, Name: "prod-remote-tiers"}, &got); err != nil {
+		t.Fatalf("get secret: %v", err)
+	}
+	master := string(got.Data["master.toml"])
+	for _, want := range []string{
+		"[master.maintenance]\nsleep_minutes = 17\n", // the user's own config is kept
+		"[storage.backend.s3.cold]\nenabled = true\n",
+		`aws_access_key_id = "AKID"`,
+		`bucket = "volumes"`,
+		`endpoint = "https://s3.example.com"`,
+		`storage_class = "GLACIER_IR"`,
+		"[storage.backend.rclone.archive]\nenabled = true\nremote_name = \"archive\"\n",
+		"[storage.backend.rclone.gcs]",
+	} {
+		if !strings.Contains(master, want) {
+			t.Errorf("master.toml missing %q:\n%s", want, master)
+		}
+	}
+	rclone := string(got.Data["rclone.conf"])
+	for _, want := range []string{
+		"[archive_store]\ntype = azureblob\naccount = acct\nkey = azkey\n",
+		"[archive]\ntype = alias\nremote = archive_store:tier/prod\n",
+		"service_account_file = /etc/seaweedfs-rclone/gcs-gcs.json",
+		"[gcs]\ntype = alias\nremote = gcs_store:gvol\n",
+	} {
+		if !strings.Contains(rclone, want) {
+			t.Errorf("rclone.conf missing %q:\n%s", want, rclone)
+		}
+	}
+	if string(got.Data["gcs-gcs.json"]) != `{"type":"service_account"}` {
+		t.Errorf("gcs key = %q", got.Data["gcs-gcs.json"])
+	}
+
+	// A missing Azure key is an error, not an unusable config.
+	secrets[1].Data = nil
+	if err := cli.Update(ctx, secrets[1]); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if _, _, err := r.ensureRemoteTierConfig(ctx, sw); err == nil || !strings.Contains(err.Error(), seaweedv1.BackupSecretKeyAzureAccountKey) {
+		t.Errorf("expected a missing account key error, got %v", err)
+	}
+
+	// Dropping every tier removes the Secret.
+	sw.Spec.RemoteTiers = nil
+	if _, _, err := r.ensureRemoteTierConfig(ctx, sw); err != nil {
+		t.Fatalf("ensureRemoteTierConfig on removal: %v", err)
+	}
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: sw.Namespace, Name: "prod-remote-tiers"}, &corev1.Secret{}); !apierrors.IsNotFound(err) {
+		t.Errorf("secret not pruned: %v", err)
+	}
+}
+
+func TestRemoteTierPodWiring(t *testing.T) {
+	sw := newTestSeaweedWithRemoteTiers()
+	r := &SeaweedReconciler{}
+
+	for name, pod := range map[string]*corev1.PodSpec{
+		"master": &r.createMasterStatefulSet(sw).Spec.Template.Spec,
+		"volume": &r.createVolumeServerStatefulSet(sw).Spec.Template.Spec,
+	} {
+		rclone := false
+		for _, v := range pod.Volumes {
+			if v.Name != remoteTierRcloneVolumeName {
+				continue
+			}
+			rclone = true
+			if v.Secret == nil || v.Secret.SecretName != "prod-remote-tiers" || len(v.Secret.Items) != 2 {
+				t.Errorf("%s rclone volume = %+v", name, v.Secret)
+			}
+		}
+		if !rclone {
+			t.Errorf("%s pod has no rclone volume", name)
+		}
+		env := false
+		for _, e := range pod.Containers[0].Env {
+			if e.Name == "RCLONE_CONFIG" && e.Value == "/etc/seaweedfs-rclone/rclone.conf" {
+				env = true
+			}
+		}
+		if !env {
+			t.Errorf("%s container has no RCLONE_CONFIG", name)
+		}
+	}
+
+	// The masters load master.toml from the tier Secret, not the ConfigMap.
+	master := r.createMasterStatefulSet(sw).Spec.Template.Spec
+	for _, v := range master.Volumes {
+		if v.Name == "master-config" && (v.Secret == nil || v.Secret.SecretName != "prod-remote-tiers") {
+			t.Errorf("master-config volume = %+v", v.VolumeSource)
+		}
+	}
+
+	// S3-only tiers need no rclone.conf.
+	sw.Spec.RemoteTiers = sw.Spec.RemoteTiers[:1]
+	for _, v := range r.createVolumeServerStatefulSet(sw).Spec.Template.Spec.Volumes {
+		if v.Name == remoteTierRcloneVolumeName {
+			t.Errorf("rclone volume mounted for s3-only tiers")
+		}
+	}
+}
+
+func TestRemoteTierCandidates(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	old := now.Add(-30 * 24 * time.Hour)
+	layout := swadmin.VolumeLayout{
+		VolumeSizeLimit: 1000,
+		Volumes: []swadmin.Volume{
+			{ID: 1, Collection: "logs", Size: 990, ModifiedAt: old},
+			{ID: 2, Collection: "logs", Size: 500, ModifiedAt: old},                     // not full
+			{ID: 3, Collection: "logs", Size: 990, ModifiedAt: now.Add(-time.Hour)},     // still written
+			{ID: 4, Collection: "media", Size: 990, ModifiedAt: old},                    // not selected
+			{ID: 5, Collection: "logs", Size: 990, ModifiedAt: old, RemoteStorage: "x"}, // already remote
+			{ID: 6, Collection: "logs-b", Size: 960, ModifiedAt: old},                   // claimed by an earlier tier
+		},
+	}
+	policy := &seaweedv1.RemoteTierPolicy{Collections: []string{"logs*"}}
+	var ids []uint32
+	for _, v := range remoteTierCandidates(policy, layout, now, map[uint32]bool{6: true}) {
+		ids = append(ids, v.ID)
+	}
+	if !reflect.DeepEqual(ids, []uint32{1}) {
+		t.Errorf("candidates = %v, want [1]", ids)
+	}
+	if got := remoteTierCandidates(policy, swadmin.VolumeLayout{Volumes: layout.Volumes}, now, nil); len(got) != 0 {
+		t.Errorf("candidates without a size limit = %v", got)
+	}
+}
+
+func TestEnsureRemoteTiers(t *testing.T) {
+	sw := evacTestSeaweed()
+	sw.Status.Volume.ReadyReplicas = 1
+	sw.Spec.RemoteTiers = []seaweedv1.RemoteTierSpec{{
+		Name:    "cold",
+		Storage: seaweedv1.BackupStorageSpec{Type: seaweedv1.BackupStorageS3, S3: &seaweedv1.S3BackupStore{Bucket: "volumes"}},
+		Policy:  &seaweedv1.RemoteTierPolicy{MinAge: &metav1.Duration{Duration: 24 * time.Hour}},
+	}}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-48 * time.Hour)
+	fa := &fakeVolumeAdmin{layout: swadmin.VolumeLayout{
+		VolumeSizeLimit: 1000,
+		Volumes: []swadmin.Volume{
+			{ID: 1, Collection: "logs", Size: 980, ModifiedAt: old},
+			{ID: 2, Collection: "logs", Size: 970, ModifiedAt: old},
+			{ID: 3, Size: 990, ModifiedAt: old, RemoteStorage: "s3.cold"},
+		},
+	}}
+	r := newEvacTestReconciler(t, fa)
+	r.Now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if done, _, err := r.ensureRemoteTiers(ctx, sw); done || err != nil {
+		t.Fatalf("ensureRemoteTiers = %v, %v", done, err)
+	}
+	if want := []string{"volume.tier.upload -dest=s3.cold -collection=logs -volumeId=1"}; !reflect.DeepEqual(fa.uploaded, want) {
+		t.Errorf("uploaded = %q, want %q (one volume per pass)", fa.uploaded, want)
+	}
+	want := []seaweedv1.RemoteTierStatus{{
+		Name: "cold", OffloadedVolumes: 2, OffloadedBytes: 1970, PendingVolumes: 1,
+		LastUploadTime: &metav1.Time{Time: now},
+	}}
+	if !reflect.DeepEqual(sw.Status.RemoteTiers, want) {
+		t.Errorf("status = %+v\nwant %+v", sw.Status.RemoteTiers, want)
+	}
+	cond := meta.FindStatusCondition(sw.Status.Conditions, seaweedv1.SeaweedConditionRemoteTiersReady)
+	if cond == nil || cond.Status != metav1.ConditionTrue || cond.Reason != "Offloading" {
+		t.Errorf("condition = %+v want True/Offloading", cond)
+	}
+
+	// Within the interval nothing else is uploaded.
+	fa.layout.Volumes[0].RemoteStorage = "s3.cold"
+	now = now.Add(time.Minute)
+	if _, _, err := r.ensureRemoteTiers(ctx, sw); err != nil {
+		t.Fatalf("ensureRemoteTiers: %v", err)
+	}
+	if len(fa.uploaded) != 1 {
+		t.Errorf("uploaded early: %q", fa.uploaded)
+	}
+
+	// A failed upload is reported and stays reported until the next pass.
+	fa.uploadErr = errors.New("access denied")
+	now = now.Add(time.Hour)
+	if _, _, err := r.ensureRemoteTiers(ctx, sw); err != nil {
+		t.Fatalf("ensureRemoteTiers: %v", err)
+	}
+	now = now.Add(time.Minute)
+	if _, _, err := r.ensureRemoteTiers(ctx, sw); err != nil {
+		t.Fatalf("ensureRemoteTiers: %v", err)
+	}
+	cond = meta.FindStatusCondition(sw.Status.Conditions, seaweedv1.SeaweedConditionRemoteTiersReady)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != "UploadFailed" || !strings.Contains(cond.Message, "access denied") {
+		t.Errorf("condition = %+v want False/UploadFailed", cond)
+	}
+	if st := sw.Status.RemoteTiers[0]; st.PendingVolumes != 1 || st.OffloadedVolumes != 2 {
+		t.Errorf("status after failure = %+v", st)
+	}
+
+	// Removing the tiers clears status and condition.
+	sw.Spec.RemoteTiers = nil
+	if _, _, err := r.ensureRemoteTiers(ctx, sw); err != nil {
+		t.Fatalf("ensureRemoteTiers: %v", err)
+	}
+	if sw.Status.RemoteTiers != nil || meta.FindStatusCondition(sw.Status.Conditions, seaweedv1.SeaweedConditionRemoteTiersReady) != nil {
+		t.Errorf("status not cleared: %+v %+v", sw.Status.RemoteTiers, sw.Status.Conditions)
+	}
+}
@@ -16,9 +16,12 @@ You may obtain a copy of the License at
 //
 // User auth and SSH host keys are supplied via two optional Secrets:
 //   - UserStoreSecret → mounted at /etc/sw/seaweedfs_sftp_config, passed
-//     to weed as -userStoreFile. Omit to run in public/no-auth mode.
-//   - HostKeysSecret → mounted read-only at /etc/sw/ssh. Omit to let the
-//     server generate an ephemeral key on startup (dev only).
+//     to weed as -userStoreFile. Omit to run in public/no-auth mode, or
+//     declare SFTPUser resources: once one exists the operator generates
+//     <name>-sftp-users and mounts it the same way.
+//   - HostKeysSecret → mounted read-only at /etc/sw/ssh. Omit to have the
+//     operator generate ed25519 and RSA keys into <name>-sftp-host-keys
+//     (see controller_sftp_hostkeys.go) and mount that instead.
 package controller
 
 import (
@@ -35,7 +38,9 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
 	"github.com/seaweedfs/seaweedfs-operator/internal/controller/label"
@@ -43,8 +48,22 @@ import (
 
 const (
 	sftpComponent = "sftp"
+
+	// sftpUserStoreKey is the key of the generated user store Secret.
+	sftpUserStoreKey = "users.json"
+
+	// sftpUserStoreAnnotation carries a digest of the generated user
+	// store. weed sftp reads the store only at startup, so adding, removing
+	// or re-keying an SFTPUser has to roll the gateway.
+	sftpUserStoreAnnotation = "seaweed.seaweedfs.com/sftp-users"
 )
 
+// sftpUserStoreSecretName is the user store the SFTPUser controller
+// generates for a cluster.
+func sftpUserStoreSecretName(m *seaweedv1.Seaweed) string {
+	return m.Name + "-sftp-users"
+}
+
 func labelsForSFTP(name string) map[string]string {
 	return map[string]string{
 		label.ManagedByLabelKey: "seaweedfs-operator",
@@ -204,14 +223,71 @@ func (r *SeaweedReconciler) buildSFTPService(m *seaweedv1.Seaweed) *corev1.Servi
 }
 
 func (r *SeaweedReconciler) ensureSFTPDeployment(ctx context.Context, m *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
-	dep := r.buildSFTPDeployment(m)
+	store, err := r.generatedSFTPUserStore(ctx, m)
+	if err != nil {
+		return ReconcileResult(err)
+	}
+	hostKeys, err := r.ensureSFTPHostKeys(ctx, m)
+	if err != nil {
+		return ReconcileResult(err)
+	}
+	// Mount generated Secrets exactly like hand-written ones.
+	view := m
+	if store != nil || hostKeys != nil {
+		view = m.DeepCopy()
+	}
+	if store != nil {
+		view.Spec.SFTP.UserStoreSecret = &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: sftpUserStoreSecretName(m)},
+			Key:                  sftpUserStoreKey,
+		}
+	}
+	if hostKeys != nil {
+		view.Spec.SFTP.HostKeysSecret = &corev1.LocalObjectReference{Name: hostKeys.Name}
+	}
+	dep := r.buildSFTPDeployment(view)
+	template := &dep.Spec.Template
+	if store != nil {
+		template.Annotations = withRevisionAnnotation(template.Annotations, sftpUserStoreAnnotation, configRevision(store.Data))
+	}
+	if hostKeys != nil {
+		mountSFTPHostKeys(&template.Spec, hostKeys.Data)
+		template.Annotations = withRevisionAnnotation(template.Annotations, sftpHostKeysAnnotation, configRevision(servedSFTPHostKeyData(hostKeys.Data)))
+	}
 	if err := controllerutil.SetControllerReference(m, dep, r.Scheme); err != nil {
 		return ReconcileResult(err)
 	}
-	_, err := r.CreateOrUpdateDeployment(dep)
+	_, err = r.CreateOrUpdateDeployment(dep)
 	return ReconcileResult(err)
 }
 
+// generatedSFTPUserStore returns the user store generated from SFTPUsers, or
+// nil when the cluster brings its own (spec.sftp.userStoreSecret) or no
+// SFTPUser has referenced it yet.
+func (r *SeaweedReconciler) generatedSFTPUserStore(ctx context.Context, m *seaweedv1.Seaweed) (*corev1.Secret, error) {
+	if m.Spec.SFTP.UserStoreSecret != nil {
+		return nil, nil
+	}
+	var secret corev1.Secret
+	if err := r.Get(ctx, types.NamespacedName{Namespace: m.Namespace, Name: sftpUserStoreSecretName(m)}, &secret); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(&secret, m) {
+		return nil, nil
+	}
+	return &secret, nil
+}
+
+// mapSFTPUserStoreToSeaweed enqueues the Seaweed owning a generated user
+// store Secret, so user changes reach the gateway Deployment.
+func mapSFTPUserStoreToSeaweed(_ context.Context, obj client.Object) []reconcile.Request {
+	owner := metav1.GetControllerOf(obj)
+	if owner == nil || owner.Kind != "Seaweed" || obj.GetName() != owner.Name+"-sftp-users" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: owner.Name}}}
+}
+
 func (r *SeaweedReconciler) buildSFTPDeployment(m *seaweedv1.Seaweed) *appsv1.Deployment {
 	labels := labelsForSFTP(m.Name)
 	podLabels := mergePodLabels(labels, m.BaseSFTPSpec().Labels())
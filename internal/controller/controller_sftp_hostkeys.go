@@ -0,0 +1,409 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Operator-managed SFTP host keys.
+//
+// Without spec.sftp.hostKeysSecret the operator generates an ed25519 and an
+// RSA host key into <name>-sftp-host-keys, which the gateway mounts as its
+// -hostKeysFolder. With spec.sftp.hostKeyRotation the keys are replaced on a
+// cron schedule: the outgoing keys stay in the Secret under a ".previous"
+// suffix for the overlap window, so both generations are offered while
+// clients learn the new fingerprints. The SSH server keeps one key per
+// algorithm, so the overlap is staggered: the previous ed25519 key is served
+// next to the new RSA key until the window ends. Rotation state lives in
+// annotations on the Secret itself, so it survives operator restarts.
+package controller
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+const (
+	sftpHostKeyEd25519 = "ssh_host_ed25519_key"
+	sftpHostKeyRSA     = "ssh_host_rsa_key"
+
+	// sftpPreviousHostKeySuffix marks the outgoing keys kept during a
+	// rotation's overlap window.
+	sftpPreviousHostKeySuffix = ".previous"
+
+	sftpHostKeyRSABits = 3072
+
+	// defaultSFTPHostKeyOverlap is how long previous keys are offered when
+	// spec.sftp.hostKeyRotation.overlapWindow is unset.
+	defaultSFTPHostKeyOverlap = 7 * 24 * time.Hour
+
+	// Rotation state, recorded on the generated Secret.
+	sftpHostKeysGeneratedAnnotation      = "seaweed.seaweedfs.com/host-keys-generated-at"
+	sftpHostKeysPreviousExpiryAnnotation = "seaweed.seaweedfs.com/host-keys-previous-expire-at"
+
+	// sftpHostKeysAnnotation carries a digest of the generated host keys on
+	// the gateway's pod template. weed reads -hostKeysFolder only at
+	// startup, so a rotation has to roll the gateway.
+	sftpHostKeysAnnotation = "seaweed.seaweedfs.com/sftp-host-keys"
+)
+
+// sftpHostKeyNames are the current keys the operator keeps in the Secret.
+var sftpHostKeyNames = []string{sftpHostKeyEd25519, sftpHostKeyRSA}
+
+// sftpOverlapHostKey is the key served from the previous generation during
+// an overlap window. Clients prefer ed25519 when they know a host by it, so
+// they keep connecting while the new RSA key is served and both new
+// fingerprints are published.
+const sftpOverlapHostKey = sftpHostKeyEd25519
+
+// servedSFTPHostKeys maps each file of the gateway's -hostKeysFolder to the
+// Secret key projected into it: the current keys, except sftpOverlapHostKey
+// while a previous one is kept. weed loads every file in the folder and the
+// SSH server replaces a host key of the same algorithm, so only one key per
+// algorithm may be mounted.
+func servedSFTPHostKeys(data map[string][]byte) []corev1.KeyToPath {
+	items := make([]corev1.KeyToPath, 0, len(sftpHostKeyNames))
+	for _, name := range sftpHostKeyNames {
+		key := name
+		if name == sftpOverlapHostKey && len(data[name+sftpPreviousHostKeySuffix]) > 0 {
+			key = name + sftpPreviousHostKeySuffix
+		}
+		items = append(items, corev1.KeyToPath{Key: key, Path: name})
+	}
+	return items
+}
+
+// mountSFTPHostKeys limits the gateway's host keys volume to the served keys.
+func mountSFTPHostKeys(podSpec *corev1.PodSpec, data map[string][]byte) {
+	for i := range podSpec.Volumes {
+		vol := &podSpec.Volumes[i]
+		if vol.Name != "sftp-hostkeys" || vol.Secret == nil {
+			continue
+		}
+		vol.Secret.Items = servedSFTPHostKeys(data)
+	}
+}
+
+// servedSFTPHostKeyData returns the served keys by file name, so the gateway
+// rolls when a rotation starts and when its overlap window ends.
+func servedSFTPHostKeyData(data map[string][]byte) map[string][]byte {
+	served := make(map[string][]byte, len(sftpHostKeyNames))
+	for _, item := range servedSFTPHostKeys(data) {
+		served[item.Path] = data[item.Key]
+	}
+	return served
+}
+
+func sftpHostKeysSecretName(m *seaweedv1.Seaweed) string {
+	return m.Name + "-sftp-host-keys"
+}
+
+// sftpManagedHostKeys reports whether the operator generates the gateway's
+// host keys.
+func sftpManagedHostKeys(m *seaweedv1.Seaweed) bool {
+	return m.Spec.SFTP != nil && m.Spec.SFTP.HostKeysSecret == nil
+}
+
+func (r *SeaweedReconciler) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+// ensureSFTPHostKeys creates or rotates the generated host keys Secret and
+// returns it; nil when the gateway brings its own hostKeysSecret, in which
+// case a previously generated Secret is removed.
+func (r *SeaweedReconciler) ensureSFTPHostKeys(ctx context.Context, m *seaweedv1.Seaweed) (*corev1.Secret, error) {
+	name := sftpHostKeysSecretName(m)
+	if !sftpManagedHostKeys(m) {
+		return nil, r.pruneOwnedSecret(ctx, m, name)
+	}
+
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: m.Namespace, Name: name}, secret)
+	switch {
+	case apierrors.IsNotFound(err):
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: m.Namespace, Labels: labelsForSFTP(m.Name)},
+			Type:       corev1.SecretTypeOpaque,
+		}
+		if _, err := advanceSFTPHostKeys(secret, m.Spec.SFTP.HostKeyRotation, r.now()); err != nil {
+			return nil, err
+		}
+		if err := controllerutil.SetControllerReference(m, secret, r.Scheme); err != nil {
+			return nil, err
+		}
+		r.Log.Info("generate sftp host keys " + name)
+		return secret, r.Create(ctx, secret)
+	case err != nil:
+		return nil, err
+	}
+	if !metav1.IsControlledBy(secret, m) {
+		return nil, fmt.Errorf("secret %s/%s exists and is not managed by Seaweed %s; set spec.sftp.hostKeysSecret to use it", m.Namespace, name, m.Name)
+	}
+	changed, err := advanceSFTPHostKeys(secret, m.Spec.SFTP.HostKeyRotation, r.now())
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		r.Log.Info("rotate sftp host keys " + name)
+		if err := r.Update(ctx, secret); err != nil {
+			return nil, err
+		}
+	}
+	return secret, nil
+}
+
+// advanceSFTPHostKeys brings the host keys Secret up to date at now: it
+// drops previous keys whose overlap window has ended, generates missing
+// keys, and rotates the current ones when the schedule says they are due.
+// It reports whether the Secret changed.
+func advanceSFTPHostKeys(secret *corev1.Secret, rot *seaweedv1.SFTPHostKeyRotation, now time.Time) (bool, error) {
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	changed := false
+
+	if expiry, ok := parseAnnotationTime(secret.Annotations, sftpHostKeysPreviousExpiryAnnotation); ok && !now.Before(expiry) {
+		dropPreviousSFTPHostKeys(secret)
+		changed = true
+	}
+
+	generatedAt, ok := parseAnnotationTime(secret.Annotations, sftpHostKeysGeneratedAnnotation)
+	if !ok {
+		generatedAt = now
+		secret.Annotations[sftpHostKeysGeneratedAnnotation] = now.UTC().Format(time.RFC3339)
+		changed = true
+	}
+	for _, name := range sftpHostKeyNames {
+		if len(secret.Data[name]) > 0 {
+			continue
+		}
+		key, err := generateSFTPHostKey(name)
+		if err != nil {
+			return false, err
+		}
+		secret.Data[name] = key
+		changed = true
+	}
+
+	if rot == nil {
+		return changed, nil
+	}
+	sched, err := cron.ParseStandard(rot.Schedule)
+	if err != nil {
+		return false, fmt.Errorf("spec.sftp.hostKeyRotation.schedule: %w", err)
+	}
+	if now.Before(sched.Next(generatedAt)) {
+		return changed, nil
+	}
+
+	dropPreviousSFTPHostKeys(secret)
+	overlap := sftpHostKeyOverlap(rot)
+	for _, name := range sftpHostKeyNames {
+		if overlap > 0 {
+			secret.Data[name+sftpPreviousHostKeySuffix] = secret.Data[name]
+		}
+		key, err := generateSFTPHostKey(name)
+		if err != nil {
+			return false, err
+		}
+		secret.Data[name] = key
+	}
+	secret.Annotations[sftpHostKeysGeneratedAnnotation] = now.UTC().Format(time.RFC3339)
+	if overlap > 0 {
+		secret.Annotations[sftpHostKeysPreviousExpiryAnnotation] = now.Add(overlap).UTC().Format(time.RFC3339)
+	}
+	return true, nil
+}
+
+func dropPreviousSFTPHostKeys(secret *corev1.Secret) {
+	for _, name := range sftpHostKeyNames {
+		delete(secret.Data, name+sftpPreviousHostKeySuffix)
+	}
+	delete(secret.Annotations, sftpHostKeysPreviousExpiryAnnotation)
+}
+
+func sftpHostKeyOverlap(rot *seaweedv1.SFTPHostKeyRotation) time.Duration {
+	if rot.OverlapWindow == nil {
+		return defaultSFTPHostKeyOverlap
+	}
+	return rot.OverlapWindow.Duration
+}
+
+func parseAnnotationTime(annotations map[string]string, key string) (time.Time, bool) {
+	v, ok := annotations[key]
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// generateSFTPHostKey returns a fresh PKCS#8 PEM private key for the named
+// host key file.
+func generateSFTPHostKey(name string) ([]byte, error) {
+	var key crypto.PrivateKey
+	switch name {
+	case sftpHostKeyEd25519:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		key = priv
+	case sftpHostKeyRSA:
+		priv, err := rsa.GenerateKey(rand.Reader, sftpHostKeyRSABits)
+		if err != nil {
+			return nil, err
+		}
+		key = priv
+	default:
+		return nil, fmt.Errorf("unknown host key %q", name)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// describeSFTPHostKey parses a PEM private key and returns its public half
+// as an SSH key type, known_hosts public key, and SHA256 fingerprint.
+func describeSFTPHostKey(data []byte) (seaweedv1.SFTPHostKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return seaweedv1.SFTPHostKey{}, fmt.Errorf("not a PEM private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return seaweedv1.SFTPHostKey{}, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return seaweedv1.SFTPHostKey{}, fmt.Errorf("unsupported private key %T", key)
+	}
+	var keyType string
+	var wire []byte
+	switch pub := signer.Public().(type) {
+	case ed25519.PublicKey:
+		keyType = "ssh-ed25519"
+		wire = append(sshWireString([]byte(keyType)), sshWireString(pub)...)
+	case *rsa.PublicKey:
+		keyType = "ssh-rsa"
+		wire = sshWireString([]byte(keyType))
+		wire = append(wire, sshWireMpint(big.NewInt(int64(pub.E)))...)
+		wire = append(wire, sshWireMpint(pub.N)...)
+	default:
+		return seaweedv1.SFTPHostKey{}, fmt.Errorf("unsupported public key %T", pub)
+	}
+	sum := sha256.Sum256(wire)
+	return seaweedv1.SFTPHostKey{
+		Type:        keyType,
+		Fingerprint: "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:]),
+		PublicKey:   keyType + " " + base64.StdEncoding.EncodeToString(wire),
+	}, nil
+}
+
+// sshWireString encodes b as an SSH wire-format string (RFC 4251 §5).
+func sshWireString(b []byte) []byte {
+	out := make([]byte, 4, 4+len(b))
+	binary.BigEndian.PutUint32(out, uint32(len(b)))
+	return append(out, b...)
+}
+
+// sshWireMpint encodes a non-negative n as an SSH wire-format mpint.
+func sshWireMpint(n *big.Int) []byte {
+	b := n.Bytes()
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return sshWireString(b)
+}
+
+// sftpHostKeysStatus describes the generated host keys Secret for
+// status.sftpHostKeys; nil when the operator does not manage the keys or has
+// not generated them yet.
+func (r *SeaweedReconciler) sftpHostKeysStatus(ctx context.Context, m *seaweedv1.Seaweed) (*seaweedv1.SFTPHostKeysStatus, error) {
+	if !sftpManagedHostKeys(m) {
+		return nil, nil
+	}
+	var secret corev1.Secret
+	if err := r.Get(ctx, types.NamespacedName{Namespace: m.Namespace, Name: sftpHostKeysSecretName(m)}, &secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	status := &seaweedv1.SFTPHostKeysStatus{SecretName: secret.Name}
+	names := make([]string, 0, len(secret.Data))
+	for name := range secret.Data {
+		names = append(names, name)
+	}
+	// Current keys first, then the previous generation.
+	sort.Slice(names, func(i, j int) bool {
+		pi, pj := strings.HasSuffix(names[i], sftpPreviousHostKeySuffix), strings.HasSuffix(names[j], sftpPreviousHostKeySuffix)
+		if pi != pj {
+			return !pi
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		key, err := describeSFTPHostKey(secret.Data[name])
+		if err != nil {
+			r.Log.Info("skip unreadable sftp host key", "secret", secret.Name, "key", name, "err", err.Error())
+			continue
+		}
+		key.Previous = strings.HasSuffix(name, sftpPreviousHostKeySuffix)
+		status.Keys = append(status.Keys, key)
+	}
+	if t, ok := parseAnnotationTime(secret.Annotations, sftpHostKeysGeneratedAnnotation); ok {
+		status.GeneratedAt = &metav1.Time{Time: t}
+		if rot := m.Spec.SFTP.HostKeyRotation; rot != nil {
+			if sched, err := cron.ParseStandard(rot.Schedule); err == nil {
+				status.NextRotationTime = &metav1.Time{Time: sched.Next(t)}
+			}
+		}
+	}
+	if t, ok := parseAnnotationTime(secret.Annotations, sftpHostKeysPreviousExpiryAnnotation); ok {
+		status.PreviousKeysExpireAt = &metav1.Time{Time: t}
+	}
+	return status, nil
+}
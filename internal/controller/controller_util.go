@@ -170,6 +170,9 @@ func (r *SeaweedReconciler) CreateOrUpdateDeployment(deploy *appsv1.Deployment)
 			}
 		}
 		// pod selector of deployment is immutable, so we don't mutate the labels of pod
+		if existingDep.Spec.Template.Annotations == nil && len(desiredDep.Spec.Template.Annotations) > 0 {
+			existingDep.Spec.Template.Annotations = map[string]string{}
+		}
 		for k, v := range desiredDep.Spec.Template.Annotations {
 			existingDep.Spec.Template.Annotations[k] = v
 		}
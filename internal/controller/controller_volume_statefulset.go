@@ -272,6 +272,9 @@ func (r *SeaweedReconciler) buildFlatVolumePodSpec(m *seaweedv1.Seaweed, disks v
 		volumes = append(volumes, tlsVols...)
 		volumeMounts = append(volumeMounts, tlsMounts...)
 	}
+	rcloneVols, rcloneMounts, rcloneEnv := remoteTierRcloneVolumesAndMounts(m)
+	volumes = append(volumes, rcloneVols...)
+	volumeMounts = append(volumeMounts, rcloneMounts...)
 
 	ports := []corev1.ContainerPort{
 		{ContainerPort: seaweedv1.VolumeHTTPPort, Name: "volume-http"},
@@ -288,7 +291,7 @@ func (r *SeaweedReconciler) buildFlatVolumePodSpec(m *seaweedv1.Seaweed, disks v
 		Image:           m.BaseVolumeSpec().Image(),
 		ImagePullPolicy: m.BaseVolumeSpec().ImagePullPolicy(),
 		SecurityContext: m.BaseVolumeSpec().ContainerSecurityContext(),
-		Env:             append(m.BaseVolumeSpec().Env(), kubernetesEnvVars...),
+		Env:             append(append(m.BaseVolumeSpec().Env(), kubernetesEnvVars...), rcloneEnv...),
 		Resources:       filterContainerResources(m.Spec.Volume.ResourceRequirements),
 		Command: []string{
 			"/bin/sh",
@@ -499,12 +502,15 @@ func (r *SeaweedReconciler) createVolumeServerTopologyStatefulSet(m *seaweedv1.S
 		volumes = append(volumes, tlsVols...)
 		volumeMounts = append(volumeMounts, tlsMounts...)
 	}
+	rcloneVols, rcloneMounts, rcloneEnv := remoteTierRcloneVolumesAndMounts(m)
+	volumes = append(volumes, rcloneVols...)
+	volumeMounts = append(volumeMounts, rcloneMounts...)
 	volumePodSpec.Containers = []corev1.Container{{
 		Name:            "volume",
 		Image:           m.BaseVolumeSpec().Image(),
 		ImagePullPolicy: getImagePullPolicy(m, topologySpec),
 		SecurityContext: getContainerSecurityContext(m, topologySpec),
-		Env:             append(getEnvVars(m, topologySpec), kubernetesEnvVars...),
+		Env:             append(append(getEnvVars(m, topologySpec), kubernetesEnvVars...), rcloneEnv...),
 		Resources:       filterContainerResources(resourceRequirements),
 		Command: []string{
 			"/bin/sh",
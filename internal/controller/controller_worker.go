@@ -6,8 +6,10 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
@@ -26,7 +28,25 @@ func (r *SeaweedReconciler) ensureWorkers(ctx context.Context, seaweedCR *seawee
 		return
 	}
 
-	if done, result, err = r.ensureWorkerDeployment(ctx, seaweedCR); done {
+	if len(seaweedCR.Spec.Worker.Pools) == 0 {
+		if done, result, err = r.ensureWorkerDeployment(ctx, seaweedCR); done {
+			return
+		}
+	} else {
+		// Pools replace the default Deployment; its selector would
+		// otherwise match every pool's pods.
+		def := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: seaweedCR.Name + "-worker", Namespace: seaweedCR.Namespace}}
+		if _, err = r.deleteIfExists(ctx, def); err != nil {
+			return ReconcileResult(err)
+		}
+		for i := range seaweedCR.Spec.Worker.Pools {
+			if done, result, err = r.ensureWorkerPoolDeployment(ctx, seaweedCR, &seaweedCR.Spec.Worker.Pools[i]); done {
+				return
+			}
+		}
+	}
+
+	if done, result, err = r.pruneWorkerPools(ctx, seaweedCR); done {
 		return
 	}
 
@@ -55,6 +75,42 @@ func (r *SeaweedReconciler) ensureWorkerDeployment(ctx context.Context, seaweedC
 	return ReconcileResult(err)
 }
 
+func (r *SeaweedReconciler) ensureWorkerPoolDeployment(ctx context.Context, seaweedCR *seaweedv1.Seaweed, pool *seaweedv1.WorkerPool) (bool, ctrl.Result, error) {
+	log := r.Log.WithValues("sw-worker-deployment", seaweedCR.Name, "pool", pool.Name)
+
+	workerDeployment := r.createWorkerPoolDeployment(seaweedCR, pool)
+	if err := controllerutil.SetControllerReference(seaweedCR, workerDeployment, r.Scheme); err != nil {
+		return ReconcileResult(err)
+	}
+	_, err := r.CreateOrUpdateDeployment(workerDeployment)
+	log.Info("ensure worker deployment " + workerDeployment.Name)
+	return ReconcileResult(err)
+}
+
+// pruneWorkerPools deletes pool Deployments no longer declared in
+// spec.worker.pools. Only Deployments carrying the pool label are listed, so
+// the default Deployment is never touched here.
+func (r *SeaweedReconciler) pruneWorkerPools(ctx context.Context, seaweedCR *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+	keep := map[string]bool{}
+	for _, pool := range seaweedCR.Spec.Worker.Pools {
+		keep[workerPoolDeploymentName(seaweedCR.Name, pool.Name)] = true
+	}
+	var deps appsv1.DeploymentList
+	if err := r.List(ctx, &deps, client.InNamespace(seaweedCR.Namespace), client.MatchingLabels(labelsForWorker(seaweedCR.Name)), client.HasLabels{workerPoolLabelKey}); err != nil {
+		return ReconcileResult(err)
+	}
+	for i := range deps.Items {
+		d := &deps.Items[i]
+		if keep[d.Name] || !metav1.IsControlledBy(d, seaweedCR) {
+			continue
+		}
+		if err := r.Delete(ctx, d); err != nil && !errors.IsNotFound(err) {
+			return ReconcileResult(err)
+		}
+	}
+	return ReconcileResult(nil)
+}
+
 // cleanupLegacyWorkerStatefulSet removes the StatefulSet + headless peer
 // Service that earlier operator versions created for workers. Safe to call
 // on every reconcile: deletes are issued only when the legacy objects
@@ -123,3 +179,13 @@ func labelsForWorker(name string) map[string]string {
 		label.InstanceLabelKey:  name,
 	}
 }
+
+// workerPoolLabelKey marks the pods and Deployment of a spec.worker.pools
+// entry with the pool name.
+const workerPoolLabelKey = "seaweedfs/worker-pool"
+
+func labelsForWorkerPool(name, pool string) map[string]string {
+	labels := labelsForWorker(name)
+	labels[workerPoolLabelKey] = pool
+	return labels
+}
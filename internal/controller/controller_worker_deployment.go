@@ -16,7 +16,31 @@ func getAdminAddress(m *seaweedv1.Seaweed) string {
 	return fmt.Sprintf("%s-admin:%d", m.Name, seaweedv1.AdminHTTPPort)
 }
 
+// workerPoolDeploymentName is the Deployment running a spec.worker.pools
+// entry.
+func workerPoolDeploymentName(name, pool string) string {
+	return name + "-worker-" + pool
+}
+
 func buildWorkerStartupScript(m *seaweedv1.Seaweed, extraArgs ...string) string {
+	return buildWorkerPoolStartupScript(m, nil, extraArgs...)
+}
+
+// buildWorkerPoolStartupScript is buildWorkerStartupScript with pool's
+// overrides applied; a nil pool is the single default Deployment.
+func buildWorkerPoolStartupScript(m *seaweedv1.Seaweed, pool *seaweedv1.WorkerPool, extraArgs ...string) string {
+	jobType, maxDetect, maxExecute := m.Spec.Worker.JobType, m.Spec.Worker.MaxDetect, m.Spec.Worker.MaxExecute
+	if pool != nil {
+		if pool.JobType != nil {
+			jobType = pool.JobType
+		}
+		if pool.MaxDetect != nil {
+			maxDetect = pool.MaxDetect
+		}
+		if pool.MaxExecute != nil {
+			maxExecute = pool.MaxExecute
+		}
+	}
 	commands := weedPreamble(m, m.BaseWorkerSpec().LoggingArgs(), "worker")
 	commands = append(commands, fmt.Sprintf("-admin=%s", getAdminAddress(m)))
 	if m.Spec.Worker.Persistence != nil && m.Spec.Worker.Persistence.Enabled {
@@ -26,14 +50,14 @@ func buildWorkerStartupScript(m *seaweedv1.Seaweed, extraArgs ...string) string
 		}
 		commands = append(commands, fmt.Sprintf("-workingDir=%s", mountPath))
 	}
-	if m.Spec.Worker.JobType != nil {
-		commands = append(commands, fmt.Sprintf("-jobType=%s", *m.Spec.Worker.JobType))
+	if jobType != nil {
+		commands = append(commands, fmt.Sprintf("-jobType=%s", *jobType))
 	}
-	if m.Spec.Worker.MaxDetect != nil {
-		commands = append(commands, fmt.Sprintf("-maxDetect=%d", *m.Spec.Worker.MaxDetect))
+	if maxDetect != nil {
+		commands = append(commands, fmt.Sprintf("-maxDetect=%d", *maxDetect))
 	}
-	if m.Spec.Worker.MaxExecute != nil {
-		commands = append(commands, fmt.Sprintf("-maxExecute=%d", *m.Spec.Worker.MaxExecute))
+	if maxExecute != nil {
+		commands = append(commands, fmt.Sprintf("-maxExecute=%d", *maxExecute))
 	}
 	if m.Spec.Worker.MetricsPort != nil {
 		commands = append(commands, fmt.Sprintf("-metricsPort=%d", *m.Spec.Worker.MetricsPort))
@@ -44,7 +68,20 @@ func buildWorkerStartupScript(m *seaweedv1.Seaweed, extraArgs ...string) string
 }
 
 func (r *SeaweedReconciler) createWorkerDeployment(m *seaweedv1.Seaweed) *appsv1.Deployment {
+	return r.createWorkerPoolDeployment(m, nil)
+}
+
+// createWorkerPoolDeployment builds the Deployment for a spec.worker.pools
+// entry, or the single default Deployment when pool is nil.
+func (r *SeaweedReconciler) createWorkerPoolDeployment(m *seaweedv1.Seaweed, pool *seaweedv1.WorkerPool) *appsv1.Deployment {
+	name := m.Name + "-worker"
 	labels := labelsForWorker(m.Name)
+	var deploymentLabels map[string]string
+	if pool != nil {
+		name = workerPoolDeploymentName(m.Name, pool.Name)
+		labels = labelsForWorkerPool(m.Name, pool.Name)
+		deploymentLabels = labels
+	}
 	podLabels := mergePodLabels(labels, m.BaseWorkerSpec().Labels())
 	annotations := withJWTSigningAnnotation(m, m.BaseWorkerSpec().Annotations())
 	var ports []corev1.ContainerPort
@@ -55,9 +92,31 @@ func (r *SeaweedReconciler) createWorkerDeployment(m *seaweedv1.Seaweed) *appsv1
 		})
 	}
 	replicas := m.Spec.Worker.Replicas
+	resources := m.Spec.Worker.ResourceRequirements
 	enableServiceLinks := false
 
 	workerPodSpec := m.BaseWorkerSpec().BuildPodSpec()
+	if pool != nil {
+		if pool.Replicas != nil {
+			replicas = *pool.Replicas
+		}
+		if pool.Resources != nil {
+			resources = *pool.Resources
+		}
+		if len(pool.NodeSelector) > 0 {
+			nodeSelector := map[string]string{}
+			for k, v := range workerPodSpec.NodeSelector {
+				nodeSelector[k] = v
+			}
+			for k, v := range pool.NodeSelector {
+				nodeSelector[k] = v
+			}
+			workerPodSpec.NodeSelector = nodeSelector
+		}
+		if len(pool.Tolerations) > 0 {
+			workerPodSpec.Tolerations = append(append([]corev1.Toleration(nil), workerPodSpec.Tolerations...), pool.Tolerations...)
+		}
+	}
 
 	var volumeMounts []corev1.VolumeMount
 	if m.Spec.Worker.Persistence != nil && m.Spec.Worker.Persistence.Enabled {
@@ -110,12 +169,12 @@ func (r *SeaweedReconciler) createWorkerDeployment(m *seaweedv1.Seaweed) *appsv1
 		ImagePullPolicy: m.BaseWorkerSpec().ImagePullPolicy(),
 		SecurityContext: m.BaseWorkerSpec().ContainerSecurityContext(),
 		Env:             append(m.BaseWorkerSpec().Env(), kubernetesEnvVars...),
-		Resources:       filterContainerResources(m.Spec.Worker.ResourceRequirements),
+		Resources:       filterContainerResources(resources),
 		VolumeMounts:    mergeVolumeMounts(volumeMounts, m.BaseWorkerSpec().VolumeMounts()),
 		Command: []string{
 			"/bin/sh",
 			"-ec",
-			buildWorkerStartupScript(m, m.BaseWorkerSpec().ExtraArgs()...),
+			buildWorkerPoolStartupScript(m, pool, m.BaseWorkerSpec().ExtraArgs()...),
 		},
 		Ports: ports,
 	}
@@ -162,8 +221,9 @@ func (r *SeaweedReconciler) createWorkerDeployment(m *seaweedv1.Seaweed) *appsv1
 
 	return &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      m.Name + "-worker",
+			Name:      name,
 			Namespace: m.Namespace,
+			Labels:    deploymentLabels,
 		},
 		Spec: appsv1.DeploymentSpec{
 			Replicas: &replicas,
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+func newTestSeaweedWithWorkerPools() *seaweedv1.Seaweed {
+	jobType := "all"
+	sw := newTestSeaweed()
+	sw.UID = "sw-uid"
+	sw.Spec.Admin = &seaweedv1.AdminSpec{}
+	sw.Spec.Worker = &seaweedv1.WorkerSpec{
+		Replicas:  2,
+		JobType:   &jobType,
+		MaxDetect: int32Ptr(1),
+		ResourceRequirements: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+		},
+	}
+	sw.Spec.Worker.NodeSelector = map[string]string{"pool": "general"}
+	heavy := "erasure_coding"
+	sw.Spec.Worker.Pools = []seaweedv1.WorkerPool{
+		{
+			Name:       "ec",
+			Replicas:   int32Ptr(1),
+			JobType:    &heavy,
+			MaxExecute: int32Ptr(4),
+			Resources: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")},
+			},
+			NodeSelector: map[string]string{"pool": "big", "disk": "ssd"},
+			Tolerations:  []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}},
+		},
+		{Name: "light"},
+	}
+	return sw
+}
+
+func TestCreateWorkerPoolDeployment(t *testing.T) {
+	sw := newTestSeaweedWithWorkerPools()
+	r := &SeaweedReconciler{}
+
+	ec := r.createWorkerPoolDeployment(sw, &sw.Spec.Worker.Pools[0])
+	if ec.Name != "prod-worker-ec" {
+		t.Errorf("name = %q", ec.Name)
+	}
+	if *ec.Spec.Replicas != 1 {
+		t.Errorf("replicas = %d want 1", *ec.Spec.Replicas)
+	}
+	if ec.Spec.Selector.MatchLabels[workerPoolLabelKey] != "ec" || ec.Labels[workerPoolLabelKey] != "ec" ||
+		ec.Spec.Template.Labels[workerPoolLabelKey] != "ec" {
+		t.Errorf("pool label missing: selector=%v labels=%v", ec.Spec.Selector.MatchLabels, ec.Labels)
+	}
+	script := ec.Spec.Template.Spec.Containers[0].Command[2]
+	for _, want := range []string{"-jobType=erasure_coding", "-maxDetect=1", "-maxExecute=4"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script %q missing %q", script, want)
+		}
+	}
+	if cpu := ec.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU]; cpu.String() != "4" {
+		t.Errorf("cpu request = %s want 4", cpu.String())
+	}
+	if ns := ec.Spec.Template.Spec.NodeSelector; ns["pool"] != "big" || ns["disk"] != "ssd" {
+		t.Errorf("nodeSelector = %v", ns)
+	}
+	if tols := ec.Spec.Template.Spec.Tolerations; len(tols) != 1 || tols[0].Key != "dedicated" {
+		t.Errorf("tolerations = %v", tols)
+	}
+
+	// A pool that sets nothing inherits spec.worker.
+	light := r.createWorkerPoolDeployment(sw, &sw.Spec.Worker.Pools[1])
+	if *light.Spec.Replicas != 2 {
+		t.Errorf("inherited replicas = %d want 2", *light.Spec.Replicas)
+	}
+	if script := light.Spec.Template.Spec.Containers[0].Command[2]; !strings.Contains(script, "-jobType=all") || strings.Contains(script, "-maxExecute") {
+		t.Errorf("inherited script = %q", script)
+	}
+	if cpu := light.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU]; cpu.String() != "100m" {
+		t.Errorf("inherited cpu request = %s want 100m", cpu.String())
+	}
+	if ns := light.Spec.Template.Spec.NodeSelector; ns["pool"] != "general" {
+		t.Errorf("inherited nodeSelector = %v", ns)
+	}
+
+	// The default Deployment keeps its unlabelled selector.
+	def := r.createWorkerDeployment(sw)
+	if def.Name != "prod-worker" || def.Labels != nil {
+		t.Errorf("default deployment = %s labels %v", def.Name, def.Labels)
+	}
+	if _, ok := def.Spec.Selector.MatchLabels[workerPoolLabelKey]; ok {
+		t.Errorf("default selector carries the pool label")
+	}
+}
+
+func TestEnsureWorkersPools(t *testing.T) {
+	sw := newTestSeaweedWithWorkerPools()
+	legacy := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "prod-worker", Namespace: sw.Namespace}}
+	r, cli := newNotificationReconciler(t, sw, legacy)
+	ctx := context.Background()
+
+	if _, _, err := r.ensureWorkers(ctx, sw); err != nil {
+		t.Fatalf("ensureWorkers: %v", err)
+	}
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: sw.Namespace, Name: "prod-worker"}, &appsv1.Deployment{}); !apierrors.IsNotFound(err) {
+		t.Errorf("default deployment should be removed once pools are set, got %v", err)
+	}
+	for _, name := range []string{"prod-worker-ec", "prod-worker-light"} {
+		if err := cli.Get(ctx, types.NamespacedName{Namespace: sw.Namespace, Name: name}, &appsv1.Deployment{}); err != nil {
+			t.Errorf("get %s: %v", name, err)
+		}
+	}
+
+	// Dropping a pool prunes its Deployment.
+	sw.Spec.Worker.Pools = sw.Spec.Worker.Pools[:1]
+	if _, _, err := r.ensureWorkers(ctx, sw); err != nil {
+		t.Fatalf("ensureWorkers after removal: %v", err)
+	}
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: sw.Namespace, Name: "prod-worker-light"}, &appsv1.Deployment{}); !apierrors.IsNotFound(err) {
+		t.Errorf("removed pool not pruned: %v", err)
+	}
+
+	status, err := r.getComponentStatus(ctx, sw, ComponentWorker)
+	if err != nil || status.Replicas != 1 {
+		t.Errorf("worker status = %+v, %v; want 1 desired replica", status, err)
+	}
+
+	// Dropping every pool brings the default Deployment back.
+	sw.Spec.Worker.Pools = nil
+	if _, _, err := r.ensureWorkers(ctx, sw); err != nil {
+		t.Fatalf("ensureWorkers without pools: %v", err)
+	}
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: sw.Namespace, Name: "prod-worker"}, &appsv1.Deployment{}); err != nil {
+		t.Errorf("default deployment not recreated: %v", err)
+	}
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: sw.Namespace, Name: "prod-worker-ec"}, &appsv1.Deployment{}); !apierrors.IsNotFound(err) {
+		t.Errorf("pool deployment not pruned: %v", err)
+	}
+}
@@ -0,0 +1,293 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IcebergCatalogClient is the subset of the Iceberg REST catalog API the
+// IcebergCatalog controller drives. Namespaces are passed as their levels.
+type IcebergCatalogClient interface {
+	// Prefix returns the path prefix the catalog serves warehouse under,
+	// taken from GET /v1/config. Empty means no prefix.
+	Prefix(ctx context.Context, warehouse string) (string, error)
+	// LoadNamespace returns a namespace's properties, or found=false when
+	// it does not exist.
+	LoadNamespace(ctx context.Context, prefix string, namespace []string) (properties map[string]string, found bool, err error)
+	// CreateNamespace creates a namespace. An existing namespace is not an
+	// error.
+	CreateNamespace(ctx context.Context, prefix string, namespace []string, properties map[string]string) error
+	// UpdateNamespaceProperties sets properties on an existing namespace.
+	UpdateNamespaceProperties(ctx context.Context, prefix string, namespace []string, updates map[string]string) error
+}
+
+// IcebergCredentials are the S3 keys catalog requests are signed with.
+type IcebergCredentials struct {
+	AccessKey string
+	SecretKey string
+	Region    string
+}
+
+// IcebergCatalogClientFactory builds a client for the catalog at endpoint.
+// A nil creds sends unsigned requests.
+type IcebergCatalogClientFactory func(endpoint string, creds *IcebergCredentials) IcebergCatalogClient
+
+// icebergRequestTimeout bounds a single catalog request.
+const icebergRequestTimeout = 30 * time.Second
+
+// NewRESTIcebergCatalogClient returns a client speaking the Iceberg REST
+// catalog protocol over HTTP, SigV4-signed for the S3 service when creds are
+// given.
+func NewRESTIcebergCatalogClient(endpoint string, creds *IcebergCredentials) IcebergCatalogClient {
+	return &restIcebergCatalogClient{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		creds:    creds,
+		http:     &http.Client{Timeout: icebergRequestTimeout},
+	}
+}
+
+type restIcebergCatalogClient struct {
+	endpoint string
+	creds    *IcebergCredentials
+	http     *http.Client
+}
+
+// icebergError is the error body of the REST catalog protocol.
+type icebergError struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    int    `json:"code"`
+	} `json:"error"`
+}
+
+func (c *restIcebergCatalogClient) Prefix(ctx context.Context, warehouse string) (string, error) {
+	var config struct {
+		Defaults  map[string]string `json:"defaults"`
+		Overrides map[string]string `json:"overrides"`
+	}
+	query := url.Values{"warehouse": {warehouse}}
+	if _, err := c.do(ctx, http.MethodGet, "/v1/config", query, nil, &config); err != nil {
+		return "", err
+	}
+	if p, ok := config.Overrides["prefix"]; ok {
+		return p, nil
+	}
+	return config.Defaults["prefix"], nil
+}
+
+func (c *restIcebergCatalogClient) LoadNamespace(ctx context.Context, prefix string, namespace []string) (map[string]string, bool, error) {
+	var resp struct {
+		Properties map[string]string `json:"properties"`
+	}
+	status, err := c.do(ctx, http.MethodGet, icebergNamespacePath(prefix, namespace), nil, nil, &resp)
+	if status == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return resp.Properties, true, nil
+}
+
+func (c *restIcebergCatalogClient) CreateNamespace(ctx context.Context, prefix string, namespace []string, properties map[string]string) error {
+	body := map[string]interface{}{"namespace": namespace}
+	if len(properties) > 0 {
+		body["properties"] = properties
+	}
+	status, err := c.do(ctx, http.MethodPost, icebergNamespacesPath(prefix), nil, body, nil)
+	if status == http.StatusConflict {
+		return nil
+	}
+	return err
+}
+
+func (c *restIcebergCatalogClient) UpdateNamespaceProperties(ctx context.Context, prefix string, namespace []string, updates map[string]string) error {
+	body := map[string]interface{}{"removals": []string{}, "updates": updates}
+	_, err := c.do(ctx, http.MethodPost, icebergNamespacePath(prefix, namespace)+"/properties", nil, body, nil)
+	return err
+}
+
+// do sends one request and decodes a 2xx JSON response into out. It returns
+// the HTTP status alongside any error so callers can treat 404/409 as
+// results rather than failures.
+func (c *restIcebergCatalogClient) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) (int, error) {
+	var payload []byte
+	if in != nil {
+		var err error
+		if payload, err = json.Marshal(in); err != nil {
+			return 0, err
+		}
+	}
+	u := c.endpoint + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.creds != nil {
+		signSigV4(req, payload, c.creds, "s3", time.Now())
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if resp.StatusCode/100 != 2 {
+		var e icebergError
+		if json.Unmarshal(data, &e) == nil && e.Error.Message != "" {
+			return resp.StatusCode, fmt.Errorf("%s %s: %s: %s", method, path, e.Error.Type, e.Error.Message)
+		}
+		return resp.StatusCode, fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("%s %s: decode response: %w", method, path, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+func icebergNamespacesPath(prefix string) string {
+	if prefix == "" {
+		return "/v1/namespaces"
+	}
+	return "/v1/" + url.PathEscape(prefix) + "/namespaces"
+}
+
+// icebergNamespacePath addresses a namespace; multi-level names are joined
+// with the unit separator, as the REST spec requires.
+func icebergNamespacePath(prefix string, namespace []string) string {
+	return icebergNamespacesPath(prefix) + "/" + url.PathEscape(strings.Join(namespace, "\x1f"))
+}
+
+// sigV4UnsignedHeaders are left out of the signature: proxies and the HTTP
+// client may rewrite them.
+var sigV4UnsignedHeaders = map[string]bool{
+	"authorization":   true,
+	"user-agent":      true,
+	"content-length":  true,
+	"accept-encoding": true,
+}
+
+// signSigV4 adds AWS Signature Version 4 headers to req for payload. It signs
+// the host and every header already on the request.
+func signSigV4(req *http.Request, payload []byte, creds *IcebergCredentials, service string, now time.Time) {
+	region := creds.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	amzDate := now.UTC().Format("20060102T150405Z")
+	day := amzDate[:8]
+	payloadHash := sha256Hex(payload)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		name := strings.ToLower(k)
+		if sigV4UnsignedHeaders[name] {
+			continue
+		}
+		headers[name] = strings.Join(v, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		sigV4CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKey, scope, signedHeaders, signature))
+}
+
+func sigV4CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, sigV4Escape(k)+"="+sigV4Escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// sigV4Escape percent-encodes everything but the RFC 3986 unreserved set.
+func sigV4Escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
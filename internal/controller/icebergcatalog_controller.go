@@ -0,0 +1,388 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// IcebergCatalogFinalizer keeps the CR around until its Service and Ingress,
+// which may live in another namespace and so cannot be garbage-collected
+// through an owner reference, have been removed.
+const IcebergCatalogFinalizer = "seaweed.seaweedfs.com/icebergcatalog-protection"
+
+// IcebergCatalogReconciler exposes a cluster's Iceberg REST catalog through
+// a dedicated Service and optional Ingress, creates the declared namespaces
+// through the REST API, and reports the endpoint and warehouse for jobs to
+// consume.
+type IcebergCatalogReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	// ClientFactory builds the REST client for a catalog endpoint. Tests
+	// inject a fake; production wires NewRESTIcebergCatalogClient.
+	ClientFactory IcebergCatalogClientFactory
+
+	// ResyncInterval is how often a Ready catalog re-checks its namespaces,
+	// recreating any dropped out of band. Zero disables the requeue.
+	ResyncInterval time.Duration
+}
+
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=icebergcatalogs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=icebergcatalogs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=icebergcatalogs/finalizers,verbs=update
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=buckets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=s3credentials,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile implements the Iceberg catalog reconciliation logic.
+func (r *IcebergCatalogReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := r.Log.WithValues("icebergcatalog", req.NamespacedName)
+
+	var ic seaweedv1.IcebergCatalog
+	if err := r.Get(ctx, req.NamespacedName, &ic); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !ic.DeletionTimestamp.IsZero() {
+		return r.handleDeletion(ctx, &ic, log)
+	}
+
+	if !controllerutil.ContainsFinalizer(&ic, IcebergCatalogFinalizer) {
+		controllerutil.AddFinalizer(&ic, IcebergCatalogFinalizer)
+		if err := r.Update(ctx, &ic); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	base := ic.Status.DeepCopy()
+	defer func() {
+		if err != nil || reflect.DeepEqual(*base, ic.Status) {
+			return
+		}
+		if uerr := r.Status().Update(ctx, &ic); uerr != nil {
+			result, err = ctrl.Result{}, uerr
+		}
+	}()
+
+	bucket, cluster, reason, message, err := resolveProvisionedBucket(ctx, r.Client, ic.Namespace, ic.Spec.WarehouseRef.Name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if reason != "" {
+		return r.pending(&ic, reason, message), nil
+	}
+	if cluster.Spec.Filer == nil || cluster.Spec.Filer.Iceberg == nil || !cluster.Spec.Filer.Iceberg.Enabled {
+		return r.pending(&ic, "IcebergDisabled",
+			fmt.Sprintf("Seaweed %s/%s does not enable spec.filer.iceberg", cluster.Namespace, cluster.Name)), nil
+	}
+	if ic.Status.ServiceNamespace != "" && ic.Status.ServiceNamespace != cluster.Namespace {
+		// The warehouse is immutable, but its Bucket can be recreated
+		// against another cluster; drop the objects left next to the old one.
+		if err := r.deleteExposure(ctx, &ic, ic.Status.ServiceNamespace); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	// The Service and Ingress live next to the cluster, so a cluster in
+	// another namespace has to grant the reference.
+	if cluster.Namespace != ic.Namespace {
+		ref := seaweedv1.SeaweedReference{Name: cluster.Name, Namespace: cluster.Namespace}
+		permitted, err := seaweedRefPermitted(ctx, r.Client, ref, kindIcebergCatalog, ic.Namespace)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !permitted {
+			// A revoked grant removes what was exposed while it held.
+			if err := r.deleteExposure(ctx, &ic, cluster.Namespace); err != nil {
+				return ctrl.Result{}, err
+			}
+			clearIcebergCatalogEndpoint(&ic)
+			msg := seaweedRefDeniedMessage(ref, kindIcebergCatalog, ic.Namespace)
+			r.setCondition(&ic, seaweedv1.IcebergCatalogConditionReferenceGranted, metav1.ConditionFalse, "ReferenceGrantMissing", msg)
+			return r.pending(&ic, "ReferenceGrantMissing", msg), nil
+		}
+		r.setCondition(&ic, seaweedv1.IcebergCatalogConditionReferenceGranted, metav1.ConditionTrue, "Granted",
+			fmt.Sprintf("a ResourceReferenceGrant in namespace %q permits the reference to cluster %q", cluster.Namespace, cluster.Name))
+	} else {
+		meta.RemoveStatusCondition(&ic.Status.Conditions, seaweedv1.IcebergCatalogConditionReferenceGranted)
+	}
+
+	creds, authStatus, reason, message, err := r.resolveCredentials(ctx, &ic)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if reason != "" {
+		return r.pending(&ic, reason, message), nil
+	}
+	ic.Status.Auth = authStatus
+	r.setCondition(&ic, seaweedv1.IcebergCatalogConditionResolved, metav1.ConditionTrue, "Resolved", "")
+
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: icebergCatalogServiceName(&ic), Namespace: cluster.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, svc, func() error {
+		buildIcebergCatalogService(cluster, &ic, svc)
+		return nil
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+	ing := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: icebergCatalogIngressName(&ic), Namespace: cluster.Namespace}}
+	if spec := ic.Spec.Ingress; spec != nil && spec.Enabled {
+		if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, ing, func() error {
+			buildIcebergCatalogIngress(cluster, svc, spec, ing)
+			return nil
+		}); err != nil {
+			return ctrl.Result{}, err
+		}
+	} else if err := r.Delete(ctx, ing); err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	ic.Status.ObservedGeneration = ic.Generation
+	ic.Status.ServiceName = svc.Name
+	ic.Status.ServiceNamespace = svc.Namespace
+	ic.Status.Endpoint = icebergCatalogEndpoint(svc)
+	ic.Status.ExternalEndpoint = icebergCatalogExternalEndpoint(ic.Spec.Ingress)
+	ic.Status.Warehouse = icebergCatalogWarehouse(bucket.Status.BucketName)
+
+	namespaces, err := r.ensureNamespaces(ctx, &ic, creds)
+	ic.Status.Namespaces = namespaces
+	if err != nil {
+		log.Info("catalog not reachable", "err", err.Error())
+		ic.Status.Phase = seaweedv1.BucketPhasePending
+		r.setCondition(&ic, seaweedv1.IcebergCatalogConditionReady, metav1.ConditionFalse, "CatalogUnavailable", err.Error())
+		return ctrl.Result{RequeueAfter: requeueAfterTransient}, nil
+	}
+
+	ic.Status.Phase = seaweedv1.BucketPhaseReady
+	r.setCondition(&ic, seaweedv1.IcebergCatalogConditionReady, metav1.ConditionTrue, "Serving", "")
+	return ctrl.Result{RequeueAfter: r.ResyncInterval}, nil
+}
+
+// resolveCredentials reads the access key pair of spec.auth.credentialsRef.
+// A non-empty reason means the credentials are not usable yet.
+func (r *IcebergCatalogReconciler) resolveCredentials(ctx context.Context, ic *seaweedv1.IcebergCatalog) (*IcebergCredentials, *seaweedv1.IcebergCatalogAuthStatus, string, string, error) {
+	auth := ic.Spec.Auth
+	if auth == nil {
+		return nil, nil, "", "", nil
+	}
+	var cred seaweedv1.S3Credentials
+	if err := r.Get(ctx, types.NamespacedName{Namespace: ic.Namespace, Name: auth.CredentialsRef.Name}, &cred); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil, "CredentialsNotFound", fmt.Sprintf("S3Credentials %q not found in namespace %q", auth.CredentialsRef.Name, ic.Namespace), nil
+		}
+		return nil, nil, "", "", err
+	}
+	if cred.Status.Phase != seaweedv1.S3PhaseReady || cred.Status.SecretName == "" {
+		return nil, nil, "CredentialsNotReady", fmt.Sprintf("S3Credentials %q is not ready", cred.Name), nil
+	}
+	secretNamespace := cred.Spec.SecretRef.Namespace
+	if secretNamespace == "" {
+		secretNamespace = cred.Namespace
+	}
+	var secret corev1.Secret
+	if err := r.Get(ctx, types.NamespacedName{Namespace: secretNamespace, Name: cred.Status.SecretName}, &secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil, "CredentialsNotReady", fmt.Sprintf("Secret %s/%s not found", secretNamespace, cred.Status.SecretName), nil
+		}
+		return nil, nil, "", "", err
+	}
+	akField, skField := credentialFields(&cred)
+	ak, sk := string(secret.Data[akField]), string(secret.Data[skField])
+	if ak == "" || sk == "" {
+		return nil, nil, "CredentialsNotReady", fmt.Sprintf("Secret %s/%s has no %q/%q keys", secretNamespace, secret.Name, akField, skField), nil
+	}
+	return &IcebergCredentials{AccessKey: ak, SecretKey: sk, Region: auth.SigningRegion},
+		&seaweedv1.IcebergCatalogAuthStatus{
+			IdentityName:    cred.Status.IdentityName,
+			SecretName:      secret.Name,
+			SecretNamespace: secret.Namespace,
+		}, "", "", nil
+}
+
+// ensureNamespaces creates the declared namespaces that are missing and
+// brings their declared properties in line, returning those that exist.
+func (r *IcebergCatalogReconciler) ensureNamespaces(ctx context.Context, ic *seaweedv1.IcebergCatalog, creds *IcebergCredentials) ([]string, error) {
+	if len(ic.Spec.Namespaces) == 0 {
+		return nil, nil
+	}
+	catalog := r.ClientFactory(ic.Status.Endpoint, creds)
+	prefix, err := catalog.Prefix(ctx, ic.Status.Warehouse)
+	if err != nil {
+		return nil, err
+	}
+	var ready []string
+	for _, ns := range ic.Spec.Namespaces {
+		levels := strings.Split(ns.Name, ".")
+		props, found, err := catalog.LoadNamespace(ctx, prefix, levels)
+		if err != nil {
+			return ready, fmt.Errorf("namespace %s: %w", ns.Name, err)
+		}
+		if !found {
+			r.Log.Info("create iceberg namespace", "catalog", ic.Name, "namespace", ns.Name)
+			if err := catalog.CreateNamespace(ctx, prefix, levels, ns.Properties); err != nil {
+				return ready, fmt.Errorf("create namespace %s: %w", ns.Name, err)
+			}
+		} else if updates := icebergPropertyUpdates(props, ns.Properties); len(updates) > 0 {
+			if err := catalog.UpdateNamespaceProperties(ctx, prefix, levels, updates); err != nil {
+				return ready, fmt.Errorf("update namespace %s: %w", ns.Name, err)
+			}
+		}
+		ready = append(ready, ns.Name)
+	}
+	return ready, nil
+}
+
+// icebergPropertyUpdates returns the declared properties whose value differs
+// from the namespace's current ones.
+func icebergPropertyUpdates(current, declared map[string]string) map[string]string {
+	updates := map[string]string{}
+	for k, v := range declared {
+		if cur, ok := current[k]; !ok || cur != v {
+			updates[k] = v
+		}
+	}
+	return updates
+}
+
+// deleteExposure removes the catalog's Service and Ingress from namespace.
+func (r *IcebergCatalogReconciler) deleteExposure(ctx context.Context, ic *seaweedv1.IcebergCatalog, namespace string) error {
+	for _, obj := range []client.Object{
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: icebergCatalogServiceName(ic)}},
+		&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: icebergCatalogIngressName(ic)}},
+	} {
+		if err := r.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// clearIcebergCatalogEndpoint drops the endpoint fields of a catalog whose
+// Service and Ingress were removed.
+func clearIcebergCatalogEndpoint(ic *seaweedv1.IcebergCatalog) {
+	ic.Status.ServiceName = ""
+	ic.Status.ServiceNamespace = ""
+	ic.Status.Endpoint = ""
+	ic.Status.ExternalEndpoint = ""
+}
+
+// handleDeletion removes the catalog Service and Ingress, then drops the
+// finalizer. Catalog namespaces, and the tables in them, are kept.
+func (r *IcebergCatalogReconciler) handleDeletion(ctx context.Context, ic *seaweedv1.IcebergCatalog, log logr.Logger) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(ic, IcebergCatalogFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	if ns := ic.Status.ServiceNamespace; ns != "" {
+		if err := r.deleteExposure(ctx, ic, ns); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	log.Info("removed iceberg catalog", "service", ic.Status.ServiceName)
+	controllerutil.RemoveFinalizer(ic, IcebergCatalogFinalizer)
+	if err := r.Update(ctx, ic); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// pending records a Pending phase with the dependency that is missing and
+// requeues on the transient cadence.
+func (r *IcebergCatalogReconciler) pending(ic *seaweedv1.IcebergCatalog, reason, message string) ctrl.Result {
+	ic.Status.Phase = seaweedv1.BucketPhasePending
+	r.setCondition(ic, seaweedv1.IcebergCatalogConditionResolved, metav1.ConditionFalse, reason, message)
+	r.setCondition(ic, seaweedv1.IcebergCatalogConditionReady, metav1.ConditionFalse, reason, message)
+	return ctrl.Result{RequeueAfter: requeueAfterTransient}
+}
+
+func (r *IcebergCatalogReconciler) setCondition(ic *seaweedv1.IcebergCatalog, condType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&ic.Status.Conditions, metav1.Condition{
+		Type:               condType,
+		Status:             status,
+		ObservedGeneration: ic.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// mapBucketToCatalogs enqueues the catalogs using a Bucket as warehouse.
+func (r *IcebergCatalogReconciler) mapBucketToCatalogs(ctx context.Context, obj client.Object) []reconcile.Request {
+	var list seaweedv1.IcebergCatalogList
+	if err := r.List(ctx, &list, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+	var reqs []reconcile.Request
+	for i := range list.Items {
+		if list.Items[i].Spec.WarehouseRef.Name == obj.GetName() {
+			reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+		}
+	}
+	return reqs
+}
+
+// mapCredentialsToCatalogs enqueues the catalogs authenticating with an
+// S3Credentials.
+func (r *IcebergCatalogReconciler) mapCredentialsToCatalogs(ctx context.Context, obj client.Object) []reconcile.Request {
+	var list seaweedv1.IcebergCatalogList
+	if err := r.List(ctx, &list, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+	var reqs []reconcile.Request
+	for i := range list.Items {
+		if auth := list.Items[i].Spec.Auth; auth != nil && auth.CredentialsRef.Name == obj.GetName() {
+			reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&list.Items[i])})
+		}
+	}
+	return reqs
+}
+
+// SetupWithManager wires the reconciler into the controller-runtime manager.
+func (r *IcebergCatalogReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.ClientFactory == nil {
+		r.ClientFactory = NewRESTIcebergCatalogClient
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&seaweedv1.IcebergCatalog{}, builder.WithPredicates(specOrDeletionChanged)).
+		// Provisioning and credential readiness surface on status, so
+		// both watches pass every update.
+		Watches(&seaweedv1.Bucket{}, handler.EnqueueRequestsFromMapFunc(r.mapBucketToCatalogs)).
+		Watches(&seaweedv1.S3Credentials{}, handler.EnqueueRequestsFromMapFunc(r.mapCredentialsToCatalogs)).
+		Complete(r)
+}
@@ -0,0 +1,400 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+var icebergCatalogKey = types.NamespacedName{Namespace: "analytics", Name: "lakehouse"}
+
+// fakeIcebergCatalog is an in-memory catalog keyed by prefix and
+// dot-joined namespace.
+type fakeIcebergCatalog struct {
+	endpoint   string
+	creds      *IcebergCredentials
+	namespaces map[string]map[string]string
+	calls      []string
+	err        error
+}
+
+func (f *fakeIcebergCatalog) Prefix(_ context.Context, warehouse string) (string, error) {
+	f.calls = append(f.calls, "Prefix:"+warehouse)
+	if f.err != nil {
+		return "", f.err
+	}
+	return strings.TrimPrefix(warehouse, "s3://"), nil
+}
+
+func (f *fakeIcebergCatalog) LoadNamespace(_ context.Context, prefix string, ns []string) (map[string]string, bool, error) {
+	props, ok := f.namespaces[prefix+"/"+strings.Join(ns, ".")]
+	return props, ok, nil
+}
+
+func (f *fakeIcebergCatalog) CreateNamespace(_ context.Context, prefix string, ns []string, props map[string]string) error {
+	key := prefix + "/" + strings.Join(ns, ".")
+	f.calls = append(f.calls, "CreateNamespace:"+key)
+	stored := map[string]string{}
+	for k, v := range props {
+		stored[k] = v
+	}
+	f.namespaces[key] = stored
+	return nil
+}
+
+func (f *fakeIcebergCatalog) UpdateNamespaceProperties(_ context.Context, prefix string, ns []string, updates map[string]string) error {
+	key := prefix + "/" + strings.Join(ns, ".")
+	f.calls = append(f.calls, "UpdateNamespaceProperties:"+key)
+	for k, v := range updates {
+		f.namespaces[key][k] = v
+	}
+	return nil
+}
+
+func testIcebergCatalogReconciler(t *testing.T, fc *fakeIcebergCatalog, objs ...client.Object) (*IcebergCatalogReconciler, client.Client) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("clientgoscheme: %v", err)
+	}
+	if err := seaweedv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("seaweedv1: %v", err)
+	}
+	cli := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&seaweedv1.IcebergCatalog{}).
+		Build()
+	r := &IcebergCatalogReconciler{
+		Client: cli,
+		Log:    logf.FromContext(context.Background()),
+		Scheme: scheme,
+		ClientFactory: func(endpoint string, creds *IcebergCredentials) IcebergCatalogClient {
+			fc.endpoint, fc.creds = endpoint, creds
+			return fc
+		},
+	}
+	return r, cli
+}
+
+func newIcebergCluster(enabled bool) *seaweedv1.Seaweed {
+	return &seaweedv1.Seaweed{
+		ObjectMeta: metav1.ObjectMeta{Name: "lake", Namespace: "storage"},
+		Spec: seaweedv1.SeaweedSpec{
+			Master: &seaweedv1.MasterSpec{Replicas: 1},
+			Filer:  &seaweedv1.FilerSpec{Replicas: 1, Iceberg: &seaweedv1.IcebergConfig{Enabled: enabled}},
+		},
+	}
+}
+
+func newIcebergWarehouse() *seaweedv1.Bucket {
+	return &seaweedv1.Bucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "warehouse", Namespace: "analytics"},
+		Spec:       seaweedv1.BucketSpec{ClusterRef: seaweedv1.BucketClusterRef{Name: "lake", Namespace: "storage"}},
+		Status:     seaweedv1.BucketStatus{BucketName: "lakehouse-warehouse"},
+	}
+}
+
+// icebergCatalogGrant lets IcebergCatalogs in "analytics" expose the
+// clusters in "storage".
+func icebergCatalogGrant() *seaweedv1.ResourceReferenceGrant {
+	return &seaweedv1.ResourceReferenceGrant{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-analytics-iceberg", Namespace: "storage"},
+		Spec: seaweedv1.ResourceReferenceGrantSpec{
+			From: []seaweedv1.ReferenceGrantFrom{{Group: groupSeaweed, Kind: kindIcebergCatalog, Namespace: "analytics"}},
+			To:   []seaweedv1.ReferenceGrantTo{{Group: groupSeaweed, Kind: kindSeaweed}},
+		},
+	}
+}
+
+func newIcebergCredentials() (*seaweedv1.S3Credentials, *corev1.Secret) {
+	cred := &seaweedv1.S3Credentials{
+		ObjectMeta: metav1.ObjectMeta{Name: "spark-creds", Namespace: "analytics"},
+		Spec: seaweedv1.S3CredentialsSpec{
+			SeaweedRef:  seaweedv1.SeaweedReference{Name: "lake", Namespace: "storage"},
+			IdentityRef: seaweedv1.S3IdentityRef{Name: "spark"},
+			SecretRef:   seaweedv1.S3SecretRef{Name: "spark-s3"},
+		},
+		Status: seaweedv1.S3CredentialsStatus{Phase: seaweedv1.S3PhaseReady, IdentityName: "spark", SecretName: "spark-s3"},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "spark-s3", Namespace: "analytics"},
+		Data:       map[string][]byte{"accessKey": []byte("AKIASPARK"), "secretKey": []byte("s3cret")},
+	}
+	return cred, secret
+}
+
+func newIcebergCatalog() *seaweedv1.IcebergCatalog {
+	return &seaweedv1.IcebergCatalog{
+		ObjectMeta: metav1.ObjectMeta{Name: icebergCatalogKey.Name, Namespace: icebergCatalogKey.Namespace},
+		Spec: seaweedv1.IcebergCatalogSpec{
+			WarehouseRef: seaweedv1.IcebergWarehouseRef{Name: "warehouse"},
+			Auth: &seaweedv1.IcebergCatalogAuth{
+				CredentialsRef: seaweedv1.IcebergCatalogCredentialsRef{Name: "spark-creds"},
+				SigningRegion:  "us-east-1",
+			},
+			Namespaces: []seaweedv1.IcebergNamespace{
+				{Name: "analytics", Properties: map[string]string{"owner": "data-platform"}},
+				{Name: "analytics.events"},
+			},
+			Ingress: &seaweedv1.IngressSpec{
+				Enabled: true,
+				Host:    "iceberg.example.com",
+				Path:    "/",
+				TLS:     []seaweedv1.IngressTLS{{Hosts: []string{"iceberg.example.com"}, SecretName: "iceberg-tls"}},
+			},
+		},
+	}
+}
+
+func reconcileIcebergCatalog(t *testing.T, r *IcebergCatalogReconciler) (ctrl.Result, *seaweedv1.IcebergCatalog) {
+	t.Helper()
+	var res ctrl.Result
+	for i := 0; i < 2; i++ {
+		var err error
+		res, err = r.Reconcile(context.Background(), ctrl.Request{NamespacedName: icebergCatalogKey})
+		if err != nil {
+			t.Fatalf("reconcile step %d: %v", i, err)
+		}
+	}
+	var ic seaweedv1.IcebergCatalog
+	if err := r.Get(context.Background(), icebergCatalogKey, &ic); err != nil {
+		t.Fatalf("get catalog: %v", err)
+	}
+	return res, &ic
+}
+
+func TestIcebergCatalogProvision(t *testing.T) {
+	cred, secret := newIcebergCredentials()
+	fc := &fakeIcebergCatalog{namespaces: map[string]map[string]string{}}
+	r, cli := testIcebergCatalogReconciler(t, fc, newIcebergCluster(true), newIcebergWarehouse(), icebergCatalogGrant(), cred, secret, newIcebergCatalog())
+	ctx := context.Background()
+
+	_, ic := reconcileIcebergCatalog(t, r)
+	if ic.Status.Phase != seaweedv1.BucketPhaseReady {
+		t.Fatalf("phase = %q, conditions %+v", ic.Status.Phase, ic.Status.Conditions)
+	}
+
+	svcName := "analytics-lakehouse-iceberg"
+	var svc corev1.Service
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: "storage", Name: svcName}, &svc); err != nil {
+		t.Fatalf("get service: %v", err)
+	}
+	if svc.Spec.Ports[0].Port != seaweedv1.FilerIcebergPort || svc.Spec.Selector["app.kubernetes.io/component"] != "filer" ||
+		svc.Spec.Selector["app.kubernetes.io/instance"] != "lake" {
+		t.Errorf("service spec = %+v", svc.Spec)
+	}
+	var ing networkingv1.Ingress
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: "storage", Name: "analytics-lakehouse-iceberg-ingress"}, &ing); err != nil {
+		t.Fatalf("get ingress: %v", err)
+	}
+	if backend := ing.Spec.Rules[0].HTTP.Paths[0].Backend.Service; backend.Name != svcName || backend.Port.Number != seaweedv1.FilerIcebergPort {
+		t.Errorf("ingress backend = %+v", backend)
+	}
+
+	if ic.Status.Endpoint != "http://analytics-lakehouse-iceberg.storage.svc:8181" {
+		t.Errorf("endpoint = %q", ic.Status.Endpoint)
+	}
+	if ic.Status.ExternalEndpoint != "https://iceberg.example.com" {
+		t.Errorf("external endpoint = %q", ic.Status.ExternalEndpoint)
+	}
+	if ic.Status.Warehouse != "s3://lakehouse-warehouse" {
+		t.Errorf("warehouse = %q", ic.Status.Warehouse)
+	}
+	if a := ic.Status.Auth; a == nil || a.IdentityName != "spark" || a.SecretName != "spark-s3" || a.SecretNamespace != "analytics" {
+		t.Errorf("auth status = %+v", a)
+	}
+	if fc.endpoint != ic.Status.Endpoint || fc.creds == nil || fc.creds.AccessKey != "AKIASPARK" || fc.creds.SecretKey != "s3cret" {
+		t.Errorf("client built for %q with %+v", fc.endpoint, fc.creds)
+	}
+	if got := fc.namespaces["lakehouse-warehouse/analytics"]["owner"]; got != "data-platform" {
+		t.Errorf("analytics properties = %v", fc.namespaces["lakehouse-warehouse/analytics"])
+	}
+	if _, ok := fc.namespaces["lakehouse-warehouse/analytics.events"]; !ok {
+		t.Errorf("expected analytics.events to be created, calls %v", fc.calls)
+	}
+	if strings.Join(ic.Status.Namespaces, ",") != "analytics,analytics.events" {
+		t.Errorf("status namespaces = %v", ic.Status.Namespaces)
+	}
+
+	// A property changed out of band is put back; nothing is recreated.
+	fc.namespaces["lakehouse-warehouse/analytics"]["owner"] = "someone-else"
+	fc.calls = nil
+	reconcileIcebergCatalog(t, r)
+	if got := fc.namespaces["lakehouse-warehouse/analytics"]["owner"]; got != "data-platform" {
+		t.Errorf("owner property = %q after resync", got)
+	}
+	for _, call := range fc.calls {
+		if strings.HasPrefix(call, "CreateNamespace:") {
+			t.Errorf("unexpected %s on resync", call)
+		}
+	}
+
+	// Disabling the Ingress removes it.
+	if err := cli.Get(ctx, icebergCatalogKey, ic); err != nil {
+		t.Fatalf("get catalog: %v", err)
+	}
+	ic.Spec.Ingress.Enabled = false
+	if err := cli.Update(ctx, ic); err != nil {
+		t.Fatalf("update catalog: %v", err)
+	}
+	_, ic = reconcileIcebergCatalog(t, r)
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: "storage", Name: "analytics-lakehouse-iceberg-ingress"}, &ing); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the ingress to be removed, got %v", err)
+	}
+	if ic.Status.ExternalEndpoint != "" {
+		t.Errorf("external endpoint = %q with the ingress disabled", ic.Status.ExternalEndpoint)
+	}
+}
+
+func TestIcebergCatalogPendingDependencies(t *testing.T) {
+	cred, secret := newIcebergCredentials()
+	cred.Status.Phase = seaweedv1.S3PhasePending
+	fc := &fakeIcebergCatalog{namespaces: map[string]map[string]string{}}
+	r, cli := testIcebergCatalogReconciler(t, fc, newIcebergCluster(false), newIcebergWarehouse(), icebergCatalogGrant(), cred, secret, newIcebergCatalog())
+	ctx := context.Background()
+
+	res, ic := reconcileIcebergCatalog(t, r)
+	cond := meta.FindStatusCondition(ic.Status.Conditions, seaweedv1.IcebergCatalogConditionReady)
+	if ic.Status.Phase != seaweedv1.BucketPhasePending || cond == nil || cond.Reason != "IcebergDisabled" || res.RequeueAfter == 0 {
+		t.Fatalf("phase = %q, ready = %+v", ic.Status.Phase, cond)
+	}
+
+	var cluster seaweedv1.Seaweed
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: "storage", Name: "lake"}, &cluster); err != nil {
+		t.Fatalf("get cluster: %v", err)
+	}
+	cluster.Spec.Filer.Iceberg.Enabled = true
+	if err := cli.Update(ctx, &cluster); err != nil {
+		t.Fatalf("update cluster: %v", err)
+	}
+	_, ic = reconcileIcebergCatalog(t, r)
+	if cond := meta.FindStatusCondition(ic.Status.Conditions, seaweedv1.IcebergCatalogConditionResolved); cond == nil || cond.Reason != "CredentialsNotReady" {
+		t.Fatalf("resolved = %+v", cond)
+	}
+	if len(fc.calls) != 0 {
+		t.Errorf("catalog called before credentials were ready: %v", fc.calls)
+	}
+
+	// An unreachable catalog keeps the endpoint published but is not Ready.
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: "analytics", Name: "spark-creds"}, cred); err != nil {
+		t.Fatalf("get credentials: %v", err)
+	}
+	cred.Status.Phase = seaweedv1.S3PhaseReady
+	if err := cli.Update(ctx, cred); err != nil {
+		t.Fatalf("update credentials: %v", err)
+	}
+	fc.err = errors.New("connection refused")
+	_, ic = reconcileIcebergCatalog(t, r)
+	cond = meta.FindStatusCondition(ic.Status.Conditions, seaweedv1.IcebergCatalogConditionReady)
+	if ic.Status.Phase != seaweedv1.BucketPhasePending || cond == nil || cond.Reason != "CatalogUnavailable" || ic.Status.Endpoint == "" {
+		t.Errorf("phase = %q, ready = %+v, endpoint %q", ic.Status.Phase, cond, ic.Status.Endpoint)
+	}
+}
+
+func TestIcebergCatalogDeletion(t *testing.T) {
+	cred, secret := newIcebergCredentials()
+	fc := &fakeIcebergCatalog{namespaces: map[string]map[string]string{}}
+	r, cli := testIcebergCatalogReconciler(t, fc, newIcebergCluster(true), newIcebergWarehouse(), icebergCatalogGrant(), cred, secret, newIcebergCatalog())
+	ctx := context.Background()
+	_, ic := reconcileIcebergCatalog(t, r)
+
+	if err := cli.Delete(ctx, ic); err != nil {
+		t.Fatalf("delete catalog: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: icebergCatalogKey}); err != nil {
+		t.Fatalf("reconcile deletion: %v", err)
+	}
+	if err := cli.Get(ctx, icebergCatalogKey, ic); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the catalog to be gone, got %v", err)
+	}
+	var svc corev1.Service
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: "storage", Name: "analytics-lakehouse-iceberg"}, &svc); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the service to be removed, got %v", err)
+	}
+	var ing networkingv1.Ingress
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: "storage", Name: "analytics-lakehouse-iceberg-ingress"}, &ing); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the ingress to be removed, got %v", err)
+	}
+	if _, ok := fc.namespaces["lakehouse-warehouse/analytics"]; !ok {
+		t.Errorf("expected catalog namespaces to be kept")
+	}
+}
+
+func TestIcebergCatalogNeedsGrant(t *testing.T) {
+	cred, secret := newIcebergCredentials()
+	grant := icebergCatalogGrant()
+	fc := &fakeIcebergCatalog{namespaces: map[string]map[string]string{}}
+	r, cli := testIcebergCatalogReconciler(t, fc, newIcebergCluster(true), newIcebergWarehouse(), cred, secret, newIcebergCatalog())
+	ctx := context.Background()
+	svcKey := types.NamespacedName{Namespace: "storage", Name: "analytics-lakehouse-iceberg"}
+	ingKey := types.NamespacedName{Namespace: "storage", Name: "analytics-lakehouse-iceberg-ingress"}
+
+	_, ic := reconcileIcebergCatalog(t, r)
+	cond := meta.FindStatusCondition(ic.Status.Conditions, seaweedv1.IcebergCatalogConditionReferenceGranted)
+	if ic.Status.Phase != seaweedv1.BucketPhasePending || cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != "ReferenceGrantMissing" {
+		t.Fatalf("phase = %q, referenceGranted = %+v", ic.Status.Phase, cond)
+	}
+	if err := cli.Get(ctx, svcKey, &corev1.Service{}); !apierrors.IsNotFound(err) {
+		t.Errorf("service created without a grant: %v", err)
+	}
+
+	if err := cli.Create(ctx, grant); err != nil {
+		t.Fatalf("create grant: %v", err)
+	}
+	_, ic = reconcileIcebergCatalog(t, r)
+	cond = meta.FindStatusCondition(ic.Status.Conditions, seaweedv1.IcebergCatalogConditionReferenceGranted)
+	if ic.Status.Phase != seaweedv1.BucketPhaseReady || cond == nil || cond.Status != metav1.ConditionTrue || cond.Reason != "Granted" {
+		t.Fatalf("phase = %q, referenceGranted = %+v", ic.Status.Phase, cond)
+	}
+	if err := cli.Get(ctx, ingKey, &networkingv1.Ingress{}); err != nil {
+		t.Fatalf("expected the ingress once granted: %v", err)
+	}
+
+	// Revoking the grant removes the Service and Ingress.
+	if err := cli.Delete(ctx, grant); err != nil {
+		t.Fatalf("delete grant: %v", err)
+	}
+	_, ic = reconcileIcebergCatalog(t, r)
+	if err := cli.Get(ctx, svcKey, &corev1.Service{}); !apierrors.IsNotFound(err) {
+		t.Errorf("service kept after revocation: %v", err)
+	}
+	if err := cli.Get(ctx, ingKey, &networkingv1.Ingress{}); !apierrors.IsNotFound(err) {
+		t.Errorf("ingress kept after revocation: %v", err)
+	}
+	if ic.Status.Phase != seaweedv1.BucketPhasePending || ic.Status.Endpoint != "" || ic.Status.ServiceNamespace != "" {
+		t.Errorf("status after revocation = %+v", ic.Status)
+	}
+}
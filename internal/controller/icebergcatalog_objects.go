@@ -0,0 +1,114 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// icebergCatalogServiceName is the name of the catalog Service. It lives in
+// the cluster's namespace, next to the filer pods it selects, so the
+// catalog's own namespace is folded in to keep names apart.
+func icebergCatalogServiceName(ic *seaweedv1.IcebergCatalog) string {
+	return boundedName(ic.Namespace+"-"+ic.Name, "-iceberg")
+}
+
+func icebergCatalogIngressName(ic *seaweedv1.IcebergCatalog) string {
+	return boundedName(ic.Namespace+"-"+ic.Name, "-iceberg-ingress")
+}
+
+// labelsForIcebergCatalog are the labels on a catalog's Service and Ingress.
+func labelsForIcebergCatalog(name string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       "seaweedfs",
+		"app.kubernetes.io/component":  "iceberg-catalog",
+		"app.kubernetes.io/instance":   name,
+		"app.kubernetes.io/managed-by": "seaweedfs-operator",
+	}
+}
+
+// icebergCatalogWarehouse is the warehouse location of a bucket.
+func icebergCatalogWarehouse(bucket string) string {
+	return "s3://" + bucket
+}
+
+// icebergCatalogEndpoint is the in-cluster catalog URI served by svc.
+func icebergCatalogEndpoint(svc *corev1.Service) string {
+	return fmt.Sprintf("http://%s.%s.svc:%d", svc.Name, svc.Namespace, svc.Spec.Ports[0].Port)
+}
+
+// icebergCatalogExternalEndpoint is the catalog URI through the Ingress:
+// https when a TLS entry covers the host.
+func icebergCatalogExternalEndpoint(spec *seaweedv1.IngressSpec) string {
+	if spec == nil || !spec.Enabled || spec.Host == "" {
+		return ""
+	}
+	scheme := "http"
+	for _, t := range spec.TLS {
+		for _, h := range t.Hosts {
+			if h == spec.Host {
+				scheme = "https"
+			}
+		}
+	}
+	return scheme + "://" + spec.Host + strings.TrimSuffix(spec.Path, "/")
+}
+
+// buildIcebergCatalogService fills svc with a Service selecting cluster's
+// filer pods on the Iceberg port.
+func buildIcebergCatalogService(cluster *seaweedv1.Seaweed, ic *seaweedv1.IcebergCatalog, svc *corev1.Service) {
+	port := cluster.Spec.Filer.Iceberg.IcebergEffectivePort()
+	svc.Labels = labelsForIcebergCatalog(svc.Name)
+	svc.Annotations = nil
+	svc.Spec.Type = corev1.ServiceTypeClusterIP
+	svc.Spec.Selector = labelsForFiler(cluster.Name)
+	svc.Spec.Ports = []corev1.ServicePort{{
+		Name:       "iceberg",
+		Protocol:   corev1.ProtocolTCP,
+		Port:       port,
+		TargetPort: intstr.FromInt(int(port)),
+	}}
+	svc.Spec.LoadBalancerIP = ""
+	if s := ic.Spec.Service; s != nil {
+		svc.Annotations = copyAnnotations(s.Annotations)
+		if s.Type != "" {
+			svc.Spec.Type = s.Type
+		}
+		if s.ClusterIP != nil {
+			svc.Spec.ClusterIP = *s.ClusterIP
+		}
+		if s.LoadBalancerIP != nil {
+			svc.Spec.LoadBalancerIP = *s.LoadBalancerIP
+		}
+	}
+}
+
+// buildIcebergCatalogIngress fills ing with the rule routing spec's host and
+// path to the catalog Service.
+func buildIcebergCatalogIngress(cluster *seaweedv1.Seaweed, svc *corev1.Service, spec *seaweedv1.IngressSpec, ing *networkingv1.Ingress) {
+	desired := buildComponentIngress(cluster, "iceberg-catalog", svc.Name, svc.Spec.Ports[0].Port, spec)
+	ing.Labels = labelsForIcebergCatalog(ing.Name)
+	ing.Annotations = desired.Annotations
+	ing.Spec = desired.Spec
+}
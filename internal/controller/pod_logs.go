@@ -0,0 +1,107 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"regexp"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// maxScrapedLogBytes bounds how much of a container's log is read when
+// counting its errors.
+const maxScrapedLogBytes = 16 << 20
+
+// glogErrorLine matches the error and fatal lines weed logs through glog,
+// e.g. "E1018 20:57:57.123456 12 filer_backup.go:130] ...".
+var glogErrorLine = regexp.MustCompile(`^[EF]\d{4} \d{2}:\d{2}:\d{2}\.\d{6} `)
+
+// PodLogReader streams the log of a container's current instance. Tests
+// inject a fake.
+type PodLogReader interface {
+	ContainerLog(ctx context.Context, namespace, pod, container string) (io.ReadCloser, error)
+}
+
+// NewPodLogReader returns a PodLogReader that reads through the API server's
+// pods/log subresource.
+func NewPodLogReader(config *rest.Config) (PodLogReader, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &apiPodLogReader{clientset: clientset}, nil
+}
+
+type apiPodLogReader struct {
+	clientset kubernetes.Interface
+}
+
+func (l *apiPodLogReader) ContainerLog(ctx context.Context, namespace, pod, container string) (io.ReadCloser, error) {
+	limit := int64(maxScrapedLogBytes)
+	return l.clientset.CoreV1().Pods(namespace).GetLogs(pod, &corev1.PodLogOptions{
+		Container:  container,
+		LimitBytes: &limit,
+	}).Stream(ctx)
+}
+
+// countLoggedErrors counts the glog error and fatal lines in r.
+func countLoggedErrors(r io.Reader) (int32, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	var n int32
+	for sc.Scan() {
+		if glogErrorLine.Match(sc.Bytes()) {
+			n++
+		}
+	}
+	return n, sc.Err()
+}
+
+// scrapeContainerErrors sums the errors logged by container in the running
+// pods, since each container last started. Pods whose container has not
+// started yet have no log to read.
+func scrapeContainerErrors(ctx context.Context, logs PodLogReader, pods []corev1.Pod, container string) (int32, error) {
+	var total int32
+	for i := range pods {
+		pod := &pods[i]
+		started := false
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.Name == container && cs.State.Running != nil {
+				started = true
+			}
+		}
+		if !started {
+			continue
+		}
+		rc, err := logs.ContainerLog(ctx, pod.Namespace, pod.Name, container)
+		if err != nil {
+			return 0, err
+		}
+		n, err := countLoggedErrors(rc)
+		rc.Close()
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
@@ -0,0 +1,84 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakePodLogs serves container logs keyed by "<namespace>/<pod>/<container>".
+type fakePodLogs map[string]string
+
+func (f fakePodLogs) ContainerLog(_ context.Context, namespace, pod, container string) (io.ReadCloser, error) {
+	log, ok := f[namespace+"/"+pod+"/"+container]
+	if !ok {
+		return nil, fmt.Errorf("no log for %s/%s/%s", namespace, pod, container)
+	}
+	return io.NopCloser(strings.NewReader(log)), nil
+}
+
+// runningPod is a pod whose container has restarted the given number of times
+// and is running again.
+func runningPod(namespace, name string, labels map[string]string, container string, restarts int32) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name:         container,
+			RestartCount: restarts,
+			State:        corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+		}}},
+	}
+}
+
+const weedErrorLog = `I0301 11:58:00.000001 1 filer_backup.go:120] traverse /buckets/photos
+E0301 11:58:30.000002 1 filer_backup.go:130] upload photos/a.jpg: connection reset
+W0301 11:58:31.000003 1 filer_backup.go:140] retrying
+E0301 11:59:00.000004 1 filer_backup.go:130] upload photos/b.jpg: 503 Slow Down
+ERROR is not a glog line
+`
+
+func TestCountLoggedErrors(t *testing.T) {
+	n, err := countLoggedErrors(strings.NewReader(weedErrorLog))
+	if err != nil || n != 2 {
+		t.Errorf("countLoggedErrors = %d, %v; want 2", n, err)
+	}
+}
+
+func TestScrapeContainerErrors(t *testing.T) {
+	waiting := runningPod("storage", "pending", nil, "weed", 0)
+	waiting.Status.ContainerStatuses[0].State = corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+	pods := []corev1.Pod{
+		*runningPod("storage", "a", nil, "weed", 0),
+		*runningPod("storage", "b", nil, "weed", 3),
+		*waiting,
+	}
+	logs := fakePodLogs{
+		"storage/a/weed": weedErrorLog,
+		"storage/b/weed": "F0301 12:00:00.000000 1 main.go:1] fatal\n",
+	}
+	n, err := scrapeContainerErrors(context.Background(), logs, pods, "weed")
+	if err != nil || n != 3 {
+		t.Errorf("scrapeContainerErrors = %d, %v; want 3 (the waiting pod has no log)", n, err)
+	}
+}
@@ -42,8 +42,15 @@ const (
 	kindS3PolicyBinding = "S3PolicyBinding"
 	kindS3OIDCProvider  = "S3OIDCProvider"
 	kindBucket          = "Bucket"
-
-	kindSeaweedCSIDriver = "SeaweedCSIDriver"
+	kindSFTPUser        = "SFTPUser"
+	kindRemoteMount     = "RemoteMount"
+	kindAdminScript     = "AdminScript"
+	kindSeaweedBackup   = "SeaweedBackup"
+	kindSeaweedRestore  = "SeaweedRestore"
+
+	kindSeaweedCSIDriver  = "SeaweedCSIDriver"
+	kindBucketReplication = "BucketReplication"
+	kindIcebergCatalog    = "IcebergCatalog"
 )
 
 // +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=resourcereferencegrants,verbs=get;list;watch
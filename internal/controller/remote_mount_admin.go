@@ -0,0 +1,195 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+
+	"github.com/seaweedfs/seaweedfs-operator/internal/controller/swadmin"
+)
+
+// RemoteMountAdmin is the surface the remote mount reconciler uses to drive
+// a cluster's `weed shell remote.*` commands. The default implementation
+// wraps swadmin.SeaweedAdmin; tests inject a fake.
+type RemoteMountAdmin interface {
+	// ConfigureRemote stores (or replaces) the remote storage configuration
+	// name; args are the remote.configure flags minus -name. They carry
+	// credentials, so implementations must not log them.
+	ConfigureRemote(ctx context.Context, name string, args []string) error
+	// DeleteRemote removes the remote storage configuration name. It is a
+	// no-op when none is stored.
+	DeleteRemote(ctx context.Context, name string) error
+	// ListMounts returns the filer's mounts keyed by local directory.
+	ListMounts(ctx context.Context) (map[string]RemoteMountLocation, error)
+	// Mount mounts loc at the empty or missing directory dir.
+	Mount(ctx context.Context, dir string, loc RemoteMountLocation) error
+	// Unmount drops the mount at dir along with its local metadata and
+	// cached content. The remote data is untouched.
+	Unmount(ctx context.Context, dir string) error
+	// SyncMetadata pulls the remote file listing under dir into the filer.
+	SyncMetadata(ctx context.Context, dir string) error
+	// Cache pulls the content of the files under dir that match args (the
+	// remote.cache filter flags) into the cluster.
+	Cache(ctx context.Context, dir string, args []string) error
+	// Uncache drops the local content of the files under dir that match
+	// args (the remote.uncache filter flags).
+	Uncache(ctx context.Context, dir string, args []string) error
+	io.Closer
+}
+
+// RemoteMountLocation is one entry of the filer's remote mount mapping.
+type RemoteMountLocation struct {
+	Name   string `json:"name"`
+	Bucket string `json:"bucket"`
+	Path   string `json:"path"`
+}
+
+// String renders the location the way remote.mount -remote expects it.
+func (l RemoteMountLocation) String() string {
+	p := strings.TrimSuffix(l.Path, "/")
+	if p != "" && !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return l.Name + "/" + l.Bucket + p
+}
+
+// RemoteMountAdminFactory creates a RemoteMountAdmin for a cluster. The
+// remote.* commands keep their state in the filer, so both masters and filer
+// are required. Replaceable in tests.
+type RemoteMountAdminFactory func(masters, filer string, grpcDialOption grpc.DialOption, log logr.Logger) (RemoteMountAdmin, error)
+
+// swadminRemoteMountAdmin is the default RemoteMountAdmin, backed by
+// swadmin.SeaweedAdmin. mu serializes command execution (which swaps the
+// shared Output writer) and Close.
+type swadminRemoteMountAdmin struct {
+	sa  *swadmin.SeaweedAdmin
+	log logr.Logger
+	mu  sync.Mutex
+}
+
+// NewSwadminRemoteMountAdmin returns a RemoteMountAdmin that drives the
+// embedded `weed shell`.
+func NewSwadminRemoteMountAdmin(masters, filer string, grpcDialOption grpc.DialOption, log logr.Logger) (RemoteMountAdmin, error) {
+	sa := swadmin.NewSeaweedAdmin(masters, filer, grpcDialOption, io.Discard)
+	return &swadminRemoteMountAdmin{sa: sa, log: log}, nil
+}
+
+// Close stops the embedded shell's background master connection.
+func (a *swadminRemoteMountAdmin) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.sa.Close()
+}
+
+// run executes cmd and returns its output. logged is what ends up in the
+// debug log, so callers can keep credentials out of it.
+func (a *swadminRemoteMountAdmin) run(ctx context.Context, cmd, logged string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var buf bytes.Buffer
+	a.sa.Output = &buf
+	defer func() { a.sa.Output = io.Discard }()
+	if err := a.sa.ProcessCommand(ctx, cmd); err != nil {
+		a.log.V(2).Info("swadmin command failed", "cmd", logged, "err", err.Error())
+		return buf.String(), fmt.Errorf("%s: %w", logged, err)
+	}
+	a.log.V(2).Info("swadmin command ok", "cmd", logged)
+	return buf.String(), nil
+}
+
+func (a *swadminRemoteMountAdmin) ConfigureRemote(ctx context.Context, name string, args []string) error {
+	logged := "remote.configure -name=" + name
+	_, err := a.run(ctx, logged+" "+strings.Join(args, " "), logged)
+	return err
+}
+
+func (a *swadminRemoteMountAdmin) DeleteRemote(ctx context.Context, name string) error {
+	cmd := "remote.configure -delete -name=" + name
+	_, err := a.run(ctx, cmd, cmd)
+	return err
+}
+
+func (a *swadminRemoteMountAdmin) ListMounts(ctx context.Context) (map[string]RemoteMountLocation, error) {
+	out, err := a.run(ctx, "remote.mount", "remote.mount")
+	if err != nil {
+		return nil, err
+	}
+	return parseRemoteMountMappings(out)
+}
+
+func (a *swadminRemoteMountAdmin) Mount(ctx context.Context, dir string, loc RemoteMountLocation) error {
+	cmd := fmt.Sprintf("remote.mount -dir=%s -remote=%s", dir, loc)
+	_, err := a.run(ctx, cmd, cmd)
+	return err
+}
+
+func (a *swadminRemoteMountAdmin) Unmount(ctx context.Context, dir string) error {
+	cmd := "remote.unmount -dir=" + dir
+	_, err := a.run(ctx, cmd, cmd)
+	return err
+}
+
+func (a *swadminRemoteMountAdmin) SyncMetadata(ctx context.Context, dir string) error {
+	cmd := "remote.meta.sync -dir=" + dir
+	_, err := a.run(ctx, cmd, cmd)
+	return err
+}
+
+func (a *swadminRemoteMountAdmin) Cache(ctx context.Context, dir string, args []string) error {
+	cmd := strings.Join(append([]string{"remote.cache", "-dir=" + dir}, args...), " ")
+	_, err := a.run(ctx, cmd, cmd)
+	return err
+}
+
+func (a *swadminRemoteMountAdmin) Uncache(ctx context.Context, dir string, args []string) error {
+	cmd := strings.Join(append([]string{"remote.uncache", "-dir=" + dir}, args...), " ")
+	_, err := a.run(ctx, cmd, cmd)
+	return err
+}
+
+// parseRemoteMountMappings decodes the mapping `remote.mount` prints when
+// called without flags: the filer's RemoteStorageMapping as JSON. An empty
+// output means nothing is mounted.
+func parseRemoteMountMappings(out string) (map[string]RemoteMountLocation, error) {
+	out = strings.TrimSpace(out)
+	if start := strings.Index(out, "{"); start > 0 {
+		out = out[start:]
+	}
+	mounts := map[string]RemoteMountLocation{}
+	if out == "" {
+		return mounts, nil
+	}
+	var mapping struct {
+		Mappings map[string]RemoteMountLocation `json:"mappings"`
+	}
+	if err := json.Unmarshal([]byte(out), &mapping); err != nil {
+		return nil, fmt.Errorf("parse remote.mount output: %w", err)
+	}
+	for dir, loc := range mapping.Mappings {
+		mounts[dir] = loc
+	}
+	return mounts, nil
+}
@@ -0,0 +1,526 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// RemoteMountFinalizer keeps the CR around until its directory has been
+// unmounted and its remote storage configuration dropped from the filer.
+const RemoteMountFinalizer = "seaweed.seaweedfs.com/remotemount-protection"
+
+// defaultRemoteMountSyncInterval applies when spec.syncInterval was not
+// defaulted by the API server (objects built in tests).
+const defaultRemoteMountSyncInterval = time.Hour
+
+// RemoteMountReconciler mounts external object store buckets into a cluster's
+// filer namespace: it stores the remote storage configuration, mounts it at
+// the local directory, and periodically refreshes the listing and applies the
+// cache policies.
+type RemoteMountReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	// AdminFactory creates the RemoteMountAdmin driving the cluster's
+	// remote.* shell commands. Tests inject a fake; production wires
+	// NewSwadminRemoteMountAdmin.
+	AdminFactory RemoteMountAdminFactory
+
+	// Now returns the current time when scheduling metadata syncs. Nil uses
+	// time.Now; tests inject a fixed clock.
+	Now func() time.Time
+}
+
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=remotemounts,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=remotemounts/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=remotemounts/finalizers,verbs=update
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=seaweeds,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+
+// Reconcile implements the remote mount reconciliation logic.
+func (r *RemoteMountReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := r.Log.WithValues("remotemount", req.NamespacedName)
+
+	var rm seaweedv1.RemoteMount
+	if err := r.Get(ctx, req.NamespacedName, &rm); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !rm.DeletionTimestamp.IsZero() {
+		return r.handleDeletion(ctx, &rm, log)
+	}
+
+	if !controllerutil.ContainsFinalizer(&rm, RemoteMountFinalizer) {
+		controllerutil.AddFinalizer(&rm, RemoteMountFinalizer)
+		if err := r.Update(ctx, &rm); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	base := rm.Status.DeepCopy()
+	defer func() {
+		if err != nil || reflect.DeepEqual(*base, rm.Status) {
+			return
+		}
+		if uerr := r.Status().Update(ctx, &rm); uerr != nil {
+			result, err = ctrl.Result{}, uerr
+		}
+	}()
+
+	ref := rm.Spec.SeaweedRef
+	permitted, err := seaweedRefPermitted(ctx, r.Client, ref, kindRemoteMount, rm.Namespace)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !permitted {
+		return r.failPhase(&rm, "ReferenceNotPermitted", seaweedRefDeniedMessage(ref, kindRemoteMount, rm.Namespace)), nil
+	}
+	seaweed, err := r.getSeaweed(ctx, &rm)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if seaweed == nil {
+		return r.pending(&rm, "SeaweedNotFound",
+			fmt.Sprintf("Seaweed %q not found in namespace %q", ref.Name, remoteMountSeaweedNamespace(&rm))), nil
+	}
+	if seaweed.Spec.Filer == nil {
+		return r.pending(&rm, "FilerDisabled", "the Seaweed cluster does not run a filer (spec.filer is unset)"), nil
+	}
+	if seaweed.Status.Filer.ReadyReplicas == 0 {
+		return r.pending(&rm, "FilerNotReady", "waiting for a ready filer"), nil
+	}
+
+	creds, err := readBackupCredentials(ctx, r.Client, rm.Namespace, rm.Spec.Storage)
+	if err != nil {
+		return r.failPhase(&rm, "CredentialsUnavailable", err.Error()), nil
+	}
+	remoteName := remoteMountRemoteName(&rm)
+	configArgs, err := remoteConfigureArgs(rm.Spec.Storage, creds)
+	if err != nil {
+		return r.failPhase(&rm, "InvalidStorage", err.Error()), nil
+	}
+	loc, err := remoteMountLocation(&rm, remoteName)
+	if err != nil {
+		return r.failPhase(&rm, "InvalidStorage", err.Error()), nil
+	}
+
+	admin, err := r.adminFor(ctx, seaweed, log)
+	if err != nil {
+		return r.failPhase(&rm, "FilerUnavailable", err.Error()), nil
+	}
+	defer closeRemoteMountAdmin(admin, log)
+
+	// remote.configure overwrites a stored configuration, so rotated
+	// credentials reach the filer on the next pass.
+	if err := admin.ConfigureRemote(ctx, remoteName, configArgs); err != nil {
+		return r.failPhase(&rm, "ConfigureFailed", err.Error()), nil
+	}
+	rm.Status.RemoteName = remoteName
+
+	dir := rm.Spec.LocalDir
+	mounts, err := admin.ListMounts(ctx)
+	if err != nil {
+		return r.failPhase(&rm, "FilerUnavailable", err.Error()), nil
+	}
+	mounted := false
+	if existing, ok := mounts[dir]; ok {
+		// Compare rendered locations: the filer stores the path without the
+		// trailing slash remotePath may carry.
+		if existing.String() != loc.String() {
+			return r.failPhase(&rm, "DirectoryMounted",
+				fmt.Sprintf("%s is already mounted from %s", dir, existing)), nil
+		}
+	} else {
+		if err := admin.Mount(ctx, dir, loc); err != nil {
+			return r.failPhase(&rm, "MountFailed", err.Error()), nil
+		}
+		log.Info("mounted remote storage", "dir", dir, "remote", loc.String())
+		mounted = true
+	}
+
+	rm.Status.ObservedGeneration = rm.Generation
+	rm.Status.Remote = loc.String()
+	rm.Status.Phase = seaweedv1.S3PhaseReady
+	r.setCondition(&rm, seaweedv1.RemoteMountConditionReady, metav1.ConditionTrue, "Mounted", "")
+
+	interval := defaultRemoteMountSyncInterval
+	if rm.Spec.SyncInterval != nil {
+		interval = rm.Spec.SyncInterval.Duration
+	}
+	now := r.now()
+	if last := rm.Status.LastSyncTime; !mounted && last != nil && now.Sub(last.Time) < interval {
+		return ctrl.Result{RequeueAfter: interval - now.Sub(last.Time)}, nil
+	}
+	// remote.mount pulls the listing itself, so a fresh mount skips the sync
+	// but still applies the cache policies.
+	if !mounted {
+		if err := admin.SyncMetadata(ctx, dir); err != nil {
+			r.setCondition(&rm, seaweedv1.RemoteMountConditionSynced, metav1.ConditionFalse, "SyncFailed", err.Error())
+			return ctrl.Result{RequeueAfter: requeueAfterTransient}, nil
+		}
+	}
+	if f := rm.Spec.Cache; f != nil {
+		if err := admin.Cache(ctx, dir, remoteFileFilterArgs(f)); err != nil {
+			r.setCondition(&rm, seaweedv1.RemoteMountConditionSynced, metav1.ConditionFalse, "CacheFailed", err.Error())
+			return ctrl.Result{RequeueAfter: requeueAfterTransient}, nil
+		}
+	}
+	if f := rm.Spec.Uncache; f != nil {
+		if err := admin.Uncache(ctx, dir, remoteFileFilterArgs(f)); err != nil {
+			r.setCondition(&rm, seaweedv1.RemoteMountConditionSynced, metav1.ConditionFalse, "UncacheFailed", err.Error())
+			return ctrl.Result{RequeueAfter: requeueAfterTransient}, nil
+		}
+	}
+	rm.Status.LastSyncTime = &metav1.Time{Time: now}
+	r.setCondition(&rm, seaweedv1.RemoteMountConditionSynced, metav1.ConditionTrue, "Synced", "")
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// handleDeletion unmounts the directory, when the mount there is still this
+// CR's, and drops the remote storage configuration. Unmounting purges the
+// directory's local metadata and cached content; the remote bucket is left
+// untouched.
+func (r *RemoteMountReconciler) handleDeletion(ctx context.Context, rm *seaweedv1.RemoteMount, log logr.Logger) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(rm, RemoteMountFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	if name := rm.Status.RemoteName; name != "" {
+		seaweed, err := r.getSeaweed(ctx, rm)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if seaweed != nil {
+			admin, err := r.adminFor(ctx, seaweed, log)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			err = unmountRemote(ctx, admin, rm.Spec.LocalDir, name)
+			closeRemoteMountAdmin(admin, log)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			log.Info("unmounted remote storage", "dir", rm.Spec.LocalDir, "remote", name)
+		}
+	}
+	controllerutil.RemoveFinalizer(rm, RemoteMountFinalizer)
+	if err := r.Update(ctx, rm); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// unmountRemote drops the mount at dir when it uses remote name, then the
+// remote storage configuration itself.
+func unmountRemote(ctx context.Context, admin RemoteMountAdmin, dir, name string) error {
+	mounts, err := admin.ListMounts(ctx)
+	if err != nil {
+		return err
+	}
+	if mounts[dir].Name == name {
+		if err := admin.Unmount(ctx, dir); err != nil {
+			return err
+		}
+	}
+	return admin.DeleteRemote(ctx, name)
+}
+
+// remoteMountRemoteName is the remote storage configuration name for rm.
+// remote.configure only accepts letters and digits, and the name is global
+// to the filer, so it is derived from the CR's namespace and name.
+func remoteMountRemoteName(rm *seaweedv1.RemoteMount) string {
+	sum := sha256.Sum256([]byte(rm.Namespace + "/" + rm.Name))
+	return "k8s" + hex.EncodeToString(sum[:6])
+}
+
+// remoteMountLocation is the remote.mount target for rm's bucket and path.
+func remoteMountLocation(rm *seaweedv1.RemoteMount, remoteName string) (RemoteMountLocation, error) {
+	st := rm.Spec.Storage
+	loc := RemoteMountLocation{Name: remoteName, Path: rm.Spec.RemotePath}
+	if loc.Path == "" {
+		loc.Path = "/"
+	}
+	switch {
+	case st.Type == seaweedv1.BackupStorageS3 && st.S3 != nil:
+		loc.Bucket = st.S3.Bucket
+	case st.Type == seaweedv1.BackupStorageGCS && st.GCS != nil:
+		loc.Bucket = st.GCS.Bucket
+	case st.Type == seaweedv1.BackupStorageAzure && st.Azure != nil:
+		loc.Bucket = st.Azure.Container
+	case st.Type == seaweedv1.BackupStorageB2 && st.B2 != nil:
+		loc.Bucket = st.B2.Bucket
+	default:
+		return RemoteMountLocation{}, fmt.Errorf("storage type %q cannot be mounted", st.Type)
+	}
+	return loc, nil
+}
+
+// remoteConfigureArgs maps a storage and its credentials onto remote.configure
+// flags. Unset credentials are omitted so s3 falls back to the filer's
+// ambient chain.
+func remoteConfigureArgs(st seaweedv1.BackupStorageSpec, creds map[string][]byte) ([]string, error) {
+	var args []string
+	flag := func(name, value string) {
+		if value != "" {
+			args = append(args, "-"+name+"="+value)
+		}
+	}
+	required := func(key string) (string, error) {
+		if len(creds[key]) == 0 {
+			return "", fmt.Errorf("storage type %s requires %s in credentialsSecret", st.Type, key)
+		}
+		return string(creds[key]), nil
+	}
+
+	switch st.Type {
+	case seaweedv1.BackupStorageS3:
+		if st.S3 == nil {
+			return nil, fmt.Errorf("type s3 requires the s3 block")
+		}
+		forcePath := true
+		if st.S3.ForcePathStyle != nil {
+			forcePath = *st.S3.ForcePathStyle
+		}
+		flag("type", "s3")
+		flag("s3.access_key", string(creds[seaweedv1.BackupSecretKeyAWSAccessKeyID]))
+		flag("s3.secret_key", string(creds[seaweedv1.BackupSecretKeyAWSSecretAccessKey]))
+		flag("s3.region", st.S3.Region)
+		flag("s3.endpoint", st.S3.Endpoint)
+		flag("s3.force_path_style", strconv.FormatBool(forcePath))
+	case seaweedv1.BackupStorageGCS:
+		if st.GCS == nil {
+			return nil, fmt.Errorf("type gcs requires the gcs block")
+		}
+		// weed loads GCS keys from a file on the filer, which the operator
+		// does not manage; only ambient credentials are supported.
+		if len(creds[seaweedv1.BackupSecretKeyGCSCredentials]) > 0 {
+			return nil, fmt.Errorf("gcs mounts use the filer's ambient credentials; remove %s from credentialsSecret", seaweedv1.BackupSecretKeyGCSCredentials)
+		}
+		flag("type", "gcs")
+	case seaweedv1.BackupStorageAzure:
+		if st.Azure == nil {
+			return nil, fmt.Errorf("type azure requires the azure block")
+		}
+		key, err := required(seaweedv1.BackupSecretKeyAzureAccountKey)
+		if err != nil {
+			return nil, err
+		}
+		flag("type", "azure")
+		flag("azure.account_name", st.Azure.AccountName)
+		flag("azure.account_key", key)
+	case seaweedv1.BackupStorageB2:
+		if st.B2 == nil {
+			return nil, fmt.Errorf("type b2 requires the b2 block")
+		}
+		id, err := required(seaweedv1.BackupSecretKeyB2AccountID)
+		if err != nil {
+			return nil, err
+		}
+		key, err := required(seaweedv1.BackupSecretKeyB2AppKey)
+		if err != nil {
+			return nil, err
+		}
+		flag("type", "b2")
+		flag("b2.key_id", id)
+		flag("b2.application_key", key)
+		flag("b2.region", st.B2.Region)
+	default:
+		return nil, fmt.Errorf("storage type %q cannot be mounted", st.Type)
+	}
+	return args, nil
+}
+
+// remoteFileFilterArgs renders a file filter as remote.cache/remote.uncache
+// flags; sizes are in bytes and ages in seconds.
+func remoteFileFilterArgs(f *seaweedv1.RemoteMountFileFilter) []string {
+	var args []string
+	if f.Include != "" {
+		args = append(args, "-include="+f.Include)
+	}
+	if f.Exclude != "" {
+		args = append(args, "-exclude="+f.Exclude)
+	}
+	if f.MinSize != nil {
+		args = append(args, fmt.Sprintf("-minSize=%d", f.MinSize.Value()))
+	}
+	if f.MaxSize != nil {
+		args = append(args, fmt.Sprintf("-maxSize=%d", f.MaxSize.Value()))
+	}
+	if f.MinAge != nil {
+		args = append(args, fmt.Sprintf("-minAge=%d", int64(f.MinAge.Seconds())))
+	}
+	if f.MaxAge != nil {
+		args = append(args, fmt.Sprintf("-maxAge=%d", int64(f.MaxAge.Seconds())))
+	}
+	return args
+}
+
+// remoteMountSeaweedNamespace is the namespace of the referenced cluster.
+func remoteMountSeaweedNamespace(rm *seaweedv1.RemoteMount) string {
+	if ns := rm.Spec.SeaweedRef.Namespace; ns != "" {
+		return ns
+	}
+	return rm.Namespace
+}
+
+// getSeaweed returns the referenced Seaweed, or nil when it does not exist.
+func (r *RemoteMountReconciler) getSeaweed(ctx context.Context, rm *seaweedv1.RemoteMount) (*seaweedv1.Seaweed, error) {
+	var seaweed seaweedv1.Seaweed
+	key := types.NamespacedName{Namespace: remoteMountSeaweedNamespace(rm), Name: rm.Spec.SeaweedRef.Name}
+	if err := r.Get(ctx, key, &seaweed); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &seaweed, nil
+}
+
+// adminFor builds a RemoteMountAdmin for the given Seaweed cluster.
+func (r *RemoteMountReconciler) adminFor(ctx context.Context, seaweed *seaweedv1.Seaweed, log logr.Logger) (RemoteMountAdmin, error) {
+	dialOption, _, err := loadSeaweedGrpcDialOption(ctx, r.Client, seaweed)
+	if err != nil {
+		return nil, err
+	}
+	return r.AdminFactory(getMasterPeersString(seaweed), getFilerAddress(seaweed), dialOption, log)
+}
+
+func closeRemoteMountAdmin(admin RemoteMountAdmin, log logr.Logger) {
+	if err := admin.Close(); err != nil {
+		log.Error(err, "close remote mount admin")
+	}
+}
+
+func (r *RemoteMountReconciler) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+// pending records a Pending phase with the dependency that is missing and
+// requeues on the transient cadence.
+func (r *RemoteMountReconciler) pending(rm *seaweedv1.RemoteMount, reason, message string) ctrl.Result {
+	rm.Status.Phase = seaweedv1.S3PhasePending
+	r.setCondition(rm, seaweedv1.RemoteMountConditionReady, metav1.ConditionFalse, reason, message)
+	return ctrl.Result{RequeueAfter: requeueAfterTransient}
+}
+
+func (r *RemoteMountReconciler) failPhase(rm *seaweedv1.RemoteMount, reason, message string) ctrl.Result {
+	r.Log.Info("reconcile failed", "reason", reason, "message", message)
+	rm.Status.Phase = seaweedv1.S3PhaseFailed
+	r.setCondition(rm, seaweedv1.RemoteMountConditionReady, metav1.ConditionFalse, reason, message)
+	return ctrl.Result{RequeueAfter: requeueAfterTransient}
+}
+
+func (r *RemoteMountReconciler) setCondition(rm *seaweedv1.RemoteMount, condType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&rm.Status.Conditions, metav1.Condition{
+		Type:               condType,
+		Status:             status,
+		ObservedGeneration: rm.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// mapSecretToRemoteMounts enqueues the mounts whose credentials live in a
+// Secret, so rotated keys reach the filer.
+func (r *RemoteMountReconciler) mapSecretToRemoteMounts(ctx context.Context, obj client.Object) []reconcile.Request {
+	var list seaweedv1.RemoteMountList
+	if err := r.List(ctx, &list, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+	var reqs []reconcile.Request
+	for i := range list.Items {
+		rm := &list.Items[i]
+		if s := rm.Spec.Storage.CredentialsSecret; s != nil && *s == obj.GetName() {
+			reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(rm)})
+		}
+	}
+	return reqs
+}
+
+// mapSeaweedToRemoteMounts enqueues the mounts of a cluster, so they are
+// created as soon as its filer comes up.
+func (r *RemoteMountReconciler) mapSeaweedToRemoteMounts(ctx context.Context, obj client.Object) []reconcile.Request {
+	var list seaweedv1.RemoteMountList
+	if err := r.List(ctx, &list); err != nil {
+		return nil
+	}
+	cluster := obj.GetNamespace() + "/" + obj.GetName()
+	var reqs []reconcile.Request
+	for i := range list.Items {
+		rm := &list.Items[i]
+		if seaweedRefKey(rm.Spec.SeaweedRef, rm.Namespace) == cluster {
+			reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(rm)})
+		}
+	}
+	return reqs
+}
+
+// seaweedSpecOrFilerReadinessChanged passes Seaweed spec changes and status
+// updates that flip whether any filer replica is ready.
+var seaweedSpecOrFilerReadinessChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldSw, ok1 := e.ObjectOld.(*seaweedv1.Seaweed)
+		newSw, ok2 := e.ObjectNew.(*seaweedv1.Seaweed)
+		if !ok1 || !ok2 {
+			return false
+		}
+		return oldSw.Generation != newSw.Generation ||
+			(oldSw.Status.Filer.ReadyReplicas > 0) != (newSw.Status.Filer.ReadyReplicas > 0)
+	},
+}
+
+// SetupWithManager wires the reconciler into the controller-runtime manager.
+func (r *RemoteMountReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.AdminFactory == nil {
+		r.AdminFactory = NewSwadminRemoteMountAdmin
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&seaweedv1.RemoteMount{}, builder.WithPredicates(specOrDeletionChanged)).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.mapSecretToRemoteMounts)).
+		Watches(&seaweedv1.Seaweed{}, handler.EnqueueRequestsFromMapFunc(r.mapSeaweedToRemoteMounts),
+			builder.WithPredicates(seaweedSpecOrFilerReadinessChanged)).
+		Complete(r)
+}
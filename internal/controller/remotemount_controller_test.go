@@ -0,0 +1,336 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// fakeRemoteMountAdmin records remote.* calls against an in-memory mapping.
+type fakeRemoteMountAdmin struct {
+	remotes map[string][]string
+	mounts  map[string]RemoteMountLocation
+	calls   []string
+	syncErr error
+}
+
+func newFakeRemoteMountAdmin() *fakeRemoteMountAdmin {
+	return &fakeRemoteMountAdmin{remotes: map[string][]string{}, mounts: map[string]RemoteMountLocation{}}
+}
+
+func (f *fakeRemoteMountAdmin) ConfigureRemote(_ context.Context, name string, args []string) error {
+	f.calls = append(f.calls, "configure "+name)
+	f.remotes[name] = args
+	return nil
+}
+
+func (f *fakeRemoteMountAdmin) DeleteRemote(_ context.Context, name string) error {
+	f.calls = append(f.calls, "delete "+name)
+	delete(f.remotes, name)
+	return nil
+}
+
+func (f *fakeRemoteMountAdmin) ListMounts(context.Context) (map[string]RemoteMountLocation, error) {
+	out := map[string]RemoteMountLocation{}
+	for k, v := range f.mounts {
+		out[k] = v
+	}
+	return out, nil
+}
+
+func (f *fakeRemoteMountAdmin) Mount(_ context.Context, dir string, loc RemoteMountLocation) error {
+	f.calls = append(f.calls, "mount "+dir+" "+loc.String())
+	f.mounts[dir] = loc
+	return nil
+}
+
+func (f *fakeRemoteMountAdmin) Unmount(_ context.Context, dir string) error {
+	f.calls = append(f.calls, "unmount "+dir)
+	delete(f.mounts, dir)
+	return nil
+}
+
+func (f *fakeRemoteMountAdmin) SyncMetadata(_ context.Context, dir string) error {
+	f.calls = append(f.calls, "sync "+dir)
+	return f.syncErr
+}
+
+func (f *fakeRemoteMountAdmin) Cache(_ context.Context, dir string, args []string) error {
+	f.calls = append(f.calls, "cache "+dir+" "+strings.Join(args, " "))
+	return nil
+}
+
+func (f *fakeRemoteMountAdmin) Uncache(_ context.Context, dir string, args []string) error {
+	f.calls = append(f.calls, "uncache "+dir+" "+strings.Join(args, " "))
+	return nil
+}
+
+func (f *fakeRemoteMountAdmin) Close() error { return nil }
+
+func testRemoteMountReconciler(t *testing.T, fa *fakeRemoteMountAdmin, objs ...client.Object) (*RemoteMountReconciler, client.Client) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("clientgoscheme: %v", err)
+	}
+	if err := seaweedv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("seaweedv1: %v", err)
+	}
+	cli := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&seaweedv1.RemoteMount{}).
+		Build()
+	r := &RemoteMountReconciler{
+		Client: cli,
+		Log:    logf.FromContext(context.Background()),
+		Scheme: scheme,
+		AdminFactory: func(_, _ string, _ grpc.DialOption, _ logr.Logger) (RemoteMountAdmin, error) {
+			return fa, nil
+		},
+	}
+	return r, cli
+}
+
+func newRemoteMountCluster() *seaweedv1.Seaweed {
+	sw := &seaweedv1.Seaweed{
+		ObjectMeta: metav1.ObjectMeta{Name: "prod", Namespace: "seaweedfs"},
+		Spec: seaweedv1.SeaweedSpec{
+			Master: &seaweedv1.MasterSpec{Replicas: 1},
+			Filer:  &seaweedv1.FilerSpec{Replicas: 1},
+		},
+	}
+	sw.Status.Filer.ReadyReplicas = 1
+	return sw
+}
+
+func newRemoteMount(namespace string) *seaweedv1.RemoteMount {
+	creds := "archive-s3"
+	return &seaweedv1.RemoteMount{
+		ObjectMeta: metav1.ObjectMeta{Name: "archive", Namespace: namespace},
+		Spec: seaweedv1.RemoteMountSpec{
+			SeaweedRef: seaweedv1.SeaweedReference{Name: "prod", Namespace: "seaweedfs"},
+			Storage: seaweedv1.BackupStorageSpec{
+				Type:              seaweedv1.BackupStorageS3,
+				S3:                &seaweedv1.S3BackupStore{Bucket: "company-archive", Region: "eu-west-1"},
+				CredentialsSecret: &creds,
+			},
+			RemotePath: "/2024/",
+			LocalDir:   "/buckets/archive",
+		},
+	}
+}
+
+func reconcileRemoteMount(t *testing.T, r *RemoteMountReconciler, rm *seaweedv1.RemoteMount) ctrl.Result {
+	t.Helper()
+	res, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(rm)})
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	return res
+}
+
+func TestRemoteMountLifecycle(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "archive-s3", Namespace: "seaweedfs"},
+		Data: map[string][]byte{
+			seaweedv1.BackupSecretKeyAWSAccessKeyID:     []byte("AKID"),
+			seaweedv1.BackupSecretKeyAWSSecretAccessKey: []byte("SECRET"),
+		},
+	}
+	rm := newRemoteMount("seaweedfs")
+	rm.Spec.Cache = &seaweedv1.RemoteMountFileFilter{Include: "*.parquet", MaxSize: ptrQuantity("1Mi")}
+	fa := newFakeRemoteMountAdmin()
+	r, cli := testRemoteMountReconciler(t, fa, newRemoteMountCluster(), secret, rm)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	r.Now = func() time.Time { return now }
+	ctx := context.Background()
+
+	reconcileRemoteMount(t, r, rm) // finalizer
+	res := reconcileRemoteMount(t, r, rm)
+	if res.RequeueAfter != defaultRemoteMountSyncInterval {
+		t.Errorf("requeue = %v, want %v", res.RequeueAfter, defaultRemoteMountSyncInterval)
+	}
+	name := remoteMountRemoteName(rm)
+	wantCalls := []string{
+		"configure " + name,
+		"mount /buckets/archive " + name + "/company-archive/2024",
+		"cache /buckets/archive -include=*.parquet -maxSize=1048576",
+	}
+	if !reflect.DeepEqual(fa.calls, wantCalls) {
+		t.Errorf("calls = %q\nwant %q", fa.calls, wantCalls)
+	}
+	wantArgs := []string{"-type=s3", "-s3.access_key=AKID", "-s3.secret_key=SECRET", "-s3.region=eu-west-1", "-s3.force_path_style=true"}
+	if !reflect.DeepEqual(fa.remotes[name], wantArgs) {
+		t.Errorf("configure args = %q, want %q", fa.remotes[name], wantArgs)
+	}
+
+	var got seaweedv1.RemoteMount
+	if err := cli.Get(ctx, client.ObjectKeyFromObject(rm), &got); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Status.Phase != seaweedv1.S3PhaseReady || got.Status.RemoteName != name || got.Status.LastSyncTime == nil {
+		t.Errorf("status = %+v", got.Status)
+	}
+	if !meta.IsStatusConditionTrue(got.Status.Conditions, seaweedv1.RemoteMountConditionSynced) {
+		t.Errorf("conditions = %+v", got.Status.Conditions)
+	}
+
+	// Before the interval elapses the mount is only re-checked, even once the
+	// filer reports the path normalised.
+	fa.mounts["/buckets/archive"] = RemoteMountLocation{Name: name, Bucket: "company-archive", Path: "/2024"}
+	fa.calls = nil
+	now = now.Add(10 * time.Minute)
+	if res := reconcileRemoteMount(t, r, rm); res.RequeueAfter != 50*time.Minute {
+		t.Errorf("requeue = %v, want 50m", res.RequeueAfter)
+	}
+	if !reflect.DeepEqual(fa.calls, []string{"configure " + name}) {
+		t.Errorf("calls = %q", fa.calls)
+	}
+
+	// A failed sync is reported on Synced while the mount stays Ready.
+	fa.calls = nil
+	fa.syncErr = errors.New("access denied")
+	now = now.Add(time.Hour)
+	reconcileRemoteMount(t, r, rm)
+	if err := cli.Get(ctx, client.ObjectKeyFromObject(rm), &got); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.RemoteMountConditionSynced)
+	if cond == nil || cond.Reason != "SyncFailed" || !meta.IsStatusConditionTrue(got.Status.Conditions, seaweedv1.RemoteMountConditionReady) {
+		t.Errorf("conditions = %+v", got.Status.Conditions)
+	}
+
+	// Deletion unmounts and drops the remote configuration.
+	fa.calls = nil
+	if err := cli.Delete(ctx, &got); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	reconcileRemoteMount(t, r, rm)
+	if !reflect.DeepEqual(fa.calls, []string{"unmount /buckets/archive", "delete " + name}) {
+		t.Errorf("calls = %q", fa.calls)
+	}
+}
+
+func TestRemoteMountDirectoryConflict(t *testing.T) {
+	rm := newRemoteMount("seaweedfs")
+	rm.Spec.Storage.CredentialsSecret = nil
+	fa := newFakeRemoteMountAdmin()
+	fa.mounts["/buckets/archive"] = RemoteMountLocation{Name: "manual", Bucket: "other", Path: "/"}
+	r, cli := testRemoteMountReconciler(t, fa, newRemoteMountCluster(), rm)
+
+	reconcileRemoteMount(t, r, rm)
+	reconcileRemoteMount(t, r, rm)
+	var got seaweedv1.RemoteMount
+	if err := cli.Get(context.Background(), client.ObjectKeyFromObject(rm), &got); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.RemoteMountConditionReady)
+	if got.Status.Phase != seaweedv1.S3PhaseFailed || cond == nil || cond.Reason != "DirectoryMounted" {
+		t.Errorf("status = %+v", got.Status)
+	}
+
+	// Someone else's mount is left alone on delete.
+	fa.calls = nil
+	if err := cli.Delete(context.Background(), &got); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	reconcileRemoteMount(t, r, rm)
+	if !reflect.DeepEqual(fa.calls, []string{"delete " + remoteMountRemoteName(rm)}) {
+		t.Errorf("calls = %q", fa.calls)
+	}
+}
+
+func TestRemoteMountCrossNamespaceNeedsGrant(t *testing.T) {
+	rm := newRemoteMount("analytics")
+	fa := newFakeRemoteMountAdmin()
+	r, cli := testRemoteMountReconciler(t, fa, newRemoteMountCluster(), rm)
+
+	reconcileRemoteMount(t, r, rm)
+	reconcileRemoteMount(t, r, rm)
+	var got seaweedv1.RemoteMount
+	if err := cli.Get(context.Background(), client.ObjectKeyFromObject(rm), &got); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.RemoteMountConditionReady)
+	if cond == nil || cond.Reason != "ReferenceNotPermitted" || len(fa.calls) != 0 {
+		t.Errorf("condition = %+v calls = %q", cond, fa.calls)
+	}
+}
+
+func TestRemoteConfigureArgs(t *testing.T) {
+	azure := seaweedv1.BackupStorageSpec{Type: seaweedv1.BackupStorageAzure, Azure: &seaweedv1.AzureBackupStore{AccountName: "acct", Container: "c"}}
+	if _, err := remoteConfigureArgs(azure, nil); err == nil {
+		t.Error("azure without an account key should fail")
+	}
+	args, err := remoteConfigureArgs(azure, map[string][]byte{seaweedv1.BackupSecretKeyAzureAccountKey: []byte("k")})
+	if want := []string{"-type=azure", "-azure.account_name=acct", "-azure.account_key=k"}; err != nil || !reflect.DeepEqual(args, want) {
+		t.Errorf("azure args = %q, %v; want %q", args, err, want)
+	}
+
+	gcs := seaweedv1.BackupStorageSpec{Type: seaweedv1.BackupStorageGCS, GCS: &seaweedv1.GCSBackupStore{Bucket: "b"}}
+	if _, err := remoteConfigureArgs(gcs, map[string][]byte{seaweedv1.BackupSecretKeyGCSCredentials: []byte("{}")}); err == nil {
+		t.Error("gcs key file should be rejected")
+	}
+	if args, err := remoteConfigureArgs(gcs, nil); err != nil || !reflect.DeepEqual(args, []string{"-type=gcs"}) {
+		t.Errorf("gcs args = %q, %v", args, err)
+	}
+}
+
+func TestParseRemoteMountMappings(t *testing.T) {
+	out := `{
+  "mappings": {
+    "/buckets/archive": {"name": "k8s01", "bucket": "company-archive", "path": "/2024"}
+  }
+}`
+	got, err := parseRemoteMountMappings(out)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := map[string]RemoteMountLocation{"/buckets/archive": {Name: "k8s01", Bucket: "company-archive", Path: "/2024"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mappings = %+v, want %+v", got, want)
+	}
+	if empty, err := parseRemoteMountMappings(""); err != nil || len(empty) != 0 {
+		t.Errorf("empty output = %+v, %v", empty, err)
+	}
+}
+
+func ptrQuantity(s string) *resource.Quantity {
+	q := resource.MustParse(s)
+	return &q
+}
@@ -0,0 +1,326 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// S3 circuit breaker reconciliation.
+//
+// The S3 gateways enforce limits stored in the filer's
+// /etc/s3/circuit_breaker.json: one global scope plus one per bucket, each
+// mapping "<action>:<type>" to a limit. The operator reads that file back to
+// detect drift and replaces a scope by writing the whole file once, in the
+// format `weed shell s3.circuitBreaker` writes. A sequence of shell commands
+// would let the gateways load a half-applied scope in between.
+
+// Circuit breaker limit types, the suffix of each action key.
+const (
+	circuitBreakerLimitCount = "Count"
+	circuitBreakerLimitMB    = "MB"
+)
+
+// S3CircuitBreakerOptions is one circuit breaker scope in the units
+// s3.circuitBreaker takes: concurrent requests for "<action>:Count" keys and
+// in-flight MiB for "<action>:MB" keys.
+type S3CircuitBreakerOptions struct {
+	Enabled bool
+	Actions map[string]int64
+}
+
+// S3CircuitBreakerConfig is the parsed circuit_breaker.json.
+type S3CircuitBreakerConfig struct {
+	Global  *S3CircuitBreakerOptions
+	Buckets map[string]*S3CircuitBreakerOptions
+}
+
+// circuitBreakerOptions converts a spec block to the options it applies, or
+// nil for a nil spec.
+func circuitBreakerOptions(spec *seaweedv1.S3CircuitBreakerSpec) (*S3CircuitBreakerOptions, error) {
+	if spec == nil {
+		return nil, nil
+	}
+	opts := &S3CircuitBreakerOptions{Enabled: spec.Enabled == nil || *spec.Enabled, Actions: map[string]int64{}}
+	for _, l := range spec.Limits {
+		if l.MaxConcurrentRequests != nil {
+			opts.Actions[string(l.Action)+":"+circuitBreakerLimitCount] = *l.MaxConcurrentRequests
+		}
+		if l.MaxConcurrentSize != nil {
+			bytes, ok := l.MaxConcurrentSize.AsInt64()
+			if !ok || bytes <= 0 {
+				return nil, fmt.Errorf("%s maxConcurrentSize %s must be a positive byte count", l.Action, l.MaxConcurrentSize.String())
+			}
+			const mib = int64(1024 * 1024)
+			opts.Actions[string(l.Action)+":"+circuitBreakerLimitMB] = (bytes + mib - 1) / mib
+		}
+	}
+	return opts, nil
+}
+
+// circuitBreakerFileOptions is one scope as stored in circuit_breaker.json.
+// The file is protojson, which quotes int64 values; json.Number decodes both
+// the quoted and the bare form.
+type circuitBreakerFileOptions struct {
+	Enabled bool                   `json:"enabled,omitempty"`
+	Actions map[string]json.Number `json:"actions,omitempty"`
+}
+
+// circuitBreakerFile is the JSON shape of circuit_breaker.json.
+type circuitBreakerFile struct {
+	Global  *circuitBreakerFileOptions            `json:"global,omitempty"`
+	Buckets map[string]*circuitBreakerFileOptions `json:"buckets,omitempty"`
+}
+
+// parseS3CircuitBreakerConfig decodes circuit_breaker.json. MB limits are
+// stored in bytes and converted back to MiB. Empty input is an empty config.
+func parseS3CircuitBreakerConfig(data []byte) (S3CircuitBreakerConfig, error) {
+	var cfg S3CircuitBreakerConfig
+	if len(data) == 0 {
+		return cfg, nil
+	}
+	var file circuitBreakerFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return cfg, fmt.Errorf("parse circuit_breaker.json: %w", err)
+	}
+	convert := func(in *circuitBreakerFileOptions) (*S3CircuitBreakerOptions, error) {
+		if in == nil {
+			return nil, nil
+		}
+		out := &S3CircuitBreakerOptions{Enabled: in.Enabled, Actions: map[string]int64{}}
+		for key, raw := range in.Actions {
+			v, err := raw.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("parse circuit_breaker.json action %q: %w", key, err)
+			}
+			if strings.HasSuffix(key, ":"+circuitBreakerLimitMB) {
+				v /= 1024 * 1024
+			}
+			out.Actions[key] = v
+		}
+		return out, nil
+	}
+	var err error
+	if cfg.Global, err = convert(file.Global); err != nil {
+		return cfg, err
+	}
+	for name, opts := range file.Buckets {
+		converted, err := convert(opts)
+		if err != nil {
+			return cfg, err
+		}
+		if converted == nil {
+			continue
+		}
+		if cfg.Buckets == nil {
+			cfg.Buckets = map[string]*S3CircuitBreakerOptions{}
+		}
+		cfg.Buckets[name] = converted
+	}
+	return cfg, nil
+}
+
+// circuitBreakerEqual reports whether two scopes apply the same limits. A nil
+// and an empty action map are equal.
+func circuitBreakerEqual(a, b *S3CircuitBreakerOptions) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if a.Enabled != b.Enabled || len(a.Actions) != len(b.Actions) {
+		return false
+	}
+	for k, v := range a.Actions {
+		if w, ok := b.Actions[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
+
+// marshalS3CircuitBreakerConfig encodes cfg as circuit_breaker.json, the
+// inverse of parseS3CircuitBreakerConfig: MB limits are stored in bytes.
+// Values are written as bare numbers, which protojson accepts for int64.
+func marshalS3CircuitBreakerConfig(cfg S3CircuitBreakerConfig) ([]byte, error) {
+	convert := func(in *S3CircuitBreakerOptions) *circuitBreakerFileOptions {
+		if in == nil {
+			return nil
+		}
+		out := &circuitBreakerFileOptions{Enabled: in.Enabled}
+		if len(in.Actions) > 0 {
+			out.Actions = make(map[string]json.Number, len(in.Actions))
+		}
+		for key, v := range in.Actions {
+			if strings.HasSuffix(key, ":"+circuitBreakerLimitMB) {
+				v *= 1024 * 1024
+			}
+			out.Actions[key] = json.Number(strconv.FormatInt(v, 10))
+		}
+		return out
+	}
+	file := circuitBreakerFile{Global: convert(cfg.Global)}
+	for name, opts := range cfg.Buckets {
+		if opts == nil {
+			continue
+		}
+		if file.Buckets == nil {
+			file.Buckets = map[string]*circuitBreakerFileOptions{}
+		}
+		file.Buckets[name] = convert(opts)
+	}
+	return json.MarshalIndent(file, "", "  ")
+}
+
+// withCircuitBreakerScope returns cfg with the scope for bucket (the global
+// one when bucket is empty) replaced by opts, or removed when opts is nil.
+// The other scopes are kept as they are.
+func withCircuitBreakerScope(cfg S3CircuitBreakerConfig, bucket string, opts *S3CircuitBreakerOptions) S3CircuitBreakerConfig {
+	if bucket == "" {
+		cfg.Global = opts
+		return cfg
+	}
+	buckets := make(map[string]*S3CircuitBreakerOptions, len(cfg.Buckets)+1)
+	for name, o := range cfg.Buckets {
+		buckets[name] = o
+	}
+	if opts == nil {
+		delete(buckets, bucket)
+	} else {
+		buckets[bucket] = opts
+	}
+	cfg.Buckets = buckets
+	if len(buckets) == 0 {
+		cfg.Buckets = nil
+	}
+	return cfg
+}
+
+// bucketStatusRateLimits mirrors an applied scope onto Bucket status.
+func bucketStatusRateLimits(opts *S3CircuitBreakerOptions) *seaweedv1.BucketStatusRateLimits {
+	if opts == nil {
+		return nil
+	}
+	out := &seaweedv1.BucketStatusRateLimits{Enabled: opts.Enabled}
+	if len(opts.Actions) > 0 {
+		out.Limits = make(map[string]int64, len(opts.Actions))
+		for k, v := range opts.Actions {
+			out.Limits[k] = v
+		}
+	}
+	return out
+}
+
+// ensureS3CircuitBreaker applies spec.s3.circuitBreaker (or
+// spec.filer.s3.circuitBreaker) as the global circuit breaker scope and
+// reports the outcome on the S3CircuitBreakerApplied condition. The filer
+// holds the config, so the step waits until one is ready; failures are
+// surfaced on the condition rather than blocking the rest of the reconcile.
+// Removing the block deletes the global scope once.
+func (r *SeaweedReconciler) ensureS3CircuitBreaker(ctx context.Context, m *seaweedv1.Seaweed) (bool, ctrl.Result, error) {
+	if r.BucketAdminFactory == nil {
+		return ReconcileResult(nil)
+	}
+	spec := s3CircuitBreakerSpec(m)
+	tracked := meta.FindStatusCondition(m.Status.Conditions, seaweedv1.SeaweedConditionS3CircuitBreakerApplied) != nil
+	if m.Spec.Filer == nil {
+		// No filer, no config to manage.
+		meta.RemoveStatusCondition(&m.Status.Conditions, seaweedv1.SeaweedConditionS3CircuitBreakerApplied)
+		return ReconcileResult(nil)
+	}
+	if (spec == nil && !tracked) || m.Status.Filer.ReadyReplicas == 0 {
+		return ReconcileResult(nil)
+	}
+
+	want, err := circuitBreakerOptions(spec)
+	if err == nil {
+		err = r.applyGlobalS3CircuitBreaker(ctx, m, want)
+	}
+	switch {
+	case err != nil:
+		r.Log.Error(err, "apply S3 circuit breaker", "seaweed", m.Name)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(m, corev1.EventTypeWarning, "S3CircuitBreakerFailed", "%v", err)
+		}
+		meta.SetStatusCondition(&m.Status.Conditions, metav1.Condition{
+			Type:               seaweedv1.SeaweedConditionS3CircuitBreakerApplied,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: m.Generation,
+			Reason:             "ApplyFailed",
+			Message:            err.Error(),
+		})
+	case spec == nil:
+		meta.RemoveStatusCondition(&m.Status.Conditions, seaweedv1.SeaweedConditionS3CircuitBreakerApplied)
+	default:
+		meta.SetStatusCondition(&m.Status.Conditions, metav1.Condition{
+			Type:               seaweedv1.SeaweedConditionS3CircuitBreakerApplied,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: m.Generation,
+			Reason:             "Applied",
+		})
+	}
+	return ReconcileResult(nil)
+}
+
+// s3CircuitBreakerSpec is the circuit breaker of the cluster's S3 gateway:
+// the standalone one, or else the filer-embedded one. The webhook rejects
+// enabling both.
+func s3CircuitBreakerSpec(m *seaweedv1.Seaweed) *seaweedv1.S3CircuitBreakerSpec {
+	if m.Spec.S3 != nil {
+		return m.Spec.S3.CircuitBreaker
+	}
+	if m.Spec.Filer != nil && m.Spec.Filer.S3 != nil && m.Spec.Filer.S3.Enabled {
+		return m.Spec.Filer.S3.CircuitBreaker
+	}
+	return nil
+}
+
+// applyGlobalS3CircuitBreaker rewrites the global scope when it differs from
+// want (nil deletes it).
+func (r *SeaweedReconciler) applyGlobalS3CircuitBreaker(ctx context.Context, m *seaweedv1.Seaweed, want *S3CircuitBreakerOptions) error {
+	adminKey, err := loadFilerAdminSigningKey(ctx, r.Client, m)
+	if err != nil {
+		return err
+	}
+	dialOption, _, err := loadSeaweedGrpcDialOption(ctx, r.Client, m)
+	if err != nil {
+		return err
+	}
+	admin, err := r.BucketAdminFactory(getMasterPeersString(m), getFilerAddress(m), adminKey, dialOption, r.Log)
+	if err != nil {
+		return err
+	}
+	defer closeBucketAdmin(admin, r.Log)
+
+	current, err := admin.GetCircuitBreaker(ctx)
+	if err != nil {
+		return err
+	}
+	if circuitBreakerEqual(current.Global, want) {
+		return nil
+	}
+	return admin.SetCircuitBreaker(ctx, "", want)
+}
@@ -0,0 +1,339 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/seaweedfs/seaweedfs/weed/filer"
+	"github.com/seaweedfs/seaweedfs/weed/pb/s3_pb"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+func int64Ptr(v int64) *int64 { return &v }
+
+func TestCircuitBreakerOptions(t *testing.T) {
+	size := resource.MustParse("1500Ki")
+	opts, err := circuitBreakerOptions(&seaweedv1.S3CircuitBreakerSpec{
+		Enabled: ptr.To(true),
+		Limits: []seaweedv1.S3ActionLimit{
+			{Action: seaweedv1.BucketAccessRead, MaxConcurrentRequests: int64Ptr(500)},
+			{Action: seaweedv1.BucketAccessWrite, MaxConcurrentRequests: int64Ptr(100), MaxConcurrentSize: &size},
+		},
+	})
+	if err != nil {
+		t.Fatalf("circuitBreakerOptions: %v", err)
+	}
+	want := &S3CircuitBreakerOptions{Enabled: true, Actions: map[string]int64{
+		"Read:Count":  500,
+		"Write:Count": 100,
+		"Write:MB":    2, // rounded up to whole MiB
+	}}
+	if !reflect.DeepEqual(opts, want) {
+		t.Errorf("options = %+v want %+v", opts, want)
+	}
+
+	zero := resource.MustParse("0")
+	if _, err := circuitBreakerOptions(&seaweedv1.S3CircuitBreakerSpec{
+		Limits: []seaweedv1.S3ActionLimit{{Action: seaweedv1.BucketAccessWrite, MaxConcurrentSize: &zero}},
+	}); err == nil {
+		t.Errorf("expected an error for a zero maxConcurrentSize")
+	}
+
+	if opts, err := circuitBreakerOptions(nil); opts != nil || err != nil {
+		t.Errorf("nil spec = %+v, %v; want nil, nil", opts, err)
+	}
+
+	if opts, _ := circuitBreakerOptions(&seaweedv1.S3CircuitBreakerSpec{}); !opts.Enabled {
+		t.Errorf("unset enabled should default to true")
+	}
+	if opts, _ := circuitBreakerOptions(&seaweedv1.S3CircuitBreakerSpec{Enabled: ptr.To(false)}); opts.Enabled {
+		t.Errorf("enabled: false was not honoured")
+	}
+}
+
+func TestS3CircuitBreakerSpec(t *testing.T) {
+	spec := &seaweedv1.S3CircuitBreakerSpec{Enabled: ptr.To(false)}
+	sw := newTestSeaweedWithFiler()
+	if s3CircuitBreakerSpec(sw) != nil {
+		t.Errorf("expected no circuit breaker without an S3 gateway")
+	}
+	sw.Spec.Filer.S3 = &seaweedv1.S3Config{Enabled: true, CircuitBreaker: spec}
+	if s3CircuitBreakerSpec(sw) != spec {
+		t.Errorf("expected the filer-embedded gateway's circuit breaker")
+	}
+	sw.Spec.Filer.S3.Enabled = false
+	if s3CircuitBreakerSpec(sw) != nil {
+		t.Errorf("expected a disabled embedded gateway to carry no circuit breaker")
+	}
+	sw.Spec.S3 = &seaweedv1.S3GatewaySpec{CircuitBreaker: spec}
+	if s3CircuitBreakerSpec(sw) != spec {
+		t.Errorf("expected the standalone gateway's circuit breaker")
+	}
+}
+
+func TestMarshalS3CircuitBreakerConfig(t *testing.T) {
+	cfg := S3CircuitBreakerConfig{
+		Global: &S3CircuitBreakerOptions{Enabled: true, Actions: map[string]int64{"Read:Count": 500}},
+		Buckets: map[string]*S3CircuitBreakerOptions{
+			"photos": {Enabled: true, Actions: map[string]int64{"Read:Count": 10, "Write:Count": 20}},
+		},
+	}
+
+	// Replacing a scope swaps it whole, dropping limits no longer listed,
+	// and keeps the other scopes.
+	next := withCircuitBreakerScope(cfg, "photos", &S3CircuitBreakerOptions{Enabled: false, Actions: map[string]int64{"Write:MB": 64}})
+	data, err := marshalS3CircuitBreakerConfig(next)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"Write:MB": 67108864`) {
+		t.Errorf("MB limits must be stored in bytes:\n%s", data)
+	}
+	got, err := parseS3CircuitBreakerConfig(data)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := S3CircuitBreakerConfig{
+		Global: cfg.Global,
+		Buckets: map[string]*S3CircuitBreakerOptions{
+			"photos": {Enabled: false, Actions: map[string]int64{"Write:MB": 64}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %+v\nwant %+v", got, want)
+	}
+	if len(cfg.Buckets["photos"].Actions) != 2 {
+		t.Errorf("withCircuitBreakerScope modified its input")
+	}
+
+	// Deleting the last bucket scope leaves only the global one.
+	data, err = marshalS3CircuitBreakerConfig(withCircuitBreakerScope(cfg, "photos", nil))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if got, _ := parseS3CircuitBreakerConfig(data); got.Buckets != nil || !circuitBreakerEqual(got.Global, cfg.Global) {
+		t.Errorf("after delete = %+v", got)
+	}
+	data, _ = marshalS3CircuitBreakerConfig(withCircuitBreakerScope(cfg, "", nil))
+	if got, _ := parseS3CircuitBreakerConfig(data); got.Global != nil || got.Buckets["photos"] == nil {
+		t.Errorf("after global delete = %+v", got)
+	}
+}
+
+// The operator writes circuit_breaker.json itself rather than through
+// s3.circuitBreaker, so its document has to decode, with the gateway's own
+// parser, to the config the command would have written for the same limits.
+func TestMarshalS3CircuitBreakerConfigMatchesShell(t *testing.T) {
+	cfg := S3CircuitBreakerConfig{
+		Global: &S3CircuitBreakerOptions{Enabled: true, Actions: map[string]int64{"Read:Count": 500, "Write:MB": 64}},
+		Buckets: map[string]*S3CircuitBreakerOptions{
+			"photos": {Enabled: false, Actions: map[string]int64{"Write:Count": 20}},
+		},
+	}
+	data, err := marshalS3CircuitBreakerConfig(cfg)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	// `s3.circuitBreaker -global -type Count -actions Read -values 500 -apply`
+	// and its siblings build this message (MB values in bytes) and save it
+	// with filer.ProtoToText.
+	shell := &s3_pb.S3CircuitBreakerConfig{
+		Global: &s3_pb.S3CircuitBreakerOptions{Enabled: true, Actions: map[string]int64{"Read:Count": 500, "Write:MB": 64 << 20}},
+		Buckets: map[string]*s3_pb.S3CircuitBreakerOptions{
+			"photos": {Enabled: false, Actions: map[string]int64{"Write:Count": 20}},
+		},
+	}
+	var shellText bytes.Buffer
+	if err := filer.ProtoToText(&shellText, shell); err != nil {
+		t.Fatalf("ProtoToText: %v", err)
+	}
+
+	got := &s3_pb.S3CircuitBreakerConfig{}
+	if err := filer.ParseS3ConfigurationFromBytes(data, got); err != nil {
+		t.Fatalf("gateway parse of operator document: %v\n%s", err, data)
+	}
+	if !proto.Equal(got, shell) {
+		t.Errorf("operator document decodes to %v\nwant the shell's %v", got, shell)
+	}
+
+	// The operator reads back what the shell writes.
+	back, err := parseS3CircuitBreakerConfig(shellText.Bytes())
+	if err != nil {
+		t.Fatalf("parse shell document: %v\n%s", err, shellText.Bytes())
+	}
+	if !reflect.DeepEqual(back, cfg) {
+		t.Errorf("shell document parses to %+v\nwant %+v", back, cfg)
+	}
+}
+
+func TestParseS3CircuitBreakerConfig(t *testing.T) {
+	// protojson quotes int64 values; MB limits are stored in bytes.
+	data := []byte(`{
+  "global": {"enabled": true, "actions": {"Read:Count": "500", "Write:MB": "104857600"}},
+  "buckets": {"photos": {"enabled": false, "actions": {"List:Count": 5}}}
+}`)
+	cfg, err := parseS3CircuitBreakerConfig(data)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := S3CircuitBreakerConfig{
+		Global: &S3CircuitBreakerOptions{Enabled: true, Actions: map[string]int64{"Read:Count": 500, "Write:MB": 100}},
+		Buckets: map[string]*S3CircuitBreakerOptions{
+			"photos": {Actions: map[string]int64{"List:Count": 5}},
+		},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("config = %+v want %+v", cfg, want)
+	}
+
+	if cfg, err := parseS3CircuitBreakerConfig(nil); err != nil || cfg.Global != nil || cfg.Buckets != nil {
+		t.Errorf("empty input = %+v, %v; want zero config", cfg, err)
+	}
+}
+
+func TestCircuitBreakerEqual(t *testing.T) {
+	a := &S3CircuitBreakerOptions{Enabled: true, Actions: map[string]int64{"Read:Count": 1}}
+	b := &S3CircuitBreakerOptions{Enabled: true, Actions: map[string]int64{"Read:Count": 1}}
+	if !circuitBreakerEqual(a, b) {
+		t.Errorf("identical options reported unequal")
+	}
+	b.Actions["Read:Count"] = 2
+	if circuitBreakerEqual(a, b) {
+		t.Errorf("differing limit reported equal")
+	}
+	if circuitBreakerEqual(a, nil) || !circuitBreakerEqual(nil, nil) {
+		t.Errorf("nil handling wrong")
+	}
+	if !circuitBreakerEqual(&S3CircuitBreakerOptions{}, &S3CircuitBreakerOptions{Actions: map[string]int64{}}) {
+		t.Errorf("nil and empty action maps reported unequal")
+	}
+}
+
+func TestReconcile_RateLimitsAppliedOnlyOnDrift(t *testing.T) {
+	bucket := newTestBucket("photos")
+	bucket.Spec.RateLimits = &seaweedv1.S3CircuitBreakerSpec{
+		Enabled: ptr.To(true),
+		Limits:  []seaweedv1.S3ActionLimit{{Action: seaweedv1.BucketAccessRead, MaxConcurrentRequests: int64Ptr(50)}},
+	}
+	fa := newFakeAdmin()
+	r, cli := testReconciler(t, fa, newTestSeaweed(), bucket)
+	key := types.NamespacedName{Namespace: bucket.Namespace, Name: bucket.Name}
+
+	reconcileUntilStable(t, r, key, 5)
+	if got := callsFor(fa.calls, "SetCircuitBreaker:"); !reflect.DeepEqual(got, []string{"SetCircuitBreaker:photos:t"}) {
+		t.Fatalf("set calls = %v", got)
+	}
+	got := &seaweedv1.Bucket{}
+	if err := cli.Get(context.Background(), key, got); err != nil {
+		t.Fatalf("get bucket: %v", err)
+	}
+	want := &seaweedv1.BucketStatusRateLimits{Enabled: true, Limits: map[string]int64{"Read:Count": 50}}
+	if !reflect.DeepEqual(got.Status.RateLimits, want) {
+		t.Errorf("status.rateLimits = %+v want %+v", got.Status.RateLimits, want)
+	}
+
+	// In sync: a resync pass reads but does not rewrite.
+	fa.calls = nil
+	reconcileUntilStable(t, r, key, 5)
+	if got := callsFor(fa.calls, "SetCircuitBreaker:"); len(got) != 0 {
+		t.Errorf("unexpected rewrite without drift: %v", got)
+	}
+
+	// Lost out of band: reapplied.
+	delete(fa.circuitBreaker.Buckets, "photos")
+	reconcileUntilStable(t, r, key, 5)
+	if got := callsFor(fa.calls, "SetCircuitBreaker:"); !reflect.DeepEqual(got, []string{"SetCircuitBreaker:photos:t"}) {
+		t.Errorf("drift not healed: %v", got)
+	}
+}
+
+func TestReconcile_RateLimitsRemovedDeletesScope(t *testing.T) {
+	bucket := newTestBucket("photos")
+	bucket.Finalizers = []string{BucketFinalizer}
+	bucket.Status.BucketName = "photos"
+	bucket.Status.RateLimits = &seaweedv1.BucketStatusRateLimits{Enabled: true, Limits: map[string]int64{"Read:Count": 50}}
+	fa := newFakeAdmin()
+	fa.existsResp["photos"] = true
+	fa.circuitBreaker.Buckets = map[string]*S3CircuitBreakerOptions{
+		"photos": {Enabled: true, Actions: map[string]int64{"Read:Count": 50}},
+	}
+	r, cli := testReconciler(t, fa, newTestSeaweed(), bucket)
+	key := types.NamespacedName{Namespace: bucket.Namespace, Name: bucket.Name}
+
+	reconcileUntilStable(t, r, key, 5)
+	if got := callsFor(fa.calls, "SetCircuitBreaker:"); !reflect.DeepEqual(got, []string{"SetCircuitBreaker:photos:f"}) {
+		t.Errorf("set calls = %v want a single delete", got)
+	}
+	got := &seaweedv1.Bucket{}
+	if err := cli.Get(context.Background(), key, got); err != nil {
+		t.Fatalf("get bucket: %v", err)
+	}
+	if got.Status.RateLimits != nil {
+		t.Errorf("status.rateLimits = %+v want nil", got.Status.RateLimits)
+	}
+}
+
+func TestEnsureS3CircuitBreaker(t *testing.T) {
+	sw := newTestSeaweedWithFiler()
+	sw.Spec.S3 = &seaweedv1.S3GatewaySpec{CircuitBreaker: &seaweedv1.S3CircuitBreakerSpec{
+		Enabled: ptr.To(true),
+		Limits:  []seaweedv1.S3ActionLimit{{Action: seaweedv1.BucketAccessWrite, MaxConcurrentRequests: int64Ptr(8)}},
+	}}
+	sw.Status.Filer.ReadyReplicas = 1
+	r, _ := newNotificationReconciler(t)
+	fa := newFakeAdmin()
+	r.BucketAdminFactory = func(_, _ string, _ []byte, _ grpc.DialOption, _ logr.Logger) (BucketAdmin, error) {
+		return fa, nil
+	}
+
+	if done, _, err := r.ensureS3CircuitBreaker(context.Background(), sw); done || err != nil {
+		t.Fatalf("ensureS3CircuitBreaker = %v, %v", done, err)
+	}
+	want := &S3CircuitBreakerOptions{Enabled: true, Actions: map[string]int64{"Write:Count": 8}}
+	if !reflect.DeepEqual(fa.circuitBreaker.Global, want) {
+		t.Errorf("global = %+v want %+v", fa.circuitBreaker.Global, want)
+	}
+	if !meta.IsStatusConditionTrue(sw.Status.Conditions, seaweedv1.SeaweedConditionS3CircuitBreakerApplied) {
+		t.Errorf("expected %s=True, got %+v", seaweedv1.SeaweedConditionS3CircuitBreakerApplied, sw.Status.Conditions)
+	}
+
+	// Apply failures surface on the condition without failing the reconcile.
+	fa.circuitBreaker.Global = nil
+	fa.circuitBreakerErr = errors.New("filer unavailable")
+	if done, _, err := r.ensureS3CircuitBreaker(context.Background(), sw); done || err != nil {
+		t.Fatalf("ensureS3CircuitBreaker on failure = %v, %v", done, err)
+	}
+	cond := meta.FindStatusCondition(sw.Status.Conditions, seaweedv1.SeaweedConditionS3CircuitBreakerApplied)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != "ApplyFailed" {
+		t.Errorf("condition = %+v want False/ApplyFailed", cond)
+	}
+
+	// Removing the block deletes the global scope and the condition.
+	fa.circuitBreakerErr = nil
+	fa.circuitBreaker.Global = want
+	fa.calls = nil
+	sw.Spec.S3.CircuitBreaker = nil
+	if _, _, err := r.ensureS3CircuitBreaker(context.Background(), sw); err != nil {
+		t.Fatalf("ensureS3CircuitBreaker on removal: %v", err)
+	}
+	if fa.circuitBreaker.Global != nil || !reflect.DeepEqual(callsFor(fa.calls, "SetCircuitBreaker:"), []string{"SetCircuitBreaker::f"}) {
+		t.Errorf("global scope not deleted: global=%+v calls=%v", fa.circuitBreaker.Global, fa.calls)
+	}
+	if meta.FindStatusCondition(sw.Status.Conditions, seaweedv1.SeaweedConditionS3CircuitBreakerApplied) != nil {
+		t.Errorf("condition should be removed once the scope is deleted")
+	}
+}
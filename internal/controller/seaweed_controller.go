@@ -24,6 +24,7 @@ import (
 
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -32,6 +33,7 @@ import (
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -95,6 +97,24 @@ type SeaweedReconciler struct {
 	// servers before a scale-down removes them. Defaulted in SetupWithManager;
 	// tests inject a fake.
 	VolumeAdminFactory VolumeAdminFactory
+	// BucketAdminFactory builds the filer-side admin used to apply
+	// spec.s3.circuitBreaker. Defaulted in SetupWithManager; nil skips the
+	// step.
+	BucketAdminFactory BucketAdminFactory
+	// AdminMaintenanceFactory builds the admin server client used to apply
+	// spec.admin.tasks. Defaulted in SetupWithManager; nil skips the step.
+	AdminMaintenanceFactory AdminMaintenanceClientFactory
+	// BackupToolImage is the image metadata-log Deployments and storage
+	// probe Jobs run the operator's backup-tool from (see
+	// ResolveBackupToolImage). Empty skips spec.backup.metadataLog, and
+	// leaves storages unprobed, with a warning event.
+	BackupToolImage string
+	// PodLogs reads data mirror logs to count the errors filer.backup
+	// reports. Nil leaves the mirrors' ErrorCount unreported.
+	PodLogs PodLogReader
+	// Now returns the current time when rotating generated SFTP host keys.
+	// Tests pin it; nil uses time.Now.
+	Now func() time.Time
 	// evac tracks in-flight background volume server evacuations.
 	evac *evacuationTracker
 }
@@ -105,9 +125,11 @@ type SeaweedReconciler struct {
 // +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=core,resources=pods/log,verbs=get
 // +kubebuilder:rbac:groups=extensions,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;list;watch;create;update;patch;delete
@@ -171,10 +193,28 @@ func (r *SeaweedReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		}
 	}
 
+	// Applied through the admin server; failures land on a condition, never block.
+	if done, result, err = r.ensureAdminTasks(ctx, seaweedCR); done {
+		return result, err
+	}
+
 	if done, result, err = r.ensureS3Gateway(ctx, seaweedCR); done {
 		return result, err
 	}
 
+	// Applied through the filer; failures land on a condition, never block.
+	if done, result, err = r.ensureS3CircuitBreaker(ctx, seaweedCR); done {
+		return result, err
+	}
+
+	// Driven through the master; failures land on a condition, never block.
+	if done, result, err = r.ensureErasureCoding(ctx, seaweedCR); done {
+		return result, err
+	}
+	if done, result, err = r.ensureRemoteTiers(ctx, seaweedCR); done {
+		return result, err
+	}
+
 	if done, result, err = r.ensureSFTPGateway(ctx, seaweedCR); done {
 		return result, err
 	}
@@ -184,6 +224,12 @@ func (r *SeaweedReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	if done, result, err = r.ensureBackupMirrors(ctx, seaweedCR); done {
 		return result, err
 	}
+	if done, result, err = r.ensureBackupMetaLogs(ctx, seaweedCR); done {
+		return result, err
+	}
+	if done, result, err = r.ensureBackupStorageProbes(ctx, seaweedCR); done {
+		return result, err
+	}
 
 	if done, result, err = r.ensureSeaweedIngress(seaweedCR); done {
 		return result, err
@@ -240,11 +286,22 @@ func (r *SeaweedReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	if r.VolumeAdminFactory == nil {
 		r.VolumeAdminFactory = NewSwadminVolumeAdmin
 	}
+	if r.BucketAdminFactory == nil {
+		r.BucketAdminFactory = NewSwadminBucketAdmin
+	}
+	if r.AdminMaintenanceFactory == nil {
+		r.AdminMaintenanceFactory = NewHTTPAdminMaintenanceClient
+	}
 	if r.evac == nil {
 		r.evac = newEvacuationTracker()
 	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&seaweedv1.Seaweed{}).
+		// Storage probe Jobs report back on completion.
+		Owns(&batchv1.Job{}).
+		// The SFTPUser controller writes the generated user store; roll
+		// the gateway when it changes.
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(mapSFTPUserStoreToSeaweed)).
 		Complete(r)
 }
 
@@ -314,6 +371,11 @@ func (r *SeaweedReconciler) updateStatus(ctx context.Context, seaweedCR *seaweed
 		log.Error(err, "Failed to get SFTP gateway status")
 		return false, err
 	}
+	sftpHostKeys, err := r.sftpHostKeysStatus(ctx, seaweedCR)
+	if err != nil {
+		log.Error(err, "Failed to get SFTP host keys status")
+		return false, err
+	}
 
 	// Determine if cluster is ready
 	// Master must have replicas and all must be ready
@@ -373,6 +435,7 @@ func (r *SeaweedReconciler) updateStatus(ctx context.Context, seaweedCR *seaweed
 	seaweedCR.Status.S3 = s3Status
 	// Same rationale as S3 above.
 	seaweedCR.Status.SFTP = sftpStatus
+	seaweedCR.Status.SFTPHostKeys = sftpHostKeys
 
 	// Build informative status message (for NotReady condition)
 	notReadyMessage := strings.Join([]string{
@@ -446,6 +509,9 @@ func (r *SeaweedReconciler) getComponentStatus(ctx context.Context, seaweedCR *s
 		}
 	case ComponentWorker:
 		if seaweedCR.Spec.Worker != nil {
+			if len(seaweedCR.Spec.Worker.Pools) > 0 {
+				return r.getWorkerPoolsStatus(ctx, seaweedCR)
+			}
 			return r.getDeploymentStatus(ctx, seaweedCR.Namespace, seaweedCR.Name+"-worker", seaweedCR.Spec.Worker.Replicas)
 		}
 	}
@@ -506,6 +572,24 @@ func (r *SeaweedReconciler) getDeploymentStatus(ctx context.Context, namespace,
 	return status, nil
 }
 
+// getWorkerPoolsStatus sums the replica counts of every worker pool.
+func (r *SeaweedReconciler) getWorkerPoolsStatus(ctx context.Context, seaweedCR *seaweedv1.Seaweed) (seaweedv1.ComponentStatus, error) {
+	status := seaweedv1.ComponentStatus{}
+	for _, pool := range seaweedCR.Spec.Worker.Pools {
+		desired := seaweedCR.Spec.Worker.Replicas
+		if pool.Replicas != nil {
+			desired = *pool.Replicas
+		}
+		poolStatus, err := r.getDeploymentStatus(ctx, seaweedCR.Namespace, workerPoolDeploymentName(seaweedCR.Name, pool.Name), desired)
+		if err != nil {
+			return status, err
+		}
+		status.Replicas += poolStatus.Replicas
+		status.ReadyReplicas += poolStatus.ReadyReplicas
+	}
+	return status, nil
+}
+
 func (r *SeaweedReconciler) getVolumeStatus(ctx context.Context, seaweedCR *seaweedv1.Seaweed) (seaweedv1.ComponentStatus, error) {
 	status := seaweedv1.ComponentStatus{}
 
@@ -18,9 +18,14 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/go-logr/logr"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -32,21 +37,61 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/internal/backuptool"
 )
 
 // SeaweedBackupReconciler turns a SeaweedBackup into a one-shot `fs.meta.save`
-// snapshot Job and tracks the Job's outcome on the CR's status.
+// snapshot Job, a Job copying the cluster's volumes, or a set of
+// VolumeSnapshots of the cluster's claims, and tracks the outcome on the CR's
+// status, running the backup's pre and post hooks around it. With
+// deletionPolicy Delete it also removes the snapshot from the storage, through
+// a cleanup Job, before the CR goes away.
 type SeaweedBackupReconciler struct {
 	client.Client
 	Log      logr.Logger
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+
+	// BackupToolImage is the image the upload container of snapshot Jobs, and
+	// object-store cleanup Jobs, run (see ResolveBackupToolImage). Empty
+	// leaves backups Pending.
+	BackupToolImage string
+
+	// AdminFactory creates the BucketAdmin filerReadOnly hooks edit
+	// filer.conf through. Tests inject a fake; SetupWithManager defaults it
+	// to NewSwadminBucketAdmin.
+	AdminFactory BucketAdminFactory
+
+	// VolumeAdminFactory creates the VolumeAdmin volumeSnapshots backups
+	// hold the cluster's volumes read-only through. Tests inject a fake;
+	// SetupWithManager defaults it to NewSwadminVolumeAdmin.
+	VolumeAdminFactory VolumeAdminFactory
+
+	// PodExecutor runs exec hooks. Nil fails them.
+	PodExecutor PodExecutor
+
+	// ExecHookNamespaces lists the namespaces whose backups may run exec
+	// hooks; "*" allows every namespace. Exec hooks elsewhere fail without
+	// running: the operator's pods/exec permission would otherwise let anyone
+	// who can create a SeaweedBackup run commands in pods they cannot exec
+	// into themselves.
+	ExecHookNamespaces []string
+
+	// HookHTTPClient sends the requests of http hooks; nil uses
+	// http.DefaultClient.
+	HookHTTPClient *http.Client
 }
 
 // +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=seaweedbackups,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=seaweedbackups/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=seaweedbackups/finalizers,verbs=update
 // +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=pods/exec,verbs=create
+// +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=snapshot.storage.k8s.io,resources=volumesnapshots,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=snapshot.storage.k8s.io,resources=volumesnapshotclasses,verbs=get
 
 // Reconcile implements the SeaweedBackup snapshot lifecycle.
 func (r *SeaweedBackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -57,42 +102,158 @@ func (r *SeaweedBackupReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	// Terminal: nothing more to do. The owned Job is retained for log access
-	// and GC'd with the CR.
+	if !backup.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(&backup, SeaweedBackupFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		if err := r.releaseOnDelete(ctx, &backup); err != nil {
+			return ctrl.Result{}, err
+		}
+		if backup.Spec.DeletionPolicy == seaweedv1.BackupDeletionDelete {
+			if done, err := r.deleteArtifact(ctx, &backup); err != nil || !done {
+				return ctrl.Result{RequeueAfter: backupRequeue}, err
+			}
+		}
+		if err := deleteCrossNamespaceJob(ctx, r.Client, backup.Namespace, backup.Status.JobNamespace, backup.Status.JobName); err != nil {
+			return ctrl.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(&backup, SeaweedBackupFinalizer)
+		return ctrl.Result{}, r.Update(ctx, &backup)
+	}
+
+	// The artifact can only be cleaned up, and read-only filer paths and
+	// volumes released, while the CR is still around.
+	needsFinalizer := backup.Spec.DeletionPolicy == seaweedv1.BackupDeletionDelete || hasFilerReadOnlyHook(backup.Spec.PreHooks) ||
+		backup.Spec.Target == seaweedv1.BackupTargetVolumeSnapshots
+	if needsFinalizer && !controllerutil.ContainsFinalizer(&backup, SeaweedBackupFinalizer) {
+		controllerutil.AddFinalizer(&backup, SeaweedBackupFinalizer)
+		if err := r.Update(ctx, &backup); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	// Terminal: nothing more to do. The Job is retained for log access and
+	// removed with the CR.
 	if backup.Status.Phase == seaweedv1.BackupPhaseCompleted || backup.Status.Phase == seaweedv1.BackupPhaseFailed {
 		return ctrl.Result{}, nil
 	}
 
+	clusterNS := backup.Spec.ClusterNamespace
+	if clusterNS == "" {
+		clusterNS = backup.Namespace
+	}
+	crossNamespace := clusterNS != backup.Namespace
+	if crossNamespace {
+		ref := seaweedv1.SeaweedReference{Name: backup.Spec.ClusterName, Namespace: clusterNS}
+		permitted, err := seaweedRefPermitted(ctx, r.Client, ref, kindSeaweedBackup, backup.Namespace)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !permitted {
+			msg := seaweedRefDeniedMessage(ref, kindSeaweedBackup, backup.Namespace)
+			meta.SetStatusCondition(&backup.Status.Conditions, metav1.Condition{
+				Type: seaweedv1.BackupConditionReferenceGranted, Status: metav1.ConditionFalse,
+				ObservedGeneration: backup.Generation, Reason: "ReferenceGrantMissing", Message: msg,
+			})
+			return r.pending(ctx, &backup, "ReferenceGrantMissing", msg)
+		}
+		if !controllerutil.ContainsFinalizer(&backup, SeaweedBackupFinalizer) {
+			controllerutil.AddFinalizer(&backup, SeaweedBackupFinalizer)
+			if err := r.Update(ctx, &backup); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{Requeue: true}, nil
+		}
+		meta.SetStatusCondition(&backup.Status.Conditions, metav1.Condition{
+			Type: seaweedv1.BackupConditionReferenceGranted, Status: metav1.ConditionTrue,
+			ObservedGeneration: backup.Generation, Reason: "Granted",
+			Message: fmt.Sprintf("a ResourceReferenceGrant in namespace %q permits the reference to cluster %q", clusterNS, ref.Name),
+		})
+	} else {
+		meta.RemoveStatusCondition(&backup.Status.Conditions, seaweedv1.BackupConditionReferenceGranted)
+	}
+
 	// Resolve the cluster and the named storage.
 	var cluster seaweedv1.Seaweed
-	if err := r.Get(ctx, types.NamespacedName{Namespace: backup.Namespace, Name: backup.Spec.ClusterName}, &cluster); err != nil {
+	if err := r.Get(ctx, types.NamespacedName{Namespace: clusterNS, Name: backup.Spec.ClusterName}, &cluster); err != nil {
 		if apierrors.IsNotFound(err) {
 			return r.pending(ctx, &backup, "ClusterNotFound",
-				"Seaweed cluster "+backup.Spec.ClusterName+" not found in namespace "+backup.Namespace)
+				"Seaweed cluster "+backup.Spec.ClusterName+" not found in namespace "+clusterNS)
 		}
 		return ctrl.Result{}, err
 	}
+	// VolumeSnapshots are taken in place; no storage or Job is involved.
+	if backup.Spec.Target == seaweedv1.BackupTargetVolumeSnapshots {
+		return r.reconcileVolumeSnapshots(ctx, &backup, &cluster)
+	}
 	st, err := resolveStorage(&cluster, backup.Spec.StorageName)
 	if err != nil {
 		return r.pending(ctx, &backup, "StorageNotFound", err.Error())
 	}
+	if r.BackupToolImage == "" {
+		return r.pending(ctx, &backup, "BackupToolUnavailable",
+			"storing a snapshot needs the operator's backup-tool image; set "+BackupToolImageEnv+" on the operator")
+	}
+
+	volumes := backup.Spec.Target == seaweedv1.BackupTargetVolumes
+	what := "metadata snapshot"
+	if volumes {
+		what = "volumes backup"
+	}
 
-	jobName := boundedName(backup.Name, "-bkp")
+	jobName := backupJobName(backup.Namespace, clusterNS, backup.Name, "-bkp")
 	var job batchv1.Job
-	err = r.Get(ctx, types.NamespacedName{Namespace: backup.Namespace, Name: jobName}, &job)
+	err = r.Get(ctx, types.NamespacedName{Namespace: clusterNS, Name: jobName}, &job)
 	switch {
 	case apierrors.IsNotFound(err):
-		built, dest := buildSnapshotJob(&cluster, jobName, &backup, st)
-		if err := controllerutil.SetControllerReference(&backup, built, r.Scheme); err != nil {
+		// Volumes backups of a cluster on a storage share segments and an
+		// index, so they run one at a time, each against the last.
+		var base *seaweedv1.SeaweedBackup
+		if volumes {
+			others, err := r.volumesBackupsOf(ctx, &backup, clusterNS)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if busy := busyVolumesBackup(others); busy != "" {
+				return r.pending(ctx, &backup, "VolumesBackupBusy", busy)
+			}
+			base = latestVolumesBackup(others)
+		}
+
+		if err := r.runPreHooks(ctx, &backup, &cluster); err != nil {
 			return ctrl.Result{}, err
 		}
+		if c := meta.FindStatusCondition(backup.Status.Conditions, seaweedv1.BackupConditionPreHooks); c != nil && c.Status == metav1.ConditionFalse {
+			return r.preHooksFailed(ctx, &backup, &cluster, c.Message)
+		}
+
+		var built *batchv1.Job
+		var dest string
+		if volumes {
+			previous := ""
+			backup.Status.Volumes = &seaweedv1.BackupVolumesStatus{}
+			if base != nil {
+				previous = backupSnapshotName(base)
+				backup.Status.Volumes.Base = qualifiedBackupName(base, backup.Namespace)
+			}
+			built, dest = buildVolumeBackupJob(&cluster, jobName, &backup, st, previous, r.BackupToolImage)
+		} else {
+			built, dest = buildSnapshotJob(&cluster, jobName, &backup, st, r.BackupToolImage)
+		}
+		if !crossNamespace {
+			if err := controllerutil.SetControllerReference(&backup, built, r.Scheme); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
 		if err := r.Create(ctx, built); err != nil && !apierrors.IsAlreadyExists(err) {
 			return ctrl.Result{}, err
 		}
-		log.Info("created snapshot job", "job", jobName, "storage", backup.Spec.StorageName)
+		log.Info("created snapshot job", "job", jobName, "namespace", clusterNS, "storage", backup.Spec.StorageName, "target", backup.Spec.Target)
 		now := metav1.Now()
 		backup.Status.Phase = seaweedv1.BackupPhaseRunning
 		backup.Status.JobName = jobName
+		backup.Status.JobNamespace = clusterNS
 		backup.Status.Destination = dest
 		backup.Status.StartTime = &now
 		backup.Status.ObservedGeneration = backup.Generation
@@ -100,7 +261,15 @@ func (r *SeaweedBackupReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 			Type: seaweedv1.BackupConditionClusterReachable, Status: metav1.ConditionTrue,
 			ObservedGeneration: backup.Generation, Reason: "Reachable", Message: "snapshot job created",
 		})
-		return ctrl.Result{}, r.Status().Update(ctx, &backup)
+		if err := r.Status().Update(ctx, &backup); err != nil {
+			return ctrl.Result{}, err
+		}
+		// A Job in another namespace is not owned, so its progress does not
+		// trigger a reconcile; poll instead.
+		if crossNamespace {
+			return ctrl.Result{RequeueAfter: backupRequeue}, nil
+		}
+		return ctrl.Result{}, nil
 	case err != nil:
 		return ctrl.Result{}, err
 	}
@@ -117,16 +286,57 @@ func (r *SeaweedBackupReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{RequeueAfter: backupRequeue}, nil
 	}
 
+	postFailure, err := r.finishHooks(ctx, &backup, &cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
 	now := metav1.Now()
 	backup.Status.CompletionTime = &now
 	if success {
+		// The report is informational: a pod already garbage-collected leaves
+		// the destination computed at Job creation and no size or checksum,
+		// and restores then verify against the manifest alone.
+		if report, err := r.snapshotReport(ctx, &job); err != nil {
+			log.Error(err, "reading snapshot upload report", "job", jobName)
+		} else {
+			backup.Status.Destination = report.URI
+			backup.Status.Size = report.Size
+			backup.Status.Checksum = "sha256:" + report.SHA256
+			backup.Status.Manifest = report.Manifest
+			backup.Status.Encryption = report.Encryption
+			if v := report.Volumes; v != nil {
+				if backup.Status.Volumes == nil {
+					backup.Status.Volumes = &seaweedv1.BackupVolumesStatus{}
+				}
+				backup.Status.Volumes.Count = int32(v.Count)
+				backup.Status.Volumes.Uploaded = v.Uploaded
+				backup.Status.Volumes.Skipped = int32(v.Skipped)
+				if v.Skipped > 0 {
+					r.Recorder.Eventf(&backup, "Warning", "VolumesSkipped",
+						"%d erasure-coded or remote-tier volumes were not copied; the manifest lists them", v.Skipped)
+				}
+			}
+		}
+	}
+	switch {
+	case success && postFailure != "":
+		// The snapshot is stored, but a post hook the backup depends on
+		// failed; it is not offered for restores.
+		backup.Status.Phase = seaweedv1.BackupPhaseFailed
+		meta.SetStatusCondition(&backup.Status.Conditions, metav1.Condition{
+			Type: seaweedv1.BackupConditionComplete, Status: metav1.ConditionFalse,
+			ObservedGeneration: backup.Generation, Reason: "PostHookFailed", Message: what + " stored, but a post hook failed: " + postFailure,
+		})
+		r.Recorder.Event(&backup, "Warning", "BackupFailed", "post hook failed: "+postFailure)
+	case success:
 		backup.Status.Phase = seaweedv1.BackupPhaseCompleted
 		meta.SetStatusCondition(&backup.Status.Conditions, metav1.Condition{
 			Type: seaweedv1.BackupConditionComplete, Status: metav1.ConditionTrue,
-			ObservedGeneration: backup.Generation, Reason: "SnapshotComplete", Message: "metadata snapshot completed",
+			ObservedGeneration: backup.Generation, Reason: "SnapshotComplete", Message: what + " completed",
 		})
-		r.Recorder.Event(&backup, "Normal", "BackupCompleted", "metadata snapshot completed")
-	} else {
+		r.Recorder.Event(&backup, "Normal", "BackupCompleted", what+" completed")
+	default:
 		backup.Status.Phase = seaweedv1.BackupPhaseFailed
 		meta.SetStatusCondition(&backup.Status.Conditions, metav1.Condition{
 			Type: seaweedv1.BackupConditionComplete, Status: metav1.ConditionFalse,
@@ -137,6 +347,30 @@ func (r *SeaweedBackupReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	return ctrl.Result{}, r.Status().Update(ctx, &backup)
 }
 
+// preHooksFailed ends a backup whose pre hooks failed without taking the
+// snapshot. Read-only paths are released and the post hooks still run, so
+// whatever the pre hooks paused is resumed.
+func (r *SeaweedBackupReconciler) preHooksFailed(ctx context.Context, backup *seaweedv1.SeaweedBackup, cluster *seaweedv1.Seaweed, failure string) (ctrl.Result, error) {
+	postFailure, err := r.finishHooks(ctx, backup, cluster)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	msg := "pre hooks failed, snapshot skipped: " + failure
+	if postFailure != "" {
+		msg += "; post hooks failed: " + postFailure
+	}
+	now := metav1.Now()
+	backup.Status.Phase = seaweedv1.BackupPhaseFailed
+	backup.Status.CompletionTime = &now
+	backup.Status.ObservedGeneration = backup.Generation
+	meta.SetStatusCondition(&backup.Status.Conditions, metav1.Condition{
+		Type: seaweedv1.BackupConditionComplete, Status: metav1.ConditionFalse,
+		ObservedGeneration: backup.Generation, Reason: "PreHookFailed", Message: msg,
+	})
+	r.Recorder.Event(backup, "Warning", "BackupFailed", "pre hooks failed; snapshot skipped")
+	return ctrl.Result{}, r.Status().Update(ctx, backup)
+}
+
 // pending records a transient blocker and requeues.
 func (r *SeaweedBackupReconciler) pending(ctx context.Context, backup *seaweedv1.SeaweedBackup, reason, msg string) (ctrl.Result, error) {
 	backup.Status.Phase = seaweedv1.BackupPhasePending
@@ -150,8 +384,194 @@ func (r *SeaweedBackupReconciler) pending(ctx context.Context, backup *seaweedv1
 	return ctrl.Result{RequeueAfter: backupRequeue}, nil
 }
 
+// deleteArtifact removes a deleted backup's snapshot from its storage with a
+// cleanup Job in the cluster's namespace, and reports whether the CR may go.
+// Backups that never completed have no artifact; when the cluster or storage
+// is gone the artifact cannot be reached and is left behind with an event.
+// A failed cleanup holds the CR until the Job is fixed or deletionPolicy is
+// switched to Retain.
+func (r *SeaweedBackupReconciler) deleteArtifact(ctx context.Context, backup *seaweedv1.SeaweedBackup) (bool, error) {
+	if backup.Status.Phase != seaweedv1.BackupPhaseCompleted {
+		return true, nil
+	}
+	clusterNS := backup.Spec.ClusterNamespace
+	if clusterNS == "" {
+		clusterNS = backup.Namespace
+	}
+	// VolumeSnapshots outlive their cluster and are deleted directly.
+	if backup.Spec.Target == seaweedv1.BackupTargetVolumeSnapshots {
+		if err := r.deleteVolumeSnapshots(ctx, backup, clusterNS); err != nil {
+			return false, err
+		}
+		r.Recorder.Eventf(backup, "Normal", "ArtifactDeleted", "deleted %d volume snapshots", len(backup.Status.VolumeSnapshots))
+		return true, nil
+	}
+	var cluster seaweedv1.Seaweed
+	if err := r.Get(ctx, types.NamespacedName{Namespace: clusterNS, Name: backup.Spec.ClusterName}, &cluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Recorder.Event(backup, "Warning", "ArtifactRetained", "cluster "+backup.Spec.ClusterName+" not found; snapshot left at "+backup.Status.Destination)
+			return true, nil
+		}
+		return false, err
+	}
+	st, err := resolveStorage(&cluster, backup.Spec.StorageName)
+	if err != nil {
+		r.Recorder.Event(backup, "Warning", "ArtifactRetained", err.Error()+"; snapshot left at "+backup.Status.Destination)
+		return true, nil
+	}
+
+	jobName := backupJobName(backup.Namespace, clusterNS, backup.Name, "-del")
+	var job batchv1.Job
+	err = r.Get(ctx, types.NamespacedName{Namespace: clusterNS, Name: jobName}, &job)
+	switch {
+	case apierrors.IsNotFound(err):
+		volumes := backup.Spec.Target == seaweedv1.BackupTargetVolumes
+		if (volumes || st.Type != seaweedv1.BackupStorageFilesystem && !strings.HasPrefix(backup.Status.Destination, legacyStagedSnapshotDir+"/")) && r.BackupToolImage == "" {
+			return false, r.artifactCondition(ctx, backup, "BackupToolUnavailable",
+				"deleting from an object store needs the operator's backup-tool image; set "+BackupToolImageEnv+" on the operator")
+		}
+		// A running volumes backup may be sharing the segments about to go.
+		if volumes {
+			others, err := r.volumesBackupsOf(ctx, backup, clusterNS)
+			if err != nil {
+				return false, err
+			}
+			for i := range others {
+				if others[i].Status.Phase == seaweedv1.BackupPhaseRunning {
+					return false, r.artifactCondition(ctx, backup, "VolumesBackupRunning",
+						"waiting for volumes backup "+qualifiedBackupName(&others[i], backup.Namespace)+" to finish before deleting shared segments")
+				}
+			}
+		}
+		// Not owned by the backup, which is already being deleted; the Job is
+		// removed below once it has done its work.
+		if err := r.Create(ctx, buildArtifactCleanupJob(&cluster, jobName, backup, st, r.BackupToolImage)); err != nil && !apierrors.IsAlreadyExists(err) {
+			return false, err
+		}
+		return false, r.artifactCondition(ctx, backup, "CleanupRunning", "cleanup job "+jobName+" created")
+	case err != nil:
+		return false, err
+	}
+
+	done, success := jobFinished(&job)
+	if !done {
+		return false, nil
+	}
+	if !success {
+		r.Recorder.Event(backup, "Warning", "ArtifactDeleteFailed", "cleanup job "+jobName+" failed")
+		return false, r.artifactCondition(ctx, backup, "CleanupFailed",
+			"cleanup job "+jobName+" failed; fix it and delete the job to retry, or set deletionPolicy: Retain to keep the snapshot")
+	}
+	if err := r.Delete(ctx, &job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+		return false, err
+	}
+	r.Recorder.Event(backup, "Normal", "ArtifactDeleted", "deleted snapshot "+backup.Status.Destination)
+	return true, nil
+}
+
+// volumesBackupsOf returns the volumes backups, other than backup, of the
+// same cluster on the same storage, in any namespace.
+func (r *SeaweedBackupReconciler) volumesBackupsOf(ctx context.Context, backup *seaweedv1.SeaweedBackup, clusterNS string) ([]seaweedv1.SeaweedBackup, error) {
+	var list seaweedv1.SeaweedBackupList
+	if err := r.List(ctx, &list); err != nil {
+		return nil, err
+	}
+	var out []seaweedv1.SeaweedBackup
+	for _, b := range list.Items {
+		ns := b.Spec.ClusterNamespace
+		if ns == "" {
+			ns = b.Namespace
+		}
+		if b.Spec.Target != seaweedv1.BackupTargetVolumes || b.Spec.ClusterName != backup.Spec.ClusterName || ns != clusterNS ||
+			b.Spec.StorageName != backup.Spec.StorageName || (b.Namespace == backup.Namespace && b.Name == backup.Name) {
+			continue
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+// busyVolumesBackup says which of others keeps a new volumes backup from
+// starting: one still running, or one whose segments are being deleted.
+func busyVolumesBackup(others []seaweedv1.SeaweedBackup) string {
+	for i := range others {
+		b := &others[i]
+		switch {
+		case b.Status.Phase == seaweedv1.BackupPhaseRunning:
+			return "volumes backup " + b.Namespace + "/" + b.Name + " of the cluster is running on this storage"
+		case !b.DeletionTimestamp.IsZero() && b.Spec.DeletionPolicy == seaweedv1.BackupDeletionDelete && b.Status.Phase == seaweedv1.BackupPhaseCompleted:
+			return "volumes backup " + b.Namespace + "/" + b.Name + " of the cluster is being deleted from this storage"
+		}
+	}
+	return ""
+}
+
+// latestVolumesBackup returns the Completed backup of others a new volumes
+// backup copies incrementally against, or nil.
+func latestVolumesBackup(others []seaweedv1.SeaweedBackup) *seaweedv1.SeaweedBackup {
+	var latest *seaweedv1.SeaweedBackup
+	for i := range others {
+		b := &others[i]
+		if b.Status.Phase != seaweedv1.BackupPhaseCompleted || !b.DeletionTimestamp.IsZero() || b.Status.StartTime == nil {
+			continue
+		}
+		if latest == nil || b.Status.StartTime.After(latest.Status.StartTime.Time) {
+			latest = b
+		}
+	}
+	return latest
+}
+
+// qualifiedBackupName names backup as seen from namespace: its name, prefixed
+// with its namespace when that differs.
+func qualifiedBackupName(backup *seaweedv1.SeaweedBackup, namespace string) string {
+	if backup.Namespace == namespace {
+		return backup.Name
+	}
+	return backup.Namespace + "/" + backup.Name
+}
+
+// artifactCondition records why a deleted backup is still waiting on its
+// artifact.
+func (r *SeaweedBackupReconciler) artifactCondition(ctx context.Context, backup *seaweedv1.SeaweedBackup, reason, msg string) error {
+	meta.SetStatusCondition(&backup.Status.Conditions, metav1.Condition{
+		Type: seaweedv1.BackupConditionArtifactDeleted, Status: metav1.ConditionFalse,
+		ObservedGeneration: backup.Generation, Reason: reason, Message: msg,
+	})
+	return r.Status().Update(ctx, backup)
+}
+
+// snapshotReport reads the upload report a snapshot Job's upload container
+// left as its termination message.
+func (r *SeaweedBackupReconciler) snapshotReport(ctx context.Context, job *batchv1.Job) (backuptool.SnapshotReport, error) {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return backuptool.SnapshotReport{}, err
+	}
+	for _, pod := range pods.Items {
+		for _, cs := range pod.Status.ContainerStatuses {
+			t := cs.State.Terminated
+			if cs.Name != snapshotUploadContainer || t == nil || t.ExitCode != 0 || t.Message == "" {
+				continue
+			}
+			var report backuptool.SnapshotReport
+			if err := json.Unmarshal([]byte(t.Message), &report); err != nil {
+				return backuptool.SnapshotReport{}, fmt.Errorf("pod %s: malformed upload report: %w", pod.Name, err)
+			}
+			return report, nil
+		}
+	}
+	return backuptool.SnapshotReport{}, fmt.Errorf("no upload report found for job %s", job.Name)
+}
+
 // SetupWithManager wires the reconciler into the manager.
 func (r *SeaweedBackupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.AdminFactory == nil {
+		r.AdminFactory = NewSwadminBucketAdmin
+	}
+	if r.VolumeAdminFactory == nil {
+		r.VolumeAdminFactory = NewSwadminVolumeAdmin
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&seaweedv1.SeaweedBackup{}).
 		Owns(&batchv1.Job{}).
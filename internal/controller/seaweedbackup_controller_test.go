@@ -18,10 +18,13 @@ package controller
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -30,6 +33,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
@@ -74,10 +78,11 @@ func newBackupReconciler(t *testing.T, objs ...client.Object) *SeaweedBackupReco
 		WithStatusSubresource(&seaweedv1.SeaweedBackup{}, &batchv1.Job{}).
 		Build()
 	return &SeaweedBackupReconciler{
-		Client:   cli,
-		Log:      logf.Log,
-		Scheme:   scheme,
-		Recorder: record.NewFakeRecorder(20),
+		Client:          cli,
+		Log:             logf.Log,
+		Scheme:          scheme,
+		Recorder:        record.NewFakeRecorder(20),
+		BackupToolImage: "operator:test",
 	}
 }
 
@@ -209,3 +214,261 @@ func TestBackupReconcileMissingStorage(t *testing.T) {
 		t.Fatalf("phase = %q, want Pending", got.Status.Phase)
 	}
 }
+
+// backupRefGrant lets SeaweedBackups and SeaweedRestores in "team" reference
+// the Seaweed clusters in "ns1".
+func backupRefGrant() *seaweedv1.ResourceReferenceGrant {
+	return &seaweedv1.ResourceReferenceGrant{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-team", Namespace: "ns1"},
+		Spec: seaweedv1.ResourceReferenceGrantSpec{
+			From: []seaweedv1.ReferenceGrantFrom{
+				{Group: groupSeaweed, Kind: kindSeaweedBackup, Namespace: "team"},
+				{Group: groupSeaweed, Kind: kindSeaweedRestore, Namespace: "team"},
+			},
+			To: []seaweedv1.ReferenceGrantTo{{Group: groupSeaweed, Kind: kindSeaweed}},
+		},
+	}
+}
+
+func TestBackupReconcileCrossNamespaceDenied(t *testing.T) {
+	backup := &seaweedv1.SeaweedBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "team"},
+		Spec:       seaweedv1.SeaweedBackupSpec{ClusterName: "c1", ClusterNamespace: "ns1", StorageName: "pvc"},
+	}
+	r := newBackupReconciler(t, clusterWithFilesystemStorage(), backup)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "team", Name: "bk1"}}
+	res, err := r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.RequeueAfter == 0 {
+		t.Error("expected a requeue while the grant is missing")
+	}
+	var got seaweedv1.SeaweedBackup
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != seaweedv1.BackupPhasePending {
+		t.Fatalf("phase = %q, want Pending", got.Status.Phase)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.BackupConditionReferenceGranted)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != "ReferenceGrantMissing" {
+		t.Errorf("ReferenceGranted condition = %+v", cond)
+	}
+	var jobs batchv1.JobList
+	if err := r.List(ctx, &jobs); err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs.Items) != 0 {
+		t.Errorf("created %d jobs without a grant", len(jobs.Items))
+	}
+}
+
+func TestBackupReconcileCrossNamespace(t *testing.T) {
+	backup := &seaweedv1.SeaweedBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "team"},
+		Spec:       seaweedv1.SeaweedBackupSpec{ClusterName: "c1", ClusterNamespace: "ns1", StorageName: "pvc"},
+	}
+	r := newBackupReconciler(t, clusterWithFilesystemStorage(), backup, backupRefGrant())
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "team", Name: "bk1"}}
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jobName := boundedName("team-bk1", "-bkp")
+	var job batchv1.Job
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: jobName}, &job); err != nil {
+		t.Fatalf("expected snapshot job %q in the cluster namespace: %v", jobName, err)
+	}
+	if len(job.OwnerReferences) != 0 {
+		t.Errorf("cross-namespace job has owner references: %+v", job.OwnerReferences)
+	}
+	if cmd := strings.Join(job.Spec.Template.Spec.Containers[0].Command, " "); !strings.Contains(cmd, "-key=c1/team/bk1/filer.meta.gz") {
+		t.Errorf("snapshot is not nested under the backup namespace: %s", cmd)
+	}
+
+	var got seaweedv1.SeaweedBackup
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.JobNamespace != "ns1" || got.Status.JobName != jobName {
+		t.Errorf("status job = %s/%s", got.Status.JobNamespace, got.Status.JobName)
+	}
+	if cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.BackupConditionReferenceGranted); cond == nil || cond.Status != metav1.ConditionTrue || cond.Reason != "Granted" {
+		t.Errorf("ReferenceGranted = %+v, want True/Granted", cond)
+	}
+	if !controllerutil.ContainsFinalizer(&got, SeaweedBackupFinalizer) {
+		t.Fatal("finalizer not added")
+	}
+
+	if err := r.Delete(ctx, &got); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: jobName}, &job); !apierrors.IsNotFound(err) {
+		t.Errorf("snapshot job not deleted with the backup: %v", err)
+	}
+}
+
+func TestBackupReconcileObjectStoreRecordsUploadReport(t *testing.T) {
+	cluster := clusterWithFilesystemStorage()
+	cluster.Spec.Backup.Storages["s3"] = seaweedv1.BackupStorageSpec{
+		Type: seaweedv1.BackupStorageS3,
+		S3:   &seaweedv1.S3BackupStore{Bucket: "b", Directory: "/"},
+	}
+	backup := &seaweedv1.SeaweedBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "ns1"},
+		Spec:       seaweedv1.SeaweedBackupSpec{ClusterName: "c1", StorageName: "s3", FilerPath: "/"},
+	}
+	r := newBackupReconciler(t, cluster, backup)
+	r.BackupToolImage = ""
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "bk1"}}
+
+	// Without the backup-tool image nothing can upload the snapshot.
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	var got seaweedv1.SeaweedBackup
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.BackupConditionClusterReachable)
+	if got.Status.Phase != seaweedv1.BackupPhasePending || cond == nil || cond.Reason != "BackupToolUnavailable" {
+		t.Fatalf("phase = %q, condition = %+v; want Pending/BackupToolUnavailable", got.Status.Phase, cond)
+	}
+
+	r.BackupToolImage = "operator:test"
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	jobName := boundedName("bk1", "-bkp")
+	var job batchv1.Job
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: jobName}, &job); err != nil {
+		t.Fatalf("expected snapshot job: %v", err)
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: jobName + "-x", Namespace: "ns1", Labels: map[string]string{"job-name": jobName}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name: snapshotUploadContainer,
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+				Message: `{"uri":"s3://b/c1/bk1/filer.meta.gz","size":1234,"sha256":"abcd","manifest":"s3://b/c1/bk1/manifest.json","encryption":"aes-256-gcm"}`,
+			}},
+		}}},
+	}
+	if err := r.Create(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	if err := r.Status().Update(ctx, &job); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != seaweedv1.BackupPhaseCompleted {
+		t.Fatalf("phase = %q, want Completed", got.Status.Phase)
+	}
+	if got.Status.Destination != "s3://b/c1/bk1/filer.meta.gz" || got.Status.Size != 1234 || got.Status.Checksum != "sha256:abcd" {
+		t.Errorf("status = destination %q, size %d, checksum %q", got.Status.Destination, got.Status.Size, got.Status.Checksum)
+	}
+	if got.Status.Manifest != "s3://b/c1/bk1/manifest.json" || got.Status.Encryption != "aes-256-gcm" {
+		t.Errorf("status = manifest %q, encryption %q", got.Status.Manifest, got.Status.Encryption)
+	}
+}
+
+func TestBackupDeletionPolicyDeleteRemovesArtifact(t *testing.T) {
+	backup := &seaweedv1.SeaweedBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "ns1"},
+		Spec: seaweedv1.SeaweedBackupSpec{
+			ClusterName: "c1", StorageName: "pvc", FilerPath: "/",
+			DeletionPolicy: seaweedv1.BackupDeletionDelete,
+		},
+		Status: seaweedv1.SeaweedBackupStatus{Phase: seaweedv1.BackupPhaseCompleted, Destination: "/backup/c1/bk1/filer.meta.gz"},
+	}
+	r := newBackupReconciler(t, clusterWithFilesystemStorage(), backup)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "bk1"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	var got seaweedv1.SeaweedBackup
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !controllerutil.ContainsFinalizer(&got, SeaweedBackupFinalizer) {
+		t.Fatal("deletionPolicy Delete must add the finalizer")
+	}
+
+	if err := r.Delete(ctx, &got); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile after delete: %v", err)
+	}
+	jobName := boundedName("bk1", "-del")
+	var job batchv1.Job
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: jobName}, &job); err != nil {
+		t.Fatalf("expected cleanup job %q: %v", jobName, err)
+	}
+	cmd := job.Spec.Template.Spec.Containers[0].Command
+	if !strings.Contains(cmd[len(cmd)-1], "rm -f /backup/c1/bk1/filer.meta.gz /backup/c1/bk1/manifest.json") {
+		t.Errorf("cleanup script wrong:\n%s", cmd[len(cmd)-1])
+	}
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatalf("backup must wait for its cleanup job: %v", err)
+	}
+	if c := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.BackupConditionArtifactDeleted); c == nil || c.Reason != "CleanupRunning" {
+		t.Errorf("ArtifactDeleted condition = %+v", c)
+	}
+
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	if err := r.Status().Update(ctx, &job); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile after cleanup: %v", err)
+	}
+	if err := r.Get(ctx, req.NamespacedName, &got); !apierrors.IsNotFound(err) {
+		t.Errorf("backup should be gone once its artifact is deleted, got err %v", err)
+	}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: jobName}, &job); !apierrors.IsNotFound(err) {
+		t.Errorf("cleanup job should be removed, got err %v", err)
+	}
+}
+
+func TestArtifactCleanupJobObjectStore(t *testing.T) {
+	m := testSeaweedForBackup()
+	st := seaweedv1.BackupStorageSpec{
+		Type: seaweedv1.BackupStorageGCS,
+		GCS:  &seaweedv1.GCSBackupStore{Bucket: "b", Directory: "/backups"},
+	}
+	backup := &seaweedv1.SeaweedBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "ns1"},
+		Spec:       seaweedv1.SeaweedBackupSpec{ClusterName: "c1", StorageName: "gcs"},
+		Status:     seaweedv1.SeaweedBackupStatus{Destination: "gs://b/backups/c1/bk1/filer.meta.gz"},
+	}
+	job := buildArtifactCleanupJob(m, "bk1-del", backup, st, "operator:test")
+	cmd := strings.Join(job.Spec.Template.Spec.Containers[0].Command, " ")
+	if !containsAll(cmd, "backup-tool snapshot-delete", "-storeType=gcs", "-bucket=b", "-key=backups/c1/bk1/filer.meta.gz") {
+		t.Errorf("object-store cleanup command wrong: %s", cmd)
+	}
+
+	// Snapshots staged in the filer before direct upload are removed there.
+	backup.Status.Destination = "/.seaweedfs-operator/backups/bk1/filer.meta.gz"
+	job = buildArtifactCleanupJob(m, "bk1-del", backup, st, "operator:test")
+	script := job.Spec.Template.Spec.Containers[0].Command
+	if !strings.Contains(script[len(script)-1], "fs.rm -r /.seaweedfs-operator/backups/bk1") {
+		t.Errorf("staged cleanup script wrong:\n%s", script[len(script)-1])
+	}
+}
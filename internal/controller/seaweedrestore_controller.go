@@ -18,11 +18,15 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"path"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -34,20 +38,34 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/internal/backuptool"
 )
 
 // SeaweedRestoreReconciler turns a SeaweedRestore into a one-shot
-// `fs.meta.load` Job and tracks its outcome on the CR's status.
+// `fs.meta.load` Job, or a Job putting back the volumes of a volumes backup,
+// and tracks its outcome on the CR's status. Mode volumeSnapshots instead
+// creates a new cluster on claims provisioned from a backup's VolumeSnapshots.
 type SeaweedRestoreReconciler struct {
 	client.Client
 	Log      logr.Logger
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+
+	// BackupToolImage is the image restores download and verify the snapshot,
+	// and point-in-time restores replay the metadata log, with (see
+	// ResolveBackupToolImage). Empty leaves restores Pending.
+	BackupToolImage string
+	// Now returns the current time when checking spec.pointInTime. Tests pin
+	// it; nil uses time.Now.
+	Now func() time.Time
 }
 
 // +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=seaweedrestores,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=seaweedrestores/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=seaweedrestores/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=seaweeds,verbs=get;list;watch;create
 
 // Reconcile implements the SeaweedRestore lifecycle.
 func (r *SeaweedRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -58,20 +76,82 @@ func (r *SeaweedRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if !restore.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(&restore, SeaweedRestoreFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		if err := deleteCrossNamespaceJob(ctx, r.Client, restore.Namespace, restore.Status.JobNamespace, restore.Status.JobName); err != nil {
+			return ctrl.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(&restore, SeaweedRestoreFinalizer)
+		return ctrl.Result{}, r.Update(ctx, &restore)
+	}
+
 	if restore.Status.Phase == seaweedv1.RestorePhaseCompleted || restore.Status.Phase == seaweedv1.RestorePhaseFailed {
 		return ctrl.Result{}, nil
 	}
 
+	clusterNS := restore.Spec.ClusterNamespace
+	if clusterNS == "" {
+		clusterNS = restore.Namespace
+	}
+	crossNamespace := clusterNS != restore.Namespace
+	if crossNamespace {
+		ref := seaweedv1.SeaweedReference{Name: restore.Spec.ClusterName, Namespace: clusterNS}
+		permitted, err := seaweedRefPermitted(ctx, r.Client, ref, kindSeaweedRestore, restore.Namespace)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !permitted {
+			msg := seaweedRefDeniedMessage(ref, kindSeaweedRestore, restore.Namespace)
+			meta.SetStatusCondition(&restore.Status.Conditions, metav1.Condition{
+				Type: seaweedv1.RestoreConditionReferenceGranted, Status: metav1.ConditionFalse,
+				ObservedGeneration: restore.Generation, Reason: "ReferenceGrantMissing", Message: msg,
+			})
+			return r.pending(ctx, &restore, "ReferenceGrantMissing", msg)
+		}
+		if !controllerutil.ContainsFinalizer(&restore, SeaweedRestoreFinalizer) {
+			controllerutil.AddFinalizer(&restore, SeaweedRestoreFinalizer)
+			if err := r.Update(ctx, &restore); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{Requeue: true}, nil
+		}
+		meta.SetStatusCondition(&restore.Status.Conditions, metav1.Condition{
+			Type: seaweedv1.RestoreConditionReferenceGranted, Status: metav1.ConditionTrue,
+			ObservedGeneration: restore.Generation, Reason: "Granted",
+			Message: fmt.Sprintf("a ResourceReferenceGrant in namespace %q permits the reference to cluster %q", clusterNS, ref.Name),
+		})
+	} else {
+		meta.RemoveStatusCondition(&restore.Status.Conditions, seaweedv1.RestoreConditionReferenceGranted)
+	}
+
+	if restore.Spec.Mode == seaweedv1.RestoreModeVolumeSnapshots {
+		return r.reconcileVolumeSnapshots(ctx, &restore, clusterNS)
+	}
+
 	var cluster seaweedv1.Seaweed
-	if err := r.Get(ctx, types.NamespacedName{Namespace: restore.Namespace, Name: restore.Spec.ClusterName}, &cluster); err != nil {
+	if err := r.Get(ctx, types.NamespacedName{Namespace: clusterNS, Name: restore.Spec.ClusterName}, &cluster); err != nil {
 		if apierrors.IsNotFound(err) {
 			return r.pending(ctx, &restore, "ClusterNotFound",
-				"Seaweed cluster "+restore.Spec.ClusterName+" not found in namespace "+restore.Namespace)
+				"Seaweed cluster "+restore.Spec.ClusterName+" not found in namespace "+clusterNS)
 		}
 		return ctrl.Result{}, err
 	}
 
-	src, err := r.resolveSource(ctx, &restore)
+	pit := restore.Spec.PointInTime
+	if pit != nil {
+		if pit.Time.After(r.now()) {
+			return r.pending(ctx, &restore, "PointInTimeInFuture",
+				"pointInTime "+pit.UTC().Format(time.RFC3339)+" has not been reached yet")
+		}
+	}
+	if r.BackupToolImage == "" {
+		return r.pending(ctx, &restore, "BackupToolUnavailable",
+			"verifying the snapshot needs the operator's backup-tool image; set "+BackupToolImageEnv+" on the operator")
+	}
+
+	src, err := r.resolveSource(ctx, &restore, &cluster)
 	if err != nil {
 		return r.pending(ctx, &restore, "SourceUnresolved", err.Error())
 	}
@@ -79,54 +159,81 @@ func (r *SeaweedRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	if err != nil {
 		return r.pending(ctx, &restore, "StorageNotFound", err.Error())
 	}
-
-	// Translate the resolved snapshot location into how the Job reads it:
-	// filesystem storages read straight off the PVC at a relative path; object
-	// stores read back from the reserved filer path via filer.cat.
-	var localPath, filerURL string
-	if st.Type == seaweedv1.BackupStorageFilesystem {
-		rel := src.metaPath
-		if rel == "" {
-			rel = metaRelPath(src.cluster, src.backupName)
+	var reseed *reseedSource
+	if restore.Spec.Mode == seaweedv1.RestoreModeFull {
+		rs, err := resolveReseedSource(&cluster, &restore, src, st)
+		if err != nil {
+			return r.pending(ctx, &restore, "DataMirrorUnresolved", err.Error())
 		}
-		localPath = path.Join(filesystemMountPath(st.Filesystem), rel)
-	} else {
-		fp := src.metaPath
-		if fp == "" {
-			fp = reservedFilerMetaPath(src.backupName)
-		}
-		if fp == "" || fp[0] != '/' {
-			fp = "/" + fp
+		reseed = &rs
+	}
+
+	// The snapshot's location relative to the storage: the PVC root for
+	// filesystem storages, the storage directory for object stores.
+	volumes := restore.Spec.Mode == seaweedv1.RestoreModeVolumes
+	snap := snapshotRef{rel: src.metaPath, sha256: src.sha256}
+	if snap.rel == "" {
+		snap.rel = metaRelPath(src.cluster, src.backupName)
+		if volumes {
+			snap.rel = volumesManifestRelPath(src.cluster, src.backupName)
 		}
-		// http:// is intentional even on TLS clusters: filer.cat reaches the
-		// filer over gRPC (mTLS via -config_dir); the filer's HTTP endpoint is
-		// plain HTTP under the operator's gRPC-only TLS model.
-		filerURL = fmt.Sprintf("http://%s%s", getFilerAddress(&cluster), fp)
 	}
 
-	jobName := boundedName(restore.Name, "-rst")
+	jobName := backupJobName(restore.Namespace, clusterNS, restore.Name, "-rst")
 	var job batchv1.Job
-	err = r.Get(ctx, types.NamespacedName{Namespace: restore.Namespace, Name: jobName}, &job)
+	err = r.Get(ctx, types.NamespacedName{Namespace: clusterNS, Name: jobName}, &job)
 	switch {
 	case apierrors.IsNotFound(err):
-		built := buildRestoreJob(&cluster, jobName, &restore, st, localPath, filerURL)
-		if err := controllerutil.SetControllerReference(&restore, built, r.Scheme); err != nil {
-			return ctrl.Result{}, err
+		var built *batchv1.Job
+		switch {
+		case volumes:
+			built = buildVolumeRestoreJob(&cluster, jobName, &restore, st, snap, r.BackupToolImage)
+		case pit != nil:
+			metaLog, _ := metaLogFor(&cluster, src.storageName)
+			built = buildPointInTimeRestoreJob(&cluster, jobName, &restore, st, snap, pointInTimeReplay{
+				toolImage:      r.BackupToolImage,
+				sourceCluster:  src.cluster,
+				since:          src.startTime,
+				until:          *pit,
+				segmentMinutes: metaLogSegmentMinutes(metaLog),
+			})
+		default:
+			built = buildRestoreJob(&cluster, jobName, &restore, st, snap, r.BackupToolImage)
+		}
+		if reseed != nil {
+			withReseed(built, &cluster, *reseed, r.BackupToolImage)
+		}
+		if !crossNamespace {
+			if err := controllerutil.SetControllerReference(&restore, built, r.Scheme); err != nil {
+				return ctrl.Result{}, err
+			}
 		}
 		if err := r.Create(ctx, built); err != nil && !apierrors.IsAlreadyExists(err) {
 			return ctrl.Result{}, err
 		}
-		log.Info("created restore job", "job", jobName, "storage", src.storageName)
+		log.Info("created restore job", "job", jobName, "namespace", clusterNS, "storage", src.storageName)
 		now := metav1.Now()
 		restore.Status.Phase = seaweedv1.RestorePhaseRunning
 		restore.Status.JobName = jobName
+		restore.Status.JobNamespace = clusterNS
 		restore.Status.StartTime = &now
+		if pit != nil {
+			restore.Status.BaseBackup = src.backup
+		}
 		restore.Status.ObservedGeneration = restore.Generation
 		meta.SetStatusCondition(&restore.Status.Conditions, metav1.Condition{
 			Type: seaweedv1.RestoreConditionSourceResolved, Status: metav1.ConditionTrue,
 			ObservedGeneration: restore.Generation, Reason: "Resolved", Message: "restore job created",
 		})
-		return ctrl.Result{}, r.Status().Update(ctx, &restore)
+		if err := r.Status().Update(ctx, &restore); err != nil {
+			return ctrl.Result{}, err
+		}
+		// A Job in another namespace is not owned, so its progress does not
+		// trigger a reconcile; poll instead.
+		if crossNamespace {
+			return ctrl.Result{RequeueAfter: backupRequeue}, nil
+		}
+		return ctrl.Result{}, nil
 	case err != nil:
 		return ctrl.Result{}, err
 	}
@@ -144,13 +251,47 @@ func (r *SeaweedRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 
 	now := metav1.Now()
 	restore.Status.CompletionTime = &now
+	completeMsg := "metadata restored"
+	switch restore.Spec.Mode {
+	case seaweedv1.RestoreModeFull:
+		if msg, ok := r.recordReseed(ctx, &restore, &job, success); ok && success {
+			completeMsg = "metadata restored; " + msg
+		}
+	case seaweedv1.RestoreModeVolumes:
+		completeMsg = "volumes restored"
+		if msg, ok := r.recordVolumes(ctx, &restore, &job); ok {
+			completeMsg = msg
+		}
+	}
 	if success {
 		restore.Status.Phase = seaweedv1.RestorePhaseCompleted
+		meta.SetStatusCondition(&restore.Status.Conditions, metav1.Condition{
+			Type: seaweedv1.RestoreConditionVerified, Status: metav1.ConditionTrue,
+			ObservedGeneration: restore.Generation, Reason: "SnapshotVerified", Message: "snapshot verified before loading",
+		})
 		meta.SetStatusCondition(&restore.Status.Conditions, metav1.Condition{
 			Type: seaweedv1.RestoreConditionComplete, Status: metav1.ConditionTrue,
-			ObservedGeneration: restore.Generation, Reason: "RestoreComplete", Message: "metadata restored",
+			ObservedGeneration: restore.Generation, Reason: "RestoreComplete", Message: completeMsg,
+		})
+		r.Recorder.Event(&restore, "Normal", "RestoreCompleted", completeMsg)
+	} else if msg, ok := r.integrityFailure(ctx, &job); ok {
+		restore.Status.Phase = seaweedv1.RestorePhaseFailed
+		meta.SetStatusCondition(&restore.Status.Conditions, metav1.Condition{
+			Type: seaweedv1.RestoreConditionVerified, Status: metav1.ConditionFalse,
+			ObservedGeneration: restore.Generation, Reason: "IntegrityCheckFailed", Message: msg,
+		})
+		meta.SetStatusCondition(&restore.Status.Conditions, metav1.Condition{
+			Type: seaweedv1.RestoreConditionComplete, Status: metav1.ConditionFalse,
+			ObservedGeneration: restore.Generation, Reason: "IntegrityCheckFailed", Message: "snapshot failed verification and was not loaded",
+		})
+		r.Recorder.Event(&restore, "Warning", "IntegrityCheckFailed", msg)
+	} else if msg, ok := r.metaLogIncomplete(ctx, &job); ok {
+		restore.Status.Phase = seaweedv1.RestorePhaseFailed
+		meta.SetStatusCondition(&restore.Status.Conditions, metav1.Condition{
+			Type: seaweedv1.RestoreConditionComplete, Status: metav1.ConditionFalse,
+			ObservedGeneration: restore.Generation, Reason: "MetaLogIncomplete", Message: msg,
 		})
-		r.Recorder.Event(&restore, "Normal", "RestoreCompleted", "metadata restore completed")
+		r.Recorder.Event(&restore, "Warning", "MetaLogIncomplete", msg)
 	} else {
 		restore.Status.Phase = seaweedv1.RestorePhaseFailed
 		meta.SetStatusCondition(&restore.Status.Conditions, metav1.Condition{
@@ -162,6 +303,189 @@ func (r *SeaweedRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	return ctrl.Result{}, r.Status().Update(ctx, &restore)
 }
 
+// integrityFailure reports whether a failed restore Job's download container
+// rejected the snapshot as corrupt or tampered with, and why.
+func (r *SeaweedRestoreReconciler) integrityFailure(ctx context.Context, job *batchv1.Job) (string, bool) {
+	msg, ok := r.initContainerExit(ctx, job, snapshotDownloadContainer, backuptool.IntegrityExitCode)
+	if ok && msg == "" {
+		msg = "snapshot failed verification; see job " + job.Name
+	}
+	return msg, ok
+}
+
+// metaLogIncomplete reports whether a failed point-in-time restore Job gave
+// up waiting for the metadata log to reach the instant to restore to, and
+// why. Nothing was loaded then.
+func (r *SeaweedRestoreReconciler) metaLogIncomplete(ctx context.Context, job *batchv1.Job) (string, bool) {
+	msg, ok := r.initContainerExit(ctx, job, metaLogCoverageContainer, backuptool.LogIncompleteExitCode)
+	if ok && msg == "" {
+		msg = "the metadata log does not reach pointInTime; see job " + job.Name
+	}
+	return msg, ok
+}
+
+// initContainerExit reports whether the init container name of one of job's
+// pods exited with exitCode, and the last line of its termination message.
+func (r *SeaweedRestoreReconciler) initContainerExit(ctx context.Context, job *batchv1.Job, name string, exitCode int32) (string, bool) {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return "", false
+	}
+	for _, pod := range pods.Items {
+		for _, cs := range pod.Status.InitContainerStatuses {
+			t := cs.State.Terminated
+			if cs.Name != name || t == nil || t.ExitCode != exitCode {
+				continue
+			}
+			msg := strings.TrimSpace(t.Message)
+			if i := strings.LastIndex(msg, "\n"); i >= 0 {
+				msg = msg[i+1:]
+			}
+			return msg, true
+		}
+	}
+	return "", false
+}
+
+// recordReseed copies the reseed report of a finished full restore Job into
+// the restore's status and sets its Reseeded condition, returning a summary.
+// It reports false when the Job stopped before the reseed ran.
+func (r *SeaweedRestoreReconciler) recordReseed(ctx context.Context, restore *seaweedv1.SeaweedRestore, job *batchv1.Job, success bool) (string, bool) {
+	report, ok := r.reseedReport(ctx, job, success)
+	if !ok {
+		return "", false
+	}
+	restore.Status.Reseed = &seaweedv1.RestoreReseedStatus{
+		Files:        report.Files,
+		Bytes:        report.Bytes,
+		Present:      report.Present,
+		Missing:      report.Missing,
+		MissingPaths: report.MissingPaths,
+	}
+	if report.Failure != "" {
+		meta.SetStatusCondition(&restore.Status.Conditions, metav1.Condition{
+			Type: seaweedv1.RestoreConditionReseeded, Status: metav1.ConditionFalse,
+			ObservedGeneration: restore.Generation, Reason: "ReseedFailed", Message: report.Failure,
+		})
+		return report.Failure, true
+	}
+	msg := fmt.Sprintf("copied %d files (%d bytes) back from the data mirror", report.Files, report.Bytes)
+	if report.Missing > 0 {
+		msg += fmt.Sprintf("; %d files with lost chunks were not in the mirror", report.Missing)
+	}
+	meta.SetStatusCondition(&restore.Status.Conditions, metav1.Condition{
+		Type: seaweedv1.RestoreConditionReseeded, Status: metav1.ConditionTrue,
+		ObservedGeneration: restore.Generation, Reason: "Reseeded", Message: msg,
+	})
+	if report.Missing > 0 {
+		r.Recorder.Event(restore, "Warning", "ReseedIncomplete", msg)
+	}
+	return msg, true
+}
+
+// reseedReport reads the report the reseed container of a finished full
+// restore Job left in its termination message.
+func (r *SeaweedRestoreReconciler) reseedReport(ctx context.Context, job *batchv1.Job, success bool) (backuptool.ReseedReport, bool) {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return backuptool.ReseedReport{}, false
+	}
+	for _, pod := range pods.Items {
+		for _, cs := range pod.Status.ContainerStatuses {
+			t := cs.State.Terminated
+			if cs.Name != restoreReseedContainer || t == nil || t.Message == "" || (success && t.ExitCode != 0) {
+				continue
+			}
+			var report backuptool.ReseedReport
+			if err := json.Unmarshal([]byte(t.Message), &report); err != nil {
+				msg := strings.TrimSpace(t.Message)
+				if i := strings.LastIndex(msg, "\n"); i >= 0 {
+					msg = msg[i+1:]
+				}
+				return backuptool.ReseedReport{Failure: msg}, true
+			}
+			return report, true
+		}
+	}
+	return backuptool.ReseedReport{}, false
+}
+
+// recordVolumes copies the report the download container of a finished
+// volumes restore Job left into the restore's status, returning a summary.
+// It reports false when there is no report to read.
+func (r *SeaweedRestoreReconciler) recordVolumes(ctx context.Context, restore *seaweedv1.SeaweedRestore, job *batchv1.Job) (string, bool) {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return "", false
+	}
+	for _, pod := range pods.Items {
+		for _, cs := range pod.Status.InitContainerStatuses {
+			t := cs.State.Terminated
+			if cs.Name != snapshotDownloadContainer || t == nil || t.ExitCode != 0 || t.Message == "" {
+				continue
+			}
+			var report backuptool.VolumeRestoreReport
+			if err := json.Unmarshal([]byte(t.Message), &report); err != nil {
+				continue
+			}
+			restore.Status.Volumes = &seaweedv1.RestoreVolumesStatus{
+				Restored: int32(report.Restored),
+				Bytes:    report.Bytes,
+				Present:  int32(report.Present),
+			}
+			return fmt.Sprintf("restored %d volumes (%d bytes); %d were already on the cluster", report.Restored, report.Bytes, report.Present), true
+		}
+	}
+	return "", false
+}
+
+// resolveReseedSource resolves the data mirror a full restore reseeds from:
+// spec.dataMirror, defaulting to the mirror of the snapshot's cluster on the
+// snapshot's storage (snapshotStorage).
+func resolveReseedSource(cluster *seaweedv1.Seaweed, restore *seaweedv1.SeaweedRestore, src restoreSource, snapshotStorage seaweedv1.BackupStorageSpec) (reseedSource, error) {
+	var dm seaweedv1.RestoreDataMirror
+	if restore.Spec.DataMirror != nil {
+		dm = *restore.Spec.DataMirror
+	}
+	rs := reseedSource{
+		storageName: dm.StorageName,
+		cluster:     dm.ClusterName,
+		sourceDir:   path.Clean("/" + filerPathOrDefault(dm.FilerPath)),
+	}
+	if rs.storageName == "" {
+		rs.storageName = src.storageName
+	}
+	if rs.cluster == "" {
+		rs.cluster = src.cluster
+	}
+	st, err := resolveStorage(cluster, rs.storageName)
+	if err != nil {
+		return reseedSource{}, err
+	}
+	// Both PVCs would be mounted as the one backup-storage volume.
+	if rs.storageName != src.storageName && st.Type == seaweedv1.BackupStorageFilesystem && snapshotStorage.Type == seaweedv1.BackupStorageFilesystem {
+		return reseedSource{}, fmt.Errorf("a data mirror on filesystem storage %q cannot be read alongside a snapshot on filesystem storage %q", rs.storageName, src.storageName)
+	}
+	rs.storage = st
+
+	target := path.Clean("/" + filerPathOrDefault(restore.Spec.FilerPath))
+	switch {
+	case filerPathWithin(target, rs.sourceDir):
+		rs.walk = target
+	case filerPathWithin(rs.sourceDir, target):
+		rs.walk = rs.sourceDir
+	default:
+		return reseedSource{}, fmt.Errorf("the data mirror of %s holds nothing under the restored %s", rs.sourceDir, target)
+	}
+	return rs, nil
+}
+
+// filerPathWithin reports whether the clean filer path p is dir or lies
+// below it.
+func filerPathWithin(p, dir string) bool {
+	return dir == "/" || p == dir || strings.HasPrefix(p, dir+"/")
+}
+
 // restoreSource is the resolved snapshot location, independent of storage type.
 // Exactly one of (backupName) or (metaPath) drives the eventual path: backupName
 // reconstructs the canonical layout, metaPath is an explicit override.
@@ -170,12 +494,20 @@ type restoreSource struct {
 	backupName  string // set when restoring a SeaweedBackup
 	metaPath    string // set when restoring an explicit BackupSource
 	cluster     string
+	// sha256 is the hex checksum the SeaweedBackup recorded, if any.
+	sha256 string
+
+	// backup and startTime identify the SeaweedBackup a point-in-time restore
+	// replays the metadata log from.
+	backup    string
+	startTime metav1.Time
 }
 
 // resolveSource resolves the restore's backup source. For a BackupName it reads
 // the referenced (Completed) SeaweedBackup; for a BackupSource it uses the
-// explicit fields.
-func (r *SeaweedRestoreReconciler) resolveSource(ctx context.Context, restore *seaweedv1.SeaweedRestore) (restoreSource, error) {
+// explicit fields. A point-in-time restore without a BackupName picks the
+// latest usable backup of the target cluster.
+func (r *SeaweedRestoreReconciler) resolveSource(ctx context.Context, restore *seaweedv1.SeaweedRestore, cluster *seaweedv1.Seaweed) (restoreSource, error) {
 	if restore.Spec.BackupSource != nil {
 		return restoreSource{
 			storageName: restore.Spec.BackupSource.StorageName,
@@ -183,6 +515,9 @@ func (r *SeaweedRestoreReconciler) resolveSource(ctx context.Context, restore *s
 			cluster:     restore.Spec.ClusterName,
 		}, nil
 	}
+	if restore.Spec.BackupName == "" {
+		return r.pointInTimeBase(ctx, restore, cluster)
+	}
 	var backup seaweedv1.SeaweedBackup
 	if err := r.Get(ctx, types.NamespacedName{Namespace: restore.Namespace, Name: restore.Spec.BackupName}, &backup); err != nil {
 		if apierrors.IsNotFound(err) {
@@ -193,11 +528,91 @@ func (r *SeaweedRestoreReconciler) resolveSource(ctx context.Context, restore *s
 	if backup.Status.Phase != seaweedv1.BackupPhaseCompleted {
 		return restoreSource{}, fmt.Errorf("backup %q is not Completed (phase %q)", backup.Name, backup.Status.Phase)
 	}
-	return restoreSource{
+	switch volumesBackup := backup.Spec.Target == seaweedv1.BackupTargetVolumes; {
+	case backup.Spec.Target == seaweedv1.BackupTargetVolumeSnapshots:
+		return restoreSource{}, fmt.Errorf("backup %q holds VolumeSnapshots; restore it into a new cluster with mode volumeSnapshots", backup.Name)
+	case volumesBackup && restore.Spec.Mode != seaweedv1.RestoreModeVolumes:
+		return restoreSource{}, fmt.Errorf("backup %q copied volumes, not metadata; restore it with mode volumes", backup.Name)
+	case !volumesBackup && restore.Spec.Mode == seaweedv1.RestoreModeVolumes:
+		return restoreSource{}, fmt.Errorf("backup %q is a metadata snapshot; mode volumes restores backups with target volumes", backup.Name)
+	}
+	if pit := restore.Spec.PointInTime; pit != nil {
+		if backup.Status.StartTime == nil || backup.Status.StartTime.After(pit.Time) {
+			return restoreSource{}, fmt.Errorf("backup %q was not taken before pointInTime %s", backup.Name, pit.UTC().Format(time.RFC3339))
+		}
+		if backupOfCluster(&backup, cluster) {
+			if _, ok := metaLogFor(cluster, backup.Spec.StorageName); !ok {
+				return restoreSource{}, fmt.Errorf("cluster %q keeps no metadata log on storage %q", cluster.Name, backup.Spec.StorageName)
+			}
+		}
+	}
+	return sourceFromBackup(&backup), nil
+}
+
+// pointInTimeBase selects the base snapshot of a point-in-time restore: the
+// latest Completed metadata SeaweedBackup of the target cluster, in the
+// restore's namespace, started at or before PointInTime on a storage the
+// cluster keeps a metadata log on.
+func (r *SeaweedRestoreReconciler) pointInTimeBase(ctx context.Context, restore *seaweedv1.SeaweedRestore, cluster *seaweedv1.Seaweed) (restoreSource, error) {
+	pit := restore.Spec.PointInTime
+	var backups seaweedv1.SeaweedBackupList
+	if err := r.List(ctx, &backups, client.InNamespace(restore.Namespace)); err != nil {
+		return restoreSource{}, err
+	}
+	var best *seaweedv1.SeaweedBackup
+	for i := range backups.Items {
+		b := &backups.Items[i]
+		if b.Status.Phase != seaweedv1.BackupPhaseCompleted || b.Status.StartTime == nil || b.Status.StartTime.After(pit.Time) {
+			continue
+		}
+		if !metadataBackup(b) {
+			continue
+		}
+		if !backupOfCluster(b, cluster) {
+			continue
+		}
+		if _, ok := metaLogFor(cluster, b.Spec.StorageName); !ok {
+			continue
+		}
+		if best == nil || b.Status.StartTime.After(best.Status.StartTime.Time) {
+			best = b
+		}
+	}
+	if best == nil {
+		return restoreSource{}, fmt.Errorf("no Completed backup of cluster %q taken before %s on a storage with a metadata log", cluster.Name, pit.UTC().Format(time.RFC3339))
+	}
+	return sourceFromBackup(best), nil
+}
+
+// sourceFromBackup is the restore source of a Completed SeaweedBackup.
+func sourceFromBackup(backup *seaweedv1.SeaweedBackup) restoreSource {
+	src := restoreSource{
 		storageName: backup.Spec.StorageName,
-		backupName:  backup.Name,
+		backupName:  backupSnapshotName(backup),
 		cluster:     backup.Spec.ClusterName,
-	}, nil
+		backup:      backup.Name,
+		sha256:      strings.TrimPrefix(backup.Status.Checksum, "sha256:"),
+	}
+	if backup.Status.StartTime != nil {
+		src.startTime = *backup.Status.StartTime
+	}
+	return src
+}
+
+// backupOfCluster reports whether backup snapshots cluster.
+func backupOfCluster(backup *seaweedv1.SeaweedBackup, cluster *seaweedv1.Seaweed) bool {
+	ns := backup.Spec.ClusterNamespace
+	if ns == "" {
+		ns = backup.Namespace
+	}
+	return backup.Spec.ClusterName == cluster.Name && ns == cluster.Namespace
+}
+
+func (r *SeaweedRestoreReconciler) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
 }
 
 // pending records a transient blocker and requeues.
@@ -18,10 +18,13 @@ package controller
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
@@ -31,6 +34,7 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/internal/backuptool"
 )
 
 func newRestoreReconciler(t *testing.T, objs ...client.Object) *SeaweedRestoreReconciler {
@@ -42,10 +46,11 @@ func newRestoreReconciler(t *testing.T, objs ...client.Object) *SeaweedRestoreRe
 		WithStatusSubresource(&seaweedv1.SeaweedRestore{}, &batchv1.Job{}).
 		Build()
 	return &SeaweedRestoreReconciler{
-		Client:   cli,
-		Log:      logf.Log,
-		Scheme:   scheme,
-		Recorder: record.NewFakeRecorder(20),
+		Client:          cli,
+		Log:             logf.Log,
+		Scheme:          scheme,
+		Recorder:        record.NewFakeRecorder(20),
+		BackupToolImage: "operator:test",
 	}
 }
 
@@ -62,7 +67,9 @@ func TestRestoreFromBackupCreatesJobAndCompletes(t *testing.T) {
 		ObjectMeta: metav1.ObjectMeta{Name: "rs1", Namespace: "ns1"},
 		Spec:       seaweedv1.SeaweedRestoreSpec{ClusterName: "c1", BackupName: "bk1", FilerPath: "/"},
 	}
-	r := newRestoreReconciler(t, clusterWithFilesystemStorage(), completedBackup("bk1"), restore)
+	backup := completedBackup("bk1")
+	backup.Status.Checksum = "sha256:abcd"
+	r := newRestoreReconciler(t, clusterWithFilesystemStorage(), backup, restore)
 	ctx := context.Background()
 	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "rs1"}}
 
@@ -75,10 +82,14 @@ func TestRestoreFromBackupCreatesJobAndCompletes(t *testing.T) {
 	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: jobName}, &job); err != nil {
 		t.Fatalf("expected restore job %q: %v", jobName, err)
 	}
+	download := strings.Join(job.Spec.Template.Spec.InitContainers[0].Command, " ")
+	if !containsAll(download, "snapshot-download", "-dir=/backup", "-key=c1/bk1/filer.meta.gz", "-sha256=abcd") {
+		t.Errorf("download does not verify the expected snapshot: %s", download)
+	}
 	cmd := job.Spec.Template.Spec.Containers[0].Command
 	joined := cmd[len(cmd)-1]
-	if !containsAll(joined, "fs.meta.load", "/backup/c1/bk1/filer.meta.gz") {
-		t.Errorf("restore script does not load the expected snapshot:\n%s", joined)
+	if !containsAll(joined, "fs.meta.load", "/scratch/restore.meta.gz") {
+		t.Errorf("restore script does not load the downloaded snapshot:\n%s", joined)
 	}
 
 	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
@@ -95,6 +106,61 @@ func TestRestoreFromBackupCreatesJobAndCompletes(t *testing.T) {
 	if got.Status.Phase != seaweedv1.RestorePhaseCompleted {
 		t.Fatalf("phase = %q, want Completed", got.Status.Phase)
 	}
+	if !meta.IsStatusConditionTrue(got.Status.Conditions, seaweedv1.RestoreConditionVerified) {
+		t.Errorf("Verified condition = %+v", meta.FindStatusCondition(got.Status.Conditions, seaweedv1.RestoreConditionVerified))
+	}
+}
+
+func TestRestoreIntegrityFailure(t *testing.T) {
+	restore := &seaweedv1.SeaweedRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "rs1", Namespace: "ns1"},
+		Spec:       seaweedv1.SeaweedRestoreSpec{ClusterName: "c1", BackupName: "bk1"},
+	}
+	r := newRestoreReconciler(t, clusterWithFilesystemStorage(), completedBackup("bk1"), restore)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "rs1"}}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	jobName := boundedName("rs1", "-rst")
+	var job batchv1.Job
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: jobName}, &job); err != nil {
+		t.Fatal(err)
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: jobName + "-x", Namespace: "ns1", Labels: map[string]string{"job-name": jobName}},
+		Status: corev1.PodStatus{InitContainerStatuses: []corev1.ContainerStatus{{
+			Name: snapshotDownloadContainer,
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+				ExitCode: backuptool.IntegrityExitCode,
+				Message:  "downloading\nbackup-tool snapshot-download: integrity check failed: checksum mismatch\n",
+			}},
+		}}},
+	}
+	if err := r.Create(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+	if err := r.Status().Update(ctx, &job); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	var got seaweedv1.SeaweedRestore
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != seaweedv1.RestorePhaseFailed {
+		t.Fatalf("phase = %q, want Failed", got.Status.Phase)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.RestoreConditionVerified)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != "IntegrityCheckFailed" ||
+		cond.Message != "backup-tool snapshot-download: integrity check failed: checksum mismatch" {
+		t.Errorf("Verified condition = %+v", cond)
+	}
 }
 
 func TestRestoreWaitsForCompletedBackup(t *testing.T) {
@@ -126,6 +192,170 @@ func TestRestoreWaitsForCompletedBackup(t *testing.T) {
 	}
 }
 
+func clusterWithMetadataLog() *seaweedv1.Seaweed {
+	m := clusterWithFilesystemStorage()
+	m.Spec.Backup.MetadataLog = []seaweedv1.BackupMetadataLogSpec{{StorageName: "pvc"}}
+	return m
+}
+
+func backupStartedAt(name string, at time.Time) *seaweedv1.SeaweedBackup {
+	b := completedBackup(name)
+	b.Status.StartTime = &metav1.Time{Time: at}
+	return b
+}
+
+func TestPointInTimeRestorePicksLatestBaseAndReplays(t *testing.T) {
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	pit := metav1.NewTime(base.Add(90 * time.Minute))
+	restore := &seaweedv1.SeaweedRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "rs1", Namespace: "ns1"},
+		Spec:       seaweedv1.SeaweedRestoreSpec{ClusterName: "c1", FilerPath: "/", PointInTime: &pit},
+	}
+	r := newRestoreReconciler(t, clusterWithMetadataLog(), restore,
+		backupStartedAt("early", base),
+		backupStartedAt("nearest", base.Add(time.Hour)),
+		backupStartedAt("after", base.Add(2*time.Hour)),
+	)
+	r.Now = func() time.Time { return base.Add(3 * time.Hour) }
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "rs1"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	var got seaweedv1.SeaweedRestore
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.BaseBackup != "nearest" {
+		t.Fatalf("baseBackup = %q, want the latest backup before pointInTime", got.Status.BaseBackup)
+	}
+
+	var job batchv1.Job
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: boundedName("rs1", "-rst")}, &job); err != nil {
+		t.Fatalf("expected restore job: %v", err)
+	}
+	pod := job.Spec.Template.Spec
+	if len(pod.InitContainers) != 3 || len(pod.Containers) != 1 {
+		t.Fatalf("want log coverage check, snapshot download and load as init containers and replay as main container, got %d/%d", len(pod.InitContainers), len(pod.Containers))
+	}
+	if check := strings.Join(pod.InitContainers[0].Command, " "); pod.InitContainers[0].Name != metaLogCoverageContainer ||
+		!containsAll(check, "backup-tool metalog-replay", "-checkOnly", "-wait=13m", "-prefix=c1/metalog", "-until=2026-05-01T13:30:00Z") {
+		t.Errorf("first init container does not wait for the log to reach pointInTime: %s", check)
+	}
+	if download := strings.Join(pod.InitContainers[1].Command, " "); !strings.Contains(download, "-key=c1/nearest/filer.meta.gz") {
+		t.Errorf("init container does not download the base snapshot: %s", download)
+	}
+	load := pod.InitContainers[2].Command
+	if !containsAll(load[len(load)-1], "fs.meta.load", "/scratch/restore.meta.gz") {
+		t.Errorf("init container does not load the base snapshot:\n%s", load[len(load)-1])
+	}
+	replay := strings.Join(pod.Containers[0].Command, " ")
+	if !containsAll(replay, "backup-tool metalog-replay", "-dir=/backup", "-prefix=c1/metalog",
+		"-since=2026-05-01T13:00:00Z", "-until=2026-05-01T13:30:00Z") {
+		t.Errorf("replay command wrong: %s", replay)
+	}
+}
+
+func TestPointInTimeRestoreLogIncomplete(t *testing.T) {
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	pit := metav1.NewTime(base.Add(90 * time.Minute))
+	restore := &seaweedv1.SeaweedRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "rs1", Namespace: "ns1"},
+		Spec:       seaweedv1.SeaweedRestoreSpec{ClusterName: "c1", PointInTime: &pit},
+	}
+	r := newRestoreReconciler(t, clusterWithMetadataLog(), restore, backupStartedAt("nearest", base.Add(time.Hour)))
+	r.Now = func() time.Time { return base.Add(3 * time.Hour) }
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "rs1"}}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	jobName := boundedName("rs1", "-rst")
+	var job batchv1.Job
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: jobName}, &job); err != nil {
+		t.Fatal(err)
+	}
+	rules := job.Spec.PodFailurePolicy.Rules
+	if last := rules[len(rules)-1]; *last.OnExitCodes.ContainerName != metaLogCoverageContainer ||
+		last.OnExitCodes.Values[0] != backuptool.LogIncompleteExitCode || last.Action != batchv1.PodFailurePolicyActionFailJob {
+		t.Errorf("an incomplete log does not fail the job at once: %+v", rules)
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: jobName + "-x", Namespace: "ns1", Labels: map[string]string{"job-name": jobName}},
+		Status: corev1.PodStatus{InitContainerStatuses: []corev1.ContainerStatus{{
+			Name: metaLogCoverageContainer,
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+				ExitCode: backuptool.LogIncompleteExitCode,
+				Message:  "backup-tool metalog-replay: metadata log incomplete: the capture has not recorded every event up to 2026-05-01T13:30:00Z\n",
+			}},
+		}}},
+	}
+	if err := r.Create(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+	if err := r.Status().Update(ctx, &job); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	var got seaweedv1.SeaweedRestore
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != seaweedv1.RestorePhaseFailed {
+		t.Fatalf("phase = %q, want Failed", got.Status.Phase)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.RestoreConditionComplete)
+	if cond == nil || cond.Reason != "MetaLogIncomplete" || !strings.Contains(cond.Message, "up to 2026-05-01T13:30:00Z") {
+		t.Errorf("Complete condition = %+v", cond)
+	}
+}
+
+func TestPointInTimeRestorePending(t *testing.T) {
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	pit := metav1.NewTime(base.Add(time.Hour))
+	cases := []struct {
+		name    string
+		cluster *seaweedv1.Seaweed
+		tool    string
+		now     time.Time
+		reason  string
+	}{
+		{"future", clusterWithMetadataLog(), "operator:test", base, "PointInTimeInFuture"},
+		{"no tool image", clusterWithMetadataLog(), "", base.Add(2 * time.Hour), "BackupToolUnavailable"},
+		{"no metadata log", clusterWithFilesystemStorage(), "operator:test", base.Add(2 * time.Hour), "SourceUnresolved"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			restore := &seaweedv1.SeaweedRestore{
+				ObjectMeta: metav1.ObjectMeta{Name: "rs1", Namespace: "ns1"},
+				Spec:       seaweedv1.SeaweedRestoreSpec{ClusterName: "c1", PointInTime: &pit},
+			}
+			r := newRestoreReconciler(t, c.cluster, restore, backupStartedAt("bk1", base))
+			r.BackupToolImage = c.tool
+			r.Now = func() time.Time { return c.now }
+			ctx := context.Background()
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "rs1"}}
+			if _, err := r.Reconcile(ctx, req); err != nil {
+				t.Fatal(err)
+			}
+			var got seaweedv1.SeaweedRestore
+			if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+				t.Fatal(err)
+			}
+			cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.RestoreConditionSourceResolved)
+			if got.Status.Phase != seaweedv1.RestorePhasePending || cond == nil || cond.Reason != c.reason {
+				t.Fatalf("phase = %q, condition = %+v; want Pending with reason %s", got.Status.Phase, cond, c.reason)
+			}
+		})
+	}
+}
+
 func containsAll(s string, subs ...string) bool {
 	for _, sub := range subs {
 		found := false
@@ -141,3 +371,216 @@ func containsAll(s string, subs ...string) bool {
 	}
 	return true
 }
+
+func TestRestoreCrossNamespace(t *testing.T) {
+	backup := &seaweedv1.SeaweedBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "bk1", Namespace: "team"},
+		Spec:       seaweedv1.SeaweedBackupSpec{ClusterName: "c1", ClusterNamespace: "ns1", StorageName: "pvc"},
+		Status:     seaweedv1.SeaweedBackupStatus{Phase: seaweedv1.BackupPhaseCompleted},
+	}
+	restore := &seaweedv1.SeaweedRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "rs1", Namespace: "team"},
+		Spec:       seaweedv1.SeaweedRestoreSpec{ClusterName: "c1", ClusterNamespace: "ns1", BackupName: "bk1"},
+	}
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "team", Name: "rs1"}}
+
+	denied := newRestoreReconciler(t, clusterWithFilesystemStorage(), backup.DeepCopy(), restore.DeepCopy())
+	if _, err := denied.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	var got seaweedv1.SeaweedRestore
+	if err := denied.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.RestoreConditionReferenceGranted)
+	if got.Status.Phase != seaweedv1.RestorePhasePending || cond == nil || cond.Reason != "ReferenceGrantMissing" {
+		t.Fatalf("phase = %q, ReferenceGranted = %+v; want Pending with ReferenceGrantMissing", got.Status.Phase, cond)
+	}
+
+	r := newRestoreReconciler(t, clusterWithFilesystemStorage(), backup, restore, backupRefGrant())
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jobName := boundedName("team-rs1", "-rst")
+	var job batchv1.Job
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: jobName}, &job); err != nil {
+		t.Fatalf("expected restore job %q in the cluster namespace: %v", jobName, err)
+	}
+	if download := strings.Join(job.Spec.Template.Spec.InitContainers[0].Command, " "); !strings.Contains(download, "-key=c1/team/bk1/filer.meta.gz") {
+		t.Errorf("restore does not download the expected snapshot: %s", download)
+	}
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.RestoreConditionReferenceGranted); cond == nil || cond.Status != metav1.ConditionTrue || cond.Reason != "Granted" {
+		t.Errorf("ReferenceGranted = %+v once the grant was found, want True/Granted", cond)
+	}
+}
+
+func TestFullRestoreReseedsFromDataMirror(t *testing.T) {
+	restore := &seaweedv1.SeaweedRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "rs1", Namespace: "ns1"},
+		Spec: seaweedv1.SeaweedRestoreSpec{
+			ClusterName: "c1", BackupName: "bk1", FilerPath: "/", Mode: seaweedv1.RestoreModeFull,
+			DataMirror: &seaweedv1.RestoreDataMirror{ClusterName: "old", FilerPath: "/buckets"},
+		},
+	}
+	r := newRestoreReconciler(t, clusterWithFilesystemStorage(), completedBackup("bk1"), restore)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "rs1"}}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	var job batchv1.Job
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: boundedName("rs1", "-rst")}, &job); err != nil {
+		t.Fatalf("expected restore job: %v", err)
+	}
+	pod := job.Spec.Template.Spec
+	if len(pod.InitContainers) != 2 || pod.InitContainers[1].Name != "restore" {
+		t.Fatalf("init containers = %+v, want download then restore", pod.InitContainers)
+	}
+	reseed := pod.Containers[0]
+	cmd := strings.Join(reseed.Command, " ")
+	if reseed.Name != restoreReseedContainer || !containsAll(cmd, "reseed", "-dir=/backup", "-prefix=old/data",
+		"-sourceDir=/buckets", "-pathPrefix=/buckets", "-excludePrefix=/.seaweedfs-operator") {
+		t.Errorf("reseed container %s: %s", reseed.Name, cmd)
+	}
+
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	if err := r.Status().Update(ctx, &job); err != nil {
+		t.Fatal(err)
+	}
+	jobPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: job.Name + "-x", Namespace: "ns1", Labels: map[string]string{"job-name": job.Name}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name: restoreReseedContainer,
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+				Message: `{"files":3,"bytes":4096,"present":1,"missing":1,"missingPaths":["/buckets/b1/gone"]}`,
+			}},
+		}}},
+	}
+	if err := r.Create(ctx, jobPod); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	var got seaweedv1.SeaweedRestore
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != seaweedv1.RestorePhaseCompleted {
+		t.Fatalf("phase = %q, want Completed", got.Status.Phase)
+	}
+	if rs := got.Status.Reseed; rs == nil || rs.Files != 3 || rs.Bytes != 4096 || rs.Missing != 1 {
+		t.Errorf("reseed status = %+v, want 3 files, 4096 bytes, 1 missing", got.Status.Reseed)
+	}
+	if !meta.IsStatusConditionTrue(got.Status.Conditions, seaweedv1.RestoreConditionReseeded) {
+		t.Errorf("Reseeded condition = %+v", meta.FindStatusCondition(got.Status.Conditions, seaweedv1.RestoreConditionReseeded))
+	}
+}
+
+func TestFullRestoreRejectsDisjointDataMirror(t *testing.T) {
+	restore := &seaweedv1.SeaweedRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "rs1", Namespace: "ns1"},
+		Spec: seaweedv1.SeaweedRestoreSpec{
+			ClusterName: "c1", BackupName: "bk1", FilerPath: "/buckets/a", Mode: seaweedv1.RestoreModeFull,
+			DataMirror: &seaweedv1.RestoreDataMirror{FilerPath: "/buckets/b"},
+		},
+	}
+	r := newRestoreReconciler(t, clusterWithFilesystemStorage(), completedBackup("bk1"), restore)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "rs1"}}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	var got seaweedv1.SeaweedRestore
+	if err := r.Get(context.Background(), req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	c := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.RestoreConditionSourceResolved)
+	if got.Status.Phase != seaweedv1.RestorePhasePending || c == nil || c.Reason != "DataMirrorUnresolved" {
+		t.Errorf("status = %+v, want Pending with reason DataMirrorUnresolved", got.Status)
+	}
+}
+
+func TestVolumesRestoreReportsRestoredVolumes(t *testing.T) {
+	restore := &seaweedv1.SeaweedRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "rs1", Namespace: "ns1"},
+		Spec:       seaweedv1.SeaweedRestoreSpec{ClusterName: "c1", BackupName: "bk1", Mode: seaweedv1.RestoreModeVolumes},
+	}
+	backup := completedBackup("bk1")
+	backup.Spec.Target = seaweedv1.BackupTargetVolumes
+	backup.Status.Checksum = "sha256:abcd"
+	r := newRestoreReconciler(t, clusterWithFilesystemStorage(), backup, restore)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "rs1"}}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	jobName := boundedName("rs1", "-rst")
+	var job batchv1.Job
+	if err := r.Get(ctx, types.NamespacedName{Namespace: "ns1", Name: jobName}, &job); err != nil {
+		t.Fatalf("expected restore job %q: %v", jobName, err)
+	}
+	download := strings.Join(job.Spec.Template.Spec.InitContainers[0].Command, " ")
+	if !containsAll(download, "volume-restore", "-manifest=c1/volumes/bk1/manifest.json", "-sha256=abcd") {
+		t.Errorf("download does not verify the expected manifest: %s", download)
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: jobName + "-x", Namespace: "ns1", Labels: map[string]string{"job-name": jobName}},
+		Status: corev1.PodStatus{InitContainerStatuses: []corev1.ContainerStatus{{
+			Name: snapshotDownloadContainer,
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+				Message: `{"restored":3,"bytes":4096,"present":2}`,
+			}},
+		}}},
+	}
+	if err := r.Create(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	if err := r.Status().Update(ctx, &job); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	var got seaweedv1.SeaweedRestore
+	if err := r.Get(ctx, req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != seaweedv1.RestorePhaseCompleted {
+		t.Fatalf("phase = %q, want Completed", got.Status.Phase)
+	}
+	if v := got.Status.Volumes; v == nil || v.Restored != 3 || v.Bytes != 4096 || v.Present != 2 {
+		t.Errorf("volumes status = %+v", v)
+	}
+}
+
+func TestRestoreRejectsMismatchedTarget(t *testing.T) {
+	restore := &seaweedv1.SeaweedRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "rs1", Namespace: "ns1"},
+		Spec:       seaweedv1.SeaweedRestoreSpec{ClusterName: "c1", BackupName: "bk1"},
+	}
+	backup := completedBackup("bk1")
+	backup.Spec.Target = seaweedv1.BackupTargetVolumes
+	r := newRestoreReconciler(t, clusterWithFilesystemStorage(), backup, restore)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "rs1"}}
+	if _, err := r.Reconcile(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	var got seaweedv1.SeaweedRestore
+	if err := r.Get(context.Background(), req.NamespacedName, &got); err != nil {
+		t.Fatal(err)
+	}
+	c := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.RestoreConditionSourceResolved)
+	if got.Status.Phase != seaweedv1.RestorePhasePending || c == nil || c.Reason != "SourceUnresolved" {
+		t.Errorf("status = %+v, want Pending with reason SourceUnresolved", got.Status)
+	}
+}
@@ -0,0 +1,290 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// SFTPUserFinalizer keeps the CR around until its entry has been dropped
+// from the generated user store, which lives in the cluster's namespace.
+const SFTPUserFinalizer = "seaweed.seaweedfs.com/sftpuser-protection"
+
+// SFTPUserReconciler provisions SFTPUsers: it creates each user's home
+// directory through the filer and aggregates every user of a cluster into
+// the generated user store Secret the SFTP gateway mounts. The Seaweed
+// reconciler watches that Secret and rolls the gateway when it changes.
+type SFTPUserReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	// AdminFactory creates a BucketAdmin used to create home directories.
+	// Tests inject a fake; production wires NewSwadminBucketAdmin.
+	AdminFactory BucketAdminFactory
+}
+
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=sftpusers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=sftpusers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=sftpusers/finalizers,verbs=update
+// +kubebuilder:rbac:groups=seaweed.seaweedfs.com,resources=seaweeds,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile implements the SFTP user reconciliation logic.
+func (r *SFTPUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := r.Log.WithValues("sftpuser", req.NamespacedName)
+
+	var user seaweedv1.SFTPUser
+	if err := r.Get(ctx, req.NamespacedName, &user); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !user.DeletionTimestamp.IsZero() {
+		return r.handleDeletion(ctx, &user, log)
+	}
+
+	if !controllerutil.ContainsFinalizer(&user, SFTPUserFinalizer) {
+		controllerutil.AddFinalizer(&user, SFTPUserFinalizer)
+		if err := r.Update(ctx, &user); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	base := user.Status.DeepCopy()
+	defer func() {
+		if err != nil || reflect.DeepEqual(*base, user.Status) {
+			return
+		}
+		if uerr := r.Status().Update(ctx, &user); uerr != nil {
+			result, err = ctrl.Result{}, uerr
+		}
+	}()
+
+	ref := user.Spec.SeaweedRef
+	permitted, err := seaweedRefPermitted(ctx, r.Client, ref, kindSFTPUser, user.Namespace)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !permitted {
+		return r.failPhase(&user, "ReferenceNotPermitted", seaweedRefDeniedMessage(ref, kindSFTPUser, user.Namespace)), nil
+	}
+	seaweed, err := r.getSeaweed(ctx, &user)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if seaweed == nil {
+		return r.pending(&user, "SeaweedNotFound",
+			fmt.Sprintf("Seaweed %q not found in namespace %q", ref.Name, sftpUserSeaweedNamespace(&user))), nil
+	}
+
+	winner, err := sftpUserConflict(ctx, r.Client, &user)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if winner != nil {
+		msg := fmt.Sprintf("username %q is already claimed by SFTPUser %s/%s", user.Spec.Username, winner.Namespace, winner.Name)
+		r.setCondition(&user, seaweedv1.SFTPUserConditionConflict, metav1.ConditionTrue, "UsernameTaken", msg)
+		return r.failPhase(&user, "Conflict", msg), nil
+	}
+	meta.RemoveStatusCondition(&user.Status.Conditions, seaweedv1.SFTPUserConditionConflict)
+
+	if _, err := renderSFTPStoreUser(ctx, r.Client, &user); err != nil {
+		var invalid *sftpUserInvalidError
+		if !errors.As(err, &invalid) {
+			return ctrl.Result{}, err
+		}
+		return r.failPhase(&user, "CredentialsUnavailable", err.Error()), nil
+	}
+
+	switch {
+	case seaweed.Spec.SFTP == nil:
+		return r.pending(&user, "SFTPGatewayDisabled", "the Seaweed cluster does not run an SFTP gateway (spec.sftp is unset)"), nil
+	case seaweed.Spec.SFTP.UserStoreSecret != nil:
+		return r.pending(&user, "UserStoreOverridden", "the Seaweed cluster's spec.sftp.userStoreSecret takes precedence over the generated user store"), nil
+	}
+
+	homeDir := sftpUserHomeDir(&user)
+	admin, err := r.adminFor(ctx, seaweed, log)
+	if err != nil {
+		return r.failPhase(&user, "FilerUnavailable", err.Error()), nil
+	}
+	created, err := admin.EnsureDirectory(ctx, homeDir, sftpUserID(user.Spec.UID), sftpUserID(user.Spec.GID))
+	closeBucketAdmin(admin, log)
+	if err != nil {
+		return r.failPhase(&user, "HomeDirectoryFailed", err.Error()), nil
+	}
+	if created {
+		log.Info("created sftp home directory", "path", homeDir)
+	}
+	user.Status.HomeDir = homeDir
+
+	if err := syncSFTPUserStore(ctx, r.Client, r.Scheme, seaweed); err != nil {
+		return ctrl.Result{}, err
+	}
+	user.Status.UserStoreSecret = sftpUserStoreSecretName(seaweed)
+	user.Status.ObservedGeneration = user.Generation
+	user.Status.Phase = seaweedv1.S3PhaseReady
+	r.setCondition(&user, seaweedv1.SFTPUserConditionReady, metav1.ConditionTrue, "Provisioned", "")
+	return ctrl.Result{}, nil
+}
+
+// handleDeletion drops the user from the generated store, then removes the
+// finalizer. The home directory and its files are kept.
+func (r *SFTPUserReconciler) handleDeletion(ctx context.Context, user *seaweedv1.SFTPUser, log logr.Logger) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(user, SFTPUserFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	seaweed, err := r.getSeaweed(ctx, user)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if seaweed != nil {
+		if err := syncSFTPUserStore(ctx, r.Client, r.Scheme, seaweed); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	log.Info("removed sftp user", "username", user.Spec.Username)
+	controllerutil.RemoveFinalizer(user, SFTPUserFinalizer)
+	if err := r.Update(ctx, user); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// getSeaweed returns the referenced Seaweed, or nil when it does not exist.
+func (r *SFTPUserReconciler) getSeaweed(ctx context.Context, user *seaweedv1.SFTPUser) (*seaweedv1.Seaweed, error) {
+	var seaweed seaweedv1.Seaweed
+	key := types.NamespacedName{Namespace: sftpUserSeaweedNamespace(user), Name: user.Spec.SeaweedRef.Name}
+	if err := r.Get(ctx, key, &seaweed); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &seaweed, nil
+}
+
+// adminFor builds a BucketAdmin for the given Seaweed cluster.
+func (r *SFTPUserReconciler) adminFor(ctx context.Context, seaweed *seaweedv1.Seaweed, log logr.Logger) (BucketAdmin, error) {
+	adminKey, err := loadFilerAdminSigningKey(ctx, r.Client, seaweed)
+	if err != nil {
+		return nil, err
+	}
+	dialOption, _, err := loadSeaweedGrpcDialOption(ctx, r.Client, seaweed)
+	if err != nil {
+		return nil, err
+	}
+	return r.AdminFactory(getMasterPeersString(seaweed), getFilerAddress(seaweed), adminKey, dialOption, log)
+}
+
+// pending records a Pending phase with the dependency that is missing and
+// requeues on the transient cadence.
+func (r *SFTPUserReconciler) pending(user *seaweedv1.SFTPUser, reason, message string) ctrl.Result {
+	user.Status.Phase = seaweedv1.S3PhasePending
+	r.setCondition(user, seaweedv1.SFTPUserConditionReady, metav1.ConditionFalse, reason, message)
+	return ctrl.Result{RequeueAfter: requeueAfterTransient}
+}
+
+func (r *SFTPUserReconciler) failPhase(user *seaweedv1.SFTPUser, reason, message string) ctrl.Result {
+	r.Log.Info("reconcile failed", "reason", reason, "message", message)
+	user.Status.Phase = seaweedv1.S3PhaseFailed
+	r.setCondition(user, seaweedv1.SFTPUserConditionReady, metav1.ConditionFalse, reason, message)
+	return ctrl.Result{RequeueAfter: requeueAfterTransient}
+}
+
+func (r *SFTPUserReconciler) setCondition(user *seaweedv1.SFTPUser, condType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&user.Status.Conditions, metav1.Condition{
+		Type:               condType,
+		Status:             status,
+		ObservedGeneration: user.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// mapSecretToSFTPUsers enqueues the users whose password or authorized keys
+// live in a Secret, so rotating either reaches the user store.
+func (r *SFTPUserReconciler) mapSecretToSFTPUsers(ctx context.Context, obj client.Object) []reconcile.Request {
+	var list seaweedv1.SFTPUserList
+	if err := r.List(ctx, &list, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+	var reqs []reconcile.Request
+	for i := range list.Items {
+		u := &list.Items[i]
+		if sel := u.Spec.PasswordSecret; sel != nil && sel.Name == obj.GetName() {
+			reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(u)})
+			continue
+		}
+		if sel := u.Spec.AuthorizedKeysSecret; sel != nil && sel.Name == obj.GetName() {
+			reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(u)})
+		}
+	}
+	return reqs
+}
+
+// mapSeaweedToSFTPUsers enqueues the users of a cluster, so enabling the
+// gateway or dropping spec.sftp.userStoreSecret provisions them.
+func (r *SFTPUserReconciler) mapSeaweedToSFTPUsers(ctx context.Context, obj client.Object) []reconcile.Request {
+	var list seaweedv1.SFTPUserList
+	if err := r.List(ctx, &list); err != nil {
+		return nil
+	}
+	cluster := obj.GetNamespace() + "/" + obj.GetName()
+	var reqs []reconcile.Request
+	for i := range list.Items {
+		u := &list.Items[i]
+		if seaweedRefKey(u.Spec.SeaweedRef, u.Namespace) == cluster {
+			reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(u)})
+		}
+	}
+	return reqs
+}
+
+// SetupWithManager wires the reconciler into the controller-runtime manager.
+func (r *SFTPUserReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.AdminFactory == nil {
+		r.AdminFactory = NewSwadminBucketAdmin
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&seaweedv1.SFTPUser{}, builder.WithPredicates(specOrDeletionChanged)).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.mapSecretToSFTPUsers)).
+		Watches(&seaweedv1.Seaweed{}, handler.EnqueueRequestsFromMapFunc(r.mapSeaweedToSFTPUsers),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
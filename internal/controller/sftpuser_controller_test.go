@@ -0,0 +1,408 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+const testSFTPKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl"
+
+func testSFTPUserReconciler(t *testing.T, fa *fakeBucketAdmin, objs ...client.Object) (*SFTPUserReconciler, client.Client) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("clientgoscheme: %v", err)
+	}
+	if err := seaweedv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("seaweedv1: %v", err)
+	}
+	cli := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&seaweedv1.SFTPUser{}).
+		Build()
+	r := &SFTPUserReconciler{
+		Client: cli,
+		Log:    logf.FromContext(context.Background()),
+		Scheme: scheme,
+		AdminFactory: func(_, _ string, _ []byte, _ grpc.DialOption, _ logr.Logger) (BucketAdmin, error) {
+			return fa, nil
+		},
+	}
+	return r, cli
+}
+
+func newSFTPCluster() *seaweedv1.Seaweed {
+	return &seaweedv1.Seaweed{
+		ObjectMeta: metav1.ObjectMeta{Name: "prod", Namespace: "seaweedfs", UID: "prod-uid"},
+		Spec: seaweedv1.SeaweedSpec{
+			Master: &seaweedv1.MasterSpec{Replicas: 1},
+			Filer:  &seaweedv1.FilerSpec{Replicas: 1},
+			SFTP:   &seaweedv1.SFTPSpec{Replicas: 1},
+		},
+	}
+}
+
+func newSFTPCredentials(namespace string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "sftp-creds", Namespace: namespace},
+		Data: map[string][]byte{
+			"password":        []byte("s3cret\n"),
+			"authorized_keys": []byte("# laptop\n" + testSFTPKey + " alice@laptop\n"),
+		},
+	}
+}
+
+func newSFTPUser(name, namespace, username string, created time.Time) *seaweedv1.SFTPUser {
+	return &seaweedv1.SFTPUser{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, CreationTimestamp: metav1.NewTime(created)},
+		Spec: seaweedv1.SFTPUserSpec{
+			SeaweedRef: seaweedv1.SeaweedReference{Name: "prod", Namespace: "seaweedfs"},
+			Username:   username,
+			PasswordSecret: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "sftp-creds"},
+				Key:                  "password",
+			},
+		},
+	}
+}
+
+func reconcileSFTPUser(t *testing.T, r *SFTPUserReconciler, user *seaweedv1.SFTPUser) ctrl.Result {
+	t.Helper()
+	var res ctrl.Result
+	for i := 0; i < 3; i++ {
+		var err error
+		res, err = r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(user)})
+		if err != nil {
+			t.Fatalf("reconcile step %d: %v", i, err)
+		}
+	}
+	return res
+}
+
+func readSFTPUserStore(t *testing.T, cli client.Client) []sftpStoreUser {
+	t.Helper()
+	var secret corev1.Secret
+	if err := cli.Get(context.Background(), types.NamespacedName{Namespace: "seaweedfs", Name: "prod-sftp-users"}, &secret); err != nil {
+		t.Fatalf("get user store: %v", err)
+	}
+	var users []sftpStoreUser
+	if err := json.Unmarshal(secret.Data[sftpUserStoreKey], &users); err != nil {
+		t.Fatalf("decode user store: %v", err)
+	}
+	return users
+}
+
+func TestSFTPUserProvisionsHomeAndStore(t *testing.T) {
+	uid, gid := int32(1001), int32(1002)
+	user := newSFTPUser("alice", "media", "alice", time.Now())
+	user.Spec.AuthorizedKeysSecret = &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "sftp-creds"},
+		Key:                  "authorized_keys",
+	}
+	user.Spec.UID, user.Spec.GID = &uid, &gid
+	user.Spec.Permissions = []seaweedv1.SFTPUserPathPermission{
+		{Path: "/home/alice", Actions: []seaweedv1.SFTPPermission{seaweedv1.SFTPPermissionAll}},
+		{Path: "/buckets/shared/", Actions: []seaweedv1.SFTPPermission{seaweedv1.SFTPPermissionRead, seaweedv1.SFTPPermissionList}},
+	}
+	grant := &seaweedv1.ResourceReferenceGrant{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-media", Namespace: "seaweedfs"},
+		Spec: seaweedv1.ResourceReferenceGrantSpec{
+			From: []seaweedv1.ReferenceGrantFrom{{Group: groupSeaweed, Kind: kindSFTPUser, Namespace: "media"}},
+			To:   []seaweedv1.ReferenceGrantTo{{Group: groupSeaweed, Kind: kindSeaweed}},
+		},
+	}
+	fa := newFakeAdmin()
+	r, cli := testSFTPUserReconciler(t, fa, newSFTPCluster(), newSFTPCredentials("media"), user, grant)
+
+	reconcileSFTPUser(t, r, user)
+
+	var got seaweedv1.SFTPUser
+	if err := cli.Get(context.Background(), client.ObjectKeyFromObject(user), &got); err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if got.Status.Phase != seaweedv1.S3PhaseReady {
+		t.Fatalf("phase = %q, conditions = %+v", got.Status.Phase, got.Status.Conditions)
+	}
+	if got.Status.HomeDir != "/home/alice" || got.Status.UserStoreSecret != "prod-sftp-users" {
+		t.Errorf("status = %+v", got.Status)
+	}
+	if owner := fa.directories["/home/alice"]; owner != "1001:1002" {
+		t.Errorf("home directory owner = %q, want 1001:1002", owner)
+	}
+
+	users := readSFTPUserStore(t, cli)
+	if len(users) != 1 {
+		t.Fatalf("store = %+v", users)
+	}
+	u := users[0]
+	if u.Username != "alice" || u.Password != "s3cret" || u.HomeDir != "/home/alice" || u.Uid != 1001 || u.Gid != 1002 {
+		t.Errorf("store entry = %+v", u)
+	}
+	if len(u.PublicKeys) != 1 || u.PublicKeys[0] != testSFTPKey {
+		t.Errorf("public keys = %q", u.PublicKeys)
+	}
+	if strings.Join(u.Permissions["/home/alice"], ",") != "*" || strings.Join(u.Permissions["/buckets/shared"], ",") != "list,read" {
+		t.Errorf("permissions = %v", u.Permissions)
+	}
+}
+
+func TestSFTPUserCrossNamespaceRequiresGrant(t *testing.T) {
+	user := newSFTPUser("alice", "media", "alice", time.Now())
+	fa := newFakeAdmin()
+	r, cli := testSFTPUserReconciler(t, fa, newSFTPCluster(), newSFTPCredentials("media"), user)
+
+	reconcileSFTPUser(t, r, user)
+
+	var got seaweedv1.SFTPUser
+	if err := cli.Get(context.Background(), client.ObjectKeyFromObject(user), &got); err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.SFTPUserConditionReady)
+	if got.Status.Phase != seaweedv1.S3PhaseFailed || cond == nil || cond.Reason != "ReferenceNotPermitted" {
+		t.Fatalf("phase = %q, ready = %+v", got.Status.Phase, cond)
+	}
+	if len(fa.calls) != 0 {
+		t.Errorf("unexpected filer calls %v", fa.calls)
+	}
+}
+
+func TestSFTPUserUsernameConflict(t *testing.T) {
+	now := time.Now()
+	older := newSFTPUser("alice", "seaweedfs", "alice", now.Add(-time.Hour))
+	newer := newSFTPUser("alice-again", "seaweedfs", "alice", now)
+	newer.Spec.HomeDir = "/srv/alice"
+	r, cli := testSFTPUserReconciler(t, newFakeAdmin(), newSFTPCluster(), newSFTPCredentials("seaweedfs"), older, newer)
+
+	reconcileSFTPUser(t, r, older)
+	reconcileSFTPUser(t, r, newer)
+
+	var got seaweedv1.SFTPUser
+	if err := cli.Get(context.Background(), client.ObjectKeyFromObject(newer), &got); err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if got.Status.Phase != seaweedv1.S3PhaseFailed || !meta.IsStatusConditionTrue(got.Status.Conditions, seaweedv1.SFTPUserConditionConflict) {
+		t.Fatalf("newer claimant phase = %q, conditions = %+v", got.Status.Phase, got.Status.Conditions)
+	}
+	users := readSFTPUserStore(t, cli)
+	if len(users) != 1 || users[0].HomeDir != "/home/alice" {
+		t.Errorf("store = %+v, want only the older claimant", users)
+	}
+}
+
+func TestSFTPUserUsernamePassesOverIneligibleClaimants(t *testing.T) {
+	now := time.Now()
+	// The oldest claimant is denied its cross-namespace reference and the
+	// next one cannot read its password; neither holds the username.
+	denied := newSFTPUser("alice", "media", "alice", now.Add(-2*time.Hour))
+	broken := newSFTPUser("alice-broken", "seaweedfs", "alice", now.Add(-time.Hour))
+	broken.Spec.PasswordSecret.Name = "missing"
+	newer := newSFTPUser("alice-again", "seaweedfs", "alice", now)
+	newer.Spec.HomeDir = "/srv/alice"
+	r, cli := testSFTPUserReconciler(t, newFakeAdmin(), newSFTPCluster(), newSFTPCredentials("seaweedfs"), denied, broken, newer)
+
+	reconcileSFTPUser(t, r, newer)
+
+	var got seaweedv1.SFTPUser
+	if err := cli.Get(context.Background(), client.ObjectKeyFromObject(newer), &got); err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if got.Status.Phase != seaweedv1.S3PhaseReady || meta.FindStatusCondition(got.Status.Conditions, seaweedv1.SFTPUserConditionConflict) != nil {
+		t.Fatalf("phase = %q, conditions = %+v; want Ready without a conflict", got.Status.Phase, got.Status.Conditions)
+	}
+	users := readSFTPUserStore(t, cli)
+	if len(users) != 1 || users[0].HomeDir != "/srv/alice" {
+		t.Errorf("store = %+v, want the eligible claimant", users)
+	}
+}
+
+func TestSFTPUserUsernameKeptOnTransientReadError(t *testing.T) {
+	now := time.Now()
+	owner := newSFTPUser("alice", "seaweedfs", "alice", now.Add(-time.Hour))
+	owner.Spec.PasswordSecret.Name = "alice-creds"
+	ownerCreds := newSFTPCredentials("seaweedfs")
+	ownerCreds.Name = "alice-creds"
+	newer := newSFTPUser("alice-again", "seaweedfs", "alice", now)
+	r, cli := testSFTPUserReconciler(t, newFakeAdmin(), newSFTPCluster(), newSFTPCredentials("seaweedfs"), ownerCreds, owner, newer)
+	// The owner's Secret cannot be read for a moment.
+	r.Client = interceptor.NewClient(cli.(client.WithWatch), interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			if key.Name == "alice-creds" {
+				return errors.New("apiserver unavailable")
+			}
+			return c.Get(ctx, key, obj, opts...)
+		},
+	})
+
+	if winner, err := sftpUserConflict(context.Background(), r.Client, newer); err == nil || winner != nil {
+		t.Errorf("sftpUserConflict = %v, %v; want the read error, not a handover", winner, err)
+	}
+	if err := syncSFTPUserStore(context.Background(), r.Client, r.Scheme, newSFTPCluster()); err == nil {
+		t.Errorf("expected syncSFTPUserStore to fail rather than hand the username over")
+	}
+}
+
+func TestSFTPUserDeletionDropsFromStore(t *testing.T) {
+	alice := newSFTPUser("alice", "seaweedfs", "alice", time.Now())
+	bob := newSFTPUser("bob", "seaweedfs", "bob", time.Now())
+	r, cli := testSFTPUserReconciler(t, newFakeAdmin(), newSFTPCluster(), newSFTPCredentials("seaweedfs"), alice, bob)
+	ctx := context.Background()
+
+	reconcileSFTPUser(t, r, alice)
+	reconcileSFTPUser(t, r, bob)
+	if users := readSFTPUserStore(t, cli); len(users) != 2 {
+		t.Fatalf("store = %+v", users)
+	}
+
+	if err := cli.Get(ctx, client.ObjectKeyFromObject(bob), bob); err != nil {
+		t.Fatalf("get bob: %v", err)
+	}
+	if err := cli.Delete(ctx, bob); err != nil {
+		t.Fatalf("delete bob: %v", err)
+	}
+	reconcileSFTPUser(t, r, bob)
+
+	users := readSFTPUserStore(t, cli)
+	if len(users) != 1 || users[0].Username != "alice" {
+		t.Errorf("store = %+v, want only alice", users)
+	}
+	if err := cli.Get(ctx, client.ObjectKeyFromObject(bob), bob); err == nil {
+		t.Errorf("expected bob to be gone once its finalizer was removed")
+	}
+}
+
+func TestSFTPUserWaitsForGateway(t *testing.T) {
+	cluster := newSFTPCluster()
+	cluster.Spec.SFTP = nil
+	user := newSFTPUser("alice", "seaweedfs", "alice", time.Now())
+	fa := newFakeAdmin()
+	r, cli := testSFTPUserReconciler(t, fa, cluster, newSFTPCredentials("seaweedfs"), user)
+
+	res := reconcileSFTPUser(t, r, user)
+
+	var got seaweedv1.SFTPUser
+	if err := cli.Get(context.Background(), client.ObjectKeyFromObject(user), &got); err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, seaweedv1.SFTPUserConditionReady)
+	if got.Status.Phase != seaweedv1.S3PhasePending || cond == nil || cond.Reason != "SFTPGatewayDisabled" {
+		t.Fatalf("phase = %q, ready = %+v", got.Status.Phase, cond)
+	}
+	if res.RequeueAfter != requeueAfterTransient {
+		t.Errorf("requeueAfter = %v", res.RequeueAfter)
+	}
+	if len(fa.calls) != 0 {
+		t.Errorf("unexpected filer calls %v", fa.calls)
+	}
+}
+
+func TestParseAuthorizedKeys(t *testing.T) {
+	keys, err := parseAuthorizedKeys([]byte("\n# comment\n" +
+		`no-pty,from="10.0.0.0/8" ` + testSFTPKey + " alice@laptop\n" +
+		"ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQ==\n"))
+	if err != nil {
+		t.Fatalf("parseAuthorizedKeys: %v", err)
+	}
+	want := []string{testSFTPKey, "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQ=="}
+	if strings.Join(keys, "|") != strings.Join(want, "|") {
+		t.Errorf("keys = %q, want %q", keys, want)
+	}
+
+	for _, bad := range []string{"not a key\n", "ssh-ed25519 !!!notbase64\n"} {
+		if _, err := parseAuthorizedKeys([]byte(bad)); err == nil {
+			t.Errorf("parseAuthorizedKeys(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestSFTPDeploymentMountsGeneratedStore(t *testing.T) {
+	m := newSFTPCluster()
+	r, cli := newNotificationReconciler(t, m)
+	ctx := context.Background()
+
+	deployment := func() *appsv1.Deployment {
+		t.Helper()
+		if _, _, err := r.ensureSFTPDeployment(ctx, m); err != nil {
+			t.Fatalf("ensureSFTPDeployment: %v", err)
+		}
+		var dep appsv1.Deployment
+		if err := cli.Get(ctx, types.NamespacedName{Namespace: "seaweedfs", Name: "prod-sftp"}, &dep); err != nil {
+			t.Fatalf("get deployment: %v", err)
+		}
+		return &dep
+	}
+
+	dep := deployment()
+	if strings.Contains(dep.Spec.Template.Spec.Containers[0].Command[2], "-userStoreFile") {
+		t.Fatalf("expected no user store before any SFTPUser exists")
+	}
+
+	if err := syncSFTPUserStore(ctx, cli, r.Scheme, m); err != nil {
+		t.Fatalf("syncSFTPUserStore: %v", err)
+	}
+	dep = deployment()
+	if !strings.Contains(dep.Spec.Template.Spec.Containers[0].Command[2], "-userStoreFile=/etc/sw/"+sftpUserStoreKey) {
+		t.Errorf("command does not load the generated store: %s", dep.Spec.Template.Spec.Containers[0].Command[2])
+	}
+	vol := podVolume(t, &dep.Spec.Template.Spec, "sftp-userstore")
+	if vol.Secret == nil || vol.Secret.SecretName != "prod-sftp-users" {
+		t.Errorf("user store volume = %+v", vol)
+	}
+	first := dep.Spec.Template.Annotations[sftpUserStoreAnnotation]
+	if first == "" {
+		t.Fatalf("expected the user store revision on the pod template")
+	}
+
+	var store corev1.Secret
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: "seaweedfs", Name: "prod-sftp-users"}, &store); err != nil {
+		t.Fatalf("get user store: %v", err)
+	}
+	store.Data[sftpUserStoreKey] = []byte(`[{"username":"alice"}]`)
+	if err := cli.Update(ctx, &store); err != nil {
+		t.Fatalf("update user store: %v", err)
+	}
+	if second := deployment().Spec.Template.Annotations[sftpUserStoreAnnotation]; second == first {
+		t.Errorf("expected the revision to change with the store, stayed %q", first)
+	}
+
+	if reqs := mapSFTPUserStoreToSeaweed(ctx, &store); len(reqs) != 1 || reqs[0].Name != "prod" {
+		t.Errorf("store Secret maps to %v", reqs)
+	}
+}
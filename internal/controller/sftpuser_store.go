@@ -0,0 +1,306 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+)
+
+// sftpStoreUser is one entry of the JSON user store weed sftp loads with
+// -userStoreFile.
+type sftpStoreUser struct {
+	Username    string              `json:"username"`
+	Password    string              `json:"password,omitempty"`
+	PublicKeys  []string            `json:"publicKeys,omitempty"`
+	HomeDir     string              `json:"homeDir"`
+	Permissions map[string][]string `json:"permissions"`
+	Uid         uint32              `json:"uid"`
+	Gid         uint32              `json:"gid"`
+}
+
+// sftpStorePermissionAll is weed sftp's wildcard permission.
+const sftpStorePermissionAll = "*"
+
+func sftpUserSeaweedNamespace(user *seaweedv1.SFTPUser) string {
+	if ns := user.Spec.SeaweedRef.Namespace; ns != "" {
+		return ns
+	}
+	return user.Namespace
+}
+
+// sftpUserHomeDir resolves spec.homeDir, defaulting to /home/<username>.
+func sftpUserHomeDir(user *seaweedv1.SFTPUser) string {
+	if user.Spec.HomeDir != "" {
+		return path.Clean(user.Spec.HomeDir)
+	}
+	return "/home/" + user.Spec.Username
+}
+
+func sftpUserID(id *int32) uint32 {
+	if id == nil || *id < 0 {
+		return 0
+	}
+	return uint32(*id)
+}
+
+// sftpUserPermissions renders spec.permissions as weed sftp's path → actions
+// map, granting everything under the home directory when none are given.
+func sftpUserPermissions(user *seaweedv1.SFTPUser) map[string][]string {
+	if len(user.Spec.Permissions) == 0 {
+		return map[string][]string{sftpUserHomeDir(user): {sftpStorePermissionAll}}
+	}
+	perms := make(map[string][]string, len(user.Spec.Permissions))
+	for _, p := range user.Spec.Permissions {
+		actions := make([]string, 0, len(p.Actions))
+		for _, a := range p.Actions {
+			if a == seaweedv1.SFTPPermissionAll {
+				actions = append(actions, sftpStorePermissionAll)
+				continue
+			}
+			actions = append(actions, string(a))
+		}
+		sort.Strings(actions)
+		perms[path.Clean(p.Path)] = actions
+	}
+	return perms
+}
+
+// renderSFTPStoreUser builds the user's store entry, reading its password and
+// authorized keys from Secrets in its own namespace.
+func renderSFTPStoreUser(ctx context.Context, c client.Client, user *seaweedv1.SFTPUser) (sftpStoreUser, error) {
+	entry := sftpStoreUser{
+		Username:    user.Spec.Username,
+		HomeDir:     sftpUserHomeDir(user),
+		Permissions: sftpUserPermissions(user),
+		Uid:         sftpUserID(user.Spec.UID),
+		Gid:         sftpUserID(user.Spec.GID),
+	}
+	if sel := user.Spec.PasswordSecret; sel != nil {
+		v, err := readSFTPUserSecretKey(ctx, c, user.Namespace, sel)
+		if err != nil {
+			return sftpStoreUser{}, err
+		}
+		entry.Password = strings.TrimRight(string(v), "\r\n")
+		if entry.Password == "" {
+			return sftpStoreUser{}, invalidSFTPUser("key %q of Secret %q holds an empty password", sel.Key, sel.Name)
+		}
+	}
+	if sel := user.Spec.AuthorizedKeysSecret; sel != nil {
+		v, err := readSFTPUserSecretKey(ctx, c, user.Namespace, sel)
+		if err != nil {
+			return sftpStoreUser{}, err
+		}
+		keys, err := parseAuthorizedKeys(v)
+		if err != nil {
+			return sftpStoreUser{}, invalidSFTPUser("key %q of Secret %q: %v", sel.Key, sel.Name, err)
+		}
+		if len(keys) == 0 {
+			return sftpStoreUser{}, invalidSFTPUser("key %q of Secret %q holds no public keys", sel.Key, sel.Name)
+		}
+		entry.PublicKeys = keys
+	}
+	return entry, nil
+}
+
+// sftpUserInvalidError is a render failure the SFTPUser's own spec or
+// Secrets cause, as opposed to a failed API read.
+type sftpUserInvalidError struct {
+	msg string
+}
+
+func (e *sftpUserInvalidError) Error() string { return e.msg }
+
+func invalidSFTPUser(format string, args ...any) error {
+	return &sftpUserInvalidError{msg: fmt.Sprintf(format, args...)}
+}
+
+func readSFTPUserSecretKey(ctx context.Context, c client.Client, namespace string, sel *corev1.SecretKeySelector) ([]byte, error) {
+	var secret corev1.Secret
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: sel.Name}, &secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, invalidSFTPUser("secret %q not found in namespace %q", sel.Name, namespace)
+		}
+		return nil, err
+	}
+	v, ok := secret.Data[sel.Key]
+	if !ok {
+		return nil, invalidSFTPUser("secret %q has no key %q", sel.Name, sel.Key)
+	}
+	return v, nil
+}
+
+// parseAuthorizedKeys reads OpenSSH authorized_keys lines and returns each
+// key as "<type> <base64>", dropping options and comments, which weed sftp
+// does not understand.
+func parseAuthorizedKeys(data []byte) ([]string, error) {
+	var keys []string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		key := ""
+		for j := 0; j+1 < len(fields); j++ {
+			if !isSSHKeyType(fields[j]) {
+				continue
+			}
+			if _, err := base64.StdEncoding.DecodeString(fields[j+1]); err != nil {
+				return nil, fmt.Errorf("line %d: malformed %s key", i+1, fields[j])
+			}
+			key = fields[j] + " " + fields[j+1]
+			break
+		}
+		if key == "" {
+			return nil, fmt.Errorf("line %d: not an OpenSSH public key", i+1)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// isSSHKeyType reports whether s names an OpenSSH public key algorithm.
+func isSSHKeyType(s string) bool {
+	return strings.HasPrefix(s, "ssh-") || strings.HasPrefix(s, "ecdsa-sha2-") || strings.HasPrefix(s, "sk-")
+}
+
+// sftpUserClaimants returns the live SFTPUsers on cluster (namespace/name)
+// claiming username, oldest first.
+func sftpUserClaimants(ctx context.Context, c client.Client, cluster, username string) ([]*seaweedv1.SFTPUser, error) {
+	var list seaweedv1.SFTPUserList
+	if err := c.List(ctx, &list); err != nil {
+		return nil, err
+	}
+	var out []*seaweedv1.SFTPUser
+	for i := range list.Items {
+		u := &list.Items[i]
+		if !u.DeletionTimestamp.IsZero() || seaweedRefKey(u.Spec.SeaweedRef, u.Namespace) != cluster {
+			continue
+		}
+		if username != "" && u.Spec.Username != username {
+			continue
+		}
+		out = append(out, u)
+	}
+	sort.Slice(out, func(i, j int) bool { return claimPrecedes(out[i], out[j]) })
+	return out, nil
+}
+
+// sftpUserConflict returns the claimant that outranks self for its username,
+// or nil when self owns it. Like syncSFTPUserStore it passes over older
+// claimants that cannot hold the username, so that a denied or broken one
+// does not lock it out.
+func sftpUserConflict(ctx context.Context, c client.Client, self *seaweedv1.SFTPUser) (*seaweedv1.SFTPUser, error) {
+	peers, err := sftpUserClaimants(ctx, c, seaweedRefKey(self.Spec.SeaweedRef, self.Namespace), self.Spec.Username)
+	if err != nil {
+		return nil, err
+	}
+	for _, peer := range peers {
+		if peer.Namespace == self.Namespace && peer.Name == self.Name {
+			return nil, nil
+		}
+		_, ok, err := sftpStoreEntry(ctx, c, peer)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return peer, nil
+		}
+	}
+	return nil, nil
+}
+
+// sftpStoreEntry renders user's store entry when it can hold its username:
+// its cluster reference is permitted and its credentials exist and are
+// usable. Any other failure is returned, so that a transient read error
+// leaves the username with its current owner rather than passing it on.
+func sftpStoreEntry(ctx context.Context, c client.Client, user *seaweedv1.SFTPUser) (sftpStoreUser, bool, error) {
+	permitted, err := seaweedRefPermitted(ctx, c, user.Spec.SeaweedRef, kindSFTPUser, user.Namespace)
+	if err != nil || !permitted {
+		return sftpStoreUser{}, false, err
+	}
+	entry, err := renderSFTPStoreUser(ctx, c, user)
+	var invalid *sftpUserInvalidError
+	switch {
+	case errors.As(err, &invalid):
+		return sftpStoreUser{}, false, nil
+	case err != nil:
+		return sftpStoreUser{}, false, err
+	}
+	return entry, true, nil
+}
+
+// syncSFTPUserStore renders every live, permitted SFTPUser of seaweed into
+// the generated user store Secret. Each username goes to its oldest claimant
+// that can hold it; users whose credentials are missing or unusable are left
+// out until they are fixed, and the username passes to the next claimant
+// meanwhile. A failed API read aborts the sync and keeps the current store. The
+// Secret is kept even when empty: removing it would drop the gateway back to
+// running without authentication.
+func syncSFTPUserStore(ctx context.Context, c client.Client, scheme *runtime.Scheme, seaweed *seaweedv1.Seaweed) error {
+	users, err := sftpUserClaimants(ctx, c, seaweed.Namespace+"/"+seaweed.Name, "")
+	if err != nil {
+		return err
+	}
+	entries := []sftpStoreUser{}
+	claimed := map[string]bool{}
+	for _, u := range users {
+		if claimed[u.Spec.Username] {
+			continue
+		}
+		entry, ok, err := sftpStoreEntry(ctx, c, u)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		claimed[u.Spec.Username] = true
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Username < entries[j].Username })
+	store, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: sftpUserStoreSecretName(seaweed), Namespace: seaweed.Namespace}}
+	_, err = controllerutil.CreateOrUpdate(ctx, c, secret, func() error {
+		secret.Labels = labelsForSFTP(seaweed.Name)
+		secret.Type = corev1.SecretTypeOpaque
+		secret.Data = map[string][]byte{sftpUserStoreKey: store}
+		return controllerutil.SetControllerReference(seaweed, secret, scheme)
+	})
+	return err
+}
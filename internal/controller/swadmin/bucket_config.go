@@ -0,0 +1,123 @@
+package swadmin
+
+import (
+	"context"
+	"strings"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+)
+
+// Filer entry extended attributes holding a bucket's S3 sub-resources. They
+// match the keys the SeaweedFS S3 gateway reads and writes for the
+// corresponding Put/GetBucket* calls.
+const (
+	bucketCORSConfigurationXMLKey = "s3-bucket-cors-configuration-xml"
+	bucketPolicyKey               = "s3-bucket-policy"
+	bucketTagPrefix               = "X-Amz-Tagging-"
+)
+
+// BucketConfig is the bucket's CORS configuration XML, policy JSON and tags
+// as stored on its filer entry. Unset sub-resources are nil.
+type BucketConfig struct {
+	CORS   []byte
+	Policy []byte
+	Tags   map[string]string
+}
+
+// GetBucketConfig reads the bucket's CORS, policy and tags in one lookup.
+func (sa *SeaweedAdmin) GetBucketConfig(ctx context.Context, bucket string) (BucketConfig, error) {
+	var cfg BucketConfig
+	err := sa.commandEnv.WithFilerClient(false, func(client filer_pb.SeaweedFilerClient) error {
+		entry, _, err := lookupBucketEntry(ctx, client, bucket)
+		if err != nil {
+			return err
+		}
+		for k, v := range entry.Extended {
+			switch {
+			case k == bucketCORSConfigurationXMLKey && len(v) > 0:
+				cfg.CORS = append([]byte(nil), v...)
+			case k == bucketPolicyKey && len(v) > 0:
+				cfg.Policy = append([]byte(nil), v...)
+			case strings.HasPrefix(k, bucketTagPrefix):
+				if cfg.Tags == nil {
+					cfg.Tags = map[string]string{}
+				}
+				cfg.Tags[strings.TrimPrefix(k, bucketTagPrefix)] = string(v)
+			}
+		}
+		return nil
+	})
+	return cfg, err
+}
+
+// SetBucketCORS stores the CORS configuration XML. Empty xml removes it.
+func (sa *SeaweedAdmin) SetBucketCORS(ctx context.Context, bucket string, xml []byte) error {
+	return sa.updateBucketExtended(ctx, bucket, func(ext map[string][]byte) bool {
+		return setExtended(ext, bucketCORSConfigurationXMLKey, xml)
+	})
+}
+
+// SetBucketPolicy stores the bucket policy JSON. Empty policy removes it.
+func (sa *SeaweedAdmin) SetBucketPolicy(ctx context.Context, bucket string, policy []byte) error {
+	return sa.updateBucketExtended(ctx, bucket, func(ext map[string][]byte) bool {
+		return setExtended(ext, bucketPolicyKey, policy)
+	})
+}
+
+// SetBucketTags replaces the bucket's tags with tags. Empty tags removes all.
+func (sa *SeaweedAdmin) SetBucketTags(ctx context.Context, bucket string, tags map[string]string) error {
+	return sa.updateBucketExtended(ctx, bucket, func(ext map[string][]byte) bool {
+		changed := false
+		for k := range ext {
+			if strings.HasPrefix(k, bucketTagPrefix) {
+				if _, keep := tags[strings.TrimPrefix(k, bucketTagPrefix)]; !keep {
+					delete(ext, k)
+					changed = true
+				}
+			}
+		}
+		for k, v := range tags {
+			if setExtended(ext, bucketTagPrefix+k, []byte(v)) {
+				changed = true
+			}
+		}
+		return changed
+	})
+}
+
+// updateBucketExtended applies mutate to the bucket entry's extended
+// attributes and writes the entry back when mutate reports a change.
+func (sa *SeaweedAdmin) updateBucketExtended(ctx context.Context, bucket string, mutate func(ext map[string][]byte) bool) error {
+	return sa.commandEnv.WithFilerClient(false, func(client filer_pb.SeaweedFilerClient) error {
+		entry, dir, err := lookupBucketEntry(ctx, client, bucket)
+		if err != nil {
+			return err
+		}
+		if entry.Extended == nil {
+			entry.Extended = make(map[string][]byte)
+		}
+		if !mutate(entry.Extended) {
+			return nil
+		}
+		_, err = client.UpdateEntry(ctx, &filer_pb.UpdateEntryRequest{Directory: dir, Entry: entry})
+		return err
+	})
+}
+
+// setExtended sets key to value, deleting it when value is empty, and reports
+// whether anything changed.
+func setExtended(ext map[string][]byte, key string, value []byte) bool {
+	old, ok := ext[key]
+	if len(value) == 0 {
+		if !ok {
+			return false
+		}
+		delete(ext, key)
+		return true
+	}
+	if ok && string(old) == string(value) {
+		return false
+	}
+	ext[key] = append([]byte(nil), value...)
+	return true
+}
@@ -0,0 +1,25 @@
+package swadmin
+
+import "testing"
+
+func TestSetExtended(t *testing.T) {
+	ext := map[string][]byte{"keep": []byte("v")}
+	if !setExtended(ext, "new", []byte("x")) || string(ext["new"]) != "x" {
+		t.Fatalf("set of a new key not applied: %q", ext)
+	}
+	if setExtended(ext, "new", []byte("x")) {
+		t.Errorf("unchanged value reported as a change")
+	}
+	if !setExtended(ext, "new", nil) {
+		t.Errorf("delete not reported as a change")
+	}
+	if _, ok := ext["new"]; ok {
+		t.Errorf("empty value should delete the key")
+	}
+	if setExtended(ext, "missing", nil) {
+		t.Errorf("deleting an absent key reported as a change")
+	}
+	if string(ext["keep"]) != "v" {
+		t.Errorf("unrelated key touched: %q", ext)
+	}
+}
@@ -0,0 +1,68 @@
+package swadmin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/seaweedfs/seaweedfs/weed/filer"
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+)
+
+// The S3 gateway loads its circuit breaker from this filer file and watches it
+// for changes; `s3.circuitBreaker -apply` writes it.
+const (
+	circuitBreakerConfigDir  = "/etc/s3"
+	circuitBreakerConfigFile = "circuit_breaker.json"
+)
+
+// circuitBreakerMu serializes the operator's read-modify-write updates of
+// circuit_breaker.json, which the Seaweed and Bucket controllers both make.
+var circuitBreakerMu sync.Mutex
+
+// GetS3CircuitBreakerConfig returns the raw circuit_breaker.json the S3
+// gateways load, or nil when no circuit breaker has been configured.
+func (sa *SeaweedAdmin) GetS3CircuitBreakerConfig(ctx context.Context) ([]byte, error) {
+	var content []byte
+	err := sa.commandEnv.WithFilerClient(false, func(client filer_pb.SeaweedFilerClient) error {
+		data, err := filer.ReadInsideFiler(ctx, client, circuitBreakerConfigDir, circuitBreakerConfigFile)
+		if err != nil {
+			if isFilerNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		content = data
+		return nil
+	})
+	return content, err
+}
+
+// UpdateS3CircuitBreakerConfig replaces circuit_breaker.json with what update
+// returns for its current content (nil when unset), in a single write. An
+// unchanged result is not written.
+func (sa *SeaweedAdmin) UpdateS3CircuitBreakerConfig(ctx context.Context, update func(current []byte) ([]byte, error)) error {
+	circuitBreakerMu.Lock()
+	defer circuitBreakerMu.Unlock()
+	return sa.commandEnv.WithFilerClient(false, func(client filer_pb.SeaweedFilerClient) error {
+		current, err := filer.ReadInsideFiler(ctx, client, circuitBreakerConfigDir, circuitBreakerConfigFile)
+		if err != nil {
+			if !isFilerNotFound(err) {
+				return fmt.Errorf("read %s: %w", circuitBreakerConfigFile, err)
+			}
+			current = nil
+		}
+		next, err := update(current)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(next, current) {
+			return nil
+		}
+		if err := filer.SaveInsideFiler(ctx, client, circuitBreakerConfigDir, circuitBreakerConfigFile, next); err != nil {
+			return fmt.Errorf("save %s: %w", circuitBreakerConfigFile, err)
+		}
+		return nil
+	})
+}
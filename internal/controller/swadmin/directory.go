@@ -0,0 +1,65 @@
+package swadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+)
+
+// homeDirectoryMode is the permission set of directories created by
+// EnsureDirectory.
+const homeDirectoryMode = 0o750
+
+// EnsureDirectory creates the filer directory dirPath, owned by uid/gid, when
+// it does not exist yet, and reports whether it did. The filer creates missing
+// parents itself. An existing directory is left untouched so ownership or
+// mode changes made through the gateway are kept.
+func (sa *SeaweedAdmin) EnsureDirectory(ctx context.Context, dirPath string, uid, gid uint32) (bool, error) {
+	dir, name := path.Split(path.Clean(dirPath))
+	if name == "" {
+		return false, nil
+	}
+	dir = path.Clean(dir)
+	created := false
+	err := sa.commandEnv.WithFilerClient(false, func(client filer_pb.SeaweedFilerClient) error {
+		resp, err := client.LookupDirectoryEntry(ctx, &filer_pb.LookupDirectoryEntryRequest{Directory: dir, Name: name})
+		switch {
+		case err == nil && resp.Entry != nil:
+			if !resp.Entry.IsDirectory {
+				return fmt.Errorf("%s exists and is not a directory", dirPath)
+			}
+			return nil
+		case err != nil && !isFilerNotFound(err):
+			return fmt.Errorf("lookup %s: %w", dirPath, err)
+		}
+		now := time.Now().Unix()
+		createResp, err := client.CreateEntry(ctx, &filer_pb.CreateEntryRequest{
+			Directory: dir,
+			Entry: &filer_pb.Entry{
+				Name:        name,
+				IsDirectory: true,
+				Attributes: &filer_pb.FuseAttributes{
+					FileMode: uint32(os.ModeDir | homeDirectoryMode),
+					Uid:      uid,
+					Gid:      gid,
+					Mtime:    now,
+					Crtime:   now,
+				},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("create %s: %w", dirPath, err)
+		}
+		if createResp.Error != "" {
+			return fmt.Errorf("create %s: %w", dirPath, errors.New(createResp.Error))
+		}
+		created = true
+		return nil
+	})
+	return created, err
+}
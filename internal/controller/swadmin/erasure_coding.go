@@ -0,0 +1,133 @@
+package swadmin
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+	"sort"
+	"time"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb/master_pb"
+)
+
+// ECVolume is one erasure-coded volume and the shards the master knows of,
+// merged across every volume server holding some of them.
+type ECVolume struct {
+	ID         uint32
+	Collection string
+	// ShardBits has bit i set when shard i is present.
+	ShardBits uint32
+}
+
+// Shards is the number of shards present.
+func (v ECVolume) Shards() int {
+	return bits.OnesCount32(v.ShardBits)
+}
+
+// Volume is one plain volume, merged across its replicas.
+type Volume struct {
+	ID         uint32
+	Collection string
+	// Size is the largest size any replica reports, in bytes.
+	Size uint64
+	// ModifiedAt is the last write to any replica.
+	ModifiedAt time.Time
+	// RemoteStorage names the backend ("s3.<tier>") holding the volume's
+	// data once it was moved to a remote tier; empty while it is local.
+	RemoteStorage string
+}
+
+// VolumeLayout is the part of the cluster topology erasure coding and remote
+// tiering need: the collections holding plain volumes (candidates for
+// encoding), every plain and EC volume, and the volume size limit fullness
+// is measured against.
+type VolumeLayout struct {
+	Collections []string
+	Volumes     []Volume
+	ECVolumes   []ECVolume
+	// VolumeSizeLimit is in bytes.
+	VolumeSizeLimit uint64
+}
+
+// VolumeLayout asks the master for the cluster topology and summarizes its
+// collections and EC volumes.
+func (sa *SeaweedAdmin) VolumeLayout(ctx context.Context) (VolumeLayout, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, masterConnectionTimeout)
+	defer cancel()
+	if sa.commandEnv.MasterClient.GetMaster(waitCtx) == "" {
+		return VolumeLayout{}, fmt.Errorf("wait for master connection: %w", waitCtx.Err())
+	}
+
+	var resp *master_pb.VolumeListResponse
+	err := sa.commandEnv.MasterClient.WithClient(false, func(client master_pb.SeaweedClient) error {
+		r, e := client.VolumeList(ctx, &master_pb.VolumeListRequest{})
+		if e != nil {
+			return e
+		}
+		resp = r
+		return nil
+	})
+	if err != nil {
+		return VolumeLayout{}, err
+	}
+	layout := volumeLayout(resp.GetTopologyInfo())
+	layout.VolumeSizeLimit = resp.GetVolumeSizeLimitMb() * 1024 * 1024
+	return layout, nil
+}
+
+// volumeLayout walks a master TopologyInfo. Split out from the RPC call so the
+// aggregation is unit-testable without a live cluster. Collections and
+// volumes come back sorted.
+func volumeLayout(topo *master_pb.TopologyInfo) VolumeLayout {
+	collections := map[string]bool{}
+	plain := map[uint32]*Volume{}
+	ec := map[uint32]*ECVolume{}
+	for _, dc := range topo.GetDataCenterInfos() {
+		for _, rack := range dc.GetRackInfos() {
+			for _, dn := range rack.GetDataNodeInfos() {
+				for _, disk := range dn.GetDiskInfos() {
+					for _, v := range disk.GetVolumeInfos() {
+						collections[v.GetCollection()] = true
+						vol := plain[v.GetId()]
+						if vol == nil {
+							vol = &Volume{ID: v.GetId(), Collection: v.GetCollection()}
+							plain[v.GetId()] = vol
+						}
+						if v.GetSize() > vol.Size {
+							vol.Size = v.GetSize()
+						}
+						if modified := time.Unix(v.GetModifiedAtSecond(), 0); modified.After(vol.ModifiedAt) {
+							vol.ModifiedAt = modified
+						}
+						if name := v.GetRemoteStorageName(); name != "" {
+							vol.RemoteStorage = name
+						}
+					}
+					for _, s := range disk.GetEcShardInfos() {
+						v := ec[s.GetId()]
+						if v == nil {
+							v = &ECVolume{ID: s.GetId(), Collection: s.GetCollection()}
+							ec[s.GetId()] = v
+						}
+						v.ShardBits |= s.GetEcIndexBits()
+					}
+				}
+			}
+		}
+	}
+
+	var layout VolumeLayout
+	for c := range collections {
+		layout.Collections = append(layout.Collections, c)
+	}
+	sort.Strings(layout.Collections)
+	for _, v := range plain {
+		layout.Volumes = append(layout.Volumes, *v)
+	}
+	sort.Slice(layout.Volumes, func(i, j int) bool { return layout.Volumes[i].ID < layout.Volumes[j].ID })
+	for _, v := range ec {
+		layout.ECVolumes = append(layout.ECVolumes, *v)
+	}
+	sort.Slice(layout.ECVolumes, func(i, j int) bool { return layout.ECVolumes[i].ID < layout.ECVolumes[j].ID })
+	return layout
+}
@@ -0,0 +1,58 @@
+package swadmin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/seaweedfs/seaweedfs/weed/filer"
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// SetPathReadOnly sets or clears the readOnly flag of the filer.conf location
+// rule for prefix, keeping the rule's other settings, and reports whether it
+// changed filer.conf. Clearing the flag drops a rule left with nothing else
+// set, so switching a path read-only and back leaves filer.conf as it was.
+// Filers pick the change up through their metadata subscription, within a
+// moment of the write.
+func (sa *SeaweedAdmin) SetPathReadOnly(ctx context.Context, prefix string, readOnly bool) (bool, error) {
+	changed := false
+	err := sa.commandEnv.WithFilerClient(false, func(client filer_pb.SeaweedFilerClient) error {
+		content, err := filer.ReadInsideFiler(ctx, client, filer.DirectoryEtcSeaweedFS, filer.FilerConfName)
+		if err != nil && !isFilerNotFound(err) {
+			return fmt.Errorf("read filer.conf: %w", err)
+		}
+		fc := filer.NewFilerConf()
+		if err == nil {
+			if err := fc.LoadFromBytes(content); err != nil {
+				return fmt.Errorf("parse filer.conf: %w", err)
+			}
+		}
+
+		conf := &filer_pb.FilerConf_PathConf{LocationPrefix: prefix}
+		if existing, found := fc.GetLocationConf(prefix); found {
+			conf = proto.Clone(existing).(*filer_pb.FilerConf_PathConf)
+		}
+		if conf.ReadOnly == readOnly {
+			return nil
+		}
+		conf.ReadOnly = readOnly
+		if !readOnly && proto.Equal(conf, &filer_pb.FilerConf_PathConf{LocationPrefix: prefix}) {
+			fc.DeleteLocationConf(prefix)
+		} else if err := fc.AddLocationConf(conf); err != nil {
+			return fmt.Errorf("set filer.conf rule %s: %w", prefix, err)
+		}
+
+		var buf bytes.Buffer
+		if err := fc.ToText(&buf); err != nil {
+			return fmt.Errorf("serialize filer.conf: %w", err)
+		}
+		if err := filer.SaveInsideFiler(ctx, client, filer.DirectoryEtcSeaweedFS, filer.FilerConfName, buf.Bytes()); err != nil {
+			return fmt.Errorf("save filer.conf: %w", err)
+		}
+		changed = true
+		return nil
+	})
+	return changed, err
+}
@@ -0,0 +1,41 @@
+package swadmin
+
+import (
+	"context"
+	"errors"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+)
+
+// FilerSignature returns the filer's signature, the id filer.sync uses to
+// key the checkpoints it keeps for that filer as a source.
+func (sa *SeaweedAdmin) FilerSignature(ctx context.Context) (int32, error) {
+	var signature int32
+	err := sa.commandEnv.WithFilerClient(false, func(client filer_pb.SeaweedFilerClient) error {
+		resp, err := client.GetFilerConfiguration(ctx, &filer_pb.GetFilerConfigurationRequest{})
+		if err != nil {
+			return err
+		}
+		signature = resp.Signature
+		return nil
+	})
+	return signature, err
+}
+
+// KvGet reads key from the filer's key-value store, returning nil when the
+// key is not set.
+func (sa *SeaweedAdmin) KvGet(ctx context.Context, key []byte) ([]byte, error) {
+	var value []byte
+	err := sa.commandEnv.WithFilerClient(false, func(client filer_pb.SeaweedFilerClient) error {
+		resp, err := client.KvGet(ctx, &filer_pb.KvGetRequest{Key: key})
+		if err != nil {
+			return err
+		}
+		if resp.Error != "" {
+			return errors.New(resp.Error)
+		}
+		value = resp.Value
+		return nil
+	})
+	return value, err
+}
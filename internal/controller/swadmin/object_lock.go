@@ -0,0 +1,215 @@
+package swadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+)
+
+// Filer entry extended attributes holding Object Lock state. The bucket keys
+// carry the default retention of the bucket's object-lock configuration; the
+// object keys carry each version's own retention and legal hold. They match
+// the keys the SeaweedFS S3 gateway reads and writes.
+const (
+	bucketObjectLockDefaultModeKey  = "s3-object-lock-default-mode"
+	bucketObjectLockDefaultDaysKey  = "s3-object-lock-default-days"
+	bucketObjectLockDefaultYearsKey = "s3-object-lock-default-years"
+
+	objectRetainUntilDateKey = "X-Amz-Object-Lock-Retain-Until-Date"
+	objectLegalHoldKey       = "X-Amz-Object-Lock-Legal-Hold"
+	objectLegalHoldOn        = "ON"
+)
+
+// retentionScanPageSize is the ListEntries page size of a retention scan, and
+// maxRetentionScanEntries caps how many entries one scan visits so a huge
+// bucket can't stall the caller.
+const (
+	retentionScanPageSize   = 1024
+	maxRetentionScanEntries = 100000
+)
+
+// ObjectLockRetention is a bucket's default retention. Exactly one of Days or
+// Years is non-zero.
+type ObjectLockRetention struct {
+	Mode  string
+	Days  int32
+	Years int32
+}
+
+// RetentionSummary describes the objects still protected by Object Lock.
+type RetentionSummary struct {
+	// EarliestExpiry is the soonest retain-until date still in the future;
+	// zero when no object is under retention.
+	EarliestExpiry time.Time
+	// LegalHold reports whether any object is under legal hold.
+	LegalHold bool
+	// Truncated reports that the scan stopped at maxRetentionScanEntries.
+	Truncated bool
+}
+
+// GetObjectLockRetention returns the bucket's default retention, or nil when
+// none is configured.
+func (sa *SeaweedAdmin) GetObjectLockRetention(ctx context.Context, bucket string) (*ObjectLockRetention, error) {
+	var ret *ObjectLockRetention
+	err := sa.commandEnv.WithFilerClient(false, func(client filer_pb.SeaweedFilerClient) error {
+		entry, _, err := lookupBucketEntry(ctx, client, bucket)
+		if err != nil {
+			return err
+		}
+		ret, err = parseObjectLockRetention(entry.Extended)
+		return err
+	})
+	return ret, err
+}
+
+// SetObjectLockRetention stores the bucket's default retention. nil removes it.
+func (sa *SeaweedAdmin) SetObjectLockRetention(ctx context.Context, bucket string, ret *ObjectLockRetention) error {
+	return sa.updateBucketExtended(ctx, bucket, func(ext map[string][]byte) bool {
+		var mode, days, years []byte
+		if ret != nil {
+			mode = []byte(ret.Mode)
+			if ret.Days > 0 {
+				days = []byte(strconv.Itoa(int(ret.Days)))
+			}
+			if ret.Years > 0 {
+				years = []byte(strconv.Itoa(int(ret.Years)))
+			}
+		}
+		changed := setExtended(ext, bucketObjectLockDefaultModeKey, mode)
+		changed = setExtended(ext, bucketObjectLockDefaultDaysKey, days) || changed
+		changed = setExtended(ext, bucketObjectLockDefaultYearsKey, years) || changed
+		return changed
+	})
+}
+
+// ScanRetention walks the bucket, including the version directories of
+// versioned objects, and summarises the retention and legal holds still in
+// force at now.
+func (sa *SeaweedAdmin) ScanRetention(ctx context.Context, bucket string, now time.Time) (RetentionSummary, error) {
+	var sum RetentionSummary
+	err := sa.commandEnv.WithFilerClient(false, func(client filer_pb.SeaweedFilerClient) error {
+		_, dir, err := lookupBucketEntry(ctx, client, bucket)
+		if err != nil {
+			return err
+		}
+		visited := 0
+		pending := []string{dir + "/" + bucket}
+		for len(pending) > 0 {
+			current := pending[len(pending)-1]
+			pending = pending[:len(pending)-1]
+			err := listDirectory(ctx, client, current, func(entry *filer_pb.Entry) bool {
+				if visited++; visited > maxRetentionScanEntries {
+					sum.Truncated = true
+					return false
+				}
+				if entry.IsDirectory {
+					pending = append(pending, current+"/"+entry.Name)
+				}
+				accumulateRetention(&sum, entry.Extended, now)
+				return true
+			})
+			if err != nil {
+				return err
+			}
+			if sum.Truncated {
+				return nil
+			}
+		}
+		return nil
+	})
+	return sum, err
+}
+
+// listDirectory pages through dir's entries, calling fn for each until it
+// returns false.
+func listDirectory(ctx context.Context, client filer_pb.SeaweedFilerClient, dir string, fn func(*filer_pb.Entry) bool) error {
+	startFrom := ""
+	for {
+		stream, err := client.ListEntries(ctx, &filer_pb.ListEntriesRequest{
+			Directory:         dir,
+			StartFromFileName: startFrom,
+			Limit:             retentionScanPageSize,
+		})
+		if err != nil {
+			return fmt.Errorf("list %s: %w", dir, err)
+		}
+		n := 0
+		for {
+			resp, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("list %s: %w", dir, err)
+			}
+			n++
+			startFrom = resp.Entry.Name
+			if !fn(resp.Entry) {
+				return nil
+			}
+		}
+		if n < retentionScanPageSize {
+			return nil
+		}
+	}
+}
+
+// accumulateRetention folds one entry's retention and legal hold into sum.
+// Expired retention is ignored: it no longer blocks deletion.
+func accumulateRetention(sum *RetentionSummary, ext map[string][]byte, now time.Time) {
+	if string(ext[objectLegalHoldKey]) == objectLegalHoldOn {
+		sum.LegalHold = true
+	}
+	until, ok := parseRetainUntil(ext[objectRetainUntilDateKey])
+	if !ok || !until.After(now) {
+		return
+	}
+	if sum.EarliestExpiry.IsZero() || until.Before(sum.EarliestExpiry) {
+		sum.EarliestExpiry = until
+	}
+}
+
+// parseRetainUntil decodes a stored retain-until date: Unix seconds, or
+// RFC 3339 as written by older gateways.
+func parseRetainUntil(v []byte) (time.Time, bool) {
+	if len(v) == 0 {
+		return time.Time{}, false
+	}
+	if secs, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), true
+	}
+	if t, err := time.Parse(time.RFC3339, string(v)); err == nil {
+		return t.UTC(), true
+	}
+	return time.Time{}, false
+}
+
+// parseObjectLockRetention reads the default retention from a bucket entry's
+// extended attributes; nil when no mode is stored.
+func parseObjectLockRetention(ext map[string][]byte) (*ObjectLockRetention, error) {
+	mode := string(ext[bucketObjectLockDefaultModeKey])
+	if mode == "" {
+		return nil, nil
+	}
+	ret := &ObjectLockRetention{Mode: mode}
+	for key, dst := range map[string]*int32{
+		bucketObjectLockDefaultDaysKey:  &ret.Days,
+		bucketObjectLockDefaultYearsKey: &ret.Years,
+	} {
+		v, ok := ext[key]
+		if !ok || len(v) == 0 {
+			continue
+		}
+		n, err := strconv.ParseInt(string(v), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", key, err)
+		}
+		*dst = int32(n)
+	}
+	return ret, nil
+}
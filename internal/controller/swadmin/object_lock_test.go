@@ -0,0 +1,52 @@
+package swadmin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseObjectLockRetention(t *testing.T) {
+	ret, err := parseObjectLockRetention(map[string][]byte{})
+	if err != nil || ret != nil {
+		t.Fatalf("no mode should read as unset, got %+v, %v", ret, err)
+	}
+	ret, err = parseObjectLockRetention(map[string][]byte{
+		bucketObjectLockDefaultModeKey:  []byte("COMPLIANCE"),
+		bucketObjectLockDefaultYearsKey: []byte("7"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *ret != (ObjectLockRetention{Mode: "COMPLIANCE", Years: 7}) {
+		t.Errorf("got %+v", *ret)
+	}
+	if _, err := parseObjectLockRetention(map[string][]byte{
+		bucketObjectLockDefaultModeKey: []byte("GOVERNANCE"),
+		bucketObjectLockDefaultDaysKey: []byte("soon"),
+	}); err == nil {
+		t.Errorf("malformed days should fail to parse")
+	}
+}
+
+func TestAccumulateRetention(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var sum RetentionSummary
+	accumulateRetention(&sum, map[string][]byte{objectRetainUntilDateKey: []byte("1735689600")}, now) // 2025, expired
+	accumulateRetention(&sum, map[string][]byte{objectRetainUntilDateKey: []byte("1798761600")}, now) // 2027
+	accumulateRetention(&sum, map[string][]byte{objectRetainUntilDateKey: []byte("2026-06-01T00:00:00Z")}, now)
+	accumulateRetention(&sum, map[string][]byte{objectRetainUntilDateKey: []byte("garbage")}, now)
+	if want := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC); !sum.EarliestExpiry.Equal(want) {
+		t.Errorf("EarliestExpiry = %v, want %v", sum.EarliestExpiry, want)
+	}
+	if sum.LegalHold {
+		t.Errorf("no legal hold was set")
+	}
+	accumulateRetention(&sum, map[string][]byte{objectLegalHoldKey: []byte("OFF")}, now)
+	if sum.LegalHold {
+		t.Errorf("legal hold OFF counted as held")
+	}
+	accumulateRetention(&sum, map[string][]byte{objectLegalHoldKey: []byte("ON")}, now)
+	if !sum.LegalHold {
+		t.Errorf("legal hold ON not reported")
+	}
+}
@@ -33,6 +33,7 @@ func init() {
 type SeaweedAdmin struct {
 	commandReg *regexp.Regexp
 	commandEnv *shell.CommandEnv
+	dialOption grpc.DialOption
 	Output     io.Writer
 	cancel     context.CancelFunc
 	closeOnce  sync.Once
@@ -66,6 +67,7 @@ func NewSeaweedAdmin(masters, filer string, dialOption grpc.DialOption, output i
 
 	return &SeaweedAdmin{
 		commandEnv: commandEnv,
+		dialOption: dialOption,
 		commandReg: reg,
 		Output:     output,
 		cancel:     cancel,
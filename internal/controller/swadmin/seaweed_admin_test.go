@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"io"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/seaweedfs/seaweedfs/weed/pb"
 	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
 	"github.com/seaweedfs/seaweedfs/weed/pb/master_pb"
 )
@@ -88,6 +90,115 @@ func TestVolumeServerVolumeCounts_EmptyTopology(t *testing.T) {
 	}
 }
 
+func TestVolumeLayout(t *testing.T) {
+	topo := &master_pb.TopologyInfo{
+		DataCenterInfos: []*master_pb.DataCenterInfo{{
+			RackInfos: []*master_pb.RackInfo{{
+				DataNodeInfos: []*master_pb.DataNodeInfo{
+					{
+						Id: "vol-0:8444",
+						DiskInfos: map[string]*master_pb.DiskInfo{"hdd": {
+							VolumeInfos: []*master_pb.VolumeInformationMessage{
+								{Id: 1, Collection: "logs", Size: 100, ModifiedAtSecond: 1000},
+								{Id: 2, RemoteStorageName: "s3.cold"},
+							},
+							EcShardInfos: []*master_pb.VolumeEcShardInformationMessage{
+								{Id: 7, Collection: "logs", EcIndexBits: 0b0000_0000_0111_1111},
+							},
+						}},
+					},
+					{
+						Id: "vol-1:8444",
+						DiskInfos: map[string]*master_pb.DiskInfo{"hdd": {
+							VolumeInfos: []*master_pb.VolumeInformationMessage{
+								{Id: 3, Collection: "logs"},
+								{Id: 1, Collection: "logs", Size: 120, ModifiedAtSecond: 900},
+							},
+							EcShardInfos: []*master_pb.VolumeEcShardInformationMessage{
+								{Id: 7, Collection: "logs", EcIndexBits: 0b0011_1111_1000_0000},
+								{Id: 5, Collection: "media", EcIndexBits: 0b0000_0000_0000_0011},
+							},
+						}},
+					},
+				},
+			}},
+		}},
+	}
+
+	got := volumeLayout(topo)
+	if want := []string{"", "logs"}; !reflect.DeepEqual(got.Collections, want) {
+		t.Errorf("collections = %q, want %q", got.Collections, want)
+	}
+	wantVolumes := []Volume{
+		{ID: 1, Collection: "logs", Size: 120, ModifiedAt: time.Unix(1000, 0)},
+		{ID: 2, ModifiedAt: time.Unix(0, 0), RemoteStorage: "s3.cold"},
+		{ID: 3, Collection: "logs", ModifiedAt: time.Unix(0, 0)},
+	}
+	if !reflect.DeepEqual(got.Volumes, wantVolumes) {
+		t.Errorf("volumes = %+v\nwant %+v (replicas merged)", got.Volumes, wantVolumes)
+	}
+	if len(got.ECVolumes) != 2 {
+		t.Fatalf("ec volumes = %+v, want 2", got.ECVolumes)
+	}
+	if v := got.ECVolumes[0]; v.ID != 5 || v.Collection != "media" || v.Shards() != 2 {
+		t.Errorf("ec volume 5 = %+v (%d shards)", v, v.Shards())
+	}
+	if v := got.ECVolumes[1]; v.ID != 7 || v.Shards() != 14 {
+		t.Errorf("ec volume 7 = %+v (%d shards), want shards merged across servers", v, v.Shards())
+	}
+
+	if got := volumeLayout(nil); len(got.Collections) != 0 || len(got.ECVolumes) != 0 {
+		t.Errorf("layout for nil topology = %+v, want empty", got)
+	}
+}
+
+func TestWritableVolumesAndReplicas(t *testing.T) {
+	vol0 := &master_pb.DataNodeInfo{
+		Id: "vol-0:8444",
+		DiskInfos: map[string]*master_pb.DiskInfo{"hdd": {
+			VolumeInfos: []*master_pb.VolumeInformationMessage{
+				{Id: 4},
+				{Id: 1},
+				{Id: 2, ReadOnly: true},
+				{Id: 5, RemoteStorageName: "s3.cold"},
+			},
+		}},
+	}
+	vol1 := &master_pb.DataNodeInfo{
+		Id: "vol-1:8444",
+		DiskInfos: map[string]*master_pb.DiskInfo{"hdd": {
+			VolumeInfos: []*master_pb.VolumeInformationMessage{
+				{Id: 1},
+				{Id: 2},
+				// One read-only replica makes the whole volume read-only.
+				{Id: 4, ReadOnly: true},
+				{Id: 6},
+			},
+		}},
+	}
+	topo := &master_pb.TopologyInfo{
+		DataCenterInfos: []*master_pb.DataCenterInfo{{
+			RackInfos: []*master_pb.RackInfo{{DataNodeInfos: []*master_pb.DataNodeInfo{vol0, vol1}}},
+		}},
+	}
+
+	if got, want := writableVolumes(topo), []uint32{1, 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("writable volumes = %v, want %v", got, want)
+	}
+	if got := writableVolumes(nil); len(got) != 0 {
+		t.Errorf("writable volumes of nil topology = %v, want none", got)
+	}
+
+	got := volumeReplicas(topo, []uint32{1, 6, 9})
+	want := map[pb.ServerAddress][]uint32{
+		pb.NewServerAddressFromDataNode(vol0): {1},
+		pb.NewServerAddressFromDataNode(vol1): {1, 6},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("replicas = %v, want %v (volume 9 is gone and skipped)", got, want)
+	}
+}
+
 func TestSeaweedAdmin_ProcessCommand_CanceledWhileWaiting(t *testing.T) {
 	sa := NewSeaweedAdmin("seaweed-master.invalid:9333", "", nil, io.Discard)
 	t.Cleanup(func() { _ = sa.Close() })
@@ -0,0 +1,131 @@
+package swadmin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/seaweedfs/seaweedfs/weed/operation"
+	"github.com/seaweedfs/seaweedfs/weed/pb"
+	"github.com/seaweedfs/seaweedfs/weed/pb/master_pb"
+	"github.com/seaweedfs/seaweedfs/weed/pb/volume_server_pb"
+)
+
+// WritableVolumes asks the master for the cluster topology and returns the
+// ids of the plain volumes no replica of which is read-only, sorted.
+func (sa *SeaweedAdmin) WritableVolumes(ctx context.Context) ([]uint32, error) {
+	topo, err := sa.topology(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return writableVolumes(topo), nil
+}
+
+// SetVolumesReadOnly marks every replica of the given volumes read-only, or
+// writable again, the way `volume.mark` does. The mark is not persisted, so
+// a volume server restarted in between, or a copy of its disk, comes up
+// writable. Volumes the master no longer knows of are skipped.
+func (sa *SeaweedAdmin) SetVolumesReadOnly(ctx context.Context, ids []uint32, readOnly bool) error {
+	topo, err := sa.topology(ctx)
+	if err != nil {
+		return err
+	}
+	for server, vids := range volumeReplicas(topo, ids) {
+		err := operation.WithVolumeServerClient(false, server, sa.dialOption, func(client volume_server_pb.VolumeServerClient) error {
+			for _, vid := range vids {
+				var e error
+				if readOnly {
+					_, e = client.VolumeMarkReadonly(ctx, &volume_server_pb.VolumeMarkReadonlyRequest{VolumeId: vid})
+				} else {
+					_, e = client.VolumeMarkWritable(ctx, &volume_server_pb.VolumeMarkWritableRequest{VolumeId: vid})
+				}
+				if e != nil {
+					return fmt.Errorf("volume %d: %w", vid, e)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("mark volumes on %s: %w", server, err)
+		}
+	}
+	return nil
+}
+
+// topology waits for the master, as ProcessCommand does, and returns its
+// view of the cluster.
+func (sa *SeaweedAdmin) topology(ctx context.Context) (*master_pb.TopologyInfo, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, masterConnectionTimeout)
+	defer cancel()
+	if sa.commandEnv.MasterClient.GetMaster(waitCtx) == "" {
+		return nil, fmt.Errorf("wait for master connection: %w", waitCtx.Err())
+	}
+
+	var resp *master_pb.VolumeListResponse
+	err := sa.commandEnv.MasterClient.WithClient(false, func(client master_pb.SeaweedClient) error {
+		r, e := client.VolumeList(ctx, &master_pb.VolumeListRequest{})
+		if e != nil {
+			return e
+		}
+		resp = r
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetTopologyInfo(), nil
+}
+
+// writableVolumes walks a master TopologyInfo for the plain volumes every
+// replica of which accepts writes. Remote-tier volumes are read-only already
+// and left out. Split out from the RPC call so it is unit-testable without a
+// live cluster.
+func writableVolumes(topo *master_pb.TopologyInfo) []uint32 {
+	writable := map[uint32]bool{}
+	forEachVolume(topo, func(_ *master_pb.DataNodeInfo, v *master_pb.VolumeInformationMessage) {
+		ok, seen := writable[v.GetId()]
+		if seen && !ok {
+			return
+		}
+		writable[v.GetId()] = !v.GetReadOnly() && v.GetRemoteStorageName() == ""
+	})
+	var ids []uint32
+	for id, ok := range writable {
+		if ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// volumeReplicas groups the replicas of the given volumes by the volume
+// server holding them.
+func volumeReplicas(topo *master_pb.TopologyInfo, ids []uint32) map[pb.ServerAddress][]uint32 {
+	want := map[uint32]bool{}
+	for _, id := range ids {
+		want[id] = true
+	}
+	replicas := map[pb.ServerAddress][]uint32{}
+	forEachVolume(topo, func(dn *master_pb.DataNodeInfo, v *master_pb.VolumeInformationMessage) {
+		if want[v.GetId()] {
+			server := pb.NewServerAddressFromDataNode(dn)
+			replicas[server] = append(replicas[server], v.GetId())
+		}
+	})
+	return replicas
+}
+
+func forEachVolume(topo *master_pb.TopologyInfo, fn func(*master_pb.DataNodeInfo, *master_pb.VolumeInformationMessage)) {
+	for _, dc := range topo.GetDataCenterInfos() {
+		for _, rack := range dc.GetRackInfos() {
+			for _, dn := range rack.GetDataNodeInfos() {
+				for _, disk := range dn.GetDiskInfos() {
+					for _, v := range disk.GetVolumeInfos() {
+						fn(dn, v)
+					}
+				}
+			}
+		}
+	}
+}
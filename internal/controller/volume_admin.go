@@ -45,9 +45,46 @@ type VolumeAdmin interface {
 	// returns an error if any volume cannot be moved (e.g. no replication-safe
 	// destination), so the caller never removes a server that still holds data.
 	EvacuateServer(ctx context.Context, node string) error
+	// VolumeLayout returns the collections holding plain volumes and every
+	// EC volume with the shards the master reports for it.
+	VolumeLayout(ctx context.Context) (swadmin.VolumeLayout, error)
+	// EncodeErasureCoding erasure-codes volume id of collection (ec.encode).
+	EncodeErasureCoding(ctx context.Context, collection string, id uint32, opts ErasureCodingEncodeOptions) error
+	// RebuildErasureCoding regenerates the missing EC shards of collection
+	// (ec.rebuild).
+	RebuildErasureCoding(ctx context.Context, collection string) error
+	// UploadToRemoteTier moves the data of volume id, in collection, to the
+	// remote storage backend dest ("s3.<tier>"), keeping only its index
+	// locally (volume.tier.upload).
+	UploadToRemoteTier(ctx context.Context, dest, collection string, id uint32) error
+	// WritableVolumes returns the ids of the plain volumes that accept
+	// writes on every replica.
+	WritableVolumes(ctx context.Context) ([]uint32, error)
+	// SetVolumesReadOnly marks every replica of the volumes ids read-only,
+	// or writable again (volume.mark). Volumes that are gone are skipped.
+	SetVolumesReadOnly(ctx context.Context, ids []uint32, readOnly bool) error
 	io.Closer
 }
 
+// ErasureCodingEncodeOptions are the erasure coding thresholds for one
+// collection, which pick the volumes to encode, and its shard layout. Nil
+// shard counts keep the default layout.
+type ErasureCodingEncodeOptions struct {
+	FullPercent  int32
+	QuietFor     time.Duration
+	DataShards   *int32
+	ParityShards *int32
+}
+
+// encodeCommand is the ec.encode invocation for volume id of collection.
+func (o ErasureCodingEncodeOptions) encodeCommand(collection string, id uint32) string {
+	cmd := fmt.Sprintf("ec.encode -collection=%s -volumeId=%d", collection, id)
+	if o.DataShards != nil && o.ParityShards != nil {
+		cmd += fmt.Sprintf(" -dataShards=%d -parityShards=%d", *o.DataShards, *o.ParityShards)
+	}
+	return cmd
+}
+
 // VolumeAdminFactory builds a VolumeAdmin for a target cluster's masters.
 // grpcDialOption carries the transport credentials for clusters with [grpc]
 // mTLS (nil to dial without TLS). Replaceable in tests.
@@ -113,6 +150,65 @@ func (a *swadminVolumeAdmin) EvacuateServer(ctx context.Context, node string) er
 	return nil
 }
 
+func (a *swadminVolumeAdmin) VolumeLayout(ctx context.Context) (swadmin.VolumeLayout, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.sa.VolumeLayout(ctx)
+}
+
+func (a *swadminVolumeAdmin) EncodeErasureCoding(ctx context.Context, collection string, id uint32, opts ErasureCodingEncodeOptions) error {
+	return a.locked(ctx, opts.encodeCommand(collection, id))
+}
+
+func (a *swadminVolumeAdmin) RebuildErasureCoding(ctx context.Context, collection string) error {
+	return a.locked(ctx, fmt.Sprintf("ec.rebuild -collection=%s -apply", collection))
+}
+
+func (a *swadminVolumeAdmin) UploadToRemoteTier(ctx context.Context, dest, collection string, id uint32) error {
+	return a.locked(ctx, tierUploadCommand(dest, collection, id))
+}
+
+func (a *swadminVolumeAdmin) WritableVolumes(ctx context.Context) ([]uint32, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.sa.WritableVolumes(ctx)
+}
+
+func (a *swadminVolumeAdmin) SetVolumesReadOnly(ctx context.Context, ids []uint32, readOnly bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.sa.SetVolumesReadOnly(ctx, ids, readOnly)
+}
+
+// tierUploadCommand is the volume.tier.upload invocation for one volume.
+func tierUploadCommand(dest, collection string, id uint32) string {
+	return fmt.Sprintf("volume.tier.upload -dest=%s -collection=%s -volumeId=%d", dest, collection, id)
+}
+
+// locked runs cmd holding the master lock, which ec.* commands require, and
+// folds the shell output into any error.
+func (a *swadminVolumeAdmin) locked(ctx context.Context, cmd string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var buf bytes.Buffer
+	a.sa.Output = &buf
+	if err := a.sa.ProcessCommand(ctx, "lock"); err != nil {
+		a.sa.Output = io.Discard
+		return fmt.Errorf("lock masters: %w", err)
+	}
+	defer func() {
+		a.sa.Output = io.Discard
+		unlockCtx, cancel := context.WithTimeout(context.Background(), unlockTimeout)
+		defer cancel()
+		_ = a.sa.ProcessCommand(unlockCtx, "unlock")
+	}()
+	if err := a.sa.ProcessCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("%s: %w (output: %s)", cmd, err, strings.TrimSpace(buf.String()))
+	}
+	return nil
+}
+
 func (a *swadminVolumeAdmin) Close() error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
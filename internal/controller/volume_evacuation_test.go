@@ -35,6 +35,7 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	seaweedv1 "github.com/seaweedfs/seaweedfs-operator/api/v1"
+	"github.com/seaweedfs/seaweedfs-operator/internal/controller/swadmin"
 )
 
 // fakeVolumeAdmin is a test double for VolumeAdmin. It is safe for concurrent
@@ -52,6 +53,18 @@ type fakeVolumeAdmin struct {
 
 	countsCalls int
 	closeCalls  int
+
+	layout     swadmin.VolumeLayout
+	encoded    []string
+	rebuilt    []string
+	rebuildErr error
+	uploaded   []string
+	uploadErr  error
+
+	writable []uint32
+	// readOnly holds the volumes SetVolumesReadOnly marked; markErr fails it.
+	readOnly map[uint32]bool
+	markErr  error
 }
 
 func (f *fakeVolumeAdmin) VolumeServerVolumeCounts(_ context.Context) (map[string]int, error) {
@@ -81,6 +94,64 @@ func (f *fakeVolumeAdmin) EvacuateServer(_ context.Context, node string) error {
 	return f.evacErr
 }
 
+func (f *fakeVolumeAdmin) VolumeLayout(_ context.Context) (swadmin.VolumeLayout, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.layout, nil
+}
+
+func (f *fakeVolumeAdmin) EncodeErasureCoding(_ context.Context, collection string, id uint32, opts ErasureCodingEncodeOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.encoded = append(f.encoded, opts.encodeCommand(collection, id))
+	return nil
+}
+
+func (f *fakeVolumeAdmin) RebuildErasureCoding(_ context.Context, collection string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rebuilt = append(f.rebuilt, collection)
+	return f.rebuildErr
+}
+
+func (f *fakeVolumeAdmin) UploadToRemoteTier(_ context.Context, dest, collection string, id uint32) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.uploaded = append(f.uploaded, tierUploadCommand(dest, collection, id))
+	return f.uploadErr
+}
+
+func (f *fakeVolumeAdmin) WritableVolumes(_ context.Context) ([]uint32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ids []uint32
+	for _, id := range f.writable {
+		if !f.readOnly[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (f *fakeVolumeAdmin) SetVolumesReadOnly(_ context.Context, ids []uint32, readOnly bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.markErr != nil {
+		return f.markErr
+	}
+	if f.readOnly == nil {
+		f.readOnly = map[uint32]bool{}
+	}
+	for _, id := range ids {
+		if readOnly {
+			f.readOnly[id] = true
+		} else {
+			delete(f.readOnly, id)
+		}
+	}
+	return nil
+}
+
 func (f *fakeVolumeAdmin) Close() error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -144,12 +144,19 @@ var _ = Describe("Backup and restore", Ordered, func() {
 			g.Expect(owner.Controller).NotTo(BeNil())
 			g.Expect(*owner.Controller).To(BeTrue())
 
+			// The weed save runs first; backup-tool then stores the snapshot
+			// and its manifest on the PVC.
+			initContainers := job.Spec.Template.Spec.InitContainers
+			g.Expect(initContainers).To(HaveLen(1))
+			g.Expect(initContainers[0].Command).To(HaveLen(3))
+			script := initContainers[0].Command[2]
+			g.Expect(script).To(ContainSubstring("fs.meta.save -o /scratch/filer.meta.gz /"))
+			g.Expect(script).To(ContainSubstring("shell -master=" + seaweedName + "-master-0."))
 			containers := job.Spec.Template.Spec.Containers
 			g.Expect(containers).To(HaveLen(1))
-			g.Expect(containers[0].Command).To(HaveLen(3))
-			script := containers[0].Command[2]
-			g.Expect(script).To(ContainSubstring("fs.meta.save -o /backup/" + seaweedName + "/adhoc-snap/filer.meta.gz /"))
-			g.Expect(script).To(ContainSubstring("shell -master=" + seaweedName + "-master-0."))
+			upload := strings.Join(containers[0].Command, " ")
+			g.Expect(upload).To(ContainSubstring("snapshot-upload"))
+			g.Expect(upload).To(ContainSubstring("-key=" + seaweedName + "/adhoc-snap/filer.meta.gz"))
 		}, time.Minute*2, time.Second*5).Should(Succeed())
 
 		By("reflecting Running status onto the SeaweedBackup")
@@ -226,10 +233,16 @@ var _ = Describe("Backup and restore", Ordered, func() {
 			g.Expect(job.OwnerReferences).NotTo(BeEmpty())
 			g.Expect(job.OwnerReferences[0].Kind).To(Equal("SeaweedRestore"))
 
+			// The snapshot is verified on download before it is loaded.
+			initContainers := job.Spec.Template.Spec.InitContainers
+			g.Expect(initContainers).To(HaveLen(1))
+			download := strings.Join(initContainers[0].Command, " ")
+			g.Expect(download).To(ContainSubstring("snapshot-download"))
+			g.Expect(download).To(ContainSubstring("-key=" + seaweedName + "/adhoc-snap/filer.meta.gz"))
 			containers := job.Spec.Template.Spec.Containers
 			g.Expect(containers).To(HaveLen(1))
 			script := containers[0].Command[2]
-			g.Expect(script).To(ContainSubstring("fs.meta.load /backup/" + seaweedName + "/adhoc-snap/filer.meta.gz"))
+			g.Expect(script).To(ContainSubstring("fs.meta.load /scratch/restore.meta.gz"))
 		}, time.Minute*2, time.Second*5).Should(Succeed())
 	})
 